@@ -0,0 +1,382 @@
+package llms
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+)
+
+// ErrMaxTurnsExceeded is returned by RunTools when the model is still calling
+// tools after the maximum number of turns.
+var ErrMaxTurnsExceeded = errors.New("llms: maximum number of turns exceeded")
+
+// ErrToolFatal is returned by RunTools when a tool fails with a
+// ToolErrorFatal error. The tool's error is wrapped as well.
+var ErrToolFatal = errors.New("llms: fatal tool error")
+
+// RunToolsOptions configures RunTools.
+type RunToolsOptions struct {
+	// MaxTurns is the maximum number of requests made to the LLM. Defaults to
+	// 10.
+	MaxTurns int
+	// ToolTimeout, if set, bounds the time each tool execution may take. A
+	// tool that has not returned by then is abandoned and reported to the
+	// model as an error, even if it ignores its context.
+	ToolTimeout time.Duration
+	// ToolTimeouts overrides ToolTimeout for individual tools, keyed by tool
+	// name. A zero value disables the timeout for that tool.
+	ToolTimeouts map[string]time.Duration
+	// ToolRetries is the maximum number of times a tool call failing with a
+	// ToolErrorTransient error is retried before the error is reported to
+	// the model. Defaults to 0, which does not retry.
+	ToolRetries int
+	// ToolRetryDelay is the delay before each retry of a tool call.
+	ToolRetryDelay time.Duration
+	// Concurrency is the maximum number of tool calls from a single response
+	// that are executed at the same time. Zero or less runs every call in the
+	// response concurrently; 1 runs them sequentially.
+	Concurrency int
+	// Stream, if set, makes RunTools use GenerateStream and receive every
+	// streamed response across all turns.
+	Stream StreamFunc
+	// SkipValidation disables checking each tool call's input against the
+	// tool's Schema before executing it, and the results of an
+	// OutputSchemaTool against its OutputSchema. By default, invalid input is
+	// reported back to the model as a tool error without calling Execute, and
+	// so are invalid results.
+	SkipValidation bool
+	// ToolFilter, if set, is called before each request with the
+	// conversation so far and the tools passed to RunTools, and returns the
+	// tools the model may call in that turn. The others are disabled with
+	// DisableTools, and calls to them are reported back to the model as
+	// errors. Tools configured on the LLM but not passed to RunTools are not
+	// affected.
+	ToolFilter func(messages []Message, tools []Tool) []Tool
+}
+
+// RunResult is the outcome of RunTools.
+type RunResult struct {
+	// Response is the final response from the LLM.
+	Response *Response
+	// Messages is the full conversation, including the input messages, every
+	// assistant response, and every tool result message.
+	Messages []Message
+	// Turns is the number of requests made to the LLM.
+	Turns int
+	// RepairedToolCalls holds the IDs of the tool calls whose input was
+	// malformed JSON and was fixed with RepairJSON before being executed.
+	// The repaired input replaces the original in Messages.
+	RepairedToolCalls []string
+}
+
+// RunTools sends messages to llm and, for as long as the model responds with
+// tool calls, executes the matching tools and feeds their results back. It
+// returns once the model replies without calling any tools.
+//
+// Tools are matched to calls by name. The tools must also be configured on llm
+// so the model knows about them. Calls to unknown tools, and to tools that are
+// not an ExecutableTool, are reported back to the model as errors. Tool errors
+// are handled according to their ToolErrorKind: transient errors are retried,
+// and fatal errors stop the loop with an error wrapping ErrToolFatal.
+func RunTools(ctx context.Context, llm LLM, messages []Message, tools []Tool, opts RunToolsOptions) (*RunResult, error) {
+	if opts.MaxTurns <= 0 {
+		opts.MaxTurns = 10
+	}
+
+	byName := make(map[string]Tool, len(tools))
+	for _, tool := range tools {
+		byName[tool.Name()] = tool
+	}
+
+	result := &RunResult{
+		Messages: append([]Message(nil), messages...),
+	}
+
+	for result.Turns < opts.MaxTurns {
+		turnCtx, turnTools := ctx, byName
+		if opts.ToolFilter != nil {
+			turnCtx, turnTools = filterTools(ctx, opts.ToolFilter(result.Messages, tools), tools)
+		}
+
+		var (
+			resp *Response
+			err  error
+		)
+		if opts.Stream != nil {
+			resp, err = llm.GenerateStream(turnCtx, result.Messages, opts.Stream)
+		} else {
+			resp, err = llm.Generate(turnCtx, result.Messages)
+		}
+		result.Turns++
+		if err != nil {
+			return result, err
+		}
+
+		msg, repaired := repairToolCalls(resp.Message)
+		result.Response = resp
+		result.Messages = append(result.Messages, msg)
+		result.RepairedToolCalls = append(result.RepairedToolCalls, repaired...)
+
+		calls := ToolCalls(msg)
+		if len(calls) == 0 {
+			return result, nil
+		}
+
+		parts, err := executeToolCalls(ctx, turnTools, calls, opts)
+		if err != nil {
+			return result, err
+		}
+		result.Messages = append(result.Messages, Message{
+			Role:  RoleUser,
+			Parts: parts,
+		})
+	}
+
+	return result, fmt.Errorf("%w: %d", ErrMaxTurnsExceeded, opts.MaxTurns)
+}
+
+// filterTools returns a copy of ctx that disables the tools not in enabled,
+// and the enabled tools by name.
+func filterTools(ctx context.Context, enabled, tools []Tool) (context.Context, map[string]Tool) {
+	byName := make(map[string]Tool, len(enabled))
+	for _, tool := range enabled {
+		byName[tool.Name()] = tool
+	}
+
+	var disabled []string
+	for _, tool := range tools {
+		if _, ok := byName[tool.Name()]; !ok {
+			disabled = append(disabled, tool.Name())
+		}
+	}
+	if len(disabled) == 0 {
+		return ctx, byName
+	}
+
+	// Keep any tools the caller disabled disabled.
+	disabled = append(disabled, CallOptionsFromContext(ctx).DisabledTools...)
+	return WithCallOptions(ctx, DisableTools(disabled...)), byName
+}
+
+// repairToolCalls returns msg with the malformed input of its tool calls
+// repaired, along with the IDs of the repaired calls. msg is not modified.
+// Input that cannot be repaired is left for validation to report.
+func repairToolCalls(msg Message) (Message, []string) {
+	var ids []string
+	for i, part := range msg.Parts {
+		call, ok := part.(ToolCallPart)
+		if !ok || len(bytes.TrimSpace(call.Input)) == 0 {
+			continue
+		}
+		input, repaired, err := RepairJSON(call.Input)
+		if err != nil || !repaired {
+			continue
+		}
+
+		if ids == nil {
+			msg.Parts = slices.Clone(msg.Parts)
+		}
+		call.Input = input
+		msg.Parts[i] = call
+		ids = append(ids, call.ID)
+	}
+	return msg, ids
+}
+
+// ToolCalls returns the tool calls in msg.
+func ToolCalls(msg Message) []ToolCallPart {
+	calls := []ToolCallPart{}
+	for _, part := range msg.Parts {
+		if call, ok := part.(ToolCallPart); ok {
+			calls = append(calls, call)
+		}
+	}
+	return calls
+}
+
+// executeToolCalls runs calls using up to opts.Concurrency workers. The
+// returned parts are in the same order as calls, regardless of the order in
+// which the executions finish. If any call fails fatally, the error of the
+// first such call is returned instead.
+func executeToolCalls(ctx context.Context, tools map[string]Tool, calls []ToolCallPart, opts RunToolsOptions) ([]Part, error) {
+	workers := opts.Concurrency
+	if workers <= 0 || workers > len(calls) {
+		workers = len(calls)
+	}
+
+	parts := make([]Part, len(calls))
+	errs := make([]error, len(calls))
+	indexes := make(chan int)
+
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				parts[i], errs[i] = executeToolCall(ctx, tools[calls[i].Name], calls[i], opts)
+			}
+		}()
+	}
+
+	for i := range calls {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return parts, nil
+}
+
+// executeToolCall runs call, retrying transient errors. It returns an error
+// only if the tool failed fatally.
+func executeToolCall(ctx context.Context, tool Tool, call ToolCallPart, opts RunToolsOptions) (ToolResultPart, error) {
+	part := ToolResultPart{
+		ToolCallID: call.ID,
+		Name:       call.Name,
+	}
+
+	if tool == nil {
+		part.Error = fmt.Errorf("unknown tool %q", call.Name)
+		part.Result = part.Error.Error()
+		return part, nil
+	}
+
+	exec, ok := tool.(ExecutableTool)
+	if !ok {
+		part.Error = fmt.Errorf("tool %q cannot be executed", call.Name)
+		part.Result = part.Error.Error()
+		return part, nil
+	}
+
+	if !opts.SkipValidation {
+		if err := recoverTool(call.Name, func() error { return ValidateToolInput(tool, call.Input) }); err != nil {
+			part.Error = err
+			part.Result = err.Error()
+			return part, nil
+		}
+	}
+
+	var res *ToolResult
+	for attempt := 0; ; attempt++ {
+		res = runToolWithTimeout(ctx, exec, call, opts.toolTimeout(call.Name))
+		if res == nil || res.Error == nil || res.errorKind() != ToolErrorTransient ||
+			attempt >= opts.ToolRetries || !sleep(ctx, opts.ToolRetryDelay) {
+			break
+		}
+	}
+	if res == nil {
+		return part, nil
+	}
+
+	if res.Error != nil && res.errorKind() == ToolErrorFatal {
+		return part, fmt.Errorf("%w: tool %q: %w", ErrToolFatal, call.Name, res.Error)
+	}
+
+	part.Result = res.Content
+	part.Error = res.Error
+	if part.Error == nil && !opts.SkipValidation {
+		if err := recoverTool(call.Name, func() error { return ValidateToolOutput(tool, res.Content) }); err != nil {
+			part.Error = err
+			part.Result = err.Error()
+		}
+	}
+	if part.Error != nil && part.Result == "" {
+		part.Result = part.Error.Error()
+	}
+
+	return part, nil
+}
+
+// recoverTool calls fn, which runs code of the tool with the given name such
+// as its Schema method, and reports a panic in it as an error.
+func recoverTool(name string, fn func() error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("tool %q panicked: %v", name, r)
+		}
+	}()
+	return fn()
+}
+
+// runToolWithTimeout runs call with runTool, abandoning it after timeout if it
+// is positive.
+func runToolWithTimeout(ctx context.Context, exec ExecutableTool, call ToolCallPart, timeout time.Duration) *ToolResult {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	return runTool(ctx, exec, call)
+}
+
+// runTool executes call and waits for it to finish or for ctx to be done,
+// whichever happens first. A tool that ignores ctx keeps running in the
+// background, but its result is discarded.
+func runTool(ctx context.Context, exec ExecutableTool, call ToolCallPart) *ToolResult {
+	done := make(chan *ToolResult, 1)
+	go func() {
+		// A panicking tool must not take down the other calls running
+		// alongside it, so report the panic back to the model like any other
+		// tool error.
+		defer func() {
+			if r := recover(); r != nil {
+				done <- &ToolResult{Error: fmt.Errorf("tool %q panicked: %v", call.Name, r)}
+			}
+		}()
+		done <- exec.Execute(ctx, call.Input)
+	}()
+
+	var res *ToolResult
+	select {
+	case res = <-done:
+	case <-ctx.Done():
+		res = &ToolResult{Error: ctx.Err()}
+	}
+
+	// Give expired executions a consistent error, whether the tool noticed
+	// the deadline itself or was abandoned.
+	if ctxErr := ctx.Err(); ctxErr != nil && res != nil && errors.Is(res.Error, ctxErr) {
+		if errors.Is(ctxErr, context.DeadlineExceeded) {
+			res = &ToolResult{Error: fmt.Errorf("tool %q timed out: %w", call.Name, ctxErr)}
+		} else {
+			res = &ToolResult{Error: fmt.Errorf("tool %q was cancelled: %w", call.Name, ctxErr)}
+		}
+	}
+
+	return res
+}
+
+// sleep waits for d, returning false if ctx is done first.
+func sleep(ctx context.Context, d time.Duration) bool {
+	if d <= 0 {
+		return ctx.Err() == nil
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+// toolTimeout returns the execution timeout for the named tool.
+func (o RunToolsOptions) toolTimeout(name string) time.Duration {
+	if timeout, ok := o.ToolTimeouts[name]; ok {
+		return timeout
+	}
+	return o.ToolTimeout
+}
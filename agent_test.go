@@ -0,0 +1,403 @@
+package llms
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/invopop/jsonschema"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// echoTool returns its "text" argument, or fails if it is empty.
+type echoTool struct {
+	delay time.Duration
+}
+
+type echoParams struct {
+	Text string `json:"text"`
+}
+
+func (echoTool) Name() string               { return "echo" }
+func (echoTool) Description() string        { return "Echoes its input" }
+func (echoTool) Schema() *jsonschema.Schema { return GenerateSchema[echoParams]() }
+
+func (e echoTool) Execute(ctx context.Context, args []byte) *ToolResult {
+	if e.delay > 0 {
+		select {
+		case <-time.After(e.delay):
+		case <-ctx.Done():
+			return &ToolResult{Error: ctx.Err()}
+		}
+	}
+
+	var params echoParams
+	if err := json.Unmarshal(args, &params); err != nil {
+		return &ToolResult{Error: err}
+	}
+	if params.Text == "" {
+		return &ToolResult{Error: errors.New("text is required")}
+	}
+	return &ToolResult{Content: params.Text}
+}
+
+func toolCallResponse(calls ...ToolCallPart) *Response {
+	parts := make([]Part, 0, len(calls))
+	for _, c := range calls {
+		parts = append(parts, c)
+	}
+	return &Response{Message: Message{Role: RoleAssistant, Parts: parts}}
+}
+
+func TestRunTools(t *testing.T) {
+	fake := newFakeLLM(
+		fakeResult{resp: toolCallResponse(
+			ToolCallPart{ID: "call_1", Name: "echo", Input: []byte(`{"text":"hi"}`)},
+			ToolCallPart{ID: "call_2", Name: "missing", Input: []byte(`{}`)},
+		)},
+		fakeResult{resp: textResponse("done")},
+	)
+
+	result, err := RunTools(context.Background(), fake, []Message{NewTextMessage(RoleUser, "go")}, []Tool{echoTool{}}, RunToolsOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, 2, result.Turns)
+	assert.Equal(t, "resp_done", result.Response.ID)
+	require.Len(t, result.Messages, 4)
+
+	toolMsg := result.Messages[2]
+	assert.Equal(t, RoleUser, toolMsg.Role)
+	require.Len(t, toolMsg.Parts, 2)
+
+	echo := toolMsg.Parts[0].(ToolResultPart)
+	assert.Equal(t, "call_1", echo.ToolCallID)
+	assert.Equal(t, "hi", echo.Result)
+	assert.NoError(t, echo.Error)
+
+	missing := toolMsg.Parts[1].(ToolResultPart)
+	assert.Equal(t, "call_2", missing.ToolCallID)
+	assert.Error(t, missing.Error)
+	assert.Contains(t, missing.Result, "unknown tool")
+}
+
+func TestRunTools_ToolError(t *testing.T) {
+	fake := newFakeLLM(
+		fakeResult{resp: toolCallResponse(ToolCallPart{ID: "call_1", Name: "echo", Input: []byte(`{"text":""}`)})},
+		fakeResult{resp: textResponse("done")},
+	)
+
+	result, err := RunTools(context.Background(), fake, nil, []Tool{echoTool{}}, RunToolsOptions{})
+	require.NoError(t, err)
+
+	part := result.Messages[1].Parts[0].(ToolResultPart)
+	assert.EqualError(t, part.Error, "text is required")
+	assert.Equal(t, "text is required", part.Result)
+}
+
+func TestRunTools_RepairsToolInput(t *testing.T) {
+	resp := toolCallResponse(
+		ToolCallPart{ID: "call_1", Name: "echo", Input: []byte(`{text: 'hi',}`)},
+		ToolCallPart{ID: "call_2", Name: "echo", Input: []byte(`{"text":"ok"}`)},
+	)
+	fake := newFakeLLM(
+		fakeResult{resp: resp},
+		fakeResult{resp: textResponse("done")},
+	)
+
+	result, err := RunTools(context.Background(), fake, nil, []Tool{echoTool{}}, RunToolsOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"call_1"}, result.RepairedToolCalls)
+
+	// The repaired input replaces the original in the history, but not in
+	// the provider's response.
+	assert.Equal(t, `{"text": "hi"}`, string(result.Messages[0].Parts[0].(ToolCallPart).Input))
+	assert.Equal(t, `{text: 'hi',}`, string(resp.Message.Parts[0].(ToolCallPart).Input))
+
+	part := result.Messages[1].Parts[0].(ToolResultPart)
+	assert.NoError(t, part.Error)
+	assert.Equal(t, "hi", part.Result)
+}
+
+// flakyTool fails with a transient error until it has been called failures
+// times.
+type flakyTool struct {
+	failures int
+	calls    atomic.Int32
+}
+
+func (*flakyTool) Name() string               { return "flaky" }
+func (*flakyTool) Description() string        { return "Fails at first" }
+func (*flakyTool) Schema() *jsonschema.Schema { return nil }
+
+func (f *flakyTool) Execute(ctx context.Context, args []byte) *ToolResult {
+	if int(f.calls.Add(1)) <= f.failures {
+		return &ToolResult{Error: errors.New("service unavailable"), Kind: ToolErrorTransient}
+	}
+	return &ToolResult{Content: "ok"}
+}
+
+func TestRunTools_TransientToolError(t *testing.T) {
+	call := ToolCallPart{ID: "call_1", Name: "flaky", Input: []byte(`{}`)}
+
+	t.Run("retried", func(t *testing.T) {
+		fake := newFakeLLM(fakeResult{resp: toolCallResponse(call)}, fakeResult{resp: textResponse("done")})
+		tool := &flakyTool{failures: 2}
+
+		result, err := RunTools(context.Background(), fake, nil, []Tool{tool}, RunToolsOptions{ToolRetries: 2})
+		require.NoError(t, err)
+		assert.Equal(t, int32(3), tool.calls.Load())
+
+		part := result.Messages[1].Parts[0].(ToolResultPart)
+		assert.NoError(t, part.Error)
+		assert.Equal(t, "ok", part.Result)
+	})
+
+	t.Run("retries exhausted", func(t *testing.T) {
+		fake := newFakeLLM(fakeResult{resp: toolCallResponse(call)}, fakeResult{resp: textResponse("done")})
+		tool := &flakyTool{failures: 5}
+
+		result, err := RunTools(context.Background(), fake, nil, []Tool{tool}, RunToolsOptions{ToolRetries: 1})
+		require.NoError(t, err)
+		assert.Equal(t, int32(2), tool.calls.Load())
+
+		part := result.Messages[1].Parts[0].(ToolResultPart)
+		assert.EqualError(t, part.Error, "service unavailable")
+	})
+
+	t.Run("not retried by default", func(t *testing.T) {
+		fake := newFakeLLM(fakeResult{resp: toolCallResponse(call)}, fakeResult{resp: textResponse("done")})
+		tool := &flakyTool{failures: 1}
+
+		_, err := RunTools(context.Background(), fake, nil, []Tool{tool}, RunToolsOptions{})
+		require.NoError(t, err)
+		assert.Equal(t, int32(1), tool.calls.Load())
+	})
+}
+
+func TestRunTools_FatalToolError(t *testing.T) {
+	errNoCredentials := errors.New("no credentials")
+	tool := NewTool("deploy", "Deploys", func(ctx context.Context, p struct{}) (string, error) {
+		return "", &ToolError{Kind: ToolErrorFatal, Err: errNoCredentials}
+	})
+	fake := newFakeLLM(
+		fakeResult{resp: toolCallResponse(
+			ToolCallPart{ID: "call_1", Name: "echo", Input: []byte(`{"text":"hi"}`)},
+			ToolCallPart{ID: "call_2", Name: "deploy", Input: []byte(`{}`)},
+		)},
+		fakeResult{resp: textResponse("done")},
+	)
+
+	result, err := RunTools(context.Background(), fake, nil, []Tool{echoTool{}, tool}, RunToolsOptions{})
+	assert.ErrorIs(t, err, ErrToolFatal)
+	assert.ErrorIs(t, err, errNoCredentials)
+	assert.Equal(t, 1, fake.Calls())
+	assert.Len(t, result.Messages, 1)
+}
+
+// optionsRecorder records the call options of every request.
+type optionsRecorder struct {
+	LLM
+	opts []CallOptions
+}
+
+func (r *optionsRecorder) Generate(ctx context.Context, messages []Message) (*Response, error) {
+	r.opts = append(r.opts, CallOptionsFromContext(ctx))
+	return r.LLM.Generate(ctx, messages)
+}
+
+func TestRunTools_ToolFilter(t *testing.T) {
+	fake := newFakeLLM(
+		fakeResult{resp: toolCallResponse(ToolCallPart{ID: "call_1", Name: "echo", Input: []byte(`{"text":"hi"}`)})},
+		fakeResult{resp: toolCallResponse(ToolCallPart{ID: "call_2", Name: "echo", Input: []byte(`{"text":"again"}`)})},
+		fakeResult{resp: textResponse("done")},
+	)
+	llm := &optionsRecorder{LLM: fake}
+
+	// Echo may only be called once.
+	filter := func(messages []Message, tools []Tool) []Tool {
+		for _, msg := range messages {
+			if len(ToolCalls(msg)) > 0 {
+				return []Tool{panicTool{}}
+			}
+		}
+		return tools
+	}
+
+	ctx := WithCallOptions(context.Background(), DisableTools("web_search"))
+	result, err := RunTools(ctx, llm, nil, []Tool{echoTool{}, panicTool{}}, RunToolsOptions{ToolFilter: filter})
+	require.NoError(t, err)
+
+	require.Len(t, llm.opts, 3)
+	assert.Equal(t, []string{"web_search"}, llm.opts[0].DisabledTools)
+	assert.Equal(t, []string{"echo", "web_search"}, llm.opts[1].DisabledTools)
+
+	first := result.Messages[1].Parts[0].(ToolResultPart)
+	assert.Equal(t, "hi", first.Result)
+
+	// The model calling a disabled tool anyway is told it is unknown.
+	second := result.Messages[3].Parts[0].(ToolResultPart)
+	assert.ErrorContains(t, second.Error, `unknown tool "echo"`)
+}
+
+func TestRunTools_MaxTurns(t *testing.T) {
+	fake := newFakeLLM(fakeResult{resp: toolCallResponse(ToolCallPart{ID: "call_1", Name: "echo", Input: []byte(`{"text":"again"}`)})})
+
+	result, err := RunTools(context.Background(), fake, nil, []Tool{echoTool{}}, RunToolsOptions{MaxTurns: 3})
+	assert.ErrorIs(t, err, ErrMaxTurnsExceeded)
+	assert.Equal(t, 3, result.Turns)
+	assert.Equal(t, 3, fake.Calls())
+}
+
+func TestRunTools_ToolTimeout(t *testing.T) {
+	fake := newFakeLLM(
+		fakeResult{resp: toolCallResponse(ToolCallPart{ID: "call_1", Name: "echo", Input: []byte(`{"text":"slow"}`)})},
+		fakeResult{resp: textResponse("done")},
+	)
+
+	result, err := RunTools(context.Background(), fake, nil, []Tool{echoTool{delay: time.Second}}, RunToolsOptions{ToolTimeout: 10 * time.Millisecond})
+	require.NoError(t, err)
+
+	part := result.Messages[1].Parts[0].(ToolResultPart)
+	assert.ErrorIs(t, part.Error, context.DeadlineExceeded)
+}
+
+// hangingTool ignores its context and blocks until release is closed.
+type hangingTool struct {
+	release chan struct{}
+}
+
+func (hangingTool) Name() string               { return "hang" }
+func (hangingTool) Description() string        { return "Never returns" }
+func (hangingTool) Schema() *jsonschema.Schema { return nil }
+
+func (h hangingTool) Execute(ctx context.Context, args []byte) *ToolResult {
+	<-h.release
+	return &ToolResult{Content: "too late"}
+}
+
+func TestRunTools_PerToolTimeout(t *testing.T) {
+	hang := hangingTool{release: make(chan struct{})}
+	t.Cleanup(func() { close(hang.release) })
+
+	fake := newFakeLLM(
+		fakeResult{resp: toolCallResponse(
+			ToolCallPart{ID: "call_1", Name: "hang", Input: []byte(`{}`)},
+			ToolCallPart{ID: "call_2", Name: "echo", Input: []byte(`{"text":"slow"}`)},
+		)},
+		fakeResult{resp: textResponse("done")},
+	)
+
+	result, err := RunTools(context.Background(), fake, nil, []Tool{hang, echoTool{delay: 50 * time.Millisecond}}, RunToolsOptions{
+		ToolTimeout:  10 * time.Millisecond,
+		ToolTimeouts: map[string]time.Duration{"hang": 20 * time.Millisecond, "echo": 0},
+	})
+	require.NoError(t, err)
+
+	parts := result.Messages[1].Parts
+	hung := parts[0].(ToolResultPart)
+	assert.ErrorIs(t, hung.Error, context.DeadlineExceeded)
+	assert.Equal(t, `tool "hang" timed out: context deadline exceeded`, hung.Result)
+
+	// The override disables the default timeout for echo.
+	echoed := parts[1].(ToolResultPart)
+	assert.NoError(t, echoed.Error)
+	assert.Equal(t, "slow", echoed.Result)
+}
+
+func TestRunTools_Stream(t *testing.T) {
+	fake := newFakeLLM(
+		fakeResult{
+			chunks: []*Response{textResponse("thinking")},
+			resp:   toolCallResponse(ToolCallPart{ID: "call_1", Name: "echo", Input: []byte(`{"text":"hi"}`)}),
+		},
+		fakeResult{chunks: []*Response{textResponse("done")}, resp: textResponse("done")},
+	)
+
+	streamed := 0
+	_, err := RunTools(context.Background(), fake, nil, []Tool{echoTool{}}, RunToolsOptions{
+		Stream: func(*Response, error) bool {
+			streamed++
+			return true
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 2, streamed)
+}
+
+// panicTool panics whenever it is executed.
+type panicTool struct{}
+
+func (panicTool) Name() string               { return "panic" }
+func (panicTool) Description() string        { return "Always panics" }
+func (panicTool) Schema() *jsonschema.Schema { return GenerateSchema[struct{}]() }
+
+func (panicTool) Execute(ctx context.Context, args []byte) *ToolResult {
+	panic("boom")
+}
+
+func TestRunTools_Parallel(t *testing.T) {
+	calls := []ToolCallPart{}
+	for _, text := range []string{"a", "b", "c", "d"} {
+		calls = append(calls, ToolCallPart{ID: "call_" + text, Name: "echo", Input: []byte(`{"text":"` + text + `"}`)})
+	}
+	calls = append(calls, ToolCallPart{ID: "call_panic", Name: "panic", Input: []byte(`{}`)})
+
+	fake := newFakeLLM(
+		fakeResult{resp: toolCallResponse(calls...)},
+		fakeResult{resp: textResponse("done")},
+	)
+
+	tools := []Tool{echoTool{delay: 50 * time.Millisecond}, panicTool{}}
+
+	start := time.Now()
+	result, err := RunTools(context.Background(), fake, nil, tools, RunToolsOptions{Concurrency: 5})
+	require.NoError(t, err)
+	assert.Less(t, time.Since(start), 150*time.Millisecond, "tools should run concurrently")
+
+	parts := result.Messages[1].Parts
+	require.Len(t, parts, 5)
+	for i, text := range []string{"a", "b", "c", "d"} {
+		part := parts[i].(ToolResultPart)
+		assert.Equal(t, "call_"+text, part.ToolCallID)
+		assert.Equal(t, text, part.Result)
+	}
+
+	panicked := parts[4].(ToolResultPart)
+	assert.ErrorContains(t, panicked.Error, "panicked")
+}
+
+// panicSchemaTool panics when its schema is asked for.
+type panicSchemaTool struct{ panicTool }
+
+func (panicSchemaTool) Name() string               { return "panic_schema" }
+func (panicSchemaTool) Schema() *jsonschema.Schema { panic("no schema") }
+
+func TestRunTools_ValidationPanic(t *testing.T) {
+	fake := newFakeLLM(
+		fakeResult{resp: toolCallResponse(ToolCallPart{ID: "call_1", Name: "panic_schema", Input: []byte(`{}`)})},
+		fakeResult{resp: textResponse("done")},
+	)
+
+	result, err := RunTools(context.Background(), fake, nil, []Tool{panicSchemaTool{}}, RunToolsOptions{})
+	require.NoError(t, err)
+	part := result.Messages[1].Parts[0].(ToolResultPart)
+	assert.EqualError(t, part.Error, `tool "panic_schema" panicked: no schema`)
+}
+
+func TestRunTools_Sequential(t *testing.T) {
+	fake := newFakeLLM(
+		fakeResult{resp: toolCallResponse(
+			ToolCallPart{ID: "call_1", Name: "echo", Input: []byte(`{"text":"a"}`)},
+			ToolCallPart{ID: "call_2", Name: "echo", Input: []byte(`{"text":"b"}`)},
+		)},
+		fakeResult{resp: textResponse("done")},
+	)
+
+	start := time.Now()
+	_, err := RunTools(context.Background(), fake, nil, []Tool{echoTool{delay: 30 * time.Millisecond}}, RunToolsOptions{Concurrency: 1})
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, time.Since(start), 60*time.Millisecond)
+}
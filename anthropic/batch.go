@@ -0,0 +1,150 @@
+package anthropic
+
+import (
+	"context"
+	"fmt"
+	"iter"
+	"net/http"
+	"slices"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/anthropics/anthropic-sdk-go/option"
+
+	"github.com/llmite-ai/llms"
+)
+
+var _ llms.BatchLLM = (*Client)(nil)
+
+// SubmitBatch submits requests to the Message Batches API. Each request is
+// built like a call to Generate, with the client's model, tools and settings
+// and the call options of ctx.
+func (a *Client) SubmitBatch(ctx context.Context, requests []llms.BatchRequest) (*llms.Batch, error) {
+	params := anthropic.MessageBatchNewParams{
+		Requests: make([]anthropic.MessageBatchNewParamsRequest, len(requests)),
+	}
+	var opts []option.RequestOption
+	for i, req := range requests {
+		body, reqOpts, err := a.BuildRequest(ctx, req.Messages)
+		if err != nil {
+			return nil, fmt.Errorf("anthropic: failed to build request %q: %w", req.ID, err)
+		}
+		params.Requests[i] = anthropic.MessageBatchNewParamsRequest{
+			CustomID: req.ID,
+			Params: anthropic.MessageBatchNewParamsRequestParams{
+				MaxTokens:     body.MaxTokens,
+				Messages:      body.Messages,
+				Model:         body.Model,
+				Temperature:   body.Temperature,
+				TopK:          body.TopK,
+				TopP:          body.TopP,
+				Metadata:      body.Metadata,
+				ServiceTier:   string(body.ServiceTier),
+				StopSequences: body.StopSequences,
+				System:        body.System,
+				Thinking:      body.Thinking,
+				ToolChoice:    body.ToolChoice,
+				Tools:         body.Tools,
+			},
+		}
+		opts = append(opts, reqOpts...)
+	}
+	// The requests enable their betas one by one, so the batch may repeat
+	// them.
+	opts = append(opts, option.WithMiddleware(dedupeBetas))
+
+	batch, err := a.client.Messages.Batches.New(ctx, params, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("anthropic: failed to submit batch: %w", wrapError(err))
+	}
+	return convertBatch(batch), nil
+}
+
+// dedupeBetas removes repeated anthropic-beta header values.
+func dedupeBetas(req *http.Request, next option.MiddlewareNext) (*http.Response, error) {
+	var betas []string
+	for _, beta := range req.Header.Values("anthropic-beta") {
+		if !slices.Contains(betas, beta) {
+			betas = append(betas, beta)
+		}
+	}
+	if betas != nil {
+		req.Header["Anthropic-Beta"] = betas
+	}
+	return next(req)
+}
+
+// GetBatch returns the current state of a batch.
+func (a *Client) GetBatch(ctx context.Context, id string) (*llms.Batch, error) {
+	batch, err := a.client.Messages.Batches.Get(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("anthropic: failed to get batch: %w", wrapError(err))
+	}
+	return convertBatch(batch), nil
+}
+
+// Results returns an iterator over the results of an ended batch, streamed
+// from the Message Batches API.
+func (a *Client) Results(ctx context.Context, id string) iter.Seq2[llms.BatchResult, error] {
+	return func(yield func(llms.BatchResult, error) bool) {
+		batch, err := a.GetBatch(ctx, id)
+		if err != nil {
+			yield(llms.BatchResult{}, err)
+			return
+		}
+		if !batch.Done() {
+			yield(llms.BatchResult{}, llms.ErrBatchInProgress)
+			return
+		}
+
+		stream := a.client.Messages.Batches.ResultsStreaming(ctx, id)
+		defer stream.Close()
+		for stream.Next() {
+			if !yield(convertBatchResult(stream.Current()), nil) {
+				return
+			}
+		}
+		if err := stream.Err(); err != nil {
+			yield(llms.BatchResult{}, fmt.Errorf("anthropic: failed to read batch results: %w", wrapError(err)))
+		}
+	}
+}
+
+func convertBatch(batch *anthropic.MessageBatch) *llms.Batch {
+	counts := batch.RequestCounts
+	out := &llms.Batch{
+		ID:        batch.ID,
+		Status:    llms.BatchInProgress,
+		Pending:   int(counts.Processing),
+		Succeeded: int(counts.Succeeded),
+		Failed:    int(counts.Errored + counts.Canceled + counts.Expired),
+		CreatedAt: batch.CreatedAt,
+		EndedAt:   batch.EndedAt,
+		ExpiresAt: batch.ExpiresAt,
+		Raw:       batch,
+	}
+	switch batch.ProcessingStatus {
+	case anthropic.MessageBatchProcessingStatusCanceling:
+		out.Status = llms.BatchCanceling
+	case anthropic.MessageBatchProcessingStatusEnded:
+		out.Status = llms.BatchEnded
+	}
+	return out
+}
+
+func convertBatchResult(result anthropic.MessageBatchIndividualResponse) llms.BatchResult {
+	out := llms.BatchResult{ID: result.CustomID}
+	switch result.Result.Type {
+	case "succeeded":
+		out.Response, out.Err = convertMessageToResponse(&result.Result.Message)
+	case "errored":
+		e := result.Result.Error.Error
+		out.Err = fmt.Errorf("anthropic: request failed: %s: %s", e.Type, e.Message)
+	case "canceled":
+		out.Err = llms.ErrBatchCanceled
+	case "expired":
+		out.Err = llms.ErrBatchExpired
+	default:
+		out.Err = fmt.Errorf("anthropic: unknown batch result type %q", result.Result.Type)
+	}
+	return out
+}
@@ -0,0 +1,125 @@
+package anthropic
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/llmite-ai/llms"
+)
+
+// messageBatch returns a Message Batches API batch with the given status.
+func messageBatch(status string, processing, succeeded, errored int) string {
+	return fmt.Sprintf(`{
+		"id": "msgbatch_1",
+		"type": "message_batch",
+		"processing_status": %q,
+		"request_counts": {"processing": %d, "succeeded": %d, "errored": %d, "canceled": 0, "expired": 1},
+		"created_at": "2025-01-01T00:00:00Z",
+		"expires_at": "2025-01-02T00:00:00Z",
+		"ended_at": null,
+		"archived_at": null,
+		"cancel_initiated_at": null,
+		"results_url": null
+	}`, status, processing, succeeded, errored)
+}
+
+func TestSubmitBatch(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/messages/batches", r.URL.Path)
+		// The files beta is enabled by two requests but sent once.
+		assert.Equal(t, []string{betaFilesAPI}, r.Header.Values("anthropic-beta"))
+
+		var body struct {
+			Requests []struct {
+				CustomID string          `json:"custom_id"`
+				Params   json.RawMessage `json:"params"`
+			} `json:"requests"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		require.Len(t, body.Requests, 3)
+		assert.Equal(t, "first", body.Requests[0].CustomID)
+		assert.JSONEq(t, `{
+			"model": "claude-test",
+			"max_tokens": 100,
+			"system": [{"type": "text", "text": "Be brief."}],
+			"messages": [{"role": "user", "content": [{"type": "text", "text": "one"}]}],
+			"tools": []
+		}`, string(body.Requests[0].Params))
+		assert.Equal(t, "third", body.Requests[2].CustomID)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(messageBatch("in_progress", 3, 0, 0)))
+	}, WithModel("claude-test"), WithMaxTokens(100))
+
+	file := []llms.Message{{Role: llms.RoleUser, Parts: []llms.Part{llms.DocumentPart{FileID: "file_1"}}}}
+	batch, err := client.SubmitBatch(context.Background(), []llms.BatchRequest{
+		{ID: "first", Messages: []llms.Message{
+			llms.NewTextMessage(llms.RoleSystem, "Be brief."),
+			llms.NewTextMessage(llms.RoleUser, "one"),
+		}},
+		{ID: "second", Messages: file},
+		{ID: "third", Messages: file},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "msgbatch_1", batch.ID)
+	assert.Equal(t, llms.BatchInProgress, batch.Status)
+	assert.Equal(t, 3, batch.Pending)
+	assert.Equal(t, time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC), batch.ExpiresAt)
+	assert.False(t, batch.Done())
+}
+
+func TestBatchResults(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/messages/batches/msgbatch_1":
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(messageBatch("ended", 0, 1, 1)))
+		case "/v1/messages/batches/msgbatch_1/results":
+			w.Header().Set("Content-Type", "application/x-jsonl")
+			fmt.Fprintln(w, `{"custom_id": "first", "result": {"type": "succeeded", "message": {"id": "msg_1", "type": "message", "role": "assistant", "model": "claude-test", "content": [{"type": "text", "text": "Hi!"}], "stop_reason": "end_turn", "usage": {"input_tokens": 5, "output_tokens": 2}}}}`)
+			fmt.Fprintln(w, `{"custom_id": "second", "result": {"type": "errored", "error": {"type": "error", "error": {"type": "invalid_request_error", "message": "bad request"}}}}`)
+			fmt.Fprintln(w, `{"custom_id": "third", "result": {"type": "expired"}}`)
+		default:
+			t.Errorf("unexpected request to %s", r.URL.Path)
+		}
+	})
+
+	batch, err := client.GetBatch(context.Background(), "msgbatch_1")
+	require.NoError(t, err)
+	assert.True(t, batch.Done())
+	assert.Equal(t, 1, batch.Succeeded)
+	assert.Equal(t, 2, batch.Failed)
+
+	var results []llms.BatchResult
+	for result, err := range client.Results(context.Background(), "msgbatch_1") {
+		require.NoError(t, err)
+		results = append(results, result)
+	}
+	require.Len(t, results, 3)
+	assert.Equal(t, "first", results[0].ID)
+	require.NoError(t, results[0].Err)
+	assert.Equal(t, "Hi!", results[0].Response.Message.Parts[0].(llms.TextPart).Text)
+	assert.Equal(t, 5, results[0].Response.Usage.InputTokens)
+	assert.Equal(t, "second", results[1].ID)
+	assert.EqualError(t, results[1].Err, "anthropic: request failed: invalid_request_error: bad request")
+	assert.ErrorIs(t, results[2].Err, llms.ErrBatchExpired)
+}
+
+func TestBatchResults_InProgress(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/messages/batches/msgbatch_1", r.URL.Path)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(messageBatch("in_progress", 1, 0, 0)))
+	})
+
+	for _, err := range client.Results(context.Background(), "msgbatch_1") {
+		assert.ErrorIs(t, err, llms.ErrBatchInProgress)
+	}
+}
@@ -2,13 +2,18 @@ package anthropic
 
 import (
 	"context"
+	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"net/http"
+	"strings"
+	"time"
 
 	"github.com/anthropics/anthropic-sdk-go"
 	"github.com/anthropics/anthropic-sdk-go/option"
 	"github.com/anthropics/anthropic-sdk-go/packages/param"
+	"github.com/invopop/jsonschema"
 
 	"github.com/llmite-ai/llms"
 )
@@ -22,11 +27,44 @@ type Client struct {
 	TopP        *float64
 	TopK        *int64
 	Tools       []llms.Tool
+	Registry    *llms.ToolRegistry
+	CacheTools  bool
+	// ToolCacheTTL is how long cached tool definitions are kept. Zero uses
+	// Anthropic's default of 5 minutes.
+	ToolCacheTTL time.Duration
+	// StopSequences are custom sequences that stop generation when the
+	// model produces them.
+	StopSequences []string
+	// OnStreamEvent, if set, receives every raw event of a streaming
+	// response before it is accumulated into the llms.Response.
+	OnStreamEvent func(event anthropic.MessageStreamEventUnion)
+	// ToolChoice, if set, controls whether and which tools the model calls.
+	// It can be overridden per call with llms.ToolChoiceOption.
+	ToolChoice *ToolChoice
+	// DisableParallelToolUse limits the model to at most one tool call per
+	// response. It can be overridden per call with llms.ParallelToolCalls.
+	DisableParallelToolUse bool
+	// ThinkingBudget, if positive, enables extended thinking with up to this
+	// many tokens of reasoning.
+	ThinkingBudget int64
+	// InterleavedThinking lets the model think between tool calls.
+	InterleavedThinking bool
 
 	client  *anthropic.Client
 	options []option.RequestOption
 }
 
+// Tool choice types accepted by WithToolChoice.
+const (
+	ToolChoiceAuto = llms.ToolChoiceAuto
+	ToolChoiceAny  = llms.ToolChoiceAny
+	ToolChoiceTool = llms.ToolChoiceTool
+	ToolChoiceNone = llms.ToolChoiceNone
+)
+
+// ToolChoice controls whether and which tools the model calls.
+type ToolChoice = llms.ToolChoice
+
 type Modifer func(*Client)
 
 // WithOptions allows you to set options on the client. This is useful for setting
@@ -38,6 +76,14 @@ func WithAnthropicClientOptions(options ...option.RequestOption) Modifer {
 	}
 }
 
+// WithHTTPClient sets the HTTP client requests are sent with, such as one
+// created by llms.NewHTTPClient.
+func WithHTTPClient(client *http.Client) Modifer {
+	return func(a *Client) {
+		a.options = append(a.options, option.WithHTTPClient(client))
+	}
+}
+
 // WithHttpLogging will log all HTTP requests and responses to the default structured
 // logger.
 func WithHttpLogging() Modifer {
@@ -61,6 +107,27 @@ func WithMaxTokens(maxTokens int64) Modifer {
 	}
 }
 
+// WithTemperature allows you to set the temperature on the client.
+func WithTemperature(temperature float64) Modifer {
+	return func(a *Client) {
+		a.Temperature = &temperature
+	}
+}
+
+// WithTopP allows you to set the top_p on the client.
+func WithTopP(topP float64) Modifer {
+	return func(a *Client) {
+		a.TopP = &topP
+	}
+}
+
+// WithTopK allows you to set the top_k on the client.
+func WithTopK(topK int64) Modifer {
+	return func(a *Client) {
+		a.TopK = &topK
+	}
+}
+
 // With Tools allows you to set the tools on the client.
 func WithTools(tools []llms.Tool) Modifer {
 	return func(a *Client) {
@@ -68,6 +135,89 @@ func WithTools(tools []llms.Tool) Modifer {
 	}
 }
 
+// WithStopSequences sets custom text sequences that stop generation. The
+// matched sequence is reported in Response.StopSequence.
+func WithStopSequences(sequences ...string) Modifer {
+	return func(a *Client) {
+		a.StopSequences = sequences
+	}
+}
+
+// WithToolChoice controls whether and which tools the model calls. choice is
+// one of ToolChoiceAuto, ToolChoiceAny, ToolChoiceTool or ToolChoiceNone; name
+// is the tool to force when choice is ToolChoiceTool and is otherwise ignored.
+func WithToolChoice(choice, name string) Modifer {
+	return func(a *Client) {
+		a.ToolChoice = &ToolChoice{Type: choice, Name: name}
+	}
+}
+
+// WithDisableParallelToolUse limits the model to at most one tool call per
+// response, for agents that must process tool calls one at a time. Use
+// llms.WithCallOptions with llms.ParallelToolCalls to change this per call.
+func WithDisableParallelToolUse() Modifer {
+	return func(a *Client) {
+		a.DisableParallelToolUse = true
+	}
+}
+
+// WithThinking enables extended thinking, allowing the model to spend up to
+// budgetTokens tokens reasoning before it answers. The budget must be at least
+// 1024 and, unless interleaved thinking is enabled, less than the max tokens.
+// The reasoning is returned as llms.ThinkingPart.
+func WithThinking(budgetTokens int64) Modifer {
+	return func(a *Client) {
+		a.ThinkingBudget = budgetTokens
+	}
+}
+
+// WithInterleavedThinking lets the model think between tool calls as well as
+// before its first response. It requires WithThinking.
+func WithInterleavedThinking() Modifer {
+	return func(a *Client) {
+		a.InterleavedThinking = true
+	}
+}
+
+// WithStreamEventHandler registers fn to receive the raw SDK events of every
+// streaming response (message_start, content_block_delta, and so on) in
+// addition to the accumulated responses passed to the StreamFunc. This is
+// useful when finer control is needed than llms.Response offers, such as
+// rendering thinking signatures. Ping events are consumed by the SDK and are
+// not delivered.
+func WithStreamEventHandler(fn func(event anthropic.MessageStreamEventUnion)) Modifer {
+	return func(a *Client) {
+		a.OnStreamEvent = fn
+	}
+}
+
+// WithToolCaching marks the tool definitions as cacheable, so they are served
+// from Anthropic's prompt cache on subsequent requests. Use llms.CachePointPart
+// to cache the system prompt and messages as well.
+func WithToolCaching() Modifer {
+	return func(a *Client) {
+		a.CacheTools = true
+	}
+}
+
+// WithToolCacheTTL marks the tool definitions as cacheable, like
+// WithToolCaching, and keeps them cached for ttl, which must be 5 minutes or
+// 1 hour.
+func WithToolCacheTTL(ttl time.Duration) Modifer {
+	return func(a *Client) {
+		a.CacheTools = true
+		a.ToolCacheTTL = ttl
+	}
+}
+
+// WithToolRegistry makes the tools in registry available to the model. The
+// registry is read on every request, so tools registered later are included.
+func WithToolRegistry(registry *llms.ToolRegistry) Modifer {
+	return func(a *Client) {
+		a.Registry = registry
+	}
+}
+
 // New creates a new Anthropic client with the packages default options.
 // This includes reading the ANTHROPIC_API_KEY, ANTHROPIC_AUTH_TOKEN, and
 // ANTHROPIC_BASE_URL environment variables.
@@ -90,22 +240,56 @@ func New(mods ...Modifer) llms.LLM {
 	return c
 }
 
+// ModelName returns the model requests are sent to.
+func (a *Client) ModelName() string {
+	return a.Model
+}
+
 // GetClient returns the underlying anthropic client.
 func (a *Client) GetClient() *anthropic.Client {
 	return a.client
 }
 
+// tools returns the client's tools followed by those in its registry, less
+// those disabled by the call options in ctx.
+func (a *Client) tools(ctx context.Context) []llms.Tool {
+	tools := a.Tools
+	if a.Registry != nil {
+		tools = append(append([]llms.Tool(nil), tools...), a.Registry.List()...)
+	}
+	return llms.CallOptionsFromContext(ctx).FilterTools(tools)
+}
+
 func (a *Client) BuildRequest(ctx context.Context, messages []llms.Message) (*anthropic.MessageNewParams, []option.RequestOption, error) {
 	system, anthMessages, err := convertMessages(messages)
 	if err != nil {
 		return nil, nil, err
 	}
 
-	tools, opts, err := convertTools(a.Tools)
+	tools, opts, err := convertTools(a.tools(ctx))
 	if err != nil {
 		return nil, nil, err
 	}
 
+	if usesFiles(messages) {
+		opts = append(opts, option.WithHeaderAdd("anthropic-beta", betaFilesAPI))
+	}
+	if a.InterleavedThinking {
+		opts = append(opts, option.WithHeaderAdd("anthropic-beta", betaInterleavedThinking))
+	}
+
+	if a.CacheTools && len(tools) > 0 {
+		if cc := tools[len(tools)-1].GetCacheControl(); cc != nil {
+			*cc, err = cacheControl(a.ToolCacheTTL)
+			if err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+	if usesExtendedCacheTTL(messages) || (a.CacheTools && a.ToolCacheTTL == time.Hour) {
+		opts = append(opts, option.WithHeaderAdd("anthropic-beta", betaExtendedCacheTTL))
+	}
+
 	body := anthropic.MessageNewParams{
 		MaxTokens: a.MaxTokens,
 		Model:     anthropic.Model(a.Model),
@@ -117,11 +301,52 @@ func (a *Client) BuildRequest(ctx context.Context, messages []llms.Message) (*an
 	if a.Temperature != nil {
 		body.Temperature = param.NewOpt(*a.Temperature)
 	}
+	if a.TopP != nil {
+		body.TopP = param.NewOpt(*a.TopP)
+	}
+	if a.TopK != nil {
+		body.TopK = param.NewOpt(*a.TopK)
+	}
+
+	callOpts := llms.CallOptionsFromContext(ctx)
+
+	stop := a.StopSequences
+	if callOpts.StopSequences != nil {
+		stop = callOpts.StopSequences
+	}
+	if len(stop) > 0 {
+		body.StopSequences = stop
+	}
+
+	if a.ThinkingBudget > 0 {
+		body.Thinking = anthropic.ThinkingConfigParamOfEnabled(a.ThinkingBudget)
+	}
+
+	toolChoice := a.ToolChoice
+	if callOpts.ToolChoice != nil {
+		toolChoice = callOpts.ToolChoice
+	}
+	if toolChoice != nil {
+		body.ToolChoice, err = convertToolChoice(*toolChoice)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	disableParallel := a.DisableParallelToolUse
+	if parallel := callOpts.ParallelToolCalls; parallel != nil {
+		disableParallel = !*parallel
+	}
+	// Anthropic only accepts tool_choice when tools are provided.
+	if disableParallel && len(tools) > 0 {
+		disableParallelToolUse(&body.ToolChoice)
+	}
 
 	return &body, opts, nil
 }
 
 func (a *Client) Generate(ctx context.Context, messages []llms.Message) (*llms.Response, error) {
+	ctx, requestID := llms.EnsureRequestID(ctx)
 	body, opts, err := a.BuildRequest(ctx, messages)
 	if err != nil {
 		return nil, fmt.Errorf("anthropic: failed to build request: %w", err)
@@ -133,13 +358,18 @@ func (a *Client) Generate(ctx context.Context, messages []llms.Message) (*llms.R
 		opts...,
 	)
 	if err != nil {
-		return nil, fmt.Errorf("anthropic: failed to generate message: %w", err)
+		return nil, fmt.Errorf("anthropic: failed to generate message: %w", wrapError(err))
 	}
 
-	return convertMessageToResponse(msg)
+	resp, err := convertMessageToResponse(msg)
+	if resp != nil {
+		resp.RequestID = requestID
+	}
+	return resp, err
 }
 
 func (a *Client) GenerateStream(ctx context.Context, messages []llms.Message, fn llms.StreamFunc) (*llms.Response, error) {
+	ctx, requestID := llms.EnsureRequestID(ctx)
 	body, opts, err := a.BuildRequest(ctx, messages)
 	if err != nil {
 		return nil, fmt.Errorf("anthropic: failed to build request: %w", err)
@@ -157,6 +387,10 @@ func (a *Client) GenerateStream(ctx context.Context, messages []llms.Message, fn
 	message := &anthropic.Message{}
 	for stream.Next() {
 		event := stream.Current()
+		if a.OnStreamEvent != nil {
+			a.OnStreamEvent(event)
+		}
+
 		err := message.Accumulate(event)
 		if err != nil {
 			if !fn(nil, fmt.Errorf("anthropic: failed to accumulate message: %w", err)) {
@@ -164,6 +398,7 @@ func (a *Client) GenerateStream(ctx context.Context, messages []llms.Message, fn
 			}
 			continue
 		}
+		accumulateUsage(message, event)
 
 		response, err := convertMessageToResponse(message)
 		if err != nil {
@@ -172,6 +407,7 @@ func (a *Client) GenerateStream(ctx context.Context, messages []llms.Message, fn
 			}
 			continue
 		}
+		response.RequestID = requestID
 
 		if !fn(response, nil) {
 			return response, nil
@@ -179,10 +415,84 @@ func (a *Client) GenerateStream(ctx context.Context, messages []llms.Message, fn
 	}
 
 	if stream.Err() != nil {
-		return nil, fmt.Errorf("anthropic: streaming request failed: %w", stream.Err())
+		return nil, fmt.Errorf("anthropic: streaming request failed: %w", wrapError(stream.Err()))
 	}
 
-	return convertMessageToResponse(message)
+	response, err := convertMessageToResponse(message)
+	if response != nil {
+		response.RequestID = requestID
+	}
+	return response, err
+}
+
+// CountTokens returns the number of input tokens messages would use, including
+// the system prompt and tools, via Anthropic's count_tokens endpoint.
+func (a *Client) CountTokens(ctx context.Context, messages []llms.Message) (int, error) {
+	body, opts, err := a.BuildRequest(ctx, messages)
+	if err != nil {
+		return 0, fmt.Errorf("anthropic: failed to build request: %w", err)
+	}
+
+	params := anthropic.MessageCountTokensParams{
+		Model:      body.Model,
+		Messages:   body.Messages,
+		Thinking:   body.Thinking,
+		ToolChoice: body.ToolChoice,
+		Tools:      countTokensTools(body.Tools),
+	}
+
+	if len(body.System) > 0 {
+		params.System = anthropic.MessageCountTokensParamsSystemUnion{
+			OfTextBlockArray: body.System,
+		}
+	}
+
+	count, err := a.client.Messages.CountTokens(ctx, params, opts...)
+	if err != nil {
+		return 0, fmt.Errorf("anthropic: failed to count tokens: %w", wrapError(err))
+	}
+
+	return int(count.InputTokens), nil
+}
+
+// countTokensTools converts the tools of a request built by BuildRequest to
+// those of a token counting request, so the tokens of every tool Generate
+// sends are counted.
+func countTokensTools(tools []anthropic.ToolUnionParam) []anthropic.MessageCountTokensToolUnionParam {
+	out := make([]anthropic.MessageCountTokensToolUnionParam, 0, len(tools))
+	for _, tool := range tools {
+		t := anthropic.MessageCountTokensToolUnionParam{
+			OfTool:                  tool.OfTool,
+			OfBashTool20250124:      tool.OfBashTool20250124,
+			OfTextEditor20250124:    tool.OfTextEditor20250124,
+			OfWebSearchTool20250305: tool.OfWebSearchTool20250305,
+		}
+		if editor := tool.OfTextEditor20250429; editor != nil {
+			t.OfTextEditor20250429 = &anthropic.MessageCountTokensToolTextEditor20250429Param{
+				CacheControl: editor.CacheControl,
+			}
+		}
+		out = append(out, t)
+	}
+	return out
+}
+
+// wrapError converts errors returned by the anthropic SDK into *llms.APIError so
+// callers can inspect the status code without depending on the SDK.
+func wrapError(err error) error {
+	var apiErr *anthropic.Error
+	if errors.As(err, &apiErr) {
+		out := &llms.APIError{
+			Provider:   ProviderAnthropic,
+			StatusCode: apiErr.StatusCode,
+			Err:        err,
+		}
+		if apiErr.Response != nil {
+			out.RetryAfter = llms.ParseRetryAfter(apiErr.Response.Header)
+		}
+		return out
+	}
+	return err
 }
 
 func convertMessageToResponse(msg *anthropic.Message) (*llms.Response, error) {
@@ -199,9 +509,19 @@ func convertMessageToResponse(msg *anthropic.Message) (*llms.Response, error) {
 			msgOut.Parts = append(msgOut.Parts, llms.TextPart{
 				Text: block.Text,
 			})
+		case "thinking":
+			msgOut.Parts = append(msgOut.Parts, llms.ThinkingPart{
+				Text:      block.Thinking,
+				Signature: block.Signature,
+			})
+		case "redacted_thinking":
+			msgOut.Parts = append(msgOut.Parts, llms.ThinkingPart{
+				Text:     block.Data,
+				Redacted: true,
+			})
 		case "tool_use":
 			msgOut.Parts = append(msgOut.Parts, llms.ToolCallPart{
-				ID:    block.ToolUseID,
+				ID:    block.ID,
 				Name:  block.Name,
 				Input: block.Input,
 			})
@@ -226,16 +546,26 @@ func convertMessageToResponse(msg *anthropic.Message) (*llms.Response, error) {
 				Name:  block.Name,
 				Input: block.Input,
 			})
+		case "web_search_tool_result":
+			part, err := convertWebSearchToolResult(block.ToolUseID, block.JSON.Content.Raw())
+			if err != nil {
+				errs = append(errs, fmt.Errorf("anthropic: failed to unmarshal web search result at index %d: %w", i, err))
+				continue
+			}
+			msgOut.Parts = append(msgOut.Parts, part)
 		default:
 			errs = append(errs, fmt.Errorf("anthropic: unsupported content block type at index %d: %#v", i, block))
 		}
 	}
 
 	out := &llms.Response{
-		ID:       msg.ID,
-		Message:  msgOut,
-		Provider: ProviderAnthropic,
-		Raw:      msg,
+		ID:           msg.ID,
+		Message:      msgOut,
+		Usage:        convertUsage(msg.Usage),
+		StopReason:   llms.StopReason(msg.StopReason),
+		StopSequence: msg.StopSequence,
+		Provider:     ProviderAnthropic,
+		Raw:          msg,
 	}
 
 	if len(errs) > 0 {
@@ -245,6 +575,76 @@ func convertMessageToResponse(msg *anthropic.Message) (*llms.Response, error) {
 	return out, nil
 }
 
+// convertWebSearchToolResult parses the content of a web_search_tool_result
+// block, which is either a list of results or an error object.
+func convertWebSearchToolResult(toolUseID, content string) (WebSearchToolResult, error) {
+	out := WebSearchToolResult{ToolUseID: toolUseID}
+
+	if strings.HasPrefix(strings.TrimSpace(content), "[") {
+		err := json.Unmarshal([]byte(content), &out.Results)
+		return out, err
+	}
+
+	var resultErr struct {
+		ErrorCode string `json:"error_code"`
+	}
+	if err := json.Unmarshal([]byte(content), &resultErr); err != nil {
+		return out, err
+	}
+	out.ErrorCode = resultErr.ErrorCode
+
+	return out, nil
+}
+
+// accumulateUsage applies the usage reported by a message_delta event to msg.
+// The SDK only accumulates output tokens, but the delta's counts are
+// cumulative and may also update the input and cache counts.
+func accumulateUsage(msg *anthropic.Message, event anthropic.MessageStreamEventUnion) {
+	if event.Type != "message_delta" {
+		return
+	}
+
+	usage := event.Usage
+	if usage.JSON.InputTokens.Valid() && usage.InputTokens > 0 {
+		msg.Usage.InputTokens = usage.InputTokens
+	}
+	if usage.JSON.CacheCreationInputTokens.Valid() && usage.CacheCreationInputTokens > 0 {
+		msg.Usage.CacheCreationInputTokens = usage.CacheCreationInputTokens
+	}
+	if usage.JSON.CacheReadInputTokens.Valid() && usage.CacheReadInputTokens > 0 {
+		msg.Usage.CacheReadInputTokens = usage.CacheReadInputTokens
+	}
+	if usage.ServerToolUse.WebSearchRequests > 0 {
+		msg.Usage.ServerToolUse.WebSearchRequests = usage.ServerToolUse.WebSearchRequests
+	}
+}
+
+// convertUsage converts Anthropic usage, whose input tokens exclude cached
+// tokens, to llms.Usage, whose input tokens include them.
+func convertUsage(usage anthropic.Usage) *llms.Usage {
+	out := &llms.Usage{
+		InputTokens:              int(usage.InputTokens + usage.CacheCreationInputTokens + usage.CacheReadInputTokens),
+		OutputTokens:             int(usage.OutputTokens),
+		CacheCreationInputTokens: int(usage.CacheCreationInputTokens),
+		CacheReadInputTokens:     int(usage.CacheReadInputTokens),
+		WebSearchRequests:        int(usage.ServerToolUse.WebSearchRequests),
+	}
+
+	// The SDK does not know about the breakdown by TTL yet.
+	if field, ok := usage.JSON.ExtraFields["cache_creation"]; ok {
+		var cacheCreation struct {
+			Ephemeral5mInputTokens int `json:"ephemeral_5m_input_tokens"`
+			Ephemeral1hInputTokens int `json:"ephemeral_1h_input_tokens"`
+		}
+		if json.Unmarshal([]byte(field.Raw()), &cacheCreation) == nil {
+			out.CacheCreation5mInputTokens = cacheCreation.Ephemeral5mInputTokens
+			out.CacheCreation1hInputTokens = cacheCreation.Ephemeral1hInputTokens
+		}
+	}
+
+	return out
+}
+
 func convertMessages(messages []llms.Message) ([]anthropic.TextBlockParam, []anthropic.MessageParam, error) {
 	system := []anthropic.TextBlockParam{}
 	out := make([]anthropic.MessageParam, 0, len(messages))
@@ -260,6 +660,15 @@ func convertMessages(messages []llms.Message) ([]anthropic.TextBlockParam, []ant
 					system = append(system, anthropic.TextBlockParam{
 						Text: p.Text,
 					})
+				case llms.CachePointPart:
+					if len(system) == 0 {
+						return system, nil, fmt.Errorf("[message %d] anthropic: cache point must follow a content block", i)
+					}
+					cc, err := cacheControl(p.TTL)
+					if err != nil {
+						return system, nil, fmt.Errorf("[message %d] %w", i, err)
+					}
+					system[len(system)-1].CacheControl = cc
 				default:
 					return system, nil, fmt.Errorf("[message %d] anthropic: unsupported message part type: %T", i, p)
 				}
@@ -289,12 +698,18 @@ func convertMessages(messages []llms.Message) ([]anthropic.TextBlockParam, []ant
 						Text: p.Text,
 					},
 				})
+			case llms.ThinkingPart:
+				if p.Redacted {
+					anthMessage.Content = append(anthMessage.Content, anthropic.NewRedactedThinkingBlock(p.Text))
+				} else {
+					anthMessage.Content = append(anthMessage.Content, anthropic.NewThinkingBlock(p.Signature, p.Text))
+				}
 			case llms.ToolCallPart:
 				anthMessage.Content = append(anthMessage.Content, anthropic.ContentBlockParamUnion{
 					OfToolUse: &anthropic.ToolUseBlockParam{
 						ID:    p.ID,
 						Name:  p.Name,
-						Input: p.Input,
+						Input: toolInput(p.Input),
 					},
 				})
 			case llms.ToolResultPart:
@@ -316,6 +731,41 @@ func convertMessages(messages []llms.Message) ([]anthropic.TextBlockParam, []ant
 				}
 
 				anthMessage.Content = append(anthMessage.Content, c)
+			case ServerToolUsePart:
+				block := &anthropic.ServerToolUseBlockParam{
+					ID:    p.ID,
+					Input: p.Input,
+				}
+				if p.Name != "web_search" {
+					// The SDK's param type only knows about web search.
+					block.SetExtraFields(map[string]any{"name": p.Name})
+				}
+				anthMessage.Content = append(anthMessage.Content, anthropic.ContentBlockParamUnion{OfServerToolUse: block})
+			case CodeExecutionToolResult:
+				anthMessage.Content = append(anthMessage.Content, convertCodeExecutionToolResultParam(p))
+			case WebSearchToolResult:
+				anthMessage.Content = append(anthMessage.Content, anthropic.ContentBlockParamUnion{
+					OfWebSearchToolResult: convertWebSearchToolResultParam(p),
+				})
+			case llms.DocumentPart:
+				doc, err := convertDocument(p)
+				if err != nil {
+					return system, nil, fmt.Errorf("[message %d, part %d] anthropic: %w", i, j, err)
+				}
+				anthMessage.Content = append(anthMessage.Content, anthropic.ContentBlockParamUnion{OfDocument: doc})
+			case llms.CachePointPart:
+				// Anthropic marks the end of a cacheable prefix on the block
+				// itself rather than with a separate block.
+				if len(anthMessage.Content) == 0 {
+					return system, nil, fmt.Errorf("[message %d, part %d] anthropic: cache point must follow a content block", i, j)
+				}
+				if cc := anthMessage.Content[len(anthMessage.Content)-1].GetCacheControl(); cc != nil {
+					control, err := cacheControl(p.TTL)
+					if err != nil {
+						return system, nil, fmt.Errorf("[message %d, part %d] %w", i, j, err)
+					}
+					*cc = control
+				}
 			default:
 				return system, nil, fmt.Errorf("[message %d, part %d] anthropic: unsupported message part type: %T", i, j, p)
 			}
@@ -327,6 +777,214 @@ func convertMessages(messages []llms.Message) ([]anthropic.TextBlockParam, []ant
 	return system, out, nil
 }
 
+// toolInput returns the raw JSON input of a tool call, which would otherwise be
+// encoded as a base64 string.
+func toolInput(input []byte) json.RawMessage {
+	if len(input) == 0 {
+		return json.RawMessage("{}")
+	}
+	return json.RawMessage(input)
+}
+
+func convertToolChoice(choice ToolChoice) (anthropic.ToolChoiceUnionParam, error) {
+	switch choice.Type {
+	case ToolChoiceAuto:
+		return anthropic.ToolChoiceUnionParam{OfAuto: &anthropic.ToolChoiceAutoParam{}}, nil
+	case ToolChoiceAny:
+		return anthropic.ToolChoiceUnionParam{OfAny: &anthropic.ToolChoiceAnyParam{}}, nil
+	case ToolChoiceTool:
+		if choice.Name == "" {
+			return anthropic.ToolChoiceUnionParam{}, fmt.Errorf("anthropic: tool choice %q requires a tool name", choice.Type)
+		}
+		return anthropic.ToolChoiceUnionParam{OfTool: &anthropic.ToolChoiceToolParam{Name: choice.Name}}, nil
+	case ToolChoiceNone:
+		return anthropic.ToolChoiceUnionParam{OfNone: &anthropic.ToolChoiceNoneParam{}}, nil
+	default:
+		return anthropic.ToolChoiceUnionParam{}, fmt.Errorf("anthropic: unsupported tool choice: %q", choice.Type)
+	}
+}
+
+// disableParallelToolUse sets disable_parallel_tool_use on choice, defaulting
+// it to auto. A choice of none has no such flag and is left unchanged.
+func disableParallelToolUse(choice *anthropic.ToolChoiceUnionParam) {
+	switch {
+	case choice.OfAny != nil:
+		choice.OfAny.DisableParallelToolUse = param.NewOpt(true)
+	case choice.OfTool != nil:
+		choice.OfTool.DisableParallelToolUse = param.NewOpt(true)
+	case choice.OfNone != nil:
+	case choice.OfAuto != nil:
+		choice.OfAuto.DisableParallelToolUse = param.NewOpt(true)
+	default:
+		choice.OfAuto = &anthropic.ToolChoiceAutoParam{DisableParallelToolUse: param.NewOpt(true)}
+	}
+}
+
+// betaFilesAPI is the beta that allows referencing uploaded files by ID.
+const betaFilesAPI = "files-api-2025-04-14"
+
+// betaExtendedCacheTTL is the beta that allows caching for an hour.
+const betaExtendedCacheTTL = "extended-cache-ttl-2025-04-11"
+
+// cacheControl returns the cache_control for a cache entry kept for ttl. The
+// SDK's param has no TTL field, so it is set as an extra field.
+func cacheControl(ttl time.Duration) (anthropic.CacheControlEphemeralParam, error) {
+	cc := anthropic.NewCacheControlEphemeralParam()
+	switch ttl {
+	case 0:
+	case 5 * time.Minute:
+		cc.SetExtraFields(map[string]any{"ttl": "5m"})
+	case time.Hour:
+		cc.SetExtraFields(map[string]any{"ttl": "1h"})
+	default:
+		return cc, fmt.Errorf("anthropic: unsupported cache TTL %s, must be 5m or 1h", ttl)
+	}
+	return cc, nil
+}
+
+// usesExtendedCacheTTL reports whether any cache point in messages asks for
+// the one hour TTL.
+func usesExtendedCacheTTL(messages []llms.Message) bool {
+	for _, message := range messages {
+		for _, part := range message.Parts {
+			if p, ok := part.(llms.CachePointPart); ok && p.TTL == time.Hour {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// betaInterleavedThinking is the beta that allows thinking between tool calls.
+const betaInterleavedThinking = "interleaved-thinking-2025-05-14"
+
+// convertDocument converts a document part to a document block. PDFs are sent
+// as base64 and text documents as plain text.
+func convertDocument(p llms.DocumentPart) (*anthropic.DocumentBlockParam, error) {
+	doc := &anthropic.DocumentBlockParam{}
+	if p.Title != "" {
+		doc.Title = param.NewOpt(p.Title)
+	}
+	if p.Context != "" {
+		doc.Context = param.NewOpt(p.Context)
+	}
+
+	mediaType := p.MediaType
+	if mediaType == "" {
+		mediaType = "application/pdf"
+	}
+
+	switch {
+	case p.FileID != "":
+		// The SDK only models file sources in its beta types.
+		doc.Source = param.Override[anthropic.DocumentBlockParamSourceUnion](map[string]any{
+			"type":    "file",
+			"file_id": p.FileID,
+		})
+	case p.URL != "":
+		doc.Source.OfURL = &anthropic.URLPDFSourceParam{URL: p.URL}
+	case len(p.Data) > 0 && mediaType == "application/pdf":
+		doc.Source.OfBase64 = &anthropic.Base64PDFSourceParam{Data: base64.StdEncoding.EncodeToString(p.Data)}
+	case len(p.Data) > 0 && mediaType == "text/plain":
+		doc.Source.OfText = &anthropic.PlainTextSourceParam{Data: string(p.Data)}
+	case len(p.Data) > 0:
+		return nil, fmt.Errorf("unsupported document media type: %s", mediaType)
+	default:
+		return nil, fmt.Errorf("document has no data, URL or file ID")
+	}
+
+	return doc, nil
+}
+
+// convertWebSearchToolResultParam converts a web search result from a previous
+// response back to a content block.
+func convertWebSearchToolResultParam(p WebSearchToolResult) *anthropic.WebSearchToolResultBlockParam {
+	block := &anthropic.WebSearchToolResultBlockParam{ToolUseID: p.ToolUseID}
+
+	if p.ErrorCode != "" {
+		block.Content.OfRequestWebSearchToolResultError = &anthropic.WebSearchToolRequestErrorParam{
+			ErrorCode: anthropic.WebSearchToolRequestErrorErrorCode(p.ErrorCode),
+		}
+		return block
+	}
+
+	block.Content.OfWebSearchToolResultBlockItem = make([]anthropic.WebSearchResultBlockParam, 0, len(p.Results))
+	for _, r := range p.Results {
+		result := anthropic.WebSearchResultBlockParam{
+			URL:              r.URL,
+			Title:            r.Title,
+			EncryptedContent: r.EncryptedContent,
+		}
+		if r.PageAge != "" {
+			result.PageAge = param.NewOpt(r.PageAge)
+		}
+		block.Content.OfWebSearchToolResultBlockItem = append(block.Content.OfWebSearchToolResultBlockItem, result)
+	}
+
+	return block
+}
+
+// convertCodeExecutionToolResultParam converts a code execution result from a
+// previous response back to a content block. The SDK has no param type for it
+// outside of its beta package, so the block is built from raw JSON.
+func convertCodeExecutionToolResultParam(p CodeExecutionToolResult) anthropic.ContentBlockParamUnion {
+	var content map[string]any
+	if p.Content.ErrorCode != "" {
+		content = map[string]any{
+			"type":       "code_execution_tool_result_error",
+			"error_code": p.Content.ErrorCode,
+		}
+	} else {
+		files := make([]map[string]any, 0, len(p.Content.Files))
+		for _, f := range p.Content.Files {
+			files = append(files, map[string]any{"type": "code_execution_output", "file_id": f.FileID})
+		}
+		content = map[string]any{
+			"type":        "code_execution_result",
+			"stdout":      p.Content.Stdout,
+			"stderr":      p.Content.Stderr,
+			"return_code": p.Content.ReturnCode,
+			"content":     files,
+		}
+	}
+
+	return param.Override[anthropic.ContentBlockParamUnion](map[string]any{
+		"type":        "code_execution_tool_result",
+		"tool_use_id": p.ToolUseID,
+		"content":     content,
+	})
+}
+
+// usesFiles reports whether any message references an uploaded file.
+func usesFiles(messages []llms.Message) bool {
+	for _, message := range messages {
+		for _, part := range message.Parts {
+			if doc, ok := part.(llms.DocumentPart); ok && doc.FileID != "" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// toolSchemas memoizes schemaKeywords, as tools are converted for every
+// request.
+var toolSchemas llms.SchemaCache[map[string]any]
+
+// schemaKeywords returns the keywords of a tool's input schema that
+// ToolInputSchemaParam has no field for, such as "$defs" and
+// "additionalProperties", to be sent as they are.
+func schemaKeywords(schema *jsonschema.Schema) (map[string]any, error) {
+	keywords, err := llms.SchemaMap(schema)
+	if err != nil {
+		return nil, err
+	}
+	for _, keyword := range []string{"type", "properties", "required"} {
+		delete(keywords, keyword)
+	}
+	return keywords, nil
+}
+
 func convertTools(tools []llms.Tool) (
 	[]anthropic.ToolUnionParam,
 	[]option.RequestOption,
@@ -337,11 +995,12 @@ func convertTools(tools []llms.Tool) (
 
 	for _, tool := range tools {
 		switch t := tool.(type) {
-		case BashTool:
+		case BashTool, *BashTool:
 			anthTool := anthropic.ToolUnionParam{
 				OfBashTool20250124: &anthropic.ToolBash20250124Param{},
 			}
 			out = append(out, anthTool)
+			opts = append(opts, option.WithHeaderAdd("anthropic-beta", betaBashTool))
 
 		case CodeExecutionTool:
 			anthTool := anthropic.ToolUnionParam{
@@ -363,14 +1022,20 @@ func convertTools(tools []llms.Tool) (
 				p.MaxUses = param.NewOpt[int64](t.MaxUses)
 			}
 
-			if t.UserLocation.City != "" || t.UserLocation.Country != "" ||
-				t.UserLocation.Region != "" || t.UserLocation.Timezone != "" {
-				p.UserLocation = anthropic.WebSearchTool20250305UserLocationParam{
-					City:     param.NewOpt(t.UserLocation.City),
-					Country:  param.NewOpt(t.UserLocation.Country),
-					Region:   param.NewOpt(t.UserLocation.Region),
-					Timezone: param.NewOpt(t.UserLocation.Timezone),
-				}
+			// Only send the location fields that are set; empty strings
+			// are rejected by the API.
+			loc := t.UserLocation
+			if loc.City != "" {
+				p.UserLocation.City = param.NewOpt(loc.City)
+			}
+			if loc.Country != "" {
+				p.UserLocation.Country = param.NewOpt(loc.Country)
+			}
+			if loc.Region != "" {
+				p.UserLocation.Region = param.NewOpt(loc.Region)
+			}
+			if loc.Timezone != "" {
+				p.UserLocation.Timezone = param.NewOpt(loc.Timezone)
 			}
 
 			anthTool := anthropic.ToolUnionParam{
@@ -382,15 +1047,20 @@ func convertTools(tools []llms.Tool) (
 			anthTool := anthropic.ToolUnionParam{
 				OfTool: &anthropic.ToolParam{
 					Name:        tool.Name(),
-					Description: param.NewOpt(tool.Description()),
+					Description: param.NewOpt(llms.ToolDescription(tool)),
 				},
 			}
 
 			schema := tool.Schema()
+			keywords, err := toolSchemas.Get(schema, schemaKeywords)
+			if err != nil {
+				return nil, nil, fmt.Errorf("anthropic: tool %s: %w", tool.Name(), err)
+			}
 
 			anthTool.OfTool.InputSchema = anthropic.ToolInputSchemaParam{
-				Properties: schema.Properties,
-				Required:   schema.Required,
+				Properties:  schema.Properties,
+				Required:    schema.Required,
+				ExtraFields: keywords,
 			}
 
 			out = append(out, anthTool)
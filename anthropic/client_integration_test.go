@@ -1,3 +1,6 @@
+//go:build integration
+// +build integration
+
 package anthropic_test
 
 import (
@@ -9,6 +12,7 @@ import (
 
 	"github.com/llmite-ai/llms"
 	"github.com/llmite-ai/llms/anthropic"
+	"github.com/llmite-ai/llms/testutil"
 )
 
 type AnthropicTestSuite struct {
@@ -327,13 +331,12 @@ type AnthropicTestSuite struct {
 // }
 
 func (suite *AnthropicTestSuite) TestGenerateWithAnthropicWebSearch() {
-	suite.T().Parallel() // Enable parallel execution for this method
 	ctx := context.Background()
 	client := anthropic.New(
 		anthropic.WithTools([]llms.Tool{
 			anthropic.WebSearchTool{},
 		}),
-		anthropic.WithHttpLogging(),
+		anthropic.WithHTTPClient(testutil.NewRecordingClient(suite.T(), "web_search")),
 	)
 
 	systemMsg := llms.NewTextMessage(llms.RoleSystem, "You are a helpful assistant that searches the web for information.")
@@ -363,5 +366,6 @@ func (suite *AnthropicTestSuite) TestGenerateWithAnthropicWebSearch() {
 }
 
 func TestAnthropicTestSuite(t *testing.T) {
+	testutil.RequireAPIKey(t, "ANTHROPIC_API_KEY")
 	suite.Run(t, new(AnthropicTestSuite))
 }
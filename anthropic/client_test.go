@@ -2,16 +2,22 @@ package anthropic
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/anthropics/anthropic-sdk-go/option"
 	"github.com/invopop/jsonschema"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	orderedmap "github.com/wk8/go-ordered-map/v2"
 
 	"github.com/llmite-ai/llms"
+	"github.com/llmite-ai/llms/testutil"
 )
 
 func TestConvertMessages(t *testing.T) {
@@ -370,3 +376,735 @@ func TestConvertTools_SchemaValidation(t *testing.T) {
 	assert.Equal(t, tool.schema.Properties, schema.Properties)
 	assert.Equal(t, tool.schema.Required, schema.Required)
 }
+
+// newTestClient returns a client that sends requests to a local server using
+// handler.
+func newTestClient(t *testing.T, handler http.HandlerFunc, mods ...Modifer) *Client {
+	t.Helper()
+
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	mods = append([]Modifer{
+		WithAnthropicClientOptions(
+			option.WithBaseURL(server.URL),
+			option.WithAPIKey("test"),
+			option.WithMaxRetries(0),
+		),
+	}, mods...)
+
+	return New(mods...).(*Client)
+}
+
+func TestCountTokens(t *testing.T) {
+	var body map[string]any
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/messages/count_tokens", r.URL.Path)
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"input_tokens": 42}`))
+	})
+
+	count, err := client.CountTokens(context.Background(), []llms.Message{
+		llms.NewTextMessage(llms.RoleSystem, "Be brief."),
+		llms.NewTextMessage(llms.RoleUser, "Hello"),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 42, count)
+	assert.NotEmpty(t, body["system"])
+	assert.Len(t, body["messages"], 1)
+}
+
+func TestCountTokens_Tools(t *testing.T) {
+	bodies := map[string]map[string]any{}
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		bodies[r.URL.Path] = body
+		assert.Contains(t, r.Header.Values("anthropic-beta"), betaCodeExecution)
+
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/v1/messages/count_tokens" {
+			w.Write([]byte(`{"input_tokens": 42}`))
+			return
+		}
+		w.Write([]byte(`{"id": "msg_1", "type": "message", "role": "assistant", "model": "claude-test", "content": [{"type": "text", "text": "Hi!"}], "stop_reason": "end_turn", "usage": {"input_tokens": 42, "output_tokens": 2}}`))
+	}, WithTools([]llms.Tool{
+		CodeExecutionTool{},
+		WebSearchTool{MaxUses: 2},
+		&mockTool{name: "lookup", schema: &jsonschema.Schema{Type: "object"}},
+	}))
+
+	messages := []llms.Message{llms.NewTextMessage(llms.RoleUser, "Hello")}
+	_, err := client.CountTokens(context.Background(), messages)
+	require.NoError(t, err)
+	_, err = client.Generate(context.Background(), messages)
+	require.NoError(t, err)
+
+	// The tokens of the tools Generate sends are counted.
+	require.Len(t, bodies["/v1/messages/count_tokens"]["tools"], 3)
+	assert.Equal(t, bodies["/v1/messages"]["tools"], bodies["/v1/messages/count_tokens"]["tools"])
+}
+
+func TestBuildRequest_ToolRegistry(t *testing.T) {
+	registry, err := llms.NewToolRegistry(&mockTool{name: "first", schema: &jsonschema.Schema{Type: "object"}})
+	require.NoError(t, err)
+
+	client := New(WithToolRegistry(registry), WithTools([]llms.Tool{
+		&mockTool{name: "static", schema: &jsonschema.Schema{Type: "object"}},
+	})).(*Client)
+
+	// Tools registered after the client is created are picked up.
+	require.NoError(t, registry.Register(&mockTool{name: "second", schema: &jsonschema.Schema{Type: "object"}}))
+
+	req, _, err := client.BuildRequest(context.Background(), []llms.Message{llms.NewTextMessage(llms.RoleUser, "hi")})
+	require.NoError(t, err)
+
+	names := []string{}
+	for _, tool := range req.Tools {
+		names = append(names, tool.OfTool.Name)
+	}
+	assert.Equal(t, []string{"static", "first", "second"}, names)
+
+	// Call options can disable tools without creating a new client.
+	ctx := llms.WithCallOptions(context.Background(), llms.DisableTools("first"))
+	req, _, err = client.BuildRequest(ctx, []llms.Message{llms.NewTextMessage(llms.RoleUser, "hi")})
+	require.NoError(t, err)
+
+	names = []string{}
+	for _, tool := range req.Tools {
+		names = append(names, tool.OfTool.Name)
+	}
+	assert.Equal(t, []string{"static", "second"}, names)
+}
+
+func TestBuildRequest_SchemaReferences(t *testing.T) {
+	client := New(WithTools([]llms.Tool{testutil.RouteTool{}})).(*Client)
+
+	req, _, err := client.BuildRequest(context.Background(), []llms.Message{llms.NewTextMessage(llms.RoleUser, "Route?")})
+	require.NoError(t, err)
+	data, err := json.Marshal(req)
+	require.NoError(t, err)
+	var body map[string]any
+	require.NoError(t, json.Unmarshal(data, &body))
+
+	// The definitions the properties refer to are sent with them.
+	params := body["tools"].([]any)[0].(map[string]any)["input_schema"].(map[string]any)
+	assert.Equal(t, false, params["additionalProperties"])
+	assert.Contains(t, params["$defs"], "RoutePlace")
+	assert.NotContains(t, params, "$schema")
+	from := params["properties"].(map[string]any)["from"].(map[string]any)
+	assert.Equal(t, "#/$defs/RoutePlace", from["$ref"])
+}
+
+func TestGenerate_Usage(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{
+			"id": "msg_1",
+			"type": "message",
+			"role": "assistant",
+			"model": "claude-sonnet-4-20250514",
+			"content": [{"type": "text", "text": "Hi"}],
+			"stop_reason": "end_turn",
+			"usage": {"input_tokens": 10, "output_tokens": 5, "cache_creation_input_tokens": 100, "cache_read_input_tokens": 200,
+				"cache_creation": {"ephemeral_5m_input_tokens": 40, "ephemeral_1h_input_tokens": 60}}
+		}`))
+	})
+
+	resp, err := client.Generate(context.Background(), []llms.Message{llms.NewTextMessage(llms.RoleUser, "Hello")})
+	require.NoError(t, err)
+	assert.Equal(t, &llms.Usage{
+		InputTokens:                310,
+		OutputTokens:               5,
+		CacheCreationInputTokens:   100,
+		CacheCreation5mInputTokens: 40,
+		CacheCreation1hInputTokens: 60,
+		CacheReadInputTokens:       200,
+	}, resp.Usage)
+}
+
+// writeSSE writes events to w as a server-sent event stream.
+func writeSSE(w http.ResponseWriter, events ...string) {
+	w.Header().Set("Content-Type", "text/event-stream")
+	for _, event := range events {
+		var typ struct {
+			Type string `json:"type"`
+		}
+		json.Unmarshal([]byte(event), &typ)
+		fmt.Fprintf(w, "event: %s\ndata: %s\n\n", typ.Type, event)
+	}
+}
+
+func TestGenerateStream_Usage(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		writeSSE(w,
+			`{"type":"message_start","message":{"id":"msg_1","type":"message","role":"assistant","model":"claude-sonnet-4-20250514","content":[],"usage":{"input_tokens":10,"output_tokens":1,"cache_read_input_tokens":200}}}`,
+			`{"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}`,
+			`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Hi"}}`,
+			`{"type":"content_block_stop","index":0}`,
+			`{"type":"message_delta","delta":{"stop_reason":"end_turn"},"usage":{"input_tokens":12,"output_tokens":7,"cache_read_input_tokens":200}}`,
+			`{"type":"message_stop"}`,
+		)
+	})
+
+	var first *llms.Usage
+	resp, err := client.GenerateStream(context.Background(), []llms.Message{llms.NewTextMessage(llms.RoleUser, "Hello")}, func(r *llms.Response, err error) bool {
+		if first == nil && r != nil {
+			first = r.Usage
+		}
+		return true
+	})
+	require.NoError(t, err)
+
+	require.NotNil(t, first)
+	assert.Equal(t, 210, first.InputTokens)
+	assert.Equal(t, 1, first.OutputTokens)
+
+	assert.Equal(t, &llms.Usage{
+		InputTokens:          212,
+		OutputTokens:         7,
+		CacheReadInputTokens: 200,
+	}, resp.Usage)
+	assert.Equal(t, llms.StopReasonEndTurn, resp.StopReason)
+}
+
+func TestBuildRequest_CachePoints(t *testing.T) {
+	client := New(WithToolCaching(), WithTools([]llms.Tool{
+		&mockTool{name: "first", schema: &jsonschema.Schema{Type: "object"}},
+		&mockTool{name: "second", schema: &jsonschema.Schema{Type: "object"}},
+	})).(*Client)
+
+	req, _, err := client.BuildRequest(context.Background(), []llms.Message{
+		{Role: llms.RoleSystem, Parts: []llms.Part{llms.TextPart{Text: "Long instructions"}, llms.CachePointPart{}}},
+		{Role: llms.RoleUser, Parts: []llms.Part{
+			llms.TextPart{Text: "Long context"},
+			llms.CachePointPart{},
+			llms.TextPart{Text: "Question"},
+		}},
+	})
+	require.NoError(t, err)
+
+	data, err := json.Marshal(req)
+	require.NoError(t, err)
+
+	var body struct {
+		System   []map[string]any `json:"system"`
+		Tools    []map[string]any `json:"tools"`
+		Messages []struct {
+			Content []map[string]any `json:"content"`
+		} `json:"messages"`
+	}
+	require.NoError(t, json.Unmarshal(data, &body))
+
+	ephemeral := map[string]any{"type": "ephemeral"}
+	assert.Equal(t, ephemeral, body.System[0]["cache_control"])
+	assert.Nil(t, body.Tools[0]["cache_control"])
+	assert.Equal(t, ephemeral, body.Tools[1]["cache_control"])
+
+	content := body.Messages[0].Content
+	require.Len(t, content, 2)
+	assert.Equal(t, ephemeral, content[0]["cache_control"])
+	assert.Nil(t, content[1]["cache_control"])
+}
+
+func TestGenerate_CacheTTL(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Contains(t, r.Header.Values("anthropic-beta"), betaExtendedCacheTTL)
+
+		var body struct {
+			System []map[string]any `json:"system"`
+			Tools  []map[string]any `json:"tools"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		assert.Equal(t, map[string]any{"type": "ephemeral", "ttl": "1h"}, body.Tools[0]["cache_control"])
+		assert.Equal(t, map[string]any{"type": "ephemeral", "ttl": "5m"}, body.System[0]["cache_control"])
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id":"msg_1","type":"message","role":"assistant","content":[],"usage":{"input_tokens":1,"output_tokens":1}}`))
+	}, WithToolCacheTTL(time.Hour), WithTools([]llms.Tool{
+		&mockTool{name: "first", schema: &jsonschema.Schema{Type: "object"}},
+	}))
+
+	_, err := client.Generate(context.Background(), []llms.Message{
+		{Role: llms.RoleSystem, Parts: []llms.Part{llms.TextPart{Text: "Long instructions"}, llms.CachePointPart{TTL: 5 * time.Minute}}},
+		llms.NewTextMessage(llms.RoleUser, "Hi"),
+	})
+	require.NoError(t, err)
+}
+
+func TestConvertMessages_InvalidCacheTTL(t *testing.T) {
+	_, _, err := convertMessages([]llms.Message{
+		{Role: llms.RoleUser, Parts: []llms.Part{llms.TextPart{Text: "Hi"}, llms.CachePointPart{TTL: time.Minute}}},
+	})
+	assert.ErrorContains(t, err, "unsupported cache TTL 1m0s")
+}
+
+func TestConvertMessages_CachePointWithoutBlock(t *testing.T) {
+	_, _, err := convertMessages([]llms.Message{
+		{Role: llms.RoleUser, Parts: []llms.Part{llms.CachePointPart{}}},
+	})
+	assert.ErrorContains(t, err, "cache point must follow a content block")
+}
+
+func TestConvertMessages_Documents(t *testing.T) {
+	_, messages, err := convertMessages([]llms.Message{{
+		Role: llms.RoleUser,
+		Parts: []llms.Part{
+			llms.DocumentPart{Data: []byte("%PDF-1.4"), Title: "Report", Context: "Q3 numbers"},
+			llms.CachePointPart{},
+			llms.DocumentPart{MediaType: "text/plain", Data: []byte("plain notes")},
+			llms.DocumentPart{URL: "https://example.com/paper.pdf"},
+			llms.DocumentPart{FileID: "file_123"},
+			llms.TextPart{Text: "Summarize these"},
+		},
+	}})
+	require.NoError(t, err)
+
+	data, err := json.Marshal(messages[0].Content)
+	require.NoError(t, err)
+
+	assert.JSONEq(t, `[
+		{"type": "document", "title": "Report", "context": "Q3 numbers", "cache_control": {"type": "ephemeral"},
+		 "source": {"type": "base64", "media_type": "application/pdf", "data": "JVBERi0xLjQ="}},
+		{"type": "document", "source": {"type": "text", "media_type": "text/plain", "data": "plain notes"}},
+		{"type": "document", "source": {"type": "url", "url": "https://example.com/paper.pdf"}},
+		{"type": "document", "source": {"type": "file", "file_id": "file_123"}},
+		{"type": "text", "text": "Summarize these"}
+	]`, string(data))
+}
+
+func TestConvertMessages_InvalidDocument(t *testing.T) {
+	_, _, err := convertMessages([]llms.Message{{
+		Role:  llms.RoleUser,
+		Parts: []llms.Part{llms.DocumentPart{MediaType: "image/png", Data: []byte("x")}},
+	}})
+	assert.ErrorContains(t, err, "unsupported document media type: image/png")
+
+	_, _, err = convertMessages([]llms.Message{{
+		Role:  llms.RoleUser,
+		Parts: []llms.Part{llms.DocumentPart{}},
+	}})
+	assert.ErrorContains(t, err, "document has no data, URL or file ID")
+}
+
+func TestGenerate_FileDocumentSendsBetaHeader(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Contains(t, r.Header.Values("anthropic-beta"), betaFilesAPI)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id":"msg_1","type":"message","role":"assistant","content":[],"usage":{"input_tokens":1,"output_tokens":1}}`))
+	})
+
+	_, err := client.Generate(context.Background(), []llms.Message{{
+		Role:  llms.RoleUser,
+		Parts: []llms.Part{llms.DocumentPart{FileID: "file_123"}},
+	}})
+	require.NoError(t, err)
+}
+
+func TestGenerate_BashTool(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Contains(t, r.Header.Values("anthropic-beta"), betaBashTool)
+
+		var body struct {
+			Tools json.RawMessage `json:"tools"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		assert.JSONEq(t, `[{"name":"bash","type":"bash_20250124"},{"name":"bash","type":"bash_20250124"}]`, string(body.Tools))
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id":"msg_1","type":"message","role":"assistant","content":[],"usage":{"input_tokens":1,"output_tokens":1}}`))
+	}, WithTools([]llms.Tool{BashTool{}, &BashTool{}}))
+
+	_, err := client.Generate(context.Background(), []llms.Message{llms.NewTextMessage(llms.RoleUser, "List files")})
+	require.NoError(t, err)
+}
+
+func TestConvertTools_WebSearch(t *testing.T) {
+	tools, _, err := convertTools([]llms.Tool{WebSearchTool{
+		MaxUses:        3,
+		AllowedDomains: []string{"example.com"},
+		UserLocation:   UserLocation{City: "Paris", Country: "FR"},
+	}})
+	require.NoError(t, err)
+
+	data, err := json.Marshal(tools)
+	require.NoError(t, err)
+	assert.JSONEq(t, `[{
+		"type": "web_search_20250305",
+		"name": "web_search",
+		"max_uses": 3,
+		"allowed_domains": ["example.com"],
+		"user_location": {"type": "approximate", "city": "Paris", "country": "FR"}
+	}]`, string(data))
+}
+
+func TestGenerate_ToolCallID(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{
+			"id": "msg_1",
+			"type": "message",
+			"role": "assistant",
+			"content": [
+				{"type": "tool_use", "id": "toolu_1", "name": "get_weather", "input": {"city": "Paris"}}
+			],
+			"stop_reason": "tool_use",
+			"usage": {"input_tokens": 10, "output_tokens": 5}
+		}`))
+	})
+
+	resp, err := client.Generate(context.Background(), []llms.Message{llms.NewTextMessage(llms.RoleUser, "Weather?")})
+	require.NoError(t, err)
+
+	// The ID is read from the tool_use block's id, so the call can be answered.
+	require.Len(t, resp.Message.Parts, 1)
+	call := resp.Message.Parts[0].(llms.ToolCallPart)
+	assert.Equal(t, "toolu_1", call.ID)
+	assert.Equal(t, "get_weather", call.Name)
+}
+
+func TestGenerate_WebSearch(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{
+			"id": "msg_1",
+			"type": "message",
+			"role": "assistant",
+			"content": [
+				{"type": "server_tool_use", "id": "srvtoolu_1", "name": "web_search", "input": {"query": "weather paris"}},
+				{"type": "web_search_tool_result", "tool_use_id": "srvtoolu_1", "content": [
+					{"type": "web_search_result", "url": "https://example.com", "title": "Forecast", "encrypted_content": "abc", "page_age": "1 day"}
+				]},
+				{"type": "server_tool_use", "id": "srvtoolu_2", "name": "web_search", "input": {"query": "again"}},
+				{"type": "web_search_tool_result", "tool_use_id": "srvtoolu_2", "content": {"type": "web_search_tool_result_error", "error_code": "max_uses_exceeded"}},
+				{"type": "text", "text": "Sunny"}
+			],
+			"usage": {"input_tokens": 10, "output_tokens": 5, "server_tool_use": {"web_search_requests": 2}}
+		}`))
+	})
+
+	resp, err := client.Generate(context.Background(), []llms.Message{llms.NewTextMessage(llms.RoleUser, "Weather?")})
+	require.NoError(t, err)
+	require.Len(t, resp.Message.Parts, 5)
+
+	assert.Equal(t, WebSearchToolResult{
+		ToolUseID: "srvtoolu_1",
+		Results: []WebSearchResult{
+			{URL: "https://example.com", Title: "Forecast", PageAge: "1 day", EncryptedContent: "abc"},
+		},
+	}, resp.Message.Parts[1])
+	assert.Equal(t, WebSearchToolResult{ToolUseID: "srvtoolu_2", ErrorCode: "max_uses_exceeded"}, resp.Message.Parts[3])
+	assert.Equal(t, 2, resp.Usage.WebSearchRequests)
+
+	// The response can be sent back as history.
+	_, messages, err := convertMessages([]llms.Message{resp.Message})
+	require.NoError(t, err)
+
+	data, err := json.Marshal(messages[0].Content[:4])
+	require.NoError(t, err)
+	assert.JSONEq(t, `[
+		{"type": "server_tool_use", "id": "srvtoolu_1", "name": "web_search", "input": {"query": "weather paris"}},
+		{"type": "web_search_tool_result", "tool_use_id": "srvtoolu_1", "content": [
+			{"type": "web_search_result", "url": "https://example.com", "title": "Forecast", "encrypted_content": "abc", "page_age": "1 day"}
+		]},
+		{"type": "server_tool_use", "id": "srvtoolu_2", "name": "web_search", "input": {"query": "again"}},
+		{"type": "web_search_tool_result", "tool_use_id": "srvtoolu_2", "content": {"type": "web_search_tool_result_error", "error_code": "max_uses_exceeded"}}
+	]`, string(data))
+}
+
+func TestGenerate_CodeExecution(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, []string{betaFilesAPI, betaCodeExecution}, r.Header.Values("anthropic-beta"))
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{
+			"id": "msg_1",
+			"type": "message",
+			"role": "assistant",
+			"content": [
+				{"type": "server_tool_use", "id": "srvtoolu_1", "name": "code_execution", "input": {"code": "print(1)"}},
+				{"type": "code_execution_tool_result", "tool_use_id": "srvtoolu_1", "content": {
+					"type": "code_execution_result", "stdout": "1\n", "stderr": "", "return_code": 0,
+					"content": [{"type": "code_execution_output", "file_id": "file_out"}]
+				}},
+				{"type": "code_execution_tool_result", "tool_use_id": "srvtoolu_2", "content": {
+					"type": "code_execution_tool_result_error", "error_code": "execution_time_exceeded"
+				}}
+			],
+			"usage": {"input_tokens": 1, "output_tokens": 1}
+		}`))
+	}, WithTools([]llms.Tool{CodeExecutionTool{}}))
+
+	resp, err := client.Generate(context.Background(), []llms.Message{{
+		Role:  llms.RoleUser,
+		Parts: []llms.Part{llms.DocumentPart{FileID: "file_in"}, llms.TextPart{Text: "Run it"}},
+	}})
+	require.NoError(t, err)
+	require.Len(t, resp.Message.Parts, 3)
+
+	assert.Equal(t, CodeExecutionToolResult{
+		ToolUseID: "srvtoolu_1",
+		Content: CodeExecutionResult{
+			Stdout: "1\n",
+			Files:  []CodeExecutionOutput{{FileID: "file_out"}},
+		},
+	}, resp.Message.Parts[1])
+	assert.Equal(t, CodeExecutionToolResult{
+		ToolUseID: "srvtoolu_2",
+		Content:   CodeExecutionResult{ErrorCode: "execution_time_exceeded"},
+	}, resp.Message.Parts[2])
+
+	// The response can be sent back as history.
+	_, messages, err := convertMessages([]llms.Message{resp.Message})
+	require.NoError(t, err)
+
+	data, err := json.Marshal(messages[0].Content)
+	require.NoError(t, err)
+	assert.JSONEq(t, `[
+		{"type": "server_tool_use", "id": "srvtoolu_1", "name": "code_execution", "input": {"code": "print(1)"}},
+		{"type": "code_execution_tool_result", "tool_use_id": "srvtoolu_1", "content": {
+			"type": "code_execution_result", "stdout": "1\n", "stderr": "", "return_code": 0,
+			"content": [{"type": "code_execution_output", "file_id": "file_out"}]
+		}},
+		{"type": "code_execution_tool_result", "tool_use_id": "srvtoolu_2", "content": {
+			"type": "code_execution_tool_result_error", "error_code": "execution_time_exceeded"
+		}}
+	]`, string(data))
+}
+
+func TestGenerate_StopSequences(t *testing.T) {
+	var body map[string]any
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{
+			"id": "msg_1",
+			"type": "message",
+			"role": "assistant",
+			"content": [{"type": "text", "text": "1, 2, 3"}],
+			"stop_reason": "stop_sequence",
+			"stop_sequence": "4",
+			"usage": {"input_tokens": 1, "output_tokens": 1}
+		}`))
+	}, WithStopSequences("4", "END"))
+
+	resp, err := client.Generate(context.Background(), []llms.Message{llms.NewTextMessage(llms.RoleUser, "Count")})
+	require.NoError(t, err)
+
+	assert.Equal(t, []any{"4", "END"}, body["stop_sequences"])
+	assert.Equal(t, llms.StopReasonStopSequence, resp.StopReason)
+	assert.Equal(t, "4", resp.StopSequence)
+
+	// Per-call stop sequences replace the client's.
+	ctx := llms.WithCallOptions(context.Background(), llms.StopSequences("3"))
+	_, err = client.Generate(ctx, []llms.Message{llms.NewTextMessage(llms.RoleUser, "Count")})
+	require.NoError(t, err)
+	assert.Equal(t, []any{"3"}, body["stop_sequences"])
+}
+
+func TestBuildRequest_Sampling(t *testing.T) {
+	client := New(WithTemperature(0.2), WithTopP(0.9), WithTopK(40)).(*Client)
+
+	req, _, err := client.BuildRequest(context.Background(), []llms.Message{llms.NewTextMessage(llms.RoleUser, "hi")})
+	require.NoError(t, err)
+
+	assert.Equal(t, 0.2, req.Temperature.Value)
+	assert.Equal(t, 0.9, req.TopP.Value)
+	assert.Equal(t, int64(40), req.TopK.Value)
+
+	req, _, err = New().(*Client).BuildRequest(context.Background(), []llms.Message{llms.NewTextMessage(llms.RoleUser, "hi")})
+	require.NoError(t, err)
+	assert.False(t, req.Temperature.Valid())
+	assert.False(t, req.TopP.Valid())
+	assert.False(t, req.TopK.Valid())
+}
+
+func TestGenerateStream_EventHandler(t *testing.T) {
+	var types []string
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		writeSSE(w,
+			`{"type":"message_start","message":{"id":"msg_1","type":"message","role":"assistant","content":[],"usage":{"input_tokens":1,"output_tokens":1}}}`,
+			`{"type":"ping"}`,
+			`{"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}`,
+			`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Hi"}}`,
+			`{"type":"content_block_stop","index":0}`,
+			`{"type":"message_delta","delta":{"stop_reason":"end_turn"},"usage":{"output_tokens":2}}`,
+			`{"type":"message_stop"}`,
+		)
+	}, WithStreamEventHandler(func(event anthropic.MessageStreamEventUnion) {
+		types = append(types, event.Type)
+	}))
+
+	_, err := client.GenerateStream(context.Background(), []llms.Message{llms.NewTextMessage(llms.RoleUser, "Hello")}, func(*llms.Response, error) bool {
+		return true
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{
+		"message_start",
+		"content_block_start",
+		"content_block_delta",
+		"content_block_stop",
+		"message_delta",
+		"message_stop",
+	}, types)
+}
+
+func TestBuildRequest_ToolChoice(t *testing.T) {
+	tests := []struct {
+		choice   string
+		name     string
+		expected string
+		err      string
+	}{
+		{choice: ToolChoiceAuto, expected: `{"type":"auto"}`},
+		{choice: ToolChoiceAny, expected: `{"type":"any"}`},
+		{choice: ToolChoiceTool, name: "get_weather", expected: `{"type":"tool","name":"get_weather"}`},
+		{choice: ToolChoiceNone, expected: `{"type":"none"}`},
+		{choice: ToolChoiceTool, err: `tool choice "tool" requires a tool name`},
+		{choice: "sometimes", err: `unsupported tool choice: "sometimes"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.choice+tt.name, func(t *testing.T) {
+			client := New(WithToolChoice(tt.choice, tt.name)).(*Client)
+			req, _, err := client.BuildRequest(context.Background(), []llms.Message{llms.NewTextMessage(llms.RoleUser, "hi")})
+			if tt.err != "" {
+				assert.ErrorContains(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+
+			data, err := json.Marshal(req.ToolChoice)
+			require.NoError(t, err)
+			assert.JSONEq(t, tt.expected, string(data))
+		})
+	}
+}
+
+func TestBuildRequest_ToolChoiceCallOption(t *testing.T) {
+	client := New(WithToolChoice(ToolChoiceAuto, "")).(*Client)
+	ctx := llms.WithCallOptions(context.Background(), llms.ToolChoiceOption(llms.ToolChoice{Type: llms.ToolChoiceTool, Name: "get_weather"}))
+
+	req, _, err := client.BuildRequest(ctx, []llms.Message{llms.NewTextMessage(llms.RoleUser, "hi")})
+	require.NoError(t, err)
+
+	data, err := json.Marshal(req.ToolChoice)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"type":"tool","name":"get_weather"}`, string(data))
+}
+
+func TestGenerate_ToolUse(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{
+			"id": "msg_1",
+			"type": "message",
+			"role": "assistant",
+			"content": [{"type": "tool_use", "id": "toolu_1", "name": "get_weather", "input": {"location": "Paris"}}],
+			"stop_reason": "tool_use",
+			"usage": {"input_tokens": 1, "output_tokens": 1}
+		}`))
+	})
+
+	resp, err := client.Generate(context.Background(), []llms.Message{llms.NewTextMessage(llms.RoleUser, "Weather?")})
+	require.NoError(t, err)
+
+	require.Len(t, resp.Message.Parts, 1)
+	call := resp.Message.Parts[0].(llms.ToolCallPart)
+	assert.Equal(t, "toolu_1", call.ID)
+	assert.Equal(t, "get_weather", call.Name)
+	assert.JSONEq(t, `{"location": "Paris"}`, string(call.Input))
+}
+
+func TestBuildRequest_DisableParallelToolUse(t *testing.T) {
+	tools := WithTools([]llms.Tool{&mockTool{name: "get_weather", schema: &jsonschema.Schema{Type: "object"}}})
+	messages := []llms.Message{llms.NewTextMessage(llms.RoleUser, "hi")}
+
+	toolChoice := func(t *testing.T, ctx context.Context, client *Client) string {
+		t.Helper()
+		req, _, err := client.BuildRequest(ctx, messages)
+		require.NoError(t, err)
+		data, err := json.Marshal(req.ToolChoice)
+		require.NoError(t, err)
+		return string(data)
+	}
+
+	ctx := context.Background()
+	disabled := New(tools, WithDisableParallelToolUse()).(*Client)
+	assert.JSONEq(t, `{"type":"auto","disable_parallel_tool_use":true}`, toolChoice(t, ctx, disabled))
+
+	forced := New(tools, WithDisableParallelToolUse(), WithToolChoice(ToolChoiceTool, "get_weather")).(*Client)
+	assert.JSONEq(t, `{"type":"tool","name":"get_weather","disable_parallel_tool_use":true}`, toolChoice(t, ctx, forced))
+
+	// Per-call options override the client setting in both directions.
+	assert.Equal(t, `null`, toolChoice(t, llms.WithCallOptions(ctx, llms.ParallelToolCalls(true)), disabled))
+	plain := New(tools).(*Client)
+	assert.Equal(t, `null`, toolChoice(t, ctx, plain))
+	assert.JSONEq(t, `{"type":"auto","disable_parallel_tool_use":true}`,
+		toolChoice(t, llms.WithCallOptions(ctx, llms.ParallelToolCalls(false)), plain))
+
+	// Without tools there is nothing to restrict, and Anthropic rejects a
+	// tool_choice.
+	noTools := New(WithDisableParallelToolUse()).(*Client)
+	assert.Equal(t, `null`, toolChoice(t, ctx, noTools))
+}
+
+func TestGenerate_Thinking(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Contains(t, r.Header.Values("anthropic-beta"), betaInterleavedThinking)
+
+		var body struct {
+			Thinking json.RawMessage `json:"thinking"`
+			Messages []struct {
+				Content json.RawMessage `json:"content"`
+			} `json:"messages"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		assert.JSONEq(t, `{"type":"enabled","budget_tokens":2048}`, string(body.Thinking))
+		require.Len(t, body.Messages, 3)
+		assert.JSONEq(t, `[
+			{"type":"thinking","thinking":"Check the weather.","signature":"sig_1"},
+			{"type":"redacted_thinking","data":"opaque"},
+			{"type":"tool_use","id":"toolu_1","name":"get_weather","input":{"location":"Paris"}}
+		]`, string(body.Messages[1].Content))
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{
+			"id": "msg_2",
+			"type": "message",
+			"role": "assistant",
+			"content": [
+				{"type": "thinking", "thinking": "It is sunny.", "signature": "sig_2"},
+				{"type": "redacted_thinking", "data": "opaque_2"},
+				{"type": "text", "text": "Sunny."}
+			],
+			"stop_reason": "end_turn",
+			"usage": {"input_tokens": 1, "output_tokens": 1}
+		}`))
+	}, WithMaxTokens(4096), WithThinking(2048), WithInterleavedThinking())
+
+	resp, err := client.Generate(context.Background(), []llms.Message{
+		llms.NewTextMessage(llms.RoleUser, "Weather in Paris?"),
+		{
+			Role: llms.RoleAssistant,
+			Parts: []llms.Part{
+				llms.ThinkingPart{Text: "Check the weather.", Signature: "sig_1"},
+				llms.ThinkingPart{Text: "opaque", Redacted: true},
+				llms.ToolCallPart{ID: "toolu_1", Name: "get_weather", Input: []byte(`{"location":"Paris"}`)},
+			},
+		},
+		{
+			Role:  llms.RoleUser,
+			Parts: []llms.Part{llms.ToolResultPart{ToolCallID: "toolu_1", Name: "get_weather", Result: "sunny"}},
+		},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, []llms.Part{
+		llms.ThinkingPart{Text: "It is sunny.", Signature: "sig_2"},
+		llms.ThinkingPart{Text: "opaque_2", Redacted: true},
+		llms.TextPart{Text: "Sunny."},
+	}, resp.Message.Parts)
+}
@@ -1,6 +1,16 @@
 package anthropic
 
-import "encoding/json"
+import (
+	"encoding/json"
+
+	"github.com/llmite-ai/llms"
+)
+
+func init() {
+	llms.RegisterPartType("anthropic.server_tool_use", ServerToolUsePart{})
+	llms.RegisterPartType("anthropic.code_execution_tool_result", CodeExecutionToolResult{})
+	llms.RegisterPartType("anthropic.web_search_tool_result", WebSearchToolResult{})
+}
 
 type ServerToolUsePart struct {
 	ID    string          `json:"id"`
@@ -18,8 +28,38 @@ type CodeExecutionToolResult struct {
 func (CodeExecutionToolResult) IsPart() {}
 
 type CodeExecutionResult struct {
-	Stdout     string          `json:"stdout"`
-	Stderr     string          `json:"stderr"`
-	ReturnCode int             `json:"return_code"`
-	Content    json.RawMessage `json:"content"` // This can be used for additional content if needed
+	Stdout     string `json:"stdout"`
+	Stderr     string `json:"stderr"`
+	ReturnCode int    `json:"return_code"`
+	// Files lists the files created by the code. They can be downloaded with
+	// the Files API.
+	Files []CodeExecutionOutput `json:"content,omitempty"`
+	// ErrorCode is set instead of the other fields when the code could not be
+	// run, for example "unavailable" or "execution_time_exceeded".
+	ErrorCode string `json:"error_code,omitempty"`
+}
+
+// CodeExecutionOutput is a file created during code execution.
+type CodeExecutionOutput struct {
+	FileID string `json:"file_id"`
+}
+
+// WebSearchToolResult holds the results of a search made with WebSearchTool.
+type WebSearchToolResult struct {
+	ToolUseID string            `json:"tool_use_id"`
+	Results   []WebSearchResult `json:"results,omitempty"`
+	// ErrorCode is set instead of Results when the search failed, for example
+	// "max_uses_exceeded" or "unavailable".
+	ErrorCode string `json:"error_code,omitempty"`
+}
+
+func (WebSearchToolResult) IsPart() {}
+
+type WebSearchResult struct {
+	URL     string `json:"url"`
+	Title   string `json:"title"`
+	PageAge string `json:"page_age,omitempty"`
+	// EncryptedContent must be sent back unchanged in later turns so the
+	// model can cite the result.
+	EncryptedContent string `json:"encrypted_content"`
 }
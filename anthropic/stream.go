@@ -0,0 +1,191 @@
+package anthropic
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/anthropics/anthropic-sdk-go"
+
+	"github.com/llmite-ai/llms"
+)
+
+// maxEventSize is the largest server-sent event line StreamDecoder accepts.
+const maxEventSize = 10 << 20
+
+// StreamDecoder reads a Messages API server-sent event stream, such as a
+// response body relayed by a proxy, and yields the typed SDK events
+// (MessageStartEvent, ContentBlockDeltaEvent, and so on). Events are
+// accumulated into a message as they are read.
+//
+//	dec := anthropic.NewStreamDecoder(resp.Body)
+//	for dec.Next() {
+//		event := dec.Event()
+//		...
+//	}
+//	if err := dec.Err(); err != nil {
+//		...
+//	}
+//	response, err := dec.Response()
+type StreamDecoder struct {
+	scanner *bufio.Scanner
+	event   anthropic.MessageStreamEventUnion
+	message anthropic.Message
+	err     error
+}
+
+// NewStreamDecoder creates a StreamDecoder reading from r.
+func NewStreamDecoder(r io.Reader) *StreamDecoder {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64<<10), maxEventSize)
+
+	return &StreamDecoder{scanner: scanner}
+}
+
+// Next advances to the next event, returning false at the end of the stream
+// or on error. Ping events are skipped, and an error event ends the stream
+// with an error.
+func (d *StreamDecoder) Next() bool {
+	if d.err != nil {
+		return false
+	}
+
+	for {
+		typ, data, ok := d.readEvent()
+		if !ok {
+			return false
+		}
+
+		switch typ {
+		case "ping":
+			continue
+		case "error":
+			d.err = decodeStreamError(data)
+			return false
+		}
+
+		var event anthropic.MessageStreamEventUnion
+		if err := json.Unmarshal(data, &event); err != nil {
+			d.err = fmt.Errorf("anthropic: failed to decode %s event: %w", typ, err)
+			return false
+		}
+
+		if err := d.message.Accumulate(event); err != nil {
+			d.err = fmt.Errorf("anthropic: failed to accumulate message: %w", err)
+			return false
+		}
+		accumulateUsage(&d.message, event)
+
+		d.event = event
+		return true
+	}
+}
+
+// Event returns the current event.
+func (d *StreamDecoder) Event() anthropic.MessageStreamEventUnion {
+	return d.event
+}
+
+// Message returns the message accumulated from the events read so far.
+func (d *StreamDecoder) Message() *anthropic.Message {
+	return &d.message
+}
+
+// Response converts the message accumulated so far to an llms.Response.
+func (d *StreamDecoder) Response() (*llms.Response, error) {
+	return convertMessageToResponse(&d.message)
+}
+
+// Err returns the error that stopped the stream, if any.
+func (d *StreamDecoder) Err() error {
+	return d.err
+}
+
+// readEvent reads the next event from the stream, returning its type and
+// data. Events without an explicit type take it from the data's "type" field.
+func (d *StreamDecoder) readEvent() (string, []byte, bool) {
+	var (
+		typ  string
+		data bytes.Buffer
+	)
+
+	dispatch := func() (string, []byte, bool) {
+		payload := data.Bytes()
+		if typ == "" {
+			var t struct {
+				Type string `json:"type"`
+			}
+			json.Unmarshal(payload, &t)
+			typ = t.Type
+		}
+		return typ, payload, true
+	}
+
+	for d.scanner.Scan() {
+		line := d.scanner.Text()
+
+		if line == "" {
+			if data.Len() == 0 {
+				typ = ""
+				continue
+			}
+			return dispatch()
+		}
+
+		// Lines starting with a colon are comments.
+		if strings.HasPrefix(line, ":") {
+			continue
+		}
+
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+
+		switch field {
+		case "event":
+			typ = value
+		case "data":
+			if data.Len() > 0 {
+				data.WriteByte('\n')
+			}
+			data.WriteString(value)
+		}
+	}
+
+	if err := d.scanner.Err(); err != nil {
+		d.err = fmt.Errorf("anthropic: failed to read stream: %w", err)
+		return "", nil, false
+	}
+
+	// A stream may end without a trailing blank line.
+	if data.Len() > 0 {
+		return dispatch()
+	}
+
+	return "", nil, false
+}
+
+// decodeStreamError converts the data of an error event to an error.
+// Overloaded errors are reported as a 529 *llms.APIError so they are retried
+// like their non-streaming equivalent.
+func decodeStreamError(data []byte) error {
+	var event struct {
+		Error struct {
+			Type    string `json:"type"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(data, &event); err != nil {
+		return fmt.Errorf("anthropic: received error while streaming: %s", data)
+	}
+
+	msg := fmt.Sprintf("received error while streaming: %s: %s", event.Error.Type, event.Error.Message)
+	if event.Error.Type == "overloaded_error" {
+		return &llms.APIError{Provider: ProviderAnthropic, StatusCode: 529, Err: errors.New(msg)}
+	}
+
+	return errors.New("anthropic: " + msg)
+}
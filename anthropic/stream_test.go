@@ -0,0 +1,82 @@
+package anthropic
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/llmite-ai/llms"
+)
+
+func TestStreamDecoder(t *testing.T) {
+	body := strings.Join([]string{
+		`: keep-alive comment`,
+		`event: message_start`,
+		`data: {"type":"message_start","message":{"id":"msg_1","type":"message","role":"assistant","content":[],"usage":{"input_tokens":5,"output_tokens":1}}}`,
+		``,
+		`event: ping`,
+		`data: {"type": "ping"}`,
+		``,
+		`event: content_block_start`,
+		`data: {"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}`,
+		``,
+		`event: content_block_delta`,
+		`data: {"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Hello"}}`,
+		``,
+		// No event line: the type comes from the data.
+		`data: {"type":"content_block_delta","index":0,`,
+		`data: "delta":{"type":"text_delta","text":" world"}}`,
+		``,
+		`event: content_block_stop`,
+		`data: {"type":"content_block_stop","index":0}`,
+		``,
+		`event: message_delta`,
+		`data: {"type":"message_delta","delta":{"stop_reason":"end_turn"},"usage":{"output_tokens":3}}`,
+		``,
+		`event: message_stop`,
+		`data: {"type":"message_stop"}`,
+	}, "\n")
+
+	dec := NewStreamDecoder(strings.NewReader(body))
+
+	var types []string
+	for dec.Next() {
+		types = append(types, dec.Event().Type)
+	}
+	require.NoError(t, dec.Err())
+
+	assert.Equal(t, []string{
+		"message_start",
+		"content_block_start",
+		"content_block_delta",
+		"content_block_delta",
+		"content_block_stop",
+		"message_delta",
+		"message_stop",
+	}, types)
+
+	assert.Equal(t, "msg_1", dec.Message().ID)
+
+	resp, err := dec.Response()
+	require.NoError(t, err)
+	assert.Equal(t, []llms.Part{llms.TextPart{Text: "Hello world"}}, resp.Message.Parts)
+	assert.Equal(t, llms.StopReasonEndTurn, resp.StopReason)
+	assert.Equal(t, 3, resp.Usage.OutputTokens)
+}
+
+func TestStreamDecoder_ErrorEvent(t *testing.T) {
+	body := "event: error\ndata: {\"type\":\"error\",\"error\":{\"type\":\"overloaded_error\",\"message\":\"Overloaded\"}}\n\n"
+
+	dec := NewStreamDecoder(strings.NewReader(body))
+	assert.False(t, dec.Next())
+	assert.EqualError(t, dec.Err(), "anthropic: api error (status 529): received error while streaming: overloaded_error: Overloaded")
+	assert.True(t, llms.IsRetryable(dec.Err()))
+}
+
+func TestStreamDecoder_InvalidData(t *testing.T) {
+	dec := NewStreamDecoder(strings.NewReader("event: message_start\ndata: {not json\n\n"))
+	assert.False(t, dec.Next())
+	assert.ErrorContains(t, dec.Err(), "failed to decode message_start event")
+}
@@ -2,25 +2,42 @@ package anthropic
 
 import (
 	"net/http"
+	"slices"
 
 	"github.com/anthropics/anthropic-sdk-go/option"
 	"github.com/invopop/jsonschema"
 )
 
+// betaBashTool is the beta that enables BashTool.
+const betaBashTool = "computer-use-2025-01-24"
+
+// BashTool gives the model Anthropic's built-in bash tool. It is sent as the
+// native bash_20250124 definition; the commands the model asks to run come
+// back as ordinary tool calls named "bash" for the caller to execute.
 type BashTool struct{}
 
 func (b BashTool) Name() string               { return "bash" }
 func (b BashTool) Description() string        { return "anthropic ran tool for executing bash commands" }
 func (b BashTool) Schema() *jsonschema.Schema { return nil }
 
+// betaCodeExecution is the beta that enables CodeExecutionTool.
+const betaCodeExecution = "code-execution-2025-05-22"
+
 type CodeExecutionTool struct{}
 
 func (c CodeExecutionTool) Name() string               { return "code_execution" }
 func (c CodeExecutionTool) Description() string        { return "anthropic ran tool for executing code" }
 func (c CodeExecutionTool) Schema() *jsonschema.Schema { return nil }
+
+// Middleware adds the code execution beta header to requests. The client
+// applies it automatically when the tool is configured.
 func (c CodeExecutionTool) Middleware() option.Middleware {
 	return func(req *http.Request, next option.MiddlewareNext) (*http.Response, error) {
-		req.Header.Set("anthropic-beta", "code-execution-2025-05-22")
+		// Add rather than set, so other betas enabled for the request are
+		// kept.
+		if !slices.Contains(req.Header.Values("anthropic-beta"), betaCodeExecution) {
+			req.Header.Add("anthropic-beta", betaCodeExecution)
+		}
 		return next(req)
 	}
 }
@@ -0,0 +1,147 @@
+package llms
+
+import (
+	"context"
+	"errors"
+	"iter"
+	"time"
+)
+
+// BatchRequest is one of the requests submitted together with
+// BatchLLM.SubmitBatch.
+type BatchRequest struct {
+	// ID identifies the request within the batch, and is used to match it
+	// with its result. It must be unique within the batch.
+	ID       string
+	Messages []Message
+}
+
+// BatchStatus is the processing status of a batch.
+type BatchStatus string
+
+const (
+	// BatchInProgress is the status of a batch whose requests are being
+	// processed.
+	BatchInProgress BatchStatus = "in_progress"
+	// BatchCanceling is the status of a batch being canceled. Requests
+	// already being processed may still finish.
+	BatchCanceling BatchStatus = "canceling"
+	// BatchEnded is the status of a batch whose processing has ended, because
+	// every request finished or because the batch was canceled or expired.
+	// Its results can be retrieved.
+	BatchEnded BatchStatus = "ended"
+)
+
+// Batch describes a batch of requests submitted with BatchLLM.SubmitBatch.
+type Batch struct {
+	// ID identifies the batch with the provider. It can be stored to resume
+	// polling the batch and retrieving its results in another process.
+	ID     string
+	Status BatchStatus
+	// Pending, Succeeded and Failed are the numbers of requests in the batch
+	// not yet processed, processed successfully, and failed, canceled or
+	// expired.
+	Pending   int
+	Succeeded int
+	Failed    int
+	CreatedAt time.Time
+	// EndedAt is when processing ended, or the zero time if it has not.
+	EndedAt time.Time
+	// ExpiresAt is when the batch expires if it has not ended by then.
+	ExpiresAt time.Time
+	// Raw is the batch as returned by the provider.
+	Raw any
+}
+
+// Done reports whether the batch has ended, so its results can be retrieved.
+func (b *Batch) Done() bool {
+	return b.Status == BatchEnded
+}
+
+// BatchResult is the result of one of the requests of a batch.
+type BatchResult struct {
+	// ID is the ID of the BatchRequest.
+	ID string
+	// Response is the response to the request, or nil if it failed.
+	Response *Response
+	// Err is the error the request failed with. It wraps ErrBatchCanceled or
+	// ErrBatchExpired if the request was not processed.
+	Err error
+}
+
+var (
+	// ErrBatchCanceled is the error of requests not processed because their
+	// batch was canceled.
+	ErrBatchCanceled = errors.New("llms: batch canceled before the request was processed")
+	// ErrBatchExpired is the error of requests not processed before their
+	// batch expired.
+	ErrBatchExpired = errors.New("llms: batch expired before the request was processed")
+	// ErrBatchInProgress is returned when retrieving the results of a batch
+	// that has not ended.
+	ErrBatchInProgress = errors.New("llms: batch has not ended")
+)
+
+// BatchLLM submits many requests at once to a provider's batch API, which
+// processes them asynchronously, usually within a day and at a discount.
+// Batches are identified by their ID, so a batch submitted by one process can
+// be polled and its results retrieved by another.
+type BatchLLM interface {
+	// SubmitBatch submits requests as a new batch and returns it.
+	SubmitBatch(ctx context.Context, requests []BatchRequest) (*Batch, error)
+	// GetBatch returns the current state of the batch with the given ID.
+	GetBatch(ctx context.Context, id string) (*Batch, error)
+	// Results returns an iterator over the results of the batch with the
+	// given ID, in no particular order. The batch must have ended. A
+	// batch that ended early, because it was canceled or expired, yields
+	// the results of the requests processed before then along with the
+	// errors of the others. A non-nil error stops the iteration; it is
+	// ErrBatchInProgress if the batch has not ended.
+	Results(ctx context.Context, id string) iter.Seq2[BatchResult, error]
+}
+
+// BatchPollConfig configures WaitForBatch.
+type BatchPollConfig struct {
+	// Interval is the delay between polls. Defaults to 30s.
+	Interval time.Duration
+	// OnPoll, if set, is called with the state of the batch after every
+	// poll, such as to report progress.
+	OnPoll func(batch *Batch)
+}
+
+// WaitForBatch polls the batch with the given ID until it ends or ctx is
+// done, and returns its last state. Since batches are identified by their ID,
+// a process that restarts can resume waiting on a batch it submitted before.
+//
+//	batch, err := llms.WaitForBatch(ctx, client, id, llms.BatchPollConfig{Interval: time.Minute})
+//	if err != nil {
+//		return err
+//	}
+//	for result, err := range client.Results(ctx, batch.ID) {
+//		...
+//	}
+func WaitForBatch(ctx context.Context, llm BatchLLM, id string, config BatchPollConfig) (*Batch, error) {
+	if config.Interval <= 0 {
+		config.Interval = 30 * time.Second
+	}
+
+	ticker := time.NewTicker(config.Interval)
+	defer ticker.Stop()
+	for {
+		batch, err := llm.GetBatch(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if config.OnPoll != nil {
+			config.OnPoll(batch)
+		}
+		if batch.Done() {
+			return batch, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return batch, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package llms
+
+import (
+	"context"
+	"errors"
+	"iter"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// pollingBatchLLM reports its batch as in progress for the first polls.
+type pollingBatchLLM struct {
+	inProgress int
+	polls      int
+	err        error
+}
+
+func (p *pollingBatchLLM) SubmitBatch(ctx context.Context, requests []BatchRequest) (*Batch, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (p *pollingBatchLLM) GetBatch(ctx context.Context, id string) (*Batch, error) {
+	p.polls++
+	if p.err != nil {
+		return nil, p.err
+	}
+	if p.polls <= p.inProgress {
+		return &Batch{ID: id, Status: BatchInProgress, Pending: p.inProgress - p.polls + 1}, nil
+	}
+	return &Batch{ID: id, Status: BatchEnded, Succeeded: p.inProgress}, nil
+}
+
+func (p *pollingBatchLLM) Results(ctx context.Context, id string) iter.Seq2[BatchResult, error] {
+	return func(yield func(BatchResult, error) bool) {}
+}
+
+func TestWaitForBatch(t *testing.T) {
+	llm := &pollingBatchLLM{inProgress: 2}
+	var pending []int
+	batch, err := WaitForBatch(context.Background(), llm, "batch_1", BatchPollConfig{
+		Interval: time.Millisecond,
+		OnPoll: func(batch *Batch) {
+			pending = append(pending, batch.Pending)
+		},
+	})
+	require.NoError(t, err)
+	assert.True(t, batch.Done())
+	assert.Equal(t, "batch_1", batch.ID)
+	assert.Equal(t, 3, llm.polls)
+	assert.Equal(t, []int{2, 1, 0}, pending)
+}
+
+func TestWaitForBatch_Context(t *testing.T) {
+	llm := &pollingBatchLLM{inProgress: 1000}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	batch, err := WaitForBatch(ctx, llm, "batch_1", BatchPollConfig{Interval: time.Millisecond})
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	require.NotNil(t, batch)
+	assert.Equal(t, BatchInProgress, batch.Status)
+}
+
+func TestWaitForBatch_Error(t *testing.T) {
+	llm := &pollingBatchLLM{err: errors.New("boom")}
+	_, err := WaitForBatch(context.Background(), llm, "batch_1", BatchPollConfig{})
+	assert.EqualError(t, err, "boom")
+}
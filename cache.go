@@ -0,0 +1,306 @@
+package llms
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Cache stores responses by key for the Cached LLM wrapper.
+type Cache interface {
+	// Get returns the cached response for key. The bool is false on a miss.
+	Get(ctx context.Context, key string) (*Response, bool, error)
+	// Set stores resp under key.
+	Set(ctx context.Context, key string, resp *Response) error
+}
+
+// Cached is an LLM that returns cached responses for requests it has already
+// seen. Requests are matched exactly on Namespace, the wrapped LLM type, its
+// model (if it implements ModelNamer), the call options, and the messages.
+//
+// Settings made on the client itself, such as its tools, temperature or system
+// prompt, are not part of the key. Clients of the same type and model whose
+// settings differ must not share a Cache unless each is given its own
+// Namespace, or they will be served each other's responses.
+//
+// Cache errors never fail a request; a failed Get is treated as a miss and a
+// failed Set is ignored.
+type Cached struct {
+	LLM   LLM
+	Cache Cache
+	// Namespace, if set, is added to every key, so that differently
+	// configured clients can share a Cache.
+	Namespace string
+}
+
+// NewCached wraps llm so that identical requests are served from cache. This is
+// especially useful for test suites and repeated prompts.
+func NewCached(llm LLM, cache Cache) LLM {
+	return &Cached{
+		LLM:   llm,
+		Cache: cache,
+	}
+}
+
+// Caching returns a Middleware that serves identical requests from cache. See
+// NewCached.
+func Caching(cache Cache) Middleware {
+	return func(llm LLM) LLM {
+		return NewCached(llm, cache)
+	}
+}
+
+// ModelName returns the model of the wrapped LLM.
+func (c *Cached) ModelName() string {
+	return modelName(c.LLM)
+}
+
+// CacheKey returns the key Cached uses for a request made to llm with messages
+// and the call options carried by ctx, when its Namespace is empty.
+func CacheKey(ctx context.Context, llm LLM, messages []Message) (string, error) {
+	return cacheKey(ctx, "", llm, messages)
+}
+
+func cacheKey(ctx context.Context, namespace string, llm LLM, messages []Message) (string, error) {
+	model := modelName(llm)
+
+	data, err := json.Marshal(struct {
+		Namespace string      `json:"namespace,omitempty"`
+		LLM       string      `json:"llm"`
+		Model     string      `json:"model"`
+		Options   CallOptions `json:"options"`
+		Messages  []Message   `json:"messages"`
+	}{
+		Namespace: namespace,
+		LLM:       fmt.Sprintf("%T", llm),
+		Model:     model,
+		Options:   CallOptionsFromContext(ctx),
+		Messages:  messages,
+	})
+	if err != nil {
+		return "", fmt.Errorf("llms: failed to compute cache key: %w", err)
+	}
+
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+func (c *Cached) lookup(ctx context.Context, messages []Message) (string, *Response) {
+	key, err := cacheKey(ctx, c.Namespace, c.LLM, messages)
+	if err != nil {
+		return "", nil
+	}
+
+	resp, ok, err := c.Cache.Get(ctx, key)
+	if err != nil || !ok {
+		return key, nil
+	}
+
+	return key, resp
+}
+
+func (c *Cached) Generate(ctx context.Context, messages []Message) (*Response, error) {
+	key, cached := c.lookup(ctx, messages)
+	if cached != nil {
+		return cached, nil
+	}
+
+	resp, err := c.LLM.Generate(ctx, messages)
+	if err == nil && key != "" {
+		_ = c.Cache.Set(ctx, key, resp)
+	}
+
+	return resp, err
+}
+
+// GenerateStream delivers a cached response to fn as a single chunk. On a miss
+// the request is streamed as normal and the final response is cached, unless fn
+// stopped the stream early.
+func (c *Cached) GenerateStream(ctx context.Context, messages []Message, fn StreamFunc) (*Response, error) {
+	key, cached := c.lookup(ctx, messages)
+	if cached != nil {
+		fn(cached, nil)
+		return cached, nil
+	}
+
+	complete := true
+	resp, err := c.LLM.GenerateStream(ctx, messages, func(r *Response, err error) bool {
+		if !fn(r, err) {
+			complete = false
+			return false
+		}
+		return true
+	})
+	if err == nil && complete && key != "" {
+		_ = c.Cache.Set(ctx, key, resp)
+	}
+
+	return resp, err
+}
+
+// LRUCache is an in-memory Cache that evicts the least recently used entry once
+// it holds more than its configured number of entries. It is safe for
+// concurrent use.
+type LRUCache struct {
+	mu      sync.Mutex
+	size    int
+	order   *list.List
+	entries map[string]*list.Element
+}
+
+type lruEntry struct {
+	key  string
+	resp *Response
+}
+
+// NewLRUCache creates an LRUCache holding at most size responses.
+func NewLRUCache(size int) *LRUCache {
+	return &LRUCache{
+		size:    max(size, 1),
+		order:   list.New(),
+		entries: map[string]*list.Element{},
+	}
+}
+
+func (c *LRUCache) Get(ctx context.Context, key string) (*Response, bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.entries[key]
+	if !ok {
+		return nil, false, nil
+	}
+
+	c.order.MoveToFront(el)
+	return el.Value.(*lruEntry).resp, true, nil
+}
+
+func (c *LRUCache) Set(ctx context.Context, key string, resp *Response) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.entries[key]; ok {
+		el.Value.(*lruEntry).resp = resp
+		c.order.MoveToFront(el)
+		return nil
+	}
+
+	c.entries[key] = c.order.PushFront(&lruEntry{key: key, resp: resp})
+
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*lruEntry).key)
+	}
+
+	return nil
+}
+
+// FileCache is a Cache that stores each response as a JSON file in a directory.
+// Responses and their candidates are stored without their Raw provider
+// payload, and every part type in a response must be registered with
+// RegisterPartType.
+type FileCache struct {
+	Dir string
+}
+
+// NewFileCache creates a FileCache storing responses in dir. The directory is
+// created on the first write if it does not exist.
+func NewFileCache(dir string) *FileCache {
+	return &FileCache{Dir: dir}
+}
+
+type cachedResponse struct {
+	ID           string            `json:"id"`
+	Message      Message           `json:"message"`
+	Usage        *Usage            `json:"usage,omitempty"`
+	StopReason   StopReason        `json:"stop_reason,omitempty"`
+	StopSequence string            `json:"stop_sequence,omitempty"`
+	Candidates   []cachedCandidate `json:"candidates,omitempty"`
+	Provider     string            `json:"provider"`
+}
+
+type cachedCandidate struct {
+	Message    Message    `json:"message"`
+	StopReason StopReason `json:"stop_reason,omitempty"`
+}
+
+func (c *FileCache) path(key string) string {
+	return filepath.Join(c.Dir, key+".json")
+}
+
+func (c *FileCache) Get(ctx context.Context, key string) (*Response, bool, error) {
+	data, err := os.ReadFile(c.path(key))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	var cr cachedResponse
+	if err := json.Unmarshal(data, &cr); err != nil {
+		return nil, false, fmt.Errorf("llms: failed to decode cached response %s: %w", key, err)
+	}
+
+	resp := &Response{
+		ID:           cr.ID,
+		Message:      cr.Message,
+		Usage:        cr.Usage,
+		StopReason:   cr.StopReason,
+		StopSequence: cr.StopSequence,
+		Provider:     cr.Provider,
+	}
+	for _, c := range cr.Candidates {
+		resp.Candidates = append(resp.Candidates, Candidate{Message: c.Message, StopReason: c.StopReason})
+	}
+	return resp, true, nil
+}
+
+func (c *FileCache) Set(ctx context.Context, key string, resp *Response) error {
+	cr := cachedResponse{
+		ID:           resp.ID,
+		Message:      resp.Message,
+		Usage:        resp.Usage,
+		StopReason:   resp.StopReason,
+		StopSequence: resp.StopSequence,
+		Provider:     resp.Provider,
+	}
+	for _, c := range resp.Candidates {
+		cr.Candidates = append(cr.Candidates, cachedCandidate{Message: c.Message, StopReason: c.StopReason})
+	}
+	data, err := json.Marshal(cr)
+	if err != nil {
+		return fmt.Errorf("llms: failed to encode response for cache: %w", err)
+	}
+
+	if err := os.MkdirAll(c.Dir, 0o755); err != nil {
+		return err
+	}
+
+	// Write to a temporary file first so that readers never see a partially
+	// written entry.
+	tmp, err := os.CreateTemp(c.Dir, key+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), c.path(key))
+}
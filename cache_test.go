@@ -0,0 +1,173 @@
+package llms
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCached_Generate(t *testing.T) {
+	fake := newFakeLLM(fakeResult{resp: textResponse("ok")})
+	llm := NewCached(fake, NewLRUCache(10))
+
+	messages := []Message{NewTextMessage(RoleUser, "hello")}
+
+	for range 3 {
+		resp, err := llm.Generate(context.Background(), messages)
+		require.NoError(t, err)
+		assert.Equal(t, "resp_ok", resp.ID)
+	}
+	assert.Equal(t, 1, fake.Calls())
+
+	_, err := llm.Generate(context.Background(), []Message{NewTextMessage(RoleUser, "bye")})
+	require.NoError(t, err)
+	assert.Equal(t, 2, fake.Calls())
+}
+
+func TestCached_Namespace(t *testing.T) {
+	cache := NewLRUCache(10)
+	messages := []Message{NewTextMessage(RoleUser, "hello")}
+
+	warm := newFakeLLM(fakeResult{resp: textResponse("warm")})
+	_, err := (&Cached{LLM: warm, Cache: cache, Namespace: "warm"}).Generate(context.Background(), messages)
+	require.NoError(t, err)
+
+	// A client with other settings doesn't get the first one's response.
+	cold := newFakeLLM(fakeResult{resp: textResponse("cold")})
+	resp, err := (&Cached{LLM: cold, Cache: cache, Namespace: "cold"}).Generate(context.Background(), messages)
+	require.NoError(t, err)
+	assert.Equal(t, "resp_cold", resp.ID)
+	assert.Equal(t, 1, cold.Calls())
+}
+
+func TestCached_DoesNotCacheErrors(t *testing.T) {
+	fake := newFakeLLM(
+		fakeResult{err: errors.New("boom")},
+		fakeResult{resp: textResponse("ok")},
+	)
+	llm := NewCached(fake, NewLRUCache(10))
+
+	_, err := llm.Generate(context.Background(), nil)
+	require.Error(t, err)
+
+	resp, err := llm.Generate(context.Background(), nil)
+	require.NoError(t, err)
+	assert.Equal(t, "resp_ok", resp.ID)
+}
+
+func TestCached_GenerateStream(t *testing.T) {
+	fake := newFakeLLM(fakeResult{
+		chunks: []*Response{textResponse("chunk")},
+		resp:   textResponse("final"),
+	})
+	llm := NewCached(fake, NewLRUCache(10))
+
+	streamed := []string{}
+	fn := func(r *Response, err error) bool {
+		streamed = append(streamed, r.ID)
+		return true
+	}
+
+	_, err := llm.GenerateStream(context.Background(), nil, fn)
+	require.NoError(t, err)
+	resp, err := llm.GenerateStream(context.Background(), nil, fn)
+	require.NoError(t, err)
+
+	assert.Equal(t, "resp_final", resp.ID)
+	assert.Equal(t, []string{"resp_chunk", "resp_final"}, streamed)
+	assert.Equal(t, 1, fake.Calls())
+}
+
+func TestLRUCache_Evicts(t *testing.T) {
+	ctx := context.Background()
+	cache := NewLRUCache(2)
+
+	require.NoError(t, cache.Set(ctx, "a", textResponse("a")))
+	require.NoError(t, cache.Set(ctx, "b", textResponse("b")))
+
+	// Touch a so that b becomes the least recently used entry.
+	_, ok, _ := cache.Get(ctx, "a")
+	require.True(t, ok)
+
+	require.NoError(t, cache.Set(ctx, "c", textResponse("c")))
+
+	_, ok, _ = cache.Get(ctx, "b")
+	assert.False(t, ok)
+	_, ok, _ = cache.Get(ctx, "a")
+	assert.True(t, ok)
+	_, ok, _ = cache.Get(ctx, "c")
+	assert.True(t, ok)
+}
+
+func TestFileCache(t *testing.T) {
+	ctx := context.Background()
+	cache := NewFileCache(t.TempDir())
+
+	_, ok, err := cache.Get(ctx, "missing")
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	resp := &Response{
+		ID: "resp_1",
+		Message: Message{
+			Role: RoleAssistant,
+			Parts: []Part{
+				TextPart{Text: "Let me check."},
+				ToolCallPart{ID: "call_1", Name: "get_weather", Input: []byte(`{"location":"Paris"}`)},
+			},
+		},
+		Usage:      &Usage{InputTokens: 10, OutputTokens: 5},
+		StopReason: StopReasonToolUse,
+		Candidates: []Candidate{
+			{Message: NewTextMessage(RoleAssistant, "Let me check."), StopReason: StopReasonToolUse},
+			{Message: NewTextMessage(RoleAssistant, "Sunny."), StopReason: StopReasonEndTurn},
+		},
+		Provider: "test",
+	}
+	require.NoError(t, cache.Set(ctx, "key", resp))
+
+	got, ok, err := cache.Get(ctx, "key")
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, resp, got)
+}
+
+func TestMessage_JSONRoundTrip(t *testing.T) {
+	msg := Message{
+		Role: RoleUser,
+		Parts: []Part{
+			TextPart{Text: "hi"},
+			ToolResultPart{ToolCallID: "call_1", Name: "tool", Result: "failed", Error: errors.New("boom")},
+			DocumentPart{Data: []byte("%PDF"), Title: "Report"},
+			CachePointPart{},
+			ThinkingPart{Text: "hmm", Signature: "sig"},
+			ImagePart{URL: "https://example.com/cat.jpg", Detail: ImageDetailLow},
+			AudioPart{MediaType: "audio/wav", Data: []byte("RIFF"), Transcript: "hi"},
+			VideoPart{URL: "https://example.com/clip.mp4"},
+		},
+	}
+
+	data, err := json.Marshal(msg)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"type":"text"`)
+
+	var got Message
+	require.NoError(t, json.Unmarshal(data, &got))
+	require.Len(t, got.Parts, 8)
+	assert.Equal(t, TextPart{Text: "hi"}, got.Parts[0])
+	assert.Equal(t, msg.Parts[2:], got.Parts[2:])
+
+	result, ok := got.Parts[1].(ToolResultPart)
+	require.True(t, ok)
+	assert.EqualError(t, result.Error, "boom")
+}
+
+func TestMessage_UnmarshalUnknownPart(t *testing.T) {
+	var msg Message
+	err := json.Unmarshal([]byte(`{"role":"user","parts":[{"type":"nope"}]}`), &msg)
+	assert.Error(t, err)
+}
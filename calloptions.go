@@ -0,0 +1,190 @@
+package llms
+
+import (
+	"context"
+	"slices"
+
+	"github.com/invopop/jsonschema"
+)
+
+// CallOptions are settings for a single Generate or GenerateStream call. They
+// travel in the context, so they reach the provider through any wrapping
+// LLMs, and take precedence over the equivalent client settings. Providers
+// ignore options they do not support.
+type CallOptions struct {
+	// ParallelToolCalls, if set, allows or prevents the model from calling
+	// more than one tool in a single response.
+	ParallelToolCalls *bool `json:"parallel_tool_calls,omitempty"`
+	// ToolChoice, if set, controls whether and which tools the model calls.
+	ToolChoice *ToolChoice `json:"tool_choice,omitempty"`
+	// StopSequences, if set, are sequences that stop generation when the
+	// model produces them.
+	StopSequences []string `json:"stop_sequences,omitempty"`
+	// FrequencyPenalty, if set, penalises tokens by how often they have
+	// already appeared.
+	FrequencyPenalty *float64 `json:"frequency_penalty,omitempty"`
+	// PresencePenalty, if set, penalises tokens that have already appeared.
+	PresencePenalty *float64 `json:"presence_penalty,omitempty"`
+	// LogitBias, if set, adjusts the likelihood of tokens, keyed by token ID.
+	LogitBias map[string]int `json:"logit_bias,omitempty"`
+	// AllowedTools, if set, limits the tools offered to the model to those
+	// with these names. The client's other tools are left out of the request.
+	AllowedTools []string `json:"allowed_tools,omitempty"`
+	// DisabledTools, if set, leaves the tools with these names out of the
+	// request.
+	DisabledTools []string `json:"disabled_tools,omitempty"`
+	// ResponseSchema, if set, constrains the model to respond with JSON
+	// matching the schema.
+	ResponseSchema *jsonschema.Schema `json:"response_schema,omitempty"`
+}
+
+// ToolEnabled reports whether the tool with the given name may be offered to
+// the model, according to AllowedTools and DisabledTools.
+func (o CallOptions) ToolEnabled(name string) bool {
+	if len(o.AllowedTools) > 0 && !slices.Contains(o.AllowedTools, name) {
+		return false
+	}
+	return !slices.Contains(o.DisabledTools, name)
+}
+
+// FilterTools returns the tools that are enabled according to AllowedTools
+// and DisabledTools, in order. Providers apply it to the tools they are
+// configured with, so that the tool set can change between calls without
+// creating a new client.
+func (o CallOptions) FilterTools(tools []Tool) []Tool {
+	if len(o.AllowedTools) == 0 && len(o.DisabledTools) == 0 {
+		return tools
+	}
+
+	out := make([]Tool, 0, len(tools))
+	for _, tool := range tools {
+		if o.ToolEnabled(tool.Name()) {
+			out = append(out, tool)
+		}
+	}
+	return out
+}
+
+// Tool choice types.
+const (
+	// ToolChoiceAuto lets the model decide whether to call tools.
+	ToolChoiceAuto = "auto"
+	// ToolChoiceAny makes the model call at least one tool.
+	ToolChoiceAny = "any"
+	// ToolChoiceTool makes the model call the named tool.
+	ToolChoiceTool = "tool"
+	// ToolChoiceNone prevents the model from calling tools.
+	ToolChoiceNone = "none"
+)
+
+// ToolChoice controls whether and which tools the model calls.
+type ToolChoice struct {
+	// Type is one of ToolChoiceAuto, ToolChoiceAny, ToolChoiceTool or
+	// ToolChoiceNone.
+	Type string `json:"type"`
+	// Name is the tool to call when Type is ToolChoiceTool.
+	Name string `json:"name,omitempty"`
+}
+
+// CallOption sets a field of CallOptions.
+type CallOption func(*CallOptions)
+
+type callOptionsKey struct{}
+
+// WithCallOptions returns a copy of ctx carrying opts, applied on top of any
+// call options ctx already carries.
+//
+//	ctx = llms.WithCallOptions(ctx, llms.ParallelToolCalls(false))
+//	resp, err := client.Generate(ctx, messages)
+func WithCallOptions(ctx context.Context, opts ...CallOption) context.Context {
+	o := CallOptionsFromContext(ctx)
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return context.WithValue(ctx, callOptionsKey{}, o)
+}
+
+// CallOptionsFromContext returns the call options carried by ctx.
+func CallOptionsFromContext(ctx context.Context) CallOptions {
+	o, _ := ctx.Value(callOptionsKey{}).(CallOptions)
+	return o
+}
+
+// ParallelToolCalls allows or prevents the model from calling more than one
+// tool in a single response.
+func ParallelToolCalls(enabled bool) CallOption {
+	return func(o *CallOptions) {
+		o.ParallelToolCalls = &enabled
+	}
+}
+
+// ToolChoiceOption controls whether and which tools the model calls.
+//
+//	ctx = llms.WithCallOptions(ctx, llms.ToolChoiceOption(llms.ToolChoice{
+//		Type: llms.ToolChoiceTool,
+//		Name: "get_weather",
+//	}))
+func ToolChoiceOption(choice ToolChoice) CallOption {
+	return func(o *CallOptions) {
+		o.ToolChoice = &choice
+	}
+}
+
+// StopSequences stops generation when the model produces any of sequences.
+func StopSequences(sequences ...string) CallOption {
+	return func(o *CallOptions) {
+		o.StopSequences = sequences
+	}
+}
+
+// FrequencyPenalty penalises tokens by how often they have already appeared,
+// making the model less likely to repeat itself.
+func FrequencyPenalty(penalty float64) CallOption {
+	return func(o *CallOptions) {
+		o.FrequencyPenalty = &penalty
+	}
+}
+
+// PresencePenalty penalises tokens that have already appeared, making the
+// model more likely to move on to new topics.
+func PresencePenalty(penalty float64) CallOption {
+	return func(o *CallOptions) {
+		o.PresencePenalty = &penalty
+	}
+}
+
+// AllowTools limits the tools offered to the model to those with the given
+// names, leaving the client's other tools out of the request.
+//
+//	ctx = llms.WithCallOptions(ctx, llms.AllowTools("search", "fetch"))
+func AllowTools(names ...string) CallOption {
+	return func(o *CallOptions) {
+		o.AllowedTools = names
+	}
+}
+
+// DisableTools leaves the tools with the given names out of the request.
+func DisableTools(names ...string) CallOption {
+	return func(o *CallOptions) {
+		o.DisabledTools = names
+	}
+}
+
+// LogitBias adjusts the likelihood of the tokens in bias, keyed by the
+// provider's token ID, typically by a value between -100 and 100.
+func LogitBias(bias map[string]int) CallOption {
+	return func(o *CallOptions) {
+		o.LogitBias = bias
+	}
+}
+
+// ResponseSchema constrains the model to respond with JSON matching schema.
+// Use GenerateSchema to build the schema from a Go type, then unmarshal the
+// response text into it:
+//
+//	ctx = llms.WithCallOptions(ctx, llms.ResponseSchema(llms.GenerateSchema[Recipe]()))
+func ResponseSchema(schema *jsonschema.Schema) CallOption {
+	return func(o *CallOptions) {
+		o.ResponseSchema = schema
+	}
+}
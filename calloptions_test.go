@@ -0,0 +1,71 @@
+package llms
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithCallOptions(t *testing.T) {
+	ctx := context.Background()
+	assert.Equal(t, CallOptions{}, CallOptionsFromContext(ctx))
+
+	ctx = WithCallOptions(ctx, ParallelToolCalls(false))
+	require.NotNil(t, CallOptionsFromContext(ctx).ParallelToolCalls)
+	assert.False(t, *CallOptionsFromContext(ctx).ParallelToolCalls)
+
+	// Later options are layered on top of earlier ones without changing the
+	// parent context.
+	child := WithCallOptions(ctx, ParallelToolCalls(true))
+	assert.True(t, *CallOptionsFromContext(child).ParallelToolCalls)
+	assert.False(t, *CallOptionsFromContext(ctx).ParallelToolCalls)
+}
+
+func TestToolChoiceOption(t *testing.T) {
+	ctx := WithCallOptions(context.Background(), ToolChoiceOption(ToolChoice{Type: ToolChoiceTool, Name: "echo"}))
+	assert.Equal(t, &ToolChoice{Type: ToolChoiceTool, Name: "echo"}, CallOptionsFromContext(ctx).ToolChoice)
+}
+
+func TestCallOptions_FilterTools(t *testing.T) {
+	tools := []Tool{echoTool{}, panicTool{}, hostedTool{}}
+
+	assert.Equal(t, tools, CallOptions{}.FilterTools(tools))
+	assert.Equal(t, []Tool{echoTool{}, hostedTool{}}, CallOptions{AllowedTools: []string{"hosted", "echo"}}.FilterTools(tools))
+	assert.Equal(t, []Tool{echoTool{}}, CallOptions{DisabledTools: []string{"panic", "hosted"}}.FilterTools(tools))
+
+	// Disabling takes precedence over allowing.
+	opts := CallOptionsFromContext(WithCallOptions(context.Background(), AllowTools("echo", "panic"), DisableTools("panic")))
+	assert.Equal(t, []Tool{echoTool{}}, opts.FilterTools(tools))
+	assert.True(t, opts.ToolEnabled("echo"))
+	assert.False(t, opts.ToolEnabled("panic"))
+	assert.False(t, opts.ToolEnabled("hosted"))
+}
+
+func TestSamplingCallOptions(t *testing.T) {
+	ctx := WithCallOptions(context.Background(),
+		StopSequences("END"),
+		FrequencyPenalty(0.5),
+		PresencePenalty(-0.5),
+		LogitBias(map[string]int{"50256": -100}),
+	)
+
+	opts := CallOptionsFromContext(ctx)
+	assert.Equal(t, []string{"END"}, opts.StopSequences)
+	assert.Equal(t, 0.5, *opts.FrequencyPenalty)
+	assert.Equal(t, -0.5, *opts.PresencePenalty)
+	assert.Equal(t, map[string]int{"50256": -100}, opts.LogitBias)
+}
+
+func TestCacheKey_CallOptions(t *testing.T) {
+	llm := newFakeLLM(fakeResult{resp: textResponse("hi")})
+	messages := []Message{NewTextMessage(RoleUser, "hello")}
+
+	plain, err := CacheKey(context.Background(), llm, messages)
+	require.NoError(t, err)
+	withOpts, err := CacheKey(WithCallOptions(context.Background(), ParallelToolCalls(false)), llm, messages)
+	require.NoError(t, err)
+
+	assert.NotEqual(t, plain, withOpts)
+}
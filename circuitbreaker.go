@@ -0,0 +1,208 @@
+package llms
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned by a CircuitBreaker while it is open. It is not
+// retryable, but a Fallback moves on to the next LLM.
+var ErrCircuitOpen = errors.New("llms: circuit breaker is open")
+
+// CircuitState is the state of a CircuitBreaker.
+type CircuitState string
+
+const (
+	// CircuitClosed lets every request through.
+	CircuitClosed CircuitState = "closed"
+	// CircuitOpen fails every request fast with ErrCircuitOpen.
+	CircuitOpen CircuitState = "open"
+	// CircuitHalfOpen lets a limited number of probe requests through to decide
+	// whether the circuit should close again.
+	CircuitHalfOpen CircuitState = "half_open"
+)
+
+// CircuitBreakerConfig controls when a CircuitBreaker opens and recovers.
+type CircuitBreakerConfig struct {
+	// FailureThreshold is the number of consecutive failures that opens the
+	// circuit. Defaults to 5.
+	FailureThreshold int
+	// OpenTimeout is how long the circuit stays open before letting probe
+	// requests through. Defaults to 30s.
+	OpenTimeout time.Duration
+	// HalfOpenProbes is the number of concurrent probe requests allowed while
+	// half-open. Defaults to 1.
+	HalfOpenProbes int
+
+	// IsFailure decides whether an error counts towards opening the circuit.
+	// Defaults to IsRetryable, so that invalid requests don't trip the breaker.
+	// Other errors, such as a cancelled context, leave the circuit as it is;
+	// only successful requests close it.
+	IsFailure func(error) bool
+	// OnStateChange, if set, is called whenever the circuit changes state.
+	OnStateChange func(from, to CircuitState)
+}
+
+// CircuitBreaker is an LLM decorator that stops sending requests to a flaky
+// provider after repeated failures. It is safe for concurrent use.
+type CircuitBreaker struct {
+	llm    LLM
+	config CircuitBreakerConfig
+
+	mu       sync.Mutex
+	state    CircuitState
+	failures int
+	openedAt time.Time
+	probes   int
+	// halfOpens counts the times the circuit has half-opened, so that probes
+	// sent before it last opened are not mistaken for current ones.
+	halfOpens int
+	now       func() time.Time
+}
+
+// NewCircuitBreaker wraps llm with a circuit breaker. The breaker opens after
+// FailureThreshold consecutive failures, fails fast with ErrCircuitOpen while
+// open, and half-opens after OpenTimeout to probe whether the provider has
+// recovered.
+func NewCircuitBreaker(llm LLM, config CircuitBreakerConfig) LLM {
+	if config.FailureThreshold <= 0 {
+		config.FailureThreshold = 5
+	}
+	if config.OpenTimeout <= 0 {
+		config.OpenTimeout = 30 * time.Second
+	}
+	if config.HalfOpenProbes <= 0 {
+		config.HalfOpenProbes = 1
+	}
+	if config.IsFailure == nil {
+		config.IsFailure = IsRetryable
+	}
+
+	return &CircuitBreaker{
+		llm:    llm,
+		config: config,
+		state:  CircuitClosed,
+		now:    time.Now,
+	}
+}
+
+// CircuitBreaking returns a Middleware that wraps an LLM with a circuit
+// breaker. See NewCircuitBreaker.
+func CircuitBreaking(config CircuitBreakerConfig) Middleware {
+	return func(llm LLM) LLM {
+		return NewCircuitBreaker(llm, config)
+	}
+}
+
+// ModelName returns the model of the wrapped LLM.
+func (cb *CircuitBreaker) ModelName() string {
+	return modelName(cb.llm)
+}
+
+// State returns the current state of the circuit.
+func (cb *CircuitBreaker) State() CircuitState {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	if cb.state == CircuitOpen && cb.now().Sub(cb.openedAt) >= cb.config.OpenTimeout {
+		return CircuitHalfOpen
+	}
+	return cb.state
+}
+
+func (cb *CircuitBreaker) setState(state CircuitState) {
+	if cb.state == state {
+		return
+	}
+
+	from := cb.state
+	cb.state = state
+	if cb.config.OnStateChange != nil {
+		cb.config.OnStateChange(from, state)
+	}
+}
+
+// allow reports whether a request may be sent and reserves a probe slot if the
+// circuit is half-open. probe is the half-open period the slot belongs to, or
+// 0 if the request is not a probe.
+func (cb *CircuitBreaker) allow() (probe int, err error) {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	if cb.state == CircuitOpen {
+		if cb.now().Sub(cb.openedAt) < cb.config.OpenTimeout {
+			return 0, ErrCircuitOpen
+		}
+		cb.setState(CircuitHalfOpen)
+		cb.probes = 0
+		cb.halfOpens++
+	}
+
+	if cb.state == CircuitHalfOpen {
+		if cb.probes >= cb.config.HalfOpenProbes {
+			return 0, ErrCircuitOpen
+		}
+		cb.probes++
+		return cb.halfOpens, nil
+	}
+
+	return 0, nil
+}
+
+// record records the outcome of a request admitted by allow. Only the probes
+// of the current half-open period decide whether a half-open circuit closes,
+// and requests admitted before the circuit opened are ignored once it has.
+// Errors that are not failures only free the probe's slot.
+func (cb *CircuitBreaker) record(probe int, err error) {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	current := probe != 0 && probe == cb.halfOpens && cb.state == CircuitHalfOpen
+	if current {
+		cb.probes--
+	}
+	if cb.state != CircuitClosed && !current {
+		return
+	}
+
+	if err == nil {
+		cb.failures = 0
+		cb.setState(CircuitClosed)
+		return
+	}
+	if !cb.config.IsFailure(err) {
+		return
+	}
+
+	cb.failures++
+	if cb.state == CircuitHalfOpen || cb.failures >= cb.config.FailureThreshold {
+		cb.openedAt = cb.now()
+		cb.setState(CircuitOpen)
+	}
+}
+
+func (cb *CircuitBreaker) Generate(ctx context.Context, messages []Message) (*Response, error) {
+	probe, err := cb.allow()
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := cb.llm.Generate(ctx, messages)
+	cb.record(probe, err)
+
+	return resp, err
+}
+
+func (cb *CircuitBreaker) GenerateStream(ctx context.Context, messages []Message, fn StreamFunc) (*Response, error) {
+	probe, err := cb.allow()
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := cb.llm.GenerateStream(ctx, messages, fn)
+	cb.record(probe, err)
+
+	return resp, err
+}
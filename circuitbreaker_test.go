@@ -0,0 +1,147 @@
+package llms
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	serverErr := &APIError{StatusCode: http.StatusInternalServerError}
+	fake := newFakeLLM(
+		fakeResult{err: serverErr},
+		fakeResult{err: serverErr},
+		fakeResult{err: serverErr},
+		fakeResult{resp: textResponse("ok")},
+	)
+
+	now := time.Now()
+	var transitions []CircuitState
+	cb := NewCircuitBreaker(fake, CircuitBreakerConfig{
+		FailureThreshold: 2,
+		OpenTimeout:      time.Minute,
+		OnStateChange: func(from, to CircuitState) {
+			transitions = append(transitions, to)
+		},
+	}).(*CircuitBreaker)
+	cb.now = func() time.Time { return now }
+
+	ctx := context.Background()
+
+	// Two consecutive failures open the circuit.
+	_, err := cb.Generate(ctx, nil)
+	assert.ErrorIs(t, err, serverErr)
+	_, err = cb.Generate(ctx, nil)
+	assert.ErrorIs(t, err, serverErr)
+	assert.Equal(t, CircuitOpen, cb.State())
+
+	// While open, requests fail fast without reaching the LLM.
+	_, err = cb.Generate(ctx, nil)
+	assert.ErrorIs(t, err, ErrCircuitOpen)
+	assert.False(t, IsRetryable(err))
+	assert.Equal(t, 2, fake.Calls())
+
+	// After the timeout a failing probe re-opens the circuit.
+	now = now.Add(time.Minute)
+	assert.Equal(t, CircuitHalfOpen, cb.State())
+	_, err = cb.Generate(ctx, nil)
+	assert.ErrorIs(t, err, serverErr)
+	assert.Equal(t, CircuitOpen, cb.State())
+
+	// A successful probe closes it again.
+	now = now.Add(time.Minute)
+	resp, err := cb.Generate(ctx, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "resp_ok", resp.ID)
+	assert.Equal(t, CircuitClosed, cb.State())
+
+	assert.Equal(t, []CircuitState{
+		CircuitOpen, CircuitHalfOpen, CircuitOpen, CircuitHalfOpen, CircuitClosed,
+	}, transitions)
+}
+
+func TestCircuitBreaker_LateRequests(t *testing.T) {
+	serverErr := &APIError{StatusCode: http.StatusInternalServerError}
+	now := time.Now()
+	cb := NewCircuitBreaker(newFakeLLM(), CircuitBreakerConfig{FailureThreshold: 1, OpenTimeout: time.Minute}).(*CircuitBreaker)
+	cb.now = func() time.Time { return now }
+
+	// A request is still running when another opens the circuit.
+	slow, err := cb.allow()
+	require.NoError(t, err)
+	failing, err := cb.allow()
+	require.NoError(t, err)
+	cb.record(failing, serverErr)
+	assert.Equal(t, CircuitOpen, cb.State())
+
+	now = now.Add(time.Minute)
+	probe, err := cb.allow()
+	require.NoError(t, err)
+	_, err = cb.allow()
+	assert.ErrorIs(t, err, ErrCircuitOpen)
+
+	// The slow request finishing neither closes the circuit nor frees the
+	// probe's slot.
+	cb.record(slow, nil)
+	assert.Equal(t, CircuitHalfOpen, cb.State())
+	_, err = cb.allow()
+	assert.ErrorIs(t, err, ErrCircuitOpen)
+
+	cb.record(probe, nil)
+	assert.Equal(t, CircuitClosed, cb.State())
+}
+
+func TestCircuitBreaker_CancelledProbe(t *testing.T) {
+	now := time.Now()
+	cb := NewCircuitBreaker(newFakeLLM(), CircuitBreakerConfig{FailureThreshold: 1, OpenTimeout: time.Minute}).(*CircuitBreaker)
+	cb.now = func() time.Time { return now }
+
+	failing, err := cb.allow()
+	require.NoError(t, err)
+	cb.record(failing, &APIError{StatusCode: http.StatusInternalServerError})
+	now = now.Add(time.Minute)
+
+	// A probe cancelled by its caller says nothing about the provider, so
+	// the circuit stays half-open and another probe may be sent.
+	probe, err := cb.allow()
+	require.NoError(t, err)
+	cb.record(probe, context.Canceled)
+	assert.Equal(t, CircuitHalfOpen, cb.State())
+
+	probe, err = cb.allow()
+	require.NoError(t, err)
+	cb.record(probe, nil)
+	assert.Equal(t, CircuitClosed, cb.State())
+}
+
+func TestCircuitBreaker_IgnoresPermanentErrors(t *testing.T) {
+	fake := newFakeLLM(fakeResult{err: &APIError{StatusCode: http.StatusBadRequest}})
+	cb := NewCircuitBreaker(fake, CircuitBreakerConfig{FailureThreshold: 1}).(*CircuitBreaker)
+
+	for range 3 {
+		_, err := cb.Generate(context.Background(), nil)
+		require.Error(t, err)
+	}
+
+	assert.Equal(t, CircuitClosed, cb.State())
+	assert.Equal(t, 3, fake.Calls())
+}
+
+func TestCircuitBreaker_WithFallback(t *testing.T) {
+	primary := NewCircuitBreaker(
+		newFakeLLM(fakeResult{err: &APIError{StatusCode: http.StatusServiceUnavailable}}),
+		CircuitBreakerConfig{FailureThreshold: 1},
+	)
+	secondary := newFakeLLM(fakeResult{resp: textResponse("secondary")})
+
+	llm := NewFallback(primary, secondary)
+	for range 3 {
+		resp, err := llm.Generate(context.Background(), nil)
+		require.NoError(t, err)
+		assert.Equal(t, "resp_secondary", resp.ID)
+	}
+}
@@ -0,0 +1,186 @@
+package llms
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// DefaultCompactionPrompt is the instruction Compactor gives the LLM when
+// summarizing a conversation.
+const DefaultCompactionPrompt = `Summarize the conversation below so that it can replace it as context for continuing the conversation. Keep every fact, decision, open question and piece of data that later turns may rely on, including the results of tool calls. Omit pleasantries and repetition. Reply with the summary only.`
+
+// compactionSummaryPrefix starts the messages holding a summary, so that
+// earlier summaries are folded into later ones.
+const compactionSummaryPrefix = "Summary of the earlier conversation:\n\n"
+
+// Compactor is a ContextStrategy that, once the conversation exceeds the
+// limit, asks an LLM to summarize its older messages and replaces them with a
+// single system message holding the summary. Leading system messages and the
+// most recent messages are kept as they are. A tool call is never separated
+// from its results, so the kept messages stay valid for every provider.
+//
+// Fit makes a request to the LLM whenever it compacts, so rather than running
+// it before every request with WithContextLimit, compact the history once with
+// Conversation.Compact:
+//
+//	err := conv.Compact(ctx, 100_000, llms.Compactor{LLM: cheapLLM})
+type Compactor struct {
+	// LLM writes the summaries. It may be a cheaper model than the one
+	// holding the conversation.
+	LLM LLM
+	// Counter counts the tokens of the conversation. Defaults to
+	// EstimatingTokenCounter.
+	Counter TokenCounter
+	// KeepRecent is the number of most recent messages that are kept rather
+	// than summarized. More are kept if needed to include the call of a kept
+	// tool result. Defaults to 4.
+	KeepRecent int
+	// Prompt is the instruction given to the LLM. Defaults to
+	// DefaultCompactionPrompt.
+	Prompt string
+}
+
+func (c Compactor) Fit(ctx context.Context, messages []Message, limit int) ([]Message, error) {
+	counter := c.Counter
+	if counter == nil {
+		counter = EstimatingTokenCounter
+	}
+
+	count, err := counter.CountTokens(ctx, messages)
+	if err != nil {
+		return nil, fmt.Errorf("llms: failed to count tokens: %w", err)
+	}
+	if count <= limit {
+		return append([]Message(nil), messages...), nil
+	}
+
+	keep := c.KeepRecent
+	if keep <= 0 {
+		keep = 4
+	}
+
+	start := 0
+	for start < len(messages) && messages[start].Role == RoleSystem && !isCompactionSummary(messages[start]) {
+		start++
+	}
+	cut := keepToolResultsWithCalls(messages, start, max(len(messages)-keep, start))
+	if cut == start || (cut == start+1 && isCompactionSummary(messages[start])) {
+		return nil, fmt.Errorf("%w: nothing left to summarize with %d tokens and a limit of %d", ErrContextLimitExceeded, count, limit)
+	}
+
+	summary, err := c.summarize(ctx, messages[start:cut])
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]Message, 0, start+1+len(messages)-cut)
+	out = append(out, messages[:start]...)
+	out = append(out, NewTextMessage(RoleSystem, compactionSummaryPrefix+summary))
+	out = append(out, messages[cut:]...)
+
+	count, err = counter.CountTokens(ctx, out)
+	if err != nil {
+		return nil, fmt.Errorf("llms: failed to count tokens: %w", err)
+	}
+	if count > limit {
+		return nil, fmt.Errorf("%w: %d tokens remain after summarizing with a limit of %d", ErrContextLimitExceeded, count, limit)
+	}
+	return out, nil
+}
+
+func (c Compactor) summarize(ctx context.Context, messages []Message) (string, error) {
+	if c.LLM == nil {
+		return "", errors.New("llms: Compactor requires an LLM")
+	}
+	prompt := c.Prompt
+	if prompt == "" {
+		prompt = DefaultCompactionPrompt
+	}
+
+	resp, err := c.LLM.Generate(ctx, []Message{
+		NewTextMessage(RoleSystem, prompt),
+		NewTextMessage(RoleUser, transcript(messages)),
+	})
+	if err != nil {
+		return "", fmt.Errorf("llms: failed to summarize conversation: %w", err)
+	}
+
+	summary := strings.TrimSpace(messageText(resp.Message))
+	if summary == "" {
+		return "", errors.New("llms: failed to summarize conversation: empty summary")
+	}
+	return summary, nil
+}
+
+// keepToolResultsWithCalls moves cut, the index of the first message to keep,
+// back to the assistant message that made the tool calls answered by the
+// message at cut, so that they are kept together. It never moves cut before
+// start.
+func keepToolResultsWithCalls(messages []Message, start, cut int) int {
+	for cut > start && cut < len(messages) && hasToolResults(messages[cut]) {
+		cut--
+	}
+	return cut
+}
+
+func hasToolCalls(msg Message) bool {
+	for _, part := range msg.Parts {
+		if _, ok := part.(ToolCallPart); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func hasToolResults(msg Message) bool {
+	for _, part := range msg.Parts {
+		if _, ok := part.(ToolResultPart); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func isCompactionSummary(msg Message) bool {
+	return msg.Role == RoleSystem && strings.HasPrefix(messageText(msg), compactionSummaryPrefix)
+}
+
+// messageText returns the text parts of msg joined together.
+func messageText(msg Message) string {
+	var sb strings.Builder
+	for _, part := range msg.Parts {
+		if p, ok := part.(TextPart); ok {
+			sb.WriteString(p.Text)
+		}
+	}
+	return sb.String()
+}
+
+// transcript renders messages as plain text for the LLM to summarize.
+func transcript(messages []Message) string {
+	var sb strings.Builder
+	for _, msg := range messages {
+		fmt.Fprintf(&sb, "%s:", msg.Role)
+		for _, part := range msg.Parts {
+			switch p := part.(type) {
+			case TextPart:
+				fmt.Fprintf(&sb, " %s", p.Text)
+			case ToolCallPart:
+				fmt.Fprintf(&sb, " [called tool %s with %s]", p.Name, p.Input)
+			case ToolResultPart:
+				if p.Error != nil {
+					fmt.Fprintf(&sb, " [tool %s failed: %s]", p.Name, p.Result)
+				} else {
+					fmt.Fprintf(&sb, " [tool %s returned: %s]", p.Name, p.Result)
+				}
+			case ThinkingPart, CachePointPart:
+			default:
+				sb.WriteString(" [attachment]")
+			}
+		}
+		sb.WriteString("\n\n")
+	}
+	return strings.TrimSpace(sb.String())
+}
@@ -0,0 +1,100 @@
+package llms
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompactor(t *testing.T) {
+	call := ToolCallPart{ID: "call_1", Name: "echo", Input: []byte(`{"text":"hi"}`)}
+	messages := []Message{
+		NewTextMessage(RoleSystem, "system"),
+		NewTextMessage(RoleUser, "one"),
+		NewTextMessage(RoleAssistant, "two"),
+		NewTextMessage(RoleUser, "three"),
+		{Role: RoleAssistant, Parts: []Part{call}},
+		{Role: RoleUser, Parts: []Part{ToolResultPart{ToolCallID: "call_1", Name: "echo", Result: "hi"}}},
+		NewTextMessage(RoleAssistant, "four"),
+	}
+
+	fake := newFakeLLM(fakeResult{resp: textResponse("they counted")})
+	compactor := Compactor{LLM: fake, Counter: countMessages, KeepRecent: 2}
+
+	out, err := compactor.Fit(context.Background(), messages, 5)
+	require.NoError(t, err)
+
+	// The tool result is kept with its call, so three messages are kept.
+	require.Len(t, out, 5)
+	assert.Equal(t, messages[0], out[0])
+	assert.Equal(t, NewTextMessage(RoleSystem, "Summary of the earlier conversation:\n\nthey counted"), out[1])
+	assert.Equal(t, messages[4:], out[2:])
+
+	received := fake.Received()
+	require.Len(t, received, 2)
+	assert.Equal(t, NewTextMessage(RoleSystem, DefaultCompactionPrompt), received[0])
+	assert.Equal(t, NewTextMessage(RoleUser, "user: one\n\nassistant: two\n\nuser: three"), received[1])
+
+	// Compacting again folds the earlier summary into the new one.
+	fake = newFakeLLM(fakeResult{resp: textResponse("they counted more")})
+	compactor.LLM = fake
+	out = append(out, NewTextMessage(RoleUser, "five"), NewTextMessage(RoleAssistant, "six"))
+
+	out, err = compactor.Fit(context.Background(), out, 4)
+	require.NoError(t, err)
+	require.Len(t, out, 4)
+	assert.Equal(t, NewTextMessage(RoleSystem, "Summary of the earlier conversation:\n\nthey counted more"), out[1])
+	assert.Contains(t, fake.Received()[1].Parts[0].(TextPart).Text, "they counted\n\nassistant: [called tool echo with {\"text\":\"hi\"}]")
+}
+
+func TestCompactor_AlreadyFits(t *testing.T) {
+	fake := newFakeLLM(fakeResult{resp: textResponse("summary")})
+	messages := []Message{NewTextMessage(RoleUser, "hi")}
+
+	out, err := Compactor{LLM: fake}.Fit(context.Background(), messages, 100)
+	require.NoError(t, err)
+	assert.Equal(t, messages, out)
+	assert.Equal(t, 0, fake.Calls())
+}
+
+func TestCompactor_CannotFit(t *testing.T) {
+	fake := newFakeLLM(fakeResult{resp: textResponse("summary")})
+	messages := []Message{
+		NewTextMessage(RoleSystem, "system"),
+		NewTextMessage(RoleUser, "one"),
+		NewTextMessage(RoleAssistant, "two"),
+	}
+
+	// Nothing is old enough to summarize.
+	_, err := Compactor{LLM: fake, Counter: countMessages}.Fit(context.Background(), messages, 1)
+	assert.ErrorIs(t, err, ErrContextLimitExceeded)
+	assert.Equal(t, 0, fake.Calls())
+
+	// The summary doesn't make enough room.
+	_, err = Compactor{LLM: fake, Counter: countMessages, KeepRecent: 1}.Fit(context.Background(), messages, 2)
+	assert.ErrorIs(t, err, ErrContextLimitExceeded)
+}
+
+func TestConversation_Compact(t *testing.T) {
+	conv := NewConversation(newFakeLLM(),
+		NewTextMessage(RoleUser, "one"),
+		NewTextMessage(RoleAssistant, "two"),
+		NewTextMessage(RoleUser, "three"),
+	)
+
+	failing := newFakeLLM(fakeResult{err: errors.New("boom")})
+	err := conv.Compact(context.Background(), 2, Compactor{LLM: failing, Counter: countMessages, KeepRecent: 1})
+	assert.ErrorContains(t, err, "boom")
+	assert.Len(t, conv.Messages(), 3)
+
+	fake := newFakeLLM(fakeResult{resp: textResponse("summary")})
+	err = conv.Compact(context.Background(), 2, Compactor{LLM: fake, Counter: countMessages, KeepRecent: 1})
+	require.NoError(t, err)
+	assert.Equal(t, []Message{
+		NewTextMessage(RoleSystem, "Summary of the earlier conversation:\n\nsummary"),
+		NewTextMessage(RoleUser, "three"),
+	}, conv.Messages())
+}
@@ -0,0 +1,65 @@
+package compat
+
+import (
+	"github.com/openai/openai-go/option"
+
+	"github.com/llmite-ai/llms"
+	"github.com/llmite-ai/llms/openai"
+)
+
+// Quirk works around a way an OpenAI-compatible server differs from OpenAI's
+// API.
+type Quirk int
+
+const (
+	// NoParallelToolCalls removes parallel_tool_calls from requests, for
+	// servers that reject it.
+	NoParallelToolCalls Quirk = iota
+	// NoToolChoice removes tool_choice from requests, for servers that only
+	// let the model decide whether to call tools.
+	NoToolChoice
+	// NoResponseFormat removes response_format from requests, for servers
+	// that do not support structured output.
+	NoResponseFormat
+	// NoStreamUsage removes stream_options from streaming requests, for
+	// servers that reject it. Streamed responses then have no usage.
+	NoStreamUsage
+	// NoSeed removes seed from requests, for servers that reject it.
+	NoSeed
+)
+
+// fields maps each quirk to the request fields it removes.
+var fields = map[Quirk]string{
+	NoParallelToolCalls: "parallel_tool_calls",
+	NoToolChoice:        "tool_choice",
+	NoResponseFormat:    "response_format",
+	NoStreamUsage:       "stream_options",
+	NoSeed:              "seed",
+}
+
+// NewOpenAICompatible creates a client for a server implementing OpenAI's chat
+// completions API, such as Together, Fireworks, vLLM or LM Studio. baseURL is
+// the URL the API's paths are relative to, such as
+// "https://api.together.xyz/v1". apiKey may be empty for servers that do not
+// require one.
+//
+// The returned client is an *openai.Client, whose fields can be set to change
+// its other settings.
+func NewOpenAICompatible(baseURL, apiKey, model string, quirks ...Quirk) llms.LLM {
+	options := []option.RequestOption{
+		option.WithBaseURL(baseURL),
+		// The key is always set, so that OPENAI_API_KEY is never sent to
+		// another server.
+		option.WithAPIKey(apiKey),
+	}
+	for _, quirk := range quirks {
+		if field, ok := fields[quirk]; ok {
+			options = append(options, option.WithJSONDel(field))
+		}
+	}
+
+	return openai.New(
+		openai.WithModel(model),
+		openai.WithRequestOptions(options...),
+	)
+}
@@ -0,0 +1,86 @@
+package compat
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/llmite-ai/llms"
+	"github.com/llmite-ai/llms/openai"
+	"github.com/llmite-ai/llms/testutil"
+)
+
+func TestNewOpenAICompatible(t *testing.T) {
+	t.Setenv("OPENAI_API_KEY", "openai-key")
+
+	var body map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/chat/completions", r.URL.Path)
+		assert.Equal(t, "Bearer test", r.Header.Get("Authorization"))
+
+		body = nil
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+
+		if body["stream"] == true {
+			w.Header().Set("Content-Type", "text/event-stream")
+			fmt.Fprint(w, `data: {"id": "1", "object": "chat.completion.chunk", "choices": [{"index": 0, "delta": {"content": "Hi"}}]}`+"\n\n")
+			fmt.Fprint(w, "data: [DONE]\n\n")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{
+			"id": "1",
+			"object": "chat.completion",
+			"choices": [{"index": 0, "finish_reason": "stop", "message": {"role": "assistant", "content": "Hi"}}]
+		}`))
+	}))
+	defer server.Close()
+
+	llm := NewOpenAICompatible(server.URL+"/v1", "test", "llama-3.1-8b", NoParallelToolCalls, NoToolChoice, NoStreamUsage, NoSeed)
+	client := llm.(*openai.Client)
+	client.Tools = []llms.Tool{testutil.WeatherTool{}}
+	client.ToolChoice = &llms.ToolChoice{Type: llms.ToolChoiceAuto}
+	client.ParallelToolCalls = new(bool)
+	seed := int64(42)
+	client.Seed = &seed
+
+	messages := []llms.Message{llms.NewTextMessage(llms.RoleUser, "Hello")}
+
+	resp, err := llm.Generate(context.Background(), messages)
+	require.NoError(t, err)
+	assert.Equal(t, "Hi", resp.Message.Parts[0].(llms.TextPart).Text)
+	assert.Equal(t, "llama-3.1-8b", body["model"])
+	assert.Contains(t, body, "tools")
+	for _, field := range []string{"parallel_tool_calls", "tool_choice", "seed"} {
+		assert.NotContains(t, body, field)
+	}
+
+	_, err = llm.GenerateStream(context.Background(), messages, func(*llms.Response, error) bool { return true })
+	require.NoError(t, err)
+	assert.NotContains(t, body, "stream_options")
+}
+
+func TestNewOpenAICompatible_NoQuirks(t *testing.T) {
+	var body map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id": "1", "object": "chat.completion", "choices": [{"index": 0, "finish_reason": "stop", "message": {"role": "assistant", "content": "Hi"}}]}`))
+	}))
+	defer server.Close()
+
+	llm := NewOpenAICompatible(server.URL, "", "local-model")
+	llm.(*openai.Client).Tools = []llms.Tool{testutil.WeatherTool{}}
+	llm.(*openai.Client).ParallelToolCalls = new(bool)
+
+	_, err := llm.Generate(context.Background(), []llms.Message{llms.NewTextMessage(llms.RoleUser, "Hello")})
+	require.NoError(t, err)
+	assert.Equal(t, false, body["parallel_tool_calls"])
+}
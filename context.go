@@ -0,0 +1,183 @@
+package llms
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrContextLimitExceeded is returned when messages cannot be reduced to fit
+// within a token limit.
+var ErrContextLimitExceeded = errors.New("llms: messages exceed context limit")
+
+// TokenCounterFunc is an adapter to allow the use of ordinary functions as a
+// TokenCounter.
+type TokenCounterFunc func(ctx context.Context, messages []Message) (int, error)
+
+func (f TokenCounterFunc) CountTokens(ctx context.Context, messages []Message) (int, error) {
+	return f(ctx, messages)
+}
+
+// EstimatingTokenCounter is a TokenCounter backed by EstimateTokens. It needs no
+// network access, but is only approximate.
+var EstimatingTokenCounter TokenCounter = TokenCounterFunc(func(ctx context.Context, messages []Message) (int, error) {
+	return EstimateTokens(messages), nil
+})
+
+// ContextStrategy reduces a conversation so that it fits within a token limit.
+type ContextStrategy interface {
+	Fit(ctx context.Context, messages []Message, limit int) ([]Message, error)
+}
+
+// FitToContext returns messages reduced by strategy so that they fit within
+// limit tokens. The input slice is never modified.
+func FitToContext(ctx context.Context, messages []Message, limit int, strategy ContextStrategy) ([]Message, error) {
+	return strategy.Fit(ctx, messages, limit)
+}
+
+// TruncateOldest is a ContextStrategy that drops the oldest non-system messages
+// until the conversation fits. A message with tool calls is dropped together
+// with the tool results that follow it. System messages and the most recent
+// message are always kept.
+type TruncateOldest struct {
+	// Counter counts the tokens of the remaining messages. Defaults to
+	// EstimatingTokenCounter.
+	Counter TokenCounter
+}
+
+func (s TruncateOldest) Fit(ctx context.Context, messages []Message, limit int) ([]Message, error) {
+	counter := s.Counter
+	if counter == nil {
+		counter = EstimatingTokenCounter
+	}
+
+	out := append([]Message(nil), messages...)
+	for {
+		count, err := counter.CountTokens(ctx, out)
+		if err != nil {
+			return nil, fmt.Errorf("llms: failed to count tokens: %w", err)
+		}
+		if count <= limit {
+			return out, nil
+		}
+
+		idx := -1
+		for i, m := range out {
+			if m.Role != RoleSystem {
+				idx = i
+				break
+			}
+		}
+		end := idx + 1
+		if idx >= 0 && hasToolCalls(out[idx]) {
+			for end < len(out) && hasToolResults(out[end]) {
+				end++
+			}
+		}
+		if idx < 0 || end >= len(out) {
+			return nil, fmt.Errorf("%w: %d tokens remain with a limit of %d", ErrContextLimitExceeded, count, limit)
+		}
+
+		out = append(out[:idx], out[end:]...)
+	}
+}
+
+// SlidingWindow is a ContextStrategy that drops the oldest turns until the
+// conversation fits. A turn starts with a user message and includes the
+// replies, tool calls and tool results that follow it, so unlike
+// TruncateOldest it never leaves the conversation starting with an assistant
+// message or a tool result. System messages and the
+// most recent turn are always kept.
+type SlidingWindow struct {
+	// Counter counts the tokens of the remaining messages. Defaults to
+	// EstimatingTokenCounter.
+	Counter TokenCounter
+}
+
+func (s SlidingWindow) Fit(ctx context.Context, messages []Message, limit int) ([]Message, error) {
+	counter := s.Counter
+	if counter == nil {
+		counter = EstimatingTokenCounter
+	}
+
+	// turns holds the index of the first message of every turn. Messages
+	// before the first user message form a turn of their own.
+	var turns []int
+	for i, m := range messages {
+		if m.Role == RoleSystem {
+			continue
+		}
+		if len(turns) == 0 || (m.Role == RoleUser && !hasToolResults(m)) {
+			turns = append(turns, i)
+		}
+	}
+
+	for dropped := 0; ; dropped++ {
+		from := len(messages)
+		if dropped < len(turns) {
+			from = turns[dropped]
+		}
+
+		out := make([]Message, 0, len(messages))
+		for i, m := range messages {
+			if m.Role == RoleSystem || i >= from {
+				out = append(out, m)
+			}
+		}
+
+		count, err := counter.CountTokens(ctx, out)
+		if err != nil {
+			return nil, fmt.Errorf("llms: failed to count tokens: %w", err)
+		}
+		if count <= limit {
+			return out, nil
+		}
+		if dropped >= len(turns)-1 {
+			return nil, fmt.Errorf("%w: %d tokens remain with a limit of %d", ErrContextLimitExceeded, count, limit)
+		}
+	}
+}
+
+// WithContextLimit wraps llm so that messages are reduced with strategy to fit
+// within limit tokens before every request.
+func WithContextLimit(llm LLM, limit int, strategy ContextStrategy) LLM {
+	return &contextLimited{
+		llm:      llm,
+		limit:    limit,
+		strategy: strategy,
+	}
+}
+
+// ContextLimiting returns a Middleware that fits messages within limit tokens
+// before every request. See WithContextLimit.
+func ContextLimiting(limit int, strategy ContextStrategy) Middleware {
+	return func(llm LLM) LLM {
+		return WithContextLimit(llm, limit, strategy)
+	}
+}
+
+type contextLimited struct {
+	llm      LLM
+	limit    int
+	strategy ContextStrategy
+}
+
+func (c *contextLimited) ModelName() string {
+	return modelName(c.llm)
+}
+
+func (c *contextLimited) Generate(ctx context.Context, messages []Message) (*Response, error) {
+	messages, err := FitToContext(ctx, messages, c.limit, c.strategy)
+	if err != nil {
+		return nil, err
+	}
+	return c.llm.Generate(ctx, messages)
+}
+
+func (c *contextLimited) GenerateStream(ctx context.Context, messages []Message, fn StreamFunc) (*Response, error) {
+	messages, err := FitToContext(ctx, messages, c.limit, c.strategy)
+	if err != nil {
+		return nil, err
+	}
+	return c.llm.GenerateStream(ctx, messages, fn)
+}
@@ -0,0 +1,146 @@
+package llms
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countMessages counts every message as a single token.
+var countMessages = TokenCounterFunc(func(ctx context.Context, messages []Message) (int, error) {
+	return len(messages), nil
+})
+
+func TestTruncateOldest(t *testing.T) {
+	messages := []Message{
+		NewTextMessage(RoleSystem, "system"),
+		NewTextMessage(RoleUser, "one"),
+		NewTextMessage(RoleAssistant, "two"),
+		NewTextMessage(RoleUser, "three"),
+	}
+
+	out, err := FitToContext(context.Background(), messages, 2, TruncateOldest{Counter: countMessages})
+	require.NoError(t, err)
+	require.Len(t, out, 2)
+	assert.Equal(t, RoleSystem, out[0].Role)
+	assert.Equal(t, NewTextMessage(RoleUser, "three"), out[1])
+
+	// The input is left untouched.
+	assert.Len(t, messages, 4)
+}
+
+func TestTruncateOldest_AlreadyFits(t *testing.T) {
+	messages := []Message{NewTextMessage(RoleUser, "hi")}
+
+	out, err := FitToContext(context.Background(), messages, 100, TruncateOldest{})
+	require.NoError(t, err)
+	assert.Equal(t, messages, out)
+}
+
+func TestTruncateOldest_CannotFit(t *testing.T) {
+	messages := []Message{
+		NewTextMessage(RoleSystem, strings.Repeat("x", 400)),
+		NewTextMessage(RoleUser, "hi"),
+	}
+
+	_, err := FitToContext(context.Background(), messages, 10, TruncateOldest{})
+	assert.ErrorIs(t, err, ErrContextLimitExceeded)
+}
+
+func TestTruncateOldest_ToolCalls(t *testing.T) {
+	messages := []Message{
+		NewTextMessage(RoleUser, "one"),
+		{Role: RoleAssistant, Parts: []Part{ToolCallPart{ID: "call_1", Name: "echo", Input: []byte(`{}`)}}},
+		{Role: RoleUser, Parts: []Part{ToolResultPart{ToolCallID: "call_1", Name: "echo", Result: "hi"}}},
+		NewTextMessage(RoleAssistant, "two"),
+		NewTextMessage(RoleUser, "three"),
+	}
+
+	// Dropping the tool call drops its result with it.
+	out, err := FitToContext(context.Background(), messages, 2, TruncateOldest{Counter: countMessages})
+	require.NoError(t, err)
+	assert.Equal(t, messages[3:], out)
+}
+
+func TestTruncateOldest_Overhead(t *testing.T) {
+	// A counter with a fixed overhead never fits, even with no messages left.
+	counter := TokenCounterFunc(func(ctx context.Context, messages []Message) (int, error) {
+		return len(messages) + 3, nil
+	})
+
+	for _, messages := range [][]Message{nil, {NewTextMessage(RoleSystem, "system")}} {
+		_, err := FitToContext(context.Background(), messages, 2, TruncateOldest{Counter: counter})
+		assert.ErrorIs(t, err, ErrContextLimitExceeded)
+	}
+}
+
+func TestSlidingWindow(t *testing.T) {
+	messages := []Message{
+		NewTextMessage(RoleSystem, "system"),
+		NewTextMessage(RoleUser, "one"),
+		{Role: RoleAssistant, Parts: []Part{ToolCallPart{ID: "call_1", Name: "echo", Input: []byte(`{}`)}}},
+		{Role: RoleUser, Parts: []Part{ToolResultPart{ToolCallID: "call_1", Name: "echo", Result: "hi"}}},
+		NewTextMessage(RoleAssistant, "two"),
+		NewTextMessage(RoleUser, "three"),
+		{Role: RoleAssistant, Parts: []Part{ToolCallPart{ID: "call_2", Name: "echo", Input: []byte(`{}`)}}},
+		{Role: RoleUser, Parts: []Part{ToolResultPart{ToolCallID: "call_2", Name: "echo", Result: "hi"}}},
+		NewTextMessage(RoleSystem, "note"),
+		NewTextMessage(RoleAssistant, "four"),
+	}
+
+	// Dropping the first two messages would fit, but the whole first turn
+	// goes.
+	out, err := FitToContext(context.Background(), messages, 8, SlidingWindow{Counter: countMessages})
+	require.NoError(t, err)
+	assert.Equal(t, append([]Message{messages[0]}, messages[5:]...), out)
+
+	out, err = FitToContext(context.Background(), messages, 100, SlidingWindow{})
+	require.NoError(t, err)
+	assert.Equal(t, messages, out)
+
+	// The most recent turn is always kept.
+	_, err = FitToContext(context.Background(), messages, 5, SlidingWindow{Counter: countMessages})
+	assert.ErrorIs(t, err, ErrContextLimitExceeded)
+}
+
+func TestSlidingWindow_LeadingAssistant(t *testing.T) {
+	messages := []Message{
+		NewTextMessage(RoleAssistant, "hello"),
+		NewTextMessage(RoleUser, "one"),
+		NewTextMessage(RoleAssistant, "two"),
+	}
+
+	out, err := FitToContext(context.Background(), messages, 2, SlidingWindow{Counter: countMessages})
+	require.NoError(t, err)
+	assert.Equal(t, messages[1:], out)
+}
+
+func TestConversation_CompactSlidingWindow(t *testing.T) {
+	conv := NewConversation(newFakeLLM(),
+		NewTextMessage(RoleSystem, "system"),
+		NewTextMessage(RoleUser, "one"),
+		NewTextMessage(RoleAssistant, "two"),
+		NewTextMessage(RoleUser, "three"),
+	)
+
+	require.NoError(t, conv.Compact(context.Background(), 2, SlidingWindow{Counter: countMessages}))
+	assert.Equal(t, []Message{
+		NewTextMessage(RoleSystem, "system"),
+		NewTextMessage(RoleUser, "three"),
+	}, conv.Messages())
+}
+
+func TestWithContextLimit(t *testing.T) {
+	fake := newFakeLLM(fakeResult{resp: textResponse("ok")})
+	llm := WithContextLimit(fake, 1, TruncateOldest{Counter: countMessages})
+
+	_, err := llm.Generate(context.Background(), []Message{
+		NewTextMessage(RoleUser, "one"),
+		NewTextMessage(RoleUser, "two"),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []Message{NewTextMessage(RoleUser, "two")}, fake.Received())
+}
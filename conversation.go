@@ -0,0 +1,170 @@
+package llms
+
+import (
+	"context"
+	"sync"
+)
+
+// Conversation keeps the message history of a multi-turn exchange with an LLM,
+// appending every request and response so callers don't have to maintain the
+// []Message slice themselves. Send leaves tool calls to the caller, who answers
+// them with SendToolResults; Run executes them with Tools, as RunTools does. A
+// Conversation is safe for concurrent use, but turns are processed one at a
+// time.
+type Conversation struct {
+	LLM LLM
+	// Tools are the tools Run executes. They must also be configured on LLM
+	// so the model knows about them.
+	Tools []Tool
+	// ToolOptions configures how Run executes tools.
+	ToolOptions RunToolsOptions
+
+	mu       sync.Mutex
+	messages []Message
+}
+
+// NewConversation creates a Conversation with llm, optionally seeded with an
+// existing history (for example a system prompt).
+func NewConversation(llm LLM, history ...Message) *Conversation {
+	return &Conversation{
+		LLM:      llm,
+		messages: append([]Message(nil), history...),
+	}
+}
+
+// Messages returns a copy of the conversation history.
+func (c *Conversation) Messages() []Message {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return append([]Message(nil), c.messages...)
+}
+
+// Append adds messages to the history without sending anything.
+func (c *Conversation) Append(messages ...Message) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.messages = append(c.messages, messages...)
+}
+
+// Send adds text as a user message and generates the assistant's reply, which
+// is appended to the history. Tool calls in the reply are not executed; see
+// ToolCalls and SendToolResults, or use Run.
+func (c *Conversation) Send(ctx context.Context, text string) (*Response, error) {
+	return c.SendMessage(ctx, NewTextMessage(RoleUser, text))
+}
+
+// SendStream is like Send but streams the reply to fn.
+func (c *Conversation) SendStream(ctx context.Context, text string, fn StreamFunc) (*Response, error) {
+	return c.SendMessageStream(ctx, NewTextMessage(RoleUser, text), fn)
+}
+
+// SendToolResults sends the results of the tool calls in the previous
+// assistant message back to the LLM and appends its reply to the history.
+func (c *Conversation) SendToolResults(ctx context.Context, results ...ToolResultPart) (*Response, error) {
+	parts := make([]Part, 0, len(results))
+	for _, r := range results {
+		parts = append(parts, r)
+	}
+
+	return c.SendMessage(ctx, Message{Role: RoleUser, Parts: parts})
+}
+
+// SendMessage adds msg to the history and generates the assistant's reply.
+func (c *Conversation) SendMessage(ctx context.Context, msg Message) (*Response, error) {
+	return c.send(ctx, msg, func(messages []Message) (*Response, error) {
+		return c.LLM.Generate(ctx, messages)
+	})
+}
+
+// SendMessageStream is like SendMessage but streams the reply to fn.
+func (c *Conversation) SendMessageStream(ctx context.Context, msg Message, fn StreamFunc) (*Response, error) {
+	return c.send(ctx, msg, func(messages []Message) (*Response, error) {
+		return c.LLM.GenerateStream(ctx, messages, fn)
+	})
+}
+
+// Run adds text as a user message and, like RunTools, executes the tool calls
+// in the replies with c.Tools until the model replies without calling any
+// tools. Every reply and tool result is appended to the history.
+//
+//	conv := llms.NewConversation(client)
+//	conv.Tools = tools
+//	result, err := conv.Run(ctx, "What's the weather in Paris?")
+func (c *Conversation) Run(ctx context.Context, text string) (*RunResult, error) {
+	return c.RunMessage(ctx, NewTextMessage(RoleUser, text))
+}
+
+// RunMessage is like Run but adds msg to the history. If RunTools fails the
+// history is left unchanged, and the partial result is returned with the
+// error.
+func (c *Conversation) RunMessage(ctx context.Context, msg Message) (*RunResult, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	messages := append(append([]Message(nil), c.messages...), msg)
+
+	result, err := RunTools(ctx, c.LLM, messages, c.Tools, c.ToolOptions)
+	if err != nil {
+		return result, err
+	}
+
+	c.messages = result.Messages
+
+	return result, nil
+}
+
+// send appends msg and the reply produced by generate to the history. If
+// generate fails the history is left unchanged so the turn can be retried.
+func (c *Conversation) send(ctx context.Context, msg Message, generate func([]Message) (*Response, error)) (*Response, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	messages := append(append([]Message(nil), c.messages...), msg)
+
+	resp, err := generate(messages)
+	if err != nil {
+		return resp, err
+	}
+
+	c.messages = append(messages, resp.Message)
+
+	return resp, nil
+}
+
+// ToolCalls returns the tool calls requested in the most recent assistant
+// message, if any.
+func (c *Conversation) ToolCalls() []ToolCallPart {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for i := len(c.messages) - 1; i >= 0; i-- {
+		if c.messages[i].Role != RoleAssistant {
+			continue
+		}
+
+		return ToolCalls(c.messages[i])
+	}
+
+	return nil
+}
+
+// Compact fits the history within limit tokens using strategy, such as a
+// Compactor, replacing the history with the result. Unlike WithContextLimit,
+// which fits every request anew, the compacted history is kept, so it only
+// needs to be compacted again once it outgrows the limit. On error the history
+// is left unchanged.
+func (c *Conversation) Compact(ctx context.Context, limit int, strategy ContextStrategy) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	messages, err := strategy.Fit(ctx, c.messages, limit)
+	if err != nil {
+		return err
+	}
+
+	c.messages = messages
+
+	return nil
+}
@@ -0,0 +1,103 @@
+package llms
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConversation_Send(t *testing.T) {
+	fake := newFakeLLM(
+		fakeResult{resp: textResponse("first")},
+		fakeResult{resp: textResponse("second")},
+	)
+
+	conv := NewConversation(fake, NewTextMessage(RoleSystem, "Be brief."))
+
+	_, err := conv.Send(context.Background(), "hello")
+	require.NoError(t, err)
+	resp, err := conv.Send(context.Background(), "again")
+	require.NoError(t, err)
+	assert.Equal(t, "resp_second", resp.ID)
+
+	assert.Equal(t, []Message{
+		NewTextMessage(RoleSystem, "Be brief."),
+		NewTextMessage(RoleUser, "hello"),
+		NewTextMessage(RoleAssistant, "first"),
+		NewTextMessage(RoleUser, "again"),
+		NewTextMessage(RoleAssistant, "second"),
+	}, conv.Messages())
+
+	// The LLM saw the full history minus its own final reply.
+	assert.Len(t, fake.Received(), 4)
+}
+
+func TestConversation_FailedTurnLeavesHistory(t *testing.T) {
+	fake := newFakeLLM(fakeResult{err: errors.New("boom")})
+	conv := NewConversation(fake)
+
+	_, err := conv.Send(context.Background(), "hello")
+	require.Error(t, err)
+	assert.Empty(t, conv.Messages())
+}
+
+func TestConversation_ToolResults(t *testing.T) {
+	call := ToolCallPart{ID: "call_1", Name: "get_weather", Input: []byte(`{}`)}
+	fake := newFakeLLM(
+		fakeResult{resp: &Response{Message: Message{Role: RoleAssistant, Parts: []Part{call}}}},
+		fakeResult{resp: textResponse("sunny")},
+	)
+	conv := NewConversation(fake)
+
+	_, err := conv.Send(context.Background(), "weather?")
+	require.NoError(t, err)
+	assert.Equal(t, []ToolCallPart{call}, conv.ToolCalls())
+
+	resp, err := conv.SendToolResults(context.Background(), ToolResultPart{ToolCallID: "call_1", Name: "get_weather", Result: "sunny"})
+	require.NoError(t, err)
+	assert.Equal(t, "resp_sunny", resp.ID)
+	assert.Empty(t, conv.ToolCalls())
+
+	messages := conv.Messages()
+	require.Len(t, messages, 4)
+	assert.IsType(t, ToolResultPart{}, messages[2].Parts[0])
+}
+
+func TestConversation_Run(t *testing.T) {
+	fake := newFakeLLM(
+		fakeResult{resp: toolCallResponse(ToolCallPart{ID: "call_1", Name: "echo", Input: []byte(`{"text":"hi"}`)})},
+		fakeResult{resp: textResponse("done")},
+	)
+	conv := NewConversation(fake)
+	conv.Tools = []Tool{echoTool{}}
+
+	result, err := conv.Run(context.Background(), "echo hi")
+	require.NoError(t, err)
+	assert.Equal(t, 2, result.Turns)
+	assert.Equal(t, "resp_done", result.Response.ID)
+
+	messages := conv.Messages()
+	require.Len(t, messages, 4)
+	assert.Equal(t, NewTextMessage(RoleUser, "echo hi"), messages[0])
+	toolResult := messages[2].Parts[0].(ToolResultPart)
+	assert.Equal(t, "call_1", toolResult.ToolCallID)
+	assert.Equal(t, "hi", toolResult.Result)
+	assert.Equal(t, NewTextMessage(RoleAssistant, "done"), messages[3])
+}
+
+func TestConversation_RunFailureLeavesHistory(t *testing.T) {
+	fake := newFakeLLM(
+		fakeResult{resp: toolCallResponse(ToolCallPart{ID: "call_1", Name: "echo", Input: []byte(`{"text":"hi"}`)})},
+		fakeResult{err: errors.New("boom")},
+	)
+	conv := NewConversation(fake)
+	conv.Tools = []Tool{echoTool{}}
+
+	result, err := conv.Run(context.Background(), "echo hi")
+	require.Error(t, err)
+	assert.Len(t, result.Messages, 3)
+	assert.Empty(t, conv.Messages())
+}
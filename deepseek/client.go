@@ -0,0 +1,193 @@
+package deepseek
+
+import (
+	"context"
+	"os"
+	"strings"
+	"time"
+
+	oai "github.com/openai/openai-go"
+	"github.com/openai/openai-go/option"
+
+	"github.com/llmite-ai/llms"
+	"github.com/llmite-ai/llms/openai"
+)
+
+const ProviderDeepSeek = "deepseek"
+
+// DefaultBaseURL is the URL of DeepSeek's API.
+const DefaultBaseURL = "https://api.deepseek.com/"
+
+// now returns the time requests are made, which decides whether they are
+// billed at off-peak prices. It is replaced in tests.
+var now = time.Now
+
+// Client sends requests to DeepSeek. It wraps an openai.Client, whose settings
+// it supports, and also returns the reasoning of deepseek-reasoner as
+// llms.ThinkingPart and reports the cost of each request. The openai.Client
+// is not embedded, as DeepSeek has none of the other endpoints it supports,
+// such as embeddings and batches.
+type Client struct {
+	// Client is the openai.Client requests are sent with.
+	Client *openai.Client
+
+	apiKey     string
+	baseURL    string
+	openaiMods []openai.Modifier
+}
+
+type Modifier func(*Client)
+
+// WithAPIKey sets the API key sent with every request, overriding the
+// DEEPSEEK_API_KEY environment variable.
+func WithAPIKey(key string) Modifier {
+	return func(c *Client) {
+		c.apiKey = key
+	}
+}
+
+// WithBaseURL sends requests to url instead of DefaultBaseURL.
+func WithBaseURL(url string) Modifier {
+	return func(c *Client) {
+		c.baseURL = url
+	}
+}
+
+// WithModel sets the model requests are sent to, "deepseek-chat" (the default)
+// or "deepseek-reasoner".
+func WithModel(model string) Modifier {
+	return func(c *Client) {
+		c.openaiMods = append(c.openaiMods, openai.WithModel(model))
+	}
+}
+
+// WithOpenAIModifiers applies modifiers of the openai package, such as
+// openai.WithTools or openai.WithTemperature, to the client.
+func WithOpenAIModifiers(mods ...openai.Modifier) Modifier {
+	return func(c *Client) {
+		c.openaiMods = append(c.openaiMods, mods...)
+	}
+}
+
+// New creates a new DeepSeek client. The API key is read from the
+// DEEPSEEK_API_KEY environment variable unless WithAPIKey is used.
+func New(mods ...Modifier) llms.LLM {
+	c := &Client{
+		apiKey:  os.Getenv("DEEPSEEK_API_KEY"),
+		baseURL: DefaultBaseURL,
+	}
+
+	for _, mod := range mods {
+		mod(c)
+	}
+
+	openaiMods := append([]openai.Modifier{
+		openai.WithModel("deepseek-chat"),
+		openai.WithRequestOptions(
+			option.WithBaseURL(c.baseURL),
+			option.WithAPIKey(c.apiKey),
+		),
+	}, c.openaiMods...)
+	c.Client = openai.New(openaiMods...).(*openai.Client)
+
+	return c
+}
+
+// ModelName returns the model requests are sent to.
+func (c *Client) ModelName() string {
+	return c.Client.ModelName()
+}
+
+// CountTokens returns an estimate of the number of input tokens messages would
+// use. See openai.Client.CountTokens.
+func (c *Client) CountTokens(ctx context.Context, messages []llms.Message) (int, error) {
+	return c.Client.CountTokens(ctx, messages)
+}
+
+func (c *Client) Generate(ctx context.Context, messages []llms.Message) (*llms.Response, error) {
+	start := now()
+
+	resp, err := c.Client.Generate(ctx, messages)
+	if resp == nil {
+		return resp, err
+	}
+
+	resp.Provider = ProviderDeepSeek
+	if raw, ok := resp.Raw.(*oai.ChatCompletion); ok && len(raw.Choices) > 0 {
+		var reasoning string
+		openai.DecodeExtraField(raw.Choices[0].Message.JSON.ExtraFields["reasoning_content"], &reasoning)
+		resp.Message.Parts = withReasoning(resp.Message.Parts, reasoning)
+	}
+	c.convertUsage(resp, start)
+
+	return resp, err
+}
+
+func (c *Client) GenerateStream(ctx context.Context, messages []llms.Message, fn llms.StreamFunc) (*llms.Response, error) {
+	start := now()
+
+	// The reasoning is streamed before the answer, in a field the openai
+	// client does not accumulate.
+	var reasoning strings.Builder
+	resp, err := c.Client.GenerateStream(ctx, messages, func(resp *llms.Response, err error) bool {
+		if resp != nil {
+			resp.Provider = ProviderDeepSeek
+			if chunk, ok := resp.Raw.(oai.ChatCompletionChunk); ok && len(chunk.Choices) > 0 {
+				var delta string
+				openai.DecodeExtraField(chunk.Choices[0].Delta.JSON.ExtraFields["reasoning_content"], &delta)
+				reasoning.WriteString(delta)
+			}
+			resp.Message.Parts = withReasoning(resp.Message.Parts, reasoning.String())
+		}
+		return fn(resp, err)
+	})
+	if resp == nil {
+		return resp, err
+	}
+
+	resp.Provider = ProviderDeepSeek
+	resp.Message.Parts = withReasoning(resp.Message.Parts, reasoning.String())
+	c.convertUsage(resp, start)
+
+	return resp, err
+}
+
+// withReasoning returns parts with reasoning as their first part, replacing
+// the reasoning added to them before.
+func withReasoning(parts []llms.Part, reasoning string) []llms.Part {
+	if reasoning == "" {
+		return parts
+	}
+
+	out := make([]llms.Part, 0, len(parts)+1)
+	out = append(out, llms.ThinkingPart{Text: reasoning})
+	for _, part := range parts {
+		if _, ok := part.(llms.ThinkingPart); !ok {
+			out = append(out, part)
+		}
+	}
+	return out
+}
+
+// convertUsage adds the tokens read from DeepSeek's context cache and the cost
+// of a request made at start to the usage of resp.
+func (c *Client) convertUsage(resp *llms.Response, start time.Time) {
+	if resp.Usage == nil {
+		return
+	}
+
+	model := c.ModelName()
+	var usage oai.CompletionUsage
+	switch raw := resp.Raw.(type) {
+	case *oai.ChatCompletion:
+		model, usage = raw.Model, raw.Usage
+	case oai.ChatCompletionChunk:
+		model, usage = raw.Model, raw.Usage
+	}
+
+	var cacheHits int
+	if openai.DecodeExtraField(usage.JSON.ExtraFields["prompt_cache_hit_tokens"], &cacheHits) {
+		resp.Usage.CacheReadInputTokens = cacheHits
+	}
+	resp.Usage.Cost = Cost(model, resp.Usage, start)
+}
@@ -0,0 +1,50 @@
+//go:build integration
+// +build integration
+
+package deepseek
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/llmite-ai/llms"
+	"github.com/llmite-ai/llms/openai"
+	"github.com/llmite-ai/llms/testutil"
+)
+
+func TestDeepSeekClientIntegration(t *testing.T) {
+	testutil.RequireAPIKey(t, "DEEPSEEK_API_KEY")
+
+	t.Run("simple generation", func(t *testing.T) {
+		client := New(WithOpenAIModifiers(openai.WithHTTPClient(testutil.NewRecordingClient(t, "simple_generation"))))
+		messages := []llms.Message{llms.NewTextMessage(llms.RoleUser, "What is the capital of France?")}
+
+		response, err := client.Generate(context.Background(), messages)
+		require.NoError(t, err)
+		assert.Equal(t, ProviderDeepSeek, response.Provider)
+		require.NotEmpty(t, response.Message.Parts)
+		assert.Contains(t, response.Message.Parts[0].(llms.TextPart).Text, "Paris")
+		require.NotNil(t, response.Usage)
+		assert.Greater(t, response.Usage.Cost, 0.0)
+	})
+
+	t.Run("streaming reasoning", func(t *testing.T) {
+		client := New(
+			WithModel("deepseek-reasoner"),
+			WithOpenAIModifiers(openai.WithHTTPClient(testutil.NewRecordingClient(t, "streaming_reasoning"))),
+		)
+		messages := []llms.Message{llms.NewTextMessage(llms.RoleUser, "What is 17 * 23?")}
+
+		response, err := client.GenerateStream(context.Background(), messages, func(r *llms.Response, err error) bool {
+			return true
+		})
+		require.NoError(t, err)
+		require.NotEmpty(t, response.Message.Parts)
+		thinking, ok := response.Message.Parts[0].(llms.ThinkingPart)
+		require.True(t, ok)
+		assert.NotEmpty(t, thinking.Text)
+	})
+}
@@ -0,0 +1,124 @@
+package deepseek
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/openai/openai-go/option"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/llmite-ai/llms"
+	"github.com/llmite-ai/llms/openai"
+)
+
+// newTestClient returns a client that sends requests to a test server running
+// handler, at a time billed at standard prices.
+func newTestClient(t *testing.T, handler http.HandlerFunc, mods ...Modifier) *Client {
+	t.Helper()
+
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	now = func() time.Time { return time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC) }
+	t.Cleanup(func() { now = time.Now })
+
+	mods = append([]Modifier{
+		WithBaseURL(server.URL),
+		WithAPIKey("test"),
+		WithOpenAIModifiers(openai.WithRequestOptions(option.WithMaxRetries(0))),
+	}, mods...)
+	return New(mods...).(*Client)
+}
+
+func TestGenerate_Reasoning(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/chat/completions", r.URL.Path)
+		assert.Equal(t, "Bearer test", r.Header.Get("Authorization"))
+
+		var body map[string]any
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		assert.Equal(t, "deepseek-reasoner", body["model"])
+		// Reasoning from earlier turns is not sent back.
+		assert.NotContains(t, fmt.Sprint(body["messages"]), "reasoning")
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{
+			"id": "1",
+			"object": "chat.completion",
+			"model": "deepseek-reasoner",
+			"choices": [{"index": 0, "finish_reason": "stop", "message": {"role": "assistant", "reasoning_content": "2 + 2 is 4.", "content": "4"}}],
+			"usage": {"prompt_tokens": 1000000, "completion_tokens": 1000000, "total_tokens": 2000000, "prompt_cache_hit_tokens": 400000, "prompt_cache_miss_tokens": 600000}
+		}`))
+	}, WithModel("deepseek-reasoner"))
+
+	messages := []llms.Message{
+		llms.NewTextMessage(llms.RoleUser, "What is 1 + 1?"),
+		{Role: llms.RoleAssistant, Parts: []llms.Part{llms.ThinkingPart{Text: "Some reasoning"}, llms.TextPart{Text: "2"}}},
+		llms.NewTextMessage(llms.RoleUser, "What is 2 + 2?"),
+	}
+	resp, err := client.Generate(context.Background(), messages)
+	require.NoError(t, err)
+	assert.Equal(t, ProviderDeepSeek, resp.Provider)
+	assert.Equal(t, []llms.Part{
+		llms.ThinkingPart{Text: "2 + 2 is 4."},
+		llms.TextPart{Text: "4"},
+	}, resp.Message.Parts)
+	assert.Equal(t, 1000000, resp.Usage.InputTokens)
+	assert.Equal(t, 400000, resp.Usage.CacheReadInputTokens)
+	assert.InDelta(t, 0.4*0.14+0.6*0.55+2.19, resp.Usage.Cost, 1e-9)
+}
+
+func TestGenerateStream_Reasoning(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, chunk := range []string{
+			`{"id": "1", "object": "chat.completion.chunk", "model": "deepseek-reasoner", "choices": [{"index": 0, "delta": {"role": "assistant", "content": null, "reasoning_content": "2 + 2"}}]}`,
+			`{"id": "1", "object": "chat.completion.chunk", "model": "deepseek-reasoner", "choices": [{"index": 0, "delta": {"content": null, "reasoning_content": " is 4."}}]}`,
+			`{"id": "1", "object": "chat.completion.chunk", "model": "deepseek-reasoner", "choices": [{"index": 0, "delta": {"content": "4", "reasoning_content": null}}]}`,
+			`{"id": "1", "object": "chat.completion.chunk", "model": "deepseek-reasoner", "choices": [], "usage": {"prompt_tokens": 10, "completion_tokens": 20, "total_tokens": 30, "prompt_cache_hit_tokens": 0}}`,
+		} {
+			fmt.Fprintf(w, "data: %s\n\n", chunk)
+		}
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}, WithModel("deepseek-reasoner"))
+
+	var thinking []string
+	resp, err := client.GenerateStream(context.Background(), []llms.Message{llms.NewTextMessage(llms.RoleUser, "What is 2 + 2?")}, func(r *llms.Response, err error) bool {
+		require.NoError(t, err)
+		assert.Equal(t, ProviderDeepSeek, r.Provider)
+		require.NotEmpty(t, r.Message.Parts)
+		thinking = append(thinking, r.Message.Parts[0].(llms.ThinkingPart).Text)
+		return true
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"2 + 2", "2 + 2 is 4.", "2 + 2 is 4."}, thinking)
+	assert.Equal(t, []llms.Part{
+		llms.ThinkingPart{Text: "2 + 2 is 4."},
+		llms.TextPart{Text: "4"},
+	}, resp.Message.Parts)
+	assert.InDelta(t, (10*0.55+20*2.19)/1e6, resp.Usage.Cost, 1e-12)
+}
+
+func TestCapabilities(t *testing.T) {
+	var llm llms.LLM = New()
+
+	// Only the endpoints the provider has are exposed.
+	_, ok := llm.(llms.TokenCounter)
+	assert.True(t, ok)
+	_, ok = llm.(llms.Embedder)
+	assert.False(t, ok)
+	_, ok = llm.(llms.ImageGenerator)
+	assert.False(t, ok)
+	_, ok = llm.(llms.Speaker)
+	assert.False(t, ok)
+	_, ok = llm.(llms.Transcriber)
+	assert.False(t, ok)
+	_, ok = llm.(llms.BatchLLM)
+	assert.False(t, ok)
+}
@@ -0,0 +1,73 @@
+package deepseek
+
+import (
+	"time"
+
+	"github.com/llmite-ai/llms"
+)
+
+// Pricing is the price of a model in US dollars per million tokens.
+type Pricing struct {
+	// CacheHitInput is the price of input tokens read from DeepSeek's context
+	// cache.
+	CacheHitInput float64
+	// CacheMissInput is the price of the other input tokens.
+	CacheMissInput float64
+	Output         float64
+}
+
+// ModelPricing is the price of a model during standard hours and during
+// DeepSeek's off-peak discount window.
+type ModelPricing struct {
+	Standard Pricing
+	OffPeak  Pricing
+}
+
+// Prices holds the pricing of DeepSeek's models, keyed by model name. Entries
+// can be changed or added when DeepSeek changes its prices.
+var Prices = map[string]ModelPricing{
+	"deepseek-chat": {
+		Standard: Pricing{CacheHitInput: 0.07, CacheMissInput: 0.27, Output: 1.10},
+		OffPeak:  Pricing{CacheHitInput: 0.035, CacheMissInput: 0.135, Output: 0.55},
+	},
+	"deepseek-reasoner": {
+		Standard: Pricing{CacheHitInput: 0.14, CacheMissInput: 0.55, Output: 2.19},
+		OffPeak:  Pricing{CacheHitInput: 0.035, CacheMissInput: 0.135, Output: 0.55},
+	},
+}
+
+// IsOffPeak reports whether a request made at t is billed at off-peak prices,
+// which apply from 16:30 to 00:30 UTC.
+func IsOffPeak(t time.Time) bool {
+	t = t.UTC()
+	minutes := t.Hour()*60 + t.Minute()
+	return minutes >= 16*60+30 || minutes < 30
+}
+
+// PricingAt returns the pricing of model for a request made at t, and whether
+// the model's pricing is known.
+func PricingAt(model string, t time.Time) (Pricing, bool) {
+	prices, ok := Prices[model]
+	if !ok {
+		return Pricing{}, false
+	}
+	if IsOffPeak(t) {
+		return prices.OffPeak, true
+	}
+	return prices.Standard, true
+}
+
+// Cost returns the cost in US dollars of a request to model made at t that
+// used usage, or 0 if the model's pricing is unknown.
+func Cost(model string, usage *llms.Usage, t time.Time) float64 {
+	pricing, ok := PricingAt(model, t)
+	if !ok || usage == nil {
+		return 0
+	}
+
+	cacheMisses := usage.InputTokens - usage.CacheReadInputTokens
+	cost := float64(usage.CacheReadInputTokens)*pricing.CacheHitInput +
+		float64(cacheMisses)*pricing.CacheMissInput +
+		float64(usage.OutputTokens)*pricing.Output
+	return cost / 1_000_000
+}
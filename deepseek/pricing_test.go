@@ -0,0 +1,47 @@
+package deepseek
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/llmite-ai/llms"
+)
+
+func TestIsOffPeak(t *testing.T) {
+	tests := []struct {
+		hour, minute int
+		want         bool
+	}{
+		{16, 29, false},
+		{16, 30, true},
+		{23, 59, true},
+		{0, 29, true},
+		{0, 30, false},
+		{12, 0, false},
+	}
+	for _, tt := range tests {
+		at := time.Date(2025, 6, 1, tt.hour, tt.minute, 0, 0, time.UTC)
+		assert.Equal(t, tt.want, IsOffPeak(at), at)
+	}
+
+	// The window is in UTC whatever the location of t.
+	beijing := time.FixedZone("CST", 8*60*60)
+	assert.True(t, IsOffPeak(time.Date(2025, 6, 2, 0, 30, 0, 0, beijing)))
+}
+
+func TestCost(t *testing.T) {
+	usage := &llms.Usage{InputTokens: 2_000_000, CacheReadInputTokens: 1_000_000, OutputTokens: 1_000_000}
+	peak := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	offPeak := time.Date(2025, 6, 1, 18, 0, 0, 0, time.UTC)
+
+	assert.InDelta(t, 0.07+0.27+1.10, Cost("deepseek-chat", usage, peak), 1e-9)
+	assert.InDelta(t, 0.035+0.135+0.55, Cost("deepseek-chat", usage, offPeak), 1e-9)
+	assert.Zero(t, Cost("unknown", usage, peak))
+	assert.Zero(t, Cost("deepseek-chat", nil, peak))
+
+	pricing, ok := PricingAt("deepseek-reasoner", offPeak)
+	assert.True(t, ok)
+	assert.Equal(t, Prices["deepseek-reasoner"].OffPeak, pricing)
+}
@@ -0,0 +1,188 @@
+package llms
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DumpConfig configures a DumpTransport.
+type DumpConfig struct {
+	// Dir is the directory interactions are written to. It is created if it
+	// does not exist.
+	Dir string
+	// Transport sends the requests. Defaults to http.DefaultTransport.
+	Transport http.RoundTripper
+	// Sanitize, if set, is called on every interaction after the default
+	// redactions and before it is written.
+	Sanitize func(*Interaction)
+	// OnError, if set, is called when an interaction cannot be written.
+	// Such errors do not fail the request.
+	OnError func(error)
+}
+
+// DumpTransport is an http.RoundTripper that writes every request and its
+// response to a numbered file in a directory, such as "000042.json", so that
+// failing production requests can be replayed. Each file is a fixture holding
+// a single interaction, which a Recorder in RecorderModeReplay can serve:
+//
+//	recorder, err := llms.NewRecorder(llms.RecorderConfig{Path: "dump/000042.json"})
+//
+// Responses are passed to the caller as they arrive, and written once their
+// body has been read or closed, so streamed responses are recorded in full
+// without being delayed. Requests that fail without a response are not
+// written. Numbering continues from the highest numbered file already in the
+// directory. It is safe for concurrent use.
+type DumpTransport struct {
+	config DumpConfig
+
+	once sync.Once
+	mu   sync.Mutex
+	next int
+}
+
+// NewDumpTransport creates a DumpTransport.
+func NewDumpTransport(config DumpConfig) *DumpTransport {
+	if config.Transport == nil {
+		config.Transport = http.DefaultTransport
+	}
+	return &DumpTransport{config: config}
+}
+
+// RoundTrip implements the http.RoundTripper interface
+func (d *DumpTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		req = req.Clone(req.Context())
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	// Files are numbered in the order requests are sent, not the order
+	// their responses complete.
+	n := d.number()
+
+	recorded := RecordedRequest{
+		Method: req.Method,
+		URL:    sanitizeURL(req.URL),
+		Header: sanitizeHeader(req.Header),
+		Body:   string(body),
+	}
+
+	resp, err := d.config.Transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	resp.Body = &dumpBody{
+		body: resp.Body,
+		write: func(respBody []byte) {
+			d.write(n, Interaction{
+				Request: recorded,
+				Response: RecordedResponse{
+					StatusCode: resp.StatusCode,
+					Header:     sanitizeHeader(resp.Header),
+					Body:       string(respBody),
+				},
+			})
+		},
+	}
+	return resp, nil
+}
+
+// number returns the number of the next file.
+func (d *DumpTransport) number() int {
+	d.once.Do(func() {
+		d.next = lastDumpNumber(d.config.Dir) + 1
+	})
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	n := d.next
+	d.next++
+	return n
+}
+
+func (d *DumpTransport) write(n int, in Interaction) {
+	if d.config.Sanitize != nil {
+		d.config.Sanitize(&in)
+	}
+
+	err := func() error {
+		data, err := json.MarshalIndent([]Interaction{in}, "", "  ")
+		if err != nil {
+			return err
+		}
+		if err := os.MkdirAll(d.config.Dir, 0o755); err != nil {
+			return err
+		}
+		return os.WriteFile(filepath.Join(d.config.Dir, fmt.Sprintf("%06d.json", n)), data, 0o644)
+	}()
+	if err != nil && d.config.OnError != nil {
+		d.config.OnError(fmt.Errorf("llms: failed to dump interaction %d: %w", n, err))
+	}
+}
+
+// lastDumpNumber returns the highest number of the files in dir, or 0.
+func lastDumpNumber(dir string) int {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return 0
+	}
+
+	last := 0
+	for _, entry := range entries {
+		name, ok := strings.CutSuffix(entry.Name(), ".json")
+		if !ok {
+			continue
+		}
+		if n, err := strconv.Atoi(name); err == nil && n > last {
+			last = n
+		}
+	}
+	return last
+}
+
+// dumpBody copies a response body as it is read, and passes the copy to write
+// once the body has been read in full or closed.
+type dumpBody struct {
+	body  io.ReadCloser
+	write func([]byte)
+
+	buf  bytes.Buffer
+	done bool
+}
+
+func (b *dumpBody) Read(p []byte) (int, error) {
+	n, err := b.body.Read(p)
+	b.buf.Write(p[:n])
+	if err != nil {
+		b.finish()
+	}
+	return n, err
+}
+
+func (b *dumpBody) Close() error {
+	b.finish()
+	return b.body.Close()
+}
+
+func (b *dumpBody) finish() {
+	if b.done {
+		return
+	}
+	b.done = true
+	b.write(b.buf.Bytes())
+}
@@ -0,0 +1,87 @@
+package llms
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDumpTransport(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte("data: {\"n\": 1}\n\n"))
+		w.(http.Flusher).Flush()
+		w.Write([]byte("data: {\"n\": 2}\n\n"))
+	}))
+	defer server.Close()
+
+	dir := filepath.Join(t.TempDir(), "dump")
+	client := NewHTTPClient(HTTPClientOptions{Dump: &DumpConfig{Dir: dir}})
+
+	post := func(client *http.Client, body string) string {
+		req, err := http.NewRequest(http.MethodPost, server.URL+"/v1/messages", strings.NewReader(body))
+		require.NoError(t, err)
+		req.Header.Set("Authorization", "Bearer secret")
+		resp, err := client.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		data, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return string(data)
+	}
+
+	want := post(client, `{"prompt": "one"}`)
+	post(client, `{"prompt": "two"}`)
+	// A new transport continues the numbering.
+	post(NewHTTPClient(HTTPClientOptions{Dump: &DumpConfig{Dir: dir}}), `{"prompt": "three"}`)
+
+	names, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	require.NoError(t, err)
+	for i := range names {
+		names[i] = filepath.Base(names[i])
+	}
+	assert.Equal(t, []string{"000001.json", "000002.json", "000003.json"}, names)
+
+	data, err := os.ReadFile(filepath.Join(dir, "000001.json"))
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "secret")
+
+	// The dumped interaction replays the streamed response in full.
+	recorder, err := NewRecorder(RecorderConfig{Path: filepath.Join(dir, "000001.json")})
+	require.NoError(t, err)
+	assert.Equal(t, want, post(recorder.Client(), `{"prompt": "one"}`))
+}
+
+func TestDumpTransport_OnError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	// A file where the directory should be makes writing fail.
+	dir := filepath.Join(t.TempDir(), "dump")
+	require.NoError(t, os.WriteFile(dir, nil, 0o644))
+
+	var errs []error
+	client := &http.Client{Transport: NewDumpTransport(DumpConfig{
+		Dir:     dir,
+		OnError: func(err error) { errs = append(errs, err) },
+	})}
+
+	resp, err := client.Get(server.URL)
+	require.NoError(t, err)
+	data, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	assert.Equal(t, "ok", string(data))
+	require.Len(t, errs, 1)
+	assert.ErrorContains(t, errs[0], "failed to dump interaction 1")
+}
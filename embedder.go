@@ -0,0 +1,47 @@
+package llms
+
+import (
+	"context"
+	"errors"
+	"math"
+)
+
+// Embedder turns text into embedding vectors, whose distance reflects how
+// close in meaning the texts are. Providers that offer embedding models
+// implement it, so that code built on embeddings, such as semantic search or
+// caching, works with any of them.
+type Embedder interface {
+	// Embed returns the embedding of text.
+	Embed(ctx context.Context, text string) ([]float32, error)
+	// EmbedBatch returns the embeddings of texts, in the same order, using as
+	// few requests as the provider allows.
+	EmbedBatch(ctx context.Context, texts []string) ([][]float32, error)
+	// Dimensions returns the length of the vectors, or 0 if it is not known
+	// before the first request.
+	Dimensions() int
+}
+
+// ErrDimensionMismatch is returned by CosineSimilarity for vectors of
+// different lengths, which come from different models or settings.
+var ErrDimensionMismatch = errors.New("llms: embeddings have different dimensions")
+
+// CosineSimilarity returns the cosine similarity of two embeddings, from -1
+// for opposite meanings to 1 for the same. Zero vectors have a similarity of
+// 0.
+func CosineSimilarity(a, b []float32) (float64, error) {
+	if len(a) != len(b) {
+		return 0, ErrDimensionMismatch
+	}
+
+	var dot, normA, normB float64
+	for i := range a {
+		x, y := float64(a[i]), float64(b[i])
+		dot += x * y
+		normA += x * x
+		normB += y * y
+	}
+	if normA == 0 || normB == 0 {
+		return 0, nil
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB)), nil
+}
@@ -0,0 +1,29 @@
+package llms
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCosineSimilarity(t *testing.T) {
+	sim, err := CosineSimilarity([]float32{1, 2, 3}, []float32{2, 4, 6})
+	require.NoError(t, err)
+	assert.InDelta(t, 1, sim, 1e-9)
+
+	sim, err = CosineSimilarity([]float32{1, 0}, []float32{0, 1})
+	require.NoError(t, err)
+	assert.InDelta(t, 0, sim, 1e-9)
+
+	sim, err = CosineSimilarity([]float32{1, 0}, []float32{-1, 0})
+	require.NoError(t, err)
+	assert.InDelta(t, -1, sim, 1e-9)
+
+	sim, err = CosineSimilarity([]float32{0, 0}, []float32{1, 0})
+	require.NoError(t, err)
+	assert.Zero(t, sim)
+
+	_, err = CosineSimilarity([]float32{1}, []float32{1, 2})
+	assert.ErrorIs(t, err, ErrDimensionMismatch)
+}
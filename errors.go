@@ -0,0 +1,109 @@
+package llms
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// APIError is returned by providers when the upstream API responds with a non
+// successful HTTP status code. The original provider SDK error is available via
+// errors.Unwrap / errors.As.
+type APIError struct {
+	Provider   string
+	StatusCode int
+	// RetryAfter is the delay requested by the provider via the Retry-After
+	// family of headers, or 0 if none was sent.
+	RetryAfter time.Duration
+	Err        error
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("%s: api error (status %d): %v", e.Provider, e.StatusCode, e.Err)
+}
+
+func (e *APIError) Unwrap() error {
+	return e.Err
+}
+
+// IsRetryable reports whether err is likely to be transient, meaning the same
+// request may succeed if it is retried or sent to another provider. Rate limits
+// (429), overloaded (529), server errors (5xx) and timeouts are considered
+// retryable. Context cancellation and ErrCircuitOpen are never retryable, as
+// retrying would only hit the same open circuit.
+func IsRetryable(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+
+	if errors.Is(err, ErrCircuitOpen) {
+		return false
+	}
+
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		switch {
+		case apiErr.StatusCode == http.StatusTooManyRequests:
+			return true
+		case apiErr.StatusCode == http.StatusRequestTimeout:
+			return true
+		case apiErr.StatusCode >= 500:
+			return true
+		}
+		return false
+	}
+
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+
+	return false
+}
+
+// ParseRetryAfter returns the delay requested by the retry-after-ms or
+// Retry-After response headers. Retry-After may either be a number of seconds
+// or an HTTP date. It returns 0 if neither header is present or valid.
+func ParseRetryAfter(header http.Header) time.Duration {
+	if header == nil {
+		return 0
+	}
+
+	if v := header.Get("retry-after-ms"); v != "" {
+		if ms, err := strconv.ParseFloat(v, 64); err == nil && ms > 0 {
+			return time.Duration(ms * float64(time.Millisecond))
+		}
+	}
+
+	v := header.Get("Retry-After")
+	if v == "" {
+		return 0
+	}
+
+	if secs, err := strconv.ParseFloat(v, 64); err == nil {
+		if secs <= 0 {
+			return 0
+		}
+		return time.Duration(secs * float64(time.Second))
+	}
+
+	if t, err := http.ParseTime(v); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d
+		}
+	}
+
+	return 0
+}
@@ -0,0 +1,116 @@
+package llms
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ExtractOptions configures Extract.
+type ExtractOptions struct {
+	// Instructions, if set, are added to the system prompt, for example to
+	// explain how to fill in fields the text doesn't mention.
+	Instructions string
+	// MaxRetries is the maximum number of times the model is asked to correct
+	// an answer that doesn't match the schema. Defaults to 2. A negative value
+	// disables retries.
+	MaxRetries int
+}
+
+const extractPrompt = `Extract information from the text given by the user. Reply with a single JSON value matching this JSON schema, and nothing else:
+
+`
+
+// Extract asks llm to extract a T from text. The model is given the schema
+// of T, as generated by GenerateSchema, and its answer is validated against
+// it, after fixing malformed JSON with RepairJSON. An invalid answer is sent
+// back to the model with the validation errors for it to correct, up to
+// opts.MaxRetries times, after which Extract fails with the last
+// *ValidationError. Repaired reports whether the accepted answer needed
+// fixing by RepairJSON.
+//
+//	type Contact struct {
+//		Name  string `json:"name"`
+//		Email string `json:"email,omitempty"`
+//	}
+//
+//	contact, _, err := llms.Extract[Contact](ctx, client, email, llms.ExtractOptions{})
+func Extract[T any](ctx context.Context, llm LLM, text string, opts ExtractOptions) (out T, repaired bool, err error) {
+	var zero T
+	if opts.MaxRetries == 0 {
+		opts.MaxRetries = 2
+	}
+
+	schema := GenerateSchema[T]()
+	schemaJSON, err := json.Marshal(schema)
+	if err != nil {
+		return zero, false, fmt.Errorf("llms: failed to marshal schema: %w", err)
+	}
+
+	prompt := extractPrompt + string(schemaJSON)
+	if opts.Instructions != "" {
+		prompt += "\n\n" + opts.Instructions
+	}
+	messages := []Message{
+		NewTextMessage(RoleSystem, prompt),
+		NewTextMessage(RoleUser, text),
+	}
+
+	for attempt := 0; ; attempt++ {
+		resp, err := llm.Generate(ctx, messages)
+		if err != nil {
+			return zero, false, err
+		}
+
+		data := []byte(extractJSON(messageText(resp.Message)))
+		fixed, wasRepaired, repairErr := RepairJSON(data)
+		if repairErr == nil {
+			data = fixed
+		}
+		err = validateJSON("", schema, data, true)
+		if err == nil {
+			var out T
+			if err := json.Unmarshal(data, &out); err != nil {
+				return zero, false, fmt.Errorf("llms: failed to decode extracted %T: %w", out, err)
+			}
+			return out, repairErr == nil && wasRepaired, nil
+		}
+
+		var validationErr *ValidationError
+		if !errors.As(err, &validationErr) {
+			return zero, false, err
+		}
+		if attempt >= opts.MaxRetries {
+			return zero, false, fmt.Errorf("llms: failed to extract %T after %d attempts: %w", zero, attempt+1, err)
+		}
+
+		messages = append(messages, resp.Message, NewTextMessage(RoleUser, fmt.Sprintf(
+			"Your answer was invalid: %s. Reply with the corrected JSON only.", err)))
+	}
+}
+
+// extractJSON returns the JSON value in a model's answer, without the code
+// fence or surrounding prose models tend to add.
+func extractJSON(text string) string {
+	text = strings.TrimSpace(text)
+	if rest, ok := strings.CutPrefix(text, "```"); ok {
+		// Drop the fence's language tag, if any.
+		if i := strings.IndexByte(rest, '\n'); i >= 0 {
+			rest = rest[i+1:]
+		}
+		text, _, _ = strings.Cut(rest, "```")
+		return strings.TrimSpace(text)
+	}
+
+	start := strings.IndexAny(text, "{[")
+	if start < 0 {
+		return text
+	}
+	end := strings.LastIndexAny(text, "}]")
+	if end < start {
+		return text[start:]
+	}
+	return text[start : end+1]
+}
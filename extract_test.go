@@ -0,0 +1,80 @@
+package llms
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type contact struct {
+	Name  string `json:"name"`
+	Email string `json:"email,omitempty"`
+}
+
+func TestExtract(t *testing.T) {
+	fake := newFakeLLM(fakeResult{resp: textResponse("Here you go:\n```json\n{\"name\": \"Ada\", \"email\": \"ada@example.com\"}\n```")})
+
+	got, repaired, err := Extract[contact](context.Background(), fake, "Mail Ada at ada@example.com", ExtractOptions{Instructions: "Lowercase emails."})
+	require.NoError(t, err)
+	assert.Equal(t, contact{Name: "Ada", Email: "ada@example.com"}, got)
+	assert.False(t, repaired)
+
+	received := fake.Received()
+	require.Len(t, received, 2)
+	assert.Contains(t, messageText(received[0]), `"required":["name"]`)
+	assert.Contains(t, messageText(received[0]), "Lowercase emails.")
+	assert.Equal(t, NewTextMessage(RoleUser, "Mail Ada at ada@example.com"), received[1])
+}
+
+func TestExtract_RepairsJSON(t *testing.T) {
+	fake := newFakeLLM(fakeResult{resp: textResponse(`{name: 'Ada', email: "ada@exam`)})
+
+	got, repaired, err := Extract[contact](context.Background(), fake, "text", ExtractOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, contact{Name: "Ada", Email: "ada@exam"}, got)
+	assert.True(t, repaired)
+	assert.Equal(t, 1, fake.Calls())
+}
+
+func TestExtract_Retry(t *testing.T) {
+	fake := newFakeLLM(
+		fakeResult{resp: textResponse(`{"email": "ada@example.com"}`)},
+		fakeResult{resp: textResponse(`{"name": "Ada"}`)},
+	)
+
+	got, repaired, err := Extract[contact](context.Background(), fake, "text", ExtractOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, contact{Name: "Ada"}, got)
+	assert.False(t, repaired)
+
+	// The invalid answer is sent back with the validation errors.
+	received := fake.Received()
+	require.Len(t, received, 4)
+	assert.Equal(t, RoleAssistant, received[2].Role)
+	assert.Equal(t, `Your answer was invalid: invalid output: missing required property "name". Reply with the corrected JSON only.`, messageText(received[3]))
+}
+
+func TestExtract_RetriesExhausted(t *testing.T) {
+	fake := newFakeLLM(fakeResult{resp: textResponse("I can't find a contact.")})
+
+	_, _, err := Extract[contact](context.Background(), fake, "text", ExtractOptions{MaxRetries: 1})
+	var validationErr *ValidationError
+	require.ErrorAs(t, err, &validationErr)
+	assert.True(t, validationErr.Output)
+	assert.Equal(t, 2, fake.Calls())
+
+	_, _, err = Extract[contact](context.Background(), fake, "text", ExtractOptions{MaxRetries: -1})
+	assert.Error(t, err)
+	assert.Equal(t, 3, fake.Calls())
+}
+
+func TestExtract_GenerateError(t *testing.T) {
+	boom := errors.New("boom")
+	fake := newFakeLLM(fakeResult{err: boom})
+
+	_, _, err := Extract[contact](context.Background(), fake, "text", ExtractOptions{})
+	assert.ErrorIs(t, err, boom)
+}
@@ -0,0 +1,109 @@
+package llms
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// FailoverEvent describes a single failover from one LLM in a Fallback to the
+// next one.
+type FailoverEvent struct {
+	// From is the index of the LLM that failed.
+	From int
+	// To is the index of the LLM that will be tried next.
+	To int
+	// Err is the error returned by the failed LLM.
+	Err error
+}
+
+// Fallback is an LLM that tries each of its LLMs in order, moving on to the next
+// one when the current one fails with an error that ShouldFallback accepts.
+type Fallback struct {
+	LLMs []LLM
+
+	// ShouldFallback decides whether an error should cause the next LLM to be
+	// tried. Defaults to IsRetryable, and also falls back on ErrCircuitOpen.
+	ShouldFallback func(error) bool
+
+	// OnFailover, if set, is called every time a request fails over to the next
+	// LLM.
+	OnFailover func(FailoverEvent)
+}
+
+// NewFallback creates an LLM that sends requests to primary and falls back to
+// each of the secondaries, in order, when a retryable error (rate limit, 5xx,
+// timeout) is returned or a circuit breaker is open.
+func NewFallback(primary LLM, secondaries ...LLM) LLM {
+	return &Fallback{
+		LLMs: append([]LLM{primary}, secondaries...),
+	}
+}
+
+func (f *Fallback) shouldFallback(err error) bool {
+	if f.ShouldFallback != nil {
+		return f.ShouldFallback(err)
+	}
+	return IsRetryable(err) || errors.Is(err, ErrCircuitOpen)
+}
+
+// next reports whether the request should move on from the LLM at index i and
+// notifies OnFailover if so.
+func (f *Fallback) next(ctx context.Context, i int, err error) bool {
+	if i+1 >= len(f.LLMs) || ctx.Err() != nil || !f.shouldFallback(err) {
+		return false
+	}
+
+	if f.OnFailover != nil {
+		f.OnFailover(FailoverEvent{From: i, To: i + 1, Err: err})
+	}
+
+	return true
+}
+
+func (f *Fallback) Generate(ctx context.Context, messages []Message) (*Response, error) {
+	if len(f.LLMs) == 0 {
+		return nil, errors.New("llms: fallback has no LLMs configured")
+	}
+
+	for i, llm := range f.LLMs {
+		resp, err := llm.Generate(ctx, messages)
+		if err == nil {
+			return resp, nil
+		}
+
+		if !f.next(ctx, i, err) {
+			return resp, err
+		}
+	}
+
+	return nil, fmt.Errorf("llms: fallback exhausted all LLMs")
+}
+
+// GenerateStream streams from each LLM in turn. A failover only happens if the
+// failing LLM has not yet delivered a response to fn, so callers never receive
+// output from two different providers for the same request.
+func (f *Fallback) GenerateStream(ctx context.Context, messages []Message, fn StreamFunc) (*Response, error) {
+	if len(f.LLMs) == 0 {
+		return nil, errors.New("llms: fallback has no LLMs configured")
+	}
+
+	for i, llm := range f.LLMs {
+		delivered := false
+		resp, err := llm.GenerateStream(ctx, messages, func(r *Response, err error) bool {
+			if r != nil {
+				delivered = true
+			}
+			return fn(r, err)
+		})
+		if err == nil {
+			return resp, nil
+		}
+
+		if delivered || !f.next(ctx, i, err) {
+			return resp, err
+		}
+	}
+
+	return nil, fmt.Errorf("llms: fallback exhausted all LLMs")
+}
@@ -0,0 +1,114 @@
+package llms
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsRetryable(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"nil", nil, false},
+		{"rate limited", &APIError{StatusCode: http.StatusTooManyRequests}, true},
+		{"overloaded", &APIError{StatusCode: 529}, true},
+		{"server error", &APIError{StatusCode: http.StatusInternalServerError}, true},
+		{"bad request", &APIError{StatusCode: http.StatusBadRequest}, false},
+		{"deadline exceeded", context.DeadlineExceeded, true},
+		{"canceled", context.Canceled, false},
+		{"plain error", errors.New("boom"), false},
+		{"circuit open", ErrCircuitOpen, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, IsRetryable(tt.err))
+		})
+	}
+}
+
+func TestFallback_Generate(t *testing.T) {
+	t.Run("primary succeeds", func(t *testing.T) {
+		primary := newFakeLLM(fakeResult{resp: textResponse("primary")})
+		secondary := newFakeLLM(fakeResult{resp: textResponse("secondary")})
+
+		resp, err := NewFallback(primary, secondary).Generate(context.Background(), nil)
+		require.NoError(t, err)
+		assert.Equal(t, "resp_primary", resp.ID)
+		assert.Equal(t, 0, secondary.Calls())
+	})
+
+	t.Run("fails over on retryable error", func(t *testing.T) {
+		primary := newFakeLLM(fakeResult{err: &APIError{StatusCode: http.StatusTooManyRequests}})
+		secondary := newFakeLLM(fakeResult{resp: textResponse("secondary")})
+
+		var events []FailoverEvent
+		f := NewFallback(primary, secondary).(*Fallback)
+		f.OnFailover = func(e FailoverEvent) { events = append(events, e) }
+
+		resp, err := f.Generate(context.Background(), nil)
+		require.NoError(t, err)
+		assert.Equal(t, "resp_secondary", resp.ID)
+		require.Len(t, events, 1)
+		assert.Equal(t, 0, events[0].From)
+		assert.Equal(t, 1, events[0].To)
+	})
+
+	t.Run("does not fail over on permanent error", func(t *testing.T) {
+		primary := newFakeLLM(fakeResult{err: &APIError{StatusCode: http.StatusBadRequest}})
+		secondary := newFakeLLM(fakeResult{resp: textResponse("secondary")})
+
+		_, err := NewFallback(primary, secondary).Generate(context.Background(), nil)
+		require.Error(t, err)
+		assert.Equal(t, 0, secondary.Calls())
+	})
+
+	t.Run("returns last error when exhausted", func(t *testing.T) {
+		lastErr := &APIError{StatusCode: http.StatusServiceUnavailable}
+		primary := newFakeLLM(fakeResult{err: &APIError{StatusCode: http.StatusInternalServerError}})
+		secondary := newFakeLLM(fakeResult{err: lastErr})
+
+		_, err := NewFallback(primary, secondary).Generate(context.Background(), nil)
+		assert.ErrorIs(t, err, lastErr)
+	})
+}
+
+func TestFallback_GenerateStream(t *testing.T) {
+	t.Run("fails over before any output", func(t *testing.T) {
+		primary := newFakeLLM(fakeResult{err: &APIError{StatusCode: 529}})
+		secondary := newFakeLLM(fakeResult{
+			chunks: []*Response{textResponse("chunk")},
+			resp:   textResponse("secondary"),
+		})
+
+		chunks := 0
+		resp, err := NewFallback(primary, secondary).GenerateStream(context.Background(), nil, func(r *Response, err error) bool {
+			chunks++
+			return true
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "resp_secondary", resp.ID)
+		assert.Equal(t, 1, chunks)
+	})
+
+	t.Run("does not fail over after output was delivered", func(t *testing.T) {
+		primary := newFakeLLM(fakeResult{
+			chunks: []*Response{textResponse("chunk")},
+			err:    &APIError{StatusCode: 529},
+		})
+		secondary := newFakeLLM(fakeResult{resp: textResponse("secondary")})
+
+		_, err := NewFallback(primary, secondary).GenerateStream(context.Background(), nil, func(r *Response, err error) bool {
+			return true
+		})
+		require.Error(t, err)
+		assert.Equal(t, 0, secondary.Calls())
+	})
+}
@@ -2,12 +2,21 @@ package gemini
 
 import (
 	"context"
+	"crypto/sha256"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"log/slog"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"sync"
 
 	"google.golang.org/genai"
 
 	"github.com/google/uuid"
+	"github.com/invopop/jsonschema"
 	"github.com/llmite-ai/llms"
 )
 
@@ -20,10 +29,35 @@ type Client struct {
 	TopP               *float64
 	TopK               *int64
 	Tools              []llms.Tool
+	Registry           *llms.ToolRegistry
 	SystemInstructions []llms.Part
+	ResponseSchema     *jsonschema.Schema
+	CandidateCount     int
+	// EmbeddingModel is the model used by Embed and EmbedBatch. Defaults to
+	// DefaultEmbeddingModel.
+	EmbeddingModel string
+	// EmbeddingDimensions, if set, shortens the embeddings of models that
+	// support it.
+	EmbeddingDimensions int
+	// ImageModel is the model used by GenerateImages. Defaults to
+	// DefaultImageModel.
+	ImageModel string
+	// SpeechModel and SpeechVoice are the model and default voice used by
+	// Speak. They default to DefaultSpeechModel and DefaultSpeechVoice.
+	SpeechModel string
+	SpeechVoice string
+	// TranscriptionModel is the model used by Transcribe. Defaults to
+	// DefaultTranscriptionModel.
+	TranscriptionModel string
 
 	client *genai.Client
 	config *genai.ClientConfig
+	logger *slog.Logger
+
+	// uploads holds the files uploaded for large inline data, by the
+	// SHA-256 of the data.
+	mu      sync.Mutex
+	uploads map[[sha256.Size]byte]*genai.File
 }
 
 type Modifer func(*Client)
@@ -54,6 +88,85 @@ func WithModel(model string) Modifer {
 	}
 }
 
+// WithMaxTokens allows you to set the max output tokens on the client.
+func WithMaxTokens(maxTokens int64) Modifer {
+	return func(c *Client) {
+		c.MaxTokens = maxTokens
+	}
+}
+
+// WithTemperature allows you to set the temperature on the client.
+func WithTemperature(temperature float64) Modifer {
+	return func(c *Client) {
+		c.Temperature = &temperature
+	}
+}
+
+// WithTopP allows you to set the top_p on the client.
+func WithTopP(topP float64) Modifer {
+	return func(c *Client) {
+		c.TopP = &topP
+	}
+}
+
+// WithTopK allows you to set the top_k on the client.
+func WithTopK(topK int64) Modifer {
+	return func(c *Client) {
+		c.TopK = &topK
+	}
+}
+
+// WithCandidateCount asks the model to generate n alternative responses,
+// which are returned in the response's Candidates.
+func WithCandidateCount(n int) Modifer {
+	return func(c *Client) {
+		c.CandidateCount = n
+	}
+}
+
+// WithEmbeddingModel sets the model used by Embed and EmbedBatch.
+func WithEmbeddingModel(model string) Modifer {
+	return func(c *Client) {
+		c.EmbeddingModel = model
+	}
+}
+
+// WithEmbeddingDimensions shortens embeddings to dimensions, for models that
+// support it.
+func WithEmbeddingDimensions(dimensions int) Modifer {
+	return func(c *Client) {
+		c.EmbeddingDimensions = dimensions
+	}
+}
+
+// WithImageModel sets the model used by GenerateImages.
+func WithImageModel(model string) Modifer {
+	return func(c *Client) {
+		c.ImageModel = model
+	}
+}
+
+// WithSpeechModel sets the model used by Speak.
+func WithSpeechModel(model string) Modifer {
+	return func(c *Client) {
+		c.SpeechModel = model
+	}
+}
+
+// WithSpeechVoice sets the voice Speak uses unless the request names one.
+func WithSpeechVoice(voice string) Modifer {
+	return func(c *Client) {
+		c.SpeechVoice = voice
+	}
+}
+
+// WithTranscriptionModel sets the model used by Transcribe.
+func WithTranscriptionModel(model string) Modifer {
+	return func(c *Client) {
+		c.TranscriptionModel = model
+	}
+}
+
 // WithSystemInstructions allows you to set system instructions on the client. These instructions will be prepended to every request.
 func WithSystemInstructions(parts ...llms.Part) Modifer {
 	return func(c *Client) {
@@ -77,6 +190,48 @@ func WithTools(tools []llms.Tool) Modifer {
 	}
 }
 
+// WithToolRegistry makes the tools in registry available to the model. The
+// registry is read on every request, so tools registered later are included.
+func WithToolRegistry(registry *llms.ToolRegistry) Modifer {
+	return func(c *Client) {
+		c.Registry = registry
+	}
+}
+
+// tools returns the client's tools followed by those in its registry, less
+// those disabled by the call options in ctx.
+func (c *Client) tools(ctx context.Context) []llms.Tool {
+	tools := c.Tools
+	if c.Registry != nil {
+		tools = append(append([]llms.Tool(nil), tools...), c.Registry.List()...)
+	}
+	return llms.CallOptionsFromContext(ctx).FilterTools(tools)
+}
+
+// WithResponseSchema constrains the model to respond with JSON matching
+// schema. Use llms.GenerateSchema to build the schema from a Go type, then
+// unmarshal the response text into it:
+//
+//	client, err := gemini.New(gemini.WithResponseSchema(llms.GenerateSchema[Recipe]()))
+//
+// The llms.ResponseSchema call option overrides schema for a single call.
+func WithResponseSchema(schema *jsonschema.Schema) Modifer {
+	return func(c *Client) {
+		c.ResponseSchema = schema
+	}
+}
+
+// WithHTTPClient sets the HTTP client requests are sent with, such as one
+// created by llms.NewHTTPClient.
+func WithHTTPClient(client *http.Client) Modifer {
+	return func(c *Client) {
+		if c.config == nil {
+			c.config = &genai.ClientConfig{}
+		}
+		c.config.HTTPClient = client
+	}
+}
+
 // WithHttpLogging will log all HTTP requests and responses to the default structured logger.
 func WithHttpLogging() Modifer {
 	return func(c *Client) {
@@ -88,6 +243,18 @@ func WithHttpLogging() Modifer {
 	}
 }
 
+// WithDebugLogging logs every response chunk received from Gemini to logger at
+// debug level. A nil logger uses llms.ClientLogger().
+func WithDebugLogging(logger *slog.Logger) Modifer {
+	if logger == nil {
+		logger = llms.ClientLogger()
+	}
+
+	return func(c *Client) {
+		c.logger = logger
+	}
+}
+
 // New creates a new Gemini client. You can pass in modifiers to customize the client.
 //
 //   - Environment Variables for BackendGeminiAPI:
@@ -127,6 +294,11 @@ func New(modifiers ...Modifer) (llms.LLM, error) {
 	return c, nil
 }
 
+// ModelName returns the model requests are sent to.
+func (c *Client) ModelName() string {
+	return c.Model
+}
+
 func (c *Client) Generate(ctx context.Context, messages []llms.Message) (*llms.Response, error) {
 	resp, err := c.GenerateStream(ctx, messages, func(response *llms.Response, err error) bool {
 		return true // Continue streaming until done
@@ -137,19 +309,45 @@ func (c *Client) Generate(ctx context.Context, messages []llms.Message) (*llms.R
 	return resp, nil
 }
 
-func (c *Client) GenerateStream(ctx context.Context, messages []llms.Message, fn llms.StreamFunc) (*llms.Response, error) {
+// BuildRequest converts messages and the client configuration into the contents
+// and config sent to the Gemini API.
+func (c *Client) BuildRequest(messages []llms.Message) ([]*genai.Content, *genai.GenerateContentConfig, error) {
+	return c.buildRequest(context.Background(), messages)
+}
+
+// buildRequest is BuildRequest with the call options in ctx applied.
+func (c *Client) buildRequest(ctx context.Context, messages []llms.Message) ([]*genai.Content, *genai.GenerateContentConfig, error) {
 	config := &genai.GenerateContentConfig{}
 	contents := make([]*genai.Content, 0, len(messages))
 
+	if c.MaxTokens > 0 {
+		config.MaxOutputTokens = int32(c.MaxTokens)
+	}
+	if c.Temperature != nil {
+		config.Temperature = genai.Ptr(float32(*c.Temperature))
+	}
+	if c.TopP != nil {
+		config.TopP = genai.Ptr(float32(*c.TopP))
+	}
+	if c.TopK != nil {
+		config.TopK = genai.Ptr(float32(*c.TopK))
+	}
+	if c.CandidateCount > 0 {
+		config.CandidateCount = int32(c.CandidateCount)
+	}
+	schema := c.ResponseSchema
+	if callSchema := llms.CallOptionsFromContext(ctx).ResponseSchema; callSchema != nil {
+		schema = callSchema
+	}
+	if schema != nil {
+		config.ResponseMIMEType = "application/json"
+		config.ResponseJsonSchema = schema
+	}
+
 	if c.SystemInstructions != nil && len(c.SystemInstructions) > 0 {
-		parts := []*genai.Part{}
-		for _, p := range c.SystemInstructions {
-			switch part := p.(type) {
-			case llms.TextPart:
-				parts = append(parts, &genai.Part{Text: part.Text})
-			default:
-				return nil, fmt.Errorf("unsupported system instruction part type for Gemini: %T", part)
-			}
+		parts, err := systemParts(c.SystemInstructions)
+		if err != nil {
+			return nil, nil, err
 		}
 
 		config.SystemInstruction = &genai.Content{
@@ -158,14 +356,14 @@ func (c *Client) GenerateStream(ctx context.Context, messages []llms.Message, fn
 	}
 
 	// Configure tools if available
-	if c.Tools != nil && len(c.Tools) > 0 {
+	if clientTools := c.tools(ctx); len(clientTools) > 0 {
 		tools := genai.Tool{
-			FunctionDeclarations: make([]*genai.FunctionDeclaration, 0, len(c.Tools)),
+			FunctionDeclarations: make([]*genai.FunctionDeclaration, 0, len(clientTools)),
 		}
-		for _, tool := range c.Tools {
+		for _, tool := range clientTools {
 			funcDef := &genai.FunctionDeclaration{
 				Name:        tool.Name(),
-				Description: tool.Description(),
+				Description: llms.ToolDescription(tool),
 			}
 
 			// Convert tool schema to JSON for Gemini
@@ -182,7 +380,21 @@ func (c *Client) GenerateStream(ctx context.Context, messages []llms.Message, fn
 		config.Tools = []*genai.Tool{&tools}
 	}
 
-	for _, msg := range messages {
+	for i, msg := range messages {
+		// System messages are appended to the client's system instructions,
+		// as Gemini only accepts them in the request config.
+		if msg.Role == llms.RoleSystem {
+			parts, err := systemParts(msg.Parts)
+			if err != nil {
+				return nil, nil, fmt.Errorf("[message %d] %w", i, err)
+			}
+			if config.SystemInstruction == nil {
+				config.SystemInstruction = &genai.Content{}
+			}
+			config.SystemInstruction.Parts = append(config.SystemInstruction.Parts, parts...)
+			continue
+		}
+
 		parts := []*genai.Part{}
 
 		for _, p := range msg.Parts {
@@ -190,17 +402,56 @@ func (c *Client) GenerateStream(ctx context.Context, messages []llms.Message, fn
 			case llms.TextPart:
 				parts = append(parts, &genai.Part{Text: part.Text})
 			case llms.ToolCallPart:
-				// parts = append(parts, &genai.Part{FunctionCall: &genai.FunctionCall{
-				// 	ID:   part.ID,
-				// 	Name: part.Name,
-				// 	Args: part.InputString(),
-				// }})
+				var args map[string]any
+				if len(part.Input) > 0 {
+					if err := json.Unmarshal(part.Input, &args); err != nil {
+						return nil, nil, fmt.Errorf("failed to unmarshal tool call input for Gemini: %s -> %w", part.Input, err)
+					}
+				}
+				parts = append(parts, &genai.Part{FunctionCall: &genai.FunctionCall{
+					ID:   part.ID,
+					Name: part.Name,
+					Args: args,
+				}})
 			case llms.ToolResultPart:
 				parts = append(parts, &genai.Part{FunctionResponse: &genai.FunctionResponse{
 					ID:       part.ToolCallID,
 					Name:     part.Name,
 					Response: map[string]any{"content": part.Result},
 				}})
+			case llms.ImagePart:
+				media, err := convertMedia(part.MediaType, part.Data, part.URL)
+				if err != nil {
+					return nil, nil, fmt.Errorf("[message %d] image: %w", i, err)
+				}
+				parts = append(parts, media)
+			case llms.AudioPart:
+				media, err := convertMedia(part.MediaType, part.Data, part.URL)
+				if err != nil {
+					return nil, nil, fmt.Errorf("[message %d] audio: %w", i, err)
+				}
+				parts = append(parts, media)
+			case llms.VideoPart:
+				media, err := convertMedia(part.MediaType, part.Data, part.URL)
+				if err != nil {
+					return nil, nil, fmt.Errorf("[message %d] video: %w", i, err)
+				}
+				parts = append(parts, media)
+			case llms.DocumentPart:
+				// Uploaded files are referenced by their URI.
+				uri := part.URL
+				if part.FileID != "" {
+					uri = part.FileID
+				}
+				mediaType := part.MediaType
+				if mediaType == "" {
+					mediaType = "application/pdf"
+				}
+				media, err := convertMedia(mediaType, part.Data, uri)
+				if err != nil {
+					return nil, nil, fmt.Errorf("[message %d] document: %w", i, err)
+				}
+				parts = append(parts, media)
 			}
 		}
 
@@ -214,57 +465,287 @@ func (c *Client) GenerateStream(ctx context.Context, messages []llms.Message, fn
 		case llms.RoleAssistant:
 			content.Role = genai.RoleModel
 		default:
-			return nil, fmt.Errorf("unsupported message role for Gemini: %q", msg.Role)
+			return nil, nil, fmt.Errorf("unsupported message role for Gemini: %q", msg.Role)
 		}
 
 		contents = append(contents, content)
 	}
 
+	return contents, config, nil
+}
+
+// convertMedia converts inline data into an InlineData part, or a URL into a
+// FileData part. Gemini requires a MIME type for both, so if mediaType is
+// empty it is detected from data or guessed from the URL's file extension.
+func convertMedia(mediaType string, data []byte, uri string) (*genai.Part, error) {
+	switch {
+	case len(data) > 0:
+		if mediaType == "" {
+			mediaType = http.DetectContentType(data)
+		}
+		return &genai.Part{InlineData: &genai.Blob{MIMEType: mediaType, Data: data}}, nil
+	case uri != "":
+		if mediaType == "" {
+			if u, err := url.Parse(uri); err == nil {
+				mediaType = mime.TypeByExtension(path.Ext(u.Path))
+			}
+		}
+		return &genai.Part{FileData: &genai.FileData{MIMEType: mediaType, FileURI: uri}}, nil
+	default:
+		return nil, errors.New("no data or URL for Gemini")
+	}
+}
+
+// systemParts converts system instruction parts into Gemini parts.
+func systemParts(in []llms.Part) ([]*genai.Part, error) {
+	parts := []*genai.Part{}
+	for _, p := range in {
+		switch part := p.(type) {
+		case llms.TextPart:
+			parts = append(parts, &genai.Part{Text: part.Text})
+		case llms.CachePointPart:
+			// Gemini caches prompt prefixes implicitly.
+		default:
+			return nil, fmt.Errorf("unsupported system instruction part type for Gemini: %T", part)
+		}
+	}
+	return parts, nil
+}
+
+func (c *Client) GenerateStream(ctx context.Context, messages []llms.Message, fn llms.StreamFunc) (*llms.Response, error) {
+	ctx, requestID := llms.EnsureRequestID(ctx)
+	messages, err := c.uploadLargeParts(ctx, messages)
+	if err != nil {
+		return nil, err
+	}
+
+	contents, config, err := c.buildRequest(ctx, messages)
+	if err != nil {
+		return nil, err
+	}
+
+	// Cancelling the context closes the stream if fn stops it early.
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
 	stream := c.client.Models.GenerateContentStream(
 		ctx, c.Model, contents, config)
 
-	out := llms.Response{}
+	out := llms.Response{RequestID: requestID}
+	var feedback *genai.GenerateContentResponsePromptFeedback
 	for resp, err := range stream {
-		fmt.Printf("Gemini response: %+v, error: %v\n", resp, err)
+		if c.logger != nil {
+			c.logger.LogAttrs(ctx, slog.LevelDebug, "Gemini stream chunk",
+				slog.String("model", c.Model),
+				slog.Any("response", resp),
+				slog.Any("error", err),
+			)
+		}
 		if err != nil {
-			return nil, err
+			return nil, wrapError(err)
 		}
 
-		if len(resp.Candidates) > 0 {
-			candidate := resp.Candidates[0]
+		for _, candidate := range resp.Candidates {
+			// Chunks carry the new content of each candidate, identified by
+			// its index.
+			for len(out.Candidates) <= int(candidate.Index) {
+				out.Candidates = append(out.Candidates, llms.Candidate{
+					Message: llms.Message{Role: llms.RoleAssistant},
+				})
+			}
+			cand := &out.Candidates[candidate.Index]
+
 			if candidate.Content != nil {
-				for _, part := range candidate.Content.Parts {
-					if part.Text != "" {
-						out.Message.Parts = append(out.Message.Parts, llms.TextPart{Text: part.Text})
-					}
-					if part.FunctionCall != nil {
-						id := part.FunctionCall.ID
-						if id == "" {
-							id = fmt.Sprintf("call-%s", uuid.NewString())
-						}
-						bts, err := json.Marshal(part.FunctionCall.Args)
-						if err != nil {
-							return nil, fmt.Errorf("failed to marshal Gemini function call args: %v -> %w", part.FunctionCall.Args, err)
-						}
-
-						out.Message.Parts = append(out.Message.Parts, llms.ToolCallPart{
-							ID:    id,
-							Name:  part.FunctionCall.Name,
-							Input: bts,
-						})
-					}
+				parts, err := convertParts(candidate.Content.Parts)
+				if err != nil {
+					return nil, err
 				}
+				cand.Message.Parts = appendStreamParts(cand.Message.Parts, parts)
 			}
+			if candidate.FinishReason != "" {
+				cand.StopReason = convertFinishReason(candidate.FinishReason, cand.Message)
+			}
+			cand.Raw = candidate
+		}
+
+		if len(out.Candidates) > 0 {
+			out.Message = out.Candidates[0].Message
+			out.StopReason = out.Candidates[0].StopReason
+		}
+
+		// Prompt feedback is only sent with the first chunk, so it is
+		// carried forward for PromptFeedback to find on later ones.
+		if resp.PromptFeedback != nil {
+			feedback = resp.PromptFeedback
+		} else {
+			resp.PromptFeedback = feedback
+		}
+		if feedback != nil && feedback.BlockReason != "" {
+			// A blocked prompt produces no candidates.
+			out.Message.Role = llms.RoleAssistant
+			out.StopReason = llms.StopReason(feedback.BlockReason)
+		}
+
+		if resp.UsageMetadata != nil {
+			// Each chunk reports the usage of the whole response so far.
+			out.Usage = convertUsage(resp.UsageMetadata)
 		}
 
 		out.Raw = resp
 
-		fn(&out, err)
+		if !fn(&out, nil) {
+			break
+		}
 	}
 
 	return &out, nil
 }
 
+// convertParts converts the parts of a candidate's content into llms parts.
+// appendStreamParts appends the parts of a streamed chunk to those streamed
+// so far. Text continuing the last part is merged into it, so a streamed
+// message has one TextPart per run of text rather than one per chunk. The
+// parts are copied, as responses already passed to the stream callback share
+// them.
+func appendStreamParts(parts, chunk []llms.Part) []llms.Part {
+	out := make([]llms.Part, len(parts), len(parts)+len(chunk))
+	copy(out, parts)
+	for _, part := range chunk {
+		if text, ok := part.(llms.TextPart); ok && len(out) > 0 {
+			if last, ok := out[len(out)-1].(llms.TextPart); ok {
+				last.Text += text.Text
+				out[len(out)-1] = last
+				continue
+			}
+		}
+		out = append(out, part)
+	}
+	return out
+}
+
+func convertParts(in []*genai.Part) ([]llms.Part, error) {
+	var parts []llms.Part
+	for _, part := range in {
+		if part.Text != "" {
+			parts = append(parts, llms.TextPart{Text: part.Text})
+		}
+		if part.FunctionCall != nil {
+			id := part.FunctionCall.ID
+			if id == "" {
+				id = fmt.Sprintf("call-%s", uuid.NewString())
+			}
+			bts, err := json.Marshal(part.FunctionCall.Args)
+			if err != nil {
+				return nil, fmt.Errorf("failed to marshal Gemini function call args: %v -> %w", part.FunctionCall.Args, err)
+			}
+
+			parts = append(parts, llms.ToolCallPart{
+				ID:    id,
+				Name:  part.FunctionCall.Name,
+				Input: bts,
+			})
+		}
+	}
+	return parts, nil
+}
+
+// convertFinishReason maps a Gemini finish reason onto llms.StopReason.
+// Gemini finishes with STOP when it calls tools, so that is reported as
+// StopReasonToolUse if msg contains tool calls.
+func convertFinishReason(reason genai.FinishReason, msg llms.Message) llms.StopReason {
+	switch reason {
+	case genai.FinishReasonStop:
+		for _, part := range msg.Parts {
+			if _, ok := part.(llms.ToolCallPart); ok {
+				return llms.StopReasonToolUse
+			}
+		}
+		return llms.StopReasonEndTurn
+	case genai.FinishReasonMaxTokens:
+		return llms.StopReasonMaxTokens
+	default:
+		return llms.StopReason(reason)
+	}
+}
+
+// SafetyRatings returns the safety ratings of a candidate generated by Gemini,
+// or nil if the candidate did not come from Gemini.
+func SafetyRatings(candidate llms.Candidate) []*genai.SafetyRating {
+	if c, ok := candidate.Raw.(*genai.Candidate); ok {
+		return c.SafetyRatings
+	}
+	return nil
+}
+
+// PromptFeedback returns Gemini's feedback on the prompt of resp, including
+// why it was blocked, or nil if resp did not come from Gemini or has none.
+// When the prompt is blocked, the response has no candidates and its
+// StopReason is the block reason, such as "SAFETY".
+func PromptFeedback(resp *llms.Response) *genai.GenerateContentResponsePromptFeedback {
+	if resp == nil {
+		return nil
+	}
+	if raw, ok := resp.Raw.(*genai.GenerateContentResponse); ok {
+		return raw.PromptFeedback
+	}
+	return nil
+}
+
+// convertUsage maps Gemini usage metadata onto llms.Usage. Gemini reports
+// thinking and tool use tokens separately from the prompt and candidates, so
+// they are added to the input and output totals.
+func convertUsage(u *genai.GenerateContentResponseUsageMetadata) *llms.Usage {
+	return &llms.Usage{
+		InputTokens:          int(u.PromptTokenCount + u.ToolUsePromptTokenCount),
+		OutputTokens:         int(u.CandidatesTokenCount + u.ThoughtsTokenCount),
+		CacheReadInputTokens: int(u.CachedContentTokenCount),
+		ReasoningTokens:      int(u.ThoughtsTokenCount),
+		ToolUseInputTokens:   int(u.ToolUsePromptTokenCount),
+	}
+}
+
+// CountTokens returns the number of input tokens messages would use, via the
+// countTokens endpoint. System instructions and tools are only counted on the
+// Vertex AI backend, as the Gemini API does not accept them.
+func (c *Client) CountTokens(ctx context.Context, messages []llms.Message) (int, error) {
+	messages, err := c.uploadLargeParts(ctx, messages)
+	if err != nil {
+		return 0, err
+	}
+
+	contents, config, err := c.buildRequest(ctx, messages)
+	if err != nil {
+		return 0, err
+	}
+
+	countConfig := &genai.CountTokensConfig{}
+	if c.client.ClientConfig().Backend == genai.BackendVertexAI {
+		countConfig.SystemInstruction = config.SystemInstruction
+		countConfig.Tools = config.Tools
+	}
+
+	resp, err := c.client.Models.CountTokens(ctx, c.Model, contents, countConfig)
+	if err != nil {
+		return 0, fmt.Errorf("gemini: failed to count tokens: %w", wrapError(err))
+	}
+
+	return int(resp.TotalTokens), nil
+}
+
+// wrapError converts errors returned by the genai SDK into *llms.APIError so
+// callers can inspect the status code without depending on the SDK.
+func wrapError(err error) error {
+	var apiErr genai.APIError
+	if errors.As(err, &apiErr) {
+		return &llms.APIError{
+			Provider:   ProviderGemini,
+			StatusCode: apiErr.Code,
+			Err:        err,
+		}
+	}
+	return err
+}
+
 //
 // func (gp *GeminiProvider) GenerateStreamResponse(ctx context.Context, messages []*types.Message, callback func(string)) (*LLMResponse, error) {
 // 	config := &genai.GenerateContentConfig{}
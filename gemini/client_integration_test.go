@@ -21,11 +21,10 @@ type GeminiTestSuite struct {
 }
 
 func (suite *GeminiTestSuite) TestGenerateStreamBasic() {
-	suite.T().Parallel()
 	ctx := context.Background()
 	client, err := gemini.New(
 		gemini.WithModel("gemini-2.5-flash"),
-		gemini.WithHttpLogging(),
+		gemini.WithHTTPClient(testutil.NewRecordingClient(suite.T(), "stream_basic")),
 	)
 	suite.Require().NoError(err)
 
@@ -47,11 +46,10 @@ func (suite *GeminiTestSuite) TestGenerateStreamBasic() {
 }
 
 func (suite *GeminiTestSuite) TestGenerateStreamSystemPrompt() {
-	suite.T().Parallel()
 	ctx := context.Background()
 	client, err := gemini.New(
 		gemini.WithSystemInstruction("You only response in 'beep'  Example: beep beep beep."),
-		gemini.WithHttpLogging(),
+		gemini.WithHTTPClient(testutil.NewRecordingClient(suite.T(), "stream_system_prompt")),
 	)
 	suite.Require().NoError(err)
 
@@ -73,14 +71,13 @@ func (suite *GeminiTestSuite) TestGenerateStreamSystemPrompt() {
 }
 
 func (suite *GeminiTestSuite) TestGenerateStreamWithToolCalls() {
-	suite.T().Parallel()
 	ctx := context.Background()
 	client, err := gemini.New(
 		gemini.WithTools([]llms.Tool{
 			testutil.NewBoopTool(),
 		}),
 		gemini.WithSystemInstruction("You are a helpful assistant. That helps the user translate things."),
-		gemini.WithHttpLogging(),
+		gemini.WithHTTPClient(testutil.NewRecordingClient(suite.T(), "stream_with_tool_calls")),
 	)
 	suite.Require().NoError(err)
 
@@ -117,5 +114,6 @@ func (suite *GeminiTestSuite) TestGenerateStreamWithToolCalls() {
 }
 
 func TestGeminiTestSuite(t *testing.T) {
+	testutil.RequireAPIKey(t, "GEMINI_API_KEY")
 	suite.Run(t, new(GeminiTestSuite))
 }
@@ -0,0 +1,448 @@
+package gemini
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genai"
+
+	"github.com/llmite-ai/llms"
+)
+
+// newTestClient returns a client that sends requests to a test server running
+// handler.
+func newTestClient(t *testing.T, handler http.HandlerFunc, mods ...Modifer) *Client {
+	t.Helper()
+
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	genaiClient, err := genai.NewClient(context.Background(), &genai.ClientConfig{
+		APIKey:      "test",
+		Backend:     genai.BackendGeminiAPI,
+		HTTPOptions: genai.HTTPOptions{BaseURL: server.URL},
+	})
+	require.NoError(t, err)
+
+	llm, err := New(append([]Modifer{WithGeminiClient(genaiClient)}, mods...)...)
+	require.NoError(t, err)
+
+	return llm.(*Client)
+}
+
+// writeSSE writes chunks as a server-sent event stream.
+func writeSSE(w http.ResponseWriter, chunks ...string) {
+	w.Header().Set("Content-Type", "text/event-stream")
+	for _, chunk := range chunks {
+		fmt.Fprintf(w, "data: %s\r\n\r\n", chunk)
+	}
+}
+
+func TestBuildRequest_ToolCalls(t *testing.T) {
+	client := &Client{Model: "gemini-2.5-flash"}
+
+	contents, _, err := client.BuildRequest([]llms.Message{
+		llms.NewTextMessage(llms.RoleUser, "What's the weather in Paris?"),
+		{
+			Role: llms.RoleAssistant,
+			Parts: []llms.Part{
+				llms.TextPart{Text: "Let me check."},
+				llms.ToolCallPart{ID: "call_1", Name: "get_weather", Input: []byte(`{"location": "Paris"}`)},
+				llms.ToolCallPart{ID: "call_2", Name: "get_time"},
+			},
+		},
+		{
+			Role: llms.RoleUser,
+			Parts: []llms.Part{
+				llms.ToolResultPart{ToolCallID: "call_1", Name: "get_weather", Result: "sunny"},
+			},
+		},
+	})
+	require.NoError(t, err)
+	require.Len(t, contents, 3)
+
+	model := contents[1]
+	assert.Equal(t, genai.RoleModel, model.Role)
+	require.Len(t, model.Parts, 3)
+	assert.Equal(t, "Let me check.", model.Parts[0].Text)
+	assert.Equal(t, &genai.FunctionCall{
+		ID:   "call_1",
+		Name: "get_weather",
+		Args: map[string]any{"location": "Paris"},
+	}, model.Parts[1].FunctionCall)
+	assert.Equal(t, &genai.FunctionCall{ID: "call_2", Name: "get_time"}, model.Parts[2].FunctionCall)
+
+	assert.Equal(t, &genai.FunctionResponse{
+		ID:       "call_1",
+		Name:     "get_weather",
+		Response: map[string]any{"content": "sunny"},
+	}, contents[2].Parts[0].FunctionResponse)
+
+	_, _, err = client.BuildRequest([]llms.Message{{
+		Role:  llms.RoleAssistant,
+		Parts: []llms.Part{llms.ToolCallPart{ID: "call_1", Name: "get_weather", Input: []byte(`not json`)}},
+	}})
+	assert.ErrorContains(t, err, "failed to unmarshal tool call input for Gemini")
+}
+
+func TestBuildRequest_SystemMessages(t *testing.T) {
+	client := &Client{
+		Model:              "gemini-2.5-flash",
+		SystemInstructions: []llms.Part{llms.TextPart{Text: "Be brief."}},
+	}
+
+	contents, config, err := client.BuildRequest([]llms.Message{
+		llms.NewTextMessage(llms.RoleSystem, "Answer in French."),
+		llms.NewTextMessage(llms.RoleUser, "Hello"),
+	})
+	require.NoError(t, err)
+
+	require.Len(t, contents, 1)
+	assert.Equal(t, genai.RoleUser, contents[0].Role)
+
+	require.NotNil(t, config.SystemInstruction)
+	assert.Equal(t, []*genai.Part{{Text: "Be brief."}, {Text: "Answer in French."}}, config.SystemInstruction.Parts)
+
+	// System messages work without client-level instructions too.
+	client.SystemInstructions = nil
+	_, config, err = client.BuildRequest([]llms.Message{
+		{Role: llms.RoleSystem, Parts: []llms.Part{llms.TextPart{Text: "Answer in French."}, llms.CachePointPart{}}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []*genai.Part{{Text: "Answer in French."}}, config.SystemInstruction.Parts)
+
+	_, _, err = client.BuildRequest([]llms.Message{
+		{Role: llms.RoleSystem, Parts: []llms.Part{llms.ToolCallPart{ID: "call_1"}}},
+	})
+	assert.EqualError(t, err, "[message 0] unsupported system instruction part type for Gemini: llms.ToolCallPart")
+}
+
+func TestGenerateStream_Usage(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1beta/models/gemini-2.5-flash:streamGenerateContent", r.URL.Path)
+		writeSSE(w,
+			`{"candidates": [{"content": {"role": "model", "parts": [{"text": "Hello"}]}}], "usageMetadata": {"promptTokenCount": 10, "totalTokenCount": 10}}`,
+			`{"candidates": [{"content": {"role": "model", "parts": [{"text": " there"}]}, "finishReason": "STOP"}], "usageMetadata": {
+				"promptTokenCount": 10,
+				"candidatesTokenCount": 5,
+				"cachedContentTokenCount": 4,
+				"toolUsePromptTokenCount": 3,
+				"thoughtsTokenCount": 7,
+				"totalTokenCount": 25
+			}}`,
+		)
+	}, WithModel("gemini-2.5-flash"))
+
+	var streamed []*llms.Usage
+	resp, err := client.GenerateStream(context.Background(), []llms.Message{llms.NewTextMessage(llms.RoleUser, "Hi")}, func(r *llms.Response, err error) bool {
+		streamed = append(streamed, r.Usage)
+		return true
+	})
+	require.NoError(t, err)
+
+	require.Len(t, streamed, 2)
+	assert.Equal(t, &llms.Usage{InputTokens: 10}, streamed[0])
+	assert.Equal(t, &llms.Usage{
+		InputTokens:          13,
+		OutputTokens:         12,
+		CacheReadInputTokens: 4,
+		ReasoningTokens:      7,
+		ToolUseInputTokens:   3,
+	}, resp.Usage)
+	assert.Equal(t, 25, resp.Usage.TotalTokens())
+}
+
+func TestGenerateStream_ToolCallOptions(t *testing.T) {
+	var declared []string
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Tools []struct {
+				FunctionDeclarations []struct {
+					Name string `json:"name"`
+				} `json:"functionDeclarations"`
+			} `json:"tools"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		for _, tool := range body.Tools {
+			for _, decl := range tool.FunctionDeclarations {
+				declared = append(declared, decl.Name)
+			}
+		}
+		writeSSE(w, `{"candidates":[{"content":{"role":"model","parts":[{"text":"Hi"}]},"finishReason":"STOP"}]}`)
+	}, WithTools([]llms.Tool{
+		llms.NewTool("first", "First tool", func(ctx context.Context, p struct{}) (string, error) { return "", nil }),
+		llms.NewTool("second", "Second tool", func(ctx context.Context, p struct{}) (string, error) { return "", nil }),
+	}))
+
+	ctx := llms.WithCallOptions(context.Background(), llms.AllowTools("second"))
+	_, err := client.Generate(ctx, []llms.Message{llms.NewTextMessage(llms.RoleUser, "Hi")})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"second"}, declared)
+}
+
+func TestBuildRequest_Sampling(t *testing.T) {
+	client, err := New(
+		WithGeminiClient(&genai.Client{}),
+		WithMaxTokens(1024),
+		WithTemperature(0.5),
+		WithTopP(0.9),
+		WithTopK(40),
+	)
+	require.NoError(t, err)
+
+	_, config, err := client.(*Client).BuildRequest([]llms.Message{llms.NewTextMessage(llms.RoleUser, "Hi")})
+	require.NoError(t, err)
+	assert.Equal(t, int32(1024), config.MaxOutputTokens)
+	assert.Equal(t, genai.Ptr[float32](0.5), config.Temperature)
+	assert.Equal(t, genai.Ptr[float32](0.9), config.TopP)
+	assert.Equal(t, genai.Ptr[float32](40), config.TopK)
+
+	// Unset parameters are left to the model's defaults.
+	_, config, err = (&Client{}).BuildRequest([]llms.Message{llms.NewTextMessage(llms.RoleUser, "Hi")})
+	require.NoError(t, err)
+	assert.Zero(t, config.MaxOutputTokens)
+	assert.Nil(t, config.Temperature)
+	assert.Nil(t, config.TopP)
+	assert.Nil(t, config.TopK)
+}
+
+func TestBuildRequest_Media(t *testing.T) {
+	png := []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\x0dIHDR")
+
+	contents, _, err := (&Client{}).BuildRequest([]llms.Message{{
+		Role: llms.RoleUser,
+		Parts: []llms.Part{
+			llms.TextPart{Text: "Describe these."},
+			llms.ImagePart{Data: png},
+			llms.ImagePart{URL: "https://example.com/cat.jpg"},
+			llms.AudioPart{MediaType: "audio/mpeg", Data: []byte("ID3")},
+			llms.VideoPart{URL: "https://www.youtube.com/watch?v=abc", MediaType: "video/mp4"},
+			llms.DocumentPart{Data: []byte("%PDF-1.4")},
+			llms.DocumentPart{FileID: "https://generativelanguage.googleapis.com/v1beta/files/abc", MediaType: "text/plain"},
+		},
+	}})
+	require.NoError(t, err)
+	require.Len(t, contents, 1)
+
+	parts := contents[0].Parts
+	require.Len(t, parts, 7)
+	assert.Equal(t, &genai.Blob{MIMEType: "image/png", Data: png}, parts[1].InlineData)
+	assert.Equal(t, &genai.FileData{MIMEType: "image/jpeg", FileURI: "https://example.com/cat.jpg"}, parts[2].FileData)
+	assert.Equal(t, &genai.Blob{MIMEType: "audio/mpeg", Data: []byte("ID3")}, parts[3].InlineData)
+	assert.Equal(t, &genai.FileData{MIMEType: "video/mp4", FileURI: "https://www.youtube.com/watch?v=abc"}, parts[4].FileData)
+	assert.Equal(t, &genai.Blob{MIMEType: "application/pdf", Data: []byte("%PDF-1.4")}, parts[5].InlineData)
+	assert.Equal(t, &genai.FileData{
+		MIMEType: "text/plain",
+		FileURI:  "https://generativelanguage.googleapis.com/v1beta/files/abc",
+	}, parts[6].FileData)
+
+	_, _, err = (&Client{}).BuildRequest([]llms.Message{{
+		Role:  llms.RoleUser,
+		Parts: []llms.Part{llms.TextPart{Text: "Hi"}, llms.VideoPart{}},
+	}})
+	assert.EqualError(t, err, "[message 0] video: no data or URL for Gemini")
+}
+
+func TestBuildRequest_ResponseSchema(t *testing.T) {
+	type recipe struct {
+		Name        string   `json:"name"`
+		Ingredients []string `json:"ingredients"`
+	}
+	schema := llms.GenerateSchema[recipe]()
+
+	client, err := New(WithGeminiClient(&genai.Client{}), WithResponseSchema(schema))
+	require.NoError(t, err)
+
+	_, config, err := client.(*Client).BuildRequest([]llms.Message{llms.NewTextMessage(llms.RoleUser, "A pancake recipe")})
+	require.NoError(t, err)
+	assert.Equal(t, "application/json", config.ResponseMIMEType)
+	assert.Same(t, schema, config.ResponseJsonSchema)
+
+	_, config, err = (&Client{}).BuildRequest([]llms.Message{llms.NewTextMessage(llms.RoleUser, "Hi")})
+	require.NoError(t, err)
+	assert.Empty(t, config.ResponseMIMEType)
+	assert.Nil(t, config.ResponseJsonSchema)
+}
+
+func TestBuildRequest_ResponseSchemaCallOption(t *testing.T) {
+	type answer struct {
+		Text string `json:"text"`
+	}
+	clientSchema := llms.GenerateSchema[answer]()
+	callSchema := llms.GenerateSchema[[]answer]()
+	messages := []llms.Message{llms.NewTextMessage(llms.RoleUser, "Hi")}
+
+	client := &Client{ResponseSchema: clientSchema}
+	ctx := llms.WithCallOptions(context.Background(), llms.ResponseSchema(callSchema))
+	_, config, err := client.buildRequest(ctx, messages)
+	require.NoError(t, err)
+	assert.Equal(t, "application/json", config.ResponseMIMEType)
+	assert.Same(t, callSchema, config.ResponseJsonSchema)
+
+	_, config, err = (&Client{}).buildRequest(ctx, messages)
+	require.NoError(t, err)
+	assert.Same(t, callSchema, config.ResponseJsonSchema)
+}
+
+func TestGenerateStream_DebugLogging(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		writeSSE(w, `{"candidates": [{"content": {"role": "model", "parts": [{"text": "Hello"}]}, "finishReason": "STOP"}]}`)
+	}, WithModel("gemini-2.5-flash"), WithDebugLogging(logger))
+
+	_, err := client.Generate(context.Background(), []llms.Message{llms.NewTextMessage(llms.RoleUser, "Hi")})
+	require.NoError(t, err)
+	assert.Contains(t, buf.String(), `"msg":"Gemini stream chunk"`)
+	assert.Contains(t, buf.String(), `"model":"gemini-2.5-flash"`)
+	assert.Contains(t, buf.String(), `Hello`)
+}
+
+func TestGenerateStream_Stop(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		writeSSE(w,
+			`{"candidates": [{"content": {"role": "model", "parts": [{"text": "one"}]}}]}`,
+			`{"candidates": [{"content": {"role": "model", "parts": [{"text": "two"}]}}]}`,
+			`{"candidates": [{"content": {"role": "model", "parts": [{"text": "three"}]}, "finishReason": "STOP"}]}`,
+		)
+	})
+
+	calls := 0
+	resp, err := client.GenerateStream(context.Background(), []llms.Message{llms.NewTextMessage(llms.RoleUser, "Count")}, func(r *llms.Response, err error) bool {
+		calls++
+		return calls < 2
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 2, calls)
+	assert.Equal(t, []llms.Part{llms.TextPart{Text: "onetwo"}}, resp.Message.Parts)
+}
+
+func TestGenerateStream_Candidates(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			GenerationConfig struct {
+				CandidateCount int `json:"candidateCount"`
+			} `json:"generationConfig"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		assert.Equal(t, 2, body.GenerationConfig.CandidateCount)
+
+		writeSSE(w,
+			`{"candidates": [
+				{"index": 0, "content": {"role": "model", "parts": [{"text": "Heads"}]}},
+				{"index": 1, "content": {"role": "model", "parts": [{"functionCall": {"name": "flip_coin", "args": {}}}]}}
+			]}`,
+			`{"candidates": [
+				{"index": 0, "content": {"role": "model", "parts": [{"text": "!"}]}, "finishReason": "STOP",
+				 "safetyRatings": [{"category": "HARM_CATEGORY_HARASSMENT", "probability": "NEGLIGIBLE"}]},
+				{"index": 1, "finishReason": "STOP"}
+			]}`,
+		)
+	}, WithCandidateCount(2))
+
+	resp, err := client.Generate(context.Background(), []llms.Message{llms.NewTextMessage(llms.RoleUser, "Flip a coin")})
+	require.NoError(t, err)
+	require.Len(t, resp.Candidates, 2)
+
+	first := resp.Candidates[0]
+	assert.Equal(t, llms.RoleAssistant, first.Message.Role)
+	assert.Equal(t, []llms.Part{llms.TextPart{Text: "Heads!"}}, first.Message.Parts)
+	assert.Equal(t, llms.StopReasonEndTurn, first.StopReason)
+	assert.Equal(t, []*genai.SafetyRating{{
+		Category:    genai.HarmCategoryHarassment,
+		Probability: genai.HarmProbabilityNegligible,
+	}}, SafetyRatings(first))
+
+	second := resp.Candidates[1]
+	require.Len(t, second.Message.Parts, 1)
+	assert.Equal(t, "flip_coin", second.Message.Parts[0].(llms.ToolCallPart).Name)
+	assert.Equal(t, llms.StopReasonToolUse, second.StopReason)
+	assert.Nil(t, SafetyRatings(second))
+
+	// The first candidate is also the response's message.
+	assert.Equal(t, first.Message, resp.Message)
+	assert.Equal(t, first.StopReason, resp.StopReason)
+}
+
+func TestGenerateStream_MergesText(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		writeSSE(w,
+			`{"candidates": [{"content": {"role": "model", "parts": [{"text": "Let me"}]}}]}`,
+			`{"candidates": [{"content": {"role": "model", "parts": [{"text": " check."}, {"functionCall": {"id": "call_1", "name": "get_weather", "args": {}}}]}}]}`,
+			`{"candidates": [{"content": {"role": "model", "parts": [{"text": "Done"}]}}]}`,
+			`{"candidates": [{"content": {"role": "model", "parts": [{"text": "."}]}, "finishReason": "STOP"}]}`,
+		)
+	})
+
+	var streamed [][]llms.Part
+	resp, err := client.GenerateStream(context.Background(), []llms.Message{llms.NewTextMessage(llms.RoleUser, "Weather?")}, func(r *llms.Response, err error) bool {
+		streamed = append(streamed, r.Message.Parts)
+		return true
+	})
+	require.NoError(t, err)
+
+	call := llms.ToolCallPart{ID: "call_1", Name: "get_weather", Input: json.RawMessage(`{}`)}
+	assert.Equal(t, []llms.Part{llms.TextPart{Text: "Let me check."}, call, llms.TextPart{Text: "Done."}}, resp.Message.Parts)
+
+	// Earlier responses are not changed by later chunks.
+	require.Len(t, streamed, 4)
+	assert.Equal(t, []llms.Part{llms.TextPart{Text: "Let me"}}, streamed[0])
+	assert.Equal(t, []llms.Part{llms.TextPart{Text: "Let me check."}, call, llms.TextPart{Text: "Done"}}, streamed[2])
+}
+
+func TestConvertFinishReason(t *testing.T) {
+	text := llms.NewTextMessage(llms.RoleAssistant, "Hi")
+	assert.Equal(t, llms.StopReasonEndTurn, convertFinishReason(genai.FinishReasonStop, text))
+	assert.Equal(t, llms.StopReasonMaxTokens, convertFinishReason(genai.FinishReasonMaxTokens, text))
+	assert.Equal(t, llms.StopReason("SAFETY"), convertFinishReason(genai.FinishReasonSafety, text))
+}
+
+func TestGenerateStream_PromptFeedback(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		writeSSE(w, `{"promptFeedback": {
+			"blockReason": "SAFETY",
+			"blockReasonMessage": "The prompt was blocked.",
+			"safetyRatings": [{"category": "HARM_CATEGORY_DANGEROUS_CONTENT", "probability": "HIGH", "blocked": true}]
+		}}`)
+	})
+
+	resp, err := client.Generate(context.Background(), []llms.Message{llms.NewTextMessage(llms.RoleUser, "Something dangerous")})
+	require.NoError(t, err)
+	assert.Empty(t, resp.Message.Parts)
+	assert.Empty(t, resp.Candidates)
+	assert.Equal(t, llms.StopReason("SAFETY"), resp.StopReason)
+
+	feedback := PromptFeedback(resp)
+	require.NotNil(t, feedback)
+	assert.Equal(t, genai.BlockedReasonSafety, feedback.BlockReason)
+	assert.Equal(t, "The prompt was blocked.", feedback.BlockReasonMessage)
+	assert.Equal(t, []*genai.SafetyRating{{
+		Category:    genai.HarmCategoryDangerousContent,
+		Probability: genai.HarmProbabilityHigh,
+		Blocked:     true,
+	}}, feedback.SafetyRatings)
+
+	// Feedback on the first chunk is available on the final response.
+	client = newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		writeSSE(w,
+			`{"candidates": [{"content": {"role": "model", "parts": [{"text": "Hi"}]}}], "promptFeedback": {"safetyRatings": [{"category": "HARM_CATEGORY_HARASSMENT", "probability": "NEGLIGIBLE"}]}}`,
+			`{"candidates": [{"content": {"role": "model", "parts": [{"text": "!"}]}, "finishReason": "STOP"}]}`,
+		)
+	})
+	resp, err = client.Generate(context.Background(), []llms.Message{llms.NewTextMessage(llms.RoleUser, "Hello")})
+	require.NoError(t, err)
+	assert.Equal(t, llms.StopReasonEndTurn, resp.StopReason)
+	require.NotNil(t, PromptFeedback(resp))
+	assert.Len(t, PromptFeedback(resp).SafetyRatings, 1)
+
+	assert.Nil(t, PromptFeedback(&llms.Response{}))
+}
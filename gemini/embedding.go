@@ -0,0 +1,83 @@
+package gemini
+
+import (
+	"context"
+	"fmt"
+
+	"google.golang.org/genai"
+
+	"github.com/llmite-ai/llms"
+)
+
+// DefaultEmbeddingModel is the model used by Embed and EmbedBatch unless the
+// client's EmbeddingModel is set.
+const DefaultEmbeddingModel = "gemini-embedding-001"
+
+// maxEmbeddingBatch is the most texts the Gemini API embeds in one request.
+const maxEmbeddingBatch = 100
+
+// embeddingDimensions holds the default dimensions of Gemini's embedding
+// models.
+var embeddingDimensions = map[string]int{
+	"gemini-embedding-001": 3072,
+	"text-embedding-004":   768,
+}
+
+var _ llms.Embedder = (*Client)(nil)
+
+// Embed returns the embedding of text. See EmbedBatch.
+func (c *Client) Embed(ctx context.Context, text string) ([]float32, error) {
+	embeddings, err := c.EmbedBatch(ctx, []string{text})
+	if err != nil {
+		return nil, err
+	}
+	return embeddings[0], nil
+}
+
+// EmbedBatch returns the embeddings of texts with the client's
+// EmbeddingModel, in requests of up to 100 texts.
+func (c *Client) EmbedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	config := &genai.EmbedContentConfig{}
+	if c.EmbeddingDimensions > 0 {
+		dimensions := int32(c.EmbeddingDimensions)
+		config.OutputDimensionality = &dimensions
+	}
+
+	out := make([][]float32, 0, len(texts))
+	for start := 0; start < len(texts); start += maxEmbeddingBatch {
+		batch := texts[start:min(start+maxEmbeddingBatch, len(texts))]
+		contents := make([]*genai.Content, len(batch))
+		for i, text := range batch {
+			contents[i] = genai.NewContentFromText(text, genai.RoleUser)
+		}
+
+		resp, err := c.client.Models.EmbedContent(ctx, c.embeddingModel(), contents, config)
+		if err != nil {
+			return nil, fmt.Errorf("gemini: failed to create embeddings: %w", wrapError(err))
+		}
+		if len(resp.Embeddings) != len(batch) {
+			return nil, fmt.Errorf("gemini: got %d embeddings for %d texts", len(resp.Embeddings), len(batch))
+		}
+		for _, e := range resp.Embeddings {
+			out = append(out, e.Values)
+		}
+	}
+	return out, nil
+}
+
+// Dimensions returns the length of the embeddings, which is
+// EmbeddingDimensions if set, or the default of the embedding model if it is
+// known.
+func (c *Client) Dimensions() int {
+	if c.EmbeddingDimensions > 0 {
+		return c.EmbeddingDimensions
+	}
+	return embeddingDimensions[c.embeddingModel()]
+}
+
+func (c *Client) embeddingModel() string {
+	if c.EmbeddingModel != "" {
+		return c.EmbeddingModel
+	}
+	return DefaultEmbeddingModel
+}
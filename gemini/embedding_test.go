@@ -0,0 +1,68 @@
+package gemini
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEmbedBatch(t *testing.T) {
+	var batches []int
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.True(t, strings.HasSuffix(r.URL.Path, "/models/text-embedding-004:batchEmbedContents"), r.URL.Path)
+
+		var body struct {
+			Requests []struct {
+				Content struct {
+					Parts []struct {
+						Text string `json:"text"`
+					} `json:"parts"`
+				} `json:"content"`
+				OutputDimensionality int `json:"outputDimensionality"`
+			} `json:"requests"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		batches = append(batches, len(body.Requests))
+
+		embeddings := make([]string, len(body.Requests))
+		for i, req := range body.Requests {
+			assert.Equal(t, 2, req.OutputDimensionality)
+			embeddings[i] = fmt.Sprintf(`{"values": [%s, 0]}`, req.Content.Parts[0].Text)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"embeddings": [%s]}`, strings.Join(embeddings, ","))
+	}, WithEmbeddingModel("text-embedding-004"), WithEmbeddingDimensions(2))
+
+	texts := make([]string, 150)
+	for i := range texts {
+		texts[i] = fmt.Sprint(i)
+	}
+	embeddings, err := client.EmbedBatch(context.Background(), texts)
+	require.NoError(t, err)
+
+	// The texts are split into batches of 100.
+	assert.Equal(t, []int{100, 50}, batches)
+	require.Len(t, embeddings, 150)
+	assert.Equal(t, []float32{0, 0}, embeddings[0])
+	assert.Equal(t, []float32{149, 0}, embeddings[149])
+	assert.Equal(t, 2, client.Dimensions())
+}
+
+func TestEmbed(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Contains(t, r.URL.Path, "/models/"+DefaultEmbeddingModel+":")
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"embeddings": [{"values": [0.5, 0.25]}]}`))
+	})
+
+	embedding, err := client.Embed(context.Background(), "hello")
+	require.NoError(t, err)
+	assert.Equal(t, []float32{0.5, 0.25}, embedding)
+	assert.Equal(t, 3072, client.Dimensions())
+}
@@ -0,0 +1,183 @@
+package gemini
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"net/http"
+	"time"
+
+	"google.golang.org/genai"
+
+	"github.com/llmite-ai/llms"
+)
+
+// inlineDataLimit is the size above which documents and videos are uploaded
+// with the Files API instead of being sent inline. Gemini rejects requests
+// larger than 20MB.
+var inlineDataLimit = 20 << 20
+
+// filePollInterval is how often UploadFile checks whether an uploaded file
+// has finished processing.
+var filePollInterval = time.Second
+
+// UploadFile uploads data to the Gemini Files API and waits until the file has
+// been processed. Reference it in messages by setting a DocumentPart's FileID
+// or another part's URL to the file's URI. Gemini deletes files automatically
+// after 48 hours.
+//
+// The Files API is only available on the Gemini API backend, not Vertex AI.
+func (c *Client) UploadFile(ctx context.Context, data []byte, mediaType string) (*genai.File, error) {
+	if mediaType == "" {
+		mediaType = http.DetectContentType(data)
+	}
+
+	file, err := c.client.Files.Upload(ctx, bytes.NewReader(data), &genai.UploadFileConfig{MIMEType: mediaType})
+	if err != nil {
+		return nil, fmt.Errorf("gemini: failed to upload file: %w", wrapError(err))
+	}
+
+	// Videos are processed asynchronously and cannot be used until they are
+	// active.
+	for file.State == genai.FileStateProcessing {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(filePollInterval):
+		}
+
+		file, err = c.GetFile(ctx, file.Name)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if file.State == genai.FileStateFailed {
+		msg := "unknown error"
+		if file.Error != nil {
+			msg = file.Error.Message
+		}
+		return nil, fmt.Errorf("gemini: failed to process file %s: %s", file.Name, msg)
+	}
+
+	return file, nil
+}
+
+// GetFile returns the metadata of an uploaded file, given its name such as
+// "files/abc123".
+func (c *Client) GetFile(ctx context.Context, name string) (*genai.File, error) {
+	file, err := c.client.Files.Get(ctx, name, nil)
+	if err != nil {
+		return nil, fmt.Errorf("gemini: failed to get file %s: %w", name, wrapError(err))
+	}
+	return file, nil
+}
+
+// DeleteFile deletes an uploaded file, given its name such as "files/abc123".
+func (c *Client) DeleteFile(ctx context.Context, name string) error {
+	if _, err := c.client.Files.Delete(ctx, name, nil); err != nil {
+		return fmt.Errorf("gemini: failed to delete file %s: %w", name, wrapError(err))
+	}
+	return nil
+}
+
+// uploadLargeParts returns messages with documents and videos too large to
+// send inline replaced by references to uploaded files. Uploads are
+// remembered until the files expire, so the same data is only uploaded once
+// across the turns of a conversation. messages is returned unchanged on the
+// Vertex AI backend, which has no Files API.
+func (c *Client) uploadLargeParts(ctx context.Context, messages []llms.Message) ([]llms.Message, error) {
+	if c.client.ClientConfig().Backend == genai.BackendVertexAI {
+		return messages, nil
+	}
+
+	var out []llms.Message
+	for i, msg := range messages {
+		var parts []llms.Part
+		for j, p := range msg.Parts {
+			replaced, err := c.uploadPart(ctx, p)
+			if err != nil {
+				return nil, fmt.Errorf("[message %d] %w", i, err)
+			}
+			if replaced == nil {
+				continue
+			}
+
+			// Copy on the first replacement, so the caller's messages are
+			// left untouched.
+			if parts == nil {
+				parts = append([]llms.Part(nil), msg.Parts...)
+			}
+			parts[j] = replaced
+		}
+
+		if parts != nil {
+			if out == nil {
+				out = append([]llms.Message(nil), messages...)
+			}
+			out[i].Parts = parts
+		}
+	}
+
+	if out == nil {
+		return messages, nil
+	}
+	return out, nil
+}
+
+// uploadPart uploads the data of a document or video part larger than
+// inlineDataLimit and returns a part referencing the file instead. It returns
+// nil if p can be sent inline.
+func (c *Client) uploadPart(ctx context.Context, p llms.Part) (llms.Part, error) {
+	switch part := p.(type) {
+	case llms.DocumentPart:
+		if len(part.Data) <= inlineDataLimit {
+			return nil, nil
+		}
+		if part.MediaType == "" {
+			part.MediaType = "application/pdf"
+		}
+		file, err := c.uploadOnce(ctx, part.Data, part.MediaType)
+		if err != nil {
+			return nil, err
+		}
+		part.Data, part.FileID = nil, file.URI
+		return part, nil
+	case llms.VideoPart:
+		if len(part.Data) <= inlineDataLimit {
+			return nil, nil
+		}
+		file, err := c.uploadOnce(ctx, part.Data, part.MediaType)
+		if err != nil {
+			return nil, err
+		}
+		return llms.VideoPart{MediaType: file.MIMEType, URL: file.URI}, nil
+	}
+	return nil, nil
+}
+
+// uploadOnce uploads data unless an unexpired upload of the same data exists.
+func (c *Client) uploadOnce(ctx context.Context, data []byte, mediaType string) (*genai.File, error) {
+	key := sha256.Sum256(data)
+
+	c.mu.Lock()
+	file, ok := c.uploads[key]
+	c.mu.Unlock()
+	if ok && (file.ExpirationTime.IsZero() || time.Now().Before(file.ExpirationTime)) {
+		return file, nil
+	}
+
+	file, err := c.UploadFile(ctx, data, mediaType)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	if c.uploads == nil {
+		c.uploads = map[[sha256.Size]byte]*genai.File{}
+	}
+	c.uploads[key] = file
+	c.mu.Unlock()
+
+	return file, nil
+}
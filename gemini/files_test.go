@@ -0,0 +1,116 @@
+package gemini
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/llmite-ai/llms"
+)
+
+// fileServer fakes the Files API and generateContent endpoints. Uploaded files
+// are processing until fetched once.
+type fileServer struct {
+	t        *testing.T
+	uploads  int
+	uploaded []byte
+	contents []json.RawMessage
+}
+
+func (s *fileServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	switch {
+	case r.Method == http.MethodPost && r.URL.Path == "/upload/v1beta/files":
+		assert.Equal(s.t, "start", r.Header.Get("X-Goog-Upload-Command"))
+		assert.Equal(s.t, "video/mp4", r.Header.Get("X-Goog-Upload-Header-Content-Type"))
+		w.Header().Set("X-Goog-Upload-URL", "http://"+r.Host+"/upload-session")
+		w.Write([]byte(`{}`))
+	case r.Method == http.MethodPost && r.URL.Path == "/upload-session":
+		s.uploads++
+		s.uploaded, _ = io.ReadAll(r.Body)
+		w.Header().Set("X-Goog-Upload-Status", "final")
+		w.Write([]byte(`{"file": {"name": "files/abc", "uri": "https://generativelanguage.googleapis.com/v1beta/files/abc", "mimeType": "video/mp4", "state": "PROCESSING"}}`))
+	case r.Method == http.MethodGet && r.URL.Path == "/v1beta/files/abc":
+		w.Write([]byte(`{"name": "files/abc", "uri": "https://generativelanguage.googleapis.com/v1beta/files/abc", "mimeType": "video/mp4", "state": "ACTIVE"}`))
+	case r.Method == http.MethodDelete && r.URL.Path == "/v1beta/files/abc":
+		w.Write([]byte(`{}`))
+	case r.URL.Path == "/v1beta/models/gemini-2.5-flash:streamGenerateContent":
+		var body struct {
+			Contents []json.RawMessage `json:"contents"`
+		}
+		require.NoError(s.t, json.NewDecoder(r.Body).Decode(&body))
+		s.contents = body.Contents
+		writeSSE(w, `{"candidates": [{"content": {"role": "model", "parts": [{"text": "A cat video."}]}, "finishReason": "STOP"}]}`)
+	default:
+		s.t.Errorf("unexpected request: %s %s", r.Method, r.URL.Path)
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func TestFiles(t *testing.T) {
+	filePollInterval = time.Millisecond
+	t.Cleanup(func() { filePollInterval = time.Second })
+
+	server := &fileServer{t: t}
+	client := newTestClient(t, server.ServeHTTP)
+
+	file, err := client.UploadFile(context.Background(), []byte("video"), "video/mp4")
+	require.NoError(t, err)
+	assert.Equal(t, "files/abc", file.Name)
+	assert.Equal(t, "ACTIVE", string(file.State))
+	assert.Equal(t, []byte("video"), server.uploaded)
+
+	file, err = client.GetFile(context.Background(), "files/abc")
+	require.NoError(t, err)
+	assert.Equal(t, "https://generativelanguage.googleapis.com/v1beta/files/abc", file.URI)
+
+	require.NoError(t, client.DeleteFile(context.Background(), "files/abc"))
+}
+
+func TestGenerate_UploadsLargeParts(t *testing.T) {
+	filePollInterval = time.Millisecond
+	inlineDataLimit = 8
+	t.Cleanup(func() {
+		filePollInterval = time.Second
+		inlineDataLimit = 20 << 20
+	})
+
+	server := &fileServer{t: t}
+	client := newTestClient(t, server.ServeHTTP, WithModel("gemini-2.5-flash"))
+
+	video := []byte("large video data")
+	messages := []llms.Message{{
+		Role: llms.RoleUser,
+		Parts: []llms.Part{
+			llms.TextPart{Text: "Describe this."},
+			llms.VideoPart{MediaType: "video/mp4", Data: video},
+			llms.VideoPart{MediaType: "video/mp4", Data: []byte("small")},
+		},
+	}}
+
+	for range 2 {
+		_, err := client.Generate(context.Background(), messages)
+		require.NoError(t, err)
+
+		require.Len(t, server.contents, 1)
+		assert.JSONEq(t, `{
+			"role": "user",
+			"parts": [
+				{"text": "Describe this."},
+				{"fileData": {"fileUri": "https://generativelanguage.googleapis.com/v1beta/files/abc", "mimeType": "video/mp4"}},
+				{"inlineData": {"data": "c21hbGw=", "mimeType": "video/mp4"}}
+			]
+		}`, string(server.contents[0]))
+	}
+
+	// The data is uploaded once and the caller's messages are unchanged.
+	assert.Equal(t, 1, server.uploads)
+	assert.Equal(t, video, messages[0].Parts[1].(llms.VideoPart).Data)
+}
@@ -0,0 +1,88 @@
+package gemini
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	"google.golang.org/genai"
+
+	"github.com/llmite-ai/llms"
+)
+
+// DefaultImageModel is the model used by GenerateImages unless the client's
+// ImageModel is set.
+const DefaultImageModel = "imagen-4.0-generate-001"
+
+// imageAspectRatios are the aspect ratios Imagen generates.
+var imageAspectRatios = []struct {
+	name  string
+	ratio float64
+}{
+	{"1:1", 1},
+	{"3:4", 3.0 / 4},
+	{"4:3", 4.0 / 3},
+	{"9:16", 9.0 / 16},
+	{"16:9", 16.0 / 9},
+}
+
+var _ llms.ImageGenerator = (*Client)(nil)
+
+// GenerateImages generates images with the client's ImageModel, an Imagen
+// model. Imagen takes an aspect ratio rather than a size, so req.Size selects
+// the closest aspect ratio it supports. Images removed by the safety filters
+// are left out of the result.
+func (c *Client) GenerateImages(ctx context.Context, req llms.ImageRequest) ([]llms.ImagePart, error) {
+	// Imagen generates 4 images unless told otherwise.
+	count := req.Count
+	if count <= 0 {
+		count = 1
+	}
+	config := &genai.GenerateImagesConfig{
+		NumberOfImages: int32(count),
+		OutputMIMEType: req.Format,
+	}
+	if req.Size != "" {
+		width, height, err := llms.ParseImageSize(req.Size)
+		if err != nil {
+			return nil, err
+		}
+		config.AspectRatio = closestAspectRatio(float64(width) / float64(height))
+	}
+
+	resp, err := c.client.Models.GenerateImages(ctx, c.imageModel(), req.Prompt, config)
+	if err != nil {
+		return nil, fmt.Errorf("gemini: failed to generate images: %w", wrapError(err))
+	}
+
+	images := make([]llms.ImagePart, 0, len(resp.GeneratedImages))
+	for _, generated := range resp.GeneratedImages {
+		if generated.Image == nil || len(generated.Image.ImageBytes) == 0 {
+			continue
+		}
+		mediaType := generated.Image.MIMEType
+		if mediaType == "" {
+			mediaType = req.Format
+		}
+		images = append(images, llms.ImagePart{MediaType: mediaType, Data: generated.Image.ImageBytes})
+	}
+	return images, nil
+}
+
+// closestAspectRatio returns the Imagen aspect ratio closest to ratio.
+func closestAspectRatio(ratio float64) string {
+	best, bestDiff := "", math.Inf(1)
+	for _, r := range imageAspectRatios {
+		if diff := math.Abs(math.Log(ratio / r.ratio)); diff < bestDiff {
+			best, bestDiff = r.name, diff
+		}
+	}
+	return best
+}
+
+func (c *Client) imageModel() string {
+	if c.ImageModel != "" {
+		return c.ImageModel
+	}
+	return DefaultImageModel
+}
@@ -0,0 +1,94 @@
+package gemini
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/llmite-ai/llms"
+)
+
+func TestGenerateImages(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.True(t, strings.HasSuffix(r.URL.Path, "/models/"+DefaultImageModel+":predict"), r.URL.Path)
+
+		var body struct {
+			Instances []struct {
+				Prompt string `json:"prompt"`
+			} `json:"instances"`
+			Parameters struct {
+				SampleCount   int    `json:"sampleCount"`
+				AspectRatio   string `json:"aspectRatio"`
+				OutputOptions struct {
+					MIMEType string `json:"mimeType"`
+				} `json:"outputOptions"`
+			} `json:"parameters"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		assert.Equal(t, "a red fox", body.Instances[0].Prompt)
+		assert.Equal(t, 3, body.Parameters.SampleCount)
+		assert.Equal(t, "16:9", body.Parameters.AspectRatio)
+		assert.Equal(t, "image/jpeg", body.Parameters.OutputOptions.MIMEType)
+
+		// The second image was removed by the safety filters.
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"predictions": [
+			{"bytesBase64Encoded": "b25l", "mimeType": "image/jpeg"},
+			{"raiFilteredReason": "filtered"},
+			{"bytesBase64Encoded": "dHdv", "mimeType": "image/jpeg"}
+		]}`))
+	})
+
+	images, err := client.GenerateImages(context.Background(), llms.ImageRequest{
+		Prompt: "a red fox",
+		Size:   "1792x1024",
+		Count:  3,
+		Format: "image/jpeg",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []llms.ImagePart{
+		{MediaType: "image/jpeg", Data: []byte("one")},
+		{MediaType: "image/jpeg", Data: []byte("two")},
+	}, images)
+}
+
+func TestGenerateImages_DefaultCount(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Parameters struct {
+				SampleCount int `json:"sampleCount"`
+			} `json:"parameters"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		assert.Equal(t, 1, body.Parameters.SampleCount)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"predictions": [{"bytesBase64Encoded": "b25l", "mimeType": "image/png"}]}`))
+	})
+
+	images, err := client.GenerateImages(context.Background(), llms.ImageRequest{Prompt: "a red fox"})
+	require.NoError(t, err)
+	assert.Len(t, images, 1)
+}
+
+func TestGenerateImages_InvalidSize(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		t.Error("unexpected request")
+	})
+
+	_, err := client.GenerateImages(context.Background(), llms.ImageRequest{Prompt: "a fox", Size: "big"})
+	assert.EqualError(t, err, `llms: invalid image size "big"`)
+}
+
+func TestClosestAspectRatio(t *testing.T) {
+	assert.Equal(t, "1:1", closestAspectRatio(1))
+	assert.Equal(t, "3:4", closestAspectRatio(768.0/1024))
+	assert.Equal(t, "4:3", closestAspectRatio(1536.0/1024))
+	assert.Equal(t, "9:16", closestAspectRatio(1024.0/1792))
+	assert.Equal(t, "16:9", closestAspectRatio(3))
+}
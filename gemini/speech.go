@@ -0,0 +1,173 @@
+package gemini
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"mime"
+	"strconv"
+	"strings"
+
+	"google.golang.org/genai"
+
+	"github.com/llmite-ai/llms"
+)
+
+const (
+	// DefaultSpeechModel is the model used by Speak unless the client's
+	// SpeechModel is set.
+	DefaultSpeechModel = "gemini-2.5-flash-preview-tts"
+	// DefaultSpeechVoice is the voice used by Speak unless the request or
+	// the client's SpeechVoice names one.
+	DefaultSpeechVoice = "Kore"
+	// DefaultTranscriptionModel is the model used by Transcribe unless the
+	// client's TranscriptionModel is set.
+	DefaultTranscriptionModel = "gemini-2.5-flash"
+)
+
+// transcriptionPrompt asks the model for a plain transcript of the audio.
+const transcriptionPrompt = "Transcribe the speech in this audio. Reply with the transcript only, without timestamps or commentary."
+
+var (
+	_ llms.Speaker     = (*Client)(nil)
+	_ llms.Transcriber = (*Client)(nil)
+)
+
+// Speak generates speech with the client's SpeechModel. Gemini generates
+// 16-bit PCM audio, which is returned as WAV unless req.Format is
+// "audio/pcm", in which case the raw samples are returned with Gemini's media
+// type, such as "audio/L16;codec=pcm;rate=24000". Gemini is steered by the
+// prompt rather than a setting, so req.Instructions are prepended to the
+// text, and should read like "Say cheerfully".
+func (c *Client) Speak(ctx context.Context, req llms.SpeechRequest) (llms.AudioPart, error) {
+	if req.Format != "" && req.Format != "audio/wav" && req.Format != "audio/pcm" {
+		return llms.AudioPart{}, fmt.Errorf("gemini: unsupported speech format %q", req.Format)
+	}
+
+	voice := req.Voice
+	if voice == "" {
+		voice = c.SpeechVoice
+	}
+	if voice == "" {
+		voice = DefaultSpeechVoice
+	}
+	prompt := req.Text
+	if req.Instructions != "" {
+		prompt = req.Instructions + ": " + req.Text
+	}
+
+	config := &genai.GenerateContentConfig{
+		ResponseModalities: []string{string(genai.ModalityAudio)},
+		SpeechConfig: &genai.SpeechConfig{
+			VoiceConfig: &genai.VoiceConfig{
+				PrebuiltVoiceConfig: &genai.PrebuiltVoiceConfig{VoiceName: voice},
+			},
+		},
+	}
+	resp, err := c.client.Models.GenerateContent(ctx, c.speechModel(), genai.Text(prompt), config)
+	if err != nil {
+		return llms.AudioPart{}, fmt.Errorf("gemini: failed to generate speech: %w", wrapError(err))
+	}
+
+	var blob *genai.Blob
+	if len(resp.Candidates) > 0 && resp.Candidates[0].Content != nil {
+		for _, part := range resp.Candidates[0].Content.Parts {
+			if part.InlineData != nil {
+				blob = part.InlineData
+				break
+			}
+		}
+	}
+	if blob == nil {
+		return llms.AudioPart{}, errors.New("gemini: response has no audio")
+	}
+
+	if req.Format == "audio/pcm" {
+		return llms.AudioPart{MediaType: blob.MIMEType, Data: blob.Data, Transcript: req.Text}, nil
+	}
+	return llms.AudioPart{MediaType: "audio/wav", Data: pcmToWAV(blob.Data, pcmRate(blob.MIMEType)), Transcript: req.Text}, nil
+}
+
+// pcmRate returns the sample rate of PCM audio from its media type, such as
+// "audio/L16;codec=pcm;rate=24000", defaulting to the 24kHz Gemini generates.
+func pcmRate(mediaType string) int {
+	_, params, err := mime.ParseMediaType(mediaType)
+	if err == nil {
+		if rate, err := strconv.Atoi(params["rate"]); err == nil && rate > 0 {
+			return rate
+		}
+	}
+	return 24000
+}
+
+// pcmToWAV wraps mono 16-bit little-endian PCM samples in a WAV header.
+func pcmToWAV(pcm []byte, rate int) []byte {
+	const channels, bitsPerSample = 1, 16
+	blockAlign := channels * bitsPerSample / 8
+
+	var buf bytes.Buffer
+	buf.Grow(44 + len(pcm))
+	buf.WriteString("RIFF")
+	binary.Write(&buf, binary.LittleEndian, uint32(36+len(pcm)))
+	buf.WriteString("WAVEfmt ")
+	binary.Write(&buf, binary.LittleEndian, uint32(16))
+	binary.Write(&buf, binary.LittleEndian, uint16(1)) // PCM
+	binary.Write(&buf, binary.LittleEndian, uint16(channels))
+	binary.Write(&buf, binary.LittleEndian, uint32(rate))
+	binary.Write(&buf, binary.LittleEndian, uint32(rate*blockAlign))
+	binary.Write(&buf, binary.LittleEndian, uint16(blockAlign))
+	binary.Write(&buf, binary.LittleEndian, uint16(bitsPerSample))
+	buf.WriteString("data")
+	binary.Write(&buf, binary.LittleEndian, uint32(len(pcm)))
+	buf.Write(pcm)
+	return buf.Bytes()
+}
+
+// Transcribe transcribes audio by asking the client's TranscriptionModel for
+// its transcript. Audio too large to send inline is uploaded with the Files
+// API, except on the Vertex AI backend.
+func (c *Client) Transcribe(ctx context.Context, req llms.TranscriptionRequest) (string, error) {
+	audio := req.Audio
+	if len(audio.Data) > inlineDataLimit && c.client.ClientConfig().Backend != genai.BackendVertexAI {
+		file, err := c.uploadOnce(ctx, audio.Data, audio.MediaType)
+		if err != nil {
+			return "", err
+		}
+		audio = llms.AudioPart{MediaType: file.MIMEType, URL: file.URI}
+	}
+	media, err := convertMedia(audio.MediaType, audio.Data, audio.URL)
+	if err != nil {
+		return "", fmt.Errorf("gemini: audio: %w", err)
+	}
+
+	prompt := transcriptionPrompt
+	if req.Language != "" {
+		prompt += fmt.Sprintf(" The speech is in the language with ISO-639-1 code %q.", req.Language)
+	}
+	if req.Prompt != "" {
+		prompt += "\n\nUse this text as context for names and spelling:\n\n" + req.Prompt
+	}
+
+	contents := []*genai.Content{genai.NewContentFromParts([]*genai.Part{media, genai.NewPartFromText(prompt)}, genai.RoleUser)}
+	resp, err := c.client.Models.GenerateContent(ctx, c.transcriptionModel(), contents, nil)
+	if err != nil {
+		return "", fmt.Errorf("gemini: failed to transcribe audio: %w", wrapError(err))
+	}
+	return strings.TrimSpace(resp.Text()), nil
+}
+
+func (c *Client) speechModel() string {
+	if c.SpeechModel != "" {
+		return c.SpeechModel
+	}
+	return DefaultSpeechModel
+}
+
+func (c *Client) transcriptionModel() string {
+	if c.TranscriptionModel != "" {
+		return c.TranscriptionModel
+	}
+	return DefaultTranscriptionModel
+}
@@ -0,0 +1,114 @@
+package gemini
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/llmite-ai/llms"
+)
+
+// speechHandler answers speech requests with pcm, checking the prompt and
+// voice of the request.
+func speechHandler(t *testing.T, prompt, voice string, pcm string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		assert.True(t, strings.HasSuffix(r.URL.Path, "/models/"+DefaultSpeechModel+":generateContent"), r.URL.Path)
+
+		var body struct {
+			Contents []struct {
+				Parts []struct {
+					Text string `json:"text"`
+				} `json:"parts"`
+			} `json:"contents"`
+			GenerationConfig struct {
+				ResponseModalities []string `json:"responseModalities"`
+				SpeechConfig       struct {
+					VoiceConfig struct {
+						PrebuiltVoiceConfig struct {
+							VoiceName string `json:"voiceName"`
+						} `json:"prebuiltVoiceConfig"`
+					} `json:"voiceConfig"`
+				} `json:"speechConfig"`
+			} `json:"generationConfig"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		assert.Equal(t, prompt, body.Contents[0].Parts[0].Text)
+		assert.Equal(t, []string{"AUDIO"}, body.GenerationConfig.ResponseModalities)
+		assert.Equal(t, voice, body.GenerationConfig.SpeechConfig.VoiceConfig.PrebuiltVoiceConfig.VoiceName)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"candidates": [{"content": {"role": "model", "parts": [
+			{"inlineData": {"mimeType": "audio/L16;codec=pcm;rate=16000", "data": "` + pcm + `"}}
+		]}}]}`))
+	}
+}
+
+func TestSpeak(t *testing.T) {
+	client := newTestClient(t, speechHandler(t, "Say cheerfully: Hello there", "Puck", "AQACAA=="))
+
+	audio, err := client.Speak(context.Background(), llms.SpeechRequest{
+		Text:         "Hello there",
+		Voice:        "Puck",
+		Instructions: "Say cheerfully",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "audio/wav", audio.MediaType)
+	assert.Equal(t, "Hello there", audio.Transcript)
+
+	// The PCM samples are wrapped in a WAV header with Gemini's sample rate.
+	require.Len(t, audio.Data, 48)
+	assert.Equal(t, "RIFF", string(audio.Data[0:4]))
+	assert.Equal(t, uint32(40), binary.LittleEndian.Uint32(audio.Data[4:8]))
+	assert.Equal(t, "WAVEfmt ", string(audio.Data[8:16]))
+	assert.Equal(t, uint32(16000), binary.LittleEndian.Uint32(audio.Data[24:28]))
+	assert.Equal(t, "data", string(audio.Data[36:40]))
+	assert.Equal(t, uint32(4), binary.LittleEndian.Uint32(audio.Data[40:44]))
+	assert.Equal(t, []byte{1, 0, 2, 0}, audio.Data[44:])
+}
+
+func TestSpeak_PCM(t *testing.T) {
+	client := newTestClient(t, speechHandler(t, "hi", "Charon", "AQACAA=="), WithSpeechVoice("Charon"))
+
+	audio, err := client.Speak(context.Background(), llms.SpeechRequest{Text: "hi", Format: "audio/pcm"})
+	require.NoError(t, err)
+	assert.Equal(t, llms.AudioPart{MediaType: "audio/L16;codec=pcm;rate=16000", Data: []byte{1, 0, 2, 0}, Transcript: "hi"}, audio)
+
+	_, err = client.Speak(context.Background(), llms.SpeechRequest{Text: "hi", Format: "audio/mpeg"})
+	assert.EqualError(t, err, `gemini: unsupported speech format "audio/mpeg"`)
+}
+
+func TestTranscribe(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.True(t, strings.HasSuffix(r.URL.Path, "/models/"+DefaultTranscriptionModel+":generateContent"), r.URL.Path)
+
+		var body struct {
+			Contents []json.RawMessage `json:"contents"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		require.Len(t, body.Contents, 1)
+		assert.JSONEq(t, `{
+			"role": "user",
+			"parts": [
+				{"inlineData": {"data": "YXVkaW8=", "mimeType": "audio/mpeg"}},
+				{"text": "`+transcriptionPrompt+` The speech is in the language with ISO-639-1 code \"en\".\n\nUse this text as context for names and spelling:\n\nAda"}
+			]
+		}`, string(body.Contents[0]))
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"candidates": [{"content": {"role": "model", "parts": [{"text": "Hello Ada.\n"}]}}]}`))
+	})
+
+	text, err := client.Transcribe(context.Background(), llms.TranscriptionRequest{
+		Audio:    llms.AudioPart{MediaType: "audio/mpeg", Data: []byte("audio")},
+		Language: "en",
+		Prompt:   "Ada",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "Hello Ada.", text)
+}
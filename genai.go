@@ -0,0 +1,231 @@
+package llms
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// genAISystems maps the hosts of provider APIs to the gen_ai.system values of
+// the OpenTelemetry GenAI semantic conventions.
+var genAISystems = []struct {
+	hostSuffix string
+	system     string
+}{
+	{"api.openai.com", "openai"},
+	{"openai.azure.com", "az.ai.openai"},
+	{"api.anthropic.com", "anthropic"},
+	{"generativelanguage.googleapis.com", "gcp.gemini"},
+	{"aiplatform.googleapis.com", "gcp.vertex_ai"},
+	{"api.mistral.ai", "mistral_ai"},
+	{"api.deepseek.com", "deepseek"},
+	{"openrouter.ai", "openrouter"},
+}
+
+// genAIModelPath matches the model in the URLs of the Gemini and Vertex AI
+// APIs, which do not send it in the body.
+var genAIModelPath = regexp.MustCompile(`/models/([^/:]+)`)
+
+// genAICall collects the attributes of the OpenTelemetry GenAI semantic
+// conventions for one request from its URL and payloads. See
+// https://opentelemetry.io/docs/specs/semconv/gen-ai/.
+type genAICall struct {
+	system        string
+	requestModel  string
+	responseModel string
+	responseID    string
+	finishReasons []string
+	inputTokens   *int
+	outputTokens  *int
+
+	// pending holds the incomplete line at the end of the stream read so far.
+	pending []byte
+}
+
+// newGenAICall returns the attributes known before req is sent. body is the
+// request body, or nil.
+func newGenAICall(req *http.Request, body []byte) *genAICall {
+	c := &genAICall{}
+
+	host := req.URL.Hostname()
+	for _, s := range genAISystems {
+		if strings.HasSuffix(host, s.hostSuffix) {
+			c.system = s.system
+			break
+		}
+	}
+
+	var payload struct {
+		Model string `json:"model"`
+	}
+	if json.Unmarshal(body, &payload) == nil && payload.Model != "" {
+		c.requestModel = payload.Model
+	} else if m := genAIModelPath.FindStringSubmatch(req.URL.Path); m != nil {
+		c.requestModel = m[1]
+	}
+
+	return c
+}
+
+// genAIUsage holds the token counts of every supported provider. Anthropic
+// reports its input tokens without those read from or written to the cache.
+type genAIUsage struct {
+	PromptTokens             *int `json:"prompt_tokens"`
+	CompletionTokens         *int `json:"completion_tokens"`
+	InputTokens              *int `json:"input_tokens"`
+	OutputTokens             *int `json:"output_tokens"`
+	CacheCreationInputTokens int  `json:"cache_creation_input_tokens"`
+	CacheReadInputTokens     int  `json:"cache_read_input_tokens"`
+}
+
+// genAIPayload is the union of the response bodies and stream events of every
+// supported provider.
+type genAIPayload struct {
+	ID      string      `json:"id"`
+	Model   string      `json:"model"`
+	Usage   *genAIUsage `json:"usage"`
+	Choices []struct {
+		FinishReason string `json:"finish_reason"`
+	} `json:"choices"`
+
+	// Anthropic
+	StopReason string        `json:"stop_reason"`
+	Message    *genAIPayload `json:"message"`
+	Delta      *struct {
+		StopReason string `json:"stop_reason"`
+	} `json:"delta"`
+
+	// Gemini
+	ResponseID   string `json:"responseId"`
+	ModelVersion string `json:"modelVersion"`
+	Candidates   []struct {
+		FinishReason string `json:"finishReason"`
+	} `json:"candidates"`
+	UsageMetadata *struct {
+		PromptTokenCount     int `json:"promptTokenCount"`
+		CandidatesTokenCount int `json:"candidatesTokenCount"`
+		ThoughtsTokenCount   int `json:"thoughtsTokenCount"`
+	} `json:"usageMetadata"`
+}
+
+// observe adds the attributes found in a response body or stream event.
+// Values in later events replace those in earlier ones, as providers report
+// usage so far in each event.
+func (c *genAICall) observe(data []byte) {
+	var p genAIPayload
+	if json.Unmarshal(data, &p) != nil {
+		return
+	}
+	if p.Message != nil {
+		c.observePayload(*p.Message)
+	}
+	c.observePayload(p)
+}
+
+func (c *genAICall) observePayload(p genAIPayload) {
+	if id := firstNonEmpty(p.ID, p.ResponseID); id != "" {
+		c.responseID = id
+	}
+	if model := firstNonEmpty(p.Model, p.ModelVersion); model != "" {
+		c.responseModel = model
+	}
+
+	for _, choice := range p.Choices {
+		c.addFinishReason(choice.FinishReason)
+	}
+	for _, candidate := range p.Candidates {
+		c.addFinishReason(candidate.FinishReason)
+	}
+	c.addFinishReason(p.StopReason)
+	if p.Delta != nil {
+		c.addFinishReason(p.Delta.StopReason)
+	}
+
+	if u := p.Usage; u != nil {
+		if u.PromptTokens != nil {
+			c.inputTokens = u.PromptTokens
+		}
+		if u.CompletionTokens != nil {
+			c.outputTokens = u.CompletionTokens
+		}
+		if u.InputTokens != nil {
+			input := *u.InputTokens + u.CacheCreationInputTokens + u.CacheReadInputTokens
+			c.inputTokens = &input
+		}
+		if u.OutputTokens != nil {
+			c.outputTokens = u.OutputTokens
+		}
+	}
+	if u := p.UsageMetadata; u != nil {
+		output := u.CandidatesTokenCount + u.ThoughtsTokenCount
+		c.inputTokens = &u.PromptTokenCount
+		c.outputTokens = &output
+	}
+}
+
+func (c *genAICall) addFinishReason(reason string) {
+	if reason != "" {
+		c.finishReasons = append(c.finishReasons, reason)
+	}
+}
+
+// observeStream adds the attributes found in the events of a server-sent
+// events stream, given its bytes in the order they are read.
+func (c *genAICall) observeStream(p []byte) {
+	c.pending = append(c.pending, p...)
+	for {
+		line, rest, ok := bytes.Cut(c.pending, []byte("\n"))
+		if !ok {
+			return
+		}
+		if data, ok := bytes.CutPrefix(bytes.TrimSpace(line), []byte("data:")); ok {
+			c.observe(bytes.TrimSpace(data))
+		}
+		c.pending = rest
+	}
+}
+
+// requestAttrs returns the attributes known before the response is received.
+func (c *genAICall) requestAttrs() []slog.Attr {
+	var attrs []slog.Attr
+	if c.system != "" {
+		attrs = append(attrs, slog.String("gen_ai.system", c.system))
+	}
+	if c.requestModel != "" {
+		attrs = append(attrs, slog.String("gen_ai.request.model", c.requestModel))
+	}
+	return attrs
+}
+
+// attrs returns every attribute found.
+func (c *genAICall) attrs() []slog.Attr {
+	attrs := c.requestAttrs()
+	if c.responseModel != "" {
+		attrs = append(attrs, slog.String("gen_ai.response.model", c.responseModel))
+	}
+	if c.responseID != "" {
+		attrs = append(attrs, slog.String("gen_ai.response.id", c.responseID))
+	}
+	if len(c.finishReasons) > 0 {
+		attrs = append(attrs, slog.Any("gen_ai.response.finish_reasons", c.finishReasons))
+	}
+	if c.inputTokens != nil {
+		attrs = append(attrs, slog.Int("gen_ai.usage.input_tokens", *c.inputTokens))
+	}
+	if c.outputTokens != nil {
+		attrs = append(attrs, slog.Int("gen_ai.usage.output_tokens", *c.outputTokens))
+	}
+	return attrs
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
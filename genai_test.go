@@ -0,0 +1,118 @@
+package llms
+
+import (
+	"log/slog"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// attrMap returns attrs keyed by name, with their values resolved.
+func attrMap(attrs []slog.Attr) map[string]any {
+	m := make(map[string]any, len(attrs))
+	for _, attr := range attrs {
+		m[attr.Key] = attr.Value.Any()
+	}
+	return m
+}
+
+func TestGenAICall(t *testing.T) {
+	tests := []struct {
+		name   string
+		url    string
+		body   string
+		events []string
+		stream bool
+		want   map[string]any
+	}{
+		{
+			name: "openai",
+			url:  "https://api.openai.com/v1/chat/completions",
+			body: `{"model": "gpt-4o", "messages": []}`,
+			events: []string{`{
+				"id": "chatcmpl-1",
+				"model": "gpt-4o-2024-08-06",
+				"choices": [{"finish_reason": "stop"}, {"finish_reason": "length"}],
+				"usage": {"prompt_tokens": 10, "completion_tokens": 20}
+			}`},
+			want: map[string]any{
+				"gen_ai.system":                  "openai",
+				"gen_ai.request.model":           "gpt-4o",
+				"gen_ai.response.model":          "gpt-4o-2024-08-06",
+				"gen_ai.response.id":             "chatcmpl-1",
+				"gen_ai.response.finish_reasons": []string{"stop", "length"},
+				"gen_ai.usage.input_tokens":      int64(10),
+				"gen_ai.usage.output_tokens":     int64(20),
+			},
+		},
+		{
+			name: "anthropic stream",
+			url:  "https://api.anthropic.com/v1/messages",
+			body: `{"model": "claude-sonnet-4-0", "stream": true}`,
+			events: []string{
+				"event: message_start\ndata: {\"type\": \"message_start\", \"message\": {\"id\": \"msg_1\", \"model\": \"claude-sonnet-4-20250514\", \"stop_reason\": null, \"usage\": {\"input_tokens\": 5, \"cache_read_input_tokens\": 100, \"output_tokens\": 1}}}\n\n",
+				"event: content_block_delta\ndata: {\"type\": \"content_block_delta\", \"index\": 0, \"delta\": {\"type\": \"text_delta\", \"text\": \"Hi\"}}\n\n",
+				"event: message_delta\ndata: {\"type\": \"message_delta\", \"delta\": {\"stop_reason\": \"end_turn\"}, \"usage\": {\"output_tokens\": 12}}\n\n",
+			},
+			stream: true,
+			want: map[string]any{
+				"gen_ai.system":                  "anthropic",
+				"gen_ai.request.model":           "claude-sonnet-4-0",
+				"gen_ai.response.model":          "claude-sonnet-4-20250514",
+				"gen_ai.response.id":             "msg_1",
+				"gen_ai.response.finish_reasons": []string{"end_turn"},
+				"gen_ai.usage.input_tokens":      int64(105),
+				"gen_ai.usage.output_tokens":     int64(12),
+			},
+		},
+		{
+			name: "gemini",
+			url:  "https://generativelanguage.googleapis.com/v1beta/models/gemini-2.5-flash:generateContent",
+			body: `{"contents": []}`,
+			events: []string{`{
+				"responseId": "resp-1",
+				"modelVersion": "gemini-2.5-flash",
+				"candidates": [{"finishReason": "STOP"}],
+				"usageMetadata": {"promptTokenCount": 7, "candidatesTokenCount": 3, "thoughtsTokenCount": 4}
+			}`},
+			want: map[string]any{
+				"gen_ai.system":                  "gcp.gemini",
+				"gen_ai.request.model":           "gemini-2.5-flash",
+				"gen_ai.response.model":          "gemini-2.5-flash",
+				"gen_ai.response.id":             "resp-1",
+				"gen_ai.response.finish_reasons": []string{"STOP"},
+				"gen_ai.usage.input_tokens":      int64(7),
+				"gen_ai.usage.output_tokens":     int64(7),
+			},
+		},
+		{
+			name: "unknown host",
+			url:  "http://localhost:8080/v1/chat/completions",
+			body: `not json`,
+			want: map[string]any{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodPost, tt.url, strings.NewReader(tt.body))
+			require.NoError(t, err)
+
+			c := newGenAICall(req, []byte(tt.body))
+			for _, event := range tt.events {
+				if tt.stream {
+					// Split events across reads.
+					c.observeStream([]byte(event[:len(event)/2]))
+					c.observeStream([]byte(event[len(event)/2:]))
+				} else {
+					c.observe([]byte(event))
+				}
+			}
+
+			assert.Equal(t, tt.want, attrMap(c.attrs()))
+		})
+	}
+}
@@ -0,0 +1,214 @@
+package llms
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// GenerateAllOption configures GenerateAll.
+type GenerateAllOption func(*generateAllOptions)
+
+type generateAllOptions struct {
+	concurrency      int
+	limiter          *RateLimiter
+	model            string
+	rateLimitRetries int
+}
+
+// Concurrency sets the maximum number of requests GenerateAll sends at the
+// same time. Defaults to 4.
+func Concurrency(n int) GenerateAllOption {
+	return func(o *generateAllOptions) {
+		o.concurrency = n
+	}
+}
+
+// WithLimiter makes GenerateAll wait on limiter for model before every
+// request, so that a large batch stays within the account's limits instead of
+// running into them.
+func WithLimiter(limiter *RateLimiter, model string) GenerateAllOption {
+	return func(o *generateAllOptions) {
+		o.limiter = limiter
+		o.model = model
+	}
+}
+
+// RateLimitRetries sets the number of times GenerateAll retries a request
+// rejected by the provider's rate limit. Defaults to 3; a negative value
+// disables retries.
+func RateLimitRetries(n int) GenerateAllOption {
+	return func(o *generateAllOptions) {
+		o.rateLimitRetries = n
+	}
+}
+
+// GenerateResult is the outcome of one of the requests sent by GenerateAll.
+type GenerateResult struct {
+	// Response is the response to the request, or nil if it failed.
+	Response *Response
+	// Err is the error the request failed with.
+	Err error
+}
+
+// GenerateAllResult holds the outcome of GenerateAll.
+type GenerateAllResult struct {
+	// Results holds the result of every request, in the order of the
+	// requests.
+	Results []GenerateResult
+	// Usage is the total usage of the successful requests.
+	Usage Usage
+	// Failed is the number of requests that failed.
+	Failed int
+}
+
+// Err returns the errors of the failed requests joined together, each
+// prefixed with the index of its request, or nil if none failed.
+func (r *GenerateAllResult) Err() error {
+	var errs []error
+	for i, result := range r.Results {
+		if result.Err != nil {
+			errs = append(errs, fmt.Errorf("request %d: %w", i, result.Err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// rateLimitBackoff is the pause after a rate limit error without a
+// Retry-After delay, doubled for every further retry of the same request.
+var rateLimitBackoff = time.Second
+
+// GenerateAll sends every request in requests to llm using a pool of workers,
+// and returns the responses in the same order. A failed request does not stop
+// the others: its error is reported in its GenerateResult.
+//
+// When the provider rejects a request with a rate limit error, every worker
+// pauses for the delay the provider asked for, or an exponential backoff,
+// before the request is retried. Use WithLimiter to stay within known limits
+// in the first place. If ctx is done, the requests not yet sent fail with
+// its error.
+//
+//	result := llms.GenerateAll(ctx, client, requests, llms.Concurrency(8))
+//	if err := result.Err(); err != nil {
+//		log.Printf("%d of %d requests failed: %v", result.Failed, len(requests), err)
+//	}
+func GenerateAll(ctx context.Context, llm LLM, requests [][]Message, opts ...GenerateAllOption) *GenerateAllResult {
+	options := generateAllOptions{concurrency: 4, rateLimitRetries: 3}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	workers := options.concurrency
+	if workers <= 0 || workers > len(requests) {
+		workers = len(requests)
+	}
+
+	g := &generateAll{llm: llm, options: options}
+	results := make([]GenerateResult, len(requests))
+	indexes := make(chan int)
+
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				results[i] = g.generate(ctx, requests[i])
+			}
+		}()
+	}
+
+	for i := range requests {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	out := &GenerateAllResult{Results: results}
+	for _, result := range results {
+		switch {
+		case result.Err != nil:
+			out.Failed++
+		case result.Response != nil && result.Response.Usage != nil:
+			out.Usage = out.Usage.Add(*result.Response.Usage)
+		}
+	}
+	return out
+}
+
+// generateAll holds the state shared by the workers of GenerateAll.
+type generateAll struct {
+	llm     LLM
+	options generateAllOptions
+
+	// resumeAt is when the workers may send requests again after a rate
+	// limit error.
+	mu       sync.Mutex
+	resumeAt time.Time
+}
+
+// generate sends messages, retrying rate limit errors.
+func (g *generateAll) generate(ctx context.Context, messages []Message) GenerateResult {
+	for attempt := 0; ; attempt++ {
+		if err := g.wait(ctx); err != nil {
+			return GenerateResult{Err: err}
+		}
+		if g.options.limiter != nil {
+			if err := g.options.limiter.Wait(ctx, g.options.model, EstimateTokens(messages)); err != nil {
+				return GenerateResult{Err: err}
+			}
+		}
+
+		resp, err := g.llm.Generate(ctx, messages)
+		if err == nil {
+			return GenerateResult{Response: resp}
+		}
+
+		var apiErr *APIError
+		if attempt >= g.options.rateLimitRetries || !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusTooManyRequests {
+			return GenerateResult{Err: err}
+		}
+		delay := apiErr.RetryAfter
+		if delay <= 0 {
+			delay = rateLimitBackoff << attempt
+		}
+		g.pause(delay)
+	}
+}
+
+// pause stops every worker from sending requests for delay.
+func (g *generateAll) pause(delay time.Duration) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if resumeAt := time.Now().Add(delay); resumeAt.After(g.resumeAt) {
+		g.resumeAt = resumeAt
+	}
+}
+
+// wait blocks until the workers may send requests, or until ctx is done.
+func (g *generateAll) wait(ctx context.Context) error {
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		g.mu.Lock()
+		delay := time.Until(g.resumeAt)
+		g.mu.Unlock()
+		if delay <= 0 {
+			return nil
+		}
+
+		// Another worker may push resumeAt further while this one sleeps,
+		// so it is checked again afterwards.
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
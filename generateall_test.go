@@ -0,0 +1,142 @@
+package llms
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// batchLLM answers each request with its text, recording how many requests
+// are in flight. Requests saying "fail" fail, and requests saying "limited"
+// are rejected by the rate limit the first time.
+type batchLLM struct {
+	mu          sync.Mutex
+	inFlight    int
+	maxInFlight int
+	limited     bool
+	calledAt    []time.Time
+}
+
+func (b *batchLLM) Generate(ctx context.Context, messages []Message) (*Response, error) {
+	text := messageText(messages[0])
+
+	b.mu.Lock()
+	b.inFlight++
+	b.maxInFlight = max(b.maxInFlight, b.inFlight)
+	b.calledAt = append(b.calledAt, time.Now())
+	limited := text == "limited" && !b.limited
+	b.limited = b.limited || limited
+	b.mu.Unlock()
+
+	time.Sleep(5 * time.Millisecond)
+
+	b.mu.Lock()
+	b.inFlight--
+	b.mu.Unlock()
+
+	switch {
+	case limited:
+		return nil, &APIError{Provider: "test", StatusCode: http.StatusTooManyRequests, RetryAfter: 20 * time.Millisecond, Err: errors.New("slow down")}
+	case text == "fail":
+		return nil, errors.New("boom")
+	}
+	resp := textResponse(text)
+	resp.Usage = &Usage{InputTokens: 10, OutputTokens: 2, Cost: 0.5}
+	return resp, nil
+}
+
+func (b *batchLLM) GenerateStream(ctx context.Context, messages []Message, fn StreamFunc) (*Response, error) {
+	return b.Generate(ctx, messages)
+}
+
+func TestGenerateAll(t *testing.T) {
+	llm := &batchLLM{}
+	requests := make([][]Message, 10)
+	for i := range requests {
+		requests[i] = []Message{NewTextMessage(RoleUser, fmt.Sprint(i))}
+	}
+	requests[3] = []Message{NewTextMessage(RoleUser, "fail")}
+
+	result := GenerateAll(context.Background(), llm, requests, Concurrency(3))
+
+	require.Len(t, result.Results, 10)
+	for i, r := range result.Results {
+		if i == 3 {
+			assert.EqualError(t, r.Err, "boom")
+			assert.Nil(t, r.Response)
+			continue
+		}
+		require.NoError(t, r.Err)
+		assert.Equal(t, fmt.Sprint(i), messageText(r.Response.Message))
+	}
+	assert.Equal(t, 3, llm.maxInFlight)
+	assert.Equal(t, 1, result.Failed)
+	assert.Equal(t, Usage{InputTokens: 90, OutputTokens: 18, Cost: 4.5}, result.Usage)
+	assert.EqualError(t, result.Err(), "request 3: boom")
+}
+
+func TestGenerateAll_RateLimited(t *testing.T) {
+	llm := &batchLLM{}
+	requests := [][]Message{
+		{NewTextMessage(RoleUser, "limited")},
+		{NewTextMessage(RoleUser, "a")},
+		{NewTextMessage(RoleUser, "b")},
+	}
+
+	start := time.Now()
+	result := GenerateAll(context.Background(), llm, requests, Concurrency(1))
+	require.NoError(t, result.Err())
+	assert.Equal(t, "limited", messageText(result.Results[0].Response.Message))
+
+	// The request is retried after the provider's Retry-After delay, and the
+	// others wait for it too.
+	require.Len(t, llm.calledAt, 4)
+	assert.GreaterOrEqual(t, llm.calledAt[1].Sub(start), 20*time.Millisecond)
+}
+
+func TestGenerateAll_NoRateLimitRetries(t *testing.T) {
+	llm := &batchLLM{}
+	result := GenerateAll(context.Background(), llm, [][]Message{{NewTextMessage(RoleUser, "limited")}}, RateLimitRetries(-1))
+
+	var apiErr *APIError
+	require.ErrorAs(t, result.Results[0].Err, &apiErr)
+	assert.Equal(t, http.StatusTooManyRequests, apiErr.StatusCode)
+	assert.Equal(t, 1, result.Failed)
+}
+
+func TestGenerateAll_Limiter(t *testing.T) {
+	limiter := NewRateLimiter(map[string]RateLimit{"*": {RequestsPerMinute: 2}})
+	now := time.Unix(0, 0)
+	limiter.now = func() time.Time { return now }
+
+	llm := &batchLLM{}
+	requests := [][]Message{
+		{NewTextMessage(RoleUser, "a")},
+		{NewTextMessage(RoleUser, "b")},
+		{NewTextMessage(RoleUser, "c")},
+	}
+
+	// The third request would wait 30s for the limiter, so it fails with the
+	// context's error instead.
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	result := GenerateAll(ctx, llm, requests, Concurrency(1), WithLimiter(limiter, "model"))
+
+	assert.NoError(t, result.Results[0].Err)
+	assert.NoError(t, result.Results[1].Err)
+	assert.ErrorIs(t, result.Results[2].Err, context.DeadlineExceeded)
+	assert.Len(t, llm.calledAt, 2)
+}
+
+func TestGenerateAll_Empty(t *testing.T) {
+	result := GenerateAll(context.Background(), &batchLLM{}, nil)
+	assert.Empty(t, result.Results)
+	assert.NoError(t, result.Err())
+}
@@ -0,0 +1,279 @@
+package llms
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+)
+
+// Guardrail checks the text generated by a model. See Guardrails.
+type Guardrail interface {
+	// Check returns a violation if text breaks the guardrail's rule, or nil
+	// if it doesn't. An error means the check itself failed.
+	Check(ctx context.Context, text string) (*GuardrailViolation, error)
+}
+
+// GuardrailFunc is an adapter to allow the use of ordinary functions as a
+// Guardrail.
+type GuardrailFunc func(ctx context.Context, text string) (*GuardrailViolation, error)
+
+func (f GuardrailFunc) Check(ctx context.Context, text string) (*GuardrailViolation, error) {
+	return f(ctx, text)
+}
+
+// GuardrailViolation describes how generated text breaks a guardrail's rule.
+type GuardrailViolation struct {
+	// Guardrail names the rule that was broken, such as "max_length".
+	Guardrail string
+	// Reason describes the violation. It is sent to the model when
+	// re-prompting, so it should say what to change.
+	Reason string
+	// Redacted is the text with the violating content removed, for
+	// guardrails that can redact it. If it is empty the violation cannot be
+	// redacted, and GuardrailRedact blocks the response instead.
+	Redacted string
+}
+
+// GuardrailError is returned by the Guardrails middleware when it blocks a
+// response.
+type GuardrailError struct {
+	Violation *GuardrailViolation
+}
+
+func (e *GuardrailError) Error() string {
+	return fmt.Sprintf("llms: response blocked by guardrail %q: %s", e.Violation.Guardrail, e.Violation.Reason)
+}
+
+// BanPatterns returns a Guardrail that rejects text matching any of patterns.
+// Matches are redacted by replacing them with "[redacted]".
+func BanPatterns(patterns ...*regexp.Regexp) Guardrail {
+	return GuardrailFunc(func(ctx context.Context, text string) (*GuardrailViolation, error) {
+		for _, re := range patterns {
+			if !re.MatchString(text) {
+				continue
+			}
+
+			redacted := text
+			for _, re := range patterns {
+				redacted = re.ReplaceAllString(redacted, "[redacted]")
+			}
+			return &GuardrailViolation{
+				Guardrail: "banned_pattern",
+				Reason:    fmt.Sprintf("the response contains text matching %q, which is not allowed", re),
+				Redacted:  redacted,
+			}, nil
+		}
+		return nil, nil
+	})
+}
+
+// MaxLength returns a Guardrail that rejects text longer than n characters.
+// Text is redacted by truncating it.
+func MaxLength(n int) Guardrail {
+	return GuardrailFunc(func(ctx context.Context, text string) (*GuardrailViolation, error) {
+		runes := []rune(text)
+		if len(runes) <= n {
+			return nil, nil
+		}
+		return &GuardrailViolation{
+			Guardrail: "max_length",
+			Reason:    fmt.Sprintf("the response is %d characters long, but must be at most %d", len(runes), n),
+			Redacted:  string(runes[:n]),
+		}, nil
+	})
+}
+
+// GuardrailAction is what the Guardrails middleware does with a response that
+// breaks a guardrail.
+type GuardrailAction int
+
+const (
+	// GuardrailBlock fails the call with a *GuardrailError.
+	GuardrailBlock GuardrailAction = iota
+	// GuardrailRedact returns the response with the violating content
+	// redacted, or blocks it if the guardrail cannot redact.
+	GuardrailRedact
+	// GuardrailReprompt sends the response back to the model with the
+	// reason it was rejected, asking for a new one.
+	GuardrailReprompt
+)
+
+// GuardrailConfig configures the Guardrails middleware.
+type GuardrailConfig struct {
+	// Guardrails are checked in order against the text of every response.
+	Guardrails []Guardrail
+	// Action is what to do with a response that breaks a guardrail.
+	// Defaults to GuardrailBlock.
+	Action GuardrailAction
+	// MaxReprompts is the maximum number of times the model is asked for a
+	// new response with GuardrailReprompt, after which the response is
+	// blocked. Defaults to 2.
+	MaxReprompts int
+}
+
+// Guardrails returns a Middleware that checks the text of every response
+// against config.Guardrails and handles violations according to
+// config.Action. Responses without text, such as those that only call tools,
+// are not checked.
+//
+//	client := llms.Chain(anthropic.New(), llms.Guardrails(llms.GuardrailConfig{
+//		Guardrails: []llms.Guardrail{
+//			llms.BanPatterns(regexp.MustCompile(`(?i)internal use only`)),
+//			llms.MaxLength(2000),
+//		},
+//		Action: llms.GuardrailReprompt,
+//	}))
+//
+// With GenerateStream, the chunks are held back until the response is
+// complete and has been checked, so that text breaking a guardrail is never
+// shown. The StreamFunc then receives the final response, redacted if need
+// be, once.
+func Guardrails(config GuardrailConfig) Middleware {
+	if config.MaxReprompts <= 0 {
+		config.MaxReprompts = 2
+	}
+	return func(llm LLM) LLM {
+		return &guarded{llm: llm, config: config}
+	}
+}
+
+type guarded struct {
+	llm    LLM
+	config GuardrailConfig
+}
+
+func (g *guarded) ModelName() string {
+	return modelName(g.llm)
+}
+
+func (g *guarded) Generate(ctx context.Context, messages []Message) (*Response, error) {
+	return g.generate(ctx, messages, func(messages []Message) (*Response, error) {
+		return g.llm.Generate(ctx, messages)
+	})
+}
+
+func (g *guarded) GenerateStream(ctx context.Context, messages []Message, fn StreamFunc) (*Response, error) {
+	resp, err := g.generate(ctx, messages, func(messages []Message) (*Response, error) {
+		return g.llm.GenerateStream(ctx, messages, func(*Response, error) bool { return true })
+	})
+	if err == nil && resp != nil {
+		fn(resp, nil)
+	}
+	return resp, err
+}
+
+func (g *guarded) generate(ctx context.Context, messages []Message, generate func([]Message) (*Response, error)) (*Response, error) {
+	for attempt := 0; ; attempt++ {
+		resp, err := generate(messages)
+		if err != nil || resp == nil {
+			return resp, err
+		}
+
+		text := messageText(resp.Message)
+		if text == "" {
+			return resp, nil
+		}
+
+		switch g.config.Action {
+		case GuardrailRedact:
+			redacted, violation, err := g.redact(ctx, text)
+			if err != nil {
+				return nil, err
+			}
+			if violation != nil {
+				return nil, &GuardrailError{Violation: violation}
+			}
+			if redacted == text {
+				return resp, nil
+			}
+
+			out := *resp
+			out.Message = replaceText(resp.Message, redacted)
+			return &out, nil
+
+		case GuardrailReprompt:
+			violation, err := g.check(ctx, text)
+			if err != nil {
+				return nil, err
+			}
+			if violation == nil {
+				return resp, nil
+			}
+			if attempt >= g.config.MaxReprompts {
+				return nil, &GuardrailError{Violation: violation}
+			}
+			// The tool calls of the rejected response are left out, as
+			// they would have no results.
+			rejected := Message{Role: resp.Message.Role}
+			for _, part := range resp.Message.Parts {
+				if _, ok := part.(ToolCallPart); !ok {
+					rejected.Parts = append(rejected.Parts, part)
+				}
+			}
+			messages = append(append([]Message(nil), messages...), rejected, NewTextMessage(RoleUser, fmt.Sprintf(
+				"Your response was rejected because %s. Respond again, following this rule.", violation.Reason)))
+
+		default:
+			violation, err := g.check(ctx, text)
+			if err != nil {
+				return nil, err
+			}
+			if violation != nil {
+				return nil, &GuardrailError{Violation: violation}
+			}
+			return resp, nil
+		}
+	}
+}
+
+// check returns the first violation of text.
+func (g *guarded) check(ctx context.Context, text string) (*GuardrailViolation, error) {
+	for _, guardrail := range g.config.Guardrails {
+		violation, err := guardrail.Check(ctx, text)
+		if err != nil {
+			return nil, fmt.Errorf("llms: guardrail check failed: %w", err)
+		}
+		if violation != nil {
+			return violation, nil
+		}
+	}
+	return nil, nil
+}
+
+// redact applies the redactions of every guardrail in turn. It returns the
+// first violation that cannot be redacted, if any.
+func (g *guarded) redact(ctx context.Context, text string) (string, *GuardrailViolation, error) {
+	for _, guardrail := range g.config.Guardrails {
+		violation, err := guardrail.Check(ctx, text)
+		if err != nil {
+			return "", nil, fmt.Errorf("llms: guardrail check failed: %w", err)
+		}
+		if violation == nil {
+			continue
+		}
+		if violation.Redacted == "" {
+			return "", violation, nil
+		}
+		text = violation.Redacted
+	}
+	return text, nil, nil
+}
+
+// replaceText returns a copy of msg whose text parts are replaced by a single
+// part holding text, in place of the first.
+func replaceText(msg Message, text string) Message {
+	parts := make([]Part, 0, len(msg.Parts))
+	replaced := false
+	for _, part := range msg.Parts {
+		if _, ok := part.(TextPart); ok {
+			if !replaced {
+				parts = append(parts, TextPart{Text: text})
+				replaced = true
+			}
+			continue
+		}
+		parts = append(parts, part)
+	}
+	msg.Parts = parts
+	return msg
+}
@@ -0,0 +1,152 @@
+package llms
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var banSecret = BanPatterns(regexp.MustCompile(`secret-\d+`))
+
+func TestBanPatterns(t *testing.T) {
+	violation, err := banSecret.Check(context.Background(), "use secret-1 and secret-22")
+	require.NoError(t, err)
+	require.NotNil(t, violation)
+	assert.Equal(t, "banned_pattern", violation.Guardrail)
+	assert.Equal(t, "use [redacted] and [redacted]", violation.Redacted)
+
+	violation, err = banSecret.Check(context.Background(), "nothing to see")
+	require.NoError(t, err)
+	assert.Nil(t, violation)
+}
+
+func TestMaxLength(t *testing.T) {
+	violation, err := MaxLength(3).Check(context.Background(), "héllo")
+	require.NoError(t, err)
+	require.NotNil(t, violation)
+	assert.Equal(t, "the response is 5 characters long, but must be at most 3", violation.Reason)
+	assert.Equal(t, "hél", violation.Redacted)
+
+	violation, err = MaxLength(5).Check(context.Background(), "héllo")
+	require.NoError(t, err)
+	assert.Nil(t, violation)
+}
+
+func TestGuardrails_Block(t *testing.T) {
+	fake := newFakeLLM(fakeResult{resp: textResponse("the code is secret-1")})
+	llm := Chain(fake, Guardrails(GuardrailConfig{Guardrails: []Guardrail{banSecret}}))
+
+	_, err := llm.Generate(context.Background(), nil)
+	var guardErr *GuardrailError
+	require.ErrorAs(t, err, &guardErr)
+	assert.Equal(t, "banned_pattern", guardErr.Violation.Guardrail)
+
+	// Responses without text, such as tool calls, are not checked.
+	fake = newFakeLLM(fakeResult{resp: toolCallResponse(ToolCallPart{ID: "call_1", Name: "echo"})})
+	llm = Chain(fake, Guardrails(GuardrailConfig{Guardrails: []Guardrail{MaxLength(0)}}))
+	_, err = llm.Generate(context.Background(), nil)
+	assert.NoError(t, err)
+}
+
+func TestGuardrails_Redact(t *testing.T) {
+	resp := textResponse("the code is secret-1")
+	resp.Message.Parts = append(resp.Message.Parts, ToolCallPart{ID: "call_1", Name: "echo"})
+	fake := newFakeLLM(fakeResult{resp: resp, chunks: []*Response{textResponse("the code")}})
+	llm := Chain(fake, Guardrails(GuardrailConfig{
+		Guardrails: []Guardrail{banSecret, MaxLength(15)},
+		Action:     GuardrailRedact,
+	}))
+
+	var streamed []string
+	out, err := llm.GenerateStream(context.Background(), nil, func(resp *Response, err error) bool {
+		streamed = append(streamed, messageText(resp.Message))
+		return true
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []Part{TextPart{Text: "the code is [re"}, ToolCallPart{ID: "call_1", Name: "echo"}}, out.Message.Parts)
+	assert.Equal(t, "the code is secret-1", messageText(resp.Message), "the provider's response is not modified")
+	// Only the redacted response is streamed.
+	assert.Equal(t, []string{"the code is [re"}, streamed)
+
+	// Violations that cannot be redacted are blocked.
+	refuse := GuardrailFunc(func(ctx context.Context, text string) (*GuardrailViolation, error) {
+		return &GuardrailViolation{Guardrail: "refuse", Reason: "no"}, nil
+	})
+	llm = Chain(fake, Guardrails(GuardrailConfig{Guardrails: []Guardrail{refuse}, Action: GuardrailRedact}))
+	_, err = llm.Generate(context.Background(), nil)
+	var guardErr *GuardrailError
+	require.ErrorAs(t, err, &guardErr)
+	assert.Equal(t, "refuse", guardErr.Violation.Guardrail)
+}
+
+func TestGuardrails_Reprompt(t *testing.T) {
+	fake := newFakeLLM(
+		fakeResult{resp: textResponse("the code is secret-1")},
+		fakeResult{resp: textResponse("I can't share it")},
+	)
+	llm := Chain(fake, Guardrails(GuardrailConfig{Guardrails: []Guardrail{banSecret}, Action: GuardrailReprompt}))
+
+	input := []Message{NewTextMessage(RoleUser, "what's the code?")}
+	resp, err := llm.Generate(context.Background(), input)
+	require.NoError(t, err)
+	assert.Equal(t, "resp_I can't share it", resp.ID)
+
+	received := fake.Received()
+	require.Len(t, received, 3)
+	assert.Equal(t, "the code is secret-1", messageText(received[1]))
+	assert.Equal(t, `Your response was rejected because the response contains text matching "secret-\\d+", which is not allowed. Respond again, following this rule.`, messageText(received[2]))
+	assert.Len(t, input, 1)
+
+	// The tool calls of a rejected response are not sent back without
+	// their results.
+	rejected := textResponse("the code is secret-1")
+	rejected.Message.Parts = append(rejected.Message.Parts, ToolCallPart{ID: "call_1", Name: "echo"})
+	fake = newFakeLLM(fakeResult{resp: rejected}, fakeResult{resp: textResponse("I can't share it")})
+	llm = Chain(fake, Guardrails(GuardrailConfig{Guardrails: []Guardrail{banSecret}, Action: GuardrailReprompt}))
+	_, err = llm.Generate(context.Background(), input)
+	require.NoError(t, err)
+	assert.Equal(t, []Part{TextPart{Text: "the code is secret-1"}}, fake.Received()[1].Parts)
+
+	// The response is blocked once the reprompts run out.
+	fake = newFakeLLM(fakeResult{resp: textResponse("secret-1")})
+	llm = Chain(fake, Guardrails(GuardrailConfig{Guardrails: []Guardrail{banSecret}, Action: GuardrailReprompt, MaxReprompts: 1}))
+	_, err = llm.Generate(context.Background(), input)
+	var guardErr *GuardrailError
+	require.ErrorAs(t, err, &guardErr)
+	assert.Equal(t, 2, fake.Calls())
+}
+
+func TestGuardrails_StreamBlocked(t *testing.T) {
+	fake := newFakeLLM(fakeResult{
+		resp:   textResponse("the code is secret-1"),
+		chunks: []*Response{textResponse("the code"), textResponse("the code is secret-1")},
+	})
+	llm := Chain(fake, Guardrails(GuardrailConfig{Guardrails: []Guardrail{banSecret}}))
+
+	// Nothing of a blocked response is streamed.
+	streamed := 0
+	_, err := llm.GenerateStream(context.Background(), nil, func(resp *Response, err error) bool {
+		streamed++
+		return true
+	})
+	var guardErr *GuardrailError
+	require.ErrorAs(t, err, &guardErr)
+	assert.Zero(t, streamed)
+}
+
+func TestGuardrails_CheckError(t *testing.T) {
+	boom := errors.New("boom")
+	failing := GuardrailFunc(func(ctx context.Context, text string) (*GuardrailViolation, error) {
+		return nil, boom
+	})
+
+	for _, action := range []GuardrailAction{GuardrailBlock, GuardrailRedact, GuardrailReprompt} {
+		llm := Chain(newFakeLLM(fakeResult{resp: textResponse("hi")}), Guardrails(GuardrailConfig{Guardrails: []Guardrail{failing}, Action: action}))
+		_, err := llm.Generate(context.Background(), nil)
+		assert.ErrorIs(t, err, boom)
+	}
+}
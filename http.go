@@ -2,30 +2,130 @@ package llms
 
 import (
 	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
 	"fmt"
 	"io"
 	"log/slog"
+	"maps"
+	"math/rand/v2"
+	"mime"
+	"net"
 	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"slices"
+	"sync/atomic"
 	"time"
+
+	"github.com/google/uuid"
 )
 
 type HTTPClientOptions struct {
 	// LogRequests indicates whether to log HTTP requests.
 	LogRequests bool
 	// Logger is the logger to use for logging HTTP requests and responses.
+	// Defaults to HTTPLogger() when LogRequests is set.
 	Logger *slog.Logger
 	// Config is the configuration for logging HTTP requests and responses.
 	Config *LoggingConfig
+	// Transport is the underlying transport requests are sent with, such as a
+	// Recorder. Defaults to http.DefaultTransport.
+	Transport http.RoundTripper
+	// Retry, if set, retries failed requests with a RetryTransport. Each
+	// attempt is logged separately. Its Transport is replaced by the logging
+	// transport, or by Transport.
+	Retry *RetryTransportConfig
+	// Metrics, if set, records the metrics of each request, and of each
+	// attempt when retrying, with a MetricsRoundTripper.
+	Metrics HTTPMetrics
+	// Dump, if set, writes each request and its response, and each attempt
+	// when retrying, to a directory with a DumpTransport. Its Transport is
+	// replaced by Transport.
+	Dump *DumpConfig
+
+	// ProxyURL, RootCAs and TLSConfig configure a copy of Transport if it is
+	// an *http.Transport, or of http.DefaultTransport if Transport is nil.
+	// They are ignored for other transports.
+	//
+	// ProxyURL, if set, sends requests through the proxy at this URL instead
+	// of the proxy set by the HTTPS_PROXY and HTTP_PROXY environment
+	// variables.
+	ProxyURL *url.URL
+	// RootCAs, if set, are the certificate authorities server certificates
+	// are verified with, such as a pool created by LoadCABundle.
+	RootCAs *x509.CertPool
+	// TLSConfig, if set, is the TLS configuration of connections, for client
+	// certificates or a minimum TLS version. RootCAs takes precedence over
+	// its RootCAs.
+	TLSConfig *tls.Config
+
+	// The timeouts and connection pooling below also configure a copy of
+	// Transport, or a new transport if it is nil. Zero fields use the
+	// defaults of DefaultTransportOptions for a new transport and keep the
+	// settings of Transport otherwise. Negative timeouts disable the timeout.
+	// There is no overall timeout, as streamed responses can take minutes.
+	//
+	// DialTimeout limits how long connecting to the server takes.
+	DialTimeout time.Duration
+	// TLSHandshakeTimeout limits how long the TLS handshake takes.
+	TLSHandshakeTimeout time.Duration
+	// ResponseHeaderTimeout limits how long the server takes to send the
+	// response headers once the request is sent. Responses that are not
+	// streamed are only sent once they have been fully generated, so it must
+	// allow for the longest generation.
+	ResponseHeaderTimeout time.Duration
+	// IdleConnTimeout is how long idle connections are kept open.
+	IdleConnTimeout time.Duration
+	// MaxIdleConnsPerHost is the number of idle connections kept open to
+	// each host, which should allow for the number of concurrent requests.
+	MaxIdleConnsPerHost int
+}
+
+// DefaultTransportOptions returns the timeouts and connection pooling of the
+// transports created by NewHTTPClient.
+func DefaultTransportOptions() HTTPClientOptions {
+	return HTTPClientOptions{
+		DialTimeout:           30 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ResponseHeaderTimeout: 10 * time.Minute,
+		IdleConnTimeout:       90 * time.Second,
+		MaxIdleConnsPerHost:   16,
+	}
 }
 
 // NewHTTPClient creates an http.Client with the provided options
 func NewHTTPClient(options HTTPClientOptions) *http.Client {
+	options.Transport = configureTransport(options)
+	if options.Dump != nil {
+		dump := *options.Dump
+		dump.Transport = options.Transport
+		options.Transport = NewDumpTransport(dump)
+	}
+	if options.Metrics != nil {
+		options.Transport = NewMetricsRoundTripper(options.Transport, options.Metrics)
+	}
+
+	if options.Retry != nil {
+		retry := *options.Retry
+		retry.Transport = NewHTTPClient(HTTPClientOptions{
+			LogRequests: options.LogRequests,
+			Logger:      options.Logger,
+			Config:      options.Config,
+			Transport:   options.Transport,
+		}).Transport
+		return &http.Client{Transport: NewRetryTransport(retry)}
+	}
+
 	if options.LogRequests == false && options.Logger == nil {
-		return http.DefaultClient
+		return &http.Client{Transport: options.Transport}
 	}
 
 	if options.Logger == nil {
-		options.Logger = slog.Default()
+		options.Logger = HTTPLogger()
 	}
 	if options.Config == nil {
 		options.Config = &LoggingConfig{
@@ -36,10 +136,93 @@ func NewHTTPClient(options HTTPClientOptions) *http.Client {
 		}
 	}
 	return &http.Client{
-		Transport: NewLoggingRoundTripper(http.DefaultTransport, options.Logger, *options.Config),
+		Transport: NewLoggingRoundTripper(options.Transport, options.Logger, *options.Config),
 	}
 }
 
+// configureTransport returns a copy of the transport of options, or a new
+// transport, with its proxy, TLS, timeout and pooling settings applied.
+func configureTransport(options HTTPClientOptions) http.RoundTripper {
+	var transport *http.Transport
+	switch base := options.Transport.(type) {
+	case nil:
+		transport = http.DefaultTransport.(*http.Transport).Clone()
+
+		defaults := DefaultTransportOptions()
+		if options.DialTimeout == 0 {
+			options.DialTimeout = defaults.DialTimeout
+		}
+		if options.TLSHandshakeTimeout == 0 {
+			options.TLSHandshakeTimeout = defaults.TLSHandshakeTimeout
+		}
+		if options.ResponseHeaderTimeout == 0 {
+			options.ResponseHeaderTimeout = defaults.ResponseHeaderTimeout
+		}
+		if options.IdleConnTimeout == 0 {
+			options.IdleConnTimeout = defaults.IdleConnTimeout
+		}
+		if options.MaxIdleConnsPerHost == 0 {
+			options.MaxIdleConnsPerHost = defaults.MaxIdleConnsPerHost
+		}
+	case *http.Transport:
+		transport = base.Clone()
+	default:
+		return options.Transport
+	}
+
+	if options.DialTimeout != 0 {
+		transport.DialContext = (&net.Dialer{
+			Timeout:   max(options.DialTimeout, 0),
+			KeepAlive: 30 * time.Second,
+		}).DialContext
+	}
+	if options.TLSHandshakeTimeout != 0 {
+		transport.TLSHandshakeTimeout = max(options.TLSHandshakeTimeout, 0)
+	}
+	if options.ResponseHeaderTimeout != 0 {
+		transport.ResponseHeaderTimeout = max(options.ResponseHeaderTimeout, 0)
+	}
+	if options.IdleConnTimeout != 0 {
+		transport.IdleConnTimeout = max(options.IdleConnTimeout, 0)
+	}
+	if options.MaxIdleConnsPerHost != 0 {
+		transport.MaxIdleConnsPerHost = options.MaxIdleConnsPerHost
+	}
+
+	if options.ProxyURL != nil {
+		transport.Proxy = http.ProxyURL(options.ProxyURL)
+	}
+	if options.TLSConfig != nil {
+		transport.TLSClientConfig = options.TLSConfig.Clone()
+	}
+	if options.RootCAs != nil {
+		if transport.TLSClientConfig == nil {
+			transport.TLSClientConfig = &tls.Config{}
+		}
+		transport.TLSClientConfig.RootCAs = options.RootCAs
+	}
+	return transport
+}
+
+// LoadCABundle returns the system certificate pool with the PEM encoded
+// certificates in the file at path added, for networks that intercept TLS
+// with their own certificate authority.
+func LoadCABundle(path string) (*x509.CertPool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("llms: failed to read CA bundle: %w", err)
+	}
+
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("llms: no certificates found in CA bundle %s", path)
+	}
+	return pool, nil
+}
+
 // NewHTTPClientWithLogging creates an http.Client with logging transport
 func NewHTTPClientWithLogging(logger *slog.Logger, config LoggingConfig) *http.Client {
 	return &http.Client{
@@ -54,21 +237,203 @@ func NewDefaultHTTPClientWithLogging() *http.Client {
 	}
 }
 
+type httpLoggerKey struct{}
+
+// WithHTTPLogger returns a copy of ctx that makes LoggingRoundTripper log the
+// requests made with it to logger instead of its own logger, for example to
+// add attributes identifying the user or job a request is made for.
+//
+//	ctx = llms.WithHTTPLogger(ctx, logger.With("job_id", jobID))
+//	resp, err := client.Generate(ctx, messages)
+func WithHTTPLogger(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, httpLoggerKey{}, logger)
+}
+
+// HTTPLoggerFromContext returns the logger set by WithHTTPLogger, or nil.
+func HTTPLoggerFromContext(ctx context.Context) *slog.Logger {
+	logger, _ := ctx.Value(httpLoggerKey{}).(*slog.Logger)
+	return logger
+}
+
+// DefaultRequestIDHeader is the header LoggingRoundTripper sends request IDs
+// in. OpenAI echoes it in its own logs.
+const DefaultRequestIDHeader = "X-Client-Request-Id"
+
+type requestIDKey struct{}
+
+// WithRequestID returns a copy of ctx carrying id, so that the HTTP requests
+// made with it, their log records and the Response they produce all share
+// id, for example to correlate them with an inbound request.
+//
+//	ctx = llms.WithRequestID(ctx, r.Header.Get("X-Request-Id"))
+//	resp, err := client.Generate(ctx, messages)
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestIDFromContext returns the ID set by WithRequestID, or "".
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// EnsureRequestID returns ctx and the request ID it carries. If it carries
+// none, a new ID is generated and added to the returned context. Providers
+// call it at the start of each call to fill in Response.RequestID.
+func EnsureRequestID(ctx context.Context) (context.Context, string) {
+	if id := RequestIDFromContext(ctx); id != "" {
+		return ctx, id
+	}
+	id := uuid.NewString()
+	return WithRequestID(ctx, id), id
+}
+
 // LoggingRoundTripper implements http.RoundTripper with logging
 type LoggingRoundTripper struct {
 	transport http.RoundTripper
 	logger    *slog.Logger
 	config    LoggingConfig
+	redacted  map[string]bool // Canonical names of the headers to redact
+	base64    *regexp.Regexp  // Matches the base64 strings omitted from bodies
+	requests  atomic.Uint64   // Number of requests, for sampling
 }
 
 // LoggingConfig controls what gets logged
 type LoggingConfig struct {
+	// Group, if set, nests the attributes of every record under a group of
+	// this name, such as "llmite.http", so that log pipelines can route LLM
+	// traffic separately. It also applies to loggers set by WithHTTPLogger.
+	Group string
+
 	LogHeaders      bool
 	LogRequestBody  bool
 	LogResponseBody bool
 	MaxBodySize     int64 // Maximum body size to log in bytes
+	// RedactHeaders lists headers whose values are logged as "[REDACTED]",
+	// in addition to DefaultRedactedHeaders.
+	RedactHeaders []string
+	// RedactPatterns are matched against logged bodies, and the text they
+	// match is logged as "[REDACTED]".
+	RedactPatterns []*regexp.Regexp
+	// RedactFuncs scrub logged request and response bodies, for redactions
+	// that patterns cannot express, such as removing fields holding personal
+	// data. They are called in order after RedactPatterns, with a copy of the
+	// body that they may modify, and return the body to log.
+	RedactFuncs []func([]byte) []byte
+	// DisableRedaction logs the values of DefaultRedactedHeaders and
+	// DefaultRedactedQueryParams, which are redacted by default.
+	// RedactHeaders and RedactPatterns still apply.
+	DisableRedaction bool
+	// StructuredJSON logs JSON bodies as nested attributes rather than
+	// strings, so that structured handlers such as slog.JSONHandler can
+	// index their fields. Bodies larger than MaxBodySize are still logged as
+	// truncated strings.
+	StructuredJSON bool
+	// IndentJSON indents JSON bodies logged as strings, for reading logs in a
+	// terminal.
+	IndentJSON bool
+	// OmitBase64 replaces base64 encoded strings in bodies, such as images
+	// and documents, with "<omitted N bytes>", so that they neither fill the
+	// logs nor push the rest of the body past MaxBodySize. Data URLs keep
+	// their "data:<media type>;base64," prefix. Streamed bodies are checked
+	// a whole server-sent event at a time.
+	OmitBase64 bool
+	// MinBase64Size is the length of the shortest string omitted by
+	// OmitBase64. Defaults to 1024.
+	MinBase64Size int
+	// Sampling, if set, logs only some requests.
+	Sampling *LogSampling
+	// Levels sets the level each event is logged at.
+	Levels LogLevels
+	// RequestIDHeader is the header the ID of each request is sent in, so
+	// that it can be matched with the provider's logs. Defaults to
+	// DefaultRequestIDHeader. The header is not overwritten if the request
+	// already has it.
+	RequestIDHeader string
+	// DisableRequestIDHeader stops the request ID from being sent. It is
+	// still logged.
+	DisableRequestIDHeader bool
+	// GenAIAttributes adds the attributes of the OpenTelemetry GenAI
+	// semantic conventions, such as gen_ai.request.model and
+	// gen_ai.usage.input_tokens, to the records of requests to LLM
+	// providers. They are parsed from the request and response bodies, which
+	// are read for this even if they are not logged.
+	GenAIAttributes bool
+	// OnRequest, if set, is called with each request before it is logged and
+	// sent, for auditing or to add headers. The request is a copy of the
+	// caller's, so it can be modified.
+	OnRequest func(*http.Request)
+	// OnResponse, if set, is called with each response once its headers have
+	// been received, and the time taken since the request was sent. It must
+	// not read the body. It is not called for requests that fail without a
+	// response.
+	//
+	// The hooks are called by LoggingRoundTripper, so NewHTTPClient only
+	// calls them when logging is enabled.
+	OnResponse func(*http.Response, time.Duration)
 }
 
+// LogSampling selects the requests logged by a LoggingRoundTripper, for
+// services making too many requests to log them all.
+type LogSampling struct {
+	// Every logs one in every Every requests. It takes precedence over Rate.
+	Every int
+	// Rate is the fraction of requests logged, between 0 and 1.
+	Rate float64
+	// LogErrors logs the requests that are not sampled if they fail or
+	// receive a 4xx or 5xx response. Their start is logged with their
+	// completion.
+	LogErrors bool
+}
+
+// LogLevels sets the levels HTTP events are logged at. Nil fields use the
+// default levels. Fields can be a *slog.LevelVar to change levels at runtime.
+type LogLevels struct {
+	// RequestStarted is the level of the event logged before a request is
+	// sent. Defaults to slog.LevelInfo.
+	RequestStarted slog.Leveler
+	// RequestCompleted is the level of the event logged when a response with
+	// a status below 400 is received, and when a streamed response body has
+	// been read. Defaults to slog.LevelInfo.
+	RequestCompleted slog.Leveler
+	// ClientError is the level of responses with a 4xx status. Defaults to
+	// slog.LevelWarn.
+	ClientError slog.Leveler
+	// ServerError is the level of responses with a 5xx status. Defaults to
+	// slog.LevelError.
+	ServerError slog.Leveler
+	// RequestFailed is the level of requests and streams that fail without a
+	// response. Defaults to slog.LevelError.
+	RequestFailed slog.Leveler
+	// StreamChunk is the level of the event logged for each chunk of
+	// server-sent events in a streamed response body read by the caller.
+	// Defaults to slog.LevelDebug.
+	StreamChunk slog.Leveler
+}
+
+// levelOf returns the level of leveler, or def if it is nil.
+func levelOf(leveler slog.Leveler, def slog.Level) slog.Level {
+	if leveler == nil {
+		return def
+	}
+	return leveler.Level()
+}
+
+// forStatus returns the level of a response with status code.
+func (l LogLevels) forStatus(code int) slog.Level {
+	switch {
+	case code >= 500:
+		return levelOf(l.ServerError, slog.LevelError)
+	case code >= 400:
+		return levelOf(l.ClientError, slog.LevelWarn)
+	default:
+		return levelOf(l.RequestCompleted, slog.LevelInfo)
+	}
+}
+
+// redactedValue replaces redacted values in logs.
+const redactedValue = "[REDACTED]"
+
 func DefaultLoggingConfig() LoggingConfig {
 	return LoggingConfig{
 		LogHeaders:      true,
@@ -84,16 +449,44 @@ func NewLoggingRoundTripper(transport http.RoundTripper, logger *slog.Logger, co
 		transport = http.DefaultTransport
 	}
 	if logger == nil {
-		logger = slog.Default()
+		logger = HTTPLogger()
 	}
 	if config.MaxBodySize == 0 {
 		config.MaxBodySize = 1024 // Default 1KB max body logging
 	}
+	if config.RequestIDHeader == "" {
+		config.RequestIDHeader = DefaultRequestIDHeader
+	}
+
+	redacted := make(map[string]bool)
+	if !config.DisableRedaction {
+		for _, name := range DefaultRedactedHeaders {
+			redacted[http.CanonicalHeaderKey(name)] = true
+		}
+	}
+	for _, name := range config.RedactHeaders {
+		redacted[http.CanonicalHeaderKey(name)] = true
+	}
+
+	var base64 *regexp.Regexp
+	if config.OmitBase64 {
+		if config.MinBase64Size <= 0 {
+			config.MinBase64Size = 1024
+		}
+		// Only whole JSON strings are matched, so the body stays valid JSON.
+		base64 = regexp.MustCompile(`"(data:[^";,]+;base64,)?([A-Za-z0-9+/]+={0,2})"`)
+	}
+
+	if config.Group != "" {
+		logger = logger.WithGroup(config.Group)
+	}
 
 	return &LoggingRoundTripper{
 		transport: transport,
 		logger:    logger,
 		config:    config,
+		redacted:  redacted,
+		base64:    base64,
 	}
 }
 
@@ -110,38 +503,81 @@ func NewDefaultLoggingRoundTripper() *LoggingRoundTripper {
 // RoundTrip implements the http.RoundTripper interface
 func (t *LoggingRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
 	start := time.Now()
-	requestID := fmt.Sprintf("%d", start.UnixNano())
+	logger := t.logger
+	if ctxLogger := HTTPLoggerFromContext(req.Context()); ctxLogger != nil {
+		logger = ctxLogger
+		if t.config.Group != "" {
+			logger = logger.WithGroup(t.config.Group)
+		}
+	}
 
 	// Clone the request to avoid modifying the original
 	reqClone := req.Clone(req.Context())
 
+	requestID := RequestIDFromContext(req.Context())
+	if requestID == "" && !t.config.DisableRequestIDHeader {
+		requestID = reqClone.Header.Get(t.config.RequestIDHeader)
+	}
+	if requestID == "" {
+		requestID = uuid.NewString()
+	}
+	if !t.config.DisableRequestIDHeader && reqClone.Header.Get(t.config.RequestIDHeader) == "" {
+		reqClone.Header.Set(t.config.RequestIDHeader, requestID)
+	}
+
+	if t.config.OnRequest != nil {
+		t.config.OnRequest(reqClone)
+	}
+
+	sampled := t.sampled()
+	logErrors := t.config.Sampling != nil && t.config.Sampling.LogErrors
+	if !sampled && !logErrors {
+		resp, err := t.transport.RoundTrip(reqClone)
+		if err == nil && t.config.OnResponse != nil {
+			t.config.OnResponse(resp, time.Since(start))
+		}
+		return resp, err
+	}
+
 	// Build request log attributes
 	reqAttrs := []slog.Attr{
 		slog.String("request_id", requestID),
-		slog.String("method", req.Method),
-		slog.String("url", req.URL.String()),
-		slog.String("user_agent", req.UserAgent()),
-		slog.String("host", req.Host),
+		slog.String("method", reqClone.Method),
+		slog.String("url", t.redactURL(reqClone.URL)),
+		slog.String("user_agent", reqClone.UserAgent()),
+		slog.String("host", reqClone.Host),
 	}
 
 	// Log request headers if enabled
-	if t.config.LogHeaders && len(req.Header) > 0 {
-		headers := make(map[string][]string)
-		for k, v := range req.Header {
-			headers[k] = v
-		}
-		reqAttrs = append(reqAttrs, slog.Any("headers", headers))
+	if t.config.LogHeaders && len(reqClone.Header) > 0 {
+		reqAttrs = append(reqAttrs, slog.Any("headers", t.redactHeaders(reqClone.Header)))
 	}
 
 	// Log request body if enabled
-	if t.config.LogRequestBody && req.Body != nil {
-		if bodyBytes, newBody, err := t.captureRequestBody(req.Body, t.config.MaxBodySize); err == nil {
-			reqAttrs = append(reqAttrs, slog.String("body", string(bodyBytes)))
+	var reqBody []byte
+	if (t.config.LogRequestBody || t.config.GenAIAttributes) && reqClone.Body != nil {
+		if bodyBytes, newBody, err := t.captureRequestBody(reqClone.Body); err == nil {
+			reqBody = bodyBytes
 			reqClone.Body = newBody
 		}
 	}
+	if t.config.LogRequestBody && reqBody != nil {
+		reqAttrs = append(reqAttrs, t.bodyAttr("body", reqBody))
+	}
+
+	var genAI *genAICall
+	if t.config.GenAIAttributes {
+		genAI = newGenAICall(reqClone, reqBody)
+		reqAttrs = append(reqAttrs, genAI.requestAttrs()...)
+	}
 
-	t.logger.LogAttrs(req.Context(), slog.LevelInfo, "HTTP request started", reqAttrs...)
+	logStarted := func() {
+		logger.LogAttrs(req.Context(), levelOf(t.config.Levels.RequestStarted, slog.LevelInfo), "HTTP request started", reqAttrs...)
+	}
+	// Requests that are not sampled are logged once they fail.
+	if sampled {
+		logStarted()
+	}
 
 	// Perform the actual request using the cloned request
 	resp, err := t.transport.RoundTrip(reqClone)
@@ -151,17 +587,32 @@ func (t *LoggingRoundTripper) RoundTrip(req *http.Request) (*http.Response, erro
 	respAttrs := []slog.Attr{
 		slog.String("request_id", requestID),
 		slog.String("method", req.Method),
-		slog.String("url", req.URL.String()),
+		slog.String("url", t.redactURL(req.URL)),
 		slog.Duration("duration", duration),
 	}
 
 	if err != nil {
+		if !sampled {
+			logStarted()
+		}
+
 		// Log error
 		errorAttrs := append(respAttrs, slog.String("error", err.Error()))
-		t.logger.LogAttrs(req.Context(), slog.LevelError, "HTTP request failed", errorAttrs...)
+		logger.LogAttrs(req.Context(), levelOf(t.config.Levels.RequestFailed, slog.LevelError), "HTTP request failed", errorAttrs...)
 		return nil, err
 	}
 
+	if t.config.OnResponse != nil {
+		t.config.OnResponse(resp, duration)
+	}
+
+	if !sampled {
+		if resp.StatusCode < 400 {
+			return resp, nil
+		}
+		logStarted()
+	}
+
 	// Add response-specific attributes
 	respAttrs = append(respAttrs,
 		slog.Int("status_code", resp.StatusCode),
@@ -171,37 +622,56 @@ func (t *LoggingRoundTripper) RoundTrip(req *http.Request) (*http.Response, erro
 
 	// Log response headers if enabled
 	if t.config.LogHeaders && len(resp.Header) > 0 {
-		headers := make(map[string][]string)
-		for k, v := range resp.Header {
-			headers[k] = v
-		}
-		respAttrs = append(respAttrs, slog.Any("response_headers", headers))
+		respAttrs = append(respAttrs, slog.Any("response_headers", t.redactHeaders(resp.Header)))
 	}
 
-	// Log response body if enabled
-	if t.config.LogResponseBody && resp.Body != nil {
-		if bodyBytes, newBody, err := t.captureResponseBody(resp.Body, t.config.MaxBodySize); err == nil {
-			respAttrs = append(respAttrs, slog.String("response_body", string(bodyBytes)))
+	// Log response body if enabled. Streamed bodies are logged as the caller
+	// reads them, rather than read in full before the response is returned.
+	if (t.config.LogResponseBody || genAI != nil) && resp.Body != nil {
+		if isStreaming(resp) {
+			resp.Body = &streamingBodyLogger{
+				body:   resp.Body,
+				t:      t,
+				logger: logger,
+				ctx:    req.Context(),
+				attrs:  respAttrs[:3:3], // request_id, method and url
+				start:  start,
+				genAI:  genAI,
+			}
+		} else if bodyBytes, newBody, err := t.captureResponseBody(resp.Body); err == nil {
+			if t.config.LogResponseBody {
+				respAttrs = append(respAttrs, t.bodyAttr("response_body", bodyBytes))
+			}
+			if genAI != nil {
+				genAI.observe(bodyBytes)
+			}
 			resp.Body = newBody
 		}
 	}
-
-	// Determine log level based on status code
-	level := slog.LevelInfo
-	if resp.StatusCode >= 400 {
-		level = slog.LevelWarn
-	}
-	if resp.StatusCode >= 500 {
-		level = slog.LevelError
+	if genAI != nil && !isStreaming(resp) {
+		respAttrs = append(respAttrs, genAI.attrs()...)
 	}
 
-	t.logger.LogAttrs(req.Context(), level, "HTTP request completed", respAttrs...)
+	logger.LogAttrs(req.Context(), t.config.Levels.forStatus(resp.StatusCode), "HTTP request completed", respAttrs...)
 
 	return resp, nil
 }
 
+// sampled reports whether the next request should be logged.
+func (t *LoggingRoundTripper) sampled() bool {
+	sampling := t.config.Sampling
+	switch {
+	case sampling == nil:
+		return true
+	case sampling.Every > 0:
+		return (t.requests.Add(1)-1)%uint64(sampling.Every) == 0
+	default:
+		return rand.Float64() < sampling.Rate
+	}
+}
+
 // captureRequestBody reads the request body for logging and returns a new body for the request
-func (t *LoggingRoundTripper) captureRequestBody(body io.ReadCloser, maxSize int64) ([]byte, io.ReadCloser, error) {
+func (t *LoggingRoundTripper) captureRequestBody(body io.ReadCloser) ([]byte, io.ReadCloser, error) {
 	if body == nil {
 		return nil, nil, nil
 	}
@@ -215,20 +685,14 @@ func (t *LoggingRoundTripper) captureRequestBody(body io.ReadCloser, maxSize int
 	// Close the original body
 	body.Close()
 
-	// Truncate for logging if necessary
-	logBytes := bodyBytes
-	if int64(len(bodyBytes)) > maxSize {
-		logBytes = bodyBytes[:maxSize]
-	}
-
 	// Create a new body with the full content for the actual request
 	newBody := io.NopCloser(bytes.NewReader(bodyBytes))
 
-	return logBytes, newBody, nil
+	return bodyBytes, newBody, nil
 }
 
 // captureResponseBody reads the response body for logging and returns a new body for the response
-func (t *LoggingRoundTripper) captureResponseBody(body io.ReadCloser, maxSize int64) ([]byte, io.ReadCloser, error) {
+func (t *LoggingRoundTripper) captureResponseBody(body io.ReadCloser) ([]byte, io.ReadCloser, error) {
 	if body == nil {
 		return nil, nil, nil
 	}
@@ -242,14 +706,229 @@ func (t *LoggingRoundTripper) captureResponseBody(body io.ReadCloser, maxSize in
 	// Close the original body
 	body.Close()
 
-	// Truncate for logging if necessary
-	logBytes := bodyBytes
-	if int64(len(bodyBytes)) > maxSize {
-		logBytes = bodyBytes[:maxSize]
-	}
-
 	// Create a new body with the full content for the caller
 	newBody := io.NopCloser(bytes.NewReader(bodyBytes))
 
-	return logBytes, newBody, nil
+	return bodyBytes, newBody, nil
+}
+
+// redactHeaders returns a copy of headers for logging, with the values of
+// redacted headers replaced.
+func (t *LoggingRoundTripper) redactHeaders(headers http.Header) map[string][]string {
+	out := make(map[string][]string, len(headers))
+	for k, v := range headers {
+		if t.redacted[http.CanonicalHeaderKey(k)] {
+			out[k] = []string{redactedValue}
+			continue
+		}
+		out[k] = v
+	}
+	return out
+}
+
+// redactURL returns u for logging, with the values of the query parameters in
+// DefaultRedactedQueryParams replaced.
+func (t *LoggingRoundTripper) redactURL(u *url.URL) string {
+	if t.config.DisableRedaction || u.RawQuery == "" {
+		return u.String()
+	}
+
+	clone := *u
+	q := clone.Query()
+	for _, name := range DefaultRedactedQueryParams {
+		if q.Has(name) {
+			q.Set(name, redactedValue)
+		}
+	}
+	clone.RawQuery = q.Encode()
+	return clone.String()
+}
+
+// bodyAttr returns the attribute body is logged as, with the text matching the
+// redaction patterns replaced. It is redacted before being truncated to
+// MaxBodySize, so that truncation cannot prevent a pattern from matching.
+func (t *LoggingRoundTripper) bodyAttr(key string, body []byte) slog.Attr {
+	if t.base64 != nil {
+		body = t.base64.ReplaceAllFunc(body, func(match []byte) []byte {
+			groups := t.base64.FindSubmatch(match)
+			if len(groups[2]) < t.config.MinBase64Size {
+				return match
+			}
+			return fmt.Appendf(nil, `"%s<omitted %d bytes>"`, groups[1], len(groups[2]))
+		})
+	}
+	for _, pattern := range t.config.RedactPatterns {
+		body = pattern.ReplaceAll(body, []byte(redactedValue))
+	}
+	if len(t.config.RedactFuncs) > 0 {
+		// body may be the request body being sent or the caller's read
+		// buffer, so the funcs are given a copy.
+		body = bytes.Clone(body)
+		for _, redact := range t.config.RedactFuncs {
+			body = redact(body)
+		}
+	}
+
+	isJSON := (t.config.StructuredJSON || t.config.IndentJSON) && json.Valid(body)
+	if isJSON && t.config.StructuredJSON && int64(len(body)) <= t.config.MaxBodySize {
+		return slog.Attr{Key: key, Value: jsonValue(body)}
+	}
+	if isJSON && t.config.IndentJSON {
+		var buf bytes.Buffer
+		if err := json.Indent(&buf, body, "", "  "); err == nil {
+			body = buf.Bytes()
+		}
+	}
+
+	if int64(len(body)) > t.config.MaxBodySize {
+		body = body[:t.config.MaxBodySize]
+	}
+	return slog.String(key, string(body))
+}
+
+// jsonValue converts a valid JSON document to a slog.Value. Objects become
+// groups, so handlers log their fields as nested attributes, sorted by name.
+func jsonValue(data []byte) slog.Value {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var v any
+	if err := decoder.Decode(&v); err != nil {
+		return slog.StringValue(string(data))
+	}
+	return toLogValue(v)
+}
+
+func toLogValue(v any) slog.Value {
+	switch v := v.(type) {
+	case map[string]any:
+		attrs := make([]slog.Attr, 0, len(v))
+		for _, key := range slices.Sorted(maps.Keys(v)) {
+			attrs = append(attrs, slog.Attr{Key: key, Value: toLogValue(v[key])})
+		}
+		return slog.GroupValue(attrs...)
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return slog.Int64Value(i)
+		}
+		f, _ := v.Float64()
+		return slog.Float64Value(f)
+	default:
+		return slog.AnyValue(v)
+	}
+}
+
+// isStreaming reports whether resp has a body of server-sent events.
+func isStreaming(resp *http.Response) bool {
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	return mediaType == "text/event-stream"
+}
+
+// maxPendingChunk is the most data streamingBodyLogger holds back waiting for
+// the end of an event. Past it, the data is logged up to its last line.
+const maxPendingChunk = 1 << 20
+
+// streamingBodyLogger logs a streamed response body as it is read, with an
+// event for each chunk and one when the body has been read or closed. Chunks
+// are made of whole server-sent events, so that redaction and OmitBase64 see
+// each event in full however the body is split across reads.
+type streamingBodyLogger struct {
+	body   io.ReadCloser
+	t      *LoggingRoundTripper
+	logger *slog.Logger
+	// ctx is the context of the request, so that chunks are logged with the
+	// trace IDs and context-scoped handlers of the request.
+	ctx   context.Context
+	attrs []slog.Attr
+	start time.Time
+	// genAI, if set, collects the GenAI attributes of the stream's events,
+	// which are logged when it finishes.
+	genAI *genAICall
+
+	// pending is the data read since the end of the last logged event.
+	pending []byte
+	bytes   int64
+	chunks  int
+	done    bool
+}
+
+func (b *streamingBodyLogger) Read(p []byte) (int, error) {
+	n, err := b.body.Read(p)
+	if n > 0 {
+		b.bytes += int64(n)
+
+		if b.genAI != nil {
+			b.genAI.observeStream(p[:n])
+		}
+		if b.t.config.LogResponseBody {
+			b.pending = append(b.pending, p[:n]...)
+			b.logEvents()
+		}
+	}
+	if err != nil {
+		b.finish(err)
+	}
+	return n, err
+}
+
+// logEvents logs the complete events in pending and keeps the rest.
+func (b *streamingBodyLogger) logEvents() {
+	end := -1
+	for _, sep := range []string{"\n\n", "\r\n\r\n", "\r\r"} {
+		if i := bytes.LastIndex(b.pending, []byte(sep)); i >= 0 {
+			end = max(end, i+len(sep))
+		}
+	}
+	if end < 0 && len(b.pending) >= maxPendingChunk {
+		end = len(b.pending)
+		if i := bytes.LastIndexAny(b.pending, "\r\n"); i >= 0 {
+			end = i + 1
+		}
+	}
+	if end <= 0 {
+		return
+	}
+	b.logChunk(b.pending[:end])
+	b.pending = append(b.pending[:0], b.pending[end:]...)
+}
+
+func (b *streamingBodyLogger) logChunk(data []byte) {
+	b.chunks++
+	attrs := append(b.attrs[:len(b.attrs):len(b.attrs)],
+		slog.Int("chunk", b.chunks),
+		b.t.bodyAttr("data", data),
+	)
+	b.logger.LogAttrs(b.ctx, levelOf(b.t.config.Levels.StreamChunk, slog.LevelDebug), "HTTP stream chunk", attrs...)
+}
+
+func (b *streamingBodyLogger) Close() error {
+	b.finish(nil)
+	return b.body.Close()
+}
+
+// finish logs the end of the stream once, as failed if err is not io.EOF.
+func (b *streamingBodyLogger) finish(err error) {
+	if b.done {
+		return
+	}
+	b.done = true
+	if len(b.pending) > 0 {
+		b.logChunk(b.pending)
+		b.pending = nil
+	}
+
+	attrs := append(b.attrs[:len(b.attrs):len(b.attrs)],
+		slog.Duration("duration", time.Since(b.start)),
+		slog.Int64("bytes", b.bytes),
+		slog.Int("chunks", b.chunks),
+	)
+	if b.genAI != nil {
+		attrs = append(attrs, b.genAI.attrs()...)
+	}
+	if err != nil && err != io.EOF {
+		attrs = append(attrs, slog.String("error", err.Error()))
+		b.logger.LogAttrs(b.ctx, levelOf(b.t.config.Levels.RequestFailed, slog.LevelError), "HTTP stream failed", attrs...)
+		return
+	}
+	b.logger.LogAttrs(b.ctx, levelOf(b.t.config.Levels.RequestCompleted, slog.LevelInfo), "HTTP stream completed", attrs...)
 }
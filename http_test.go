@@ -0,0 +1,847 @@
+package llms
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"encoding/pem"
+	"io"
+	"log"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// logRecords decodes the JSON log records written to buf.
+func logRecords(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	t.Helper()
+
+	var records []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var record map[string]any
+		require.NoError(t, json.Unmarshal([]byte(line), &record))
+		records = append(records, record)
+	}
+	return records
+}
+
+func TestLoggingRoundTripper_Redaction(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "sk-secret", r.Header.Get("Authorization"))
+		assert.Equal(t, "abc", r.URL.Query().Get("key"))
+		w.Header().Set("Set-Cookie", "session=1")
+		w.Header().Set("X-Request-Id", "req_1")
+		w.Write([]byte(`{"token": "tok-123456"}`))
+	}))
+	defer server.Close()
+
+	var buf bytes.Buffer
+	client := NewHTTPClient(HTTPClientOptions{
+		Logger: slog.New(slog.NewJSONHandler(&buf, nil)),
+		Config: &LoggingConfig{
+			LogHeaders:      true,
+			LogRequestBody:  true,
+			LogResponseBody: true,
+			RedactHeaders:   []string{"x-custom-secret"},
+			RedactPatterns:  []*regexp.Regexp{regexp.MustCompile(`(sk|tok)-[a-z0-9]+`)},
+		},
+	})
+
+	req, err := http.NewRequest(http.MethodPost, server.URL+"/v1/generate?key=abc&alt=sse", strings.NewReader(`{"api_key": "sk-abcdef", "prompt": "hi"}`))
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "sk-secret")
+	req.Header.Set("X-Custom-Secret", "hunter2")
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	// The request and response are unchanged.
+	var body bytes.Buffer
+	body.ReadFrom(resp.Body)
+	assert.Equal(t, `{"token": "tok-123456"}`, body.String())
+
+	logs := buf.String()
+	for _, secret := range []string{"sk-secret", "hunter2", "abc&", "sk-abcdef", "tok-123456", "session=1"} {
+		assert.NotContains(t, logs, secret)
+	}
+
+	records := logRecords(t, &buf)
+	require.Len(t, records, 2)
+
+	request := records[0]
+	assert.Contains(t, request["url"], "key=%5BREDACTED%5D")
+	assert.Contains(t, request["url"], "alt=sse")
+	headers := request["headers"].(map[string]any)
+	assert.Equal(t, []any{"[REDACTED]"}, headers["Authorization"])
+	assert.Equal(t, []any{"[REDACTED]"}, headers["X-Custom-Secret"])
+	assert.Equal(t, []any{"application/json"}, headers["Content-Type"])
+	assert.Equal(t, `{"api_key": "[REDACTED]", "prompt": "hi"}`, request["body"])
+
+	response := records[1]
+	responseHeaders := response["response_headers"].(map[string]any)
+	assert.Equal(t, []any{"[REDACTED]"}, responseHeaders["Set-Cookie"])
+	assert.Equal(t, []any{"req_1"}, responseHeaders["X-Request-Id"])
+	assert.Equal(t, `{"token": "[REDACTED]"}`, response["response_body"])
+}
+
+func TestLoggingRoundTripper_DisableRedaction(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	var buf bytes.Buffer
+	client := NewHTTPClient(HTTPClientOptions{
+		Logger: slog.New(slog.NewJSONHandler(&buf, nil)),
+		Config: &LoggingConfig{
+			LogHeaders:       true,
+			DisableRedaction: true,
+			RedactHeaders:    []string{"X-Custom-Secret"},
+		},
+	})
+
+	req, err := http.NewRequest(http.MethodGet, server.URL+"?key=abc", nil)
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "sk-secret")
+	req.Header.Set("X-Custom-Secret", "hunter2")
+
+	resp, err := client.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	request := logRecords(t, &buf)[0]
+	assert.Contains(t, request["url"], "key=abc")
+	headers := request["headers"].(map[string]any)
+	assert.Equal(t, []any{"sk-secret"}, headers["Authorization"])
+	assert.Equal(t, []any{"[REDACTED]"}, headers["X-Custom-Secret"])
+}
+
+func TestLoggingRoundTripper_RedactsBeforeTruncating(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	var buf bytes.Buffer
+	client := NewHTTPClient(HTTPClientOptions{
+		Logger: slog.New(slog.NewJSONHandler(&buf, nil)),
+		Config: &LoggingConfig{
+			LogRequestBody: true,
+			MaxBodySize:    8,
+			RedactPatterns: []*regexp.Regexp{regexp.MustCompile(`sk-[a-z0-9]+`)},
+		},
+	})
+
+	resp, err := client.Post(server.URL, "text/plain", strings.NewReader("key: sk-abcdef"))
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	assert.Equal(t, "key: [RE", logRecords(t, &buf)[0]["body"])
+}
+
+func TestLoggingRoundTripper_JSONBodies(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id": "msg_1", "usage": {"input_tokens": 10}}`))
+	}))
+	defer server.Close()
+
+	requestBody := `{"model": "claude", "messages": [{"role": "user", "content": "hi"}], "temperature": 0.5}`
+
+	t.Run("structured", func(t *testing.T) {
+		var buf bytes.Buffer
+		client := NewHTTPClient(HTTPClientOptions{
+			Logger: slog.New(slog.NewJSONHandler(&buf, nil)),
+			Config: &LoggingConfig{LogRequestBody: true, LogResponseBody: true, StructuredJSON: true},
+		})
+
+		resp, err := client.Post(server.URL, "application/json", strings.NewReader(requestBody))
+		require.NoError(t, err)
+		resp.Body.Close()
+
+		records := logRecords(t, &buf)
+		assert.Equal(t, map[string]any{
+			"model":       "claude",
+			"messages":    []any{map[string]any{"role": "user", "content": "hi"}},
+			"temperature": 0.5,
+		}, records[0]["body"])
+		assert.Equal(t, map[string]any{
+			"id":    "msg_1",
+			"usage": map[string]any{"input_tokens": float64(10)},
+		}, records[1]["response_body"])
+	})
+
+	t.Run("large and invalid bodies are strings", func(t *testing.T) {
+		var buf bytes.Buffer
+		client := NewHTTPClient(HTTPClientOptions{
+			Logger: slog.New(slog.NewJSONHandler(&buf, nil)),
+			Config: &LoggingConfig{LogRequestBody: true, MaxBodySize: 16, StructuredJSON: true},
+		})
+
+		for _, body := range []string{requestBody, "not json"} {
+			resp, err := client.Post(server.URL, "application/json", strings.NewReader(body))
+			require.NoError(t, err)
+			resp.Body.Close()
+		}
+
+		records := logRecords(t, &buf)
+		assert.Equal(t, requestBody[:16], records[0]["body"])
+		assert.Equal(t, "not json", records[2]["body"])
+	})
+
+	t.Run("indented", func(t *testing.T) {
+		var buf bytes.Buffer
+		client := NewHTTPClient(HTTPClientOptions{
+			Logger: slog.New(slog.NewJSONHandler(&buf, nil)),
+			Config: &LoggingConfig{LogResponseBody: true, IndentJSON: true},
+		})
+
+		resp, err := client.Get(server.URL)
+		require.NoError(t, err)
+		resp.Body.Close()
+
+		assert.Equal(t, "{\n  \"id\": \"msg_1\",\n  \"usage\": {\n    \"input_tokens\": 10\n  }\n}", logRecords(t, &buf)[1]["response_body"])
+	})
+}
+
+func TestLoggingRoundTripper_Levels(t *testing.T) {
+	status := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	levels := func(config LoggingConfig) []string {
+		var buf bytes.Buffer
+		client := NewHTTPClient(HTTPClientOptions{
+			Logger: slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})),
+			Config: &config,
+		})
+		resp, err := client.Get(server.URL)
+		require.NoError(t, err)
+		resp.Body.Close()
+
+		var out []string
+		for _, record := range logRecords(t, &buf) {
+			out = append(out, record["level"].(string))
+		}
+		return out
+	}
+
+	for _, tt := range []struct {
+		status int
+		config LoggingConfig
+		want   []string
+	}{
+		{http.StatusOK, LoggingConfig{}, []string{"INFO", "INFO"}},
+		{http.StatusTooManyRequests, LoggingConfig{}, []string{"INFO", "WARN"}},
+		{http.StatusBadGateway, LoggingConfig{}, []string{"INFO", "ERROR"}},
+		{http.StatusOK, LoggingConfig{Levels: LogLevels{RequestStarted: slog.LevelDebug, RequestCompleted: slog.LevelDebug}}, []string{"DEBUG", "DEBUG"}},
+		{http.StatusBadRequest, LoggingConfig{Levels: LogLevels{ClientError: slog.LevelError}}, []string{"INFO", "ERROR"}},
+		{http.StatusServiceUnavailable, LoggingConfig{Levels: LogLevels{ServerError: slog.LevelWarn}}, []string{"INFO", "WARN"}},
+	} {
+		status = tt.status
+		assert.Equal(t, tt.want, levels(tt.config), "status %d", tt.status)
+	}
+}
+
+func TestLoggingRoundTripper_Streaming(t *testing.T) {
+	events := []string{"data: {\"delta\": \"Hel\"}\n\n", "data: {\"delta\": \"lo\"}\n\n"}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream; charset=utf-8")
+		for _, event := range events {
+			w.Write([]byte(event))
+			w.(http.Flusher).Flush()
+		}
+	}))
+	defer server.Close()
+
+	var buf bytes.Buffer
+	chunkLevel := new(slog.LevelVar)
+	chunkLevel.Set(slog.LevelInfo)
+	client := NewHTTPClient(HTTPClientOptions{
+		Logger: slog.New(slog.NewJSONHandler(&buf, nil)),
+		Config: &LoggingConfig{
+			LogResponseBody: true,
+			Levels:          LogLevels{StreamChunk: chunkLevel},
+		},
+	})
+
+	resp, err := client.Get(server.URL)
+	require.NoError(t, err)
+
+	// The response is returned before its body is read.
+	records := logRecords(t, &buf)
+	require.Len(t, records, 2)
+	assert.Equal(t, "HTTP request completed", records[1]["msg"])
+	assert.NotContains(t, records[1], "response_body")
+
+	var body bytes.Buffer
+	_, err = body.ReadFrom(resp.Body)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	assert.Equal(t, strings.Join(events, ""), body.String())
+
+	records = logRecords(t, &buf)[2:]
+	require.GreaterOrEqual(t, len(records), 2)
+	var data strings.Builder
+	for _, record := range records[:len(records)-1] {
+		assert.Equal(t, "HTTP stream chunk", record["msg"])
+		assert.Equal(t, "INFO", record["level"])
+		data.WriteString(record["data"].(string))
+	}
+	assert.Equal(t, body.String(), data.String())
+
+	// Closing after the body was read does not log the end twice.
+	last := records[len(records)-1]
+	assert.Equal(t, "HTTP stream completed", last["msg"])
+	assert.Equal(t, float64(body.Len()), last["bytes"])
+	assert.Equal(t, float64(len(records)-1), last["chunks"])
+}
+
+// readInPieces reads r to the end with reads of at most size bytes.
+func readInPieces(t *testing.T, r io.Reader, size int) string {
+	t.Helper()
+
+	var out strings.Builder
+	p := make([]byte, size)
+	for {
+		n, err := r.Read(p)
+		out.Write(p[:n])
+		if err == io.EOF {
+			return out.String()
+		}
+		require.NoError(t, err)
+	}
+}
+
+func TestLoggingRoundTripper_StreamingRedaction(t *testing.T) {
+	events := "data: {\"key\": \"sk-abcdef123456\"}\n\ndata: {\"delta\": \"hi\"}\n\ndata: [DONE]"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte(events))
+	}))
+	defer server.Close()
+
+	var buf bytes.Buffer
+	client := NewHTTPClient(HTTPClientOptions{
+		Logger: slog.New(slog.NewJSONHandler(&buf, nil)),
+		Config: &LoggingConfig{
+			LogResponseBody: true,
+			RedactPatterns:  []*regexp.Regexp{regexp.MustCompile(`sk-[a-z0-9]+`)},
+			Levels:          LogLevels{StreamChunk: slog.LevelInfo},
+		},
+	})
+
+	resp, err := client.Get(server.URL)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	// The secret is split across reads, but logged whole and redacted.
+	assert.Equal(t, events, readInPieces(t, resp.Body, 5))
+
+	var chunks []string
+	for _, record := range logRecords(t, &buf) {
+		if record["msg"] == "HTTP stream chunk" {
+			chunks = append(chunks, record["data"].(string))
+		}
+	}
+	assert.Equal(t, []string{
+		"data: {\"key\": \"[REDACTED]\"}\n\n",
+		"data: {\"delta\": \"hi\"}\n\n",
+		"data: [DONE]",
+	}, chunks)
+}
+
+func TestLoggingRoundTripper_StreamingOmitBase64(t *testing.T) {
+	audio := strings.Repeat("UklGRiQAAABXQVZF", 64)
+	events := `data: {"delta": {"audio": {"data": "` + audio + `"}}}` + "\n\n"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte(events))
+	}))
+	defer server.Close()
+
+	var buf bytes.Buffer
+	client := NewHTTPClient(HTTPClientOptions{
+		Logger: slog.New(slog.NewJSONHandler(&buf, nil)),
+		Config: &LoggingConfig{
+			LogResponseBody: true,
+			OmitBase64:      true,
+			MaxBodySize:     4096,
+			Levels:          LogLevels{StreamChunk: slog.LevelInfo},
+		},
+	})
+
+	resp, err := client.Get(server.URL)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	// The string is split across reads, but omitted whole.
+	assert.Equal(t, events, readInPieces(t, resp.Body, 100))
+
+	var chunks []string
+	for _, record := range logRecords(t, &buf) {
+		if record["msg"] == "HTTP stream chunk" {
+			chunks = append(chunks, record["data"].(string))
+		}
+	}
+	assert.Equal(t, []string{`data: {"delta": {"audio": {"data": "<omitted 1024 bytes>"}}}` + "\n\n"}, chunks)
+}
+
+func TestLoggingRoundTripper_Hooks(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "audit-1", r.Header.Get("X-Audit-Id"))
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	var buf bytes.Buffer
+	var status int
+	var duration time.Duration
+	client := NewHTTPClient(HTTPClientOptions{
+		Logger: slog.New(slog.NewJSONHandler(&buf, nil)),
+		Config: &LoggingConfig{
+			LogHeaders: true,
+			OnRequest: func(r *http.Request) {
+				r.Header.Set("X-Audit-Id", "audit-1")
+			},
+			OnResponse: func(r *http.Response, d time.Duration) {
+				status = r.StatusCode
+				duration = d
+			},
+		},
+	})
+
+	req, err := http.NewRequest(http.MethodGet, server.URL, nil)
+	require.NoError(t, err)
+	resp, err := client.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	assert.Equal(t, http.StatusAccepted, status)
+	assert.Greater(t, duration, time.Duration(0))
+
+	// The caller's request is unchanged, and the logs show the headers sent.
+	assert.Empty(t, req.Header.Get("X-Audit-Id"))
+	headers := logRecords(t, &buf)[0]["headers"].(map[string]any)
+	assert.Equal(t, []any{"audit-1"}, headers["X-Audit-Id"])
+}
+
+func TestNewHTTPClient_TLS(t *testing.T) {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	server.Config.ErrorLog = log.New(io.Discard, "", 0)
+	server.StartTLS()
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "ca.pem")
+	cert := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	require.NoError(t, os.WriteFile(path, cert, 0o600))
+
+	pool, err := LoadCABundle(path)
+	require.NoError(t, err)
+
+	// The server's certificate is only trusted with the bundle.
+	_, err = NewHTTPClient(HTTPClientOptions{}).Get(server.URL)
+	require.Error(t, err)
+
+	var buf bytes.Buffer
+	client := NewHTTPClient(HTTPClientOptions{
+		Logger:    slog.New(slog.NewJSONHandler(&buf, nil)),
+		RootCAs:   pool,
+		TLSConfig: &tls.Config{MinVersion: tls.VersionTLS12},
+	})
+	resp, err := client.Get(server.URL)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Len(t, logRecords(t, &buf), 2)
+
+	// The default transport is not modified.
+	if config := http.DefaultTransport.(*http.Transport).TLSClientConfig; config != nil {
+		assert.Nil(t, config.RootCAs)
+	}
+
+	_, err = LoadCABundle(filepath.Join(t.TempDir(), "missing.pem"))
+	assert.Error(t, err)
+
+	empty := filepath.Join(t.TempDir(), "empty.pem")
+	require.NoError(t, os.WriteFile(empty, []byte("not a certificate"), 0o600))
+	_, err = LoadCABundle(empty)
+	assert.Error(t, err)
+}
+
+func TestNewHTTPClient_Proxy(t *testing.T) {
+	var proxied string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = r.URL.String()
+		w.Write([]byte("from proxy"))
+	}))
+	defer proxy.Close()
+
+	proxyURL, err := url.Parse(proxy.URL)
+	require.NoError(t, err)
+
+	client := NewHTTPClient(HTTPClientOptions{ProxyURL: proxyURL})
+	resp, err := client.Get("http://api.example.com/v1/messages")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+	assert.Equal(t, "from proxy", string(body))
+	assert.Equal(t, "http://api.example.com/v1/messages", proxied)
+}
+
+func TestNewHTTPClient_Timeouts(t *testing.T) {
+	defaults := DefaultTransportOptions()
+
+	transport := NewHTTPClient(HTTPClientOptions{}).Transport.(*http.Transport)
+	assert.Equal(t, defaults.TLSHandshakeTimeout, transport.TLSHandshakeTimeout)
+	assert.Equal(t, defaults.ResponseHeaderTimeout, transport.ResponseHeaderTimeout)
+	assert.Equal(t, defaults.IdleConnTimeout, transport.IdleConnTimeout)
+	assert.Equal(t, defaults.MaxIdleConnsPerHost, transport.MaxIdleConnsPerHost)
+	assert.NotSame(t, http.DefaultTransport, transport)
+
+	transport = NewHTTPClient(HTTPClientOptions{
+		TLSHandshakeTimeout:   -1,
+		ResponseHeaderTimeout: time.Minute,
+		MaxIdleConnsPerHost:   64,
+	}).Transport.(*http.Transport)
+	assert.Zero(t, transport.TLSHandshakeTimeout)
+	assert.Equal(t, time.Minute, transport.ResponseHeaderTimeout)
+	assert.Equal(t, 64, transport.MaxIdleConnsPerHost)
+
+	// The settings of a custom transport are kept unless set.
+	custom := &http.Transport{IdleConnTimeout: time.Second, MaxIdleConnsPerHost: 4}
+	transport = NewHTTPClient(HTTPClientOptions{
+		Transport:           custom,
+		MaxIdleConnsPerHost: 8,
+	}).Transport.(*http.Transport)
+	assert.Equal(t, time.Second, transport.IdleConnTimeout)
+	assert.Zero(t, transport.ResponseHeaderTimeout)
+	assert.Equal(t, 8, transport.MaxIdleConnsPerHost)
+	assert.Equal(t, 4, custom.MaxIdleConnsPerHost)
+}
+
+func TestNewHTTPClient_ResponseHeaderTimeout(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	client := NewHTTPClient(HTTPClientOptions{ResponseHeaderTimeout: 10 * time.Millisecond})
+	_, err := client.Get(server.URL)
+	assert.ErrorContains(t, err, "timeout awaiting response headers")
+}
+
+func TestLoggingRoundTripper_OmitBase64(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	image := strings.Repeat("iVBORw0KGgo+/", 100) + "=="
+	body := `{"model": "claude", "messages": [{"role": "user", "content": [` +
+		`{"type": "image", "source": {"type": "base64", "media_type": "image/png", "data": "` + image + `"}},` +
+		`{"type": "image_url", "image_url": {"url": "data:image/png;base64,` + image + `"}},` +
+		`{"type": "text", "text": "Describe \"these\" images."}]}]}`
+
+	var buf bytes.Buffer
+	client := NewHTTPClient(HTTPClientOptions{
+		Logger: slog.New(slog.NewJSONHandler(&buf, nil)),
+		Config: &LoggingConfig{
+			LogRequestBody: true,
+			OmitBase64:     true,
+			MinBase64Size:  512,
+		},
+	})
+
+	resp, err := client.Post(server.URL, "application/json", strings.NewReader(body))
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	logged := logRecords(t, &buf)[0]["body"].(string)
+	assert.True(t, json.Valid([]byte(logged)), logged)
+	assert.NotContains(t, logged, image)
+	assert.Contains(t, logged, `"data": "<omitted 1302 bytes>"`)
+	assert.Contains(t, logged, `"url": "data:image/png;base64,<omitted 1302 bytes>"`)
+	assert.Contains(t, logged, `"media_type": "image/png"`)
+	assert.Contains(t, logged, `Describe \"these\" images.`)
+}
+
+func TestLoggingRoundTripper_Sampling(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("fail") != "" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	run := func(sampling LogSampling, paths ...string) []map[string]any {
+		var buf bytes.Buffer
+		responses := 0
+		client := NewHTTPClient(HTTPClientOptions{
+			Logger: slog.New(slog.NewJSONHandler(&buf, nil)),
+			Config: &LoggingConfig{
+				Sampling:   &sampling,
+				OnResponse: func(*http.Response, time.Duration) { responses++ },
+			},
+		})
+		for _, path := range paths {
+			resp, err := client.Get(server.URL + path)
+			require.NoError(t, err)
+			resp.Body.Close()
+		}
+		// Hooks are called for every request.
+		assert.Equal(t, len(paths), responses)
+
+		if buf.Len() == 0 {
+			return nil
+		}
+		return logRecords(t, &buf)
+	}
+
+	records := run(LogSampling{Every: 2}, "/1", "/2", "/3", "/4", "/5")
+	require.Len(t, records, 6)
+	for i, path := range []string{"/1", "/3", "/5"} {
+		assert.Equal(t, server.URL+path, records[2*i]["url"])
+		assert.Equal(t, "HTTP request started", records[2*i]["msg"])
+		assert.Equal(t, "HTTP request completed", records[2*i+1]["msg"])
+	}
+
+	assert.Empty(t, run(LogSampling{Rate: 0}, "/1", "/2"))
+	assert.Len(t, run(LogSampling{Rate: 1}, "/1", "/2"), 4)
+
+	records = run(LogSampling{LogErrors: true}, "/1", "/2?fail=1", "/3")
+	require.Len(t, records, 2)
+	assert.Equal(t, "HTTP request started", records[0]["msg"])
+	assert.Equal(t, server.URL+"/2?fail=1", records[0]["url"])
+	assert.Equal(t, float64(http.StatusInternalServerError), records[1]["status_code"])
+}
+
+type traceIDKey struct{}
+
+// traceHandler adds the trace ID carried by the context of each record.
+type traceHandler struct {
+	slog.Handler
+}
+
+func (h traceHandler) Handle(ctx context.Context, r slog.Record) error {
+	if id, ok := ctx.Value(traceIDKey{}).(string); ok {
+		r.AddAttrs(slog.String("trace_id", id))
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h traceHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return traceHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h traceHandler) WithGroup(name string) slog.Handler {
+	return traceHandler{h.Handler.WithGroup(name)}
+}
+
+func TestLoggingRoundTripper_ContextLogger(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte("data: {}\n\n"))
+	}))
+	defer server.Close()
+
+	var defaultBuf, requestBuf bytes.Buffer
+	client := NewHTTPClient(HTTPClientOptions{
+		Logger: slog.New(slog.NewJSONHandler(&defaultBuf, nil)),
+		Config: &LoggingConfig{
+			LogResponseBody: true,
+			Levels:          LogLevels{StreamChunk: slog.LevelInfo},
+		},
+	})
+
+	ctx := context.WithValue(context.Background(), traceIDKey{}, "trace-1")
+	ctx = WithHTTPLogger(ctx, slog.New(traceHandler{slog.NewJSONHandler(&requestBuf, nil)}).With("job", "nightly"))
+	assert.NotNil(t, HTTPLoggerFromContext(ctx))
+	assert.Nil(t, HTTPLoggerFromContext(context.Background()))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+	require.NoError(t, err)
+	resp, err := client.Do(req)
+	require.NoError(t, err)
+	io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	assert.Zero(t, defaultBuf.Len())
+
+	var messages []string
+	for _, record := range logRecords(t, &requestBuf) {
+		messages = append(messages, record["msg"].(string))
+		assert.Equal(t, "nightly", record["job"])
+		assert.Equal(t, "trace-1", record["trace_id"], record["msg"])
+	}
+	assert.Equal(t, []string{"HTTP request started", "HTTP request completed", "HTTP stream chunk", "HTTP stream completed"}, messages)
+}
+
+func TestLoggingRoundTripper_RequestID(t *testing.T) {
+	var received []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = append(received, r.Header.Get("X-Trace"))
+	}))
+	defer server.Close()
+
+	var buf bytes.Buffer
+	client := NewHTTPClient(HTTPClientOptions{
+		Logger: slog.New(slog.NewJSONHandler(&buf, nil)),
+		Config: &LoggingConfig{RequestIDHeader: "X-Trace"},
+	})
+
+	do := func(ctx context.Context, header string) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+		require.NoError(t, err)
+		if header != "" {
+			req.Header.Set("X-Trace", header)
+		}
+		resp, err := client.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		// The header is added to a copy of the caller's request.
+		assert.Equal(t, header, req.Header.Get("X-Trace"))
+	}
+
+	ctx, id := EnsureRequestID(context.Background())
+	assert.Equal(t, id, RequestIDFromContext(ctx))
+	again, sameID := EnsureRequestID(ctx)
+	assert.Equal(t, ctx, again)
+	assert.Equal(t, id, sameID)
+
+	do(ctx, "")
+	do(context.Background(), "from-caller")
+	do(context.Background(), "")
+
+	require.Len(t, received, 3)
+	assert.Equal(t, id, received[0])
+	assert.Equal(t, "from-caller", received[1])
+	assert.NotEmpty(t, received[2])
+	assert.NotEqual(t, id, received[2])
+
+	// The ID sent is the one logged.
+	records := logRecords(t, &buf)
+	require.Len(t, records, 6)
+	for i, record := range records {
+		assert.Equal(t, received[i/2], record["request_id"], record["msg"])
+	}
+}
+
+func TestLoggingRoundTripper_DisableRequestIDHeader(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Empty(t, r.Header.Get(DefaultRequestIDHeader))
+	}))
+	defer server.Close()
+
+	client := NewHTTPClient(HTTPClientOptions{
+		Logger: slog.New(slog.NewJSONHandler(io.Discard, nil)),
+		Config: &LoggingConfig{DisableRequestIDHeader: true},
+	})
+
+	resp, err := client.Get(server.URL)
+	require.NoError(t, err)
+	resp.Body.Close()
+}
+
+func TestLoggingRoundTripper_GenAIAttributes(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte("data: {\"id\": \"chatcmpl-1\", \"model\": \"gpt-4o-mini\", \"choices\": [{\"delta\": {\"content\": \"Hi\"}}]}\n\n"))
+		w.Write([]byte("data: {\"id\": \"chatcmpl-1\", \"model\": \"gpt-4o-mini\", \"choices\": [{\"delta\": {}, \"finish_reason\": \"stop\"}]}\n\n"))
+		w.Write([]byte("data: {\"id\": \"chatcmpl-1\", \"model\": \"gpt-4o-mini\", \"choices\": [], \"usage\": {\"prompt_tokens\": 3, \"completion_tokens\": 1}}\n\n"))
+		w.Write([]byte("data: [DONE]\n\n"))
+	}))
+	defer server.Close()
+
+	// The bodies are parsed without being logged.
+	var buf bytes.Buffer
+	client := NewHTTPClient(HTTPClientOptions{
+		Logger: slog.New(slog.NewJSONHandler(&buf, nil)),
+		Config: &LoggingConfig{GenAIAttributes: true},
+	})
+
+	resp, err := client.Post(server.URL, "application/json", strings.NewReader(`{"model": "gpt-4o-mini", "stream": true}`))
+	require.NoError(t, err)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Contains(t, string(body), "[DONE]")
+
+	records := logRecords(t, &buf)
+	require.Len(t, records, 3)
+	assert.Equal(t, "HTTP request started", records[0]["msg"])
+	assert.Equal(t, "gpt-4o-mini", records[0]["gen_ai.request.model"])
+	assert.NotContains(t, records[0], "body")
+	assert.Equal(t, "HTTP request completed", records[1]["msg"])
+
+	completed := records[2]
+	assert.Equal(t, "HTTP stream completed", completed["msg"])
+	assert.Equal(t, "gpt-4o-mini", completed["gen_ai.request.model"])
+	assert.Equal(t, "gpt-4o-mini", completed["gen_ai.response.model"])
+	assert.Equal(t, "chatcmpl-1", completed["gen_ai.response.id"])
+	assert.Equal(t, []any{"stop"}, completed["gen_ai.response.finish_reasons"])
+	assert.Equal(t, 3.0, completed["gen_ai.usage.input_tokens"])
+	assert.Equal(t, 1.0, completed["gen_ai.usage.output_tokens"])
+}
+
+func TestLoggingRoundTripper_RedactFuncs(t *testing.T) {
+	var received string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		received = string(data)
+		w.Write([]byte(`{"customer_id": "cus_123", "text": "ok"}`))
+	}))
+	defer server.Close()
+
+	customerID := regexp.MustCompile(`cus_[0-9]+`)
+	var buf bytes.Buffer
+	client := NewHTTPClient(HTTPClientOptions{
+		Logger: slog.New(slog.NewJSONHandler(&buf, nil)),
+		Config: &LoggingConfig{
+			LogRequestBody:  true,
+			LogResponseBody: true,
+			RedactFuncs: []func([]byte) []byte{
+				func(body []byte) []byte {
+					// Modifying the body in place must not change the
+					// request sent.
+					for i, c := range body {
+						if c == 'a' {
+							body[i] = 'A'
+						}
+					}
+					return body
+				},
+				func(body []byte) []byte {
+					return customerID.ReplaceAll(body, []byte("<customer>"))
+				},
+			},
+		},
+	})
+
+	resp, err := client.Post(server.URL, "application/json", strings.NewReader(`{"name": "alice"}`))
+	require.NoError(t, err)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	assert.Equal(t, `{"name": "alice"}`, received)
+	assert.Equal(t, `{"customer_id": "cus_123", "text": "ok"}`, string(body))
+
+	records := logRecords(t, &buf)
+	assert.Equal(t, `{"nAme": "Alice"}`, records[0]["body"])
+	assert.Equal(t, `{"customer_id": "<customer>", "text": "ok"}`, records[1]["response_body"])
+}
@@ -0,0 +1,49 @@
+package llms
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ImageRequest describes the images to generate. See ImageGenerator.
+type ImageRequest struct {
+	// Prompt describes the images.
+	Prompt string
+	// Size is the size of the images as "WIDTHxHEIGHT", such as "1024x1024".
+	// Providers that take an aspect ratio instead use the closest one they
+	// support. If empty, the provider's default is used.
+	Size string
+	// Count is the number of images to generate. Defaults to 1.
+	Count int
+	// Format is the MIME type of the images, such as "image/png" or
+	// "image/jpeg". If empty, the provider's default is used.
+	Format string
+}
+
+// ImageGenerator generates images from a text prompt. Providers that offer
+// image models implement it, so that applications mixing chat and image
+// generation can depend on this package alone.
+type ImageGenerator interface {
+	// GenerateImages returns the images generated for req. Providers return
+	// the image data where they can, and a URL otherwise. Fewer than
+	// req.Count images may be returned if some were filtered out by the
+	// provider.
+	GenerateImages(ctx context.Context, req ImageRequest) ([]ImagePart, error)
+}
+
+// ParseImageSize parses an ImageRequest size of the form "WIDTHxHEIGHT".
+func ParseImageSize(size string) (width, height int, err error) {
+	w, h, ok := strings.Cut(size, "x")
+	if ok {
+		width, err = strconv.Atoi(w)
+		if err == nil {
+			height, err = strconv.Atoi(h)
+		}
+	}
+	if !ok || err != nil || width <= 0 || height <= 0 {
+		return 0, 0, fmt.Errorf("llms: invalid image size %q", size)
+	}
+	return width, height, nil
+}
@@ -0,0 +1,20 @@
+package llms
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseImageSize(t *testing.T) {
+	width, height, err := ParseImageSize("1536x1024")
+	require.NoError(t, err)
+	assert.Equal(t, 1536, width)
+	assert.Equal(t, 1024, height)
+
+	for _, size := range []string{"", "auto", "1024", "1024x", "x1024", "0x1024", "-1x5", "axb"} {
+		_, _, err := ParseImageSize(size)
+		assert.EqualError(t, err, `llms: invalid image size "`+size+`"`, size)
+	}
+}
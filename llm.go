@@ -11,10 +11,132 @@ type LLM interface {
 	GenerateStream(ctx context.Context, messages []Message, fn StreamFunc) (*Response, error)
 }
 
+// ModelNamer is implemented by LLMs that can report the model they send
+// requests to.
+type ModelNamer interface {
+	ModelName() string
+}
+
+// StopReason is why the model stopped generating. Providers map their own
+// values to the constants below where they have an equivalent, and pass any
+// other value through unchanged.
+type StopReason string
+
+const (
+	// StopReasonEndTurn means the model finished its turn naturally.
+	StopReasonEndTurn StopReason = "end_turn"
+	// StopReasonMaxTokens means the output token limit was reached.
+	StopReasonMaxTokens StopReason = "max_tokens"
+	// StopReasonStopSequence means one of the request's stop sequences was
+	// generated.
+	StopReasonStopSequence StopReason = "stop_sequence"
+	// StopReasonToolUse means the model is waiting for tool results.
+	StopReasonToolUse StopReason = "tool_use"
+)
+
+// Usage reports the tokens consumed by a request.
+type Usage struct {
+	// InputTokens is the total number of input tokens, including those read
+	// from or written to the provider's prompt cache.
+	InputTokens int `json:"input_tokens"`
+	// OutputTokens is the number of generated tokens.
+	OutputTokens int `json:"output_tokens"`
+	// CacheCreationInputTokens is the number of input tokens written to the
+	// prompt cache.
+	CacheCreationInputTokens int `json:"cache_creation_input_tokens,omitempty"`
+	// CacheCreation5mInputTokens and CacheCreation1hInputTokens break
+	// CacheCreationInputTokens down by cache lifetime, for providers that
+	// report it.
+	CacheCreation5mInputTokens int `json:"cache_creation_5m_input_tokens,omitempty"`
+	CacheCreation1hInputTokens int `json:"cache_creation_1h_input_tokens,omitempty"`
+	// CacheReadInputTokens is the number of input tokens read from the prompt
+	// cache.
+	CacheReadInputTokens int `json:"cache_read_input_tokens,omitempty"`
+	// ReasoningTokens is the number of output tokens the model spent
+	// reasoning, for providers that report it. They are included in
+	// OutputTokens.
+	ReasoningTokens int `json:"reasoning_tokens,omitempty"`
+	// ToolUseInputTokens is the number of input tokens added by the results
+	// of provider-executed tools, for providers that report it. They are
+	// included in InputTokens.
+	ToolUseInputTokens int `json:"tool_use_input_tokens,omitempty"`
+	// Cost is the price of the request in US dollars, for providers that
+	// report it.
+	Cost float64 `json:"cost,omitempty"`
+	// WebSearchRequests is the number of searches made by a provider-hosted
+	// web search tool, which are usually billed separately.
+	WebSearchRequests int `json:"web_search_requests,omitempty"`
+}
+
+// TotalTokens returns the sum of the input and output tokens.
+func (u Usage) TotalTokens() int {
+	return u.InputTokens + u.OutputTokens
+}
+
+// Add returns the sum of u and other, for totalling the usage of several
+// requests.
+func (u Usage) Add(other Usage) Usage {
+	return Usage{
+		InputTokens:                u.InputTokens + other.InputTokens,
+		OutputTokens:               u.OutputTokens + other.OutputTokens,
+		CacheCreationInputTokens:   u.CacheCreationInputTokens + other.CacheCreationInputTokens,
+		CacheCreation5mInputTokens: u.CacheCreation5mInputTokens + other.CacheCreation5mInputTokens,
+		CacheCreation1hInputTokens: u.CacheCreation1hInputTokens + other.CacheCreation1hInputTokens,
+		CacheReadInputTokens:       u.CacheReadInputTokens + other.CacheReadInputTokens,
+		ReasoningTokens:            u.ReasoningTokens + other.ReasoningTokens,
+		ToolUseInputTokens:         u.ToolUseInputTokens + other.ToolUseInputTokens,
+		Cost:                       u.Cost + other.Cost,
+		WebSearchRequests:          u.WebSearchRequests + other.WebSearchRequests,
+	}
+}
+
+// modelName returns the model reported by llm, or "" if it does not implement
+// ModelNamer.
+func modelName(llm LLM) string {
+	if namer, ok := llm.(ModelNamer); ok {
+		return namer.ModelName()
+	}
+	return ""
+}
+
+// TokenCounter is implemented by LLMs that can count the input tokens of a
+// request before it is sent, so callers can budget their context window.
+type TokenCounter interface {
+	CountTokens(ctx context.Context, messages []Message) (int, error)
+}
+
 type Response struct {
 	ID      string  `json:"id"`
 	Message Message `json:"message"`
+	// Usage reports the tokens consumed by the request, if the provider
+	// returned them. While streaming it reflects the usage so far.
+	Usage *Usage `json:"usage,omitempty"`
+	// StopReason is why the model stopped generating, if the provider
+	// reported it.
+	StopReason StopReason `json:"stop_reason,omitempty"`
+	// StopSequence is the stop sequence that ended generation, when
+	// StopReason is StopReasonStopSequence.
+	StopSequence string `json:"stop_sequence,omitempty"`
+	// Candidates holds every alternative response the model generated, for
+	// providers that can generate several for one request. Message and
+	// StopReason are those of the first candidate.
+	Candidates []Candidate `json:"candidates,omitempty"`
+	// RequestID identifies the call that produced the response. It is the
+	// ID set with WithRequestID, or one generated by the provider, and is
+	// also logged and sent by LoggingRoundTripper.
+	RequestID string `json:"request_id,omitempty"`
 
 	Provider string
 	Raw      any
 }
+
+// Candidate is one of several alternative responses generated for a request.
+type Candidate struct {
+	Message Message `json:"message"`
+	// StopReason is why the model stopped generating this candidate, if the
+	// provider reported it.
+	StopReason StopReason `json:"stop_reason,omitempty"`
+
+	// Raw is the provider's representation of the candidate.
+	Raw any
+}
@@ -0,0 +1,80 @@
+package llms
+
+import (
+	"context"
+	"sync"
+)
+
+// fakeLLM is a scripted LLM used by the tests in this package. Each call pops
+// the next result off of results; once results are exhausted the last one is
+// repeated.
+type fakeLLM struct {
+	mu       sync.Mutex
+	results  []fakeResult
+	calls    int
+	received [][]Message
+}
+
+type fakeResult struct {
+	resp *Response
+	err  error
+	// chunks are delivered to the StreamFunc before resp/err is returned.
+	chunks []*Response
+}
+
+func newFakeLLM(results ...fakeResult) *fakeLLM {
+	return &fakeLLM{results: results}
+}
+
+func textResponse(text string) *Response {
+	return &Response{
+		ID:      "resp_" + text,
+		Message: NewTextMessage(RoleAssistant, text),
+	}
+}
+
+func (f *fakeLLM) next(messages []Message) fakeResult {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.received = append(f.received, messages)
+
+	i := f.calls
+	if i >= len(f.results) {
+		i = len(f.results) - 1
+	}
+	f.calls++
+
+	return f.results[i]
+}
+
+func (f *fakeLLM) Calls() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.calls
+}
+
+// Received returns the messages passed to the most recent call.
+func (f *fakeLLM) Received() []Message {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if len(f.received) == 0 {
+		return nil
+	}
+	return f.received[len(f.received)-1]
+}
+
+func (f *fakeLLM) Generate(ctx context.Context, messages []Message) (*Response, error) {
+	r := f.next(messages)
+	return r.resp, r.err
+}
+
+func (f *fakeLLM) GenerateStream(ctx context.Context, messages []Message, fn StreamFunc) (*Response, error) {
+	r := f.next(messages)
+	for _, chunk := range r.chunks {
+		if !fn(chunk, nil) {
+			return chunk, nil
+		}
+	}
+	return r.resp, r.err
+}
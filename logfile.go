@@ -0,0 +1,227 @@
+package llms
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultMaxLogFileSize is the size at which a RotatingFile is rotated if
+// RotatingFileConfig.MaxSize is zero.
+const DefaultMaxLogFileSize = 100 << 20
+
+// RotatingFileConfig configures a RotatingFile.
+type RotatingFileConfig struct {
+	// Path is the file logs are written to. Rotated files are kept next to it,
+	// with the time of rotation added to their name.
+	Path string
+	// MaxSize is the size in bytes at which the file is rotated. Defaults to
+	// DefaultMaxLogFileSize; negative disables rotation by size.
+	MaxSize int64
+	// MaxAge is how long the file is written to before it is rotated. Zero
+	// disables rotation by time.
+	MaxAge time.Duration
+	// MaxBackups is the number of rotated files to keep. The oldest are
+	// deleted first. Zero keeps all of them.
+	MaxBackups int
+}
+
+// RotatingFile is an io.WriteCloser that writes to a file and rotates it when
+// it grows too large or too old, so that the HTTP traffic of long-running
+// services can be logged without filling the disk. It is safe for concurrent
+// use.
+type RotatingFile struct {
+	config RotatingFileConfig
+	now    func() time.Time
+
+	mu     sync.Mutex
+	file   *os.File
+	size   int64
+	opened time.Time
+}
+
+// NewRotatingFile opens the file at config.Path for appending, creating it and
+// its directory if needed.
+func NewRotatingFile(config RotatingFileConfig) (*RotatingFile, error) {
+	if config.Path == "" {
+		return nil, errors.New("llms: log file path is required")
+	}
+	if config.MaxSize == 0 {
+		config.MaxSize = DefaultMaxLogFileSize
+	}
+
+	f := &RotatingFile{config: config, now: time.Now}
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// NewFileLogger returns a logger writing JSON records to a RotatingFile, for
+// use as HTTPClientOptions.Logger so that LLM traffic is kept apart from
+// application logs. The returned file must be closed when the logger is no
+// longer used. A nil opts uses the default handler options.
+func NewFileLogger(config RotatingFileConfig, opts *slog.HandlerOptions) (*slog.Logger, *RotatingFile, error) {
+	f, err := NewRotatingFile(config)
+	if err != nil {
+		return nil, nil, err
+	}
+	return slog.New(slog.NewJSONHandler(f, opts)), f, nil
+}
+
+// Write writes p to the file, rotating it first if writing p would exceed
+// MaxSize or the file is older than MaxAge. If rotation fails, p is still
+// written to the current file and the rotation error is returned; rotation is
+// tried again on the next write.
+func (f *RotatingFile) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.file == nil {
+		return 0, os.ErrClosed
+	}
+	var rotateErr error
+	if f.shouldRotate(len(p)) {
+		rotateErr = f.rotate()
+	}
+
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+	if err != nil {
+		return n, err
+	}
+	return n, rotateErr
+}
+
+// Rotate renames the current file, opens a new one and closes the old one. If
+// rotation fails, the current file is kept open and written to.
+func (f *RotatingFile) Rotate() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.file == nil {
+		return os.ErrClosed
+	}
+	return f.rotate()
+}
+
+// Close closes the file. Writes after Close fail.
+func (f *RotatingFile) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.file == nil {
+		return nil
+	}
+	err := f.file.Close()
+	f.file = nil
+	return err
+}
+
+func (f *RotatingFile) shouldRotate(n int) bool {
+	// An empty file is never rotated, so that writes larger than MaxSize
+	// still succeed.
+	if f.size == 0 {
+		return false
+	}
+	if f.config.MaxSize > 0 && f.size+int64(n) > f.config.MaxSize {
+		return true
+	}
+	return f.config.MaxAge > 0 && f.now().Sub(f.opened) >= f.config.MaxAge
+}
+
+func (f *RotatingFile) open() error {
+	if err := os.MkdirAll(filepath.Dir(f.config.Path), 0o755); err != nil {
+		return fmt.Errorf("llms: failed to create log directory: %w", err)
+	}
+
+	// f.file is only replaced once the new file is open, so that a failed
+	// rotation leaves the current one in place.
+	file, err := os.OpenFile(f.config.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("llms: failed to open log file: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("llms: failed to open log file: %w", err)
+	}
+
+	f.file = file
+	f.size = info.Size()
+	f.opened = f.now()
+	return nil
+}
+
+func (f *RotatingFile) rotate() error {
+	// The file is renamed while still open, so that it can be written to
+	// until its replacement is open. It is missing if a previous rotation
+	// renamed it but failed to open the new file, or if it was removed.
+	if err := os.Rename(f.config.Path, f.backupName(f.now())); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("llms: failed to rotate log file: %w", err)
+	}
+
+	old := f.file
+	if err := f.open(); err != nil {
+		return err
+	}
+	if err := old.Close(); err != nil {
+		return fmt.Errorf("llms: failed to close log file: %w", err)
+	}
+	return f.removeOldBackups()
+}
+
+// backupTimeFormat sorts lexically in time order and contains no characters
+// that are invalid in file names.
+const backupTimeFormat = "20060102T150405.000000000"
+
+// backupName returns the name of the file rotated at t, such as
+// "llm-20250102T150405.000000000.log" for "llm.log".
+func (f *RotatingFile) backupName(t time.Time) string {
+	ext := filepath.Ext(f.config.Path)
+	base := strings.TrimSuffix(f.config.Path, ext)
+	return base + "-" + t.UTC().Format(backupTimeFormat) + ext
+}
+
+func (f *RotatingFile) removeOldBackups() error {
+	if f.config.MaxBackups <= 0 {
+		return nil
+	}
+
+	dir := filepath.Dir(f.config.Path)
+	ext := filepath.Ext(f.config.Path)
+	prefix := strings.TrimSuffix(filepath.Base(f.config.Path), ext) + "-"
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return fmt.Errorf("llms: failed to list rotated log files: %w", err)
+	}
+
+	var backups []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.Type().IsRegular() && strings.HasPrefix(name, prefix) && strings.HasSuffix(name, ext) {
+			if _, err := time.Parse(backupTimeFormat, strings.TrimSuffix(strings.TrimPrefix(name, prefix), ext)); err == nil {
+				backups = append(backups, name)
+			}
+		}
+	}
+	if len(backups) <= f.config.MaxBackups {
+		return nil
+	}
+
+	sort.Strings(backups)
+	for _, name := range backups[:len(backups)-f.config.MaxBackups] {
+		if err := os.Remove(filepath.Join(dir, name)); err != nil {
+			return fmt.Errorf("llms: failed to remove rotated log file: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,146 @@
+package llms
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRotatingFile_Size(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "llm.log")
+	f, err := NewRotatingFile(RotatingFileConfig{Path: path, MaxSize: 10, MaxBackups: 2})
+	require.NoError(t, err)
+	defer f.Close()
+
+	clock := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	f.now = func() time.Time {
+		clock = clock.Add(time.Second)
+		return clock
+	}
+
+	for _, line := range []string{"aaaaaa\n", "bbbbbb\n", "cccccc\n", "dddddd\n"} {
+		_, err := f.Write([]byte(line))
+		require.NoError(t, err)
+	}
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "dddddd\n", string(data))
+
+	// Only the two newest rotated files are kept.
+	backups, err := filepath.Glob(filepath.Join(dir, "llm-*.log"))
+	require.NoError(t, err)
+	require.Len(t, backups, 2)
+	data, err = os.ReadFile(backups[0])
+	require.NoError(t, err)
+	assert.Equal(t, "bbbbbb\n", string(data))
+	data, err = os.ReadFile(backups[1])
+	require.NoError(t, err)
+	assert.Equal(t, "cccccc\n", string(data))
+}
+
+func TestRotatingFile_Age(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs", "llm.log")
+	f, err := NewRotatingFile(RotatingFileConfig{Path: path, MaxSize: -1, MaxAge: time.Hour})
+	require.NoError(t, err)
+	defer f.Close()
+
+	clock := time.Now()
+	f.now = func() time.Time { return clock }
+
+	_, err = f.Write([]byte("old\n"))
+	require.NoError(t, err)
+	_, err = f.Write([]byte("still old\n"))
+	require.NoError(t, err)
+
+	clock = clock.Add(time.Hour)
+	_, err = f.Write([]byte("new\n"))
+	require.NoError(t, err)
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "new\n", string(data))
+
+	backups, err := filepath.Glob(filepath.Join(filepath.Dir(path), "llm-*.log"))
+	require.NoError(t, err)
+	require.Len(t, backups, 1)
+	data, err = os.ReadFile(backups[0])
+	require.NoError(t, err)
+	assert.Equal(t, "old\nstill old\n", string(data))
+}
+
+func TestRotatingFile_RotationFailure(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "llm.log")
+	f, err := NewRotatingFile(RotatingFileConfig{Path: path, MaxSize: 10})
+	require.NoError(t, err)
+	defer f.Close()
+
+	clock := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	f.now = func() time.Time { return clock }
+
+	// A non-empty directory in the way of the rotated file makes the rename
+	// fail.
+	blocker := f.backupName(clock)
+	require.NoError(t, os.MkdirAll(filepath.Join(blocker, "x"), 0o755))
+
+	_, err = f.Write([]byte("aaaaaa\n"))
+	require.NoError(t, err)
+	n, err := f.Write([]byte("bbbbbb\n"))
+	assert.ErrorContains(t, err, "failed to rotate log file")
+	assert.Equal(t, 7, n)
+
+	// The current file is kept and rotation is retried on the next write.
+	require.NoError(t, os.RemoveAll(blocker))
+	_, err = f.Write([]byte("cccccc\n"))
+	require.NoError(t, err)
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "cccccc\n", string(data))
+	data, err = os.ReadFile(blocker)
+	require.NoError(t, err)
+	assert.Equal(t, "aaaaaa\nbbbbbb\n", string(data))
+
+	// A file removed from under the writer is recreated on rotation.
+	require.NoError(t, os.Remove(path))
+	require.NoError(t, f.Rotate())
+	_, err = f.Write([]byte("dddddd\n"))
+	require.NoError(t, err)
+	data, err = os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "dddddd\n", string(data))
+}
+
+func TestRotatingFile_Closed(t *testing.T) {
+	f, err := NewRotatingFile(RotatingFileConfig{Path: filepath.Join(t.TempDir(), "llm.log")})
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	_, err = f.Write([]byte("x"))
+	assert.ErrorIs(t, err, os.ErrClosed)
+	assert.NoError(t, f.Close())
+}
+
+func TestNewFileLogger(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "llm.log")
+	logger, f, err := NewFileLogger(RotatingFileConfig{Path: path}, nil)
+	require.NoError(t, err)
+
+	logger.Info("HTTP request completed", "status", 200)
+	require.NoError(t, f.Close())
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	var entry map[string]any
+	require.NoError(t, json.Unmarshal([]byte(strings.TrimSpace(string(data))), &entry))
+	assert.Equal(t, "HTTP request completed", entry["msg"])
+	assert.Equal(t, 200.0, entry["status"])
+}
@@ -0,0 +1,49 @@
+package llms
+
+import (
+	"log/slog"
+	"sync/atomic"
+)
+
+var (
+	httpLogger   atomic.Pointer[slog.Logger]
+	clientLogger atomic.Pointer[slog.Logger]
+)
+
+// SetHTTPLogger sets the logger HTTP traffic is logged to when no logger is
+// given, such as by NewHTTPClient and the WithHttpLogging modifiers of the
+// providers. A nil logger restores the default, slog.Default(). It only
+// affects clients created afterwards.
+//
+// Together with SetClientLogger, it lets LLM traffic be routed separately
+// from application logs and from each other:
+//
+//	llms.SetHTTPLogger(slog.New(handler).WithGroup("llmite.http"))
+//	llms.SetClientLogger(slog.New(handler).WithGroup("llmite.client"))
+func SetHTTPLogger(logger *slog.Logger) {
+	httpLogger.Store(logger)
+}
+
+// HTTPLogger returns the logger set by SetHTTPLogger, or slog.Default().
+func HTTPLogger() *slog.Logger {
+	if logger := httpLogger.Load(); logger != nil {
+		return logger
+	}
+	return slog.Default()
+}
+
+// SetClientLogger sets the logger calls are logged to when no logger is
+// given, such as by the Logging and UsageLogging middleware. A nil logger
+// restores the default, slog.Default(). It only affects middleware and
+// clients created afterwards.
+func SetClientLogger(logger *slog.Logger) {
+	clientLogger.Store(logger)
+}
+
+// ClientLogger returns the logger set by SetClientLogger, or slog.Default().
+func ClientLogger() *slog.Logger {
+	if logger := clientLogger.Load(); logger != nil {
+		return logger
+	}
+	return slog.Default()
+}
@@ -0,0 +1,78 @@
+package llms
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDefaultLoggers(t *testing.T) {
+	var httpBuf, clientBuf bytes.Buffer
+	SetHTTPLogger(slog.New(slog.NewJSONHandler(&httpBuf, nil)))
+	SetClientLogger(slog.New(slog.NewJSONHandler(&clientBuf, nil)))
+	t.Cleanup(func() {
+		SetHTTPLogger(nil)
+		SetClientLogger(nil)
+	})
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	resp, err := NewDefaultHTTPClientWithLogging().Get(server.URL)
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	llm := Chain(newFakeLLM(fakeResult{resp: textResponse("hi")}), Logging(nil))
+	_, err = llm.Generate(context.Background(), nil)
+	require.NoError(t, err)
+
+	var httpMessages, clientMessages []string
+	for _, record := range logRecords(t, &httpBuf) {
+		httpMessages = append(httpMessages, record["msg"].(string))
+	}
+	for _, record := range logRecords(t, &clientBuf) {
+		clientMessages = append(clientMessages, record["msg"].(string))
+	}
+	assert.Equal(t, []string{"HTTP request started", "HTTP request completed"}, httpMessages)
+	assert.Equal(t, []string{"LLM call completed"}, clientMessages)
+
+	SetHTTPLogger(nil)
+	assert.Equal(t, slog.Default(), HTTPLogger())
+}
+
+func TestLoggingRoundTripper_Group(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	var buf, ctxBuf bytes.Buffer
+	client := NewHTTPClient(HTTPClientOptions{
+		Logger: slog.New(slog.NewJSONHandler(&buf, nil)),
+		Config: &LoggingConfig{Group: "llmite.http"},
+	})
+
+	resp, err := client.Get(server.URL)
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	ctx := WithHTTPLogger(context.Background(), slog.New(slog.NewJSONHandler(&ctxBuf, nil)))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+	require.NoError(t, err)
+	resp, err = client.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	for _, b := range []*bytes.Buffer{&buf, &ctxBuf} {
+		for _, record := range logRecords(t, b) {
+			group, ok := record["llmite.http"].(map[string]any)
+			require.True(t, ok, record)
+			assert.Equal(t, "GET", group["method"])
+			assert.NotContains(t, record, "method")
+		}
+	}
+}
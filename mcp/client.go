@@ -0,0 +1,332 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os/exec"
+	"strings"
+	"sync/atomic"
+
+	"github.com/invopop/jsonschema"
+
+	"github.com/llmite-ai/llms"
+)
+
+// ProtocolVersion is the version of the Model Context Protocol the client
+// speaks.
+const ProtocolVersion = "2025-03-26"
+
+// Client is a connection to an MCP server. Its tools can be used with any
+// provider, and executed by llms.RunTools:
+//
+//	client, err := mcp.NewHTTPClient(ctx, "https://example.com/mcp")
+//	if err != nil {
+//		return err
+//	}
+//	defer client.Close()
+//
+//	tools, err := client.Tools(ctx)
+//
+// A Client is safe for concurrent use.
+type Client struct {
+	transport transport
+	nextID    atomic.Int64
+
+	name       string
+	version    string
+	httpClient *http.Client
+	header     http.Header
+
+	serverInfo Implementation
+}
+
+// Implementation describes an MCP client or server.
+type Implementation struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+type Modifier func(*Client)
+
+// WithClientInfo sets the name and version the client reports to the server.
+// Defaults to "llms" with an empty version.
+func WithClientInfo(name, version string) Modifier {
+	return func(c *Client) {
+		c.name = name
+		c.version = version
+	}
+}
+
+// WithHTTPClient sets the HTTP client used by NewHTTPClient. Defaults to
+// http.DefaultClient.
+func WithHTTPClient(client *http.Client) Modifier {
+	return func(c *Client) {
+		c.httpClient = client
+	}
+}
+
+// WithHeader adds a header to every request sent by NewHTTPClient, such as
+// an Authorization header.
+func WithHeader(key, value string) Modifier {
+	return func(c *Client) {
+		if c.header == nil {
+			c.header = http.Header{}
+		}
+		c.header.Add(key, value)
+	}
+}
+
+// NewStdioClient starts cmd and connects to the MCP server it runs, which
+// reads requests from its stdin and writes responses to its stdout. cmd must
+// not have been started. Close stops the server by closing its stdin, and
+// kills it if it has not exited five seconds later.
+func NewStdioClient(ctx context.Context, cmd *exec.Cmd, mods ...Modifier) (*Client, error) {
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, fmt.Errorf("mcp: failed to create stdin pipe: %w", err)
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("mcp: failed to create stdout pipe: %w", err)
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("mcp: failed to start server: %w", err)
+	}
+
+	c := newClient(mods)
+	c.transport = newStdioTransport(stdout, stdin, cmd)
+	return c.start(ctx)
+}
+
+// NewHTTPClient connects to the MCP server at url using the streamable HTTP
+// transport.
+func NewHTTPClient(ctx context.Context, url string, mods ...Modifier) (*Client, error) {
+	c := newClient(mods)
+	c.transport = &httpTransport{
+		url:    url,
+		client: c.httpClient,
+		header: c.header,
+	}
+	return c.start(ctx)
+}
+
+func newClient(mods []Modifier) *Client {
+	c := &Client{
+		name:       "llms",
+		httpClient: http.DefaultClient,
+	}
+	for _, mod := range mods {
+		mod(c)
+	}
+	return c
+}
+
+// start initializes the connection, closing it if that fails.
+func (c *Client) start(ctx context.Context) (*Client, error) {
+	if err := c.initialize(ctx); err != nil {
+		c.transport.close()
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *Client) initialize(ctx context.Context) error {
+	params := map[string]any{
+		"protocolVersion": ProtocolVersion,
+		"capabilities":    map[string]any{},
+		"clientInfo":      Implementation{Name: c.name, Version: c.version},
+	}
+	var result struct {
+		ProtocolVersion string         `json:"protocolVersion"`
+		ServerInfo      Implementation `json:"serverInfo"`
+	}
+	if err := c.call(ctx, "initialize", params, &result); err != nil {
+		return fmt.Errorf("mcp: failed to initialize: %w", err)
+	}
+	c.serverInfo = result.ServerInfo
+
+	return c.notify(ctx, "notifications/initialized")
+}
+
+// ServerInfo returns the name and version the server reported.
+func (c *Client) ServerInfo() Implementation {
+	return c.serverInfo
+}
+
+// Close closes the connection to the server.
+func (c *Client) Close() error {
+	return c.transport.close()
+}
+
+// Tools lists the tools of the server. Each is an llms.ExecutableTool that
+// calls the server when it is executed. Their names are the server's, so use
+// llms.NamespaceTools when combining them with tools that might share their
+// names.
+func (c *Client) Tools(ctx context.Context) ([]llms.Tool, error) {
+	var tools []llms.Tool
+	cursor := ""
+	for {
+		params := map[string]any{}
+		if cursor != "" {
+			params["cursor"] = cursor
+		}
+
+		var result struct {
+			Tools []struct {
+				Name        string             `json:"name"`
+				Description string             `json:"description"`
+				InputSchema *jsonschema.Schema `json:"inputSchema"`
+			} `json:"tools"`
+			NextCursor string `json:"nextCursor"`
+		}
+		if err := c.call(ctx, "tools/list", params, &result); err != nil {
+			return nil, fmt.Errorf("mcp: failed to list tools: %w", err)
+		}
+
+		for _, t := range result.Tools {
+			tools = append(tools, &tool{
+				client:      c,
+				name:        t.Name,
+				description: t.Description,
+				schema:      t.InputSchema,
+			})
+		}
+
+		if result.NextCursor == "" {
+			return tools, nil
+		}
+		cursor = result.NextCursor
+	}
+}
+
+// CallToolResult is the result of a tool call.
+type CallToolResult struct {
+	Content []Content `json:"content"`
+	// StructuredContent is the result as JSON, if the tool returned it.
+	StructuredContent json.RawMessage `json:"structuredContent,omitempty"`
+	// IsError reports whether the tool failed. The content describes the
+	// failure.
+	IsError bool `json:"isError,omitempty"`
+}
+
+// Content is an item of content returned by a tool. Only the fields of its
+// Type are set.
+type Content struct {
+	Type     string    `json:"type"`
+	Text     string    `json:"text,omitempty"`
+	Data     string    `json:"data,omitempty"`
+	MimeType string    `json:"mimeType,omitempty"`
+	Resource *Resource `json:"resource,omitempty"`
+}
+
+// Resource is a resource embedded in a tool result.
+type Resource struct {
+	URI      string `json:"uri"`
+	MimeType string `json:"mimeType,omitempty"`
+	Text     string `json:"text,omitempty"`
+}
+
+// Text returns the text of the result's content, one item per line. Content
+// that has no text, such as images, is replaced by a short description.
+func (r *CallToolResult) Text() string {
+	if len(r.Content) == 0 && len(r.StructuredContent) > 0 {
+		return string(r.StructuredContent)
+	}
+
+	lines := make([]string, 0, len(r.Content))
+	for _, content := range r.Content {
+		switch {
+		case content.Type == "text":
+			lines = append(lines, content.Text)
+		case content.Resource != nil && content.Resource.Text != "":
+			lines = append(lines, content.Resource.Text)
+		case content.Resource != nil:
+			lines = append(lines, fmt.Sprintf("[resource %s]", content.Resource.URI))
+		default:
+			lines = append(lines, fmt.Sprintf("[%s %s]", content.Type, content.MimeType))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// CallTool calls the named tool with args, which must be a JSON object.
+func (c *Client) CallTool(ctx context.Context, name string, args json.RawMessage) (*CallToolResult, error) {
+	if len(args) == 0 {
+		args = json.RawMessage("{}")
+	}
+	params := map[string]any{
+		"name":      name,
+		"arguments": args,
+	}
+
+	var result CallToolResult
+	if err := c.call(ctx, "tools/call", params, &result); err != nil {
+		return nil, fmt.Errorf("mcp: failed to call tool %q: %w", name, err)
+	}
+	return &result, nil
+}
+
+// call sends a request and decodes its result into result.
+func (c *Client) call(ctx context.Context, method string, params, result any) error {
+	id := c.nextID.Add(1)
+	msg, err := c.transport.send(ctx, &request{
+		JSONRPC: "2.0",
+		ID:      &id,
+		Method:  method,
+		Params:  params,
+	})
+	if err != nil {
+		return err
+	}
+	if msg.Error != nil {
+		return msg.Error
+	}
+	if err := json.Unmarshal(msg.Result, result); err != nil {
+		return fmt.Errorf("mcp: failed to decode %s result: %w", method, err)
+	}
+	return nil
+}
+
+// notify sends a notification, which has no response.
+func (c *Client) notify(ctx context.Context, method string) error {
+	_, err := c.transport.send(ctx, &request{
+		JSONRPC: "2.0",
+		Method:  method,
+	})
+	return err
+}
+
+// tool is a tool of an MCP server.
+type tool struct {
+	client      *Client
+	name        string
+	description string
+	schema      *jsonschema.Schema
+}
+
+func (t *tool) Name() string               { return t.name }
+func (t *tool) Description() string        { return t.description }
+func (t *tool) Schema() *jsonschema.Schema { return t.schema }
+
+// Execute calls the tool on the server. Errors reported by the tool and by the
+// server are returned to the model, while failures to reach the server are
+// transient.
+func (t *tool) Execute(ctx context.Context, args []byte) *llms.ToolResult {
+	result, err := t.client.CallTool(ctx, t.name, args)
+	if err != nil {
+		var rpcErr *RPCError
+		if errors.As(err, &rpcErr) {
+			return &llms.ToolResult{Error: err}
+		}
+		return &llms.ToolResult{Error: err, Kind: llms.ToolErrorTransient}
+	}
+
+	text := result.Text()
+	if result.IsError {
+		return &llms.ToolResult{Content: text, Error: errors.New(text)}
+	}
+	return &llms.ToolResult{Content: text}
+}
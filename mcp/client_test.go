@@ -0,0 +1,303 @@
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os/exec"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/llmite-ai/llms"
+)
+
+// fakeServer answers MCP requests with a fixed set of tools. It lists its
+// tools over two pages to exercise pagination.
+type fakeServer struct {
+	mu      sync.Mutex
+	methods []string
+}
+
+func (s *fakeServer) handle(msg map[string]any) (any, *RPCError) {
+	method, _ := msg["method"].(string)
+	s.mu.Lock()
+	s.methods = append(s.methods, method)
+	s.mu.Unlock()
+
+	params, _ := msg["params"].(map[string]any)
+	switch method {
+	case "initialize":
+		return map[string]any{
+			"protocolVersion": ProtocolVersion,
+			"capabilities":    map[string]any{"tools": map[string]any{}},
+			"serverInfo":      map[string]any{"name": "fake", "version": "1.0.0"},
+		}, nil
+	case "tools/list":
+		if params["cursor"] == nil {
+			return map[string]any{
+				"tools": []any{map[string]any{
+					"name":        "add",
+					"description": "Adds two numbers",
+					"inputSchema": map[string]any{
+						"type":       "object",
+						"properties": map[string]any{"a": map[string]any{"type": "number"}, "b": map[string]any{"type": "number"}},
+						"required":   []string{"a", "b"},
+					},
+				}},
+				"nextCursor": "page2",
+			}, nil
+		}
+		return map[string]any{
+			"tools": []any{map[string]any{
+				"name":        "fail",
+				"description": "Always fails",
+				"inputSchema": map[string]any{"type": "object"},
+			}},
+		}, nil
+	case "tools/call":
+		args, _ := params["arguments"].(map[string]any)
+		switch params["name"] {
+		case "add":
+			a, _ := args["a"].(float64)
+			b, _ := args["b"].(float64)
+			return map[string]any{"content": []any{map[string]any{"type": "text", "text": fmt.Sprint(a + b)}}}, nil
+		case "fail":
+			return map[string]any{"content": []any{map[string]any{"type": "text", "text": "it broke"}}, "isError": true}, nil
+		}
+		return nil, &RPCError{Code: -32602, Message: "unknown tool"}
+	}
+	return nil, &RPCError{Code: -32601, Message: "method not found"}
+}
+
+// respond returns the JSON-RPC response to msg, or nil for notifications.
+func (s *fakeServer) respond(msg map[string]any) map[string]any {
+	result, rpcErr := s.handle(msg)
+	id, ok := msg["id"]
+	if !ok {
+		return nil
+	}
+	resp := map[string]any{"jsonrpc": "2.0", "id": id}
+	if rpcErr != nil {
+		resp["error"] = rpcErr
+	} else {
+		resp["result"] = result
+	}
+	return resp
+}
+
+func (s *fakeServer) Methods() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.methods...)
+}
+
+// newHTTPServer serves server over the streamable HTTP transport, responding
+// with server-sent events if stream is set. It records the requests it
+// receives, without their bodies.
+func newHTTPServer(t *testing.T, server *fakeServer, stream bool) (*httptest.Server, *[]*http.Request) {
+	var (
+		mu       sync.Mutex
+		requests []*http.Request
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests = append(requests, r.Clone(context.Background()))
+		mu.Unlock()
+
+		if r.Method == http.MethodDelete {
+			return
+		}
+
+		var msg map[string]any
+		if err := json.NewDecoder(r.Body).Decode(&msg); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		resp := server.respond(msg)
+		if resp == nil {
+			w.WriteHeader(http.StatusAccepted)
+			return
+		}
+
+		w.Header().Set("Mcp-Session-Id", "session-1")
+		if !stream {
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(resp)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		data, _ := json.Marshal(resp)
+		fmt.Fprint(w, "data: {\"jsonrpc\":\"2.0\",\"method\":\"notifications/progress\"}\n\n")
+		fmt.Fprintf(w, "event: message\ndata: %s\n\n", data)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &requests
+}
+
+// execute executes an MCP tool, all of which are executable.
+func execute(tool llms.Tool, args []byte) *llms.ToolResult {
+	return tool.(llms.ExecutableTool).Execute(context.Background(), args)
+}
+
+func TestHTTPClient(t *testing.T) {
+	for _, stream := range []bool{false, true} {
+		t.Run(fmt.Sprintf("stream=%t", stream), func(t *testing.T) {
+			server := &fakeServer{}
+			srv, requests := newHTTPServer(t, server, stream)
+
+			ctx := context.Background()
+			client, err := NewHTTPClient(ctx, srv.URL, WithHeader("Authorization", "Bearer token"))
+			require.NoError(t, err)
+			assert.Equal(t, Implementation{Name: "fake", Version: "1.0.0"}, client.ServerInfo())
+
+			tools, err := client.Tools(ctx)
+			require.NoError(t, err)
+			require.Len(t, tools, 2)
+			assert.Equal(t, "add", tools[0].Name())
+			assert.Equal(t, "Adds two numbers", tools[0].Description())
+			assert.Equal(t, []string{"a", "b"}, tools[0].Schema().Required)
+			assert.Equal(t, "fail", tools[1].Name())
+
+			res := execute(tools[0], []byte(`{"a":1,"b":2}`))
+			require.NoError(t, res.Error)
+			assert.Equal(t, "3", res.Content)
+
+			res = execute(tools[1], []byte(`{}`))
+			assert.EqualError(t, res.Error, "it broke")
+			assert.Equal(t, llms.ToolErrorUser, res.Kind)
+
+			require.NoError(t, client.Close())
+
+			assert.Equal(t, []string{"initialize", "notifications/initialized", "tools/list", "tools/list", "tools/call", "tools/call"}, server.Methods())
+			first, last := (*requests)[0], (*requests)[len(*requests)-1]
+			assert.Equal(t, "Bearer token", first.Header.Get("Authorization"))
+			assert.Empty(t, first.Header.Get("Mcp-Session-Id"))
+			assert.Equal(t, "session-1", (*requests)[1].Header.Get("Mcp-Session-Id"))
+			assert.Equal(t, ProtocolVersion, (*requests)[1].Header.Get("Mcp-Protocol-Version"))
+
+			// Closing the client ends the session.
+			assert.Equal(t, http.MethodDelete, last.Method)
+			assert.Equal(t, "session-1", last.Header.Get("Mcp-Session-Id"))
+		})
+	}
+}
+
+func TestHTTPClient_Unreachable(t *testing.T) {
+	server := &fakeServer{}
+	srv, _ := newHTTPServer(t, server, false)
+
+	ctx := context.Background()
+	client, err := NewHTTPClient(ctx, srv.URL)
+	require.NoError(t, err)
+	tools, err := client.Tools(ctx)
+	require.NoError(t, err)
+
+	srv.Close()
+
+	res := execute(tools[0], []byte(`{"a":1,"b":2}`))
+	assert.Error(t, res.Error)
+	assert.Equal(t, llms.ToolErrorTransient, res.Kind)
+}
+
+func TestStdioClient(t *testing.T) {
+	server := &fakeServer{}
+
+	// Run the server in-process, connected to the client by pipes.
+	clientR, serverW := io.Pipe()
+	serverR, clientW := io.Pipe()
+	go func() {
+		defer serverW.Close()
+		scanner := bufio.NewScanner(serverR)
+		enc := json.NewEncoder(serverW)
+		// Ping the client before anything else, as servers may.
+		enc.Encode(map[string]any{"jsonrpc": "2.0", "id": "ping-1", "method": "ping"})
+		for scanner.Scan() {
+			var msg map[string]any
+			if err := json.Unmarshal(scanner.Bytes(), &msg); err != nil {
+				continue
+			}
+			if _, ok := msg["method"]; !ok {
+				// The client's answer to the ping.
+				assert.Equal(t, "ping-1", msg["id"])
+				continue
+			}
+			if resp := server.respond(msg); resp != nil {
+				enc.Encode(resp)
+			}
+		}
+	}()
+
+	ctx := context.Background()
+	c := newClient(nil)
+	c.transport = newStdioTransport(clientR, clientW, nil)
+	client, err := c.start(ctx)
+	require.NoError(t, err)
+
+	tools, err := client.Tools(ctx)
+	require.NoError(t, err)
+	require.Len(t, tools, 2)
+
+	// Calls may be made concurrently.
+	var wg sync.WaitGroup
+	for i := range 5 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			res := execute(tools[0], []byte(fmt.Sprintf(`{"a":%d,"b":1}`, i)))
+			assert.NoError(t, res.Error)
+			assert.Equal(t, fmt.Sprint(i+1), res.Content)
+		}()
+	}
+	wg.Wait()
+
+	_, err = client.CallTool(ctx, "missing", nil)
+	var rpcErr *RPCError
+	require.ErrorAs(t, err, &rpcErr)
+	assert.Equal(t, -32602, rpcErr.Code)
+
+	require.NoError(t, client.Close())
+	_, err = client.CallTool(ctx, "add", nil)
+	assert.ErrorIs(t, err, ErrClosed)
+}
+
+func TestStdioTransport_CloseKillsServer(t *testing.T) {
+	// sleep ignores its stdin, so it doesn't exit when it is closed.
+	cmd := exec.Command("sleep", "60")
+	stdin, err := cmd.StdinPipe()
+	require.NoError(t, err)
+	stdout, err := cmd.StdoutPipe()
+	require.NoError(t, err)
+	if err := cmd.Start(); err != nil {
+		t.Skipf("sleep not available: %v", err)
+	}
+
+	transport := newStdioTransport(stdout, stdin, cmd)
+	transport.closeTimeout = 10 * time.Millisecond
+
+	start := time.Now()
+	err = transport.close()
+	assert.ErrorContains(t, err, "was killed")
+	assert.Less(t, time.Since(start), 10*time.Second)
+	assert.NotNil(t, cmd.ProcessState)
+}
+
+func TestCallToolResult_Text(t *testing.T) {
+	result := &CallToolResult{Content: []Content{
+		{Type: "text", Text: "hello"},
+		{Type: "image", Data: "aGk=", MimeType: "image/png"},
+		{Type: "resource", Resource: &Resource{URI: "file:///a.txt", Text: "contents"}},
+	}}
+	assert.Equal(t, "hello\n[image image/png]\ncontents", result.Text())
+
+	result = &CallToolResult{StructuredContent: json.RawMessage(`{"sum":3}`)}
+	assert.Equal(t, `{"sum":3}`, result.Text())
+}
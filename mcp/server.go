@@ -0,0 +1,250 @@
+package mcp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+
+	"github.com/invopop/jsonschema"
+
+	"github.com/llmite-ai/llms"
+)
+
+// Server serves tools to MCP hosts, such as Claude Desktop, either over stdio
+// with Serve or over the streamable HTTP transport as an http.Handler:
+//
+//	server := mcp.NewServer("weather", "1.0.0", weatherTool)
+//	if err := server.Serve(ctx, os.Stdin, os.Stdout); err != nil {
+//		log.Fatal(err)
+//	}
+//
+// Tool calls are validated against the tool's schema before being executed,
+// as by llms.RunTools. Tools that are not an llms.ExecutableTool are listed
+// but fail when called.
+type Server struct {
+	info   Implementation
+	tools  []llms.Tool
+	byName map[string]llms.Tool
+}
+
+// NewServer creates a Server with the given name and version, which it
+// reports to clients, serving tools.
+func NewServer(name, version string, tools ...llms.Tool) *Server {
+	s := &Server{
+		info:   Implementation{Name: name, Version: version},
+		tools:  tools,
+		byName: make(map[string]llms.Tool, len(tools)),
+	}
+	for _, tool := range tools {
+		s.byName[tool.Name()] = tool
+	}
+	return s
+}
+
+// Serve reads requests from r, one JSON message per line, and writes the
+// responses to w, until r is exhausted or ctx is done. Requests are handled
+// concurrently, so responses may be written in a different order.
+func (s *Server) Serve(ctx context.Context, r io.Reader, w io.Writer) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		writeMu sync.Mutex
+		wg      sync.WaitGroup
+	)
+	defer wg.Wait()
+
+	write := func(resp *response) {
+		data, err := json.Marshal(resp)
+		if err != nil {
+			return
+		}
+		writeMu.Lock()
+		defer writeMu.Unlock()
+		w.Write(append(data, '\n'))
+	}
+
+	lines := make(chan []byte)
+	errs := make(chan error, 1)
+	go func() {
+		br := bufio.NewReader(r)
+		for {
+			line, err := br.ReadBytes('\n')
+			if line = bytes.TrimSpace(line); len(line) > 0 {
+				select {
+				case lines <- line:
+				case <-ctx.Done():
+					return
+				}
+			}
+			if err != nil {
+				if errors.Is(err, io.EOF) {
+					err = nil
+				}
+				errs <- err
+				return
+			}
+		}
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case err := <-errs:
+			return err
+		case line := <-lines:
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if resp := s.handle(ctx, line); resp != nil {
+					write(resp)
+				}
+			}()
+		}
+	}
+}
+
+// ServeHTTP implements the streamable HTTP transport. Every request is
+// answered with a single JSON response. The server is stateless, so it does
+// not issue session IDs.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	resp := s.handle(r.Context(), body)
+	if resp == nil {
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// handle returns the response to a message, or nil if it needs none.
+func (s *Server) handle(ctx context.Context, data []byte) *response {
+	var msg message
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return &response{JSONRPC: "2.0", ID: json.RawMessage("null"), Error: &RPCError{Code: codeParseError, Message: err.Error()}}
+	}
+	if len(msg.ID) == 0 {
+		// Notifications, and responses to requests the server never
+		// sends.
+		return nil
+	}
+
+	resp := &response{JSONRPC: "2.0", ID: msg.ID}
+	result, err := s.call(ctx, msg.Method, msg.Params)
+	if err != nil {
+		var rpcErr *RPCError
+		if !errors.As(err, &rpcErr) {
+			rpcErr = &RPCError{Code: codeInvalidRequest, Message: err.Error()}
+		}
+		resp.Error = rpcErr
+	} else {
+		resp.Result = result
+	}
+	return resp
+}
+
+func (s *Server) call(ctx context.Context, method string, params json.RawMessage) (any, error) {
+	switch method {
+	case "initialize":
+		return map[string]any{
+			"protocolVersion": ProtocolVersion,
+			"capabilities":    map[string]any{"tools": map[string]any{}},
+			"serverInfo":      s.info,
+		}, nil
+	case "ping":
+		return struct{}{}, nil
+	case "tools/list":
+		return map[string]any{"tools": s.listTools()}, nil
+	case "tools/call":
+		var p struct {
+			Name      string          `json:"name"`
+			Arguments json.RawMessage `json:"arguments"`
+		}
+		if err := json.Unmarshal(params, &p); err != nil {
+			return nil, &RPCError{Code: codeInvalidParams, Message: err.Error()}
+		}
+		return s.callTool(ctx, p.Name, p.Arguments)
+	default:
+		return nil, &RPCError{Code: codeMethodNotFound, Message: fmt.Sprintf("method %q not found", method)}
+	}
+}
+
+func (s *Server) listTools() []map[string]any {
+	tools := make([]map[string]any, 0, len(s.tools))
+	for _, tool := range s.tools {
+		schema := tool.Schema()
+		if schema == nil {
+			// MCP requires an input schema.
+			schema = &jsonschema.Schema{Type: "object"}
+		}
+		tools = append(tools, map[string]any{
+			"name":        tool.Name(),
+			"description": llms.ToolDescription(tool),
+			"inputSchema": schema,
+		})
+	}
+	return tools
+}
+
+// callTool executes a tool. Failures of the tool are reported in the result,
+// so the model can see them, and only unknown tools are protocol errors.
+func (s *Server) callTool(ctx context.Context, name string, args json.RawMessage) (result *CallToolResult, err error) {
+	tool, ok := s.byName[name]
+	if !ok {
+		return nil, &RPCError{Code: codeInvalidParams, Message: fmt.Sprintf("unknown tool %q", name)}
+	}
+
+	errorResult := func(err error) *CallToolResult {
+		return &CallToolResult{Content: []Content{{Type: "text", Text: err.Error()}}, IsError: true}
+	}
+
+	// A panicking tool must not take down the server.
+	defer func() {
+		if r := recover(); r != nil {
+			result, err = errorResult(fmt.Errorf("tool %q panicked: %v", name, r)), nil
+		}
+	}()
+
+	exec, ok := tool.(llms.ExecutableTool)
+	if !ok {
+		return errorResult(fmt.Errorf("tool %q cannot be executed", name)), nil
+	}
+	if len(args) == 0 || string(args) == "null" {
+		args = json.RawMessage("{}")
+	}
+	if err := llms.ValidateToolInput(tool, args); err != nil {
+		return errorResult(err), nil
+	}
+
+	res := exec.Execute(ctx, args)
+	switch {
+	case res == nil || (res.Error == nil && res.Content == ""):
+		return &CallToolResult{Content: []Content{}}, nil
+	case res.Error != nil && res.Content == "":
+		return errorResult(res.Error), nil
+	default:
+		return &CallToolResult{
+			Content: []Content{{Type: "text", Text: res.Content}},
+			IsError: res.Error != nil,
+		}, nil
+	}
+}
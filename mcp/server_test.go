@@ -0,0 +1,123 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/invopop/jsonschema"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/llmite-ai/llms"
+)
+
+type greetParams struct {
+	Name string `json:"name"`
+}
+
+// hostedTool is a tool without an Execute method.
+type hostedTool struct{}
+
+func (hostedTool) Name() string               { return "hosted" }
+func (hostedTool) Description() string        { return "Runs elsewhere" }
+func (hostedTool) Schema() *jsonschema.Schema { return nil }
+
+func newTestServer() *Server {
+	greet := llms.NewTool("greet", "Greets someone", func(ctx context.Context, p greetParams) (string, error) {
+		if p.Name == "nobody" {
+			return "", errors.New("nobody to greet")
+		}
+		return "Hello " + p.Name, nil
+	})
+	crash := llms.NewTool("crash", "Panics", func(ctx context.Context, p struct{}) (string, error) {
+		panic("boom")
+	})
+	return NewServer("test", "0.1.0", greet, crash, hostedTool{})
+}
+
+// testServer exercises a client connected to the server returned by
+// newTestServer.
+func testServer(t *testing.T, client *Client) {
+	ctx := context.Background()
+	assert.Equal(t, Implementation{Name: "test", Version: "0.1.0"}, client.ServerInfo())
+
+	tools, err := client.Tools(ctx)
+	require.NoError(t, err)
+	require.Len(t, tools, 3)
+	assert.Equal(t, "greet", tools[0].Name())
+	assert.Equal(t, "Greets someone", tools[0].Description())
+	assert.Equal(t, []string{"name"}, tools[0].Schema().Required)
+	assert.Equal(t, "object", tools[2].Schema().Type)
+
+	res := execute(tools[0], []byte(`{"name":"Ada"}`))
+	require.NoError(t, res.Error)
+	assert.Equal(t, "Hello Ada", res.Content)
+
+	res = execute(tools[0], []byte(`{"name":"nobody"}`))
+	assert.EqualError(t, res.Error, "nobody to greet")
+
+	// Input is validated against the tool's schema.
+	res = execute(tools[0], []byte(`{}`))
+	assert.ErrorContains(t, res.Error, "name")
+
+	res = execute(tools[1], nil)
+	assert.ErrorContains(t, res.Error, `tool "crash" panicked: boom`)
+
+	res = execute(tools[2], nil)
+	assert.ErrorContains(t, res.Error, "cannot be executed")
+
+	_, err = client.CallTool(ctx, "missing", nil)
+	var rpcErr *RPCError
+	require.ErrorAs(t, err, &rpcErr)
+	assert.Equal(t, codeInvalidParams, rpcErr.Code)
+}
+
+func TestServer_HTTP(t *testing.T) {
+	srv := httptest.NewServer(newTestServer())
+	defer srv.Close()
+
+	client, err := NewHTTPClient(context.Background(), srv.URL)
+	require.NoError(t, err)
+	defer client.Close()
+
+	testServer(t, client)
+
+	resp, err := http.Get(srv.URL)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+}
+
+func TestServer_Serve(t *testing.T) {
+	clientR, serverW := io.Pipe()
+	serverR, clientW := io.Pipe()
+
+	served := make(chan error, 1)
+	go func() {
+		served <- newTestServer().Serve(context.Background(), serverR, serverW)
+		serverW.Close()
+	}()
+
+	c := newClient(nil)
+	c.transport = newStdioTransport(clientR, clientW, nil)
+	client, err := c.start(context.Background())
+	require.NoError(t, err)
+
+	testServer(t, client)
+
+	// Closing the client's end of the connection stops the server.
+	require.NoError(t, client.Close())
+	assert.NoError(t, <-served)
+}
+
+func TestServer_ParseError(t *testing.T) {
+	var out strings.Builder
+	err := newTestServer().Serve(context.Background(), strings.NewReader("not json\n"), &out)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"jsonrpc":"2.0","id":null,"error":{"code":-32700,"message":"invalid character 'o' in literal null (expecting 'u')"}}`, out.String())
+}
@@ -0,0 +1,403 @@
+package mcp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrClosed is returned by calls made after the connection to the server was
+// closed.
+var ErrClosed = errors.New("mcp: connection closed")
+
+// request is a JSON-RPC request, or a notification if ID is nil.
+type request struct {
+	JSONRPC string `json:"jsonrpc"`
+	ID      *int64 `json:"id,omitempty"`
+	Method  string `json:"method"`
+	Params  any    `json:"params,omitempty"`
+}
+
+// message is a JSON-RPC message received from the other side. Responses have
+// an ID and no method, requests have both, and notifications have no ID.
+type message struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method,omitempty"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *RPCError       `json:"error,omitempty"`
+}
+
+// JSON-RPC error codes.
+const (
+	codeParseError     = -32700
+	codeInvalidRequest = -32600
+	codeMethodNotFound = -32601
+	codeInvalidParams  = -32602
+)
+
+// RPCError is an error returned by the server in response to a request.
+type RPCError struct {
+	Code    int             `json:"code"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data,omitempty"`
+}
+
+func (e *RPCError) Error() string {
+	return fmt.Sprintf("mcp: server error %d: %s", e.Code, e.Message)
+}
+
+// transport exchanges JSON-RPC messages with a server.
+type transport interface {
+	// send sends req and returns the response to it, or nil for
+	// notifications.
+	send(ctx context.Context, req *request) (*message, error)
+	close() error
+}
+
+// stdioCloseTimeout is how long a server is given to exit after its stdin is
+// closed before it is killed.
+const stdioCloseTimeout = 5 * time.Second
+
+// stdioTransport talks to a server over newline delimited JSON, as used by
+// servers running as a subprocess.
+type stdioTransport struct {
+	w            io.WriteCloser
+	cmd          *exec.Cmd
+	closeTimeout time.Duration
+
+	writeMu sync.Mutex
+
+	mu      sync.Mutex
+	pending map[int64]chan *message
+	err     error
+	done    chan struct{}
+}
+
+func newStdioTransport(r io.Reader, w io.WriteCloser, cmd *exec.Cmd) *stdioTransport {
+	t := &stdioTransport{
+		w:            w,
+		cmd:          cmd,
+		closeTimeout: stdioCloseTimeout,
+		pending:      make(map[int64]chan *message),
+		done:         make(chan struct{}),
+	}
+	go t.read(r)
+	return t
+}
+
+func (t *stdioTransport) send(ctx context.Context, req *request) (*message, error) {
+	data, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("mcp: failed to encode request: %w", err)
+	}
+
+	var ch chan *message
+	if req.ID != nil {
+		ch = make(chan *message, 1)
+		t.mu.Lock()
+		if err := t.err; err != nil {
+			t.mu.Unlock()
+			return nil, err
+		}
+		t.pending[*req.ID] = ch
+		t.mu.Unlock()
+
+		defer func() {
+			t.mu.Lock()
+			delete(t.pending, *req.ID)
+			t.mu.Unlock()
+		}()
+	}
+
+	if err := t.write(data); err != nil {
+		return nil, err
+	}
+	if ch == nil {
+		return nil, nil
+	}
+
+	select {
+	case msg := <-ch:
+		return msg, nil
+	case <-t.done:
+		return nil, ErrClosed
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (t *stdioTransport) write(data []byte) error {
+	t.writeMu.Lock()
+	defer t.writeMu.Unlock()
+
+	if _, err := t.w.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("mcp: failed to write request: %w", err)
+	}
+	return nil
+}
+
+// read dispatches the messages from the server until r is exhausted.
+func (t *stdioTransport) read(r io.Reader) {
+	br := bufio.NewReader(r)
+	for {
+		line, err := br.ReadBytes('\n')
+		if line = bytes.TrimSpace(line); len(line) > 0 {
+			t.dispatch(line)
+		}
+		if err != nil {
+			t.mu.Lock()
+			t.err = ErrClosed
+			t.mu.Unlock()
+			close(t.done)
+			return
+		}
+	}
+}
+
+func (t *stdioTransport) dispatch(line []byte) {
+	var msg message
+	if err := json.Unmarshal(line, &msg); err != nil {
+		return
+	}
+
+	if msg.Method != "" {
+		// Requests from the server must be answered, or it may wait
+		// forever. The answer is written separately, so that reading is
+		// not blocked on a server that is itself blocked writing.
+		// Notifications are ignored.
+		if len(msg.ID) > 0 {
+			if resp, err := json.Marshal(serverRequestResponse(&msg)); err == nil {
+				go t.write(resp)
+			}
+		}
+		return
+	}
+
+	var id int64
+	if err := json.Unmarshal(msg.ID, &id); err != nil {
+		return
+	}
+	t.mu.Lock()
+	ch := t.pending[id]
+	t.mu.Unlock()
+	if ch != nil {
+		ch <- &msg
+	}
+}
+
+func (t *stdioTransport) close() error {
+	t.mu.Lock()
+	t.err = ErrClosed
+	t.mu.Unlock()
+
+	err := t.w.Close()
+	if t.cmd != nil {
+		if waitErr := t.wait(); err == nil {
+			err = waitErr
+		}
+	}
+	return err
+}
+
+// wait waits for the server to exit after its stdin was closed, killing it if
+// it is still running after closeTimeout.
+func (t *stdioTransport) wait() error {
+	exited := make(chan error, 1)
+	go func() {
+		exited <- t.cmd.Wait()
+	}()
+
+	timer := time.NewTimer(t.closeTimeout)
+	defer timer.Stop()
+
+	select {
+	case err := <-exited:
+		return err
+	case <-timer.C:
+		t.cmd.Process.Kill()
+		<-exited
+		return fmt.Errorf("mcp: server did not exit within %s of closing its stdin and was killed", t.closeTimeout)
+	}
+}
+
+// response is a JSON-RPC response sent to the other side.
+type response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  any             `json:"result,omitempty"`
+	Error   *RPCError       `json:"error,omitempty"`
+}
+
+// serverRequestResponse answers a request sent by the server. Only pings are
+// supported, as the client declares no capabilities.
+func serverRequestResponse(msg *message) any {
+	resp := response{JSONRPC: "2.0", ID: msg.ID}
+	if msg.Method == "ping" {
+		resp.Result = struct{}{}
+	} else {
+		resp.Error = &RPCError{Code: codeMethodNotFound, Message: "method not found"}
+	}
+	return resp
+}
+
+// httpTransport talks to a server over the streamable HTTP transport. Every
+// message is POSTed to the server's endpoint, which responds with either a
+// JSON message or a stream of server-sent events ending with the response.
+type httpTransport struct {
+	url    string
+	client *http.Client
+	header http.Header
+
+	mu        sync.Mutex
+	sessionID string
+}
+
+const (
+	sessionIDHeader       = "Mcp-Session-Id"
+	protocolVersionHeader = "Mcp-Protocol-Version"
+)
+
+func (t *httpTransport) send(ctx context.Context, req *request) (*message, error) {
+	data, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("mcp: failed to encode request: %w", err)
+	}
+
+	httpReq, err := t.newRequest(ctx, http.MethodPost, bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Accept", "application/json, text/event-stream")
+
+	resp, err := t.client.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("mcp: request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("mcp: server responded with status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	if id := resp.Header.Get(sessionIDHeader); id != "" {
+		t.mu.Lock()
+		t.sessionID = id
+		t.mu.Unlock()
+	}
+	if req.ID == nil {
+		return nil, nil
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if mediaType == "text/event-stream" {
+		return t.readStream(ctx, resp.Body, *req.ID)
+	}
+
+	var msg message
+	if err := json.NewDecoder(resp.Body).Decode(&msg); err != nil {
+		return nil, fmt.Errorf("mcp: failed to decode response: %w", err)
+	}
+	return &msg, nil
+}
+
+// readStream reads server-sent events until the response to the request with
+// the given ID, answering any requests the server sends in between.
+func (t *httpTransport) readStream(ctx context.Context, body io.Reader, id int64) (*message, error) {
+	var data bytes.Buffer
+	br := bufio.NewReader(body)
+	for {
+		line, err := br.ReadString('\n')
+		line = strings.TrimRight(line, "\r\n")
+
+		switch {
+		case strings.HasPrefix(line, "data:"):
+			data.WriteString(strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+		case line == "" && data.Len() > 0:
+			var msg message
+			if jsonErr := json.Unmarshal(data.Bytes(), &msg); jsonErr == nil {
+				if msg.Method == "" {
+					var got int64
+					if json.Unmarshal(msg.ID, &got) == nil && got == id {
+						return &msg, nil
+					}
+				} else if len(msg.ID) > 0 {
+					t.reply(ctx, serverRequestResponse(&msg))
+				}
+			}
+			data.Reset()
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("mcp: stream ended without a response: %w", err)
+		}
+	}
+}
+
+// reply sends the response to a request from the server.
+func (t *httpTransport) reply(ctx context.Context, resp any) {
+	data, err := json.Marshal(resp)
+	if err != nil {
+		return
+	}
+	httpReq, err := t.newRequest(ctx, http.MethodPost, bytes.NewReader(data))
+	if err != nil {
+		return
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	if resp, err := t.client.Do(httpReq); err == nil {
+		resp.Body.Close()
+	}
+}
+
+func (t *httpTransport) newRequest(ctx context.Context, method string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, t.url, body)
+	if err != nil {
+		return nil, fmt.Errorf("mcp: failed to build request: %w", err)
+	}
+	for key, values := range t.header {
+		req.Header[key] = values
+	}
+
+	t.mu.Lock()
+	sessionID := t.sessionID
+	t.mu.Unlock()
+	if sessionID != "" {
+		req.Header.Set(sessionIDHeader, sessionID)
+	}
+	req.Header.Set(protocolVersionHeader, ProtocolVersion)
+	return req, nil
+}
+
+// close ends the session, if the server started one.
+func (t *httpTransport) close() error {
+	t.mu.Lock()
+	sessionID := t.sessionID
+	t.mu.Unlock()
+	if sessionID == "" {
+		return nil
+	}
+
+	req, err := t.newRequest(context.Background(), http.MethodDelete, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("mcp: failed to end session: %w", err)
+	}
+	resp.Body.Close()
+	return nil
+}
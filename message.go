@@ -1,5 +1,10 @@
 package llms
 
+import (
+	"encoding/json"
+	"fmt"
+)
+
 type Role string
 
 const (
@@ -19,3 +24,46 @@ func NewTextMessage(role Role, text string) Message {
 		Parts: []Part{TextPart{Text: text}},
 	}
 }
+
+// MarshalJSON encodes the message with each part tagged by its registered type
+// name (see RegisterPartType) so that it can be decoded again.
+func (m Message) MarshalJSON() ([]byte, error) {
+	parts := make([]json.RawMessage, 0, len(m.Parts))
+	for i, part := range m.Parts {
+		data, err := MarshalPart(part)
+		if err != nil {
+			return nil, fmt.Errorf("[part %d] %w", i, err)
+		}
+		parts = append(parts, data)
+	}
+
+	return json.Marshal(struct {
+		Role  Role              `json:"role"`
+		Parts []json.RawMessage `json:"parts"`
+	}{
+		Role:  m.Role,
+		Parts: parts,
+	})
+}
+
+func (m *Message) UnmarshalJSON(data []byte) error {
+	var in struct {
+		Role  Role              `json:"role"`
+		Parts []json.RawMessage `json:"parts"`
+	}
+	if err := json.Unmarshal(data, &in); err != nil {
+		return err
+	}
+
+	m.Role = in.Role
+	m.Parts = make([]Part, 0, len(in.Parts))
+	for i, raw := range in.Parts {
+		part, err := UnmarshalPart(raw)
+		if err != nil {
+			return fmt.Errorf("[part %d] %w", i, err)
+		}
+		m.Parts = append(m.Parts, part)
+	}
+
+	return nil
+}
@@ -0,0 +1,192 @@
+package llms
+
+import (
+	"expvar"
+	"io"
+	"net/http"
+	"net/http/httptrace"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// HTTPMetrics records metrics of HTTP requests. Implementations adapt them to
+// a metrics system such as Prometheus or expvar, and must be safe for
+// concurrent use.
+type HTTPMetrics interface {
+	RecordRequest(RequestMetrics)
+}
+
+// HTTPMetricsFunc adapts a function to the HTTPMetrics interface.
+type HTTPMetricsFunc func(RequestMetrics)
+
+func (f HTTPMetricsFunc) RecordRequest(m RequestMetrics) {
+	f(m)
+}
+
+// RequestMetrics describes a completed HTTP request.
+type RequestMetrics struct {
+	Host   string
+	Method string
+	// StatusCode is 0 if the request failed without a response.
+	StatusCode int
+	// Err is the error sending the request or reading the response body.
+	Err error
+	// Duration is the time from sending the request until the response body
+	// was read or closed.
+	Duration time.Duration
+	// TimeToFirstByte is the time from sending the request until the first
+	// byte of the response was received.
+	TimeToFirstByte time.Duration
+	RequestBytes    int64
+	// ResponseBytes is the number of bytes of the response body read by the
+	// caller.
+	ResponseBytes int64
+}
+
+// MetricsRoundTripper is an http.RoundTripper that records the metrics of
+// each request once its response body has been read or closed.
+type MetricsRoundTripper struct {
+	transport http.RoundTripper
+	metrics   HTTPMetrics
+}
+
+// NewMetricsRoundTripper creates a MetricsRoundTripper recording to metrics.
+// A nil transport uses http.DefaultTransport.
+func NewMetricsRoundTripper(transport http.RoundTripper, metrics HTTPMetrics) *MetricsRoundTripper {
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	return &MetricsRoundTripper{transport: transport, metrics: metrics}
+}
+
+// RoundTrip implements the http.RoundTripper interface
+func (t *MetricsRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	m := RequestMetrics{Host: req.URL.Host, Method: req.Method}
+
+	var firstByte time.Time
+	trace := &httptrace.ClientTrace{
+		GotFirstResponseByte: func() { firstByte = time.Now() },
+	}
+	reqClone := req.Clone(httptrace.WithClientTrace(req.Context(), trace))
+
+	var reqBody *countingReader
+	if req.Body != nil && req.Body != http.NoBody {
+		reqBody = &countingReader{ReadCloser: req.Body}
+		reqClone.Body = reqBody
+	}
+
+	resp, err := t.transport.RoundTrip(reqClone)
+	if reqBody != nil {
+		m.RequestBytes = reqBody.n
+	}
+	if err != nil {
+		m.Err = err
+		m.Duration = time.Since(start)
+		t.metrics.RecordRequest(m)
+		return nil, err
+	}
+
+	// Transports that do not trace, such as a Recorder, have received the
+	// first byte once they return.
+	if firstByte.IsZero() {
+		firstByte = time.Now()
+	}
+	m.StatusCode = resp.StatusCode
+	m.TimeToFirstByte = firstByte.Sub(start)
+
+	resp.Body = &metricsBody{
+		countingReader: countingReader{ReadCloser: resp.Body},
+		record: func(n int64, err error) {
+			m.ResponseBytes = n
+			m.Err = err
+			m.Duration = time.Since(start)
+			t.metrics.RecordRequest(m)
+		},
+	}
+	return resp, nil
+}
+
+// countingReader counts the bytes read from a body.
+type countingReader struct {
+	io.ReadCloser
+	n int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	r.n += int64(n)
+	return n, err
+}
+
+// metricsBody calls record once, when the body has been read or closed.
+type metricsBody struct {
+	countingReader
+	record func(n int64, err error)
+	once   sync.Once
+}
+
+func (b *metricsBody) Read(p []byte) (int, error) {
+	n, err := b.countingReader.Read(p)
+	if err != nil {
+		if err == io.EOF {
+			b.finish(nil)
+		} else {
+			b.finish(err)
+		}
+	}
+	return n, err
+}
+
+func (b *metricsBody) Close() error {
+	b.finish(nil)
+	return b.countingReader.Close()
+}
+
+func (b *metricsBody) finish(err error) {
+	b.once.Do(func() { b.record(b.n, err) })
+}
+
+// ExpvarMetrics is an HTTPMetrics publishing counters per host to expvar, so
+// they are served by the /debug/vars handler. For each host it publishes the
+// number of requests, errors and responses per status code, the bytes sent and
+// received, and the total latency and time to first byte in milliseconds.
+type ExpvarMetrics struct {
+	mu   sync.Mutex // Guards the creation of the map of each host
+	vars *expvar.Map
+}
+
+// NewExpvarMetrics creates an ExpvarMetrics publishing to the expvar variable
+// name. Like expvar.NewMap, it panics if name is already in use.
+func NewExpvarMetrics(name string) *ExpvarMetrics {
+	return &ExpvarMetrics{vars: expvar.NewMap(name)}
+}
+
+func (e *ExpvarMetrics) RecordRequest(m RequestMetrics) {
+	host := e.host(m.Host)
+	host.Add("requests", 1)
+	if m.Err != nil {
+		host.Add("errors", 1)
+	}
+	if m.StatusCode != 0 {
+		host.Add("status_"+strconv.Itoa(m.StatusCode), 1)
+	}
+	host.Add("request_bytes", m.RequestBytes)
+	host.Add("response_bytes", m.ResponseBytes)
+	host.AddFloat("latency_ms", float64(m.Duration)/float64(time.Millisecond))
+	host.AddFloat("ttfb_ms", float64(m.TimeToFirstByte)/float64(time.Millisecond))
+}
+
+// host returns the map of the counters of name, creating it if needed.
+func (e *ExpvarMetrics) host(name string) *expvar.Map {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if host, ok := e.vars.Get(name).(*expvar.Map); ok {
+		return host
+	}
+	host := new(expvar.Map).Init()
+	e.vars.Set(name, host)
+	return host
+}
@@ -0,0 +1,105 @@
+package llms
+
+import (
+	"encoding/json"
+	"errors"
+	"expvar"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// metricsRecorder collects the metrics of requests.
+type metricsRecorder struct {
+	mu      sync.Mutex
+	metrics []RequestMetrics
+}
+
+func (r *metricsRecorder) RecordRequest(m RequestMetrics) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.metrics = append(r.metrics, m)
+}
+
+func TestMetricsRoundTripper(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.ReadAll(r.Body)
+		time.Sleep(5 * time.Millisecond)
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte("hello"))
+	}))
+	defer server.Close()
+
+	recorder := &metricsRecorder{}
+	client := NewHTTPClient(HTTPClientOptions{Metrics: recorder})
+
+	resp, err := client.Post(server.URL, "application/json", io.NopCloser(strings.NewReader(`{"a": 1}`)))
+	require.NoError(t, err)
+
+	// Metrics are recorded once the body has been read.
+	assert.Empty(t, recorder.metrics)
+	io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	require.Len(t, recorder.metrics, 1)
+	m := recorder.metrics[0]
+	serverURL, _ := url.Parse(server.URL)
+	assert.Equal(t, serverURL.Host, m.Host)
+	assert.Equal(t, http.MethodPost, m.Method)
+	assert.Equal(t, http.StatusCreated, m.StatusCode)
+	assert.NoError(t, m.Err)
+	assert.Equal(t, int64(8), m.RequestBytes)
+	assert.Equal(t, int64(5), m.ResponseBytes)
+	assert.GreaterOrEqual(t, m.TimeToFirstByte, 5*time.Millisecond)
+	assert.GreaterOrEqual(t, m.Duration, m.TimeToFirstByte)
+}
+
+func TestMetricsRoundTripper_Error(t *testing.T) {
+	var recorded []RequestMetrics
+	failing := roundTripperFunc(func(*http.Request) (*http.Response, error) {
+		return nil, errors.New("connection refused")
+	})
+	client := &http.Client{Transport: NewMetricsRoundTripper(failing, HTTPMetricsFunc(func(m RequestMetrics) {
+		recorded = append(recorded, m)
+	}))}
+
+	_, err := client.Get("http://api.example.com")
+	require.Error(t, err)
+	require.Len(t, recorded, 1)
+	assert.Zero(t, recorded[0].StatusCode)
+	assert.EqualError(t, recorded[0].Err, "connection refused")
+}
+
+func TestExpvarMetrics(t *testing.T) {
+	metrics := NewExpvarMetrics("llms_test_http")
+	metrics.RecordRequest(RequestMetrics{Host: "api.example.com", StatusCode: 200, RequestBytes: 10, ResponseBytes: 20, Duration: 2 * time.Millisecond})
+	metrics.RecordRequest(RequestMetrics{Host: "api.example.com", StatusCode: 429, Duration: time.Millisecond})
+	metrics.RecordRequest(RequestMetrics{Host: "api.example.com", Err: errors.New("reset")})
+
+	var vars map[string]map[string]float64
+	require.NoError(t, json.Unmarshal([]byte(expvar.Get("llms_test_http").String()), &vars))
+	assert.Equal(t, map[string]float64{
+		"requests":       3,
+		"errors":         1,
+		"status_200":     1,
+		"status_429":     1,
+		"request_bytes":  10,
+		"response_bytes": 20,
+		"latency_ms":     3,
+		"ttfb_ms":        0,
+	}, vars["api.example.com"])
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
@@ -0,0 +1,191 @@
+package llms
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// Middleware wraps an LLM to add behaviour around its calls, such as retries,
+// caching or logging.
+type Middleware func(LLM) LLM
+
+// Chain wraps llm with mws. The first middleware is the outermost, so
+//
+//	Chain(llm, Logging(nil), Retry(DefaultRetryConfig()))
+//
+// logs once per call, however many times the call is retried.
+func Chain(llm LLM, mws ...Middleware) LLM {
+	for i := len(mws) - 1; i >= 0; i-- {
+		llm = mws[i](llm)
+	}
+	return llm
+}
+
+// CallMetrics describes a single Generate or GenerateStream call.
+type CallMetrics struct {
+	// Model is the model reported by the wrapped LLM, if it implements
+	// ModelNamer.
+	Model string
+	// Provider is the provider that produced the response, if any.
+	Provider string
+	// Stream is true for GenerateStream calls.
+	Stream bool
+	// Duration is the time taken by the call.
+	Duration time.Duration
+	// Err is the error returned by the call, if any.
+	Err error
+	// Usage is the usage reported with the response, if any.
+	Usage *Usage
+	// RequestID is the ID of the call. See WithRequestID.
+	RequestID string
+}
+
+// Metrics returns a Middleware that calls fn after every call with its
+// metrics. fn is called synchronously, so it should not block.
+func Metrics(fn func(ctx context.Context, m CallMetrics)) Middleware {
+	return func(llm LLM) LLM {
+		return &observed{llm: llm, observe: fn}
+	}
+}
+
+// Logging returns a Middleware that logs every call to logger. Successful calls
+// are logged at info level and failed calls at error level. A nil logger uses
+// ClientLogger().
+func Logging(logger *slog.Logger) Middleware {
+	if logger == nil {
+		logger = ClientLogger()
+	}
+
+	return Metrics(func(ctx context.Context, m CallMetrics) {
+		attrs := []slog.Attr{
+			slog.String("model", m.Model),
+			slog.Bool("stream", m.Stream),
+			slog.Duration("duration", m.Duration),
+			slog.String("request_id", m.RequestID),
+		}
+		if m.Provider != "" {
+			attrs = append(attrs, slog.String("provider", m.Provider))
+		}
+
+		if m.Err != nil {
+			attrs = append(attrs, slog.String("error", m.Err.Error()))
+			logger.LogAttrs(ctx, slog.LevelError, "LLM call failed", attrs...)
+			return
+		}
+
+		logger.LogAttrs(ctx, slog.LevelInfo, "LLM call completed", attrs...)
+	})
+}
+
+// ModelPrice is the price of a model in US dollars per million tokens.
+type ModelPrice struct {
+	Input  float64
+	Output float64
+	// CacheRead and CacheWrite are the prices of input tokens read from and
+	// written to the prompt cache. Zero means the same price as Input.
+	CacheRead  float64
+	CacheWrite float64
+}
+
+// Cost returns the price of u in US dollars.
+func (p ModelPrice) Cost(u Usage) float64 {
+	cacheRead, cacheWrite := p.CacheRead, p.CacheWrite
+	if cacheRead == 0 {
+		cacheRead = p.Input
+	}
+	if cacheWrite == 0 {
+		cacheWrite = p.Input
+	}
+
+	uncached := u.InputTokens - u.CacheReadInputTokens - u.CacheCreationInputTokens
+	total := float64(uncached)*p.Input +
+		float64(u.CacheReadInputTokens)*cacheRead +
+		float64(u.CacheCreationInputTokens)*cacheWrite +
+		float64(u.OutputTokens)*p.Output
+	return total / 1e6
+}
+
+// UsageLogging returns a Middleware that logs the tokens used by every
+// successful call to logger at info level, along with its cost. The cost is
+// the one reported by the provider if there is one, and is otherwise computed
+// from the model's entry in prices. Calls whose response has no usage are not
+// logged. A nil logger uses ClientLogger().
+func UsageLogging(logger *slog.Logger, prices map[string]ModelPrice) Middleware {
+	if logger == nil {
+		logger = ClientLogger()
+	}
+
+	return Metrics(func(ctx context.Context, m CallMetrics) {
+		if m.Err != nil || m.Usage == nil {
+			return
+		}
+
+		u := m.Usage
+		attrs := []slog.Attr{
+			slog.String("model", m.Model),
+			slog.String("request_id", m.RequestID),
+			slog.Int("input_tokens", u.InputTokens),
+			slog.Int("output_tokens", u.OutputTokens),
+			slog.Int("cache_read_tokens", u.CacheReadInputTokens),
+			slog.Int("cache_write_tokens", u.CacheCreationInputTokens),
+		}
+		if m.Provider != "" {
+			attrs = append(attrs, slog.String("provider", m.Provider))
+		}
+		if u.ReasoningTokens > 0 {
+			attrs = append(attrs, slog.Int("reasoning_tokens", u.ReasoningTokens))
+		}
+
+		if u.Cost > 0 {
+			attrs = append(attrs, slog.Float64("cost", u.Cost))
+		} else if price, ok := prices[m.Model]; ok {
+			attrs = append(attrs, slog.Float64("cost", price.Cost(*u)))
+		}
+
+		logger.LogAttrs(ctx, slog.LevelInfo, "LLM usage", attrs...)
+	})
+}
+
+type observed struct {
+	llm     LLM
+	observe func(context.Context, CallMetrics)
+}
+
+func (o *observed) ModelName() string {
+	return modelName(o.llm)
+}
+
+// Generate adds a request ID to ctx before calling the wrapped LLM, so that
+// the metrics of failed calls carry the same ID as their HTTP logs.
+func (o *observed) Generate(ctx context.Context, messages []Message) (*Response, error) {
+	ctx, _ = EnsureRequestID(ctx)
+	start := time.Now()
+	resp, err := o.llm.Generate(ctx, messages)
+	o.report(ctx, false, start, resp, err)
+	return resp, err
+}
+
+func (o *observed) GenerateStream(ctx context.Context, messages []Message, fn StreamFunc) (*Response, error) {
+	ctx, _ = EnsureRequestID(ctx)
+	start := time.Now()
+	resp, err := o.llm.GenerateStream(ctx, messages, fn)
+	o.report(ctx, true, start, resp, err)
+	return resp, err
+}
+
+func (o *observed) report(ctx context.Context, stream bool, start time.Time, resp *Response, err error) {
+	m := CallMetrics{
+		Model:     modelName(o.llm),
+		Stream:    stream,
+		Duration:  time.Since(start),
+		Err:       err,
+		RequestID: RequestIDFromContext(ctx),
+	}
+	if resp != nil {
+		m.Provider = resp.Provider
+		m.Usage = resp.Usage
+	}
+
+	o.observe(ctx, m)
+}
@@ -0,0 +1,168 @@
+package llms
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// namedLLM adds a model name to a fakeLLM.
+type namedLLM struct {
+	*fakeLLM
+	model string
+}
+
+func (n namedLLM) ModelName() string { return n.model }
+
+// tagging returns a middleware that records name in order when a call enters
+// it.
+func tagging(name string, order *[]string) Middleware {
+	return func(llm LLM) LLM {
+		return &taggedLLM{LLM: llm, name: name, order: order}
+	}
+}
+
+type taggedLLM struct {
+	LLM
+	name  string
+	order *[]string
+}
+
+func (t *taggedLLM) Generate(ctx context.Context, messages []Message) (*Response, error) {
+	*t.order = append(*t.order, t.name)
+	return t.LLM.Generate(ctx, messages)
+}
+
+func TestChain_Order(t *testing.T) {
+	var order []string
+	llm := Chain(newFakeLLM(fakeResult{resp: textResponse("hi")}),
+		tagging("outer", &order),
+		tagging("inner", &order),
+	)
+
+	_, err := llm.Generate(context.Background(), nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"outer", "inner"}, order)
+}
+
+func TestChain_RetryInsideCache(t *testing.T) {
+	fake := newFakeLLM(
+		fakeResult{err: &APIError{StatusCode: 500, Err: errors.New("boom")}},
+		fakeResult{resp: textResponse("hi")},
+	)
+	llm := Chain(namedLLM{fakeLLM: fake, model: "m"},
+		Caching(NewLRUCache(10)),
+		Retry(RetryConfig{InitialBackoff: 1, MaxBackoff: 1}),
+	)
+
+	for range 2 {
+		resp, err := llm.Generate(context.Background(), []Message{NewTextMessage(RoleUser, "hello")})
+		require.NoError(t, err)
+		assert.Equal(t, "resp_hi", resp.ID)
+	}
+
+	// One failure plus one retry; the second call was served from cache.
+	assert.Equal(t, 2, fake.Calls())
+	assert.Equal(t, "m", llm.(ModelNamer).ModelName())
+}
+
+func TestMetrics(t *testing.T) {
+	var got []CallMetrics
+	fail := errors.New("boom")
+	fake := newFakeLLM(fakeResult{resp: &Response{Provider: "fake"}}, fakeResult{err: fail})
+	llm := Chain(namedLLM{fakeLLM: fake, model: "m"}, Metrics(func(ctx context.Context, m CallMetrics) {
+		got = append(got, m)
+	}))
+
+	_, err := llm.Generate(WithRequestID(context.Background(), "req-1"), nil)
+	require.NoError(t, err)
+	_, err = llm.GenerateStream(context.Background(), nil, func(*Response, error) bool { return true })
+	require.ErrorIs(t, err, fail)
+
+	require.Len(t, got, 2)
+	assert.Equal(t, "m", got[0].Model)
+	assert.Equal(t, "fake", got[0].Provider)
+	assert.False(t, got[0].Stream)
+	assert.NoError(t, got[0].Err)
+	assert.Equal(t, "req-1", got[0].RequestID)
+	assert.True(t, got[1].Stream)
+	assert.ErrorIs(t, got[1].Err, fail)
+	// Calls without a request ID are given one, even if they fail.
+	assert.NotEmpty(t, got[1].RequestID)
+}
+
+func TestLogging(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+	fake := newFakeLLM(fakeResult{err: errors.New("boom")})
+	llm := Chain(namedLLM{fakeLLM: fake, model: "m"}, Logging(logger))
+
+	_, err := llm.Generate(context.Background(), nil)
+	require.Error(t, err)
+
+	var entry map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &entry))
+	assert.Equal(t, "ERROR", entry["level"])
+	assert.Equal(t, "LLM call failed", entry["msg"])
+	assert.Equal(t, "m", entry["model"])
+	assert.Equal(t, "boom", entry["error"])
+}
+
+func TestModelPrice_Cost(t *testing.T) {
+	price := ModelPrice{Input: 3, Output: 15, CacheRead: 0.3}
+	cost := price.Cost(Usage{
+		InputTokens:              1_000_000,
+		OutputTokens:             100_000,
+		CacheReadInputTokens:     500_000,
+		CacheCreationInputTokens: 100_000,
+	})
+
+	// 400k uncached and 100k cache writes at $3, 500k cache reads at $0.30
+	// and 100k output tokens at $15.
+	assert.InDelta(t, 1.2+0.3+0.15+1.5, cost, 1e-9)
+}
+
+func TestUsageLogging(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+	fake := newFakeLLM(
+		fakeResult{resp: &Response{Provider: "fake", Usage: &Usage{InputTokens: 1000, OutputTokens: 200, CacheReadInputTokens: 400}}},
+		fakeResult{resp: &Response{Usage: &Usage{InputTokens: 10, OutputTokens: 5, Cost: 0.5}}},
+		fakeResult{resp: &Response{}},
+		fakeResult{err: errors.New("boom")},
+	)
+	llm := Chain(namedLLM{fakeLLM: fake, model: "m"}, UsageLogging(logger, map[string]ModelPrice{
+		"m": {Input: 1, Output: 2},
+	}))
+
+	for range 4 {
+		llm.Generate(context.Background(), nil)
+	}
+
+	var entries []map[string]any
+	dec := json.NewDecoder(&buf)
+	for dec.More() {
+		var entry map[string]any
+		require.NoError(t, dec.Decode(&entry))
+		entries = append(entries, entry)
+	}
+
+	// Calls without usage and failed calls are not logged.
+	require.Len(t, entries, 2)
+	assert.Equal(t, "LLM usage", entries[0]["msg"])
+	assert.Equal(t, "m", entries[0]["model"])
+	assert.Equal(t, "fake", entries[0]["provider"])
+	assert.Equal(t, 1000.0, entries[0]["input_tokens"])
+	assert.Equal(t, 200.0, entries[0]["output_tokens"])
+	assert.Equal(t, 400.0, entries[0]["cache_read_tokens"])
+	assert.InDelta(t, 0.0014, entries[0]["cost"], 1e-12)
+
+	// A cost reported by the provider takes precedence over the price table.
+	assert.Equal(t, 0.5, entries[1]["cost"])
+}
@@ -0,0 +1,708 @@
+package mistral
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/invopop/jsonschema"
+
+	"github.com/llmite-ai/llms"
+)
+
+const ProviderMistral = "mistral"
+
+// DefaultBaseURL is the URL of Mistral's API.
+const DefaultBaseURL = "https://api.mistral.ai/v1"
+
+type Client struct {
+	Model       string
+	MaxTokens   int64
+	Temperature *float64
+	TopP        *float64
+	Tools       []llms.Tool
+	Registry    *llms.ToolRegistry
+	// Seed, if set, makes sampling deterministic on a best-effort basis.
+	Seed *int64
+	// ToolChoice, if set, controls whether and which tools the model calls.
+	// It can be overridden per call with llms.ToolChoiceOption.
+	ToolChoice *llms.ToolChoice
+	// ParallelToolCalls, if set, allows or prevents the model from calling
+	// more than one tool in a single response. It can be overridden per call
+	// with llms.ParallelToolCalls.
+	ParallelToolCalls *bool
+	// StopSequences are custom sequences that stop generation when the
+	// model produces them.
+	StopSequences []string
+	// JSONMode makes the model respond with a JSON object.
+	JSONMode bool
+	// SafePrompt prepends Mistral's safety prompt to the conversation.
+	SafePrompt bool
+	// BaseURL is the URL requests are sent to. Defaults to DefaultBaseURL.
+	BaseURL string
+	// APIKey authenticates requests. Defaults to the MISTRAL_API_KEY
+	// environment variable.
+	APIKey string
+
+	httpClient *http.Client
+}
+
+type Modifier func(*Client)
+
+// WithAPIKey sets the API key sent with every request, overriding the
+// MISTRAL_API_KEY environment variable.
+func WithAPIKey(key string) Modifier {
+	return func(c *Client) {
+		c.APIKey = key
+	}
+}
+
+// WithBaseURL sends requests to the server at url instead of Mistral's API.
+func WithBaseURL(url string) Modifier {
+	return func(c *Client) {
+		c.BaseURL = url
+	}
+}
+
+// WithHTTPClient sets the HTTP client requests are sent with.
+func WithHTTPClient(client *http.Client) Modifier {
+	return func(c *Client) {
+		c.httpClient = client
+	}
+}
+
+// WithHttpLogging will log all HTTP requests and responses to the default structured
+// logger.
+func WithHttpLogging() Modifier {
+	return func(c *Client) {
+		c.httpClient = llms.NewDefaultHTTPClientWithLogging()
+	}
+}
+
+// WithModel allows you to set the model on the client. The default model is "mistral-large-latest".
+func WithModel(model string) Modifier {
+	return func(c *Client) {
+		c.Model = model
+	}
+}
+
+// WithMaxTokens allows you to set the max tokens on the client.
+func WithMaxTokens(maxTokens int64) Modifier {
+	return func(c *Client) {
+		c.MaxTokens = maxTokens
+	}
+}
+
+// WithTemperature allows you to set the temperature on the client.
+func WithTemperature(temperature float64) Modifier {
+	return func(c *Client) {
+		c.Temperature = &temperature
+	}
+}
+
+// WithTopP allows you to set the top_p on the client.
+func WithTopP(topP float64) Modifier {
+	return func(c *Client) {
+		c.TopP = &topP
+	}
+}
+
+// WithSeed makes sampling deterministic on a best-effort basis. It is sent as
+// Mistral's random_seed.
+func WithSeed(seed int) Modifier {
+	return func(c *Client) {
+		s := int64(seed)
+		c.Seed = &s
+	}
+}
+
+// WithStopSequences sets custom sequences that stop generation when the model
+// produces them.
+func WithStopSequences(sequences ...string) Modifier {
+	return func(c *Client) {
+		c.StopSequences = sequences
+	}
+}
+
+// WithToolChoice controls whether and which tools the model calls. choice is
+// one of llms.ToolChoiceAuto, llms.ToolChoiceAny, llms.ToolChoiceTool or
+// llms.ToolChoiceNone; name is the function to force when choice is
+// llms.ToolChoiceTool and is otherwise ignored.
+func WithToolChoice(choice, name string) Modifier {
+	return func(c *Client) {
+		c.ToolChoice = &llms.ToolChoice{Type: choice, Name: name}
+	}
+}
+
+// WithParallelToolCalls allows or prevents the model from calling more than
+// one tool in a single response.
+func WithParallelToolCalls(enabled bool) Modifier {
+	return func(c *Client) {
+		c.ParallelToolCalls = &enabled
+	}
+}
+
+// WithJSONMode makes the model respond with a JSON object. The prompt should
+// also ask for JSON and describe its shape.
+func WithJSONMode() Modifier {
+	return func(c *Client) {
+		c.JSONMode = true
+	}
+}
+
+// WithSafePrompt prepends Mistral's safety prompt to the conversation, asking
+// the model to avoid harmful content.
+func WithSafePrompt() Modifier {
+	return func(c *Client) {
+		c.SafePrompt = true
+	}
+}
+
+// WithTools allows you to set the tools on the client.
+func WithTools(tools []llms.Tool) Modifier {
+	return func(c *Client) {
+		c.Tools = tools
+	}
+}
+
+// WithToolRegistry makes the tools in registry available to the model. The
+// registry is read on every request, so tools registered later are included.
+func WithToolRegistry(registry *llms.ToolRegistry) Modifier {
+	return func(c *Client) {
+		c.Registry = registry
+	}
+}
+
+// tools returns the client's tools followed by those in its registry, less
+// those disabled by the call options in ctx.
+func (c *Client) tools(ctx context.Context) []llms.Tool {
+	tools := c.Tools
+	if c.Registry != nil {
+		tools = append(append([]llms.Tool(nil), tools...), c.Registry.List()...)
+	}
+	return llms.CallOptionsFromContext(ctx).FilterTools(tools)
+}
+
+// New creates a new Mistral client. The API key is read from the
+// MISTRAL_API_KEY environment variable unless WithAPIKey is used.
+func New(mods ...Modifier) llms.LLM {
+	c := &Client{
+		Model:     "mistral-large-latest",
+		MaxTokens: 1024,
+		BaseURL:   DefaultBaseURL,
+		APIKey:    os.Getenv("MISTRAL_API_KEY"),
+	}
+
+	for _, mod := range mods {
+		mod(c)
+	}
+
+	if c.httpClient == nil {
+		c.httpClient = http.DefaultClient
+	}
+
+	return c
+}
+
+// ModelName returns the model requests are sent to.
+func (c *Client) ModelName() string {
+	return c.Model
+}
+
+// BuildRequest converts messages and the client's settings to the body of a
+// chat completion request.
+func (c *Client) BuildRequest(ctx context.Context, messages []llms.Message) (*ChatCompletionRequest, error) {
+	mistralMessages, err := convertMessages(messages)
+	if err != nil {
+		return nil, err
+	}
+
+	tools, err := convertTools(c.tools(ctx))
+	if err != nil {
+		return nil, err
+	}
+
+	req := &ChatCompletionRequest{
+		Model:       c.Model,
+		Messages:    mistralMessages,
+		Tools:       tools,
+		Temperature: c.Temperature,
+		TopP:        c.TopP,
+		RandomSeed:  c.Seed,
+		SafePrompt:  c.SafePrompt,
+	}
+
+	if c.MaxTokens > 0 {
+		req.MaxTokens = &c.MaxTokens
+	}
+
+	if c.JSONMode {
+		req.ResponseFormat = &ResponseFormat{Type: "json_object"}
+	}
+
+	callOpts := llms.CallOptionsFromContext(ctx)
+
+	req.Stop = c.StopSequences
+	if callOpts.StopSequences != nil {
+		req.Stop = callOpts.StopSequences
+	}
+
+	if len(tools) > 0 {
+		toolChoice := c.ToolChoice
+		if callOpts.ToolChoice != nil {
+			toolChoice = callOpts.ToolChoice
+		}
+		if toolChoice != nil {
+			req.ToolChoice, err = convertToolChoice(*toolChoice)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		req.ParallelToolCalls = c.ParallelToolCalls
+		if callOpts.ParallelToolCalls != nil {
+			req.ParallelToolCalls = callOpts.ParallelToolCalls
+		}
+	}
+
+	return req, nil
+}
+
+func convertToolChoice(choice llms.ToolChoice) (any, error) {
+	switch choice.Type {
+	case llms.ToolChoiceAuto, llms.ToolChoiceAny, llms.ToolChoiceNone:
+		return choice.Type, nil
+	case llms.ToolChoiceTool:
+		if choice.Name == "" {
+			return nil, fmt.Errorf("mistral: tool choice %q requires a tool name", choice.Type)
+		}
+		return ToolChoice{Type: "function", Function: ToolChoiceFunction{Name: choice.Name}}, nil
+	default:
+		return nil, fmt.Errorf("mistral: unsupported tool choice: %q", choice.Type)
+	}
+}
+
+func (c *Client) Generate(ctx context.Context, messages []llms.Message) (*llms.Response, error) {
+	ctx, requestID := llms.EnsureRequestID(ctx)
+	req, err := c.BuildRequest(ctx, messages)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.do(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("mistral: failed to generate message: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var completion ChatCompletionResponse
+	if err := json.NewDecoder(resp.Body).Decode(&completion); err != nil {
+		return nil, fmt.Errorf("mistral: failed to decode response: %w", err)
+	}
+
+	if len(completion.Choices) == 0 {
+		return nil, fmt.Errorf("mistral: no choices returned")
+	}
+
+	choice := completion.Choices[0]
+	msgOut := llms.Message{
+		Role:  llms.RoleAssistant,
+		Parts: []llms.Part{},
+	}
+
+	if choice.Message.Content != "" {
+		msgOut.Parts = append(msgOut.Parts, llms.TextPart{Text: string(choice.Message.Content)})
+	}
+
+	for _, toolCall := range choice.Message.ToolCalls {
+		msgOut.Parts = append(msgOut.Parts, llms.ToolCallPart{
+			ID:    toolCall.ID,
+			Name:  toolCall.Function.Name,
+			Input: []byte(toolCall.Function.Arguments),
+		})
+	}
+
+	return &llms.Response{
+		ID:         completion.ID,
+		Message:    msgOut,
+		Usage:      convertUsage(completion.Usage),
+		StopReason: convertFinishReason(choice.FinishReason),
+		RequestID:  requestID,
+		Provider:   ProviderMistral,
+		Raw:        &completion,
+	}, nil
+}
+
+func (c *Client) GenerateStream(ctx context.Context, messages []llms.Message, fn llms.StreamFunc) (*llms.Response, error) {
+	ctx, requestID := llms.EnsureRequestID(ctx)
+	req, err := c.BuildRequest(ctx, messages)
+	if err != nil {
+		return nil, err
+	}
+	req.Stream = true
+
+	// Cancelling the context closes the stream if fn stops it early.
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	resp, err := c.do(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("mistral: failed to generate message: %w", err)
+	}
+	defer resp.Body.Close()
+
+	out := &llms.Response{
+		Message: llms.Message{
+			Role:  llms.RoleAssistant,
+			Parts: []llms.Part{},
+		},
+		RequestID: requestID,
+		Provider:  ProviderMistral,
+	}
+
+	// The text is accumulated into a single part, followed by the tool calls
+	// in the order the model made them.
+	var text strings.Builder
+	var toolCalls []llms.ToolCallPart
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data: ")
+		if !ok {
+			continue
+		}
+		if data == "[DONE]" {
+			break
+		}
+
+		var chunk ChatCompletionChunk
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return out, fmt.Errorf("mistral: failed to decode stream chunk: %w", err)
+		}
+
+		if chunk.ID != "" && out.ID == "" {
+			out.ID = chunk.ID
+		}
+		if chunk.Usage != nil {
+			out.Usage = convertUsage(*chunk.Usage)
+		}
+		if len(chunk.Choices) == 0 {
+			continue
+		}
+
+		choice := chunk.Choices[0]
+		if choice.FinishReason != nil {
+			out.StopReason = convertFinishReason(*choice.FinishReason)
+		}
+
+		delta := choice.Delta
+		text.WriteString(string(delta.Content))
+		for _, toolCall := range delta.ToolCalls {
+			for len(toolCalls) <= toolCall.Index {
+				toolCalls = append(toolCalls, llms.ToolCallPart{})
+			}
+			call := &toolCalls[toolCall.Index]
+			if toolCall.ID != "" {
+				call.ID = toolCall.ID
+			}
+			call.Name += toolCall.Function.Name
+			call.Input = append(call.Input, toolCall.Function.Arguments...)
+		}
+
+		out.Message.Parts = streamParts(text.String(), toolCalls)
+		// Raw holds the current chunk, so fn can read the latest delta.
+		out.Raw = chunk
+
+		if !fn(out, nil) {
+			return out, nil
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return out, fmt.Errorf("mistral: streaming error: %w", err)
+	}
+
+	return out, nil
+}
+
+// streamParts returns the parts of a message accumulated from a stream. The
+// tool calls are copied, as they keep changing while the stream is read.
+func streamParts(text string, toolCalls []llms.ToolCallPart) []llms.Part {
+	parts := make([]llms.Part, 0, len(toolCalls)+1)
+	if text != "" {
+		parts = append(parts, llms.TextPart{Text: text})
+	}
+	for _, call := range toolCalls {
+		call.Input = append([]byte(nil), call.Input...)
+		parts = append(parts, call)
+	}
+	return parts
+}
+
+// do sends a chat completion request. Responses with a non-2xx status are
+// returned as *llms.APIError.
+func (c *Client) do(ctx context.Context, body *ChatCompletionRequest) (*http.Response, error) {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(c.BaseURL, "/")+"/chat/completions", bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	if body.Stream {
+		req.Header.Set("Accept", "text/event-stream")
+	}
+	if c.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.APIKey)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+		return nil, &llms.APIError{
+			Provider:   ProviderMistral,
+			StatusCode: resp.StatusCode,
+			RetryAfter: llms.ParseRetryAfter(resp.Header),
+			Err:        readError(resp.Body),
+		}
+	}
+
+	return resp, nil
+}
+
+// readError returns the message of an error response body.
+func readError(body io.Reader) error {
+	data, err := io.ReadAll(io.LimitReader(body, 64*1024))
+	if err != nil {
+		return err
+	}
+
+	var errResp errorResponse
+	if err := json.Unmarshal(data, &errResp); err == nil && errResp.Message != "" {
+		return errors.New(errResp.Message)
+	}
+	return errors.New(strings.TrimSpace(string(data)))
+}
+
+func convertUsage(usage Usage) *llms.Usage {
+	return &llms.Usage{
+		InputTokens:  usage.PromptTokens,
+		OutputTokens: usage.CompletionTokens,
+	}
+}
+
+// convertFinishReason maps a Mistral finish reason onto llms.StopReason.
+func convertFinishReason(reason string) llms.StopReason {
+	switch reason {
+	case "stop":
+		return llms.StopReasonEndTurn
+	case "length", "model_length":
+		return llms.StopReasonMaxTokens
+	case "tool_calls":
+		return llms.StopReasonToolUse
+	default:
+		return llms.StopReason(reason)
+	}
+}
+
+func convertMessages(messages []llms.Message) ([]Message, error) {
+	out := make([]Message, 0, len(messages))
+
+	for i, message := range messages {
+		switch message.Role {
+		case llms.RoleSystem:
+			content := ""
+			for _, part := range message.Parts {
+				switch p := part.(type) {
+				case llms.TextPart:
+					content += p.Text
+				case llms.CachePointPart:
+					// Mistral does not support prompt caching.
+				default:
+					return nil, fmt.Errorf("[message %d] mistral: unsupported system message part type: %T", i, p)
+				}
+			}
+
+			out = append(out, Message{Role: "system", Content: content})
+
+		case llms.RoleUser:
+			// Tool results are sent as separate tool messages, which must
+			// directly follow the assistant message that made the calls, so
+			// they come before the rest of the user message.
+			content := ""
+			hasToolResults := false
+			hasImages := false
+			var chunks []ContentChunk
+			for j, part := range message.Parts {
+				switch p := part.(type) {
+				case llms.TextPart:
+					content += p.Text
+					chunks = append(chunks, ContentChunk{Type: "text", Text: p.Text})
+				case llms.ImagePart:
+					url, err := imageURL(p)
+					if err != nil {
+						return nil, fmt.Errorf("[message %d, part %d] mistral: %w", i, j, err)
+					}
+					hasImages = true
+					chunks = append(chunks, ContentChunk{Type: "image_url", ImageURL: url})
+				case llms.ToolResultPart:
+					hasToolResults = true
+					out = append(out, Message{
+						Role:       "tool",
+						Content:    p.Result,
+						ToolCallID: p.ToolCallID,
+						Name:       p.Name,
+					})
+				case llms.CachePointPart:
+					// Mistral does not support prompt caching.
+				default:
+					return nil, fmt.Errorf("[message %d] mistral: unsupported user message part type: %T", i, p)
+				}
+			}
+
+			switch {
+			case hasImages:
+				out = append(out, Message{Role: "user", Content: chunks})
+			case content != "" || !hasToolResults:
+				out = append(out, Message{Role: "user", Content: content})
+			}
+
+		case llms.RoleAssistant:
+			content := ""
+			var toolCalls []ToolCall
+			var toolResults []Message
+
+			for _, part := range message.Parts {
+				switch p := part.(type) {
+				case llms.TextPart:
+					content += p.Text
+				case llms.ToolCallPart:
+					toolCalls = append(toolCalls, ToolCall{
+						ID:   p.ID,
+						Type: "function",
+						Function: FunctionCall{
+							Name:      p.Name,
+							Arguments: toolArguments(p.Input),
+						},
+					})
+				case llms.ToolResultPart:
+					// Tool results must follow the assistant message that
+					// made the calls.
+					toolResults = append(toolResults, Message{
+						Role:       "tool",
+						Content:    p.Result,
+						ToolCallID: p.ToolCallID,
+						Name:       p.Name,
+					})
+				case llms.CachePointPart:
+					// Mistral does not support prompt caching.
+				case llms.ThinkingPart:
+					// Reasoning from other providers cannot be replayed.
+				default:
+					return nil, fmt.Errorf("[message %d] mistral: unsupported assistant message part type: %T", i, p)
+				}
+			}
+
+			if content != "" || len(toolCalls) > 0 {
+				out = append(out, Message{Role: "assistant", Content: content, ToolCalls: toolCalls})
+			}
+
+			out = append(out, toolResults...)
+
+		default:
+			return nil, fmt.Errorf("[message %d] mistral: unsupported message role: %s", i, message.Role)
+		}
+	}
+
+	return out, nil
+}
+
+// imageURL returns the URL of an image. Image data is sent inline as a base64
+// data URL.
+func imageURL(p llms.ImagePart) (string, error) {
+	switch {
+	case len(p.Data) > 0:
+		mediaType := p.MediaType
+		if mediaType == "" {
+			mediaType = http.DetectContentType(p.Data)
+		}
+		return "data:" + mediaType + ";base64," + base64.StdEncoding.EncodeToString(p.Data), nil
+	case p.URL != "":
+		return p.URL, nil
+	default:
+		return "", errors.New("image has no data or URL")
+	}
+}
+
+// toolArguments returns the JSON arguments of a tool call. Mistral requires a
+// JSON object even for calls without arguments.
+func toolArguments(input []byte) string {
+	if len(input) == 0 {
+		return "{}"
+	}
+	return string(input)
+}
+
+// toolSchemas memoizes convertSchema, as tools are converted for every
+// request.
+var toolSchemas llms.SchemaCache[map[string]any]
+
+// convertSchema converts a tool's input schema into function parameters,
+// keeping every keyword, such as "$defs" for schemas using references.
+func convertSchema(schema *jsonschema.Schema) (map[string]any, error) {
+	parameters, err := llms.SchemaMap(schema)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := parameters["type"]; !ok {
+		parameters["type"] = "object"
+	}
+	return parameters, nil
+}
+
+func convertTools(tools []llms.Tool) ([]Tool, error) {
+	if len(tools) == 0 {
+		return nil, nil
+	}
+
+	out := make([]Tool, 0, len(tools))
+
+	for _, tool := range tools {
+		schema := tool.Schema()
+		if schema == nil {
+			return nil, fmt.Errorf("mistral: tool %s has no schema", tool.Name())
+		}
+		parameters, err := toolSchemas.Get(schema, convertSchema)
+		if err != nil {
+			return nil, fmt.Errorf("mistral: tool %s: %w", tool.Name(), err)
+		}
+
+		out = append(out, Tool{
+			Type: "function",
+			Function: Function{
+				Name:        tool.Name(),
+				Description: llms.ToolDescription(tool),
+				Parameters:  parameters,
+			},
+		})
+	}
+
+	return out, nil
+}
@@ -0,0 +1,86 @@
+//go:build integration
+// +build integration
+
+package mistral
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/llmite-ai/llms"
+	"github.com/llmite-ai/llms/testutil"
+)
+
+func TestMistralClientIntegration(t *testing.T) {
+	testutil.RequireAPIKey(t, "MISTRAL_API_KEY")
+
+	t.Run("simple generation", func(t *testing.T) {
+		client := New(WithModel("mistral-small-latest"), WithHTTPClient(testutil.NewRecordingClient(t, "simple_generation")))
+		messages := []llms.Message{llms.NewTextMessage(llms.RoleUser, "What is the capital of France?")}
+
+		response, err := client.Generate(context.Background(), messages)
+		require.NoError(t, err)
+		assert.Equal(t, ProviderMistral, response.Provider)
+		require.NotEmpty(t, response.Message.Parts)
+		assert.Contains(t, response.Message.Parts[0].(llms.TextPart).Text, "Paris")
+	})
+
+	t.Run("streaming", func(t *testing.T) {
+		client := New(WithModel("mistral-small-latest"), WithHTTPClient(testutil.NewRecordingClient(t, "streaming")))
+		messages := []llms.Message{llms.NewTextMessage(llms.RoleUser, "Count from 1 to 5.")}
+
+		chunks := 0
+		response, err := client.GenerateStream(context.Background(), messages, func(r *llms.Response, err error) bool {
+			chunks++
+			return true
+		})
+		require.NoError(t, err)
+		assert.Greater(t, chunks, 1)
+		assert.NotNil(t, response.Usage)
+	})
+
+	t.Run("tool calling", func(t *testing.T) {
+		client := New(
+			WithModel("mistral-small-latest"),
+			WithTools([]llms.Tool{testutil.WeatherTool{}}),
+			WithToolChoice(llms.ToolChoiceAny, ""),
+			WithHTTPClient(testutil.NewRecordingClient(t, "tool_calling")),
+		)
+		messages := []llms.Message{llms.NewTextMessage(llms.RoleUser, "What's the weather in Paris?")}
+
+		response, err := client.Generate(context.Background(), messages)
+		require.NoError(t, err)
+		assert.Equal(t, llms.StopReasonToolUse, response.StopReason)
+
+		var call llms.ToolCallPart
+		for _, part := range response.Message.Parts {
+			if p, ok := part.(llms.ToolCallPart); ok {
+				call = p
+			}
+		}
+		require.NotEmpty(t, call.ID)
+
+		var params testutil.WeatherToolParams
+		require.NoError(t, json.Unmarshal(call.Input, &params))
+	})
+
+	t.Run("json mode", func(t *testing.T) {
+		client := New(
+			WithModel("mistral-small-latest"),
+			WithJSONMode(),
+			WithHTTPClient(testutil.NewRecordingClient(t, "json_mode")),
+		)
+		messages := []llms.Message{llms.NewTextMessage(llms.RoleUser, `Return a JSON object with a "capital" field for France.`)}
+
+		response, err := client.Generate(context.Background(), messages)
+		require.NoError(t, err)
+
+		var out map[string]any
+		require.NoError(t, json.Unmarshal([]byte(response.Message.Parts[0].(llms.TextPart).Text), &out))
+		assert.Contains(t, out, "capital")
+	})
+}
@@ -0,0 +1,291 @@
+package mistral
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/llmite-ai/llms"
+	"github.com/llmite-ai/llms/testutil"
+)
+
+// newTestClient returns a client that sends requests to a test server running
+// handler.
+func newTestClient(t *testing.T, handler http.HandlerFunc, mods ...Modifier) *Client {
+	t.Helper()
+
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	mods = append([]Modifier{WithBaseURL(server.URL), WithAPIKey("test")}, mods...)
+	return New(mods...).(*Client)
+}
+
+// writeSSE writes chunks as a server-sent event stream, ending with [DONE].
+func writeSSE(w http.ResponseWriter, chunks ...string) {
+	w.Header().Set("Content-Type", "text/event-stream")
+	for _, chunk := range chunks {
+		fmt.Fprintf(w, "data: %s\n\n", chunk)
+	}
+	fmt.Fprint(w, "data: [DONE]\n\n")
+}
+
+func TestClientDefaults(t *testing.T) {
+	t.Setenv("MISTRAL_API_KEY", "env-key")
+
+	client := New().(*Client)
+	assert.Equal(t, "mistral-large-latest", client.Model)
+	assert.Equal(t, int64(1024), client.MaxTokens)
+	assert.Equal(t, DefaultBaseURL, client.BaseURL)
+	assert.Equal(t, "env-key", client.APIKey)
+	assert.Equal(t, "mistral-large-latest", client.ModelName())
+}
+
+func TestConvertMessages(t *testing.T) {
+	messages := []llms.Message{
+		llms.NewTextMessage(llms.RoleSystem, "Be brief."),
+		{
+			Role: llms.RoleUser,
+			Parts: []llms.Part{
+				llms.TextPart{Text: "What's in this image?"},
+				llms.ImagePart{MediaType: "image/png", Data: []byte("png")},
+			},
+		},
+		{
+			Role: llms.RoleAssistant,
+			Parts: []llms.Part{
+				llms.TextPart{Text: "Let me check."},
+				llms.ToolCallPart{ID: "abc123def", Name: "get_weather", Input: []byte(`{"location":"Paris"}`)},
+				llms.ToolCallPart{ID: "ghi456jkl", Name: "get_time"},
+			},
+		},
+		{
+			Role: llms.RoleUser,
+			Parts: []llms.Part{
+				llms.ToolResultPart{ToolCallID: "abc123def", Name: "get_weather", Result: "sunny"},
+				llms.ToolResultPart{ToolCallID: "ghi456jkl", Name: "get_time", Result: "noon"},
+			},
+		},
+	}
+
+	result, err := convertMessages(messages)
+	require.NoError(t, err)
+
+	data, err := json.Marshal(result)
+	require.NoError(t, err)
+	assert.JSONEq(t, `[
+		{"role": "system", "content": "Be brief."},
+		{"role": "user", "content": [
+			{"type": "text", "text": "What's in this image?"},
+			{"type": "image_url", "image_url": "data:image/png;base64,cG5n"}
+		]},
+		{"role": "assistant", "content": "Let me check.", "tool_calls": [
+			{"id": "abc123def", "type": "function", "function": {"name": "get_weather", "arguments": "{\"location\":\"Paris\"}"}},
+			{"id": "ghi456jkl", "type": "function", "function": {"name": "get_time", "arguments": "{}"}}
+		]},
+		{"role": "tool", "content": "sunny", "tool_call_id": "abc123def", "name": "get_weather"},
+		{"role": "tool", "content": "noon", "tool_call_id": "ghi456jkl", "name": "get_time"}
+	]`, string(data))
+
+	_, err = convertMessages([]llms.Message{{Role: llms.RoleUser, Parts: []llms.Part{llms.AudioPart{}}}})
+	assert.EqualError(t, err, "[message 0] mistral: unsupported user message part type: llms.AudioPart")
+}
+
+func TestBuildRequest(t *testing.T) {
+	client := New(
+		WithModel("mistral-small-latest"),
+		WithTools([]llms.Tool{testutil.WeatherTool{}}),
+		WithToolChoice(llms.ToolChoiceAny, ""),
+		WithParallelToolCalls(false),
+		WithJSONMode(),
+		WithSeed(42),
+		WithTemperature(0.3),
+		WithStopSequences("END"),
+		WithSafePrompt(),
+	).(*Client)
+
+	req, err := client.BuildRequest(context.Background(), []llms.Message{llms.NewTextMessage(llms.RoleUser, "Hi")})
+	require.NoError(t, err)
+
+	data, err := json.Marshal(req)
+	require.NoError(t, err)
+
+	var body map[string]any
+	require.NoError(t, json.Unmarshal(data, &body))
+	assert.Equal(t, "mistral-small-latest", body["model"])
+	assert.Equal(t, "any", body["tool_choice"])
+	assert.Equal(t, false, body["parallel_tool_calls"])
+	assert.Equal(t, map[string]any{"type": "json_object"}, body["response_format"])
+	assert.Equal(t, float64(42), body["random_seed"])
+	assert.Equal(t, 0.3, body["temperature"])
+	assert.Equal(t, float64(1024), body["max_tokens"])
+	assert.Equal(t, []any{"END"}, body["stop"])
+	assert.Equal(t, true, body["safe_prompt"])
+	assert.Len(t, body["tools"], 1)
+
+	// Call options override the client's settings.
+	ctx := llms.WithCallOptions(context.Background(),
+		llms.ToolChoiceOption(llms.ToolChoice{Type: llms.ToolChoiceTool, Name: "get_weather"}),
+		llms.StopSequences("STOP"),
+	)
+	req, err = client.BuildRequest(ctx, []llms.Message{llms.NewTextMessage(llms.RoleUser, "Hi")})
+	require.NoError(t, err)
+	assert.Equal(t, ToolChoice{Type: "function", Function: ToolChoiceFunction{Name: "get_weather"}}, req.ToolChoice)
+	assert.Equal(t, []string{"STOP"}, req.Stop)
+
+	// Tool settings are only sent with tools.
+	req, err = New(WithToolChoice(llms.ToolChoiceAny, "")).(*Client).BuildRequest(context.Background(), nil)
+	require.NoError(t, err)
+	assert.Nil(t, req.ToolChoice)
+}
+
+func TestBuildRequest_SchemaReferences(t *testing.T) {
+	client := New(WithTools([]llms.Tool{testutil.RouteTool{}})).(*Client)
+
+	req, err := client.BuildRequest(context.Background(), []llms.Message{llms.NewTextMessage(llms.RoleUser, "Route?")})
+	require.NoError(t, err)
+	data, err := json.Marshal(req)
+	require.NoError(t, err)
+	var body map[string]any
+	require.NoError(t, json.Unmarshal(data, &body))
+
+	// The definitions the properties refer to are sent with them.
+	params := body["tools"].([]any)[0].(map[string]any)["function"].(map[string]any)["parameters"].(map[string]any)
+	assert.Equal(t, false, params["additionalProperties"])
+	assert.Contains(t, params["$defs"], "RoutePlace")
+	assert.NotContains(t, params, "$schema")
+	from := params["properties"].(map[string]any)["from"].(map[string]any)
+	assert.Equal(t, "#/$defs/RoutePlace", from["$ref"])
+}
+
+func TestGenerate(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/chat/completions", r.URL.Path)
+		assert.Equal(t, "Bearer test", r.Header.Get("Authorization"))
+
+		var body ChatCompletionRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		assert.False(t, body.Stream)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{
+			"id": "cmpl_1",
+			"model": "mistral-large-latest",
+			"choices": [{
+				"index": 0,
+				"finish_reason": "tool_calls",
+				"message": {
+					"role": "assistant",
+					"content": "Checking.",
+					"tool_calls": [{"id": "abc123def", "function": {"name": "get_weather", "arguments": "{\"location\":\"Paris\"}"}}]
+				}
+			}],
+			"usage": {"prompt_tokens": 12, "completion_tokens": 8, "total_tokens": 20}
+		}`))
+	})
+
+	resp, err := client.Generate(context.Background(), []llms.Message{llms.NewTextMessage(llms.RoleUser, "Weather in Paris?")})
+	require.NoError(t, err)
+	assert.Equal(t, "cmpl_1", resp.ID)
+	assert.Equal(t, ProviderMistral, resp.Provider)
+	assert.Equal(t, llms.StopReasonToolUse, resp.StopReason)
+	assert.Equal(t, &llms.Usage{InputTokens: 12, OutputTokens: 8}, resp.Usage)
+	assert.Equal(t, []llms.Part{
+		llms.TextPart{Text: "Checking."},
+		llms.ToolCallPart{ID: "abc123def", Name: "get_weather", Input: []byte(`{"location":"Paris"}`)},
+	}, resp.Message.Parts)
+}
+
+func TestGenerate_ContentChunks(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{
+			"id": "cmpl_1",
+			"choices": [{"index": 0, "finish_reason": "stop", "message": {"role": "assistant", "content": [
+				{"type": "text", "text": "Hello"},
+				{"type": "text", "text": " there"}
+			]}}]
+		}`))
+	})
+
+	resp, err := client.Generate(context.Background(), []llms.Message{llms.NewTextMessage(llms.RoleUser, "Hi")})
+	require.NoError(t, err)
+	assert.Equal(t, []llms.Part{llms.TextPart{Text: "Hello there"}}, resp.Message.Parts)
+	assert.Equal(t, llms.StopReasonEndTurn, resp.StopReason)
+}
+
+func TestGenerate_APIError(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Retry-After", "3")
+		w.WriteHeader(http.StatusTooManyRequests)
+		w.Write([]byte(`{"object": "error", "message": "Requests rate limit exceeded", "type": "rate_limited"}`))
+	})
+
+	_, err := client.Generate(context.Background(), []llms.Message{llms.NewTextMessage(llms.RoleUser, "Hi")})
+	var apiErr *llms.APIError
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, ProviderMistral, apiErr.Provider)
+	assert.Equal(t, http.StatusTooManyRequests, apiErr.StatusCode)
+	assert.Equal(t, "3s", apiErr.RetryAfter.String())
+	assert.True(t, llms.IsRetryable(err))
+	assert.EqualError(t, err, "mistral: failed to generate message: mistral: api error (status 429): Requests rate limit exceeded")
+}
+
+func TestGenerateStream(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		var body ChatCompletionRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		assert.True(t, body.Stream)
+
+		writeSSE(w,
+			`{"id": "cmpl_1", "choices": [{"index": 0, "delta": {"role": "assistant", "content": ""}, "finish_reason": null}]}`,
+			`{"id": "cmpl_1", "choices": [{"index": 0, "delta": {"content": "Hello"}, "finish_reason": null}]}`,
+			`{"id": "cmpl_1", "choices": [{"index": 0, "delta": {"content": " there"}, "finish_reason": null}]}`,
+			`{"id": "cmpl_1", "choices": [{"index": 0, "delta": {"tool_calls": [{"id": "abc123def", "function": {"name": "get_weather", "arguments": "{\"location\":\"Paris\"}"}, "index": 0}]}, "finish_reason": "tool_calls"}], "usage": {"prompt_tokens": 10, "completion_tokens": 5, "total_tokens": 15}}`,
+		)
+	})
+
+	var texts []string
+	resp, err := client.GenerateStream(context.Background(), []llms.Message{llms.NewTextMessage(llms.RoleUser, "Hi")}, func(r *llms.Response, err error) bool {
+		require.NoError(t, err)
+		if len(r.Message.Parts) > 0 {
+			texts = append(texts, r.Message.Parts[0].(llms.TextPart).Text)
+		}
+		return true
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"Hello", "Hello there", "Hello there"}, texts)
+	assert.Equal(t, "cmpl_1", resp.ID)
+	assert.Equal(t, llms.StopReasonToolUse, resp.StopReason)
+	assert.Equal(t, &llms.Usage{InputTokens: 10, OutputTokens: 5}, resp.Usage)
+	assert.Equal(t, []llms.Part{
+		llms.TextPart{Text: "Hello there"},
+		llms.ToolCallPart{ID: "abc123def", Name: "get_weather", Input: []byte(`{"location":"Paris"}`)},
+	}, resp.Message.Parts)
+}
+
+func TestGenerateStream_Stop(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		writeSSE(w,
+			`{"id": "cmpl_1", "choices": [{"index": 0, "delta": {"content": "one"}}]}`,
+			`{"id": "cmpl_1", "choices": [{"index": 0, "delta": {"content": " two"}}]}`,
+		)
+	})
+
+	calls := 0
+	resp, err := client.GenerateStream(context.Background(), []llms.Message{llms.NewTextMessage(llms.RoleUser, "Count")}, func(r *llms.Response, err error) bool {
+		calls++
+		return false
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 1, calls)
+	assert.Equal(t, []llms.Part{llms.TextPart{Text: "one"}}, resp.Message.Parts)
+}
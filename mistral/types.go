@@ -0,0 +1,166 @@
+package mistral
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// ChatCompletionRequest is the body of a request to the chat completions
+// endpoint.
+type ChatCompletionRequest struct {
+	Model             string          `json:"model"`
+	Messages          []Message       `json:"messages"`
+	Tools             []Tool          `json:"tools,omitempty"`
+	ToolChoice        any             `json:"tool_choice,omitempty"` // "auto", "any", "none", or a ToolChoice
+	ParallelToolCalls *bool           `json:"parallel_tool_calls,omitempty"`
+	ResponseFormat    *ResponseFormat `json:"response_format,omitempty"`
+	MaxTokens         *int64          `json:"max_tokens,omitempty"`
+	Temperature       *float64        `json:"temperature,omitempty"` // Range: 0-1.5
+	TopP              *float64        `json:"top_p,omitempty"`
+	RandomSeed        *int64          `json:"random_seed,omitempty"`
+	Stop              []string        `json:"stop,omitempty"`
+	SafePrompt        bool            `json:"safe_prompt,omitempty"`
+	Stream            bool            `json:"stream,omitempty"`
+}
+
+// Message is a message in a chat completion request.
+type Message struct {
+	Role       string     `json:"role"`                   // "system", "user", "assistant", or "tool"
+	Content    any        `json:"content"`                // string or []ContentChunk
+	ToolCalls  []ToolCall `json:"tool_calls,omitempty"`   // For assistant messages with tool calls
+	ToolCallID string     `json:"tool_call_id,omitempty"` // For tool messages
+	Name       string     `json:"name,omitempty"`         // For tool messages, the name of the tool
+}
+
+// ContentChunk is one part of a multi-part user message.
+type ContentChunk struct {
+	Type     string `json:"type"` // "text" or "image_url"
+	Text     string `json:"text,omitempty"`
+	ImageURL string `json:"image_url,omitempty"` // URL or base64 data URL
+}
+
+// Tool is a function the model may call.
+type Tool struct {
+	Type     string   `json:"type"` // Only "function" is supported
+	Function Function `json:"function"`
+}
+
+// Function describes a function the model may call.
+type Function struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Parameters  any    `json:"parameters"` // JSON Schema object
+}
+
+// ToolChoice forces the model to call a specific function.
+type ToolChoice struct {
+	Type     string             `json:"type"` // "function"
+	Function ToolChoiceFunction `json:"function"`
+}
+
+// ToolChoiceFunction names the function of a ToolChoice.
+type ToolChoiceFunction struct {
+	Name string `json:"name"`
+}
+
+// ToolCall is a function call made by the model.
+type ToolCall struct {
+	ID       string       `json:"id,omitempty"`
+	Type     string       `json:"type,omitempty"`
+	Index    int          `json:"index,omitempty"` // Position of the call in streamed deltas
+	Function FunctionCall `json:"function"`
+}
+
+// FunctionCall is the function and JSON arguments of a ToolCall.
+type FunctionCall struct {
+	Name      string `json:"name"`
+	Arguments string `json:"arguments"`
+}
+
+// ResponseFormat constrains the format of the model's output.
+type ResponseFormat struct {
+	Type string `json:"type"` // "text" or "json_object"
+}
+
+// ChatCompletionResponse is the response of the chat completions endpoint.
+type ChatCompletionResponse struct {
+	ID      string   `json:"id"`
+	Model   string   `json:"model"`
+	Created int64    `json:"created"`
+	Choices []Choice `json:"choices"`
+	Usage   Usage    `json:"usage"`
+}
+
+// Choice is a message generated by the model.
+type Choice struct {
+	Index        int              `json:"index"`
+	Message      AssistantMessage `json:"message"`
+	FinishReason string           `json:"finish_reason"` // "stop", "length", "model_length", "tool_calls", or "error"
+}
+
+// AssistantMessage is a message generated by the model.
+type AssistantMessage struct {
+	Role      string     `json:"role"`
+	Content   Content    `json:"content"`
+	ToolCalls []ToolCall `json:"tool_calls,omitempty"`
+}
+
+// ChatCompletionChunk is an event of a streamed chat completion.
+type ChatCompletionChunk struct {
+	ID      string        `json:"id"`
+	Model   string        `json:"model"`
+	Choices []ChunkChoice `json:"choices"`
+	Usage   *Usage        `json:"usage,omitempty"` // Only set on the last chunk
+}
+
+// ChunkChoice is the change to a choice in a streamed chat completion.
+type ChunkChoice struct {
+	Index        int              `json:"index"`
+	Delta        AssistantMessage `json:"delta"`
+	FinishReason *string          `json:"finish_reason"`
+}
+
+// Usage reports the tokens consumed by a request.
+type Usage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+}
+
+// Content is the text of a message generated by the model. Mistral returns it
+// as a string, or as an array of chunks for some models, whose text is
+// concatenated.
+type Content string
+
+func (c *Content) UnmarshalJSON(data []byte) error {
+	if len(data) > 0 && data[0] == '[' {
+		var chunks []ContentChunk
+		if err := json.Unmarshal(data, &chunks); err != nil {
+			return err
+		}
+
+		var b strings.Builder
+		for _, chunk := range chunks {
+			if chunk.Type == "text" {
+				b.WriteString(chunk.Text)
+			}
+		}
+		*c = Content(b.String())
+		return nil
+	}
+
+	var s *string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	if s != nil {
+		*c = Content(*s)
+	}
+	return nil
+}
+
+// errorResponse is the body of a failed request.
+type errorResponse struct {
+	Message string `json:"message"`
+	Type    string `json:"type"`
+}
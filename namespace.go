@@ -0,0 +1,85 @@
+package llms
+
+import (
+	"context"
+	"strings"
+
+	"github.com/invopop/jsonschema"
+)
+
+// ToolNamespaceSeparator separates a namespace from a tool name, as in
+// "github__create_issue". It only uses characters every provider accepts in
+// tool names.
+const ToolNamespaceSeparator = "__"
+
+// NamespaceTools prefixes the name of each tool with namespace and
+// ToolNamespaceSeparator, so that tool sets from different sources, such as
+// local tools and those of an MCP server, can be combined without their names
+// colliding:
+//
+//	tools := append(llms.NamespaceTools("github", githubTools...), localTools...)
+//
+// See RenameTool.
+func NamespaceTools(namespace string, tools ...Tool) []Tool {
+	out := make([]Tool, 0, len(tools))
+	for _, tool := range tools {
+		out = append(out, RenameTool(tool, namespace+ToolNamespaceSeparator+tool.Name()))
+	}
+	return out
+}
+
+// RenameTool returns tool under a new name. The model sees and calls the new
+// name, and calls to it execute the original tool, so the renaming is undone
+// when RunTools executes tool calls. The returned tool is an ExecutableTool if
+// tool is.
+//
+// Only tools executed locally should be renamed. Providers recognise the tools
+// they execute themselves, such as Anthropic's BashTool, by type, which the
+// renamed tool hides.
+func RenameTool(tool Tool, name string) Tool {
+	renamed := renamedTool{tool: OriginalTool(tool), name: name}
+	if _, ok := renamed.tool.(ExecutableTool); ok {
+		return renamedExecutableTool{renamed}
+	}
+	return renamed
+}
+
+// OriginalTool returns the tool that was renamed by RenameTool or
+// NamespaceTools, or tool itself if it was not renamed.
+func OriginalTool(tool Tool) Tool {
+	switch t := tool.(type) {
+	case renamedTool:
+		return t.tool
+	case renamedExecutableTool:
+		return t.tool
+	default:
+		return tool
+	}
+}
+
+// SplitToolName splits a name produced by NamespaceTools into its namespace
+// and the original tool name. The namespace is empty if name has none.
+func SplitToolName(name string) (namespace, tool string) {
+	namespace, tool, ok := strings.Cut(name, ToolNamespaceSeparator)
+	if !ok {
+		return "", name
+	}
+	return namespace, tool
+}
+
+type renamedTool struct {
+	tool Tool
+	name string
+}
+
+func (t renamedTool) Name() string               { return t.name }
+func (t renamedTool) Description() string        { return t.tool.Description() }
+func (t renamedTool) Schema() *jsonschema.Schema { return t.tool.Schema() }
+
+type renamedExecutableTool struct {
+	renamedTool
+}
+
+func (t renamedExecutableTool) Execute(ctx context.Context, args []byte) *ToolResult {
+	return t.tool.(ExecutableTool).Execute(ctx, args)
+}
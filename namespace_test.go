@@ -0,0 +1,64 @@
+package llms
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNamespaceTools(t *testing.T) {
+	tools := NamespaceTools("github", echoTool{}, hostedTool{})
+	require.Len(t, tools, 2)
+
+	assert.Equal(t, "github__echo", tools[0].Name())
+	assert.Equal(t, echoTool{}.Description(), tools[0].Description())
+	assert.Equal(t, echoTool{}.Schema(), tools[0].Schema())
+	assert.Equal(t, echoTool{}, OriginalTool(tools[0]))
+
+	// Tools keep being executable, or not, after renaming.
+	exec, ok := tools[0].(ExecutableTool)
+	require.True(t, ok)
+	assert.Equal(t, "hi", exec.Execute(context.Background(), []byte(`{"text":"hi"}`)).Content)
+
+	assert.Equal(t, "github__hosted", tools[1].Name())
+	_, ok = tools[1].(ExecutableTool)
+	assert.False(t, ok)
+
+	// Renaming a renamed tool wraps the original only once.
+	renamed := RenameTool(tools[0], "echo2")
+	assert.Equal(t, "echo2", renamed.Name())
+	assert.Equal(t, echoTool{}, OriginalTool(renamed))
+}
+
+func TestNamespaceTools_Collisions(t *testing.T) {
+	_, err := NewToolRegistry(echoTool{}, echoTool{})
+	require.ErrorIs(t, err, ErrDuplicateTool)
+
+	tools := append(NamespaceTools("remote", echoTool{}), echoTool{})
+	registry, err := NewToolRegistry(tools...)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"remote__echo", "echo"}, registry.Names())
+
+	fake := newFakeLLM(
+		fakeResult{resp: toolCallResponse(ToolCallPart{ID: "call_1", Name: "remote__echo", Input: []byte(`{"text":"hi"}`)})},
+		fakeResult{resp: textResponse("done")},
+	)
+	result, err := RunTools(context.Background(), fake, nil, registry.List(), RunToolsOptions{})
+	require.NoError(t, err)
+
+	part := result.Messages[1].Parts[0].(ToolResultPart)
+	assert.Equal(t, "remote__echo", part.Name)
+	assert.Equal(t, "hi", part.Result)
+}
+
+func TestSplitToolName(t *testing.T) {
+	namespace, name := SplitToolName("github__create_issue")
+	assert.Equal(t, "github", namespace)
+	assert.Equal(t, "create_issue", name)
+
+	namespace, name = SplitToolName("create_issue")
+	assert.Equal(t, "", namespace)
+	assert.Equal(t, "create_issue", name)
+}
@@ -0,0 +1,84 @@
+package openai
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/openai/openai-go/option"
+)
+
+// AzureScope is the Microsoft Entra ID scope of the tokens Azure OpenAI
+// accepts.
+const AzureScope = "https://cognitiveservices.azure.com/.default"
+
+// WithAzure configures the client to use an Azure OpenAI deployment. endpoint
+// is the resource endpoint, such as "https://my-resource.openai.azure.com",
+// deployment is the name of the model deployment, and apiVersion is the Azure
+// OpenAI API version, such as "2024-10-21".
+//
+// Requests are authenticated with the AZURE_OPENAI_API_KEY environment
+// variable unless WithAzureAPIKey or WithAzureTokenProvider is used.
+//
+//	client := openai.New(
+//		openai.WithAzure("https://my-resource.openai.azure.com", "gpt-4o", "2024-10-21"),
+//		openai.WithAzureAPIKey(key),
+//	)
+func WithAzure(endpoint, deployment, apiVersion string) Modifier {
+	return func(c *Client) {
+		baseURL := strings.TrimSuffix(endpoint, "/") + "/openai/deployments/" + url.PathEscape(deployment) + "/"
+
+		// Azure routes requests by deployment rather than by model.
+		c.Model = deployment
+		c.options = append(c.options,
+			option.WithBaseURL(baseURL),
+			option.WithQuery("api-version", apiVersion),
+			// Azure does not accept OpenAI API keys, so do not send one
+			// read from OPENAI_API_KEY.
+			option.WithHeaderDel("authorization"),
+		)
+
+		if key := os.Getenv("AZURE_OPENAI_API_KEY"); key != "" {
+			c.options = append(c.options, option.WithHeader("api-key", key))
+		}
+	}
+}
+
+// WithAzureAPIKey authenticates requests to Azure OpenAI with an API key. It
+// is used together with WithAzure.
+func WithAzureAPIKey(key string) Modifier {
+	return func(c *Client) {
+		c.options = append(c.options, option.WithHeader("api-key", key))
+	}
+}
+
+// WithAzureTokenProvider authenticates requests to Azure OpenAI with Microsoft
+// Entra ID. It is used together with WithAzure. fn is called before every
+// request to get a bearer token for AzureScope, so it should cache tokens until
+// they expire, as azidentity credentials do:
+//
+//	cred, err := azidentity.NewDefaultAzureCredential(nil)
+//	...
+//	openai.WithAzureTokenProvider(func(ctx context.Context) (string, error) {
+//		token, err := cred.GetToken(ctx, policy.TokenRequestOptions{
+//			Scopes: []string{openai.AzureScope},
+//		})
+//		return token.Token, err
+//	})
+func WithAzureTokenProvider(fn func(ctx context.Context) (string, error)) Modifier {
+	return func(c *Client) {
+		c.options = append(c.options, option.WithMiddleware(func(req *http.Request, next option.MiddlewareNext) (*http.Response, error) {
+			token, err := fn(req.Context())
+			if err != nil {
+				return nil, fmt.Errorf("openai: failed to get Azure token: %w", err)
+			}
+
+			req.Header.Del("api-key")
+			req.Header.Set("Authorization", "Bearer "+token)
+			return next(req)
+		}))
+	}
+}
@@ -0,0 +1,78 @@
+package openai
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/openai/openai-go/option"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/llmite-ai/llms"
+)
+
+func newAzureTestServer(t *testing.T, check func(r *http.Request)) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/openai/deployments/my-gpt-4o/chat/completions", r.URL.Path)
+		assert.Equal(t, "2024-10-21", r.URL.Query().Get("api-version"))
+		check(r)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{
+			"id": "chatcmpl_1",
+			"object": "chat.completion",
+			"choices": [{"index": 0, "finish_reason": "stop", "message": {"role": "assistant", "content": "Hi"}}]
+		}`))
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func TestWithAzure_APIKey(t *testing.T) {
+	t.Setenv("OPENAI_API_KEY", "sk-openai")
+	t.Setenv("AZURE_OPENAI_API_KEY", "")
+
+	server := newAzureTestServer(t, func(r *http.Request) {
+		assert.Equal(t, "azure-key", r.Header.Get("api-key"))
+		assert.Empty(t, r.Header.Get("Authorization"))
+	})
+
+	client := New(WithAzure(server.URL+"/", "my-gpt-4o", "2024-10-21"), WithAzureAPIKey("azure-key"))
+	assert.Equal(t, "my-gpt-4o", client.(*Client).Model)
+
+	resp, err := client.Generate(context.Background(), []llms.Message{llms.NewTextMessage(llms.RoleUser, "Hello")})
+	require.NoError(t, err)
+	assert.Equal(t, "chatcmpl_1", resp.ID)
+}
+
+func TestWithAzure_TokenProvider(t *testing.T) {
+	t.Setenv("AZURE_OPENAI_API_KEY", "azure-key")
+
+	server := newAzureTestServer(t, func(r *http.Request) {
+		assert.Equal(t, "Bearer entra-token", r.Header.Get("Authorization"))
+		assert.Empty(t, r.Header.Get("api-key"))
+	})
+
+	client := New(WithAzure(server.URL, "my-gpt-4o", "2024-10-21"), WithAzureTokenProvider(func(ctx context.Context) (string, error) {
+		return "entra-token", nil
+	}))
+
+	_, err := client.Generate(context.Background(), []llms.Message{llms.NewTextMessage(llms.RoleUser, "Hello")})
+	require.NoError(t, err)
+
+	failing := New(
+		WithOpenAIClientOptions(option.WithMaxRetries(0)),
+		WithAzure(server.URL, "my-gpt-4o", "2024-10-21"),
+		WithAzureTokenProvider(func(ctx context.Context) (string, error) {
+			return "", errors.New("no credentials")
+		}),
+	)
+	_, err = failing.Generate(context.Background(), []llms.Message{llms.NewTextMessage(llms.RoleUser, "Hello")})
+	assert.ErrorContains(t, err, "failed to get Azure token: no credentials")
+}
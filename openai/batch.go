@@ -0,0 +1,236 @@
+package openai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/openai/openai-go"
+
+	"github.com/llmite-ai/llms"
+)
+
+var _ llms.BatchLLM = (*Client)(nil)
+
+// batchInputLine is a line of the JSONL file of requests of a batch.
+type batchInputLine struct {
+	CustomID string                          `json:"custom_id"`
+	Method   string                          `json:"method"`
+	URL      string                          `json:"url"`
+	Body     *openai.ChatCompletionNewParams `json:"body"`
+}
+
+// batchOutputLine is a line of the output or error file of a batch.
+type batchOutputLine struct {
+	CustomID string `json:"custom_id"`
+	Response *struct {
+		StatusCode int             `json:"status_code"`
+		Body       json.RawMessage `json:"body"`
+	} `json:"response"`
+	Error *struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// SubmitBatch submits requests to the Batch API. Each request is built like a
+// call to Generate, with the client's model, tools and settings and the call
+// options of ctx. The requests are uploaded as a file, which is processed
+// within 24 hours.
+func (c *Client) SubmitBatch(ctx context.Context, requests []llms.BatchRequest) (*llms.Batch, error) {
+	var input bytes.Buffer
+	enc := json.NewEncoder(&input)
+	for _, req := range requests {
+		params, err := c.BuildRequest(ctx, req.Messages)
+		if err != nil {
+			return nil, fmt.Errorf("openai: failed to build request %q: %w", req.ID, err)
+		}
+		line := batchInputLine{
+			CustomID: req.ID,
+			Method:   "POST",
+			URL:      string(openai.BatchNewParamsEndpointV1ChatCompletions),
+			Body:     params,
+		}
+		if err := enc.Encode(line); err != nil {
+			return nil, fmt.Errorf("openai: failed to encode request %q: %w", req.ID, err)
+		}
+	}
+
+	file, err := c.client.Files.New(ctx, openai.FileNewParams{
+		File:    openai.File(&input, "batch.jsonl", "application/jsonl"),
+		Purpose: openai.FilePurposeBatch,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("openai: failed to upload batch requests: %w", wrapError(err))
+	}
+
+	batch, err := c.client.Batches.New(ctx, openai.BatchNewParams{
+		InputFileID:      file.ID,
+		Endpoint:         openai.BatchNewParamsEndpointV1ChatCompletions,
+		CompletionWindow: openai.BatchNewParamsCompletionWindow24h,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("openai: failed to submit batch: %w", wrapError(err))
+	}
+	return convertBatch(batch), nil
+}
+
+// GetBatch returns the current state of a batch.
+func (c *Client) GetBatch(ctx context.Context, id string) (*llms.Batch, error) {
+	batch, err := c.client.Batches.Get(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("openai: failed to get batch: %w", wrapError(err))
+	}
+	return convertBatch(batch), nil
+}
+
+// Results returns an iterator over the results of an ended batch, read from
+// its output file and then its error file. A batch that failed validation
+// has no results, and yields an error describing why it failed.
+func (c *Client) Results(ctx context.Context, id string) iter.Seq2[llms.BatchResult, error] {
+	return func(yield func(llms.BatchResult, error) bool) {
+		batch, err := c.client.Batches.Get(ctx, id)
+		if err != nil {
+			yield(llms.BatchResult{}, fmt.Errorf("openai: failed to get batch: %w", wrapError(err)))
+			return
+		}
+		if !convertBatch(batch).Done() {
+			yield(llms.BatchResult{}, llms.ErrBatchInProgress)
+			return
+		}
+		if batch.Status == openai.BatchStatusFailed && batch.OutputFileID == "" && batch.ErrorFileID == "" {
+			yield(llms.BatchResult{}, batchError(batch))
+			return
+		}
+
+		for _, fileID := range []string{batch.OutputFileID, batch.ErrorFileID} {
+			if fileID == "" {
+				continue
+			}
+			if !c.fileResults(ctx, fileID, yield) {
+				return
+			}
+		}
+	}
+}
+
+// fileResults yields the results in the output or error file with the given
+// ID. It returns false if the iteration stopped.
+func (c *Client) fileResults(ctx context.Context, fileID string, yield func(llms.BatchResult, error) bool) bool {
+	resp, err := c.client.Files.Content(ctx, fileID)
+	if err != nil {
+		return yield(llms.BatchResult{}, fmt.Errorf("openai: failed to download batch results: %w", wrapError(err)))
+	}
+	defer resp.Body.Close()
+
+	dec := json.NewDecoder(resp.Body)
+	for {
+		var line batchOutputLine
+		err := dec.Decode(&line)
+		if errors.Is(err, io.EOF) {
+			return true
+		}
+		if err != nil {
+			yield(llms.BatchResult{}, fmt.Errorf("openai: failed to read batch results: %w", err))
+			return false
+		}
+		if !yield(c.convertBatchResult(line), nil) {
+			return false
+		}
+	}
+}
+
+func (c *Client) convertBatchResult(line batchOutputLine) llms.BatchResult {
+	out := llms.BatchResult{ID: line.CustomID}
+	switch {
+	case line.Error != nil:
+		switch line.Error.Code {
+		case "batch_expired":
+			out.Err = llms.ErrBatchExpired
+		case "batch_cancelled":
+			out.Err = llms.ErrBatchCanceled
+		default:
+			out.Err = fmt.Errorf("openai: request failed: %s: %s", line.Error.Code, line.Error.Message)
+		}
+	case line.Response == nil:
+		out.Err = errors.New("openai: batch result has no response")
+	case line.Response.StatusCode != http.StatusOK:
+		var body struct {
+			Error struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		message := string(line.Response.Body)
+		if json.Unmarshal(line.Response.Body, &body) == nil && body.Error.Message != "" {
+			message = body.Error.Message
+		}
+		out.Err = &llms.APIError{
+			Provider:   ProviderOpenAI,
+			StatusCode: line.Response.StatusCode,
+			Err:        errors.New(message),
+		}
+	default:
+		var completion openai.ChatCompletion
+		if err := json.Unmarshal(line.Response.Body, &completion); err != nil {
+			out.Err = fmt.Errorf("openai: failed to decode batch result: %w", err)
+			break
+		}
+		out.Response, out.Err = c.convertCompletion(&completion, "")
+	}
+	return out
+}
+
+// batchError describes why a batch failed validation.
+func batchError(batch *openai.Batch) error {
+	var messages []string
+	for _, e := range batch.Errors.Data {
+		messages = append(messages, e.Message)
+	}
+	return fmt.Errorf("openai: batch failed: %s", strings.Join(messages, "; "))
+}
+
+func convertBatch(batch *openai.Batch) *llms.Batch {
+	counts := batch.RequestCounts
+	out := &llms.Batch{
+		ID:        batch.ID,
+		Pending:   int(counts.Total - counts.Completed - counts.Failed),
+		Succeeded: int(counts.Completed),
+		Failed:    int(counts.Failed),
+		CreatedAt: unixTime(batch.CreatedAt),
+		ExpiresAt: unixTime(batch.ExpiresAt),
+		Raw:       batch,
+	}
+	switch batch.Status {
+	case openai.BatchStatusCancelling:
+		out.Status = llms.BatchCanceling
+	case openai.BatchStatusCompleted, openai.BatchStatusFailed, openai.BatchStatusExpired, openai.BatchStatusCancelled:
+		out.Status = llms.BatchEnded
+		// The requests left unprocessed are reported in the error file.
+		out.Failed += out.Pending
+		out.Pending = 0
+		for _, t := range []int64{batch.CompletedAt, batch.FailedAt, batch.ExpiredAt, batch.CancelledAt} {
+			if t != 0 {
+				out.EndedAt = unixTime(t)
+				break
+			}
+		}
+	default:
+		out.Status = llms.BatchInProgress
+	}
+	return out
+}
+
+// unixTime converts a Unix timestamp, returning the zero time for 0.
+func unixTime(t int64) time.Time {
+	if t == 0 {
+		return time.Time{}
+	}
+	return time.Unix(t, 0).UTC()
+}
@@ -0,0 +1,153 @@
+package openai
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/llmite-ai/llms"
+)
+
+// openAIBatch returns a Batch API batch with the given status.
+func openAIBatch(status string, total, completed, failed int, outputFileID, errorFileID string) string {
+	return fmt.Sprintf(`{
+		"id": "batch_1",
+		"object": "batch",
+		"endpoint": "/v1/chat/completions",
+		"input_file_id": "file_in",
+		"completion_window": "24h",
+		"status": %q,
+		"created_at": 1735689600,
+		"expires_at": 1735776000,
+		"completed_at": 1735700000,
+		"output_file_id": %q,
+		"error_file_id": %q,
+		"request_counts": {"total": %d, "completed": %d, "failed": %d}
+	}`, status, outputFileID, errorFileID, total, completed, failed)
+}
+
+func TestSubmitBatch(t *testing.T) {
+	var lines []map[string]any
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/files":
+			require.NoError(t, r.ParseMultipartForm(1<<20))
+			assert.Equal(t, "batch", r.FormValue("purpose"))
+			file, _, err := r.FormFile("file")
+			require.NoError(t, err)
+			defer file.Close()
+			scanner := bufio.NewScanner(file)
+			for scanner.Scan() {
+				var line map[string]any
+				require.NoError(t, json.Unmarshal(scanner.Bytes(), &line))
+				lines = append(lines, line)
+			}
+			w.Write([]byte(`{"id": "file_in", "object": "file", "bytes": 1, "created_at": 1, "filename": "batch.jsonl", "purpose": "batch", "status": "processed"}`))
+		case "/batches":
+			var body map[string]any
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+			assert.Equal(t, map[string]any{
+				"input_file_id":     "file_in",
+				"endpoint":          "/v1/chat/completions",
+				"completion_window": "24h",
+			}, body)
+			w.Write([]byte(openAIBatch("validating", 2, 0, 0, "", "")))
+		default:
+			t.Errorf("unexpected request to %s", r.URL.Path)
+		}
+	}, WithModel("gpt-test"))
+
+	batch, err := client.SubmitBatch(context.Background(), []llms.BatchRequest{
+		{ID: "first", Messages: []llms.Message{llms.NewTextMessage(llms.RoleUser, "one")}},
+		{ID: "second", Messages: []llms.Message{llms.NewTextMessage(llms.RoleUser, "two")}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "batch_1", batch.ID)
+	assert.Equal(t, llms.BatchInProgress, batch.Status)
+	assert.Equal(t, 2, batch.Pending)
+	assert.Equal(t, time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), batch.CreatedAt)
+
+	require.Len(t, lines, 2)
+	assert.Equal(t, "first", lines[0]["custom_id"])
+	assert.Equal(t, "POST", lines[0]["method"])
+	assert.Equal(t, "/v1/chat/completions", lines[0]["url"])
+	body := lines[1]["body"].(map[string]any)
+	assert.Equal(t, "gpt-test", body["model"])
+	assert.Equal(t, []any{map[string]any{"role": "user", "content": "two"}}, body["messages"])
+}
+
+func TestBatchResults(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/batches/batch_1":
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(openAIBatch("expired", 4, 1, 1, "file_out", "file_err")))
+		case "/files/file_out/content":
+			fmt.Fprintln(w, `{"id": "req_1", "custom_id": "first", "response": {"status_code": 200, "body": {"id": "chatcmpl_1", "object": "chat.completion", "created": 1, "model": "gpt-test", "choices": [{"index": 0, "finish_reason": "stop", "message": {"role": "assistant", "content": "Hi!"}}], "usage": {"prompt_tokens": 5, "completion_tokens": 2, "total_tokens": 7}}}, "error": null}`)
+		case "/files/file_err/content":
+			fmt.Fprintln(w, `{"id": "req_2", "custom_id": "second", "response": {"status_code": 400, "body": {"error": {"message": "bad request"}}}, "error": null}`)
+			fmt.Fprintln(w, `{"id": "req_3", "custom_id": "third", "response": null, "error": {"code": "batch_expired", "message": "expired"}}`)
+			fmt.Fprintln(w, `{"id": "req_4", "custom_id": "fourth", "response": null, "error": {"code": "batch_cancelled", "message": "cancelled"}}`)
+		default:
+			t.Errorf("unexpected request to %s", r.URL.Path)
+		}
+	})
+
+	// The requests left unprocessed when the batch expired count as failed.
+	batch, err := client.GetBatch(context.Background(), "batch_1")
+	require.NoError(t, err)
+	assert.True(t, batch.Done())
+	assert.Equal(t, 0, batch.Pending)
+	assert.Equal(t, 1, batch.Succeeded)
+	assert.Equal(t, 3, batch.Failed)
+	assert.Equal(t, time.Unix(1735700000, 0).UTC(), batch.EndedAt)
+
+	var results []llms.BatchResult
+	for result, err := range client.Results(context.Background(), "batch_1") {
+		require.NoError(t, err)
+		results = append(results, result)
+	}
+	require.Len(t, results, 4)
+
+	assert.Equal(t, "first", results[0].ID)
+	require.NoError(t, results[0].Err)
+	assert.Equal(t, "Hi!", results[0].Response.Message.Parts[0].(llms.TextPart).Text)
+	assert.Equal(t, 5, results[0].Response.Usage.InputTokens)
+
+	var apiErr *llms.APIError
+	require.ErrorAs(t, results[1].Err, &apiErr)
+	assert.Equal(t, http.StatusBadRequest, apiErr.StatusCode)
+	assert.EqualError(t, apiErr.Err, "bad request")
+	assert.ErrorIs(t, results[2].Err, llms.ErrBatchExpired)
+	assert.ErrorIs(t, results[3].Err, llms.ErrBatchCanceled)
+}
+
+func TestBatchResults_Unfinished(t *testing.T) {
+	status := "in_progress"
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/batches/batch_1", r.URL.Path)
+		w.Header().Set("Content-Type", "application/json")
+		if status == "failed" {
+			w.Write([]byte(`{"id": "batch_1", "object": "batch", "status": "failed", "errors": {"data": [{"code": "invalid_json", "message": "line 1 is not valid JSON"}]}}`))
+			return
+		}
+		w.Write([]byte(openAIBatch(status, 1, 0, 0, "", "")))
+	})
+
+	for _, err := range client.Results(context.Background(), "batch_1") {
+		assert.ErrorIs(t, err, llms.ErrBatchInProgress)
+	}
+
+	status = "failed"
+	for _, err := range client.Results(context.Background(), "batch_1") {
+		assert.EqualError(t, err, "openai: batch failed: line 1 is not valid JSON")
+	}
+}
@@ -2,11 +2,18 @@ package openai
 
 import (
 	"context"
+	"encoding/base64"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"net/http"
+	"strings"
 
+	"github.com/invopop/jsonschema"
 	"github.com/openai/openai-go"
 	"github.com/openai/openai-go/option"
+	"github.com/openai/openai-go/packages/respjson"
+	"github.com/openai/openai-go/shared"
 
 	"github.com/llmite-ai/llms"
 )
@@ -19,6 +26,53 @@ type Client struct {
 	Temperature *float64
 	TopP        *float64
 	Tools       []llms.Tool
+	Registry    *llms.ToolRegistry
+	// ReasoningEffort, if set, is how much effort reasoning models spend
+	// thinking: "low", "medium" or "high".
+	ReasoningEffort string
+	// Seed, if set, makes sampling deterministic on a best-effort basis.
+	Seed *int64
+	// ToolChoice, if set, controls whether and which tools the model calls.
+	// It can be overridden per call with llms.ToolChoiceOption.
+	ToolChoice *llms.ToolChoice
+	// ParallelToolCalls, if set, allows or prevents the model from calling
+	// more than one tool in a single response. It can be overridden per call
+	// with llms.ParallelToolCalls.
+	ParallelToolCalls *bool
+	// AudioVoice and AudioFormat, if either is set, make audio models
+	// respond with speech as well as text. AudioVoice defaults to
+	// DefaultSpeechVoice, and AudioFormat to "wav", or "pcm16" when streaming,
+	// the only format audio can be streamed in.
+	AudioVoice  string
+	AudioFormat string
+	// ModerationModel is the model used by Moderate. Defaults to
+	// DefaultModerationModel.
+	ModerationModel string
+	// EmbeddingModel is the model used by Embed and EmbedBatch. Defaults to
+	// DefaultEmbeddingModel.
+	EmbeddingModel string
+	// EmbeddingDimensions, if set, shortens the embeddings of models that
+	// support it, such as text-embedding-3-small.
+	EmbeddingDimensions int
+	// ImageModel is the model used by GenerateImages. Defaults to
+	// DefaultImageModel.
+	ImageModel string
+	// SpeechModel and SpeechVoice are the model and default voice used by
+	// Speak. They default to DefaultSpeechModel and DefaultSpeechVoice.
+	SpeechModel string
+	SpeechVoice string
+	// TranscriptionModel is the model used by Transcribe. Defaults to
+	// DefaultTranscriptionModel.
+	TranscriptionModel string
+	// StopSequences are custom sequences that stop generation when the
+	// model produces them.
+	StopSequences []string
+	// FrequencyPenalty and PresencePenalty, if set, penalise repeated
+	// tokens, between -2 and 2.
+	FrequencyPenalty *float64
+	PresencePenalty  *float64
+	// LogitBias adjusts the likelihood of tokens, keyed by token ID.
+	LogitBias map[string]int
 
 	client  *openai.Client
 	options []option.RequestOption
@@ -35,6 +89,23 @@ func WithOpenAIClientOptions(options ...option.RequestOption) Modifier {
 	}
 }
 
+// WithRequestOptions adds options sent with every request, such as extra
+// headers or body fields for OpenAI-compatible servers. Unlike
+// WithOpenAIClientOptions, it keeps the options set by other modifiers.
+func WithRequestOptions(options ...option.RequestOption) Modifier {
+	return func(c *Client) {
+		c.options = append(c.options, options...)
+	}
+}
+
+// WithHTTPClient sets the HTTP client requests are sent with, such as one
+// created by llms.NewHTTPClient.
+func WithHTTPClient(client *http.Client) Modifier {
+	return func(c *Client) {
+		c.options = append(c.options, option.WithHTTPClient(client))
+	}
+}
+
 // WithHttpLogging will log all HTTP requests and responses to the default structured
 // logger.
 func WithHttpLogging() Modifier {
@@ -44,6 +115,25 @@ func WithHttpLogging() Modifier {
 	}
 }
 
+// WithBaseURL sends requests to the server at url instead of OpenAI, for
+// OpenAI-compatible servers such as vLLM, LM Studio or Ollama
+// ("http://localhost:11434/v1/"). It overrides the OPENAI_BASE_URL environment
+// variable.
+func WithBaseURL(url string) Modifier {
+	return func(c *Client) {
+		c.options = append(c.options, option.WithBaseURL(url))
+	}
+}
+
+// WithAPIKey sets the API key sent with every request, overriding the
+// OPENAI_API_KEY environment variable. Local servers that do not check keys
+// accept any value.
+func WithAPIKey(key string) Modifier {
+	return func(c *Client) {
+		c.options = append(c.options, option.WithAPIKey(key))
+	}
+}
+
 // WithModel allows you to set the model on the client. The default model is "gpt-4o".
 func WithModel(model string) Modifier {
 	return func(c *Client) {
@@ -72,6 +162,137 @@ func WithTopP(topP float64) Modifier {
 	}
 }
 
+// WithReasoningEffort sets how much effort reasoning models, such as o3 and
+// o4-mini, spend thinking before they answer: "low", "medium" or "high".
+func WithReasoningEffort(effort string) Modifier {
+	return func(c *Client) {
+		c.ReasoningEffort = effort
+	}
+}
+
+// WithSeed makes sampling deterministic on a best-effort basis, so repeated
+// requests with the same seed and parameters usually return the same result.
+// Compare SystemFingerprint across responses to detect backend changes that
+// may affect determinism.
+func WithSeed(seed int) Modifier {
+	return func(c *Client) {
+		s := int64(seed)
+		c.Seed = &s
+	}
+}
+
+// WithToolChoice controls whether and which tools the model calls. choice is
+// one of llms.ToolChoiceAuto, llms.ToolChoiceAny (sent as "required"),
+// llms.ToolChoiceTool or llms.ToolChoiceNone; name is the function to force
+// when choice is llms.ToolChoiceTool and is otherwise ignored.
+func WithToolChoice(choice, name string) Modifier {
+	return func(c *Client) {
+		c.ToolChoice = &llms.ToolChoice{Type: choice, Name: name}
+	}
+}
+
+// WithParallelToolCalls allows or prevents the model from calling more than
+// one tool in a single response.
+func WithParallelToolCalls(enabled bool) Modifier {
+	return func(c *Client) {
+		c.ParallelToolCalls = &enabled
+	}
+}
+
+// WithAudioOutput makes audio models, such as gpt-4o-audio-preview, respond
+// with speech as well as text. voice is a voice such as "alloy" and format is
+// the audio format, such as "wav" or "mp3"; empty values use the defaults
+// described on Client. The speech is returned as an llms.AudioPart holding
+// the audio and its transcript, also when streaming.
+func WithAudioOutput(voice, format string) Modifier {
+	return func(c *Client) {
+		c.AudioVoice = voice
+		c.AudioFormat = format
+	}
+}
+
+// WithModerationModel sets the model used by Moderate.
+func WithModerationModel(model string) Modifier {
+	return func(c *Client) {
+		c.ModerationModel = model
+	}
+}
+
+// WithEmbeddingModel sets the model used by Embed and EmbedBatch.
+func WithEmbeddingModel(model string) Modifier {
+	return func(c *Client) {
+		c.EmbeddingModel = model
+	}
+}
+
+// WithEmbeddingDimensions shortens embeddings to dimensions, for models that
+// support it.
+func WithEmbeddingDimensions(dimensions int) Modifier {
+	return func(c *Client) {
+		c.EmbeddingDimensions = dimensions
+	}
+}
+
+// WithImageModel sets the model used by GenerateImages.
+func WithImageModel(model string) Modifier {
+	return func(c *Client) {
+		c.ImageModel = model
+	}
+}
+
+// WithSpeechModel sets the model used by Speak.
+func WithSpeechModel(model string) Modifier {
+	return func(c *Client) {
+		c.SpeechModel = model
+	}
+}
+
+// WithSpeechVoice sets the voice Speak uses unless the request names one.
+func WithSpeechVoice(voice string) Modifier {
+	return func(c *Client) {
+		c.SpeechVoice = voice
+	}
+}
+
+// WithTranscriptionModel sets the model used by Transcribe.
+func WithTranscriptionModel(model string) Modifier {
+	return func(c *Client) {
+		c.TranscriptionModel = model
+	}
+}
+
+// WithStopSequences sets custom sequences that stop generation when the model
+// produces them. OpenAI accepts up to four.
+func WithStopSequences(sequences ...string) Modifier {
+	return func(c *Client) {
+		c.StopSequences = sequences
+	}
+}
+
+// WithFrequencyPenalty penalises tokens by how often they have already
+// appeared, between -2 and 2.
+func WithFrequencyPenalty(penalty float64) Modifier {
+	return func(c *Client) {
+		c.FrequencyPenalty = &penalty
+	}
+}
+
+// WithPresencePenalty penalises tokens that have already appeared, between -2
+// and 2.
+func WithPresencePenalty(penalty float64) Modifier {
+	return func(c *Client) {
+		c.PresencePenalty = &penalty
+	}
+}
+
+// WithLogitBias adjusts the likelihood of tokens, keyed by token ID, by a value
+// between -100 and 100.
+func WithLogitBias(bias map[string]int) Modifier {
+	return func(c *Client) {
+		c.LogitBias = bias
+	}
+}
+
 // WithTools allows you to set the tools on the client.
 func WithTools(tools []llms.Tool) Modifier {
 	return func(c *Client) {
@@ -79,6 +300,24 @@ func WithTools(tools []llms.Tool) Modifier {
 	}
 }
 
+// WithToolRegistry makes the tools in registry available to the model. The
+// registry is read on every request, so tools registered later are included.
+func WithToolRegistry(registry *llms.ToolRegistry) Modifier {
+	return func(c *Client) {
+		c.Registry = registry
+	}
+}
+
+// tools returns the client's tools followed by those in its registry, less
+// those disabled by the call options in ctx.
+func (c *Client) tools(ctx context.Context) []llms.Tool {
+	tools := c.Tools
+	if c.Registry != nil {
+		tools = append(append([]llms.Tool(nil), tools...), c.Registry.List()...)
+	}
+	return llms.CallOptionsFromContext(ctx).FilterTools(tools)
+}
+
 // New creates a new OpenAI client with the default options.
 // This includes reading the OPENAI_API_KEY environment variable.
 func New(mods ...Modifier) llms.LLM {
@@ -100,18 +339,25 @@ func New(mods ...Modifier) llms.LLM {
 	return c
 }
 
+// ModelName returns the model requests are sent to.
+func (c *Client) ModelName() string {
+	return c.Model
+}
+
 // GetClient returns the underlying OpenAI client.
 func (c *Client) GetClient() *openai.Client {
 	return c.client
 }
 
-func (c *Client) Generate(ctx context.Context, messages []llms.Message) (*llms.Response, error) {
+// BuildRequest converts messages and the client's settings to the parameters
+// of a chat completion request.
+func (c *Client) BuildRequest(ctx context.Context, messages []llms.Message) (*openai.ChatCompletionNewParams, error) {
 	oaiMessages, err := convertMessages(messages)
 	if err != nil {
 		return nil, err
 	}
 
-	tools, err := convertTools(c.Tools)
+	tools, err := convertTools(c.tools(ctx))
 	if err != nil {
 		return nil, err
 	}
@@ -122,6 +368,67 @@ func (c *Client) Generate(ctx context.Context, messages []llms.Message) (*llms.R
 		Tools:    tools,
 	}
 
+	callOpts := llms.CallOptionsFromContext(ctx)
+
+	if c.Seed != nil {
+		params.Seed = openai.Int(*c.Seed)
+	}
+
+	stop := c.StopSequences
+	if callOpts.StopSequences != nil {
+		stop = callOpts.StopSequences
+	}
+	if len(stop) > 0 {
+		params.Stop.OfStringArray = stop
+	}
+
+	if c.AudioVoice != "" || c.AudioFormat != "" {
+		voice := c.AudioVoice
+		if voice == "" {
+			voice = DefaultSpeechVoice
+		}
+		params.Modalities = []string{"text", "audio"}
+		params.Audio = openai.ChatCompletionAudioParam{
+			Voice:  openai.ChatCompletionAudioParamVoice(voice),
+			Format: openai.ChatCompletionAudioParamFormat(c.audioFormat(false)),
+		}
+	}
+
+	// OpenAI only accepts tool_choice and parallel_tool_calls when tools are
+	// provided.
+	if len(tools) > 0 {
+		toolChoice := c.ToolChoice
+		if callOpts.ToolChoice != nil {
+			toolChoice = callOpts.ToolChoice
+		}
+		if toolChoice != nil {
+			params.ToolChoice, err = convertToolChoice(*toolChoice)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		parallel := c.ParallelToolCalls
+		if callOpts.ParallelToolCalls != nil {
+			parallel = callOpts.ParallelToolCalls
+		}
+		if parallel != nil {
+			params.ParallelToolCalls = openai.Bool(*parallel)
+		}
+	}
+
+	if c.ReasoningEffort != "" {
+		params.ReasoningEffort = shared.ReasoningEffort(c.ReasoningEffort)
+	}
+
+	// Reasoning models reject max_tokens and the sampling parameters.
+	if isReasoningModel(c.Model) {
+		if c.MaxTokens > 0 {
+			params.MaxCompletionTokens = openai.Int(c.MaxTokens)
+		}
+		return &params, nil
+	}
+
 	if c.MaxTokens > 0 {
 		params.MaxTokens = openai.Int(c.MaxTokens)
 	}
@@ -134,11 +441,85 @@ func (c *Client) Generate(ctx context.Context, messages []llms.Message) (*llms.R
 		params.TopP = openai.Float(*c.TopP)
 	}
 
-	oaiResponse, err := c.client.Chat.Completions.New(ctx, params)
+	frequencyPenalty := c.FrequencyPenalty
+	if callOpts.FrequencyPenalty != nil {
+		frequencyPenalty = callOpts.FrequencyPenalty
+	}
+	if frequencyPenalty != nil {
+		params.FrequencyPenalty = openai.Float(*frequencyPenalty)
+	}
+
+	presencePenalty := c.PresencePenalty
+	if callOpts.PresencePenalty != nil {
+		presencePenalty = callOpts.PresencePenalty
+	}
+	if presencePenalty != nil {
+		params.PresencePenalty = openai.Float(*presencePenalty)
+	}
+
+	logitBias := c.LogitBias
+	if callOpts.LogitBias != nil {
+		logitBias = callOpts.LogitBias
+	}
+	if len(logitBias) > 0 {
+		params.LogitBias = make(map[string]int64, len(logitBias))
+		for token, bias := range logitBias {
+			params.LogitBias[token] = int64(bias)
+		}
+	}
+
+	return &params, nil
+}
+
+func convertToolChoice(choice llms.ToolChoice) (openai.ChatCompletionToolChoiceOptionUnionParam, error) {
+	switch choice.Type {
+	case llms.ToolChoiceAuto, llms.ToolChoiceNone:
+		return openai.ChatCompletionToolChoiceOptionUnionParam{OfAuto: openai.String(choice.Type)}, nil
+	case llms.ToolChoiceAny:
+		return openai.ChatCompletionToolChoiceOptionUnionParam{OfAuto: openai.String("required")}, nil
+	case llms.ToolChoiceTool:
+		if choice.Name == "" {
+			return openai.ChatCompletionToolChoiceOptionUnionParam{}, fmt.Errorf("openai: tool choice %q requires a tool name", choice.Type)
+		}
+		return openai.ChatCompletionToolChoiceOptionUnionParam{
+			OfChatCompletionNamedToolChoice: &openai.ChatCompletionNamedToolChoiceParam{
+				Function: openai.ChatCompletionNamedToolChoiceFunctionParam{Name: choice.Name},
+			},
+		}, nil
+	default:
+		return openai.ChatCompletionToolChoiceOptionUnionParam{}, fmt.Errorf("openai: unsupported tool choice: %q", choice.Type)
+	}
+}
+
+// isReasoningModel reports whether model is an o-series reasoning model.
+func isReasoningModel(model string) bool {
+	for _, prefix := range []string{"o1", "o3", "o4"} {
+		if model == prefix || strings.HasPrefix(model, prefix+"-") {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *Client) Generate(ctx context.Context, messages []llms.Message) (*llms.Response, error) {
+	ctx, requestID := llms.EnsureRequestID(ctx)
+	params, err := c.BuildRequest(ctx, messages)
 	if err != nil {
-		return nil, fmt.Errorf("openai: failed to generate message: %w", err)
+		return nil, err
 	}
 
+	oaiResponse, err := c.client.Chat.Completions.New(ctx, *params)
+	if err != nil {
+		return nil, fmt.Errorf("openai: failed to generate message: %w", wrapError(err))
+	}
+
+	return c.convertCompletion(oaiResponse, requestID)
+}
+
+// convertCompletion converts a chat completion into an llms.Response. If
+// some of its content cannot be converted, the response is returned along
+// with the errors.
+func (c *Client) convertCompletion(oaiResponse *openai.ChatCompletion, requestID string) (*llms.Response, error) {
 	if len(oaiResponse.Choices) == 0 {
 		return nil, fmt.Errorf("openai: no choices returned")
 	}
@@ -158,6 +539,16 @@ func (c *Client) Generate(ctx context.Context, messages []llms.Message) (*llms.R
 		})
 	}
 
+	// Handle audio content
+	if choice.Message.JSON.Audio.Valid() {
+		audio, err := convertAudioResponse(choice.Message.Audio, c.audioFormat(false))
+		if err != nil {
+			errs = append(errs, err)
+		} else {
+			msgOut.Parts = append(msgOut.Parts, audio)
+		}
+	}
+
 	// Handle tool calls
 	for _, toolCall := range choice.Message.ToolCalls {
 		if toolCall.Type == "function" {
@@ -170,10 +561,12 @@ func (c *Client) Generate(ctx context.Context, messages []llms.Message) (*llms.R
 	}
 
 	out := &llms.Response{
-		ID:       oaiResponse.ID,
-		Message:  msgOut,
-		Provider: ProviderOpenAI,
-		Raw:      oaiResponse,
+		ID:        oaiResponse.ID,
+		Message:   msgOut,
+		Usage:     convertUsage(oaiResponse.Usage),
+		RequestID: requestID,
+		Provider:  ProviderOpenAI,
+		Raw:       oaiResponse,
 	}
 
 	if len(errs) > 0 {
@@ -184,35 +577,19 @@ func (c *Client) Generate(ctx context.Context, messages []llms.Message) (*llms.R
 }
 
 func (c *Client) GenerateStream(ctx context.Context, messages []llms.Message, fn llms.StreamFunc) (*llms.Response, error) {
-	oaiMessages, err := convertMessages(messages)
+	ctx, requestID := llms.EnsureRequestID(ctx)
+	params, err := c.BuildRequest(ctx, messages)
 	if err != nil {
 		return nil, err
 	}
 
-	tools, err := convertTools(c.Tools)
-	if err != nil {
-		return nil, err
-	}
-
-	params := openai.ChatCompletionNewParams{
-		Model:    openai.ChatModel(c.Model),
-		Messages: oaiMessages,
-		Tools:    tools,
-	}
-
-	if c.MaxTokens > 0 {
-		params.MaxTokens = openai.Int(c.MaxTokens)
-	}
-
-	if c.Temperature != nil {
-		params.Temperature = openai.Float(*c.Temperature)
+	// Ask for a final chunk reporting the usage of the whole request.
+	params.StreamOptions.IncludeUsage = openai.Bool(true)
+	if params.Audio.Format != "" {
+		params.Audio.Format = openai.ChatCompletionAudioParamFormat(c.audioFormat(true))
 	}
 
-	if c.TopP != nil {
-		params.TopP = openai.Float(*c.TopP)
-	}
-
-	stream := c.client.Chat.Completions.NewStreaming(ctx, params)
+	stream := c.client.Chat.Completions.NewStreaming(ctx, *params)
 	defer stream.Close()
 
 	out := &llms.Response{
@@ -220,69 +597,211 @@ func (c *Client) GenerateStream(ctx context.Context, messages []llms.Message, fn
 			Role:  llms.RoleAssistant,
 			Parts: []llms.Part{},
 		},
-		Provider: ProviderOpenAI,
+		RequestID: requestID,
+		Provider:  ProviderOpenAI,
 	}
 
-	// Track tool calls across chunks
-	toolCalls := make(map[string]*llms.ToolCallPart)
+	// The text is accumulated into a single part, followed by the audio and
+	// the tool calls in the order the model made them. Tool call deltas are
+	// matched by index, since only the first delta of each call carries its
+	// ID.
+	var text strings.Builder
+	var audio *llms.AudioPart
+	var toolCalls []llms.ToolCallPart
 
 	for stream.Next() {
 		chunk := stream.Current()
-		
+
 		if chunk.ID != "" && out.ID == "" {
 			out.ID = chunk.ID
 		}
 
+		if chunk.JSON.Usage.Valid() {
+			out.Usage = convertUsage(chunk.Usage)
+		}
+
 		if len(chunk.Choices) == 0 {
+			// The final chunk only reports usage. It is kept as Raw so that
+			// non-standard usage fields, such as the cost reported by
+			// OpenAI-compatible servers, can be read from the response.
+			out.Raw = chunk
+			continue
+		}
+
+		// Deltas with fields unknown to the SDK, such as the reasoning of
+		// OpenAI-compatible servers, are passed on so they can be read from
+		// Raw.
+		delta := chunk.Choices[0].Delta
+		if delta.Content == "" && len(delta.ToolCalls) == 0 && len(delta.JSON.ExtraFields) == 0 {
 			continue
 		}
 
-		choice := chunk.Choices[0]
-		delta := choice.Delta
-
-		// Handle text content
-		if delta.Content != "" {
-			textPart := llms.TextPart{Text: delta.Content}
-			out.Message.Parts = append(out.Message.Parts, textPart)
-			
-			// Call the stream function with the response and error
-			continueStream := fn(out, nil)
-			if !continueStream {
-				return out, nil
+		text.WriteString(delta.Content)
+
+		if field, ok := delta.JSON.ExtraFields["audio"]; ok {
+			var d audioDelta
+			if err := json.Unmarshal([]byte(field.Raw()), &d); err != nil {
+				return out, fmt.Errorf("openai: failed to decode audio: %w", err)
+			}
+			data, err := base64.StdEncoding.DecodeString(d.Data)
+			if err != nil {
+				return out, fmt.Errorf("openai: failed to decode audio: %w", err)
+			}
+			if audio == nil {
+				audio = &llms.AudioPart{MediaType: audioMediaTypes[c.audioFormat(true)]}
 			}
+			if d.ID != "" {
+				audio.ID = d.ID
+			}
+			audio.Data = append(audio.Data, data...)
+			audio.Transcript += d.Transcript
 		}
 
-		// Handle tool calls
 		for _, toolCall := range delta.ToolCalls {
-			if toolCall.Type == "function" {
-				if existingCall, exists := toolCalls[toolCall.ID]; exists {
-					// Append to existing tool call
-					existingCall.Input = append(existingCall.Input, []byte(toolCall.Function.Arguments)...)
-				} else {
-					// Create new tool call
-					newCall := &llms.ToolCallPart{
-						ID:    toolCall.ID,
-						Name:  toolCall.Function.Name,
-						Input: []byte(toolCall.Function.Arguments),
-					}
-					toolCalls[toolCall.ID] = newCall
-				}
+			i := int(toolCall.Index)
+			for len(toolCalls) <= i {
+				toolCalls = append(toolCalls, llms.ToolCallPart{})
+			}
+			call := &toolCalls[i]
+			if toolCall.ID != "" {
+				call.ID = toolCall.ID
 			}
+			call.Name += toolCall.Function.Name
+			call.Input = append(call.Input, toolCall.Function.Arguments...)
 		}
 
+		out.Message.Parts = streamParts(text.String(), audio, toolCalls)
+		// Raw holds the current chunk, so fn can read the latest delta.
 		out.Raw = chunk
+
+		if !fn(out, nil) {
+			return out, nil
+		}
 	}
 
 	if err := stream.Err(); err != nil {
-		return out, fmt.Errorf("openai: streaming error: %w", err)
+		return out, fmt.Errorf("openai: streaming error: %w", wrapError(err))
+	}
+
+	return out, nil
+}
+
+// streamParts returns the parts of a message accumulated from a stream. The
+// audio and tool calls are copied, as they keep changing while the stream is
+// read.
+func streamParts(text string, audio *llms.AudioPart, toolCalls []llms.ToolCallPart) []llms.Part {
+	parts := make([]llms.Part, 0, len(toolCalls)+2)
+	if text != "" {
+		parts = append(parts, llms.TextPart{Text: text})
+	}
+	if audio != nil {
+		part := *audio
+		part.Data = append([]byte(nil), audio.Data...)
+		parts = append(parts, part)
 	}
+	for _, call := range toolCalls {
+		call.Input = append([]byte(nil), call.Input...)
+		parts = append(parts, call)
+	}
+	return parts
+}
+
+// audioMediaTypes maps the audio output formats to their media types.
+var audioMediaTypes = map[string]string{
+	"wav":   "audio/wav",
+	"mp3":   "audio/mpeg",
+	"flac":  "audio/flac",
+	"opus":  "audio/opus",
+	"aac":   "audio/aac",
+	"pcm16": "audio/pcm",
+}
 
-	// Add accumulated tool calls to the message
-	for _, toolCall := range toolCalls {
-		out.Message.Parts = append(out.Message.Parts, *toolCall)
+// audioDelta is the audio of a streamed chunk, which the SDK does not decode.
+type audioDelta struct {
+	ID         string `json:"id"`
+	Data       string `json:"data"`
+	Transcript string `json:"transcript"`
+}
+
+// audioFormat returns the format audio output is requested in.
+func (c *Client) audioFormat(stream bool) string {
+	switch {
+	case c.AudioFormat != "":
+		return c.AudioFormat
+	case stream:
+		return "pcm16"
+	default:
+		return "wav"
 	}
+}
 
-	return out, nil
+func convertAudioResponse(audio openai.ChatCompletionAudio, format string) (llms.AudioPart, error) {
+	data, err := base64.StdEncoding.DecodeString(audio.Data)
+	if err != nil {
+		return llms.AudioPart{}, fmt.Errorf("openai: failed to decode audio: %w", err)
+	}
+
+	return llms.AudioPart{
+		MediaType:  audioMediaTypes[format],
+		Data:       data,
+		Transcript: audio.Transcript,
+		ID:         audio.ID,
+	}, nil
+}
+
+// SystemFingerprint returns the fingerprint of the backend configuration that
+// produced resp, or "" if resp did not come from OpenAI or has none.
+func SystemFingerprint(resp *llms.Response) string {
+	if resp == nil {
+		return ""
+	}
+
+	switch raw := resp.Raw.(type) {
+	case *openai.ChatCompletion:
+		return raw.SystemFingerprint
+	case openai.ChatCompletionChunk:
+		return raw.SystemFingerprint
+	}
+	return ""
+}
+
+// DecodeExtraField decodes a field the OpenAI SDK does not know about, such as
+// one added by an OpenAI-compatible server, into v, and reports whether it was
+// present. Such fields are not reported as valid by the SDK, so their raw JSON
+// is checked instead.
+func DecodeExtraField(field respjson.Field, v any) bool {
+	raw := field.Raw()
+	if raw == "" || raw == "null" {
+		return false
+	}
+	return json.Unmarshal([]byte(raw), v) == nil
+}
+
+func convertUsage(usage openai.CompletionUsage) *llms.Usage {
+	return &llms.Usage{
+		InputTokens:          int(usage.PromptTokens),
+		OutputTokens:         int(usage.CompletionTokens),
+		CacheReadInputTokens: int(usage.PromptTokensDetails.CachedTokens),
+		ReasoningTokens:      int(usage.CompletionTokensDetails.ReasoningTokens),
+	}
+}
+
+// wrapError converts errors returned by the openai SDK into *llms.APIError so
+// callers can inspect the status code without depending on the SDK.
+func wrapError(err error) error {
+	var apiErr *openai.Error
+	if errors.As(err, &apiErr) {
+		out := &llms.APIError{
+			Provider:   ProviderOpenAI,
+			StatusCode: apiErr.StatusCode,
+			Err:        err,
+		}
+		if apiErr.Response != nil {
+			out.RetryAfter = llms.ParseRetryAfter(apiErr.Response.Header)
+		}
+		return out
+	}
+	return err
 }
 
 func convertMessages(messages []llms.Message) ([]openai.ChatCompletionMessageParamUnion, error) {
@@ -297,53 +816,114 @@ func convertMessages(messages []llms.Message) ([]openai.ChatCompletionMessagePar
 				switch p := part.(type) {
 				case llms.TextPart:
 					content += p.Text
+				case llms.CachePointPart:
+					// OpenAI caches prompt prefixes automatically.
 				default:
 					return nil, fmt.Errorf("[message %d] openai: unsupported system message part type: %T", i, p)
 				}
 			}
-			
+
 			out = append(out, openai.SystemMessage(content))
 
 		case llms.RoleUser:
-			// Convert user message
+			// Tool results are sent as separate tool messages, which must
+			// directly follow the assistant message that made the calls, so
+			// they come before the rest of the user message.
 			content := ""
-			for _, part := range message.Parts {
+			hasToolResults := false
+			hasMedia := false
+			var contentParts []openai.ChatCompletionContentPartUnionParam
+			for j, part := range message.Parts {
 				switch p := part.(type) {
 				case llms.TextPart:
 					content += p.Text
+					contentParts = append(contentParts, openai.TextContentPart(p.Text))
+				case llms.ImagePart:
+					image, err := convertImage(p)
+					if err != nil {
+						return nil, fmt.Errorf("[message %d, part %d] openai: %w", i, j, err)
+					}
+					hasMedia = true
+					contentParts = append(contentParts, openai.ImageContentPart(image))
+				case llms.AudioPart:
+					audio, err := convertAudio(p)
+					if err != nil {
+						return nil, fmt.Errorf("[message %d, part %d] openai: %w", i, j, err)
+					}
+					hasMedia = true
+					contentParts = append(contentParts, openai.InputAudioContentPart(audio))
+				case llms.ToolResultPart:
+					hasToolResults = true
+					out = append(out, openai.ToolMessage(p.Result, p.ToolCallID))
+				case llms.CachePointPart:
+					// OpenAI caches prompt prefixes automatically.
 				default:
 					return nil, fmt.Errorf("[message %d] openai: unsupported user message part type: %T", i, p)
 				}
 			}
-			
-			out = append(out, openai.UserMessage(content))
+
+			switch {
+			case hasMedia:
+				out = append(out, openai.UserMessage(contentParts))
+			case content != "" || !hasToolResults:
+				out = append(out, openai.UserMessage(content))
+			}
 
 		case llms.RoleAssistant:
 			// Convert assistant message
 			content := ""
-			hasToolResults := false
-			
+			audioID := ""
+			var toolCalls []openai.ChatCompletionMessageToolCallParam
+			var toolResults []openai.ChatCompletionMessageParamUnion
+
 			for _, part := range message.Parts {
 				switch p := part.(type) {
 				case llms.TextPart:
 					content += p.Text
 				case llms.ToolCallPart:
-					// TODO: Handle tool calls properly
+					toolCalls = append(toolCalls, openai.ChatCompletionMessageToolCallParam{
+						ID: p.ID,
+						Function: openai.ChatCompletionMessageToolCallFunctionParam{
+							Name:      p.Name,
+							Arguments: toolArguments(p.Input),
+						},
+					})
 				case llms.ToolResultPart:
-					hasToolResults = true
-					// Tool results are handled as separate messages
-					out = append(out, openai.ToolMessage(p.Result, p.ToolCallID))
+					// Tool results must follow the assistant message that
+					// made the calls.
+					toolResults = append(toolResults, openai.ToolMessage(p.Result, p.ToolCallID))
+				case llms.CachePointPart:
+					// OpenAI caches prompt prefixes automatically.
+				case llms.AudioPart:
+					// Generated audio is referred to by ID. Audio without
+					// one, such as audio from another provider, is replaced
+					// by its transcript.
+					if p.ID != "" {
+						audioID = p.ID
+					} else {
+						content += p.Transcript
+					}
+				case llms.ThinkingPart:
+					// Reasoning from other providers cannot be replayed.
 				default:
 					return nil, fmt.Errorf("[message %d] openai: unsupported assistant message part type: %T", i, p)
 				}
 			}
-			
-			// Only add assistant message if there's content and no tool results
-			if content != "" && !hasToolResults {
-				out = append(out, openai.AssistantMessage(content))
+
+			if content != "" || audioID != "" || len(toolCalls) > 0 {
+				assistant := openai.ChatCompletionAssistantMessageParam{
+					ToolCalls: toolCalls,
+				}
+				if content != "" {
+					assistant.Content.OfString = openai.String(content)
+				}
+				if audioID != "" {
+					assistant.Audio.ID = audioID
+				}
+				out = append(out, openai.ChatCompletionMessageParamUnion{OfAssistant: &assistant})
 			}
-			
-			// TODO: Implement tool calls - this is complex due to the OpenAI SDK API structure
+
+			out = append(out, toolResults...)
 
 		default:
 			return nil, fmt.Errorf("[message %d] openai: unsupported message role: %s", i, message.Role)
@@ -353,6 +933,80 @@ func convertMessages(messages []llms.Message) ([]openai.ChatCompletionMessagePar
 	return out, nil
 }
 
+// convertImage converts an image to an image_url content part. Image data is
+// sent inline as a base64 data URL.
+func convertImage(p llms.ImagePart) (openai.ChatCompletionContentPartImageImageURLParam, error) {
+	image := openai.ChatCompletionContentPartImageImageURLParam{
+		URL:    p.URL,
+		Detail: p.Detail,
+	}
+
+	switch {
+	case len(p.Data) > 0:
+		mediaType := p.MediaType
+		if mediaType == "" {
+			mediaType = http.DetectContentType(p.Data)
+		}
+		image.URL = "data:" + mediaType + ";base64," + base64.StdEncoding.EncodeToString(p.Data)
+	case p.URL == "":
+		return image, errors.New("image has no data or URL")
+	}
+
+	return image, nil
+}
+
+// audioFormats maps the media types of audio OpenAI accepts as input to their
+// formats.
+var audioFormats = map[string]string{
+	"audio/wav":   "wav",
+	"audio/wave":  "wav",
+	"audio/x-wav": "wav",
+	"audio/mpeg":  "mp3",
+	"audio/mp3":   "mp3",
+}
+
+// convertAudio converts audio to an input_audio content part. OpenAI only
+// accepts inline WAV and MP3 audio.
+func convertAudio(p llms.AudioPart) (openai.ChatCompletionContentPartInputAudioInputAudioParam, error) {
+	if len(p.Data) == 0 {
+		return openai.ChatCompletionContentPartInputAudioInputAudioParam{}, errors.New("audio has no data")
+	}
+
+	mediaType := p.MediaType
+	if mediaType == "" {
+		mediaType = http.DetectContentType(p.Data)
+	}
+	format, ok := audioFormats[mediaType]
+	if !ok {
+		return openai.ChatCompletionContentPartInputAudioInputAudioParam{}, fmt.Errorf("unsupported audio type %q, must be WAV or MP3", mediaType)
+	}
+
+	return openai.ChatCompletionContentPartInputAudioInputAudioParam{
+		Data:   base64.StdEncoding.EncodeToString(p.Data),
+		Format: format,
+	}, nil
+}
+
+// toolArguments returns the JSON arguments of a tool call. OpenAI requires a
+// JSON object even for calls without arguments.
+func toolArguments(input []byte) string {
+	if len(input) == 0 {
+		return "{}"
+	}
+	return string(input)
+}
+
+// toolSchemas memoizes convertSchema, as tools are converted for every
+// request.
+var toolSchemas llms.SchemaCache[map[string]any]
+
+// convertSchema converts a tool's input schema into function parameters,
+// keeping every keyword, such as "$defs" for schemas using references and
+// "additionalProperties" for strict mode.
+func convertSchema(schema *jsonschema.Schema) (map[string]any, error) {
+	return llms.SchemaMap(schema)
+}
+
 func convertTools(tools []llms.Tool) ([]openai.ChatCompletionToolParam, error) {
 	if len(tools) == 0 {
 		return nil, nil
@@ -365,27 +1019,16 @@ func convertTools(tools []llms.Tool) ([]openai.ChatCompletionToolParam, error) {
 		if schema == nil {
 			return nil, fmt.Errorf("openai: tool %s has no schema", tool.Name())
 		}
-
-		// Convert jsonschema to map[string]interface{}
-		schemaMap := make(map[string]interface{})
-		if schema.Type != "" {
-			schemaMap["type"] = schema.Type
-		}
-		if schema.Properties != nil {
-			schemaMap["properties"] = schema.Properties
-		}
-		if schema.Required != nil {
-			schemaMap["required"] = schema.Required
-		}
-		if schema.Description != "" {
-			schemaMap["description"] = schema.Description
+		schemaMap, err := toolSchemas.Get(schema, convertSchema)
+		if err != nil {
+			return nil, fmt.Errorf("openai: tool %s: %w", tool.Name(), err)
 		}
 
 		out = append(out, openai.ChatCompletionToolParam{
 			Type: "function",
 			Function: openai.FunctionDefinitionParam{
 				Name:        tool.Name(),
-				Description: openai.String(tool.Description()),
+				Description: openai.String(llms.ToolDescription(tool)),
 				Parameters:  openai.FunctionParameters(schemaMap),
 			},
 		})
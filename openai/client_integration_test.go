@@ -6,7 +6,6 @@ package openai
 import (
 	"context"
 	"log"
-	"os"
 	"testing"
 
 	"github.com/llmite-ai/llms"
@@ -16,14 +15,11 @@ import (
 )
 
 func TestOpenAIClientIntegration(t *testing.T) {
-	apiKey := os.Getenv("OPENAI_API_KEY")
-	if apiKey == "" {
-		t.Skip("OPENAI_API_KEY not set, skipping integration test")
-	}
-
-	client := New(WithModel("gpt-4o-mini"))
+	testutil.RequireAPIKey(t, "OPENAI_API_KEY")
 
 	t.Run("simple generation", func(t *testing.T) {
+		client := New(WithModel("gpt-4o-mini"), WithHTTPClient(testutil.NewRecordingClient(t, "simple_generation")))
+
 		messages := []llms.Message{
 			{
 				Role: llms.RoleUser,
@@ -53,6 +49,8 @@ func TestOpenAIClientIntegration(t *testing.T) {
 	})
 
 	t.Run("system message", func(t *testing.T) {
+		client := New(WithModel("gpt-4o-mini"), WithHTTPClient(testutil.NewRecordingClient(t, "system_message")))
+
 		messages := []llms.Message{
 			{
 				Role: llms.RoleSystem,
@@ -84,6 +82,8 @@ func TestOpenAIClientIntegration(t *testing.T) {
 	})
 
 	t.Run("streaming", func(t *testing.T) {
+		client := New(WithModel("gpt-4o-mini"), WithHTTPClient(testutil.NewRecordingClient(t, "streaming")))
+
 		messages := []llms.Message{
 			{
 				Role: llms.RoleUser,
@@ -127,6 +127,7 @@ func TestOpenAIClientIntegration(t *testing.T) {
 		clientWithTools := New(
 			WithModel("gpt-4o-mini"),
 			WithTools(tools),
+			WithHTTPClient(testutil.NewRecordingClient(t, "tool_calling")),
 		)
 
 		messages := []llms.Message{
@@ -166,6 +167,7 @@ func TestOpenAIClientIntegration(t *testing.T) {
 		clientWithTools := New(
 			WithModel("gpt-4o-mini"),
 			WithTools(tools),
+			WithHTTPClient(testutil.NewRecordingClient(t, "conversation_with_tool_result")),
 		)
 
 		messages := []llms.Message{
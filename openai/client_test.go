@@ -1,10 +1,19 @@
 package openai
 
 import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 
 	"github.com/llmite-ai/llms"
 	"github.com/llmite-ai/llms/testutil"
+	"github.com/openai/openai-go"
+	"github.com/openai/openai-go/option"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -74,8 +83,13 @@ func TestConvertMessages(t *testing.T) {
 
 		result, err := convertMessages(messages)
 		require.NoError(t, err)
-		// Tool calls are not yet implemented, so we expect 0 messages
-		assert.Len(t, result, 0)
+		require.Len(t, result, 1)
+		require.NotNil(t, result[0].OfAssistant)
+		toolCalls := result[0].OfAssistant.ToolCalls
+		require.Len(t, toolCalls, 1)
+		assert.Equal(t, "call_123", toolCalls[0].ID)
+		assert.Equal(t, "get_weather", toolCalls[0].Function.Name)
+		assert.JSONEq(t, `{"location": "San Francisco"}`, toolCalls[0].Function.Arguments)
 	})
 
 	t.Run("tool result message", func(t *testing.T) {
@@ -126,8 +140,111 @@ func TestConvertMessages(t *testing.T) {
 
 		result, err := convertMessages(messages)
 		require.NoError(t, err)
-		// Tool calls are not yet implemented, so we expect 2 messages (system and user)
-		assert.Len(t, result, 2)
+		assert.Len(t, result, 3)
+	})
+
+	t.Run("user message with images", func(t *testing.T) {
+		png := []byte("\x89PNG\r\n\x1a\n")
+		messages := []llms.Message{
+			{
+				Role: llms.RoleUser,
+				Parts: []llms.Part{
+					llms.TextPart{Text: "Compare these."},
+					llms.ImagePart{Data: png},
+					llms.ImagePart{URL: "https://example.com/cat.jpg", Detail: llms.ImageDetailLow},
+				},
+			},
+		}
+
+		result, err := convertMessages(messages)
+		require.NoError(t, err)
+
+		data, err := json.Marshal(result)
+		require.NoError(t, err)
+		assert.JSONEq(t, `[{"role": "user", "content": [
+			{"type": "text", "text": "Compare these."},
+			{"type": "image_url", "image_url": {"url": "data:image/png;base64,iVBORw0KGgo="}},
+			{"type": "image_url", "image_url": {"url": "https://example.com/cat.jpg", "detail": "low"}}
+		]}]`, string(data))
+
+		_, err = convertMessages([]llms.Message{{Role: llms.RoleUser, Parts: []llms.Part{llms.ImagePart{}}}})
+		assert.ErrorContains(t, err, "image has no data or URL")
+	})
+
+	t.Run("audio", func(t *testing.T) {
+		wav := []byte("RIFF\x00\x00\x00\x00WAVEfmt ")
+		messages := []llms.Message{
+			{
+				Role: llms.RoleUser,
+				Parts: []llms.Part{
+					llms.TextPart{Text: "Transcribe this."},
+					llms.AudioPart{Data: wav},
+				},
+			},
+			{
+				Role:  llms.RoleAssistant,
+				Parts: []llms.Part{llms.AudioPart{ID: "audio_1", Transcript: "Hello"}},
+			},
+			{
+				Role:  llms.RoleAssistant,
+				Parts: []llms.Part{llms.AudioPart{Transcript: "Hi again"}},
+			},
+		}
+
+		result, err := convertMessages(messages)
+		require.NoError(t, err)
+
+		data, err := json.Marshal(result)
+		require.NoError(t, err)
+		assert.JSONEq(t, `[
+			{"role": "user", "content": [
+				{"type": "text", "text": "Transcribe this."},
+				{"type": "input_audio", "input_audio": {"data": "UklGRgAAAABXQVZFZm10IA==", "format": "wav"}}
+			]},
+			{"role": "assistant", "audio": {"id": "audio_1"}},
+			{"role": "assistant", "content": "Hi again"}
+		]`, string(data))
+
+		_, err = convertMessages([]llms.Message{{Role: llms.RoleUser, Parts: []llms.Part{llms.AudioPart{MediaType: "audio/ogg", Data: []byte("x")}}}})
+		assert.ErrorContains(t, err, `unsupported audio type "audio/ogg"`)
+	})
+
+	t.Run("tool conversation", func(t *testing.T) {
+		messages := []llms.Message{
+			llms.NewTextMessage(llms.RoleUser, "What's the weather?"),
+			{
+				Role: llms.RoleAssistant,
+				Parts: []llms.Part{
+					llms.TextPart{Text: "Let me check."},
+					llms.ToolCallPart{ID: "call_1", Name: "get_weather", Input: []byte(`{"location":"Paris"}`)},
+					llms.ToolCallPart{ID: "call_2", Name: "get_time"},
+				},
+			},
+			{
+				Role: llms.RoleUser,
+				Parts: []llms.Part{
+					llms.ToolResultPart{ToolCallID: "call_1", Name: "get_weather", Result: "Sunny"},
+					llms.ToolResultPart{ToolCallID: "call_2", Name: "get_time", Result: "Noon"},
+					llms.TextPart{Text: "Thanks"},
+				},
+			},
+		}
+
+		result, err := convertMessages(messages)
+		require.NoError(t, err)
+
+		data, err := json.Marshal(result)
+		require.NoError(t, err)
+		assert.JSONEq(t, `[
+			{"role": "user", "content": "What's the weather?"},
+			{"role": "assistant", "content": "Let me check.", "tool_calls": [
+				{"id": "call_1", "type": "function", "function": {"name": "get_weather", "arguments": "{\"location\":\"Paris\"}"}},
+				{"id": "call_2", "type": "function", "function": {"name": "get_time", "arguments": "{}"}}
+			]},
+			{"role": "tool", "tool_call_id": "call_1", "content": "Sunny"},
+			{"role": "tool", "tool_call_id": "call_2", "content": "Noon"},
+			{"role": "user", "content": "Thanks"}
+		]`, string(data))
 	})
 }
 
@@ -188,4 +305,499 @@ func TestClientDefaults(t *testing.T) {
 	assert.Nil(t, oaiClient.TopP)
 	assert.Nil(t, oaiClient.Tools)
 	assert.NotNil(t, oaiClient.client)
-}
\ No newline at end of file
+}
+func TestCountTokens(t *testing.T) {
+	messages := []llms.Message{
+		llms.NewTextMessage(llms.RoleUser, "12345678"),
+	}
+
+	client := New(WithModel("gpt-4o")).(*Client)
+	count, err := client.CountTokens(context.Background(), messages)
+	require.NoError(t, err)
+	// 3 for "123", "456", "78" + 1 for the role + 3 for the message + 3 for
+	// the reply.
+	assert.Equal(t, 10, count)
+
+	// Tool definitions from the client and its registry are counted.
+	registry, err := llms.NewToolRegistry(&testutil.BoopTool{})
+	require.NoError(t, err)
+	client = New(WithModel("gpt-4o"), WithTools([]llms.Tool{testutil.WeatherTool{}}), WithToolRegistry(registry)).(*Client)
+	withTools, err := client.CountTokens(context.Background(), messages)
+	require.NoError(t, err)
+	enc, err := encodingForModel("gpt-4o")
+	require.NoError(t, err)
+	definitions := 0
+	for _, tool := range []llms.Tool{testutil.WeatherTool{}, &testutil.BoopTool{}} {
+		schemaMap, err := llms.SchemaMap(tool.Schema())
+		require.NoError(t, err)
+		parameters, err := json.Marshal(schemaMap)
+		require.NoError(t, err)
+		definitions += len(enc.EncodeOrdinary(tool.Name())) +
+			len(enc.EncodeOrdinary(llms.ToolDescription(tool))) +
+			len(enc.EncodeOrdinary(string(parameters)))
+	}
+	assert.Greater(t, definitions, 0)
+	assert.Equal(t, count+definitions, withTools)
+
+	// Tools filtered out of the call are not.
+	ctx := llms.WithCallOptions(context.Background(), llms.AllowTools(testutil.WeatherTool{}.Name()))
+	filtered, err := client.CountTokens(ctx, messages)
+	require.NoError(t, err)
+	assert.Less(t, filtered, withTools)
+	assert.Greater(t, filtered, count)
+
+	assert.Equal(t, "o200k_base", encodingName("gpt-4o-2024-08-06"))
+	assert.Equal(t, "cl100k_base", encodingName("gpt-4"))
+	assert.Equal(t, "o200k_base", encodingName("o3-mini"))
+}
+
+func newTestClient(t *testing.T, handler http.HandlerFunc, mods ...Modifier) *Client {
+	t.Helper()
+
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	mods = append([]Modifier{
+		WithOpenAIClientOptions(
+			option.WithBaseURL(server.URL),
+			option.WithAPIKey("test"),
+			option.WithMaxRetries(0),
+		),
+	}, mods...)
+
+	return New(mods...).(*Client)
+}
+
+func TestBuildRequest_ReasoningModel(t *testing.T) {
+	messages := []llms.Message{llms.NewTextMessage(llms.RoleUser, "hi")}
+	mods := []Modifier{WithMaxTokens(2048), WithTemperature(0.5), WithTopP(0.9), WithReasoningEffort("high")}
+
+	reasoning := New(append(mods, WithModel("o4-mini"))...).(*Client)
+	params, err := reasoning.BuildRequest(context.Background(), messages)
+	require.NoError(t, err)
+	data, err := json.Marshal(params)
+	require.NoError(t, err)
+
+	var body map[string]any
+	require.NoError(t, json.Unmarshal(data, &body))
+	assert.Equal(t, float64(2048), body["max_completion_tokens"])
+	assert.Equal(t, "high", body["reasoning_effort"])
+	assert.NotContains(t, body, "max_tokens")
+	assert.NotContains(t, body, "temperature")
+	assert.NotContains(t, body, "top_p")
+
+	chat := New(append(mods, WithModel("gpt-4o"))...).(*Client)
+	params, err = chat.BuildRequest(context.Background(), messages)
+	require.NoError(t, err)
+	data, err = json.Marshal(params)
+	require.NoError(t, err)
+
+	body = nil
+	require.NoError(t, json.Unmarshal(data, &body))
+	assert.Equal(t, float64(2048), body["max_tokens"])
+	assert.Equal(t, 0.5, body["temperature"])
+	assert.NotContains(t, body, "max_completion_tokens")
+}
+
+func TestGenerate_Usage(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{
+			"id": "chatcmpl_1",
+			"object": "chat.completion",
+			"model": "o4-mini",
+			"choices": [{"index": 0, "finish_reason": "stop", "message": {"role": "assistant", "content": "Hi"}}],
+			"usage": {
+				"prompt_tokens": 100,
+				"completion_tokens": 50,
+				"total_tokens": 150,
+				"prompt_tokens_details": {"cached_tokens": 80},
+				"completion_tokens_details": {"reasoning_tokens": 30}
+			}
+		}`))
+	}, WithModel("o4-mini"))
+
+	resp, err := client.Generate(context.Background(), []llms.Message{llms.NewTextMessage(llms.RoleUser, "Hello")})
+	require.NoError(t, err)
+	assert.Equal(t, &llms.Usage{
+		InputTokens:          100,
+		OutputTokens:         50,
+		CacheReadInputTokens: 80,
+		ReasoningTokens:      30,
+	}, resp.Usage)
+}
+
+func TestGenerateStream_Usage(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			StreamOptions json.RawMessage `json:"stream_options"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		assert.JSONEq(t, `{"include_usage": true}`, string(body.StreamOptions))
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, chunk := range []string{
+			`{"id":"chatcmpl_1","object":"chat.completion.chunk","choices":[{"index":0,"delta":{"role":"assistant","content":"Hi"}}],"usage":null}`,
+			`{"id":"chatcmpl_1","object":"chat.completion.chunk","choices":[{"index":0,"delta":{},"finish_reason":"stop"}],"usage":null}`,
+			`{"id":"chatcmpl_1","object":"chat.completion.chunk","choices":[],"usage":{"prompt_tokens":10,"completion_tokens":2,"total_tokens":12}}`,
+			`[DONE]`,
+		} {
+			fmt.Fprintf(w, "data: %s\n\n", chunk)
+		}
+	})
+
+	resp, err := client.GenerateStream(context.Background(), []llms.Message{llms.NewTextMessage(llms.RoleUser, "Hello")},
+		func(resp *llms.Response, err error) bool { return true })
+	require.NoError(t, err)
+	assert.Equal(t, &llms.Usage{InputTokens: 10, OutputTokens: 2}, resp.Usage)
+
+	// The usage chunk is kept as Raw, so extra usage fields can be read.
+	raw, ok := resp.Raw.(openai.ChatCompletionChunk)
+	require.True(t, ok)
+	assert.Equal(t, int64(12), raw.Usage.TotalTokens)
+}
+
+func TestGenerate_Seed(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Seed int `json:"seed"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		assert.Equal(t, 42, body.Seed)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{
+			"id": "chatcmpl_1",
+			"object": "chat.completion",
+			"system_fingerprint": "fp_123",
+			"choices": [{"index": 0, "finish_reason": "stop", "message": {"role": "assistant", "content": "Hi"}}]
+		}`))
+	}, WithSeed(42))
+
+	resp, err := client.Generate(context.Background(), []llms.Message{llms.NewTextMessage(llms.RoleUser, "Hello")})
+	require.NoError(t, err)
+	assert.Equal(t, "fp_123", SystemFingerprint(resp))
+	assert.Equal(t, "", SystemFingerprint(&llms.Response{}))
+}
+
+func TestBuildRequest_ToolChoice(t *testing.T) {
+	tools := WithTools([]llms.Tool{&testutil.WeatherTool{}})
+	messages := []llms.Message{llms.NewTextMessage(llms.RoleUser, "hi")}
+
+	tests := []struct {
+		choice   string
+		name     string
+		expected string
+		err      string
+	}{
+		{choice: llms.ToolChoiceAuto, expected: `"auto"`},
+		{choice: llms.ToolChoiceAny, expected: `"required"`},
+		{choice: llms.ToolChoiceTool, name: "get_weather", expected: `{"type":"function","function":{"name":"get_weather"}}`},
+		{choice: llms.ToolChoiceNone, expected: `"none"`},
+		{choice: llms.ToolChoiceTool, err: `tool choice "tool" requires a tool name`},
+		{choice: "sometimes", err: `unsupported tool choice: "sometimes"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.choice+tt.name, func(t *testing.T) {
+			client := New(tools, WithToolChoice(tt.choice, tt.name)).(*Client)
+			params, err := client.BuildRequest(context.Background(), messages)
+			if tt.err != "" {
+				assert.ErrorContains(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+
+			data, err := json.Marshal(params.ToolChoice)
+			require.NoError(t, err)
+			assert.JSONEq(t, tt.expected, string(data))
+		})
+	}
+}
+
+func TestBuildRequest_ToolCallOptions(t *testing.T) {
+	messages := []llms.Message{llms.NewTextMessage(llms.RoleUser, "hi")}
+	client := New(WithTools([]llms.Tool{&testutil.WeatherTool{}}), WithParallelToolCalls(false)).(*Client)
+
+	body := func(t *testing.T, ctx context.Context, client *Client) map[string]any {
+		t.Helper()
+		params, err := client.BuildRequest(ctx, messages)
+		require.NoError(t, err)
+		data, err := json.Marshal(params)
+		require.NoError(t, err)
+		var out map[string]any
+		require.NoError(t, json.Unmarshal(data, &out))
+		return out
+	}
+
+	ctx := context.Background()
+	assert.Equal(t, false, body(t, ctx, client)["parallel_tool_calls"])
+	assert.NotContains(t, body(t, ctx, client), "tool_choice")
+
+	// Per-call options override the client settings.
+	ctx = llms.WithCallOptions(ctx,
+		llms.ParallelToolCalls(true),
+		llms.ToolChoiceOption(llms.ToolChoice{Type: llms.ToolChoiceAny}),
+	)
+	got := body(t, ctx, client)
+	assert.Equal(t, true, got["parallel_tool_calls"])
+	assert.Equal(t, "required", got["tool_choice"])
+
+	// Without tools, OpenAI rejects both settings, so they are not sent.
+	got = body(t, ctx, New(WithParallelToolCalls(false)).(*Client))
+	assert.NotContains(t, got, "parallel_tool_calls")
+	assert.NotContains(t, got, "tool_choice")
+}
+
+func TestBuildRequest_SchemaReferences(t *testing.T) {
+	client := New(WithTools([]llms.Tool{testutil.RouteTool{}})).(*Client)
+
+	req, err := client.BuildRequest(context.Background(), []llms.Message{llms.NewTextMessage(llms.RoleUser, "Route?")})
+	require.NoError(t, err)
+	data, err := json.Marshal(req)
+	require.NoError(t, err)
+	var body map[string]any
+	require.NoError(t, json.Unmarshal(data, &body))
+
+	// The definitions the properties refer to are sent with them.
+	params := body["tools"].([]any)[0].(map[string]any)["function"].(map[string]any)["parameters"].(map[string]any)
+	assert.Equal(t, false, params["additionalProperties"])
+	assert.Contains(t, params["$defs"], "RoutePlace")
+	assert.NotContains(t, params, "$schema")
+	from := params["properties"].(map[string]any)["from"].(map[string]any)
+	assert.Equal(t, "#/$defs/RoutePlace", from["$ref"])
+}
+
+func TestGenerate_AudioOutput(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Modalities []string        `json:"modalities"`
+			Audio      json.RawMessage `json:"audio"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		assert.Equal(t, []string{"text", "audio"}, body.Modalities)
+		assert.JSONEq(t, `{"voice": "alloy", "format": "mp3"}`, string(body.Audio))
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{
+			"id": "chatcmpl_1",
+			"object": "chat.completion",
+			"choices": [{"index": 0, "finish_reason": "stop", "message": {
+				"role": "assistant",
+				"content": null,
+				"audio": {"id": "audio_1", "data": "SUQz", "expires_at": 1700000000, "transcript": "Hello"}
+			}}]
+		}`))
+	}, WithModel("gpt-4o-audio-preview"), WithAudioOutput("alloy", "mp3"))
+
+	resp, err := client.Generate(context.Background(), []llms.Message{llms.NewTextMessage(llms.RoleUser, "Say hello")})
+	require.NoError(t, err)
+	assert.Equal(t, []llms.Part{llms.AudioPart{
+		MediaType:  "audio/mpeg",
+		Data:       []byte("ID3"),
+		Transcript: "Hello",
+		ID:         "audio_1",
+	}}, resp.Message.Parts)
+}
+
+func TestGenerateStream_AudioOutput(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Audio json.RawMessage `json:"audio"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		// Streamed audio defaults to the only format it can be streamed in.
+		assert.JSONEq(t, `{"voice": "alloy", "format": "pcm16"}`, string(body.Audio))
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, chunk := range []string{
+			`{"id":"chatcmpl_1","object":"chat.completion.chunk","choices":[{"index":0,"delta":{"role":"assistant","audio":{"id":"audio_1","transcript":"Hel"}}}]}`,
+			`{"id":"chatcmpl_1","object":"chat.completion.chunk","choices":[{"index":0,"delta":{"audio":{"data":"AAE=","transcript":"lo"}}}]}`,
+			`{"id":"chatcmpl_1","object":"chat.completion.chunk","choices":[{"index":0,"delta":{"audio":{"data":"AgM="}}}]}`,
+			`[DONE]`,
+		} {
+			fmt.Fprintf(w, "data: %s\n\n", chunk)
+		}
+	}, WithModel("gpt-4o-audio-preview"), WithAudioOutput("alloy", ""))
+
+	resp, err := client.GenerateStream(context.Background(), []llms.Message{llms.NewTextMessage(llms.RoleUser, "Say hello")},
+		func(resp *llms.Response, err error) bool { return true })
+	require.NoError(t, err)
+	assert.Equal(t, []llms.Part{llms.AudioPart{
+		MediaType:  "audio/pcm",
+		Data:       []byte{0, 1, 2, 3},
+		Transcript: "Hello",
+		ID:         "audio_1",
+	}}, resp.Message.Parts)
+}
+
+func TestGenerate_AudioOutputDefaults(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Audio json.RawMessage `json:"audio"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		assert.JSONEq(t, `{"voice": "verse", "format": "wav"}`, string(body.Audio))
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id": "chatcmpl_1", "object": "chat.completion", "choices": [{"index": 0, "finish_reason": "stop", "message": {"role": "assistant", "content": null, "audio": {"id": "audio_1", "data": "UklGRg==", "expires_at": 1700000000, "transcript": "Hi"}}}]}`))
+	}, WithModel("gpt-4o-audio-preview"), WithAudioOutput("verse", ""))
+
+	resp, err := client.Generate(context.Background(), []llms.Message{llms.NewTextMessage(llms.RoleUser, "Say hi")})
+	require.NoError(t, err)
+	// The media type is that of the format sent.
+	assert.Equal(t, "audio/wav", resp.Message.Parts[0].(llms.AudioPart).MediaType)
+}
+
+func TestWithBaseURL(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/chat/completions", r.URL.Path)
+		assert.Equal(t, "Bearer local-key", r.Header.Get("Authorization"))
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{
+			"id": "chatcmpl_1",
+			"object": "chat.completion",
+			"choices": [{"index": 0, "finish_reason": "stop", "message": {"role": "assistant", "content": "Hi"}}]
+		}`))
+	}))
+	t.Cleanup(server.Close)
+
+	client := New(WithBaseURL(server.URL+"/v1/"), WithAPIKey("local-key"), WithModel("llama3.2"))
+
+	resp, err := client.Generate(context.Background(), []llms.Message{llms.NewTextMessage(llms.RoleUser, "Hello")})
+	require.NoError(t, err)
+	assert.Equal(t, []llms.Part{llms.TextPart{Text: "Hi"}}, resp.Message.Parts)
+}
+
+func TestGenerateStream_Accumulates(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, chunk := range []string{
+			`{"id":"chatcmpl_1","object":"chat.completion.chunk","choices":[{"index":0,"delta":{"role":"assistant","content":"Let "}}]}`,
+			`{"id":"chatcmpl_1","object":"chat.completion.chunk","choices":[{"index":0,"delta":{"content":"me check."}}]}`,
+			`{"id":"chatcmpl_1","object":"chat.completion.chunk","choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"id":"call_1","type":"function","function":{"name":"get_weather","arguments":""}}]}}]}`,
+			`{"id":"chatcmpl_1","object":"chat.completion.chunk","choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"function":{"arguments":"{\"location\":"}}]}}]}`,
+			`{"id":"chatcmpl_1","object":"chat.completion.chunk","choices":[{"index":0,"delta":{"tool_calls":[{"index":1,"id":"call_2","type":"function","function":{"name":"get_time","arguments":"{}"}}]}}]}`,
+			`{"id":"chatcmpl_1","object":"chat.completion.chunk","choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"function":{"arguments":"\"Paris\"}"}}]}}]}`,
+			`{"id":"chatcmpl_1","object":"chat.completion.chunk","choices":[{"index":0,"delta":{},"finish_reason":"tool_calls"}]}`,
+			`[DONE]`,
+		} {
+			fmt.Fprintf(w, "data: %s\n\n", chunk)
+		}
+	})
+
+	var texts, deltas []string
+	resp, err := client.GenerateStream(context.Background(), []llms.Message{llms.NewTextMessage(llms.RoleUser, "Weather?")},
+		func(resp *llms.Response, err error) bool {
+			require.NoError(t, err)
+			// The text is a single growing part.
+			text, ok := resp.Message.Parts[0].(llms.TextPart)
+			require.True(t, ok)
+			texts = append(texts, text.Text)
+			deltas = append(deltas, resp.Raw.(openai.ChatCompletionChunk).Choices[0].Delta.Content)
+			return true
+		})
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"Let ", "Let me check.", "Let me check.", "Let me check.", "Let me check.", "Let me check."}, texts)
+	assert.Equal(t, []string{"Let ", "me check.", "", "", "", ""}, deltas)
+	assert.Equal(t, []llms.Part{
+		llms.TextPart{Text: "Let me check."},
+		llms.ToolCallPart{ID: "call_1", Name: "get_weather", Input: []byte(`{"location":"Paris"}`)},
+		llms.ToolCallPart{ID: "call_2", Name: "get_time", Input: []byte(`{}`)},
+	}, resp.Message.Parts)
+}
+
+func TestGenerateStream_Stop(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, chunk := range []string{
+			`{"id":"chatcmpl_1","object":"chat.completion.chunk","choices":[{"index":0,"delta":{"content":"One"}}]}`,
+			`{"id":"chatcmpl_1","object":"chat.completion.chunk","choices":[{"index":0,"delta":{"content":" two"}}]}`,
+			`[DONE]`,
+		} {
+			fmt.Fprintf(w, "data: %s\n\n", chunk)
+		}
+	})
+
+	resp, err := client.GenerateStream(context.Background(), []llms.Message{llms.NewTextMessage(llms.RoleUser, "Count")},
+		func(resp *llms.Response, err error) bool { return false })
+	require.NoError(t, err)
+	assert.Equal(t, []llms.Part{llms.TextPart{Text: "One"}}, resp.Message.Parts)
+}
+
+func TestBuildRequest_Sampling(t *testing.T) {
+	messages := []llms.Message{llms.NewTextMessage(llms.RoleUser, "hi")}
+	client := New(
+		WithStopSequences("END"),
+		WithFrequencyPenalty(0.5),
+		WithPresencePenalty(0.25),
+		WithLogitBias(map[string]int{"50256": -100}),
+	).(*Client)
+
+	body := func(t *testing.T, ctx context.Context) map[string]any {
+		t.Helper()
+		params, err := client.BuildRequest(ctx, messages)
+		require.NoError(t, err)
+		data, err := json.Marshal(params)
+		require.NoError(t, err)
+		var out map[string]any
+		require.NoError(t, json.Unmarshal(data, &out))
+		return out
+	}
+
+	got := body(t, context.Background())
+	assert.Equal(t, []any{"END"}, got["stop"])
+	assert.Equal(t, 0.5, got["frequency_penalty"])
+	assert.Equal(t, 0.25, got["presence_penalty"])
+	assert.Equal(t, map[string]any{"50256": float64(-100)}, got["logit_bias"])
+
+	// Per-call options override the client settings.
+	got = body(t, llms.WithCallOptions(context.Background(),
+		llms.StopSequences("STOP", "DONE"),
+		llms.FrequencyPenalty(-1),
+		llms.PresencePenalty(1),
+		llms.LogitBias(map[string]int{"1": 5}),
+	))
+	assert.Equal(t, []any{"STOP", "DONE"}, got["stop"])
+	assert.Equal(t, float64(-1), got["frequency_penalty"])
+	assert.Equal(t, float64(1), got["presence_penalty"])
+	assert.Equal(t, map[string]any{"1": float64(5)}, got["logit_bias"])
+}
+
+func TestGenerate_RequestID(t *testing.T) {
+	var received string
+	httpClient := llms.NewHTTPClient(llms.HTTPClientOptions{
+		Logger: slog.New(slog.NewJSONHandler(io.Discard, nil)),
+	})
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		received = r.Header.Get(llms.DefaultRequestIDHeader)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id": "chatcmpl_1", "choices": [{"index": 0, "message": {"role": "assistant", "content": "Hi"}}]}`))
+	}, WithHTTPClient(httpClient))
+
+	messages := []llms.Message{llms.NewTextMessage(llms.RoleUser, "Hello")}
+	resp, err := client.Generate(llms.WithRequestID(context.Background(), "req-1"), messages)
+	require.NoError(t, err)
+	assert.Equal(t, "req-1", resp.RequestID)
+	assert.Equal(t, "req-1", received)
+
+	resp, err = client.Generate(context.Background(), messages)
+	require.NoError(t, err)
+	assert.NotEmpty(t, resp.RequestID)
+	assert.Equal(t, resp.RequestID, received)
+}
+
+func TestDecodeExtraField(t *testing.T) {
+	var completion openai.ChatCompletion
+	require.NoError(t, json.Unmarshal([]byte(`{"id": "chatcmpl_1", "provider": "Groq", "cost": null}`), &completion))
+
+	var provider string
+	assert.True(t, DecodeExtraField(completion.JSON.ExtraFields["provider"], &provider))
+	assert.Equal(t, "Groq", provider)
+
+	var cost float64
+	assert.False(t, DecodeExtraField(completion.JSON.ExtraFields["cost"], &cost))
+	assert.False(t, DecodeExtraField(completion.JSON.ExtraFields["missing"], &cost))
+}
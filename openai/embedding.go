@@ -0,0 +1,101 @@
+package openai
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/openai/openai-go"
+
+	"github.com/llmite-ai/llms"
+)
+
+// DefaultEmbeddingModel is the model used by Embed and EmbedBatch unless the
+// client's EmbeddingModel is set.
+const DefaultEmbeddingModel = "text-embedding-3-small"
+
+// maxEmbeddingBatch is the most texts the OpenAI API embeds in one request.
+const maxEmbeddingBatch = 2048
+
+// embeddingDimensions holds the default dimensions of OpenAI's embedding
+// models.
+var embeddingDimensions = map[string]int{
+	"text-embedding-3-small": 1536,
+	"text-embedding-3-large": 3072,
+	"text-embedding-ada-002": 1536,
+}
+
+var _ llms.Embedder = (*Client)(nil)
+
+// Embed returns the embedding of text. See EmbedBatch.
+func (c *Client) Embed(ctx context.Context, text string) ([]float32, error) {
+	embeddings, err := c.EmbedBatch(ctx, []string{text})
+	if err != nil {
+		return nil, err
+	}
+	return embeddings[0], nil
+}
+
+// EmbedBatch returns the embeddings of texts with the client's
+// EmbeddingModel, in requests of up to 2048 texts.
+func (c *Client) EmbedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	out := make([][]float32, 0, len(texts))
+	for start := 0; start < len(texts); start += maxEmbeddingBatch {
+		batch := texts[start:min(start+maxEmbeddingBatch, len(texts))]
+		embeddings, err := c.embed(ctx, batch)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, embeddings...)
+	}
+	return out, nil
+}
+
+// embed returns the embeddings of texts in a single request.
+func (c *Client) embed(ctx context.Context, texts []string) ([][]float32, error) {
+	params := openai.EmbeddingNewParams{
+		Model:          openai.EmbeddingModel(c.embeddingModel()),
+		Input:          openai.EmbeddingNewParamsInputUnion{OfArrayOfStrings: texts},
+		EncodingFormat: openai.EmbeddingNewParamsEncodingFormatFloat,
+	}
+	if c.EmbeddingDimensions > 0 {
+		params.Dimensions = openai.Int(int64(c.EmbeddingDimensions))
+	}
+
+	resp, err := c.client.Embeddings.New(ctx, params)
+	if err != nil {
+		return nil, fmt.Errorf("openai: failed to create embeddings: %w", wrapError(err))
+	}
+	if len(resp.Data) != len(texts) {
+		return nil, fmt.Errorf("openai: got %d embeddings for %d texts", len(resp.Data), len(texts))
+	}
+
+	out := make([][]float32, len(texts))
+	for _, e := range resp.Data {
+		if e.Index < 0 || int(e.Index) >= len(out) {
+			return nil, fmt.Errorf("openai: embedding index %d out of range", e.Index)
+		}
+		vector := make([]float32, len(e.Embedding))
+		for i, v := range e.Embedding {
+			vector[i] = float32(v)
+		}
+		out[e.Index] = vector
+	}
+	return out, nil
+}
+
+// Dimensions returns the length of the embeddings, which is
+// EmbeddingDimensions if set, or the default of the embedding model if it is
+// known.
+func (c *Client) Dimensions() int {
+	if c.EmbeddingDimensions > 0 {
+		return c.EmbeddingDimensions
+	}
+	return embeddingDimensions[c.embeddingModel()]
+}
+
+func (c *Client) embeddingModel() string {
+	if c.EmbeddingModel != "" {
+		return c.EmbeddingModel
+	}
+	return DefaultEmbeddingModel
+}
@@ -0,0 +1,90 @@
+package openai
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEmbedBatch(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/embeddings", r.URL.Path)
+
+		var body map[string]any
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		assert.Equal(t, "text-embedding-3-large", body["model"])
+		assert.Equal(t, []any{"one", "two"}, body["input"])
+		assert.Equal(t, float64(2), body["dimensions"])
+
+		// The embeddings are returned out of order.
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{
+			"object": "list",
+			"model": "text-embedding-3-large",
+			"data": [
+				{"object": "embedding", "index": 1, "embedding": [0.3, 0.4]},
+				{"object": "embedding", "index": 0, "embedding": [0.1, 0.2]}
+			],
+			"usage": {"prompt_tokens": 2, "total_tokens": 2}
+		}`))
+	}, WithEmbeddingModel("text-embedding-3-large"), WithEmbeddingDimensions(2))
+
+	embeddings, err := client.EmbedBatch(context.Background(), []string{"one", "two"})
+	require.NoError(t, err)
+	assert.Equal(t, [][]float32{{0.1, 0.2}, {0.3, 0.4}}, embeddings)
+	assert.Equal(t, 2, client.Dimensions())
+
+	embeddings, err = client.EmbedBatch(context.Background(), nil)
+	require.NoError(t, err)
+	assert.Empty(t, embeddings)
+}
+
+func TestEmbed(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		assert.Equal(t, DefaultEmbeddingModel, body["model"])
+		assert.NotContains(t, body, "dimensions")
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"object": "list", "model": "m", "data": [{"object": "embedding", "index": 0, "embedding": [1, 0]}], "usage": {}}`))
+	})
+
+	embedding, err := client.Embed(context.Background(), "hello")
+	require.NoError(t, err)
+	assert.Equal(t, []float32{1, 0}, embedding)
+	assert.Equal(t, 1536, client.Dimensions())
+}
+
+func TestEmbedBatch_Chunked(t *testing.T) {
+	var sizes []int
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Input []string `json:"input"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		sizes = append(sizes, len(body.Input))
+
+		data := make([]map[string]any, len(body.Input))
+		for i, text := range body.Input {
+			data[i] = map[string]any{"object": "embedding", "index": i, "embedding": []float64{float64(len(text))}}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{"object": "list", "model": "m", "data": data, "usage": map[string]any{}})
+	})
+
+	texts := make([]string, maxEmbeddingBatch+1)
+	for i := range texts {
+		texts[i] = strings.Repeat("x", i%7)
+	}
+	embeddings, err := client.EmbedBatch(context.Background(), texts)
+	require.NoError(t, err)
+	assert.Equal(t, []int{maxEmbeddingBatch, 1}, sizes)
+	require.Len(t, embeddings, len(texts))
+	assert.Equal(t, []float32{float32(maxEmbeddingBatch % 7)}, embeddings[maxEmbeddingBatch])
+}
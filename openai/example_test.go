@@ -86,7 +86,7 @@ func ExampleClient_GenerateStream() {
 	}
 }
 
-func ExampleClient_WithLogging() {
+func ExampleWithHttpLogging() {
 	// Create OpenAI client with HTTP logging
 	client := openai.New(
 		openai.WithModel("gpt-4o-mini"),
@@ -0,0 +1,86 @@
+package openai
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"github.com/openai/openai-go"
+
+	"github.com/llmite-ai/llms"
+)
+
+// DefaultImageModel is the model used by GenerateImages unless the client's
+// ImageModel is set.
+const DefaultImageModel = "gpt-image-1"
+
+// imageFormats maps the MIME types of llms.ImageRequest to OpenAI's output
+// formats.
+var imageFormats = map[string]openai.ImageGenerateParamsOutputFormat{
+	"image/png":  openai.ImageGenerateParamsOutputFormatPNG,
+	"image/jpeg": openai.ImageGenerateParamsOutputFormatJPEG,
+	"image/webp": openai.ImageGenerateParamsOutputFormatWebP,
+}
+
+var _ llms.ImageGenerator = (*Client)(nil)
+
+// GenerateImages generates images with the client's ImageModel. The DALL·E
+// models only generate PNG images.
+func (c *Client) GenerateImages(ctx context.Context, req llms.ImageRequest) ([]llms.ImagePart, error) {
+	model := c.imageModel()
+	params := openai.ImageGenerateParams{
+		Prompt: req.Prompt,
+		Model:  openai.ImageModel(model),
+		Size:   openai.ImageGenerateParamsSize(req.Size),
+	}
+	if req.Count > 0 {
+		params.N = openai.Int(int64(req.Count))
+	}
+
+	// The DALL·E models return URLs unless asked for the data, and take no
+	// output format, while the GPT image models always return the data.
+	mediaType := "image/png"
+	if strings.HasPrefix(model, "dall-e") {
+		if req.Format != "" && req.Format != mediaType {
+			return nil, fmt.Errorf("openai: %s does not generate %s images", model, req.Format)
+		}
+		params.ResponseFormat = openai.ImageGenerateParamsResponseFormatB64JSON
+	} else if req.Format != "" {
+		format, ok := imageFormats[req.Format]
+		if !ok {
+			return nil, fmt.Errorf("openai: unsupported image format %q", req.Format)
+		}
+		params.OutputFormat = format
+		mediaType = req.Format
+	}
+
+	resp, err := c.client.Images.Generate(ctx, params)
+	if err != nil {
+		return nil, fmt.Errorf("openai: failed to generate images: %w", wrapError(err))
+	}
+	if resp.OutputFormat != "" {
+		mediaType = "image/" + string(resp.OutputFormat)
+	}
+
+	images := make([]llms.ImagePart, 0, len(resp.Data))
+	for _, image := range resp.Data {
+		if image.B64JSON == "" {
+			images = append(images, llms.ImagePart{MediaType: mediaType, URL: image.URL})
+			continue
+		}
+		data, err := base64.StdEncoding.DecodeString(image.B64JSON)
+		if err != nil {
+			return nil, fmt.Errorf("openai: failed to decode image: %w", err)
+		}
+		images = append(images, llms.ImagePart{MediaType: mediaType, Data: data})
+	}
+	return images, nil
+}
+
+func (c *Client) imageModel() string {
+	if c.ImageModel != "" {
+		return c.ImageModel
+	}
+	return DefaultImageModel
+}
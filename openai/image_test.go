@@ -0,0 +1,78 @@
+package openai
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/llmite-ai/llms"
+)
+
+func TestGenerateImages(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/images/generations", r.URL.Path)
+
+		var body map[string]any
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		assert.Equal(t, map[string]any{
+			"prompt":        "a red fox",
+			"model":         DefaultImageModel,
+			"size":          "1536x1024",
+			"n":             float64(2),
+			"output_format": "jpeg",
+		}, body)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{
+			"created": 1,
+			"output_format": "jpeg",
+			"data": [{"b64_json": "b25l"}, {"b64_json": "dHdv"}]
+		}`))
+	})
+
+	images, err := client.GenerateImages(context.Background(), llms.ImageRequest{
+		Prompt: "a red fox",
+		Size:   "1536x1024",
+		Count:  2,
+		Format: "image/jpeg",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []llms.ImagePart{
+		{MediaType: "image/jpeg", Data: []byte("one")},
+		{MediaType: "image/jpeg", Data: []byte("two")},
+	}, images)
+}
+
+func TestGenerateImages_DallE(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		assert.Equal(t, "dall-e-3", body["model"])
+		assert.Equal(t, "b64_json", body["response_format"])
+		assert.NotContains(t, body, "output_format")
+		assert.NotContains(t, body, "size")
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"created": 1, "data": [{"b64_json": "b25l", "revised_prompt": "a fox"}]}`))
+	}, WithImageModel("dall-e-3"))
+
+	images, err := client.GenerateImages(context.Background(), llms.ImageRequest{Prompt: "a fox"})
+	require.NoError(t, err)
+	assert.Equal(t, []llms.ImagePart{{MediaType: "image/png", Data: []byte("one")}}, images)
+
+	_, err = client.GenerateImages(context.Background(), llms.ImageRequest{Prompt: "a fox", Format: "image/jpeg"})
+	assert.EqualError(t, err, "openai: dall-e-3 does not generate image/jpeg images")
+}
+
+func TestGenerateImages_UnsupportedFormat(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		t.Error("unexpected request")
+	})
+
+	_, err := client.GenerateImages(context.Background(), llms.ImageRequest{Prompt: "a fox", Format: "image/gif"})
+	assert.EqualError(t, err, `openai: unsupported image format "image/gif"`)
+}
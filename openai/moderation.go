@@ -0,0 +1,229 @@
+package openai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/openai/openai-go"
+
+	"github.com/llmite-ai/llms"
+)
+
+// DefaultModerationModel is the model used by Moderate unless the client's
+// ModerationModel is set.
+const DefaultModerationModel = "omni-moderation-latest"
+
+// ModerationResult is the classification of content by the moderation
+// endpoint.
+type ModerationResult struct {
+	// Flagged is true if the content was flagged in any category.
+	Flagged bool
+	// Categories lists the flagged categories, such as "harassment" or
+	// "violence/graphic".
+	Categories []string
+	// Scores is the model's confidence in each category, from 0 to 1.
+	Scores ModerationScores
+}
+
+// ModerationScores holds the score of each moderation category.
+type ModerationScores struct {
+	Harassment            float64
+	HarassmentThreatening float64
+	Hate                  float64
+	HateThreatening       float64
+	Illicit               float64
+	IllicitViolent        float64
+	SelfHarm              float64
+	SelfHarmInstructions  float64
+	SelfHarmIntent        float64
+	Sexual                float64
+	SexualMinors          float64
+	Violence              float64
+	ViolenceGraphic       float64
+}
+
+// ModerationError is returned by the InputModeration middleware when the
+// input is flagged.
+type ModerationError struct {
+	Result *ModerationResult
+}
+
+func (e *ModerationError) Error() string {
+	return "openai: input flagged by moderation: " + strings.Join(e.Result.Categories, ", ")
+}
+
+// Moderate classifies the text and images in parts with OpenAI's moderation
+// endpoint. Other parts are ignored.
+func (c *Client) Moderate(ctx context.Context, parts ...llms.Part) (*ModerationResult, error) {
+	inputs := make([]openai.ModerationMultiModalInputUnionParam, 0, len(parts))
+	for i, part := range parts {
+		switch p := part.(type) {
+		case llms.TextPart:
+			inputs = append(inputs, openai.ModerationMultiModalInputUnionParam{
+				OfText: &openai.ModerationTextInputParam{Text: p.Text},
+			})
+		case llms.ImagePart:
+			image, err := convertImage(p)
+			if err != nil {
+				return nil, fmt.Errorf("[part %d] openai: %w", i, err)
+			}
+			inputs = append(inputs, openai.ModerationMultiModalInputUnionParam{
+				OfImageURL: &openai.ModerationImageURLInputParam{
+					ImageURL: openai.ModerationImageURLInputImageURLParam{URL: image.URL},
+				},
+			})
+		}
+	}
+
+	if len(inputs) == 0 {
+		return &ModerationResult{}, nil
+	}
+
+	model := c.ModerationModel
+	if model == "" {
+		model = DefaultModerationModel
+	}
+
+	resp, err := c.client.Moderations.New(ctx, openai.ModerationNewParams{
+		Model: openai.ModerationModel(model),
+		Input: openai.ModerationNewParamsInputUnion{OfModerationMultiModalArray: inputs},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("openai: failed to moderate content: %w", wrapError(err))
+	}
+	if len(resp.Results) == 0 {
+		return nil, errors.New("openai: no moderation results returned")
+	}
+
+	return convertModeration(resp.Results[0]), nil
+}
+
+func convertModeration(m openai.Moderation) *ModerationResult {
+	out := &ModerationResult{
+		Flagged: m.Flagged,
+		Scores: ModerationScores{
+			Harassment:            m.CategoryScores.Harassment,
+			HarassmentThreatening: m.CategoryScores.HarassmentThreatening,
+			Hate:                  m.CategoryScores.Hate,
+			HateThreatening:       m.CategoryScores.HateThreatening,
+			Illicit:               m.CategoryScores.Illicit,
+			IllicitViolent:        m.CategoryScores.IllicitViolent,
+			SelfHarm:              m.CategoryScores.SelfHarm,
+			SelfHarmInstructions:  m.CategoryScores.SelfHarmInstructions,
+			SelfHarmIntent:        m.CategoryScores.SelfHarmIntent,
+			Sexual:                m.CategoryScores.Sexual,
+			SexualMinors:          m.CategoryScores.SexualMinors,
+			Violence:              m.CategoryScores.Violence,
+			ViolenceGraphic:       m.CategoryScores.ViolenceGraphic,
+		},
+	}
+
+	categories := []struct {
+		name    string
+		flagged bool
+	}{
+		{"harassment", m.Categories.Harassment},
+		{"harassment/threatening", m.Categories.HarassmentThreatening},
+		{"hate", m.Categories.Hate},
+		{"hate/threatening", m.Categories.HateThreatening},
+		{"illicit", m.Categories.Illicit},
+		{"illicit/violent", m.Categories.IllicitViolent},
+		{"self-harm", m.Categories.SelfHarm},
+		{"self-harm/instructions", m.Categories.SelfHarmInstructions},
+		{"self-harm/intent", m.Categories.SelfHarmIntent},
+		{"sexual", m.Categories.Sexual},
+		{"sexual/minors", m.Categories.SexualMinors},
+		{"violence", m.Categories.Violence},
+		{"violence/graphic", m.Categories.ViolenceGraphic},
+	}
+	for _, category := range categories {
+		if category.flagged {
+			out.Categories = append(out.Categories, category.name)
+		}
+	}
+
+	return out
+}
+
+// InputModeration returns an llms.Middleware that classifies the last user
+// message with moderator before every call. Flagged input is rejected with a
+// *ModerationError without calling the wrapped LLM.
+//
+//	client := llms.Chain(anthropic.New(), openai.InputModeration(openai.New().(*openai.Client)))
+func InputModeration(moderator *Client) llms.Middleware {
+	return func(llm llms.LLM) llms.LLM {
+		return &moderated{llm: llm, moderator: moderator}
+	}
+}
+
+type moderated struct {
+	llm       llms.LLM
+	moderator *Client
+}
+
+func (m *moderated) ModelName() string {
+	if namer, ok := m.llm.(llms.ModelNamer); ok {
+		return namer.ModelName()
+	}
+	return ""
+}
+
+func (m *moderated) Generate(ctx context.Context, messages []llms.Message) (*llms.Response, error) {
+	if err := m.check(ctx, messages); err != nil {
+		return nil, err
+	}
+	return m.llm.Generate(ctx, messages)
+}
+
+func (m *moderated) GenerateStream(ctx context.Context, messages []llms.Message, fn llms.StreamFunc) (*llms.Response, error) {
+	if err := m.check(ctx, messages); err != nil {
+		return nil, err
+	}
+	return m.llm.GenerateStream(ctx, messages, fn)
+}
+
+// check moderates the last user message, which is the only new input in a
+// conversation whose earlier turns have already been checked.
+func (m *moderated) check(ctx context.Context, messages []llms.Message) error {
+	for i := len(messages) - 1; i >= 0; i-- {
+		if messages[i].Role != llms.RoleUser {
+			continue
+		}
+
+		result, err := m.moderator.Moderate(ctx, messages[i].Parts...)
+		if err != nil {
+			return err
+		}
+		if result.Flagged {
+			return &ModerationError{Result: result}
+		}
+		return nil
+	}
+
+	return nil
+}
+
+// OutputModeration returns an llms.Guardrail that classifies generated text
+// with moderator, for use with llms.Guardrails. Flagged text cannot be
+// redacted.
+//
+//	client := llms.Chain(anthropic.New(), llms.Guardrails(llms.GuardrailConfig{
+//		Guardrails: []llms.Guardrail{openai.OutputModeration(moderator)},
+//	}))
+func OutputModeration(moderator *Client) llms.Guardrail {
+	return llms.GuardrailFunc(func(ctx context.Context, text string) (*llms.GuardrailViolation, error) {
+		result, err := moderator.Moderate(ctx, llms.TextPart{Text: text})
+		if err != nil {
+			return nil, err
+		}
+		if !result.Flagged {
+			return nil, nil
+		}
+		return &llms.GuardrailViolation{
+			Guardrail: "moderation",
+			Reason:    "the response was flagged for " + strings.Join(result.Categories, ", "),
+		}, nil
+	})
+}
@@ -0,0 +1,138 @@
+package openai
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/llmite-ai/llms"
+)
+
+// moderationResponse returns a moderation response flagging categories.
+func moderationResponse(flagged bool, categories map[string]bool, scores map[string]float64) string {
+	data, _ := json.Marshal(map[string]any{
+		"id":    "modr_1",
+		"model": DefaultModerationModel,
+		"results": []any{map[string]any{
+			"flagged":                      flagged,
+			"categories":                   categories,
+			"category_scores":              scores,
+			"category_applied_input_types": map[string]any{},
+		}},
+	})
+	return string(data)
+}
+
+func TestModerate(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/moderations", r.URL.Path)
+
+		var body struct {
+			Model string          `json:"model"`
+			Input json.RawMessage `json:"input"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		assert.Equal(t, DefaultModerationModel, body.Model)
+		assert.JSONEq(t, `[
+			{"type": "text", "text": "some text"},
+			{"type": "image_url", "image_url": {"url": "https://example.com/image.png"}}
+		]`, string(body.Input))
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(moderationResponse(true,
+			map[string]bool{"violence": true, "violence/graphic": true},
+			map[string]float64{"violence": 0.9, "violence/graphic": 0.7, "hate": 0.01},
+		)))
+	})
+
+	result, err := client.Moderate(context.Background(),
+		llms.TextPart{Text: "some text"},
+		llms.ImagePart{URL: "https://example.com/image.png"},
+		llms.CachePointPart{},
+	)
+	require.NoError(t, err)
+	assert.True(t, result.Flagged)
+	assert.Equal(t, []string{"violence", "violence/graphic"}, result.Categories)
+	assert.Equal(t, ModerationScores{Violence: 0.9, ViolenceGraphic: 0.7, Hate: 0.01}, result.Scores)
+}
+
+func TestInputModeration(t *testing.T) {
+	flagged := true
+	moderator := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Input []struct {
+				Text string `json:"text"`
+			} `json:"input"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		// Only the last user message is moderated.
+		require.Len(t, body.Input, 1)
+		assert.Equal(t, "latest", body.Input[0].Text)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(moderationResponse(flagged, map[string]bool{"harassment": flagged}, nil)))
+	})
+
+	calls := 0
+	chat := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{
+			"id": "chatcmpl_1",
+			"object": "chat.completion",
+			"choices": [{"index": 0, "finish_reason": "stop", "message": {"role": "assistant", "content": "Hi"}}]
+		}`))
+	})
+
+	llm := llms.Chain(chat, InputModeration(moderator))
+	messages := []llms.Message{
+		llms.NewTextMessage(llms.RoleUser, "earlier"),
+		llms.NewTextMessage(llms.RoleAssistant, "reply"),
+		llms.NewTextMessage(llms.RoleUser, "latest"),
+	}
+
+	_, err := llm.Generate(context.Background(), messages)
+	var modErr *ModerationError
+	require.ErrorAs(t, err, &modErr)
+	assert.Equal(t, []string{"harassment"}, modErr.Result.Categories)
+	assert.EqualError(t, err, "openai: input flagged by moderation: harassment")
+	assert.Equal(t, 0, calls)
+
+	flagged = false
+	resp, err := llm.Generate(context.Background(), messages)
+	require.NoError(t, err)
+	assert.Equal(t, "chatcmpl_1", resp.ID)
+	assert.Equal(t, 1, calls)
+}
+
+func TestOutputModeration(t *testing.T) {
+	moderator := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Input []struct {
+				Text string `json:"text"`
+			} `json:"input"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		require.Len(t, body.Input, 1)
+		flagged := body.Input[0].Text == "bad"
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(moderationResponse(flagged, map[string]bool{"hate": flagged}, nil)))
+	})
+	guardrail := OutputModeration(moderator)
+
+	violation, err := guardrail.Check(context.Background(), "bad")
+	require.NoError(t, err)
+	require.NotNil(t, violation)
+	assert.Equal(t, "moderation", violation.Guardrail)
+	assert.Equal(t, "the response was flagged for hate", violation.Reason)
+	assert.Empty(t, violation.Redacted)
+
+	violation, err = guardrail.Check(context.Background(), "good")
+	require.NoError(t, err)
+	assert.Nil(t, violation)
+}
@@ -0,0 +1,158 @@
+package openai
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+
+	"github.com/openai/openai-go"
+
+	"github.com/llmite-ai/llms"
+)
+
+const (
+	// DefaultSpeechModel is the model used by Speak unless the client's
+	// SpeechModel is set.
+	DefaultSpeechModel = "gpt-4o-mini-tts"
+	// DefaultSpeechVoice is the voice used by Speak unless the request or
+	// the client's SpeechVoice names one.
+	DefaultSpeechVoice = "alloy"
+	// DefaultTranscriptionModel is the model used by Transcribe unless the
+	// client's TranscriptionModel is set.
+	DefaultTranscriptionModel = "gpt-4o-transcribe"
+)
+
+// speechFormats maps the MIME types of llms.SpeechRequest to OpenAI's speech
+// formats.
+var speechFormats = map[string]openai.AudioSpeechNewParamsResponseFormat{
+	"audio/mpeg": openai.AudioSpeechNewParamsResponseFormatMP3,
+	"audio/opus": openai.AudioSpeechNewParamsResponseFormatOpus,
+	"audio/aac":  openai.AudioSpeechNewParamsResponseFormatAAC,
+	"audio/flac": openai.AudioSpeechNewParamsResponseFormatFLAC,
+	"audio/wav":  openai.AudioSpeechNewParamsResponseFormatWAV,
+	"audio/pcm":  openai.AudioSpeechNewParamsResponseFormatPCM,
+}
+
+// transcriptionExtensions maps the media types of audio OpenAI transcribes to
+// the file extensions it recognises them by.
+var transcriptionExtensions = map[string]string{
+	"audio/wav":       "wav",
+	"audio/wave":      "wav",
+	"audio/x-wav":     "wav",
+	"audio/mpeg":      "mp3",
+	"audio/mp3":       "mp3",
+	"audio/flac":      "flac",
+	"audio/ogg":       "ogg",
+	"application/ogg": "ogg",
+	"audio/webm":      "webm",
+	"audio/mp4":       "m4a",
+	"audio/m4a":       "m4a",
+	"audio/x-m4a":     "m4a",
+	"video/mp4":       "mp4",
+}
+
+var (
+	_ llms.Speaker     = (*Client)(nil)
+	_ llms.Transcriber = (*Client)(nil)
+)
+
+// Speak generates speech with the client's SpeechModel. The audio is MP3
+// unless req.Format asks for another format. Instructions are only followed
+// by the GPT speech models, such as gpt-4o-mini-tts.
+func (c *Client) Speak(ctx context.Context, req llms.SpeechRequest) (llms.AudioPart, error) {
+	mediaType := req.Format
+	if mediaType == "" {
+		mediaType = "audio/mpeg"
+	}
+	format, ok := speechFormats[mediaType]
+	if !ok {
+		return llms.AudioPart{}, fmt.Errorf("openai: unsupported speech format %q", req.Format)
+	}
+
+	voice := req.Voice
+	if voice == "" {
+		voice = c.SpeechVoice
+	}
+	if voice == "" {
+		voice = DefaultSpeechVoice
+	}
+
+	params := openai.AudioSpeechNewParams{
+		Input:          req.Text,
+		Model:          openai.SpeechModel(c.speechModel()),
+		Voice:          openai.AudioSpeechNewParamsVoice(voice),
+		ResponseFormat: format,
+	}
+	if req.Instructions != "" {
+		params.Instructions = openai.String(req.Instructions)
+	}
+
+	resp, err := c.client.Audio.Speech.New(ctx, params)
+	if err != nil {
+		return llms.AudioPart{}, fmt.Errorf("openai: failed to generate speech: %w", wrapError(err))
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return llms.AudioPart{}, fmt.Errorf("openai: failed to read speech: %w", err)
+	}
+	return llms.AudioPart{MediaType: mediaType, Data: data, Transcript: req.Text}, nil
+}
+
+// Transcribe transcribes audio with the client's TranscriptionModel. The
+// audio must be inline, in a format such as WAV, MP3, FLAC, Ogg, WebM or
+// M4A.
+func (c *Client) Transcribe(ctx context.Context, req llms.TranscriptionRequest) (string, error) {
+	if len(req.Audio.Data) == 0 {
+		return "", errors.New("openai: audio has no data")
+	}
+	mediaType := req.Audio.MediaType
+	if mediaType == "" {
+		mediaType = http.DetectContentType(req.Audio.Data)
+	}
+	// Parameters such as codecs=opus don't change the extension.
+	baseType, _, err := mime.ParseMediaType(mediaType)
+	if err != nil {
+		return "", fmt.Errorf("openai: unsupported audio type %q", mediaType)
+	}
+	ext, ok := transcriptionExtensions[baseType]
+	if !ok {
+		return "", fmt.Errorf("openai: unsupported audio type %q", mediaType)
+	}
+
+	params := openai.AudioTranscriptionNewParams{
+		File:  openai.File(bytes.NewReader(req.Audio.Data), "audio."+ext, mediaType),
+		Model: openai.AudioModel(c.transcriptionModel()),
+	}
+	if req.Language != "" {
+		params.Language = openai.String(req.Language)
+	}
+	if req.Prompt != "" {
+		params.Prompt = openai.String(req.Prompt)
+	}
+
+	transcription, err := c.client.Audio.Transcriptions.New(ctx, params)
+	if err != nil {
+		return "", fmt.Errorf("openai: failed to transcribe audio: %w", wrapError(err))
+	}
+	return transcription.Text, nil
+}
+
+func (c *Client) speechModel() string {
+	if c.SpeechModel != "" {
+		return c.SpeechModel
+	}
+	return DefaultSpeechModel
+}
+
+func (c *Client) transcriptionModel() string {
+	if c.TranscriptionModel != "" {
+		return c.TranscriptionModel
+	}
+	return DefaultTranscriptionModel
+}
@@ -0,0 +1,123 @@
+package openai
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/llmite-ai/llms"
+)
+
+func TestSpeak(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/audio/speech", r.URL.Path)
+
+		var body map[string]any
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		assert.Equal(t, map[string]any{
+			"input":           "Hello there",
+			"model":           DefaultSpeechModel,
+			"voice":           "nova",
+			"response_format": "wav",
+			"instructions":    "Say cheerfully",
+		}, body)
+
+		w.Header().Set("Content-Type", "audio/wav")
+		w.Write([]byte("RIFF...."))
+	}, WithSpeechVoice("nova"))
+
+	audio, err := client.Speak(context.Background(), llms.SpeechRequest{
+		Text:         "Hello there",
+		Format:       "audio/wav",
+		Instructions: "Say cheerfully",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, llms.AudioPart{MediaType: "audio/wav", Data: []byte("RIFF...."), Transcript: "Hello there"}, audio)
+}
+
+func TestSpeak_Defaults(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		assert.Equal(t, "tts-1", body["model"])
+		assert.Equal(t, DefaultSpeechVoice, body["voice"])
+		assert.Equal(t, "mp3", body["response_format"])
+		assert.NotContains(t, body, "instructions")
+
+		w.Header().Set("Content-Type", "audio/mpeg")
+		w.Write([]byte("ID3"))
+	}, WithSpeechModel("tts-1"))
+
+	audio, err := client.Speak(context.Background(), llms.SpeechRequest{Text: "hi"})
+	require.NoError(t, err)
+	assert.Equal(t, "audio/mpeg", audio.MediaType)
+	assert.Equal(t, []byte("ID3"), audio.Data)
+
+	_, err = client.Speak(context.Background(), llms.SpeechRequest{Text: "hi", Format: "audio/ogg"})
+	assert.EqualError(t, err, `openai: unsupported speech format "audio/ogg"`)
+}
+
+func TestTranscribe(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/audio/transcriptions", r.URL.Path)
+
+		require.NoError(t, r.ParseMultipartForm(1<<20))
+		assert.Equal(t, "whisper-1", r.FormValue("model"))
+		assert.Equal(t, "en", r.FormValue("language"))
+		assert.Equal(t, "Names: Ada.", r.FormValue("prompt"))
+
+		file, header, err := r.FormFile("file")
+		require.NoError(t, err)
+		defer file.Close()
+		assert.Equal(t, "audio.mp3", header.Filename)
+		data, err := io.ReadAll(file)
+		require.NoError(t, err)
+		assert.Equal(t, "ID3 audio", string(data))
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"text": "Hello Ada."}`))
+	}, WithTranscriptionModel("whisper-1"))
+
+	text, err := client.Transcribe(context.Background(), llms.TranscriptionRequest{
+		Audio:    llms.AudioPart{MediaType: "audio/mpeg", Data: []byte("ID3 audio")},
+		Language: "en",
+		Prompt:   "Names: Ada.",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "Hello Ada.", text)
+}
+
+func TestTranscribe_MediaTypeParameters(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseMultipartForm(1<<20))
+		_, header, err := r.FormFile("file")
+		require.NoError(t, err)
+		assert.Equal(t, "audio.webm", header.Filename)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"text": "Hello."}`))
+	})
+
+	text, err := client.Transcribe(context.Background(), llms.TranscriptionRequest{
+		Audio: llms.AudioPart{MediaType: "audio/webm;codecs=opus", Data: []byte("webm audio")},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "Hello.", text)
+}
+
+func TestTranscribe_InvalidAudio(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		t.Error("unexpected request")
+	})
+
+	_, err := client.Transcribe(context.Background(), llms.TranscriptionRequest{Audio: llms.AudioPart{URL: "https://example.com/a.mp3"}})
+	assert.EqualError(t, err, "openai: audio has no data")
+
+	_, err = client.Transcribe(context.Background(), llms.TranscriptionRequest{Audio: llms.AudioPart{MediaType: "audio/x-unknown", Data: []byte("x")}})
+	assert.EqualError(t, err, `openai: unsupported audio type "audio/x-unknown"`)
+}
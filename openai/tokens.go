@@ -0,0 +1,107 @@
+package openai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/pkoukk/tiktoken-go"
+	tiktoken_loader "github.com/pkoukk/tiktoken-go-loader"
+
+	"github.com/llmite-ai/llms"
+)
+
+var (
+	loadEncodings sync.Once
+	encodingsMu   sync.Mutex
+	encodings     = map[string]*tiktoken.Tiktoken{}
+)
+
+// encodingName returns the name of the tiktoken encoding used by model.
+// Models tiktoken does not know, like the o-series and newer GPT models, use
+// o200k_base.
+func encodingName(model string) string {
+	if name, ok := tiktoken.MODEL_TO_ENCODING[model]; ok {
+		return name
+	}
+	for prefix, name := range tiktoken.MODEL_PREFIX_TO_ENCODING {
+		if strings.HasPrefix(model, prefix) {
+			return name
+		}
+	}
+	return tiktoken.MODEL_O200K_BASE
+}
+
+// encodingForModel returns the tiktoken encoding of model. The encodings are
+// embedded in the binary, so they are never downloaded.
+func encodingForModel(model string) (*tiktoken.Tiktoken, error) {
+	loadEncodings.Do(func() {
+		tiktoken.SetBpeLoader(tiktoken_loader.NewOfflineLoader())
+	})
+
+	name := encodingName(model)
+	encodingsMu.Lock()
+	defer encodingsMu.Unlock()
+	if enc, ok := encodings[name]; ok {
+		return enc, nil
+	}
+	enc, err := tiktoken.GetEncoding(name)
+	if err != nil {
+		return nil, err
+	}
+	encodings[name] = enc
+	return enc, nil
+}
+
+// CountTokens returns the number of input tokens messages would use, counted
+// with the tiktoken encoding of the client's model plus the fixed per message
+// overhead of the chat format. The definitions of the tools the request would
+// include, from the client and its registry, are counted too: their name,
+// description and JSON parameters, but not the overhead of the format OpenAI
+// renders them in, which is undocumented. OpenAI has no token counting
+// endpoint, and images, audio and files are not counted, so it is intended for
+// budgeting context, not for billing.
+func (c *Client) CountTokens(ctx context.Context, messages []llms.Message) (int, error) {
+	const (
+		tokensPerMessage = 3
+		tokensPerReply   = 3
+	)
+
+	enc, err := encodingForModel(c.Model)
+	if err != nil {
+		return 0, fmt.Errorf("openai: failed to load token encoding: %w", err)
+	}
+	count := func(text string) int {
+		return len(enc.EncodeOrdinary(text))
+	}
+
+	total := tokensPerReply
+	for _, m := range messages {
+		total += tokensPerMessage + count(string(m.Role))
+		for _, part := range m.Parts {
+			switch p := part.(type) {
+			case llms.TextPart:
+				total += count(p.Text)
+			case llms.ToolCallPart:
+				total += count(p.Name) + count(string(p.Input))
+			case llms.ToolResultPart:
+				total += count(p.Result)
+			}
+		}
+	}
+
+	tools, err := convertTools(c.tools(ctx))
+	if err != nil {
+		return 0, err
+	}
+	for _, tool := range tools {
+		parameters, err := json.Marshal(tool.Function.Parameters)
+		if err != nil {
+			return 0, fmt.Errorf("openai: tool %s: %w", tool.Function.Name, err)
+		}
+		total += count(tool.Function.Name) + count(tool.Function.Description.Value) + count(string(parameters))
+	}
+	return total, nil
+}
@@ -0,0 +1,242 @@
+package openrouter
+
+import (
+	"context"
+	"os"
+
+	oai "github.com/openai/openai-go"
+	"github.com/openai/openai-go/option"
+
+	"github.com/llmite-ai/llms"
+	"github.com/llmite-ai/llms/openai"
+)
+
+const ProviderOpenRouter = "openrouter"
+
+// DefaultBaseURL is the URL of OpenRouter's API.
+const DefaultBaseURL = "https://openrouter.ai/api/v1/"
+
+// ProviderPreferences controls which upstream providers OpenRouter routes
+// requests to. See https://openrouter.ai/docs/features/provider-routing.
+type ProviderPreferences struct {
+	// Order lists the providers to try first, in order, such as
+	// "anthropic" or "together".
+	Order []string `json:"order,omitempty"`
+	// AllowFallbacks, if false, prevents OpenRouter from using providers
+	// other than those in Order.
+	AllowFallbacks *bool `json:"allow_fallbacks,omitempty"`
+	// RequireParameters only routes to providers that support every
+	// parameter of the request.
+	RequireParameters bool `json:"require_parameters,omitempty"`
+	// DataCollection is "allow" or "deny". "deny" only routes to providers
+	// that do not store or train on requests.
+	DataCollection string `json:"data_collection,omitempty"`
+	// Only and Ignore restrict the providers requests are routed to.
+	Only   []string `json:"only,omitempty"`
+	Ignore []string `json:"ignore,omitempty"`
+	// Quantizations restricts routing to providers serving the model at one
+	// of these quantization levels, such as "fp8".
+	Quantizations []string `json:"quantizations,omitempty"`
+	// Sort orders providers by "price", "throughput" or "latency" instead of
+	// OpenRouter's default load balancing.
+	Sort string `json:"sort,omitempty"`
+}
+
+// Client sends requests to OpenRouter. It wraps an openai.Client, whose
+// settings it supports, with OpenRouter's routing options. The openai.Client
+// is not embedded, as OpenRouter has none of the other endpoints it supports,
+// such as embeddings and batches.
+type Client struct {
+	// Client is the openai.Client requests are sent with.
+	Client *openai.Client
+
+	apiKey         string
+	baseURL        string
+	providers      *ProviderPreferences
+	fallbackModels []string
+	appURL         string
+	appTitle       string
+	openaiMods     []openai.Modifier
+}
+
+type Modifier func(*Client)
+
+// WithAPIKey sets the API key sent with every request, overriding the
+// OPENROUTER_API_KEY environment variable.
+func WithAPIKey(key string) Modifier {
+	return func(c *Client) {
+		c.apiKey = key
+	}
+}
+
+// WithBaseURL sends requests to url instead of DefaultBaseURL.
+func WithBaseURL(url string) Modifier {
+	return func(c *Client) {
+		c.baseURL = url
+	}
+}
+
+// WithModel sets the model requests are sent to, such as
+// "anthropic/claude-sonnet-4". The default model is "openrouter/auto", which
+// lets OpenRouter pick a model for each request.
+func WithModel(model string) Modifier {
+	return func(c *Client) {
+		c.openaiMods = append(c.openaiMods, openai.WithModel(model))
+	}
+}
+
+// WithProviderPreferences controls which upstream providers serve requests.
+func WithProviderPreferences(preferences ProviderPreferences) Modifier {
+	return func(c *Client) {
+		c.providers = &preferences
+	}
+}
+
+// WithFallbackModels sets the models OpenRouter tries, in order, when the
+// client's model is unavailable or rejects the request. Use RouteOf to find
+// out which model served a response.
+func WithFallbackModels(models ...string) Modifier {
+	return func(c *Client) {
+		c.fallbackModels = models
+	}
+}
+
+// WithAppAttribution identifies the application making requests, so that it
+// appears in OpenRouter's rankings. url is sent as the HTTP-Referer header and
+// title as the X-Title header.
+func WithAppAttribution(url, title string) Modifier {
+	return func(c *Client) {
+		c.appURL = url
+		c.appTitle = title
+	}
+}
+
+// WithOpenAIModifiers applies modifiers of the openai package, such as
+// openai.WithTools or openai.WithTemperature, to the client.
+func WithOpenAIModifiers(mods ...openai.Modifier) Modifier {
+	return func(c *Client) {
+		c.openaiMods = append(c.openaiMods, mods...)
+	}
+}
+
+// New creates a new OpenRouter client. The API key is read from the
+// OPENROUTER_API_KEY environment variable unless WithAPIKey is used.
+func New(mods ...Modifier) llms.LLM {
+	c := &Client{
+		apiKey:  os.Getenv("OPENROUTER_API_KEY"),
+		baseURL: DefaultBaseURL,
+	}
+
+	for _, mod := range mods {
+		mod(c)
+	}
+
+	options := []option.RequestOption{
+		option.WithBaseURL(c.baseURL),
+		option.WithAPIKey(c.apiKey),
+		// Ask for the cost of each request to be reported with its usage.
+		option.WithJSONSet("usage", map[string]any{"include": true}),
+	}
+	if c.providers != nil {
+		options = append(options, option.WithJSONSet("provider", c.providers))
+	}
+	if len(c.fallbackModels) > 0 {
+		options = append(options, option.WithJSONSet("models", c.fallbackModels))
+	}
+	if c.appURL != "" {
+		options = append(options, option.WithHeader("HTTP-Referer", c.appURL))
+	}
+	if c.appTitle != "" {
+		options = append(options, option.WithHeader("X-Title", c.appTitle))
+	}
+
+	openaiMods := append([]openai.Modifier{
+		openai.WithModel("openrouter/auto"),
+		openai.WithRequestOptions(options...),
+	}, c.openaiMods...)
+	c.Client = openai.New(openaiMods...).(*openai.Client)
+
+	return c
+}
+
+// ModelName returns the model requests are sent to.
+func (c *Client) ModelName() string {
+	return c.Client.ModelName()
+}
+
+// CountTokens returns an estimate of the number of input tokens messages would
+// use. See openai.Client.CountTokens.
+func (c *Client) CountTokens(ctx context.Context, messages []llms.Message) (int, error) {
+	return c.Client.CountTokens(ctx, messages)
+}
+
+func (c *Client) Generate(ctx context.Context, messages []llms.Message) (*llms.Response, error) {
+	resp, err := c.Client.Generate(ctx, messages)
+	if resp != nil {
+		convertResponse(resp)
+	}
+	return resp, err
+}
+
+func (c *Client) GenerateStream(ctx context.Context, messages []llms.Message, fn llms.StreamFunc) (*llms.Response, error) {
+	resp, err := c.Client.GenerateStream(ctx, messages, func(resp *llms.Response, err error) bool {
+		if resp != nil {
+			resp.Provider = ProviderOpenRouter
+		}
+		return fn(resp, err)
+	})
+	if resp != nil {
+		convertResponse(resp)
+	}
+	return resp, err
+}
+
+// convertResponse marks resp as coming from OpenRouter and adds the cost
+// OpenRouter reports to its usage.
+func convertResponse(resp *llms.Response) {
+	resp.Provider = ProviderOpenRouter
+
+	var usage oai.CompletionUsage
+	switch raw := resp.Raw.(type) {
+	case *oai.ChatCompletion:
+		usage = raw.Usage
+	case oai.ChatCompletionChunk:
+		usage = raw.Usage
+	default:
+		return
+	}
+
+	var cost float64
+	if openai.DecodeExtraField(usage.JSON.ExtraFields["cost"], &cost) && resp.Usage != nil {
+		resp.Usage.Cost = cost
+	}
+}
+
+// Route describes how OpenRouter served a request.
+type Route struct {
+	// Model is the model that generated the response. It differs from the
+	// requested model when a fallback model or "openrouter/auto" was used.
+	Model string
+	// Provider is the upstream provider that served the request, such as
+	// "Anthropic".
+	Provider string
+}
+
+// RouteOf returns the model and provider that served resp. Its fields are
+// empty if resp did not come from OpenRouter.
+func RouteOf(resp *llms.Response) Route {
+	if resp == nil {
+		return Route{}
+	}
+
+	var route Route
+	switch raw := resp.Raw.(type) {
+	case *oai.ChatCompletion:
+		route.Model = raw.Model
+		openai.DecodeExtraField(raw.JSON.ExtraFields["provider"], &route.Provider)
+	case oai.ChatCompletionChunk:
+		route.Model = raw.Model
+		openai.DecodeExtraField(raw.JSON.ExtraFields["provider"], &route.Provider)
+	}
+	return route
+}
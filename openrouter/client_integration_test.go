@@ -0,0 +1,54 @@
+//go:build integration
+// +build integration
+
+package openrouter
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/llmite-ai/llms"
+	"github.com/llmite-ai/llms/openai"
+	"github.com/llmite-ai/llms/testutil"
+)
+
+func TestOpenRouterClientIntegration(t *testing.T) {
+	testutil.RequireAPIKey(t, "OPENROUTER_API_KEY")
+
+	t.Run("simple generation", func(t *testing.T) {
+		client := New(
+			WithModel("openai/gpt-4o-mini"),
+			WithOpenAIModifiers(openai.WithHTTPClient(testutil.NewRecordingClient(t, "simple_generation"))),
+		)
+		messages := []llms.Message{llms.NewTextMessage(llms.RoleUser, "What is the capital of France?")}
+
+		response, err := client.Generate(context.Background(), messages)
+		require.NoError(t, err)
+		assert.Equal(t, ProviderOpenRouter, response.Provider)
+		require.NotEmpty(t, response.Message.Parts)
+		assert.Contains(t, response.Message.Parts[0].(llms.TextPart).Text, "Paris")
+		require.NotNil(t, response.Usage)
+		assert.Greater(t, response.Usage.Cost, 0.0)
+		assert.NotEmpty(t, RouteOf(response).Provider)
+	})
+
+	t.Run("fallback models", func(t *testing.T) {
+		client := New(
+			WithModel("openai/gpt-4o-mini"),
+			WithFallbackModels("google/gemini-2.5-flash"),
+			WithOpenAIModifiers(openai.WithHTTPClient(testutil.NewRecordingClient(t, "fallback_models"))),
+		)
+		messages := []llms.Message{llms.NewTextMessage(llms.RoleUser, "Say hello.")}
+
+		response, err := client.GenerateStream(context.Background(), messages, func(r *llms.Response, err error) bool {
+			return true
+		})
+		require.NoError(t, err)
+		assert.NotEmpty(t, RouteOf(response).Model)
+		require.NotNil(t, response.Usage)
+		assert.Greater(t, response.Usage.Cost, 0.0)
+	})
+}
@@ -0,0 +1,128 @@
+package openrouter
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/openai/openai-go/option"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/llmite-ai/llms"
+	"github.com/llmite-ai/llms/openai"
+)
+
+// newTestClient returns a client that sends requests to a test server running
+// handler.
+func newTestClient(t *testing.T, handler http.HandlerFunc, mods ...Modifier) *Client {
+	t.Helper()
+
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	mods = append([]Modifier{
+		WithBaseURL(server.URL),
+		WithAPIKey("test"),
+		WithOpenAIModifiers(openai.WithRequestOptions(option.WithMaxRetries(0))),
+	}, mods...)
+	return New(mods...).(*Client)
+}
+
+func TestGenerate(t *testing.T) {
+	allowFallbacks := false
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/chat/completions", r.URL.Path)
+		assert.Equal(t, "Bearer test", r.Header.Get("Authorization"))
+		assert.Equal(t, "https://example.com", r.Header.Get("HTTP-Referer"))
+		assert.Equal(t, "Example", r.Header.Get("X-Title"))
+
+		var body map[string]any
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		assert.Equal(t, "anthropic/claude-sonnet-4", body["model"])
+		assert.Equal(t, []any{"openai/gpt-4o", "google/gemini-2.5-flash"}, body["models"])
+		assert.Equal(t, map[string]any{"include": true}, body["usage"])
+		assert.Equal(t, map[string]any{
+			"order":           []any{"anthropic", "amazon-bedrock"},
+			"allow_fallbacks": false,
+			"sort":            "price",
+		}, body["provider"])
+		assert.NotEmpty(t, body["messages"])
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{
+			"id": "gen-1",
+			"object": "chat.completion",
+			"model": "openai/gpt-4o",
+			"provider": "OpenAI",
+			"choices": [{"index": 0, "finish_reason": "stop", "message": {"role": "assistant", "content": "Hi"}}],
+			"usage": {"prompt_tokens": 10, "completion_tokens": 2, "total_tokens": 12, "cost": 0.00015}
+		}`))
+	},
+		WithModel("anthropic/claude-sonnet-4"),
+		WithFallbackModels("openai/gpt-4o", "google/gemini-2.5-flash"),
+		WithProviderPreferences(ProviderPreferences{
+			Order:          []string{"anthropic", "amazon-bedrock"},
+			AllowFallbacks: &allowFallbacks,
+			Sort:           "price",
+		}),
+		WithAppAttribution("https://example.com", "Example"),
+	)
+
+	resp, err := client.Generate(context.Background(), []llms.Message{llms.NewTextMessage(llms.RoleUser, "Hello")})
+	require.NoError(t, err)
+	assert.Equal(t, ProviderOpenRouter, resp.Provider)
+	assert.Equal(t, &llms.Usage{InputTokens: 10, OutputTokens: 2, Cost: 0.00015}, resp.Usage)
+	assert.Equal(t, Route{Model: "openai/gpt-4o", Provider: "OpenAI"}, RouteOf(resp))
+	assert.Equal(t, "anthropic/claude-sonnet-4", client.ModelName())
+}
+
+func TestGenerateStream(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, chunk := range []string{
+			`{"id": "gen-1", "object": "chat.completion.chunk", "model": "anthropic/claude-sonnet-4", "provider": "Anthropic", "choices": [{"index": 0, "delta": {"role": "assistant", "content": "Hi"}}]}`,
+			`{"id": "gen-1", "object": "chat.completion.chunk", "model": "anthropic/claude-sonnet-4", "provider": "Anthropic", "choices": [], "usage": {"prompt_tokens": 10, "completion_tokens": 1, "total_tokens": 11, "cost": 0.0002}}`,
+		} {
+			fmt.Fprintf(w, "data: %s\n\n", chunk)
+		}
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	})
+
+	var providers []string
+	resp, err := client.GenerateStream(context.Background(), []llms.Message{llms.NewTextMessage(llms.RoleUser, "Hello")}, func(r *llms.Response, err error) bool {
+		providers = append(providers, r.Provider)
+		return true
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{ProviderOpenRouter}, providers)
+	assert.Equal(t, ProviderOpenRouter, resp.Provider)
+	assert.Equal(t, &llms.Usage{InputTokens: 10, OutputTokens: 1, Cost: 0.0002}, resp.Usage)
+	assert.Equal(t, Route{Model: "anthropic/claude-sonnet-4", Provider: "Anthropic"}, RouteOf(resp))
+}
+
+func TestRouteOf(t *testing.T) {
+	assert.Equal(t, Route{}, RouteOf(nil))
+	assert.Equal(t, Route{}, RouteOf(&llms.Response{Raw: "not openrouter"}))
+}
+
+func TestCapabilities(t *testing.T) {
+	var llm llms.LLM = New()
+
+	// Only the endpoints the provider has are exposed.
+	_, ok := llm.(llms.TokenCounter)
+	assert.True(t, ok)
+	_, ok = llm.(llms.Embedder)
+	assert.False(t, ok)
+	_, ok = llm.(llms.ImageGenerator)
+	assert.False(t, ok)
+	_, ok = llm.(llms.Speaker)
+	assert.False(t, ok)
+	_, ok = llm.(llms.Transcriber)
+	assert.False(t, ok)
+	_, ok = llm.(llms.BatchLLM)
+	assert.False(t, ok)
+}
@@ -0,0 +1,195 @@
+package llms
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// PartialJSONField is a top-level field of a JSON object whose value has been
+// read completely.
+type PartialJSONField struct {
+	Name  string
+	Value json.RawMessage
+}
+
+// PartialJSONParser parses a JSON object incrementally, as its bytes are
+// streamed in, and exposes each top-level field as soon as its value is
+// complete. It is meant for the arguments of a streaming tool call, so that
+// a UI can show the "location" argument before the rest of the call has
+// arrived. See StreamToolCallFields.
+//
+// String, object and array values are complete once they are closed. Numbers,
+// booleans and null are complete once followed by a delimiter, since more
+// digits could follow. The parser does not validate its input; malformed JSON
+// yields fewer or odd fields, but no error.
+type PartialJSONParser struct {
+	buf    []byte
+	fields []PartialJSONField
+
+	depth    int
+	inString bool
+	escaped  bool
+	state    partialJSONState
+	key      string
+	start    int  // of the current key or value
+	scalar   bool // whether the current value is a number or literal
+}
+
+type partialJSONState int
+
+const (
+	partialKey        partialJSONState = iota // expecting a key
+	partialInKey                              // reading a key
+	partialColon                              // expecting a colon
+	partialValue                              // expecting a value
+	partialInValue                            // reading a value
+	partialAfterValue                         // expecting a comma or the end
+)
+
+// Write appends data to the JSON read so far. It never fails.
+func (p *PartialJSONParser) Write(data []byte) (int, error) {
+	for _, c := range data {
+		p.buf = append(p.buf, c)
+		p.consume(c, len(p.buf)-1)
+	}
+	return len(data), nil
+}
+
+// Bytes returns the JSON read so far.
+func (p *PartialJSONParser) Bytes() []byte {
+	return p.buf
+}
+
+// Fields returns the complete fields read so far, in the order they appeared.
+func (p *PartialJSONParser) Fields() []PartialJSONField {
+	return p.fields
+}
+
+// Field returns the value of the named field, if it is complete.
+func (p *PartialJSONParser) Field(name string) (json.RawMessage, bool) {
+	for _, f := range p.fields {
+		if f.Name == name {
+			return f.Value, true
+		}
+	}
+	return nil, false
+}
+
+// Reset discards everything read so far.
+func (p *PartialJSONParser) Reset() {
+	*p = PartialJSONParser{buf: p.buf[:0]}
+}
+
+func (p *PartialJSONParser) consume(c byte, i int) {
+	if p.inString {
+		switch {
+		case p.escaped:
+			p.escaped = false
+		case c == '\\':
+			p.escaped = true
+		case c == '"':
+			p.inString = false
+			if p.depth == 1 && p.state == partialInKey {
+				if err := json.Unmarshal(p.buf[p.start:i+1], &p.key); err == nil {
+					p.state = partialColon
+				}
+			} else if p.depth == 1 && p.state == partialInValue {
+				p.complete(i + 1)
+			}
+		}
+		return
+	}
+
+	switch c {
+	case '"':
+		p.inString = true
+		if p.depth == 1 {
+			switch p.state {
+			case partialKey:
+				p.state, p.start = partialInKey, i
+			case partialValue:
+				p.state, p.start, p.scalar = partialInValue, i, false
+			}
+		}
+	case '{', '[':
+		if p.depth == 0 {
+			p.state = partialKey
+		} else if p.depth == 1 && p.state == partialValue {
+			p.state, p.start, p.scalar = partialInValue, i, false
+		}
+		p.depth++
+	case '}', ']':
+		if p.depth == 1 && p.state == partialInValue && p.scalar {
+			p.complete(i)
+		}
+		p.depth--
+		if p.depth == 1 && p.state == partialInValue {
+			p.complete(i + 1)
+		}
+	case ',':
+		if p.depth == 1 {
+			if p.state == partialInValue && p.scalar {
+				p.complete(i)
+			}
+			p.state = partialKey
+		}
+	case ':':
+		if p.depth == 1 && p.state == partialColon {
+			p.state = partialValue
+		}
+	case ' ', '\t', '\n', '\r':
+		if p.depth == 1 && p.state == partialInValue && p.scalar {
+			p.complete(i)
+		}
+	default:
+		if p.depth == 1 && p.state == partialValue {
+			p.state, p.start, p.scalar = partialInValue, i, true
+		}
+	}
+}
+
+// complete records the current value, which ends before end.
+func (p *PartialJSONParser) complete(end int) {
+	value := bytes.Clone(p.buf[p.start:end])
+	p.fields = append(p.fields, PartialJSONField{Name: p.key, Value: value})
+	p.state = partialAfterValue
+}
+
+// StreamToolCallFields returns a StreamFunc that calls fn for each top-level
+// argument of a tool call as soon as it has been streamed completely, before
+// passing every response on to next. It works with any provider that streams
+// tool call arguments, and with those that only send complete calls.
+//
+//	resp, err := client.GenerateStream(ctx, messages, llms.StreamToolCallFields(
+//		func(call llms.ToolCallPart, field llms.PartialJSONField) {
+//			if call.Name == "get_weather" && field.Name == "location" {
+//				showLocation(field.Value)
+//			}
+//		},
+//		func(resp *llms.Response, err error) bool { return err == nil },
+//	))
+func StreamToolCallFields(fn func(call ToolCallPart, field PartialJSONField), next StreamFunc) StreamFunc {
+	var parsers []*PartialJSONParser
+	return func(resp *Response, err error) bool {
+		if resp != nil {
+			for i, call := range ToolCalls(resp.Message) {
+				if i == len(parsers) {
+					parsers = append(parsers, &PartialJSONParser{})
+				}
+				p := parsers[i]
+
+				// Providers may replace the input rather than extend it,
+				// as Anthropic does with the "{}" it starts with.
+				if !bytes.HasPrefix(call.Input, p.Bytes()) {
+					p.Reset()
+				}
+				seen := len(p.Fields())
+				p.Write(call.Input[len(p.Bytes()):])
+				for _, field := range p.Fields()[seen:] {
+					fn(call, field)
+				}
+			}
+		}
+		return next(resp, err)
+	}
+}
@@ -0,0 +1,87 @@
+package llms
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPartialJSONParser(t *testing.T) {
+	input := `{"location": "San \"Fran\"", "days": 3, "units":{"temp":"C","wind":[1,2]},"alerts" : true, "note":null}`
+
+	var p PartialJSONParser
+	var seen []string
+	for i := range len(input) {
+		p.Write([]byte{input[i]})
+		if n := len(p.Fields()); n > len(seen) {
+			seen = append(seen, p.Fields()[n-1].Name+"@"+string(input[i]))
+		}
+	}
+
+	// Each field is available as soon as it is complete: strings, objects
+	// and arrays on their closing character, and scalars on the delimiter
+	// that follows them.
+	assert.Equal(t, []string{"location@\"", "days@,", "units@}", "alerts@,", "note@}"}, seen)
+
+	assert.Equal(t, []PartialJSONField{
+		{Name: "location", Value: json.RawMessage(`"San \"Fran\""`)},
+		{Name: "days", Value: json.RawMessage(`3`)},
+		{Name: "units", Value: json.RawMessage(`{"temp":"C","wind":[1,2]}`)},
+		{Name: "alerts", Value: json.RawMessage(`true`)},
+		{Name: "note", Value: json.RawMessage(`null`)},
+	}, p.Fields())
+
+	value, ok := p.Field("days")
+	require.True(t, ok)
+	assert.Equal(t, json.RawMessage(`3`), value)
+	_, ok = p.Field("missing")
+	assert.False(t, ok)
+}
+
+func TestPartialJSONParser_Incomplete(t *testing.T) {
+	var p PartialJSONParser
+	p.Write([]byte(`{"location": "Paris", "days": 1`))
+
+	// The number could continue, so only the location is complete.
+	require.Len(t, p.Fields(), 1)
+	assert.Equal(t, "location", p.Fields()[0].Name)
+
+	p.Reset()
+	assert.Empty(t, p.Bytes())
+	assert.Empty(t, p.Fields())
+}
+
+func TestStreamToolCallFields(t *testing.T) {
+	chunk := func(inputs ...string) *Response {
+		var calls []ToolCallPart
+		for i, input := range inputs {
+			calls = append(calls, ToolCallPart{ID: "call_" + string(rune('1'+i)), Name: "get_weather", Input: []byte(input)})
+		}
+		return toolCallResponse(calls...)
+	}
+
+	var got []string
+	var responses int
+	fn := StreamToolCallFields(func(call ToolCallPart, field PartialJSONField) {
+		got = append(got, call.ID+":"+field.Name+"="+string(field.Value))
+	}, func(resp *Response, err error) bool {
+		responses++
+		return true
+	})
+
+	// Anthropic starts with "{}" and replaces it with the streamed input.
+	fn(chunk(`{}`), nil)
+	fn(chunk(`{"location": "Pa`), nil)
+	fn(chunk(`{"location": "Paris", "da`), nil)
+	fn(chunk(`{"location": "Paris", "days": 2}`, `{"location":"Rome"`), nil)
+	fn(nil, assert.AnError)
+
+	assert.Equal(t, []string{
+		`call_1:location="Paris"`,
+		`call_1:days=2`,
+		`call_2:location="Rome"`,
+	}, got)
+	assert.Equal(t, 5, responses)
+}
@@ -1,5 +1,14 @@
 package llms // convertMessages converts the internal message format to the format
 
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+)
+
 type Part interface {
 	IsPart()
 }
@@ -26,3 +35,229 @@ type ToolResultPart struct {
 }
 
 func (ToolResultPart) IsPart() {}
+
+// MarshalJSON encodes the error as its message, since error values do not
+// survive a JSON round trip.
+func (p ToolResultPart) MarshalJSON() ([]byte, error) {
+	type alias ToolResultPart
+	out := struct {
+		alias
+		Error string `json:"error,omitempty"`
+	}{alias: alias(p)}
+
+	if p.Error != nil {
+		out.Error = p.Error.Error()
+	}
+
+	return json.Marshal(out)
+}
+
+func (p *ToolResultPart) UnmarshalJSON(data []byte) error {
+	type alias ToolResultPart
+	in := struct {
+		*alias
+		Error string `json:"error,omitempty"`
+	}{alias: (*alias)(p)}
+
+	if err := json.Unmarshal(data, &in); err != nil {
+		return err
+	}
+
+	if in.Error != "" {
+		p.Error = errors.New(in.Error)
+	}
+
+	return nil
+}
+
+// DocumentPart is a document, such as a PDF, given to the model as input.
+// Exactly one of Data, URL or FileID should be set.
+type DocumentPart struct {
+	// MediaType is the document's MIME type, such as "application/pdf" or
+	// "text/plain". Defaults to "application/pdf".
+	MediaType string `json:"media_type,omitempty"`
+	// Data is the content of the document.
+	Data []byte `json:"data,omitempty"`
+	// URL is the location of a document the provider fetches itself.
+	URL string `json:"url,omitempty"`
+	// FileID references a document previously uploaded to the provider.
+	FileID string `json:"file_id,omitempty"`
+	// Title is an optional title for the document.
+	Title string `json:"title,omitempty"`
+	// Context is optional information about the document that is given to
+	// the model but is not part of the document itself.
+	Context string `json:"context,omitempty"`
+}
+
+func (DocumentPart) IsPart() {}
+
+// Detail levels for ImagePart.
+const (
+	ImageDetailAuto = "auto"
+	ImageDetailLow  = "low"
+	ImageDetailHigh = "high"
+)
+
+// ImagePart is an image given to the model as input. Exactly one of Data or
+// URL should be set.
+type ImagePart struct {
+	// MediaType is the image's MIME type, such as "image/png". If empty, it is
+	// detected from Data.
+	MediaType string `json:"media_type,omitempty"`
+	// Data is the content of the image.
+	Data []byte `json:"data,omitempty"`
+	// URL is the location of an image the provider fetches itself.
+	URL string `json:"url,omitempty"`
+	// Detail is the resolution at which the model views the image, one of
+	// ImageDetailAuto, ImageDetailLow or ImageDetailHigh. Providers without
+	// such a setting ignore it.
+	Detail string `json:"detail,omitempty"`
+}
+
+func (ImagePart) IsPart() {}
+
+// AudioPart is audio given to the model as input, or generated by it. Inputs
+// should set exactly one of Data or URL.
+type AudioPart struct {
+	// MediaType is the audio's MIME type, such as "audio/wav" or
+	// "audio/mpeg". If empty, it is detected from Data.
+	MediaType string `json:"media_type,omitempty"`
+	// Data is the content of the audio.
+	Data []byte `json:"data,omitempty"`
+	// URL is the location of audio the provider fetches itself.
+	URL string `json:"url,omitempty"`
+	// Transcript is the text of audio generated by the model.
+	Transcript string `json:"transcript,omitempty"`
+	// ID identifies audio generated by the model, so that later requests can
+	// refer to it instead of sending it again.
+	ID string `json:"id,omitempty"`
+}
+
+func (AudioPart) IsPart() {}
+
+// VideoPart is a video given to the model as input. Exactly one of Data or URL
+// should be set.
+type VideoPart struct {
+	// MediaType is the video's MIME type, such as "video/mp4". If empty, it is
+	// detected from Data.
+	MediaType string `json:"media_type,omitempty"`
+	// Data is the content of the video.
+	Data []byte `json:"data,omitempty"`
+	// URL is the location of a video the provider fetches itself.
+	URL string `json:"url,omitempty"`
+}
+
+func (VideoPart) IsPart() {}
+
+// CachePointPart marks the end of a cacheable prompt prefix. Providers that
+// support explicit prompt caching cache everything up to and including the
+// part before it, across the tools, system prompt and messages. Providers that
+// cache automatically, or not at all, ignore it.
+type CachePointPart struct {
+	// TTL is how long the cached prefix is kept. Zero uses the provider's
+	// default. Anthropic accepts 5 minutes and 1 hour.
+	TTL time.Duration `json:"ttl,omitempty"`
+}
+
+func (CachePointPart) IsPart() {}
+
+// ThinkingPart is reasoning produced by the model before its answer, returned
+// by providers that expose it. Assistant messages must be sent back with their
+// thinking parts unchanged, including the signature, for the model to continue
+// a tool-use turn.
+type ThinkingPart struct {
+	// Text is the model's reasoning. For redacted thinking it holds the
+	// encrypted reasoning instead.
+	Text string `json:"text"`
+	// Signature verifies that the thinking was produced by the model.
+	Signature string `json:"signature,omitempty"`
+	// Redacted is true when the provider encrypted the reasoning.
+	Redacted bool `json:"redacted,omitempty"`
+}
+
+func (ThinkingPart) IsPart() {}
+
+var (
+	partTypesMu     sync.RWMutex
+	partTypesByName = map[string]reflect.Type{}
+	partNamesByType = map[reflect.Type]string{}
+)
+
+func init() {
+	RegisterPartType("text", TextPart{})
+	RegisterPartType("tool_call", ToolCallPart{})
+	RegisterPartType("tool_result", ToolResultPart{})
+	RegisterPartType("document", DocumentPart{})
+	RegisterPartType("image", ImagePart{})
+	RegisterPartType("audio", AudioPart{})
+	RegisterPartType("video", VideoPart{})
+	RegisterPartType("cache_point", CachePointPart{})
+	RegisterPartType("thinking", ThinkingPart{})
+}
+
+// RegisterPartType registers a Part implementation under name so that messages
+// containing it can be encoded to and decoded from JSON. Providers call this for
+// their own part types. Registering the same name twice replaces the previous
+// registration.
+func RegisterPartType(name string, part Part) {
+	partTypesMu.Lock()
+	defer partTypesMu.Unlock()
+
+	t := reflect.TypeOf(part)
+	partTypesByName[name] = t
+	partNamesByType[t] = name
+}
+
+// MarshalPart encodes part as a JSON object with an additional "type" field
+// holding its registered name.
+func MarshalPart(part Part) ([]byte, error) {
+	partTypesMu.RLock()
+	name, ok := partNamesByType[reflect.TypeOf(part)]
+	partTypesMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("llms: part type %T is not registered", part)
+	}
+
+	data, err := json.Marshal(part)
+	if err != nil {
+		return nil, err
+	}
+
+	fields := map[string]json.RawMessage{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, fmt.Errorf("llms: part type %T must encode to a JSON object: %w", part, err)
+	}
+
+	fields["type"], _ = json.Marshal(name)
+
+	return json.Marshal(fields)
+}
+
+// UnmarshalPart decodes a part previously encoded with MarshalPart.
+func UnmarshalPart(data []byte) (Part, error) {
+	var envelope struct {
+		Type string `json:"type"`
+	}
+	if err := json.Unmarshal(data, &envelope); err != nil {
+		return nil, err
+	}
+
+	partTypesMu.RLock()
+	t, ok := partTypesByName[envelope.Type]
+	partTypesMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("llms: unknown part type %q", envelope.Type)
+	}
+
+	v := reflect.New(t)
+	if err := json.Unmarshal(data, v.Interface()); err != nil {
+		return nil, fmt.Errorf("llms: failed to decode %q part: %w", envelope.Type, err)
+	}
+
+	part, ok := v.Elem().Interface().(Part)
+	if !ok {
+		return nil, fmt.Errorf("llms: registered type %s does not implement Part", t)
+	}
+
+	return part, nil
+}
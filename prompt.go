@@ -0,0 +1,110 @@
+package llms
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+	"time"
+)
+
+// SectionKind orders the sections of a system prompt.
+type SectionKind int
+
+const (
+	// SectionPersona describes who the model is.
+	SectionPersona SectionKind = iota
+	// SectionRules lists what the model must and must not do.
+	SectionRules
+	// SectionTools explains when and how to use the tools.
+	SectionTools
+	// SectionContext holds information that changes between requests, such
+	// as the date or details about the user. It comes last so that changes to
+	// it don't invalidate the cached prefix of the prompt.
+	SectionContext
+)
+
+// PromptSection is a section of a system prompt composed with NewSystemPrompt.
+type PromptSection struct {
+	Kind SectionKind
+	// Title, if set, is rendered as a Markdown heading above the text.
+	Title string
+	Text  string
+	// Cache, if set, is placed after the section, so that providers with
+	// explicit prompt caching, like Anthropic, cache the prompt up to and
+	// including it.
+	Cache *CachePointPart
+}
+
+// Cached returns a copy of s that marks the end of a cacheable prefix. A ttl
+// of zero uses the provider's default. Anthropic allows at most four cache
+// points per request, across the tools, system prompt and messages.
+func (s PromptSection) Cached(ttl time.Duration) PromptSection {
+	s.Cache = &CachePointPart{TTL: ttl}
+	return s
+}
+
+// PersonaSection returns a section describing who the model is.
+func PersonaSection(text string) PromptSection {
+	return PromptSection{Kind: SectionPersona, Text: text}
+}
+
+// RulesSection returns a section listing rules, one per line.
+func RulesSection(rules ...string) PromptSection {
+	lines := make([]string, 0, len(rules))
+	for _, rule := range rules {
+		lines = append(lines, "- "+rule)
+	}
+	return PromptSection{Kind: SectionRules, Title: "Rules", Text: strings.Join(lines, "\n")}
+}
+
+// ToolGuidanceSection returns a section explaining how to use the tools.
+func ToolGuidanceSection(text string) PromptSection {
+	return PromptSection{Kind: SectionTools, Title: "Tools", Text: text}
+}
+
+// ContextSection returns a section of information that changes between
+// requests.
+func ContextSection(title, text string) PromptSection {
+	return PromptSection{Kind: SectionContext, Title: title, Text: text}
+}
+
+// NewSystemPrompt composes a system message from sections. Sections are ordered
+// by kind, and sections of the same kind keep the order they were given in, so
+// the same sections always produce the same prompt. Each section is a separate
+// text part, followed by its cache point if it has one. Sections without text
+// are left out.
+//
+//	msg := llms.NewSystemPrompt(
+//		llms.PersonaSection("You are a helpful travel agent.").Cached(0),
+//		llms.ContextSection("Today", time.Now().Format(time.DateOnly)),
+//		llms.RulesSection("Only book refundable fares.").Cached(0),
+//	)
+func NewSystemPrompt(sections ...PromptSection) Message {
+	sections = slices.Clone(sections)
+	slices.SortStableFunc(sections, func(a, b PromptSection) int {
+		return cmp.Compare(a.Kind, b.Kind)
+	})
+
+	msg := Message{Role: RoleSystem}
+	for _, s := range sections {
+		if s.Text == "" {
+			continue
+		}
+
+		text := s.Text
+		if s.Title != "" {
+			text = "## " + s.Title + "\n\n" + text
+		}
+		// Providers without separate system blocks join the parts
+		// together.
+		if len(msg.Parts) > 0 {
+			text = "\n\n" + text
+		}
+
+		msg.Parts = append(msg.Parts, TextPart{Text: text})
+		if s.Cache != nil {
+			msg.Parts = append(msg.Parts, *s.Cache)
+		}
+	}
+	return msg
+}
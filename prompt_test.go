@@ -0,0 +1,27 @@
+package llms
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewSystemPrompt(t *testing.T) {
+	msg := NewSystemPrompt(
+		ContextSection("User", "Ada"),
+		RulesSection("Be brief.", "Be kind.").Cached(time.Hour),
+		PersonaSection("You are a travel agent.").Cached(0),
+		ToolGuidanceSection(""),
+		ContextSection("Today", "2025-01-01"),
+	)
+
+	assert.Equal(t, Message{Role: RoleSystem, Parts: []Part{
+		TextPart{Text: "You are a travel agent."},
+		CachePointPart{},
+		TextPart{Text: "\n\n## Rules\n\n- Be brief.\n- Be kind."},
+		CachePointPart{TTL: time.Hour},
+		TextPart{Text: "\n\n## User\n\nAda"},
+		TextPart{Text: "\n\n## Today\n\n2025-01-01"},
+	}}, msg)
+}
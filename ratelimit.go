@@ -0,0 +1,212 @@
+package llms
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// RateLimit describes the per minute limits for a single model. A zero value for
+// either field disables that limit.
+type RateLimit struct {
+	RequestsPerMinute int
+	TokensPerMinute   int
+}
+
+// RateLimiter enforces requests-per-minute and tokens-per-minute limits for a
+// set of models using token buckets. A single RateLimiter is meant to be shared
+// by every client talking to the same account so that bursts from different
+// goroutines are smoothed out before they hit the provider. It is safe for
+// concurrent use.
+type RateLimiter struct {
+	mu      sync.Mutex
+	limits  map[string]RateLimit
+	buckets map[string]*rateBuckets
+	now     func() time.Time
+}
+
+// NewRateLimiter creates a RateLimiter with the given per model limits. The "*"
+// key, if present, applies to every model without an explicit entry.
+func NewRateLimiter(limits map[string]RateLimit) *RateLimiter {
+	return &RateLimiter{
+		limits:  limits,
+		buckets: map[string]*rateBuckets{},
+		now:     time.Now,
+	}
+}
+
+type rateBuckets struct {
+	requests *tokenBucket
+	tokens   *tokenBucket
+}
+
+// tokenBucket is a token bucket that allows its level to go negative. Callers
+// reserve capacity up front and then wait for the bucket to refill, which keeps
+// waiters in FIFO order.
+type tokenBucket struct {
+	capacity float64
+	level    float64
+	perSec   float64
+	last     time.Time
+}
+
+func newTokenBucket(perMinute int, now time.Time) *tokenBucket {
+	if perMinute <= 0 {
+		return nil
+	}
+	return &tokenBucket{
+		capacity: float64(perMinute),
+		level:    float64(perMinute),
+		perSec:   float64(perMinute) / 60,
+		last:     now,
+	}
+}
+
+// reserve takes n from the bucket and returns how long the caller must wait
+// before the reservation is covered.
+func (b *tokenBucket) reserve(n float64, now time.Time) time.Duration {
+	if b == nil {
+		return 0
+	}
+
+	b.level = min(b.capacity, b.level+now.Sub(b.last).Seconds()*b.perSec)
+	b.last = now
+
+	// A single request larger than the bucket could never be satisfied, so
+	// clamp it to a full bucket.
+	b.level -= min(n, b.capacity)
+	if b.level >= 0 {
+		return 0
+	}
+
+	return time.Duration(-b.level / b.perSec * float64(time.Second))
+}
+
+func (b *tokenBucket) cancel(n float64) {
+	if b == nil {
+		return
+	}
+	b.level = min(b.capacity, b.level+min(n, b.capacity))
+}
+
+func (l *RateLimiter) bucketsFor(model string) *rateBuckets {
+	if b, ok := l.buckets[model]; ok {
+		return b
+	}
+
+	limit, ok := l.limits[model]
+	if !ok {
+		limit, ok = l.limits["*"]
+	}
+	if !ok {
+		return nil
+	}
+
+	now := l.now()
+	b := &rateBuckets{
+		requests: newTokenBucket(limit.RequestsPerMinute, now),
+		tokens:   newTokenBucket(limit.TokensPerMinute, now),
+	}
+	l.buckets[model] = b
+
+	return b
+}
+
+// Wait blocks until a request for model consuming the given number of tokens is
+// allowed, or until ctx is done.
+func (l *RateLimiter) Wait(ctx context.Context, model string, tokens int) error {
+	l.mu.Lock()
+	b := l.bucketsFor(model)
+	if b == nil {
+		l.mu.Unlock()
+		return nil
+	}
+
+	now := l.now()
+	delay := max(
+		b.requests.reserve(1, now),
+		b.tokens.reserve(float64(tokens), now),
+	)
+	l.mu.Unlock()
+
+	if delay <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		l.mu.Lock()
+		b.requests.cancel(1)
+		b.tokens.cancel(float64(tokens))
+		l.mu.Unlock()
+		return ctx.Err()
+	}
+}
+
+// EstimateTokens returns a rough estimate of the number of tokens in messages,
+// using the common heuristic of four characters per token. It is intended for
+// budgeting, not billing.
+func EstimateTokens(messages []Message) int {
+	chars := 0
+	for _, m := range messages {
+		for _, part := range m.Parts {
+			switch p := part.(type) {
+			case TextPart:
+				chars += len(p.Text)
+			case ToolCallPart:
+				chars += len(p.Name) + len(p.Input)
+			case ToolResultPart:
+				chars += len(p.Result)
+			}
+		}
+	}
+
+	return (chars + 3) / 4
+}
+
+// WithRateLimit wraps llm so that every request first waits on limiter for the
+// given model. The token cost of a request is estimated with EstimateTokens.
+func WithRateLimit(llm LLM, limiter *RateLimiter, model string) LLM {
+	return &rateLimited{
+		llm:     llm,
+		limiter: limiter,
+		model:   model,
+	}
+}
+
+// RateLimiting returns a Middleware that waits on limiter before every
+// request. See WithRateLimit.
+func RateLimiting(limiter *RateLimiter, model string) Middleware {
+	return func(llm LLM) LLM {
+		return WithRateLimit(llm, limiter, model)
+	}
+}
+
+type rateLimited struct {
+	llm     LLM
+	limiter *RateLimiter
+	model   string
+}
+
+func (r *rateLimited) ModelName() string {
+	return modelName(r.llm)
+}
+
+func (r *rateLimited) Generate(ctx context.Context, messages []Message) (*Response, error) {
+	if err := r.limiter.Wait(ctx, r.model, EstimateTokens(messages)); err != nil {
+		return nil, err
+	}
+	return r.llm.Generate(ctx, messages)
+}
+
+func (r *rateLimited) GenerateStream(ctx context.Context, messages []Message, fn StreamFunc) (*Response, error) {
+	if err := r.limiter.Wait(ctx, r.model, EstimateTokens(messages)); err != nil {
+		return nil, err
+	}
+	return r.llm.GenerateStream(ctx, messages, fn)
+}
@@ -0,0 +1,94 @@
+package llms
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRateLimiter_RequestsPerMinute(t *testing.T) {
+	limiter := NewRateLimiter(map[string]RateLimit{
+		"model": {RequestsPerMinute: 2},
+	})
+
+	ctx := context.Background()
+	require.NoError(t, limiter.Wait(ctx, "model", 0))
+	require.NoError(t, limiter.Wait(ctx, "model", 0))
+
+	// The bucket is now empty, so the third request has to wait ~30s.
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, limiter.Wait(ctx, "model", 0), context.DeadlineExceeded)
+}
+
+func TestRateLimiter_TokensPerMinute(t *testing.T) {
+	limiter := NewRateLimiter(map[string]RateLimit{
+		"*": {TokensPerMinute: 100},
+	})
+
+	ctx := context.Background()
+	require.NoError(t, limiter.Wait(ctx, "any-model", 100))
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, limiter.Wait(ctx, "any-model", 50), context.DeadlineExceeded)
+}
+
+func TestRateLimiter_Refill(t *testing.T) {
+	now := time.Now()
+	limiter := NewRateLimiter(map[string]RateLimit{
+		"model": {RequestsPerMinute: 60},
+	})
+	limiter.now = func() time.Time { return now }
+
+	for range 60 {
+		require.NoError(t, limiter.Wait(context.Background(), "model", 0))
+	}
+
+	// One second later exactly one more request is available.
+	now = now.Add(time.Second)
+	require.NoError(t, limiter.Wait(context.Background(), "model", 0))
+}
+
+func TestRateLimiter_UnlimitedModel(t *testing.T) {
+	limiter := NewRateLimiter(map[string]RateLimit{
+		"model": {RequestsPerMinute: 1},
+	})
+
+	for range 10 {
+		require.NoError(t, limiter.Wait(context.Background(), "other", 0))
+	}
+}
+
+func TestWithRateLimit_SharedAcrossGoroutines(t *testing.T) {
+	limiter := NewRateLimiter(map[string]RateLimit{
+		"model": {RequestsPerMinute: 5},
+	})
+	fake := newFakeLLM(fakeResult{resp: textResponse("ok")})
+	llm := WithRateLimit(fake, limiter, "model")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _ = llm.Generate(ctx, nil)
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, 5, fake.Calls())
+}
+
+func TestEstimateTokens(t *testing.T) {
+	messages := []Message{NewTextMessage(RoleUser, "12345678")}
+	assert.Equal(t, 2, EstimateTokens(messages))
+	assert.Equal(t, 0, EstimateTokens(nil))
+}
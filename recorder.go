@@ -0,0 +1,266 @@
+package llms
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// RecorderMode controls whether a Recorder talks to the network.
+type RecorderMode string
+
+const (
+	// RecorderModeReplay serves every request from the fixture file and fails
+	// requests that have no recorded interaction. This is the mode CI should
+	// use.
+	RecorderModeReplay RecorderMode = "replay"
+	// RecorderModeRecord sends every request to the network and records the
+	// sanitized interaction to the fixture file, replacing its contents.
+	RecorderModeRecord RecorderMode = "record"
+	// RecorderModeReplayOrRecord replays recorded interactions and falls back to
+	// the network, recording the result, for requests that have none.
+	RecorderModeReplayOrRecord RecorderMode = "replay_or_record"
+)
+
+// ErrNoRecordedInteraction is returned by a Recorder in replay mode when a
+// request has no matching recorded interaction.
+var ErrNoRecordedInteraction = errors.New("llms: no recorded interaction for request")
+
+// DefaultRedactedHeaders are the request headers removed from fixtures and
+// redacted from HTTP logs. They carry credentials for the supported providers.
+var DefaultRedactedHeaders = []string{
+	"Authorization",
+	"X-Api-Key",
+	"X-Goog-Api-Key",
+	"Api-Key",
+	"Openai-Organization",
+	"Openai-Project",
+	"Cookie",
+	"Set-Cookie",
+}
+
+// DefaultRedactedQueryParams are the query parameters removed from recorded
+// URLs and redacted from HTTP logs.
+var DefaultRedactedQueryParams = []string{"key", "api_key"}
+
+// Interaction is a single recorded request/response pair.
+type Interaction struct {
+	Request  RecordedRequest  `json:"request"`
+	Response RecordedResponse `json:"response"`
+}
+
+// RecordedRequest is the sanitized form of a recorded request.
+type RecordedRequest struct {
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Header http.Header `json:"header,omitempty"`
+	Body   string      `json:"body,omitempty"`
+}
+
+// RecordedResponse is the sanitized form of a recorded response. Streaming
+// responses are recorded in full, so replaying them produces the same server
+// sent events.
+type RecordedResponse struct {
+	StatusCode int         `json:"status_code"`
+	Header     http.Header `json:"header,omitempty"`
+	Body       string      `json:"body,omitempty"`
+}
+
+// RecorderConfig configures a Recorder.
+type RecorderConfig struct {
+	// Mode defaults to RecorderModeReplay.
+	Mode RecorderMode
+	// Path is the fixture file interactions are read from and written to.
+	Path string
+	// Transport is used for real requests. Defaults to http.DefaultTransport.
+	Transport http.RoundTripper
+	// Sanitize, if set, is called on every interaction after the default
+	// redactions and before it is written to the fixture file.
+	Sanitize func(*Interaction)
+}
+
+// Recorder is an http.RoundTripper that records provider traffic to sanitized
+// fixture files and replays it, so that tests can run deterministically without
+// API keys. It is safe for concurrent use.
+type Recorder struct {
+	config RecorderConfig
+
+	mu           sync.Mutex
+	interactions []Interaction
+	used         []bool
+}
+
+// NewRecorder creates a Recorder, loading any existing fixture file unless the
+// mode is RecorderModeRecord.
+func NewRecorder(config RecorderConfig) (*Recorder, error) {
+	if config.Mode == "" {
+		config.Mode = RecorderModeReplay
+	}
+	if config.Transport == nil {
+		config.Transport = http.DefaultTransport
+	}
+
+	r := &Recorder{config: config}
+
+	if config.Mode == RecorderModeRecord {
+		return r, nil
+	}
+
+	data, err := os.ReadFile(config.Path)
+	switch {
+	case errors.Is(err, fs.ErrNotExist) && config.Mode == RecorderModeReplayOrRecord:
+		return r, nil
+	case err != nil:
+		return nil, fmt.Errorf("llms: failed to read fixture %s: %w", config.Path, err)
+	}
+
+	if err := json.Unmarshal(data, &r.interactions); err != nil {
+		return nil, fmt.Errorf("llms: failed to decode fixture %s: %w", config.Path, err)
+	}
+	r.used = make([]bool, len(r.interactions))
+
+	return r, nil
+}
+
+// Client returns an http.Client using the recorder as its transport.
+func (r *Recorder) Client() *http.Client {
+	return &http.Client{Transport: r}
+}
+
+// RoundTrip implements the http.RoundTripper interface
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	recorded := RecordedRequest{
+		Method: req.Method,
+		URL:    sanitizeURL(req.URL),
+		Header: sanitizeHeader(req.Header),
+		Body:   string(body),
+	}
+
+	if r.config.Mode != RecorderModeRecord {
+		if resp, ok := r.replay(req, recorded); ok {
+			return resp, nil
+		}
+		if r.config.Mode == RecorderModeReplay {
+			return nil, fmt.Errorf("%w: %s %s", ErrNoRecordedInteraction, recorded.Method, recorded.URL)
+		}
+	}
+
+	resp, err := r.config.Transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	respBody, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+
+	interaction := Interaction{
+		Request: recorded,
+		Response: RecordedResponse{
+			StatusCode: resp.StatusCode,
+			Header:     sanitizeHeader(resp.Header),
+			Body:       string(respBody),
+		},
+	}
+	if r.config.Sanitize != nil {
+		r.config.Sanitize(&interaction)
+	}
+
+	if err := r.record(interaction); err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// replay returns the first unused interaction matching the request.
+func (r *Recorder) replay(req *http.Request, recorded RecordedRequest) (*http.Response, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i, in := range r.interactions {
+		if r.used[i] || in.Request.Method != recorded.Method || in.Request.URL != recorded.URL || in.Request.Body != recorded.Body {
+			continue
+		}
+		r.used[i] = true
+
+		header := in.Response.Header.Clone()
+		if header == nil {
+			header = http.Header{}
+		}
+
+		return &http.Response{
+			Status:        fmt.Sprintf("%d %s", in.Response.StatusCode, http.StatusText(in.Response.StatusCode)),
+			StatusCode:    in.Response.StatusCode,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        header,
+			Body:          io.NopCloser(strings.NewReader(in.Response.Body)),
+			ContentLength: int64(len(in.Response.Body)),
+			Request:       req,
+		}, true
+	}
+
+	return nil, false
+}
+
+func (r *Recorder) record(in Interaction) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.interactions = append(r.interactions, in)
+	r.used = append(r.used, true)
+
+	data, err := json.MarshalIndent(r.interactions, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(r.config.Path), 0o755); err != nil {
+		return err
+	}
+
+	return os.WriteFile(r.config.Path, data, 0o644)
+}
+
+func sanitizeHeader(h http.Header) http.Header {
+	out := h.Clone()
+	for _, name := range DefaultRedactedHeaders {
+		out.Del(name)
+	}
+	return out
+}
+
+func sanitizeURL(u *url.URL) string {
+	clone := *u
+	q := clone.Query()
+	for _, name := range DefaultRedactedQueryParams {
+		q.Del(name)
+	}
+	clone.RawQuery = q.Encode()
+	return clone.String()
+}
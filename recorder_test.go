@@ -0,0 +1,70 @@
+package llms
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecorder_RecordAndReplay(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"echo":` + string(body) + `}`))
+	}))
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "fixtures", "echo.json")
+
+	rec, err := NewRecorder(RecorderConfig{Mode: RecorderModeRecord, Path: path})
+	require.NoError(t, err)
+
+	req, _ := http.NewRequest(http.MethodPost, server.URL+"/v1/messages?key=secret", strings.NewReader(`"hi"`))
+	req.Header.Set("X-Api-Key", "secret")
+	resp, err := rec.Client().Do(req)
+	require.NoError(t, err)
+	body, _ := io.ReadAll(resp.Body)
+	assert.Equal(t, `{"echo":"hi"}`, string(body))
+
+	fixture, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.NotContains(t, string(fixture), "secret")
+
+	replay, err := NewRecorder(RecorderConfig{Path: path})
+	require.NoError(t, err)
+
+	req, _ = http.NewRequest(http.MethodPost, server.URL+"/v1/messages?key=other", strings.NewReader(`"hi"`))
+	resp, err = replay.Client().Do(req)
+	require.NoError(t, err)
+	body, _ = io.ReadAll(resp.Body)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, `{"echo":"hi"}`, string(body))
+	assert.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+	assert.Equal(t, 1, calls, "replay should not hit the network")
+
+	// Each interaction is only replayed once.
+	req, _ = http.NewRequest(http.MethodPost, server.URL+"/v1/messages", strings.NewReader(`"hi"`))
+	_, err = replay.Client().Do(req)
+	assert.ErrorIs(t, err, ErrNoRecordedInteraction)
+}
+
+func TestRecorder_ReplayMissingFixture(t *testing.T) {
+	_, err := NewRecorder(RecorderConfig{Path: filepath.Join(t.TempDir(), "missing.json")})
+	assert.Error(t, err)
+
+	rec, err := NewRecorder(RecorderConfig{
+		Mode: RecorderModeReplayOrRecord,
+		Path: filepath.Join(t.TempDir(), "missing.json"),
+	})
+	require.NoError(t, err)
+	assert.NotNil(t, rec)
+}
@@ -0,0 +1,127 @@
+package llms
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ErrDuplicateTool is returned when registering a tool whose name is already
+// taken.
+var ErrDuplicateTool = errors.New("llms: duplicate tool name")
+
+// ToolRegistry is a named set of tools that can be shared between provider
+// clients (see each provider's WithToolRegistry) and RunTools. Tools are kept
+// in registration order. A ToolRegistry is safe for concurrent use.
+type ToolRegistry struct {
+	mu    sync.RWMutex
+	tools map[string]Tool
+	order []string
+}
+
+// NewToolRegistry creates a ToolRegistry containing tools. It returns an error
+// wrapping ErrDuplicateTool if two tools share a name.
+func NewToolRegistry(tools ...Tool) (*ToolRegistry, error) {
+	r := &ToolRegistry{tools: map[string]Tool{}}
+	if err := r.Register(tools...); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Register adds tools to the registry. If any tool's name is already taken,
+// none of the tools are added and an error wrapping ErrDuplicateTool is
+// returned.
+func (r *ToolRegistry) Register(tools ...Tool) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.tools == nil {
+		r.tools = map[string]Tool{}
+	}
+
+	seen := map[string]bool{}
+	for _, tool := range tools {
+		name := tool.Name()
+		if _, ok := r.tools[name]; ok || seen[name] {
+			return fmt.Errorf("%w: %q", ErrDuplicateTool, name)
+		}
+		seen[name] = true
+	}
+
+	for _, tool := range tools {
+		r.tools[tool.Name()] = tool
+		r.order = append(r.order, tool.Name())
+	}
+
+	return nil
+}
+
+// Unregister removes the tool with the given name. It reports whether the tool
+// was registered.
+func (r *ToolRegistry) Unregister(name string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.tools[name]; !ok {
+		return false
+	}
+
+	delete(r.tools, name)
+	for i, n := range r.order {
+		if n == name {
+			r.order = append(r.order[:i], r.order[i+1:]...)
+			break
+		}
+	}
+
+	return true
+}
+
+// Get returns the tool with the given name.
+func (r *ToolRegistry) Get(name string) (Tool, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	tool, ok := r.tools[name]
+	return tool, ok
+}
+
+// Executable returns the tool with the given name if it is registered and is
+// an ExecutableTool.
+func (r *ToolRegistry) Executable(name string) (ExecutableTool, bool) {
+	tool, ok := r.Get(name)
+	if !ok {
+		return nil, false
+	}
+	exec, ok := tool.(ExecutableTool)
+	return exec, ok
+}
+
+// List returns the registered tools in registration order.
+func (r *ToolRegistry) List() []Tool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	out := make([]Tool, 0, len(r.order))
+	for _, name := range r.order {
+		out = append(out, r.tools[name])
+	}
+	return out
+}
+
+// Names returns the names of the registered tools in registration order.
+func (r *ToolRegistry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return append([]string(nil), r.order...)
+}
+
+// Len returns the number of registered tools.
+func (r *ToolRegistry) Len() int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return len(r.order)
+}
@@ -0,0 +1,80 @@
+package llms
+
+import (
+	"context"
+	"testing"
+
+	"github.com/invopop/jsonschema"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestToolRegistry(t *testing.T) {
+	registry, err := NewToolRegistry(echoTool{})
+	require.NoError(t, err)
+
+	require.NoError(t, registry.Register(panicTool{}))
+	assert.Equal(t, []string{"echo", "panic"}, registry.Names())
+	assert.Equal(t, 2, registry.Len())
+	assert.Equal(t, []Tool{echoTool{}, panicTool{}}, registry.List())
+
+	tool, ok := registry.Get("echo")
+	require.True(t, ok)
+	assert.Equal(t, echoTool{}, tool)
+
+	_, ok = registry.Get("missing")
+	assert.False(t, ok)
+
+	assert.True(t, registry.Unregister("echo"))
+	assert.False(t, registry.Unregister("echo"))
+	assert.Equal(t, []string{"panic"}, registry.Names())
+}
+
+// hostedTool describes a tool that the provider executes itself.
+type hostedTool struct{}
+
+func (hostedTool) Name() string               { return "hosted" }
+func (hostedTool) Description() string        { return "Runs on the provider" }
+func (hostedTool) Schema() *jsonschema.Schema { return nil }
+
+func TestToolRegistry_Executable(t *testing.T) {
+	registry, err := NewToolRegistry(echoTool{}, hostedTool{})
+	require.NoError(t, err)
+
+	exec, ok := registry.Executable("echo")
+	require.True(t, ok)
+	assert.Equal(t, echoTool{}, exec)
+
+	_, ok = registry.Executable("hosted")
+	assert.False(t, ok)
+
+	_, ok = registry.Executable("missing")
+	assert.False(t, ok)
+}
+
+func TestToolRegistry_Duplicates(t *testing.T) {
+	_, err := NewToolRegistry(echoTool{}, echoTool{})
+	assert.ErrorIs(t, err, ErrDuplicateTool)
+
+	registry, err := NewToolRegistry(echoTool{})
+	require.NoError(t, err)
+
+	// A batch containing a duplicate is rejected as a whole.
+	err = registry.Register(panicTool{}, echoTool{})
+	assert.ErrorIs(t, err, ErrDuplicateTool)
+	assert.Equal(t, []string{"echo"}, registry.Names())
+}
+
+func TestToolRegistry_WithRunTools(t *testing.T) {
+	registry, err := NewToolRegistry(echoTool{})
+	require.NoError(t, err)
+
+	fake := newFakeLLM(
+		fakeResult{resp: toolCallResponse(ToolCallPart{ID: "call_1", Name: "echo", Input: []byte(`{"text":"hi"}`)})},
+		fakeResult{resp: textResponse("done")},
+	)
+
+	result, err := RunTools(context.Background(), fake, nil, registry.List(), RunToolsOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "hi", result.Messages[1].Parts[0].(ToolResultPart).Result)
+}
@@ -0,0 +1,187 @@
+package llms
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+)
+
+// ErrUnrepairableJSON is returned by RepairJSON when the input cannot be
+// turned into valid JSON.
+var ErrUnrepairableJSON = errors.New("llms: unable to repair JSON")
+
+// RepairJSON makes a best effort at fixing malformed JSON produced by a model.
+// It fixes trailing commas, unquoted object keys, single-quoted strings, raw
+// newlines in strings, Python's True, False and None, and JSON truncated in
+// the middle of a string, literal or container, as happens when the model
+// runs out of tokens. Repaired reports whether the input needed fixing; valid
+// JSON is returned unchanged. If the result is still not valid JSON,
+// ErrUnrepairableJSON is returned.
+func RepairJSON(data []byte) (out []byte, repaired bool, err error) {
+	if json.Valid(data) {
+		return data, false, nil
+	}
+
+	out = repairJSON(bytes.TrimSpace(data))
+	if !json.Valid(out) {
+		return nil, false, ErrUnrepairableJSON
+	}
+	return out, true, nil
+}
+
+func repairJSON(in []byte) []byte {
+	var (
+		out   = make([]byte, 0, len(in)+8)
+		stack []byte // the open containers, '{' or '['
+		// expectKey is set when the next token of the innermost object is a
+		// key, and afterKey once that key has been read but not its colon.
+		expectKey, afterKey bool
+	)
+	inObject := func() bool {
+		return len(stack) > 0 && stack[len(stack)-1] == '{'
+	}
+
+	for i := 0; i < len(in); {
+		c := in[i]
+		switch {
+		case c == '"' || c == '\'':
+			s, n := repairString(in[i:])
+			out = append(out, s...)
+			i += n
+			if inObject() && expectKey {
+				expectKey, afterKey = false, true
+			}
+		case c == '{' || c == '[':
+			out = append(out, c)
+			stack = append(stack, c)
+			expectKey = c == '{'
+			i++
+		case c == '}' || c == ']':
+			out = closeValue(out, afterKey)
+			afterKey = false
+			if len(stack) > 0 {
+				// Use the closer that matches, whichever the model wrote.
+				out = append(out, closer(stack[len(stack)-1]))
+				stack = stack[:len(stack)-1]
+			}
+			expectKey = false
+			i++
+		case c == ',':
+			out = append(out, c)
+			expectKey = inObject()
+			i++
+		case c == ':':
+			out = append(out, c)
+			afterKey = false
+			i++
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			out = append(out, c)
+			i++
+		default:
+			n := bytes.IndexAny(in[i:], " \t\n\r,:{}[]\"'")
+			if n < 0 {
+				n = len(in) - i
+			}
+			word := string(in[i : i+n])
+			i += n
+
+			if inObject() && expectKey {
+				// An unquoted key.
+				key, _ := json.Marshal(word)
+				out = append(out, key...)
+				expectKey, afterKey = false, true
+				continue
+			}
+			out = append(out, repairLiteral(word, i == len(in))...)
+		}
+	}
+
+	out = closeValue(out, afterKey)
+	for j := len(stack) - 1; j >= 0; j-- {
+		out = append(out, closer(stack[j]))
+	}
+	return out
+}
+
+// repairString reads the string starting with the quote at in[0] and returns
+// it as a JSON string, along with the number of bytes read. An unterminated
+// string is closed.
+func repairString(in []byte) ([]byte, int) {
+	quote := in[0]
+	out := []byte{'"'}
+	for i := 1; i < len(in); i++ {
+		c := in[i]
+		switch {
+		case c == quote:
+			return append(out, '"'), i + 1
+		case c == '\\':
+			if i+1 == len(in) {
+				// A truncated escape sequence.
+				return append(out, '"'), len(in)
+			}
+			i++
+			if in[i] == '\'' {
+				out = append(out, '\'')
+			} else {
+				out = append(out, '\\', in[i])
+			}
+		case c == '"':
+			out = append(out, '\\', '"')
+		case c == '\n':
+			out = append(out, '\\', 'n')
+		case c == '\r':
+			out = append(out, '\\', 'r')
+		case c == '\t':
+			out = append(out, '\\', 't')
+		default:
+			out = append(out, c)
+		}
+	}
+	return append(out, '"'), len(in)
+}
+
+// repairLiteral fixes a bare word in value position. If it ends the input it
+// may have been truncated, so partial literals are completed.
+func repairLiteral(word string, last bool) string {
+	switch word {
+	case "True":
+		return "true"
+	case "False":
+		return "false"
+	case "None":
+		return "null"
+	}
+	if last {
+		for _, lit := range []string{"true", "false", "null"} {
+			if strings.HasPrefix(lit, word) {
+				return lit
+			}
+		}
+		// A truncated number, such as "1." or "2e".
+		return strings.TrimRight(word, ".-+eE")
+	}
+	return word
+}
+
+// closeValue prepares out to be followed by a closing bracket, removing a
+// trailing comma and supplying a value for a key left without one.
+func closeValue(out []byte, afterKey bool) []byte {
+	trimmed := bytes.TrimRight(out, " \t\n\r")
+	switch {
+	case afterKey:
+		return append(trimmed, ":null"...)
+	case bytes.HasSuffix(trimmed, []byte(",")):
+		return trimmed[:len(trimmed)-1]
+	case bytes.HasSuffix(trimmed, []byte(":")):
+		return append(trimmed, "null"...)
+	}
+	return out
+}
+
+func closer(open byte) byte {
+	if open == '{' {
+		return '}'
+	}
+	return ']'
+}
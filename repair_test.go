@@ -0,0 +1,51 @@
+package llms
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRepairJSON(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"trailing commas", `{"a": [1, 2,], "b": 3,}`, `{"a": [1, 2], "b": 3}`},
+		{"unquoted keys", `{a: 1, b_c: {d: true}}`, `{"a": 1, "b_c": {"d": true}}`},
+		{"single quotes", `{'a': 'it\'s "x"'}`, `{"a": "it's \"x\""}`},
+		{"python literals", `{"a": True, "b": False, "c": None}`, `{"a": true, "b": false, "c": null}`},
+		{"raw newline", "{\"a\": \"one\ntwo\"}", `{"a": "one\ntwo"}`},
+		{"truncated string", `{"location": "San Fran`, `{"location": "San Fran"}`},
+		{"truncated escape", `{"a": "x\`, `{"a": "x"}`},
+		{"truncated containers", `{"a": [{"b": 1`, `{"a": [{"b": 1}]}`},
+		{"truncated literal", `{"a": tr`, `{"a": true}`},
+		{"truncated number", `[1.`, `[1]`},
+		{"truncated after colon", `{"a": `, `{"a":null}`},
+		{"truncated after key", `{"a"`, `{"a":null}`},
+		{"truncated after comma", `{"a": 1, `, `{"a": 1}`},
+		{"mismatched closer", `{"a": 1]`, `{"a": 1}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, repaired, err := RepairJSON([]byte(tt.in))
+			require.NoError(t, err)
+			assert.True(t, repaired)
+			assert.Equal(t, tt.want, string(out))
+		})
+	}
+}
+
+func TestRepairJSON_Valid(t *testing.T) {
+	out, repaired, err := RepairJSON([]byte(`{"a": [1, 2]}`))
+	require.NoError(t, err)
+	assert.False(t, repaired)
+	assert.Equal(t, `{"a": [1, 2]}`, string(out))
+}
+
+func TestRepairJSON_Unrepairable(t *testing.T) {
+	_, _, err := RepairJSON([]byte(`{"a": hello world}`))
+	assert.ErrorIs(t, err, ErrUnrepairableJSON)
+}
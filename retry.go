@@ -0,0 +1,155 @@
+package llms
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"time"
+)
+
+// RetryConfig controls how WithRetry retries failed requests.
+type RetryConfig struct {
+	// MaxRetries is the maximum number of retries after the initial attempt.
+	// Defaults to 3; a negative value disables retries.
+	MaxRetries int
+	// InitialBackoff is the delay before the first retry. Defaults to 500ms.
+	InitialBackoff time.Duration
+	// MaxBackoff caps the computed exponential backoff. It does not cap delays
+	// requested by the provider via Retry-After. Defaults to 30s.
+	MaxBackoff time.Duration
+	// Multiplier is the factor the backoff grows by after each retry. Defaults
+	// to 2.
+	Multiplier float64
+
+	// ShouldRetry decides whether an error should be retried. Defaults to
+	// IsRetryable.
+	ShouldRetry func(error) bool
+	// OnRetry, if set, is called before sleeping ahead of each retry.
+	OnRetry func(attempt int, err error, delay time.Duration)
+}
+
+// DefaultRetryConfig returns the RetryConfig used when fields are left unset.
+func DefaultRetryConfig() RetryConfig {
+	return RetryConfig{
+		MaxRetries:     3,
+		InitialBackoff: 500 * time.Millisecond,
+		MaxBackoff:     30 * time.Second,
+		Multiplier:     2,
+	}
+}
+
+// WithRetry wraps llm so that requests failing with retryable errors (429, 5xx,
+// 529 overloaded, timeouts) are retried with jittered exponential backoff. If the
+// provider returned a Retry-After header, that delay is used instead.
+//
+// Streaming requests are only retried if no response has been delivered to the
+// StreamFunc yet.
+func WithRetry(llm LLM, config RetryConfig) LLM {
+	defaults := DefaultRetryConfig()
+	if config.MaxRetries == 0 {
+		config.MaxRetries = defaults.MaxRetries
+	}
+	if config.InitialBackoff == 0 {
+		config.InitialBackoff = defaults.InitialBackoff
+	}
+	if config.MaxBackoff == 0 {
+		config.MaxBackoff = defaults.MaxBackoff
+	}
+	if config.Multiplier == 0 {
+		config.Multiplier = defaults.Multiplier
+	}
+	if config.ShouldRetry == nil {
+		config.ShouldRetry = IsRetryable
+	}
+
+	return &retrier{llm: llm, config: config}
+}
+
+// Retry returns a Middleware that retries failed requests. See WithRetry.
+func Retry(config RetryConfig) Middleware {
+	return func(llm LLM) LLM {
+		return WithRetry(llm, config)
+	}
+}
+
+type retrier struct {
+	llm    LLM
+	config RetryConfig
+}
+
+func (r *retrier) ModelName() string {
+	return modelName(r.llm)
+}
+
+func (r *retrier) Generate(ctx context.Context, messages []Message) (*Response, error) {
+	for attempt := 0; ; attempt++ {
+		resp, err := r.llm.Generate(ctx, messages)
+		if err == nil {
+			return resp, nil
+		}
+
+		if !r.wait(ctx, attempt, err) {
+			return resp, err
+		}
+	}
+}
+
+func (r *retrier) GenerateStream(ctx context.Context, messages []Message, fn StreamFunc) (*Response, error) {
+	for attempt := 0; ; attempt++ {
+		delivered := false
+		resp, err := r.llm.GenerateStream(ctx, messages, func(resp *Response, err error) bool {
+			if resp != nil {
+				delivered = true
+			}
+			return fn(resp, err)
+		})
+		if err == nil {
+			return resp, nil
+		}
+
+		if delivered || !r.wait(ctx, attempt, err) {
+			return resp, err
+		}
+	}
+}
+
+// wait sleeps ahead of the next retry. It returns false if the request should
+// not be retried.
+func (r *retrier) wait(ctx context.Context, attempt int, err error) bool {
+	if attempt >= r.config.MaxRetries || ctx.Err() != nil || !r.config.ShouldRetry(err) {
+		return false
+	}
+
+	delay := r.backoff(attempt, err)
+	if r.config.OnRetry != nil {
+		r.config.OnRetry(attempt+1, err, delay)
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+// backoff returns the delay before the given retry attempt. Retry-After takes
+// precedence, otherwise an exponential backoff with equal jitter is used.
+func (r *retrier) backoff(attempt int, err error) time.Duration {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.RetryAfter > 0 {
+		return apiErr.RetryAfter
+	}
+
+	backoff := float64(r.config.InitialBackoff)
+	for range attempt {
+		backoff *= r.config.Multiplier
+	}
+	backoff = min(backoff, float64(r.config.MaxBackoff))
+
+	half := backoff / 2
+	return time.Duration(half + rand.Float64()*half)
+}
@@ -0,0 +1,122 @@
+package llms
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithRetry_Generate(t *testing.T) {
+	t.Run("retries retryable errors", func(t *testing.T) {
+		fake := newFakeLLM(
+			fakeResult{err: &APIError{StatusCode: http.StatusTooManyRequests}},
+			fakeResult{err: &APIError{StatusCode: 529}},
+			fakeResult{resp: textResponse("ok")},
+		)
+
+		attempts := []int{}
+		llm := WithRetry(fake, RetryConfig{
+			InitialBackoff: time.Millisecond,
+			OnRetry: func(attempt int, err error, delay time.Duration) {
+				attempts = append(attempts, attempt)
+			},
+		})
+
+		resp, err := llm.Generate(context.Background(), nil)
+		require.NoError(t, err)
+		assert.Equal(t, "resp_ok", resp.ID)
+		assert.Equal(t, 3, fake.Calls())
+		assert.Equal(t, []int{1, 2}, attempts)
+	})
+
+	t.Run("gives up after max retries", func(t *testing.T) {
+		fake := newFakeLLM(fakeResult{err: &APIError{StatusCode: http.StatusInternalServerError}})
+
+		llm := WithRetry(fake, RetryConfig{MaxRetries: 2, InitialBackoff: time.Millisecond})
+
+		_, err := llm.Generate(context.Background(), nil)
+		require.Error(t, err)
+		assert.Equal(t, 3, fake.Calls())
+	})
+
+	t.Run("negative max retries disables retries", func(t *testing.T) {
+		fake := newFakeLLM(fakeResult{err: &APIError{StatusCode: http.StatusInternalServerError}})
+
+		_, err := WithRetry(fake, RetryConfig{MaxRetries: -1, InitialBackoff: time.Millisecond}).Generate(context.Background(), nil)
+		require.Error(t, err)
+		assert.Equal(t, 1, fake.Calls())
+	})
+
+	t.Run("does not retry permanent errors", func(t *testing.T) {
+		fake := newFakeLLM(fakeResult{err: &APIError{StatusCode: http.StatusUnauthorized}})
+
+		_, err := WithRetry(fake, RetryConfig{InitialBackoff: time.Millisecond}).Generate(context.Background(), nil)
+		require.Error(t, err)
+		assert.Equal(t, 1, fake.Calls())
+	})
+
+	t.Run("respects retry after", func(t *testing.T) {
+		fake := newFakeLLM(
+			fakeResult{err: &APIError{StatusCode: http.StatusTooManyRequests, RetryAfter: 5 * time.Millisecond}},
+			fakeResult{resp: textResponse("ok")},
+		)
+
+		var delay time.Duration
+		llm := WithRetry(fake, RetryConfig{
+			InitialBackoff: time.Hour,
+			OnRetry:        func(_ int, _ error, d time.Duration) { delay = d },
+		})
+
+		_, err := llm.Generate(context.Background(), nil)
+		require.NoError(t, err)
+		assert.Equal(t, 5*time.Millisecond, delay)
+	})
+
+	t.Run("stops when context is cancelled", func(t *testing.T) {
+		fake := newFakeLLM(fakeResult{err: &APIError{StatusCode: http.StatusServiceUnavailable}})
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+
+		_, err := WithRetry(fake, RetryConfig{InitialBackoff: time.Hour}).Generate(ctx, nil)
+		require.Error(t, err)
+		assert.Equal(t, 1, fake.Calls())
+	})
+}
+
+func TestWithRetry_GenerateStream(t *testing.T) {
+	fake := newFakeLLM(
+		fakeResult{err: &APIError{StatusCode: 529}},
+		fakeResult{chunks: []*Response{textResponse("chunk")}, err: &APIError{StatusCode: 529}},
+		fakeResult{resp: textResponse("ok")},
+	)
+
+	llm := WithRetry(fake, RetryConfig{InitialBackoff: time.Millisecond})
+
+	_, err := llm.GenerateStream(context.Background(), nil, func(*Response, error) bool { return true })
+	require.Error(t, err, "should not retry once output was streamed")
+	assert.Equal(t, 2, fake.Calls())
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	tests := []struct {
+		name   string
+		header http.Header
+		want   time.Duration
+	}{
+		{"none", http.Header{}, 0},
+		{"seconds", http.Header{"Retry-After": {"2"}}, 2 * time.Second},
+		{"milliseconds", http.Header{"Retry-After-Ms": {"150"}}, 150 * time.Millisecond},
+		{"invalid", http.Header{"Retry-After": {"soon"}}, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, ParseRetryAfter(tt.header))
+		})
+	}
+}
@@ -0,0 +1,213 @@
+package llms
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// RetryTransportConfig controls how a RetryTransport retries failed requests.
+type RetryTransportConfig struct {
+	// Transport sends the requests. Defaults to http.DefaultTransport.
+	Transport http.RoundTripper
+	// MaxRetries is the maximum number of retries after the initial attempt.
+	// Defaults to 3; a negative value disables retries, as with WithRetry.
+	MaxRetries int
+	// InitialBackoff is the delay before the first retry when the server does
+	// not request one. Defaults to 500ms.
+	InitialBackoff time.Duration
+	// MaxBackoff caps the computed exponential backoff. Defaults to 30s.
+	MaxBackoff time.Duration
+	// Multiplier is the factor the backoff grows by after each retry.
+	// Defaults to 2.
+	Multiplier float64
+	// MaxRetryAfter is the longest delay requested by the server that is
+	// waited for. Responses asking for longer delays are returned without
+	// retrying. Defaults to one minute.
+	MaxRetryAfter time.Duration
+
+	// ShouldRetry decides whether a request should be retried, given its
+	// response or the error sending it. Defaults to retrying connection
+	// errors and 408, 429 and 5xx responses.
+	ShouldRetry func(*http.Response, error) bool
+	// OnRetry, if set, is called before sleeping ahead of each retry. resp is
+	// nil if the request failed without a response.
+	OnRetry func(attempt int, resp *http.Response, err error, delay time.Duration)
+}
+
+// RetryTransport is an http.RoundTripper that retries failed requests. It
+// waits for the delay the server asks for in the Retry-After header, or until
+// an exhausted rate limit resets according to the anthropic-ratelimit-* and
+// x-ratelimit-* headers, and otherwise backs off exponentially with jitter.
+//
+// Unlike WithRetry it works below the provider clients, so one transport can
+// be shared by clients of every provider through their WithHTTPClient
+// modifiers. The retries built into the provider SDKs should then be disabled.
+type RetryTransport struct {
+	config RetryTransportConfig
+}
+
+// NewRetryTransport creates a RetryTransport.
+func NewRetryTransport(config RetryTransportConfig) *RetryTransport {
+	defaults := DefaultRetryConfig()
+	if config.Transport == nil {
+		config.Transport = http.DefaultTransport
+	}
+	if config.MaxRetries == 0 {
+		config.MaxRetries = defaults.MaxRetries
+	}
+	if config.InitialBackoff == 0 {
+		config.InitialBackoff = defaults.InitialBackoff
+	}
+	if config.MaxBackoff == 0 {
+		config.MaxBackoff = defaults.MaxBackoff
+	}
+	if config.Multiplier == 0 {
+		config.Multiplier = defaults.Multiplier
+	}
+	if config.MaxRetryAfter == 0 {
+		config.MaxRetryAfter = time.Minute
+	}
+	if config.ShouldRetry == nil {
+		config.ShouldRetry = shouldRetryHTTP
+	}
+
+	return &RetryTransport{config: config}
+}
+
+// RoundTrip implements the http.RoundTripper interface
+func (t *RetryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// The body is sent again with each attempt, so it must be replayable.
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		body, err := io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		req = req.Clone(req.Context())
+		req.Body = io.NopCloser(bytes.NewReader(body))
+		req.GetBody = func() (io.ReadCloser, error) {
+			return io.NopCloser(bytes.NewReader(body)), nil
+		}
+	}
+
+	for attempt := 0; ; attempt++ {
+		attemptReq := req
+		if attempt > 0 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			attemptReq = req.Clone(req.Context())
+			attemptReq.Body = body
+		}
+
+		resp, err := t.config.Transport.RoundTrip(attemptReq)
+
+		delay, ok := t.delay(req.Context(), attempt, resp, err)
+		if !ok {
+			return resp, err
+		}
+
+		if t.config.OnRetry != nil {
+			t.config.OnRetry(attempt+1, resp, err, delay)
+		}
+		if resp != nil {
+			// Drain the body so the connection can be reused.
+			io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+			resp.Body.Close()
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// delay returns the delay before retrying a request, and false if it should
+// not be retried.
+func (t *RetryTransport) delay(ctx context.Context, attempt int, resp *http.Response, err error) (time.Duration, bool) {
+	if attempt >= t.config.MaxRetries || ctx.Err() != nil || !t.config.ShouldRetry(resp, err) {
+		return 0, false
+	}
+
+	if resp != nil {
+		requested := ParseRetryAfter(resp.Header)
+		if requested == 0 {
+			requested = rateLimitReset(resp.Header)
+		}
+		if requested > t.config.MaxRetryAfter {
+			return 0, false
+		}
+		if requested > 0 {
+			return requested, true
+		}
+	}
+
+	backoff := float64(t.config.InitialBackoff)
+	for range attempt {
+		backoff *= t.config.Multiplier
+	}
+	backoff = min(backoff, float64(t.config.MaxBackoff))
+
+	half := backoff / 2
+	return time.Duration(half + rand.Float64()*half), true
+}
+
+// shouldRetryHTTP is the default RetryTransportConfig.ShouldRetry.
+func shouldRetryHTTP(resp *http.Response, err error) bool {
+	if err != nil {
+		return !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests:
+		return true
+	case resp.StatusCode == http.StatusRequestTimeout:
+		return true
+	case resp.StatusCode >= 500:
+		return true
+	}
+	return false
+}
+
+// rateLimitHeaders lists the remaining and reset headers of each rate limit.
+var rateLimitHeaders = []struct{ remaining, reset string }{
+	{"anthropic-ratelimit-requests-remaining", "anthropic-ratelimit-requests-reset"},
+	{"anthropic-ratelimit-tokens-remaining", "anthropic-ratelimit-tokens-reset"},
+	{"anthropic-ratelimit-input-tokens-remaining", "anthropic-ratelimit-input-tokens-reset"},
+	{"anthropic-ratelimit-output-tokens-remaining", "anthropic-ratelimit-output-tokens-reset"},
+	{"x-ratelimit-remaining-requests", "x-ratelimit-reset-requests"},
+	{"x-ratelimit-remaining-tokens", "x-ratelimit-reset-tokens"},
+}
+
+// rateLimitReset returns the time until the last exhausted rate limit in
+// header resets, or 0 if none is exhausted. Anthropic reports resets as
+// RFC 3339 times and OpenAI as durations such as "1m30s".
+func rateLimitReset(header http.Header) time.Duration {
+	var longest time.Duration
+	for _, limit := range rateLimitHeaders {
+		if strings.TrimSpace(header.Get(limit.remaining)) != "0" {
+			continue
+		}
+
+		reset := strings.TrimSpace(header.Get(limit.reset))
+		var d time.Duration
+		if t, err := time.Parse(time.RFC3339, reset); err == nil {
+			d = time.Until(t)
+		} else if parsed, err := time.ParseDuration(reset); err == nil {
+			d = parsed
+		}
+		longest = max(longest, d)
+	}
+	return longest
+}
@@ -0,0 +1,157 @@
+package llms
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRetryTransport(t *testing.T) {
+	var attempts atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		assert.Equal(t, `{"prompt": "hi"}`, string(body))
+
+		switch attempts.Add(1) {
+		case 1:
+			w.Header().Set("Retry-After", "0.01")
+			w.WriteHeader(http.StatusTooManyRequests)
+		case 2:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		default:
+			w.Write([]byte("ok"))
+		}
+	}))
+	defer server.Close()
+
+	var delays []time.Duration
+	client := &http.Client{Transport: NewRetryTransport(RetryTransportConfig{
+		InitialBackoff: time.Millisecond,
+		OnRetry: func(attempt int, resp *http.Response, err error, delay time.Duration) {
+			assert.Equal(t, len(delays)+1, attempt)
+			delays = append(delays, delay)
+		},
+	})}
+
+	// The body cannot be replayed with GetBody.
+	req, err := http.NewRequest(http.MethodPost, server.URL, io.NopCloser(strings.NewReader(`{"prompt": "hi"}`)))
+	require.NoError(t, err)
+	require.Nil(t, req.GetBody)
+
+	resp, err := client.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+	assert.Equal(t, "ok", string(body))
+	assert.Equal(t, int32(3), attempts.Load())
+	require.Len(t, delays, 2)
+	assert.Equal(t, 10*time.Millisecond, delays[0])
+	assert.LessOrEqual(t, delays[1], 2*time.Millisecond)
+}
+
+func TestRetryTransport_GivesUp(t *testing.T) {
+	tests := []struct {
+		name       string
+		header     http.Header
+		status     int
+		maxRetries int
+		attempts   int32
+	}{
+		{"not retryable", nil, http.StatusBadRequest, 2, 1},
+		{"max retries", nil, http.StatusInternalServerError, 2, 3},
+		{"retries disabled", nil, http.StatusInternalServerError, -1, 1},
+		{"retry after too long", http.Header{"Retry-After": {"120"}}, http.StatusTooManyRequests, 2, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var attempts atomic.Int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				attempts.Add(1)
+				for k, v := range tt.header {
+					w.Header()[k] = v
+				}
+				w.WriteHeader(tt.status)
+			}))
+			defer server.Close()
+
+			client := &http.Client{Transport: NewRetryTransport(RetryTransportConfig{
+				MaxRetries:     tt.maxRetries,
+				InitialBackoff: time.Millisecond,
+			})}
+
+			resp, err := client.Get(server.URL)
+			require.NoError(t, err)
+			resp.Body.Close()
+			assert.Equal(t, tt.status, resp.StatusCode)
+			assert.Equal(t, tt.attempts, attempts.Load())
+		})
+	}
+}
+
+func TestRateLimitReset(t *testing.T) {
+	reset := time.Now().Add(30 * time.Second).UTC().Format(time.RFC3339)
+
+	tests := []struct {
+		name   string
+		header http.Header
+		want   time.Duration
+	}{
+		{"none", http.Header{}, 0},
+		{"not exhausted", http.Header{
+			"X-Ratelimit-Remaining-Requests": {"5"},
+			"X-Ratelimit-Reset-Requests":     {"10s"},
+		}, 0},
+		{"openai", http.Header{
+			"X-Ratelimit-Remaining-Requests": {"0"},
+			"X-Ratelimit-Reset-Requests":     {"1m30s"},
+			"X-Ratelimit-Remaining-Tokens":   {"0"},
+			"X-Ratelimit-Reset-Tokens":       {"250ms"},
+		}, 90 * time.Second},
+		{"anthropic", http.Header{
+			"Anthropic-Ratelimit-Tokens-Remaining": {"0"},
+			"Anthropic-Ratelimit-Tokens-Reset":     {reset},
+		}, 30 * time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.InDelta(t, tt.want, rateLimitReset(tt.header), float64(time.Second))
+		})
+	}
+}
+
+func TestNewHTTPClient_Retry(t *testing.T) {
+	var attempts atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if attempts.Add(1) == 1 {
+			w.Header().Set("X-Ratelimit-Remaining-Requests", "0")
+			w.Header().Set("X-Ratelimit-Reset-Requests", "5ms")
+			w.WriteHeader(http.StatusTooManyRequests)
+		}
+	}))
+	defer server.Close()
+
+	var logged atomic.Int32
+	client := NewHTTPClient(HTTPClientOptions{
+		Logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+		Config: &LoggingConfig{
+			OnRequest: func(*http.Request) { logged.Add(1) },
+		},
+		Retry: &RetryTransportConfig{},
+	})
+
+	resp, err := client.Get(server.URL)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, int32(2), attempts.Load())
+	assert.Equal(t, int32(2), logged.Load())
+}
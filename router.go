@@ -0,0 +1,127 @@
+package llms
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// RoutingStrategy decides how a Router picks a target for each request.
+type RoutingStrategy string
+
+const (
+	// RoundRobin sends requests to each target in turn.
+	RoundRobin RoutingStrategy = "round_robin"
+	// LeastPending sends each request to the target with the fewest in-flight
+	// requests, breaking ties by target order.
+	LeastPending RoutingStrategy = "least_pending"
+	// Weighted distributes requests proportionally to each target's Weight using
+	// smooth weighted round-robin.
+	Weighted RoutingStrategy = "weighted"
+)
+
+// RouterTarget is a single LLM in a Router's pool.
+type RouterTarget struct {
+	LLM LLM
+	// Weight is only used by the Weighted strategy. Targets with a weight of 0
+	// or less are treated as having a weight of 1.
+	Weight int
+}
+
+// Router is an LLM that distributes requests across a pool of LLMs. This is
+// useful for spreading load across multiple API keys or regional endpoints.
+// A Router is safe for concurrent use.
+type Router struct {
+	Strategy RoutingStrategy
+	Targets  []RouterTarget
+
+	mu      sync.Mutex
+	next    int
+	pending []int
+	current []int
+}
+
+// NewRouter creates a Router that distributes requests across the given LLMs
+// using strategy. All targets are given equal weight.
+func NewRouter(strategy RoutingStrategy, llms ...LLM) LLM {
+	targets := make([]RouterTarget, 0, len(llms))
+	for _, llm := range llms {
+		targets = append(targets, RouterTarget{LLM: llm, Weight: 1})
+	}
+
+	return &Router{
+		Strategy: strategy,
+		Targets:  targets,
+	}
+}
+
+// acquire picks a target and marks it as having one more pending request. The
+// returned func must be called once the request has finished.
+func (r *Router) acquire() (LLM, func(), error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if len(r.Targets) == 0 {
+		return nil, nil, errors.New("llms: router has no targets configured")
+	}
+
+	if len(r.pending) != len(r.Targets) {
+		r.pending = make([]int, len(r.Targets))
+		r.current = make([]int, len(r.Targets))
+	}
+
+	var idx int
+	switch r.Strategy {
+	case LeastPending:
+		for i := range r.Targets {
+			if r.pending[i] < r.pending[idx] {
+				idx = i
+			}
+		}
+	case Weighted:
+		total := 0
+		for i, t := range r.Targets {
+			w := max(t.Weight, 1)
+			total += w
+			r.current[i] += w
+			if r.current[i] > r.current[idx] {
+				idx = i
+			}
+		}
+		r.current[idx] -= total
+	default:
+		idx = r.next % len(r.Targets)
+		r.next++
+	}
+
+	r.pending[idx]++
+	release := func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		if idx < len(r.pending) {
+			r.pending[idx]--
+		}
+	}
+
+	return r.Targets[idx].LLM, release, nil
+}
+
+func (r *Router) Generate(ctx context.Context, messages []Message) (*Response, error) {
+	llm, release, err := r.acquire()
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	return llm.Generate(ctx, messages)
+}
+
+func (r *Router) GenerateStream(ctx context.Context, messages []Message, fn StreamFunc) (*Response, error) {
+	llm, release, err := r.acquire()
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	return llm.GenerateStream(ctx, messages, fn)
+}
@@ -0,0 +1,73 @@
+package llms
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRouter_RoundRobin(t *testing.T) {
+	a := newFakeLLM(fakeResult{resp: textResponse("a")})
+	b := newFakeLLM(fakeResult{resp: textResponse("b")})
+
+	router := NewRouter(RoundRobin, a, b)
+
+	ids := []string{}
+	for range 4 {
+		resp, err := router.Generate(context.Background(), nil)
+		require.NoError(t, err)
+		ids = append(ids, resp.ID)
+	}
+
+	assert.Equal(t, []string{"resp_a", "resp_b", "resp_a", "resp_b"}, ids)
+}
+
+func TestRouter_Weighted(t *testing.T) {
+	a := newFakeLLM(fakeResult{resp: textResponse("a")})
+	b := newFakeLLM(fakeResult{resp: textResponse("b")})
+
+	router := &Router{
+		Strategy: Weighted,
+		Targets: []RouterTarget{
+			{LLM: a, Weight: 3},
+			{LLM: b, Weight: 1},
+		},
+	}
+
+	for range 8 {
+		_, err := router.Generate(context.Background(), nil)
+		require.NoError(t, err)
+	}
+
+	assert.Equal(t, 6, a.Calls())
+	assert.Equal(t, 2, b.Calls())
+}
+
+func TestRouter_LeastPending(t *testing.T) {
+	a := newFakeLLM(fakeResult{resp: textResponse("a")})
+	b := newFakeLLM(fakeResult{resp: textResponse("b")})
+
+	router := NewRouter(LeastPending, a, b).(*Router)
+
+	// Hold a request open against the first target.
+	first, release, err := router.acquire()
+	require.NoError(t, err)
+	assert.Same(t, a, first)
+
+	resp, err := router.Generate(context.Background(), nil)
+	require.NoError(t, err)
+	assert.Equal(t, "resp_b", resp.ID)
+
+	release()
+
+	resp, err = router.Generate(context.Background(), nil)
+	require.NoError(t, err)
+	assert.Equal(t, "resp_a", resp.ID)
+}
+
+func TestRouter_NoTargets(t *testing.T) {
+	_, err := (&Router{}).Generate(context.Background(), nil)
+	assert.Error(t, err)
+}
@@ -0,0 +1,376 @@
+package llms
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/invopop/jsonschema"
+	orderedmap "github.com/wk8/go-ordered-map/v2"
+)
+
+// SchemaOptions controls the shape of the schemas generated by
+// GenerateSchemaWithOptions. The zero value produces the same schemas as
+// GenerateSchema.
+type SchemaOptions struct {
+	// AllowAdditionalProperties omits "additionalProperties": false from
+	// object schemas, so the model may add properties of its own.
+	AllowAdditionalProperties bool
+	// UseReferences defines named types once under "$defs" and refers to
+	// them with "$ref", instead of inlining them wherever they are used. The
+	// root type is always inlined.
+	UseReferences bool
+	// Descriptions replaces the descriptions of properties, keyed by their
+	// path of JSON property names separated by dots, such as
+	// "address.city". The empty path is the root schema. Arrays are stepped
+	// through, so "ingredients.name" is the name of each ingredient when
+	// "ingredients" is a list of objects.
+	Descriptions map[string]string
+	// SortProperties orders the properties of every object alphabetically
+	// rather than in the order of the struct fields. Models generate
+	// properties in the order they are listed.
+	SortProperties bool
+}
+
+// SchemaOption sets a field of SchemaOptions.
+type SchemaOption func(*SchemaOptions)
+
+// AllowAdditionalProperties sets SchemaOptions.AllowAdditionalProperties.
+func AllowAdditionalProperties(allow bool) SchemaOption {
+	return func(o *SchemaOptions) {
+		o.AllowAdditionalProperties = allow
+	}
+}
+
+// UseReferences sets SchemaOptions.UseReferences.
+func UseReferences(use bool) SchemaOption {
+	return func(o *SchemaOptions) {
+		o.UseReferences = use
+	}
+}
+
+// PropertyDescription replaces the description of the property at path. See
+// SchemaOptions.Descriptions.
+func PropertyDescription(path, description string) SchemaOption {
+	return func(o *SchemaOptions) {
+		if o.Descriptions == nil {
+			o.Descriptions = make(map[string]string)
+		}
+		o.Descriptions[path] = description
+	}
+}
+
+// SortProperties sets SchemaOptions.SortProperties.
+func SortProperties(sort bool) SchemaOption {
+	return func(o *SchemaOptions) {
+		o.SortProperties = sort
+	}
+}
+
+// schemaCacheKey identifies a generated schema. Schemas with Descriptions are
+// not cached, as maps are not comparable.
+type schemaCacheKey struct {
+	t                         reflect.Type
+	allowAdditionalProperties bool
+	useReferences             bool
+	sortProperties            bool
+}
+
+// schemaCache holds the schemas generated by GenerateSchemaWithOptions, as
+// tools generate their schema for every request and reflection is slow. The
+// cached schemas are never handed out, only copies of them.
+var schemaCache sync.Map // schemaCacheKey -> *jsonschema.Schema
+
+// GenerateSchemaWithOptions generates a JSON schema for the given type T,
+// like GenerateSchema, with opts applied. Use it when a provider needs a
+// different shape of schema than the defaults, for example:
+//
+//	schema := llms.GenerateSchemaWithOptions[Recipe](
+//		llms.PropertyDescription("ingredients.quantity", "Quantity in grams"),
+//		llms.SortProperties(true),
+//	)
+//
+// Schemas are cached per type and options, and every call returns a copy of
+// the cached schema that the caller may modify.
+func GenerateSchemaWithOptions[T any](opts ...SchemaOption) *jsonschema.Schema {
+	var o SchemaOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	key := schemaCacheKey{
+		t:                         reflect.TypeFor[T](),
+		allowAdditionalProperties: o.AllowAdditionalProperties,
+		useReferences:             o.UseReferences,
+		sortProperties:            o.SortProperties,
+	}
+	cacheable := len(o.Descriptions) == 0
+	if cacheable {
+		if schema, ok := schemaCache.Load(key); ok {
+			return copySchema(schema.(*jsonschema.Schema))
+		}
+	}
+
+	schema := generateSchema[T](o)
+	if cacheable {
+		schemaCache.Store(key, copySchema(schema))
+	}
+	return schema
+}
+
+func generateSchema[T any](o SchemaOptions) *jsonschema.Schema {
+	reflector := jsonschema.Reflector{
+		AllowAdditionalProperties: o.AllowAdditionalProperties,
+		DoNotReference:            !o.UseReferences,
+		ExpandedStruct:            o.UseReferences,
+		Mapper:                    enumSchema,
+	}
+	var v T
+	schema := reflector.Reflect(v)
+
+	for path, description := range o.Descriptions {
+		if s := schemaAtPath(schema, path); s != nil {
+			s.Description = description
+		}
+	}
+	if o.SortProperties {
+		walkSchema(schema, sortProperties)
+	}
+
+	return schema
+}
+
+// SchemaMap returns the keywords of schema's root, each mapped to its JSON
+// encoding, for providers that take tool parameters as a map. Every keyword is
+// kept, such as "$defs" and "additionalProperties", except the "$schema" and
+// "$id" metadata. The values are json.RawMessage, so nested properties stay in
+// the schema's order.
+func SchemaMap(schema *jsonschema.Schema) (map[string]any, error) {
+	data, err := json.Marshal(schema)
+	if err != nil {
+		return nil, fmt.Errorf("llms: failed to marshal schema: %w", err)
+	}
+	var keywords map[string]json.RawMessage
+	if err := json.Unmarshal(data, &keywords); err != nil || keywords == nil {
+		return nil, errors.New("llms: schema is not an object")
+	}
+
+	out := make(map[string]any, len(keywords))
+	for keyword, value := range keywords {
+		if keyword != "$schema" && keyword != "$id" {
+			out[keyword] = value
+		}
+	}
+	return out, nil
+}
+
+// SchemaCache memoizes the conversion of tool schemas into a provider's
+// representation, so that it is not repeated for every request. Conversions
+// are keyed by the schema's JSON encoding rather than its pointer, so a schema
+// modified in place is converted again, and equal schemas share a conversion.
+// The conversions are shared between callers and must not be modified. The
+// zero value is ready to use and it is safe for concurrent use.
+type SchemaCache[V any] struct {
+	mu      sync.Mutex
+	entries map[[sha256.Size]byte]V
+}
+
+// maxSchemaCacheEntries bounds a SchemaCache, for tools that build a new
+// schema for every request. The cache is emptied when it is reached.
+const maxSchemaCacheEntries = 1024
+
+// Get returns the conversion of schema, calling convert if no schema with the
+// same content has been converted yet. Failed conversions are not cached.
+func (c *SchemaCache[V]) Get(schema *jsonschema.Schema, convert func(*jsonschema.Schema) (V, error)) (V, error) {
+	data, err := json.Marshal(schema)
+	if err != nil {
+		var zero V
+		return zero, fmt.Errorf("llms: failed to marshal schema: %w", err)
+	}
+	key := sha256.Sum256(data)
+
+	c.mu.Lock()
+	v, ok := c.entries[key]
+	c.mu.Unlock()
+	if ok {
+		return v, nil
+	}
+
+	v, err = convert(schema)
+	if err != nil {
+		return v, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.entries == nil || len(c.entries) >= maxSchemaCacheEntries {
+		c.entries = make(map[[sha256.Size]byte]V)
+	}
+	c.entries[key] = v
+	return v, nil
+}
+
+// copySchema returns a deep copy of s. The values of Enum, Const, Default,
+// Examples and Extras are shared, as the generated schemas only hold
+// immutable values there.
+func copySchema(s *jsonschema.Schema) *jsonschema.Schema {
+	if s == nil || s == jsonschema.TrueSchema || s == jsonschema.FalseSchema {
+		return s
+	}
+
+	out := *s
+	out.Definitions = copySchemaMap(s.Definitions)
+	out.AllOf = copySchemaSlice(s.AllOf)
+	out.AnyOf = copySchemaSlice(s.AnyOf)
+	out.OneOf = copySchemaSlice(s.OneOf)
+	out.Not = copySchema(s.Not)
+	out.If = copySchema(s.If)
+	out.Then = copySchema(s.Then)
+	out.Else = copySchema(s.Else)
+	out.DependentSchemas = copySchemaMap(s.DependentSchemas)
+	out.PrefixItems = copySchemaSlice(s.PrefixItems)
+	out.Items = copySchema(s.Items)
+	out.Contains = copySchema(s.Contains)
+	if s.Properties != nil {
+		out.Properties = orderedmap.New[string, *jsonschema.Schema](s.Properties.Len())
+		for pair := s.Properties.Oldest(); pair != nil; pair = pair.Next() {
+			out.Properties.Set(pair.Key, copySchema(pair.Value))
+		}
+	}
+	out.PatternProperties = copySchemaMap(s.PatternProperties)
+	out.AdditionalProperties = copySchema(s.AdditionalProperties)
+	out.PropertyNames = copySchema(s.PropertyNames)
+	out.ContentSchema = copySchema(s.ContentSchema)
+
+	out.Enum = slices.Clone(s.Enum)
+	out.Required = slices.Clone(s.Required)
+	out.Examples = slices.Clone(s.Examples)
+	out.Extras = maps.Clone(s.Extras)
+	if s.DependentRequired != nil {
+		out.DependentRequired = make(map[string][]string, len(s.DependentRequired))
+		for name, required := range s.DependentRequired {
+			out.DependentRequired[name] = slices.Clone(required)
+		}
+	}
+	for _, p := range []**uint64{
+		&out.MaxLength, &out.MinLength, &out.MaxItems, &out.MinItems,
+		&out.MaxContains, &out.MinContains, &out.MaxProperties, &out.MinProperties,
+	} {
+		if *p != nil {
+			v := **p
+			*p = &v
+		}
+	}
+	return &out
+}
+
+func copySchemaSlice(schemas []*jsonschema.Schema) []*jsonschema.Schema {
+	if schemas == nil {
+		return nil
+	}
+	out := make([]*jsonschema.Schema, len(schemas))
+	for i, s := range schemas {
+		out[i] = copySchema(s)
+	}
+	return out
+}
+
+func copySchemaMap[M ~map[string]*jsonschema.Schema](schemas M) M {
+	if schemas == nil {
+		return nil
+	}
+	out := make(M, len(schemas))
+	for name, s := range schemas {
+		out[name] = copySchema(s)
+	}
+	return out
+}
+
+// schemaAtPath returns the schema of the property at path within root, or nil
+// if there is none. References to root's definitions are followed on the way,
+// but the schema of the last property is returned as is, so that a
+// description set on it does not change the other uses of a definition.
+func schemaAtPath(root *jsonschema.Schema, path string) *jsonschema.Schema {
+	s := root
+	if path == "" {
+		return s
+	}
+
+	for _, name := range strings.Split(path, ".") {
+		s = resolveSchema(root, s)
+		for s != nil && s.Items != nil && s.Properties == nil {
+			s = resolveSchema(root, s.Items)
+		}
+		if s == nil || s.Properties == nil {
+			return nil
+		}
+
+		var ok bool
+		if s, ok = s.Properties.Get(name); !ok {
+			return nil
+		}
+	}
+	return s
+}
+
+// resolveSchema returns the definition s refers to, or s itself.
+func resolveSchema(root, s *jsonschema.Schema) *jsonschema.Schema {
+	if s == nil || s.Ref == "" {
+		return s
+	}
+	name, ok := strings.CutPrefix(s.Ref, "#/$defs/")
+	if !ok {
+		return nil
+	}
+	return root.Definitions[name]
+}
+
+// walkSchema calls fn for s and every schema nested in it.
+func walkSchema(s *jsonschema.Schema, fn func(*jsonschema.Schema)) {
+	if s == nil {
+		return
+	}
+	fn(s)
+
+	if s.Properties != nil {
+		for pair := s.Properties.Oldest(); pair != nil; pair = pair.Next() {
+			walkSchema(pair.Value, fn)
+		}
+	}
+	for _, def := range s.Definitions {
+		walkSchema(def, fn)
+	}
+	walkSchema(s.Items, fn)
+	walkSchema(s.AdditionalProperties, fn)
+	for _, list := range [][]*jsonschema.Schema{s.AllOf, s.AnyOf, s.OneOf, s.PrefixItems} {
+		for _, sub := range list {
+			walkSchema(sub, fn)
+		}
+	}
+}
+
+// sortProperties orders the properties of s by name.
+func sortProperties(s *jsonschema.Schema) {
+	if s.Properties == nil || s.Properties.Len() < 2 {
+		return
+	}
+
+	names := make([]string, 0, s.Properties.Len())
+	for pair := s.Properties.Oldest(); pair != nil; pair = pair.Next() {
+		names = append(names, pair.Key)
+	}
+	slices.Sort(names)
+
+	sorted := orderedmap.New[string, *jsonschema.Schema]()
+	for _, name := range names {
+		prop, _ := s.Properties.Get(name)
+		sorted.Set(name, prop)
+	}
+	s.Properties = sorted
+}
@@ -0,0 +1,179 @@
+package llms
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+	"testing"
+
+	"github.com/invopop/jsonschema"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type schemaIngredient struct {
+	Quantity int    `json:"quantity" jsonschema:"description=Amount"`
+	Name     string `json:"name"`
+}
+
+type schemaRecipe struct {
+	Title       string             `json:"title"`
+	Ingredients []schemaIngredient `json:"ingredients"`
+	Main        schemaIngredient   `json:"main"`
+}
+
+// propertyNames returns the names of the properties of s in order.
+func propertyNames(s *jsonschema.Schema) []string {
+	var names []string
+	for pair := s.Properties.Oldest(); pair != nil; pair = pair.Next() {
+		names = append(names, pair.Key)
+	}
+	return names
+}
+
+func TestGenerateSchemaWithOptions_Defaults(t *testing.T) {
+	want, err := json.Marshal(GenerateSchema[schemaRecipe]())
+	require.NoError(t, err)
+	got, err := json.Marshal(GenerateSchemaWithOptions[schemaRecipe]())
+	require.NoError(t, err)
+	assert.JSONEq(t, string(want), string(got))
+	assert.Contains(t, string(got), `"additionalProperties":false`)
+}
+
+func TestGenerateSchemaWithOptions_AllowAdditionalProperties(t *testing.T) {
+	schema := GenerateSchemaWithOptions[schemaRecipe](AllowAdditionalProperties(true))
+
+	data, err := json.Marshal(schema)
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "additionalProperties")
+}
+
+func TestGenerateSchemaWithOptions_UseReferences(t *testing.T) {
+	schema := GenerateSchemaWithOptions[schemaRecipe](UseReferences(true))
+
+	// The root is inlined, so providers still see an object schema.
+	assert.Equal(t, "object", schema.Type)
+	require.Contains(t, schema.Definitions, "schemaIngredient")
+
+	main, ok := schema.Properties.Get("main")
+	require.True(t, ok)
+	assert.Equal(t, "#/$defs/schemaIngredient", main.Ref)
+	ingredients, ok := schema.Properties.Get("ingredients")
+	require.True(t, ok)
+	assert.Equal(t, "#/$defs/schemaIngredient", ingredients.Items.Ref)
+}
+
+func TestSchemaMap(t *testing.T) {
+	keywords, err := SchemaMap(GenerateSchemaWithOptions[schemaRecipe](UseReferences(true)))
+	require.NoError(t, err)
+	assert.NotContains(t, keywords, "$schema")
+	assert.Equal(t, json.RawMessage(`false`), keywords["additionalProperties"])
+	assert.Contains(t, string(keywords["$defs"].(json.RawMessage)), "schemaIngredient")
+	// Properties keep the order of the struct fields.
+	assert.Regexp(t, `^\{"title":.*"ingredients":.*"main":`, string(keywords["properties"].(json.RawMessage)))
+
+	_, err = SchemaMap(jsonschema.TrueSchema)
+	assert.EqualError(t, err, "llms: schema is not an object")
+}
+
+func TestGenerateSchemaWithOptions_Descriptions(t *testing.T) {
+	for _, refs := range []bool{false, true} {
+		schema := GenerateSchemaWithOptions[schemaRecipe](
+			UseReferences(refs),
+			PropertyDescription("", "A recipe"),
+			PropertyDescription("title", "Name of the dish"),
+			PropertyDescription("ingredients.quantity", "Quantity in grams"),
+			PropertyDescription("main", "The main ingredient"),
+			PropertyDescription("missing.path", "ignored"),
+		)
+
+		assert.Equal(t, "A recipe", schema.Description)
+		assert.Equal(t, "Name of the dish", schemaAtPath(schema, "title").Description)
+		assert.Equal(t, "Quantity in grams", schemaAtPath(schema, "ingredients.quantity").Description)
+		assert.Equal(t, "The main ingredient", schemaAtPath(schema, "main").Description)
+		assert.Nil(t, schemaAtPath(schema, "missing.path"))
+	}
+
+	// Without references each use of a type has its own schema, so only the
+	// property at the path is changed.
+	schema := GenerateSchemaWithOptions[schemaRecipe](PropertyDescription("ingredients.quantity", "Quantity in grams"))
+	assert.Equal(t, "Amount", schemaAtPath(schema, "main.quantity").Description)
+}
+
+func TestGenerateSchemaWithOptions_SortProperties(t *testing.T) {
+	schema := GenerateSchemaWithOptions[schemaRecipe]()
+	assert.Equal(t, []string{"title", "ingredients", "main"}, propertyNames(schema))
+
+	schema = GenerateSchemaWithOptions[schemaRecipe](SortProperties(true))
+	assert.Equal(t, []string{"ingredients", "main", "title"}, propertyNames(schema))
+	assert.Equal(t, []string{"name", "quantity"}, propertyNames(schemaAtPath(schema, "main")))
+	assert.Equal(t, []string{"name", "quantity"}, propertyNames(schemaAtPath(schema, "ingredients").Items))
+
+	// Sorting does not change which properties are required.
+	assert.ElementsMatch(t, []string{"title", "ingredients", "main"}, schema.Required)
+}
+
+func TestGenerateSchemaWithOptions_Cached(t *testing.T) {
+	schema := GenerateSchema[schemaRecipe]()
+	assert.Equal(t, schema, GenerateSchema[schemaRecipe]())
+	assert.Equal(t, schema, GenerateSchemaWithOptions[schemaRecipe]())
+	assert.NotEqual(t, schema, GenerateSchemaWithOptions[schemaRecipe](SortProperties(true)))
+
+	// Every call returns a copy, so modifying one does not change the others.
+	schema.Required = append(schema.Required, "extra")
+	schemaAtPath(schema, "main.name").Description = "Changed"
+	schema.Properties.Set("extra", &jsonschema.Schema{Type: "string"})
+	fresh := GenerateSchema[schemaRecipe]()
+	assert.NotContains(t, fresh.Required, "extra")
+	assert.Empty(t, schemaAtPath(fresh, "main.name").Description)
+	_, ok := fresh.Properties.Get("extra")
+	assert.False(t, ok)
+
+	// Descriptions modify the schema, so it is generated afresh.
+	described := GenerateSchemaWithOptions[schemaRecipe](PropertyDescription("title", "Name"))
+	assert.Equal(t, "Name", schemaAtPath(described, "title").Description)
+	assert.Empty(t, schemaAtPath(GenerateSchema[schemaRecipe](), "title").Description)
+}
+
+func TestSchemaCache(t *testing.T) {
+	var cache SchemaCache[string]
+	calls := 0
+	convert := func(s *jsonschema.Schema) (string, error) {
+		calls++
+		return s.Type, nil
+	}
+
+	got, err := cache.Get(&jsonschema.Schema{Type: "object"}, convert)
+	require.NoError(t, err)
+	assert.Equal(t, "object", got)
+
+	// Schemas are keyed by content, so an equal schema is not converted again.
+	schema := &jsonschema.Schema{Type: "object"}
+	got, err = cache.Get(schema, convert)
+	require.NoError(t, err)
+	assert.Equal(t, "object", got)
+	assert.Equal(t, 1, calls)
+
+	// A schema modified in place is converted again.
+	schema.Type = "array"
+	got, err = cache.Get(schema, convert)
+	require.NoError(t, err)
+	assert.Equal(t, "array", got)
+	assert.Equal(t, 2, calls)
+
+	// Failed conversions are not cached.
+	failing := func(*jsonschema.Schema) (string, error) { return "", errors.New("boom") }
+	_, err = cache.Get(&jsonschema.Schema{Type: "string"}, failing)
+	assert.EqualError(t, err, "boom")
+	got, err = cache.Get(&jsonschema.Schema{Type: "string"}, convert)
+	require.NoError(t, err)
+	assert.Equal(t, "string", got)
+	assert.Equal(t, 3, calls)
+
+	// The cache is bounded.
+	for i := range maxSchemaCacheEntries {
+		_, err := cache.Get(&jsonschema.Schema{Type: "string", Title: strconv.Itoa(i)}, convert)
+		require.NoError(t, err)
+	}
+	assert.LessOrEqual(t, len(cache.entries), maxSchemaCacheEntries)
+}
@@ -0,0 +1,243 @@
+package llms
+
+import (
+	"context"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// PIIPattern detects a kind of sensitive data in outgoing messages. See
+// ScrubPII.
+type PIIPattern struct {
+	// Name identifies the kind of data, such as "email". Matches are masked
+	// as the upper-cased name in brackets, such as "[EMAIL]".
+	Name    string
+	Pattern *regexp.Regexp
+	// Validate, if set, filters out false positives among the matches.
+	Validate func(match string) bool
+}
+
+// DefaultPIIPatterns are the patterns ScrubPII uses unless configured
+// otherwise. They detect email addresses, credit card numbers, US social
+// security numbers, phone numbers, IPv4 addresses, and common secrets: AWS
+// access keys, API keys in the sk-... style, bearer tokens and private keys.
+// Like any pattern matching, they miss some data and flag some that isn't
+// sensitive.
+var DefaultPIIPatterns = []PIIPattern{
+	{Name: "private_key", Pattern: regexp.MustCompile(`-----BEGIN [A-Z ]*PRIVATE KEY-----[\s\S]*?(?:-----END [A-Z ]*PRIVATE KEY-----|$)`)},
+	{Name: "aws_access_key", Pattern: regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`)},
+	{Name: "api_key", Pattern: regexp.MustCompile(`\bsk-[A-Za-z0-9_-]{20,}`)},
+	{Name: "bearer_token", Pattern: regexp.MustCompile(`(?i)\bbearer\s+[A-Za-z0-9._~+/-]{20,}=*`)},
+	{Name: "email", Pattern: regexp.MustCompile(`\b[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}\b`)},
+	{Name: "credit_card", Pattern: regexp.MustCompile(`\b(?:\d[ -]?){12,18}\d\b`), Validate: luhnValid},
+	{Name: "ssn", Pattern: regexp.MustCompile(`\b\d{3}-\d{2}-\d{4}\b`)},
+	{Name: "phone", Pattern: regexp.MustCompile(`(?:\+\d{1,3}[ .-]?)?(?:\(\d{3}\)|\b\d{3})[ .-]?\d{3}[ .-]?\d{4}\b`)},
+	{Name: "ip_address", Pattern: regexp.MustCompile(`\b(?:(?:25[0-5]|2[0-4]\d|1?\d?\d)\.){3}(?:25[0-5]|2[0-4]\d|1?\d?\d)\b`)},
+}
+
+// luhnValid reports whether the digits in s pass the Luhn checksum used by
+// credit card numbers.
+func luhnValid(s string) bool {
+	sum, double := 0, false
+	for i := len(s) - 1; i >= 0; i-- {
+		c := s[i]
+		if c < '0' || c > '9' {
+			continue
+		}
+		d := int(c - '0')
+		if double {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+	}
+	return sum%10 == 0
+}
+
+// ScrubFinding records sensitive data found in a message. It never holds the
+// data itself, so it is safe to log.
+type ScrubFinding struct {
+	// Pattern is the name of the pattern that matched.
+	Pattern string
+	// Message and Part are the indexes of the message and of the part in it.
+	Message int
+	Part    int
+	// Count is the number of matches in the part.
+	Count int
+}
+
+// ScrubRecord is the audit record of a request checked by ScrubPII.
+type ScrubRecord struct {
+	Findings []ScrubFinding
+	// Rejected is true if the request was rejected rather than masked.
+	Rejected bool
+}
+
+// PIIError is returned by the ScrubPII middleware when it rejects a request.
+type PIIError struct {
+	Record ScrubRecord
+}
+
+func (e *PIIError) Error() string {
+	var names []string
+	for _, f := range e.Record.Findings {
+		if !slices.Contains(names, f.Pattern) {
+			names = append(names, f.Pattern)
+		}
+	}
+	return "llms: request contains sensitive data: " + strings.Join(names, ", ")
+}
+
+// ScrubMessages returns a copy of messages with the matches of patterns
+// masked in their text parts and tool results, along with what was found.
+// messages is not modified.
+func ScrubMessages(messages []Message, patterns []PIIPattern) ([]Message, []ScrubFinding) {
+	var findings []ScrubFinding
+	out := make([]Message, len(messages))
+	for i, msg := range messages {
+		out[i] = msg
+		cloned := false
+		for j, part := range msg.Parts {
+			var text string
+			switch p := part.(type) {
+			case TextPart:
+				text = p.Text
+			case ToolResultPart:
+				text = p.Result
+			default:
+				continue
+			}
+
+			scrubbed := text
+			for _, pattern := range patterns {
+				var count int
+				scrubbed, count = maskPattern(scrubbed, pattern)
+				if count > 0 {
+					findings = append(findings, ScrubFinding{Pattern: pattern.Name, Message: i, Part: j, Count: count})
+				}
+			}
+			if scrubbed == text {
+				continue
+			}
+
+			if !cloned {
+				out[i].Parts = slices.Clone(msg.Parts)
+				cloned = true
+			}
+			switch p := part.(type) {
+			case TextPart:
+				p.Text = scrubbed
+				out[i].Parts[j] = p
+			case ToolResultPart:
+				p.Result = scrubbed
+				out[i].Parts[j] = p
+			}
+		}
+	}
+	return out, findings
+}
+
+// maskPattern masks the matches of pattern in text, returning the number of
+// matches masked.
+func maskPattern(text string, pattern PIIPattern) (string, int) {
+	mask := "[" + strings.ToUpper(pattern.Name) + "]"
+	count := 0
+	text = pattern.Pattern.ReplaceAllStringFunc(text, func(match string) string {
+		if pattern.Validate != nil && !pattern.Validate(match) {
+			return match
+		}
+		count++
+		return mask
+	})
+	return text, count
+}
+
+// ScrubAction is what the ScrubPII middleware does with a request that
+// contains sensitive data.
+type ScrubAction int
+
+const (
+	// ScrubMask replaces the sensitive data with a mask naming its kind,
+	// such as "[EMAIL]", before sending the request.
+	ScrubMask ScrubAction = iota
+	// ScrubReject fails the call with a *PIIError without sending it.
+	ScrubReject
+)
+
+// ScrubConfig configures the ScrubPII middleware.
+type ScrubConfig struct {
+	// Patterns detect the sensitive data. Defaults to DefaultPIIPatterns.
+	Patterns []PIIPattern
+	// Action is what to do with a request that contains sensitive data.
+	// Defaults to ScrubMask.
+	Action ScrubAction
+	// Audit, if set, is called with the record of every request in which
+	// sensitive data was found. It is called synchronously, so it should not
+	// block.
+	Audit func(ctx context.Context, record ScrubRecord)
+}
+
+// ScrubPII returns a Middleware that scans the text and tool results of
+// outgoing messages for sensitive data, such as email addresses and API keys,
+// and masks it or rejects the request according to config.Action. The whole
+// conversation is scanned on every call, as masks are not stored in the
+// caller's history.
+//
+//	client := llms.Chain(anthropic.New(), llms.ScrubPII(llms.ScrubConfig{
+//		Audit: func(ctx context.Context, r llms.ScrubRecord) {
+//			slog.InfoContext(ctx, "scrubbed request", "findings", len(r.Findings))
+//		},
+//	}))
+func ScrubPII(config ScrubConfig) Middleware {
+	if config.Patterns == nil {
+		config.Patterns = DefaultPIIPatterns
+	}
+	return func(llm LLM) LLM {
+		return &scrubbed{llm: llm, config: config}
+	}
+}
+
+type scrubbed struct {
+	llm    LLM
+	config ScrubConfig
+}
+
+func (s *scrubbed) ModelName() string {
+	return modelName(s.llm)
+}
+
+func (s *scrubbed) Generate(ctx context.Context, messages []Message) (*Response, error) {
+	messages, err := s.scrub(ctx, messages)
+	if err != nil {
+		return nil, err
+	}
+	return s.llm.Generate(ctx, messages)
+}
+
+func (s *scrubbed) GenerateStream(ctx context.Context, messages []Message, fn StreamFunc) (*Response, error) {
+	messages, err := s.scrub(ctx, messages)
+	if err != nil {
+		return nil, err
+	}
+	return s.llm.GenerateStream(ctx, messages, fn)
+}
+
+func (s *scrubbed) scrub(ctx context.Context, messages []Message) ([]Message, error) {
+	out, findings := ScrubMessages(messages, s.config.Patterns)
+	if len(findings) == 0 {
+		return messages, nil
+	}
+
+	record := ScrubRecord{Findings: findings, Rejected: s.config.Action == ScrubReject}
+	if s.config.Audit != nil {
+		s.config.Audit(ctx, record)
+	}
+	if record.Rejected {
+		return nil, &PIIError{Record: record}
+	}
+	return out, nil
+}
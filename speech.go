@@ -0,0 +1,45 @@
+package llms
+
+import "context"
+
+// SpeechRequest describes the speech to generate. See Speaker.
+type SpeechRequest struct {
+	// Text is the text to speak.
+	Text string
+	// Voice is the name of the provider's voice to speak with. If empty, the
+	// client's default voice is used.
+	Voice string
+	// Format is the MIME type of the audio, such as "audio/mpeg" or
+	// "audio/wav". If empty, the provider's default is used.
+	Format string
+	// Instructions, if set, describe how to speak, such as "Say cheerfully".
+	// Providers without such a setting ignore it.
+	Instructions string
+}
+
+// Speaker turns text into speech. Providers that offer text-to-speech models
+// implement it.
+type Speaker interface {
+	// Speak returns the speech for req as audio whose Transcript is
+	// req.Text.
+	Speak(ctx context.Context, req SpeechRequest) (AudioPart, error)
+}
+
+// TranscriptionRequest describes the audio to transcribe. See Transcriber.
+type TranscriptionRequest struct {
+	// Audio is the audio to transcribe.
+	Audio AudioPart
+	// Language, if set, is the ISO-639-1 code of the spoken language, such
+	// as "en", which improves accuracy and latency.
+	Language string
+	// Prompt, if set, is text that guides the transcription, such as the
+	// spelling of names or the previous part of the recording.
+	Prompt string
+}
+
+// Transcriber turns speech into text. Providers that offer speech-to-text
+// models implement it.
+type Transcriber interface {
+	// Transcribe returns the text spoken in req.Audio.
+	Transcribe(ctx context.Context, req TranscriptionRequest) (string, error)
+}
@@ -117,3 +117,41 @@ func (t CalculatorTool) Execute(ctx context.Context, args []byte) *llms.ToolResu
 	}
 }
 
+
+// RouteTool plans a route between two places. Its schema defines the place
+// type once under "$defs", to test schemas using references.
+type RouteTool struct{}
+
+func (t RouteTool) Name() string {
+	return "plan_route"
+}
+
+func (t RouteTool) Description() string {
+	return "Plan a route between two places"
+}
+
+type RoutePlace struct {
+	City    string `json:"city"`
+	Country string `json:"country"`
+}
+
+type RouteToolParams struct {
+	From RoutePlace `json:"from"`
+	To   RoutePlace `json:"to"`
+}
+
+func (t RouteTool) Schema() *jsonschema.Schema {
+	return llms.GenerateSchemaWithOptions[RouteToolParams](llms.UseReferences(true))
+}
+
+func (t RouteTool) Execute(ctx context.Context, args []byte) *llms.ToolResult {
+	var params RouteToolParams
+	if err := json.Unmarshal(args, &params); err != nil {
+		return &llms.ToolResult{ID: "route", Error: err}
+	}
+
+	return &llms.ToolResult{
+		ID:      "route",
+		Content: params.From.City + ` to ` + params.To.City + `: 3 hours`,
+	}
+}
@@ -1,6 +1,12 @@
 package llms
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+
 	"github.com/invopop/jsonschema"
 )
 
@@ -24,24 +30,253 @@ type Tool interface {
 	Schema() *jsonschema.Schema
 }
 
+// ExecutableTool is a Tool that runs locally. RunTools executes the calls the
+// model makes to it and sends the results back. Tools that only describe a
+// capability the provider executes itself, such as a hosted web search,
+// implement Tool alone.
+type ExecutableTool interface {
+	Tool
+
+	// Execute runs the tool with the input generated by the model, which is
+	// JSON matching the tool's Schema. Failures are reported in the result's
+	// Error, which is sent back to the model.
+	Execute(ctx context.Context, args []byte) *ToolResult
+}
+
+// OutputSchemaTool is implemented by tools whose results are JSON matching a
+// schema. The schema is added to the tool's description for the model (see
+// ToolDescription), and RunTools validates results against it.
+type OutputSchemaTool interface {
+	Tool
+
+	// OutputSchema returns the JSON schema of the tool's results.
+	OutputSchema() *jsonschema.Schema
+}
+
+// toolOutputSchema returns the output schema of tool, or of the tool it
+// renames, or nil.
+func toolOutputSchema(tool Tool) *jsonschema.Schema {
+	if t, ok := tool.(OutputSchemaTool); ok {
+		return t.OutputSchema()
+	}
+	if t, ok := OriginalTool(tool).(OutputSchemaTool); ok {
+		return t.OutputSchema()
+	}
+	return nil
+}
+
+// ToolDescription returns the description of tool sent to the model. For an
+// OutputSchemaTool, it is followed by the output schema, so the model knows
+// what the results will contain. Providers use it in place of Description.
+func ToolDescription(tool Tool) string {
+	schema := toolOutputSchema(tool)
+	if schema == nil {
+		return tool.Description()
+	}
+	// The schema's identifiers mean nothing to the model.
+	trimmed := *schema
+	trimmed.Version = ""
+	trimmed.ID = ""
+	data, err := json.Marshal(&trimmed)
+	if err != nil {
+		return tool.Description()
+	}
+	return tool.Description() + "\n\nThe result is JSON matching this schema: " + string(data)
+}
+
 // ToolResult represents the result of tool execution
 type ToolResult struct {
 	ID      string `json:"id"`
 	Content string `json:"content"`
 	Error   error  `json:"error,omitempty"`
+	// Kind classifies Error, telling RunTools how to handle it. If it is
+	// ToolErrorUser, the kind of a ToolError wrapped by Error is used
+	// instead.
+	Kind ToolErrorKind `json:"kind,omitempty"`
+}
+
+// errorKind returns the kind of the result's error.
+func (r *ToolResult) errorKind() ToolErrorKind {
+	if r.Kind != ToolErrorUser {
+		return r.Kind
+	}
+	var toolErr *ToolError
+	if errors.As(r.Error, &toolErr) {
+		return toolErr.Kind
+	}
+	return ToolErrorUser
+}
+
+// ToolErrorKind classifies tool errors.
+type ToolErrorKind int
+
+const (
+	// ToolErrorUser is an error the model can correct, such as invalid
+	// input. It is reported back to the model. This is the default.
+	ToolErrorUser ToolErrorKind = iota
+	// ToolErrorTransient is a temporary failure, such as a timeout in a
+	// service the tool depends on. RunTools retries the tool up to
+	// RunToolsOptions.ToolRetries times before reporting it to the model.
+	ToolErrorTransient
+	// ToolErrorFatal is an error that makes continuing pointless, such as
+	// missing credentials. RunTools stops and returns it.
+	ToolErrorFatal
+)
+
+func (k ToolErrorKind) String() string {
+	switch k {
+	case ToolErrorUser:
+		return "user"
+	case ToolErrorTransient:
+		return "transient"
+	case ToolErrorFatal:
+		return "fatal"
+	default:
+		return fmt.Sprintf("ToolErrorKind(%d)", int(k))
+	}
+}
+
+// ToolError classifies an error returned by a tool. Tools created with NewTool
+// can return one to have the error retried or abort RunTools:
+//
+//	return "", &llms.ToolError{Kind: llms.ToolErrorTransient, Err: err}
+type ToolError struct {
+	Kind ToolErrorKind
+	Err  error
+}
+
+func (e *ToolError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ToolError) Unwrap() error {
+	return e.Err
 }
 
 // GenerateSchema generates a JSON schema for the given type T.
 // This is a convenience wrapper around github.com/invopop/jsonschema that sets some
 // reasonable defaults for LLM tools. It is recommended to use this function to
 // generate the schema for your tool input types, but you can also construct the
-// schema manually if you need more control.
+// schema manually if you need more control, or use GenerateSchemaWithOptions.
+//
+// Fields can be limited to a fixed set of values with the enum keyword of the
+// jsonschema struct tag, such as `jsonschema:"enum=celsius,enum=fahrenheit"`,
+// or by giving them a type that implements SchemaEnumer. Models are much more
+// accurate when the values they may use are spelled out.
+//
+// Schemas are cached, and every call returns a copy of the cached schema that
+// the caller may modify.
 func GenerateSchema[T any]() *jsonschema.Schema {
-	reflector := jsonschema.Reflector{
-		AllowAdditionalProperties: false,
-		DoNotReference:            true,
+	return GenerateSchemaWithOptions[T]()
+}
+
+// SchemaEnumer is implemented by types whose values are limited to a fixed
+// set, such as string enumerations. GenerateSchema lists the values as the
+// enum of the type's schema wherever it is used.
+//
+//	type Unit string
+//
+//	func (Unit) SchemaEnum() []any { return []any{"celsius", "fahrenheit"} }
+type SchemaEnumer interface {
+	SchemaEnum() []any
+}
+
+var schemaEnumerType = reflect.TypeFor[SchemaEnumer]()
+
+// enumSchema returns the schema of t if it implements SchemaEnumer, or nil.
+func enumSchema(t reflect.Type) *jsonschema.Schema {
+	var enumer SchemaEnumer
+	switch {
+	case t.Kind() == reflect.Interface:
+		return nil
+	case t.Implements(schemaEnumerType):
+		enumer = reflect.Zero(t).Interface().(SchemaEnumer)
+	case reflect.PointerTo(t).Implements(schemaEnumerType):
+		enumer = reflect.New(t).Interface().(SchemaEnumer)
+	default:
+		return nil
+	}
+
+	schema := &jsonschema.Schema{Enum: enumer.SchemaEnum()}
+	switch t.Kind() {
+	case reflect.String:
+		schema.Type = "string"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		schema.Type = "integer"
+	case reflect.Float32, reflect.Float64:
+		schema.Type = "number"
+	case reflect.Bool:
+		schema.Type = "boolean"
+	}
+	return schema
+}
+
+// NewTool creates an ExecutableTool from a typed function. The schema is
+// generated from T with GenerateSchema, and the model's input is unmarshalled
+// into a T before fn is called. Errors returned by fn are reported back to the
+// model as tool errors, unless they are classified otherwise by a ToolError.
+func NewTool[T any](name, description string, fn func(ctx context.Context, params T) (string, error)) ExecutableTool {
+	return &funcTool[T]{
+		name:        name,
+		description: description,
+		schema:      GenerateSchema[T](),
+		fn:          fn,
+	}
+}
+
+// NewStructuredTool creates an ExecutableTool from a typed function whose result
+// is a value rather than text, like NewTool. The result is marshalled to JSON
+// and the tool is an OutputSchemaTool with an output schema generated from
+// Out.
+func NewStructuredTool[In, Out any](name, description string, fn func(ctx context.Context, params In) (Out, error)) ExecutableTool {
+	return &structuredTool[In, Out]{
+		funcTool: funcTool[In]{
+			name:        name,
+			description: description,
+			schema:      GenerateSchema[In](),
+			fn: func(ctx context.Context, params In) (string, error) {
+				out, err := fn(ctx, params)
+				if err != nil {
+					return "", err
+				}
+				data, err := json.Marshal(out)
+				if err != nil {
+					return "", fmt.Errorf("failed to marshal result of tool %q: %w", name, err)
+				}
+				return string(data), nil
+			},
+		},
+		outputSchema: GenerateSchema[Out](),
+	}
+}
+
+type structuredTool[In, Out any] struct {
+	funcTool[In]
+	outputSchema *jsonschema.Schema
+}
+
+func (t *structuredTool[In, Out]) OutputSchema() *jsonschema.Schema { return t.outputSchema }
+
+type funcTool[T any] struct {
+	name        string
+	description string
+	schema      *jsonschema.Schema
+	fn          func(context.Context, T) (string, error)
+}
+
+func (t *funcTool[T]) Name() string               { return t.name }
+func (t *funcTool[T]) Description() string        { return t.description }
+func (t *funcTool[T]) Schema() *jsonschema.Schema { return t.schema }
+
+func (t *funcTool[T]) Execute(ctx context.Context, args []byte) *ToolResult {
+	var params T
+	if len(args) > 0 {
+		if err := json.Unmarshal(args, &params); err != nil {
+			return &ToolResult{Error: fmt.Errorf("invalid input for tool %q: %w", t.name, err)}
+		}
 	}
-	var v T
 
-	return reflector.Reflect(v)
+	content, err := t.fn(ctx, params)
+	return &ToolResult{Content: content, Error: err}
 }
@@ -1,6 +1,10 @@
 package llms
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"testing"
 	"time"
 
@@ -320,4 +324,102 @@ func TestGenerateSchema_MapTypes(t *testing.T) {
 	intMapSchema, exists := schema.Properties.Get("int_map")
 	assert.True(t, exists)
 	assert.Equal(t, "object", intMapSchema.Type)
-}
\ No newline at end of file
+}
+type enumUnit string
+
+func (enumUnit) SchemaEnum() []any { return []any{"celsius", "fahrenheit"} }
+
+type enumLevel int
+
+func (*enumLevel) SchemaEnum() []any { return []any{1, 2, 3} }
+
+type StructWithEnums struct {
+	Unit    enumUnit   `json:"unit"`
+	Units   []enumUnit `json:"units"`
+	Level   *enumLevel `json:"level,omitempty"`
+	Mode    string     `json:"mode" jsonschema:"enum=fast,enum=slow"`
+	Retries int        `json:"retries" jsonschema:"enum=0,enum=3"`
+}
+
+func TestGenerateSchema_Enums(t *testing.T) {
+	schema := GenerateSchema[StructWithEnums]()
+
+	unit, ok := schema.Properties.Get("unit")
+	require.True(t, ok)
+	assert.Equal(t, "string", unit.Type)
+	assert.Equal(t, []any{"celsius", "fahrenheit"}, unit.Enum)
+
+	units, ok := schema.Properties.Get("units")
+	require.True(t, ok)
+	assert.Equal(t, []any{"celsius", "fahrenheit"}, units.Items.Enum)
+
+	// Pointer receivers and pointer fields are supported.
+	level, ok := schema.Properties.Get("level")
+	require.True(t, ok)
+	assert.Equal(t, "integer", level.Type)
+	assert.Equal(t, []any{1, 2, 3}, level.Enum)
+
+	mode, ok := schema.Properties.Get("mode")
+	require.True(t, ok)
+	assert.Equal(t, []any{"fast", "slow"}, mode.Enum)
+
+	retries, ok := schema.Properties.Get("retries")
+	require.True(t, ok)
+	assert.Equal(t, []any{json.Number("0"), json.Number("3")}, retries.Enum)
+
+	// The enums are enforced when validating tool input.
+	tool := NewTool("convert", "", func(context.Context, StructWithEnums) (string, error) { return "", nil })
+	assert.NoError(t, ValidateToolInput(tool, []byte(`{"unit": "celsius", "units": [], "mode": "fast", "retries": 3}`)))
+	assert.Error(t, ValidateToolInput(tool, []byte(`{"unit": "kelvin", "units": [], "mode": "fast", "retries": 3}`)))
+}
+
+func TestNewStructuredTool(t *testing.T) {
+	type sum struct {
+		Total int `json:"total"`
+	}
+	tool := NewStructuredTool("add", "Adds numbers", func(ctx context.Context, p struct {
+		Numbers []int `json:"numbers"`
+	}) (sum, error) {
+		var s sum
+		for _, n := range p.Numbers {
+			s.Total += n
+		}
+		return s, nil
+	})
+
+	res := tool.Execute(context.Background(), []byte(`{"numbers":[1,2,3]}`))
+	require.NoError(t, res.Error)
+	assert.JSONEq(t, `{"total":6}`, res.Content)
+
+	schemaTool, ok := tool.(OutputSchemaTool)
+	require.True(t, ok)
+	assert.Equal(t, GenerateSchema[sum](), schemaTool.OutputSchema())
+
+	assert.Equal(t, "Adds numbers\n\nThe result is JSON matching this schema: "+
+		`{"properties":{"total":{"type":"integer"}},"additionalProperties":false,"type":"object","required":["total"]}`,
+		ToolDescription(tool))
+	assert.Equal(t, "Echoes its input", ToolDescription(echoTool{}))
+}
+
+func TestNewTool(t *testing.T) {
+	tool := NewTool("greet", "Greets someone", func(ctx context.Context, p SimpleStruct) (string, error) {
+		if p.Age < 0 {
+			return "", errors.New("age must not be negative")
+		}
+		return fmt.Sprintf("Hello %s (%d)", p.Name, p.Age), nil
+	})
+
+	assert.Equal(t, "greet", tool.Name())
+	assert.Equal(t, "Greets someone", tool.Description())
+	assert.Equal(t, GenerateSchema[SimpleStruct](), tool.Schema())
+
+	res := tool.Execute(context.Background(), []byte(`{"name":"Ada","age":36}`))
+	require.NoError(t, res.Error)
+	assert.Equal(t, "Hello Ada (36)", res.Content)
+
+	res = tool.Execute(context.Background(), []byte(`{"name":"Ada","age":-1}`))
+	assert.EqualError(t, res.Error, "age must not be negative")
+
+	res = tool.Execute(context.Background(), []byte(`{"name":`))
+	assert.ErrorContains(t, res.Error, `invalid input for tool "greet"`)
+}
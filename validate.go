@@ -0,0 +1,486 @@
+package llms
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/invopop/jsonschema"
+)
+
+// ValidationError is returned by ValidateToolInput when the input produced by
+// the model does not match the tool's schema. Its message is written to be fed
+// back to the model so it can correct the call. It is also returned by
+// ValidateToolOutput, with Output set, when a tool's result does not match its
+// output schema, and by Extract when the model's answer does not match the
+// schema of the extracted type.
+type ValidationError struct {
+	// Tool is the name of the tool being called. It is empty for the output
+	// of Extract.
+	Tool string
+	// Output is set if the tool's result, rather than its input, is invalid.
+	Output bool
+	// Issues lists every way in which the input violates the schema.
+	Issues []ValidationIssue
+}
+
+// ValidationIssue is a single schema violation.
+type ValidationIssue struct {
+	// Path is a JSON pointer to the offending value, or "" for the input as a
+	// whole.
+	Path string
+	// Message describes the violation.
+	Message string
+}
+
+func (i ValidationIssue) String() string {
+	if i.Path == "" {
+		return i.Message
+	}
+	return i.Path + ": " + i.Message
+}
+
+func (e *ValidationError) Error() string {
+	issues := make([]string, len(e.Issues))
+	for i, issue := range e.Issues {
+		issues[i] = issue.String()
+	}
+	what := "input"
+	if e.Output {
+		what = "output"
+	}
+	if e.Tool == "" {
+		return fmt.Sprintf("invalid %s: %s", what, strings.Join(issues, "; "))
+	}
+	return fmt.Sprintf("invalid %s for tool %q: %s", what, e.Tool, strings.Join(issues, "; "))
+}
+
+// ValidateToolInput checks input against tool.Schema(). It returns a
+// *ValidationError describing every violation, or nil if the input is valid or
+// the tool has no schema. Empty input is treated as an empty object.
+//
+// The common JSON Schema keywords are supported: type, enum, const, the
+// numeric, string, array and object constraints, allOf/anyOf/oneOf/not,
+// if/then/else, and local $ref. Unknown keywords, such as format, are ignored.
+func ValidateToolInput(tool Tool, input []byte) error {
+	schema := tool.Schema()
+	if schema == nil {
+		return nil
+	}
+
+	if len(bytes.TrimSpace(input)) == 0 {
+		input = []byte("{}")
+	}
+	return validateJSON(tool.Name(), schema, input, false)
+}
+
+// ValidateToolOutput checks output, the content of a tool's result, against
+// the output schema of an OutputSchemaTool. It returns a *ValidationError
+// describing every violation, or nil if the output is valid or the tool has no
+// output schema.
+func ValidateToolOutput(tool Tool, output string) error {
+	schema := toolOutputSchema(tool)
+	if schema == nil {
+		return nil
+	}
+	return validateJSON(tool.Name(), schema, []byte(output), true)
+}
+
+func validateJSON(toolName string, schema *jsonschema.Schema, data []byte, output bool) error {
+	// Validate against the schema's JSON form so that boolean schemas and
+	// references are interpreted exactly as the provider sees them.
+	raw, err := json.Marshal(schema)
+	if err != nil {
+		return fmt.Errorf("llms: failed to marshal schema for tool %q: %w", toolName, err)
+	}
+	var s any
+	if err := json.Unmarshal(raw, &s); err != nil {
+		return fmt.Errorf("llms: failed to unmarshal schema for tool %q: %w", toolName, err)
+	}
+
+	var v any
+	if err := json.Unmarshal(data, &v); err != nil {
+		what := "input"
+		if output {
+			what = "output"
+		}
+		return &ValidationError{
+			Tool:   toolName,
+			Output: output,
+			Issues: []ValidationIssue{{Message: what + " is not valid JSON: " + err.Error()}},
+		}
+	}
+
+	vd := &validator{root: s}
+	vd.validate(s, v, "")
+	if len(vd.issues) > 0 {
+		return &ValidationError{Tool: toolName, Output: output, Issues: vd.issues}
+	}
+
+	return nil
+}
+
+// maxValidationDepth bounds the nesting of schemas a value is validated
+// against, so that deeply recursive schemas fail rather than exhaust the
+// stack.
+const maxValidationDepth = 512
+
+type validator struct {
+	root   any
+	issues []ValidationIssue
+	// refs holds the references being followed for each path, to detect
+	// references that loop back to themselves without descending into the
+	// value.
+	refs  map[refVisit]bool
+	depth int
+}
+
+type refVisit struct {
+	ref, path string
+}
+
+func (vd *validator) addf(path, format string, args ...any) {
+	vd.issues = append(vd.issues, ValidationIssue{Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+// matches reports whether v is valid against schema without recording any
+// issues.
+func (vd *validator) matches(schema, v any, path string) bool {
+	sub := &validator{root: vd.root, refs: vd.refs, depth: vd.depth}
+	sub.validate(schema, v, path)
+	return len(sub.issues) == 0
+}
+
+func (vd *validator) validate(schema, v any, path string) {
+	if vd.depth >= maxValidationDepth {
+		vd.addf(path, "schema is nested more than %d levels deep", maxValidationDepth)
+		return
+	}
+	vd.depth++
+	defer func() { vd.depth-- }()
+
+	switch s := schema.(type) {
+	case bool:
+		if !s {
+			vd.addf(path, "value is not allowed")
+		}
+		return
+	case map[string]any:
+		vd.validateObjectSchema(s, v, path)
+	}
+}
+
+func (vd *validator) validateObjectSchema(s map[string]any, v any, path string) {
+	if ref, ok := s["$ref"].(string); ok {
+		if target, ok := vd.resolve(ref); ok {
+			visit := refVisit{ref: ref, path: path}
+			if vd.refs[visit] {
+				vd.addf(path, "schema reference %q is circular", ref)
+				return
+			}
+			if vd.refs == nil {
+				vd.refs = make(map[refVisit]bool)
+			}
+			vd.refs[visit] = true
+			vd.validate(target, v, path)
+			delete(vd.refs, visit)
+		}
+	}
+
+	if t, ok := s["type"]; ok && !typeMatches(t, v) {
+		vd.addf(path, "expected %s, got %s", describeType(t), jsonType(v))
+		return
+	}
+
+	if enum, ok := s["enum"].([]any); ok {
+		found := false
+		for _, e := range enum {
+			if reflect.DeepEqual(e, v) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			vd.addf(path, "must be one of %s", compactJSON(enum))
+		}
+	}
+
+	if c, ok := s["const"]; ok && !reflect.DeepEqual(c, v) {
+		vd.addf(path, "must be %s", compactJSON(c))
+	}
+
+	switch val := v.(type) {
+	case string:
+		vd.validateString(s, val, path)
+	case float64:
+		vd.validateNumber(s, val, path)
+	case []any:
+		vd.validateArray(s, val, path)
+	case map[string]any:
+		vd.validateObject(s, val, path)
+	}
+
+	if all, ok := s["allOf"].([]any); ok {
+		for _, sub := range all {
+			vd.validate(sub, v, path)
+		}
+	}
+
+	if anyOf, ok := s["anyOf"].([]any); ok {
+		matched := false
+		for _, sub := range anyOf {
+			if vd.matches(sub, v, path) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			vd.addf(path, "must match at least one of the allowed schemas")
+		}
+	}
+
+	if oneOf, ok := s["oneOf"].([]any); ok {
+		n := 0
+		for _, sub := range oneOf {
+			if vd.matches(sub, v, path) {
+				n++
+			}
+		}
+		if n != 1 {
+			vd.addf(path, "must match exactly one of the allowed schemas, matched %d", n)
+		}
+	}
+
+	if not, ok := s["not"]; ok && vd.matches(not, v, path) {
+		vd.addf(path, "must not match the disallowed schema")
+	}
+
+	if cond, ok := s["if"]; ok {
+		if vd.matches(cond, v, path) {
+			if then, ok := s["then"]; ok {
+				vd.validate(then, v, path)
+			}
+		} else if els, ok := s["else"]; ok {
+			vd.validate(els, v, path)
+		}
+	}
+}
+
+func (vd *validator) validateString(s map[string]any, v, path string) {
+	n := utf8.RuneCountInString(v)
+	if min, ok := s["minLength"].(float64); ok && float64(n) < min {
+		vd.addf(path, "must be at least %v characters long", min)
+	}
+	if max, ok := s["maxLength"].(float64); ok && float64(n) > max {
+		vd.addf(path, "must be at most %v characters long", max)
+	}
+	if pattern, ok := s["pattern"].(string); ok {
+		if re, err := regexp.Compile(pattern); err == nil && !re.MatchString(v) {
+			vd.addf(path, "must match pattern %q", pattern)
+		}
+	}
+}
+
+func (vd *validator) validateNumber(s map[string]any, v float64, path string) {
+	if min, ok := s["minimum"].(float64); ok && v < min {
+		vd.addf(path, "must be >= %v", min)
+	}
+	if max, ok := s["maximum"].(float64); ok && v > max {
+		vd.addf(path, "must be <= %v", max)
+	}
+	if min, ok := s["exclusiveMinimum"].(float64); ok && v <= min {
+		vd.addf(path, "must be > %v", min)
+	}
+	if max, ok := s["exclusiveMaximum"].(float64); ok && v >= max {
+		vd.addf(path, "must be < %v", max)
+	}
+	if m, ok := s["multipleOf"].(float64); ok && m > 0 {
+		if q := v / m; math.Abs(q-math.Round(q)) > 1e-9 {
+			vd.addf(path, "must be a multiple of %v", m)
+		}
+	}
+}
+
+func (vd *validator) validateArray(s map[string]any, v []any, path string) {
+	if min, ok := s["minItems"].(float64); ok && float64(len(v)) < min {
+		vd.addf(path, "must have at least %v items", min)
+	}
+	if max, ok := s["maxItems"].(float64); ok && float64(len(v)) > max {
+		vd.addf(path, "must have at most %v items", max)
+	}
+	if unique, _ := s["uniqueItems"].(bool); unique {
+	outer:
+		for i := range v {
+			for j := i + 1; j < len(v); j++ {
+				if reflect.DeepEqual(v[i], v[j]) {
+					vd.addf(path, "items must be unique")
+					break outer
+				}
+			}
+		}
+	}
+
+	prefix, _ := s["prefixItems"].([]any)
+	items, hasItems := s["items"]
+	for i, item := range v {
+		itemPath := path + "/" + strconv.Itoa(i)
+		switch {
+		case i < len(prefix):
+			vd.validate(prefix[i], item, itemPath)
+		case hasItems:
+			vd.validate(items, item, itemPath)
+		}
+	}
+}
+
+func (vd *validator) validateObject(s map[string]any, v map[string]any, path string) {
+	if required, ok := s["required"].([]any); ok {
+		for _, r := range required {
+			name, _ := r.(string)
+			if _, ok := v[name]; !ok {
+				vd.addf(path, "missing required property %q", name)
+			}
+		}
+	}
+	if min, ok := s["minProperties"].(float64); ok && float64(len(v)) < min {
+		vd.addf(path, "must have at least %v properties", min)
+	}
+	if max, ok := s["maxProperties"].(float64); ok && float64(len(v)) > max {
+		vd.addf(path, "must have at most %v properties", max)
+	}
+
+	properties, _ := s["properties"].(map[string]any)
+	patterns, _ := s["patternProperties"].(map[string]any)
+	additional, hasAdditional := s["additionalProperties"]
+
+	keys := make([]string, 0, len(v))
+	for k := range v {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		propPath := path + "/" + escapePointer(k)
+		matched := false
+
+		if prop, ok := properties[k]; ok {
+			matched = true
+			vd.validate(prop, v[k], propPath)
+		}
+		for pattern, prop := range patterns {
+			if re, err := regexp.Compile(pattern); err == nil && re.MatchString(k) {
+				matched = true
+				vd.validate(prop, v[k], propPath)
+			}
+		}
+
+		if matched || !hasAdditional {
+			continue
+		}
+		if allowed, ok := additional.(bool); ok && !allowed {
+			vd.addf(path, "unexpected property %q", k)
+			continue
+		}
+		vd.validate(additional, v[k], propPath)
+	}
+}
+
+// resolve looks up a local reference such as "#/$defs/Location".
+func (vd *validator) resolve(ref string) (any, bool) {
+	if !strings.HasPrefix(ref, "#") {
+		return nil, false
+	}
+
+	target := vd.root
+	for _, token := range strings.Split(strings.TrimPrefix(ref, "#"), "/") {
+		if token == "" {
+			continue
+		}
+		token = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+		m, ok := target.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		if target, ok = m[token]; !ok {
+			return nil, false
+		}
+	}
+
+	return target, true
+}
+
+func typeMatches(t, v any) bool {
+	switch t := t.(type) {
+	case string:
+		return typeIs(t, v)
+	case []any:
+		for _, tt := range t {
+			if name, ok := tt.(string); ok && typeIs(name, v) {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+func typeIs(t string, v any) bool {
+	switch t {
+	case "integer":
+		n, ok := v.(float64)
+		return ok && n == math.Trunc(n)
+	case "number":
+		_, ok := v.(float64)
+		return ok
+	default:
+		return jsonType(v) == t
+	}
+}
+
+func jsonType(v any) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+func describeType(t any) string {
+	if types, ok := t.([]any); ok {
+		names := make([]string, 0, len(types))
+		for _, tt := range types {
+			names = append(names, fmt.Sprint(tt))
+		}
+		return strings.Join(names, " or ")
+	}
+	return fmt.Sprint(t)
+}
+
+func escapePointer(s string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(s, "~", "~0"), "/", "~1")
+}
+
+func compactJSON(v any) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(b)
+}
@@ -0,0 +1,198 @@
+package llms
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/invopop/jsonschema"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	orderedmap "github.com/wk8/go-ordered-map/v2"
+)
+
+type schemaTool struct {
+	schema *jsonschema.Schema
+}
+
+func (schemaTool) Name() string                 { return "schema_tool" }
+func (schemaTool) Description() string          { return "" }
+func (s schemaTool) Schema() *jsonschema.Schema { return s.schema }
+
+type validateParams struct {
+	Location string   `json:"location" jsonschema:"minLength=2"`
+	Unit     string   `json:"unit,omitempty" jsonschema:"enum=celsius,enum=fahrenheit"`
+	Days     int      `json:"days,omitempty" jsonschema:"minimum=1,maximum=7"`
+	Tags     []string `json:"tags,omitempty" jsonschema:"maxItems=2"`
+	Nested   *struct {
+		Name string `json:"name"`
+	} `json:"nested,omitempty"`
+}
+
+func TestValidateToolInput(t *testing.T) {
+	tool := schemaTool{schema: GenerateSchema[validateParams]()}
+
+	tests := []struct {
+		name   string
+		input  string
+		issues []string
+	}{
+		{name: "valid", input: `{"location":"Paris","unit":"celsius","days":3,"tags":["a"]}`},
+		{name: "missing required", input: `{}`, issues: []string{`missing required property "location"`}},
+		{name: "empty input", input: ``, issues: []string{`missing required property "location"`}},
+		{name: "wrong type", input: `{"location":12}`, issues: []string{`/location: expected string, got number`}},
+		{name: "not an integer", input: `{"location":"Paris","days":1.5}`, issues: []string{`/days: expected integer, got number`}},
+		{name: "enum", input: `{"location":"Paris","unit":"kelvin"}`, issues: []string{`/unit: must be one of ["celsius","fahrenheit"]`}},
+		{name: "range", input: `{"location":"Paris","days":10}`, issues: []string{`/days: must be <= 7`}},
+		{name: "min length", input: `{"location":"P"}`, issues: []string{`/location: must be at least 2 characters long`}},
+		{name: "max items", input: `{"location":"Paris","tags":["a","b","c"]}`, issues: []string{`/tags: must have at most 2 items`}},
+		{name: "additional property", input: `{"location":"Paris","extra":true}`, issues: []string{`unexpected property "extra"`}},
+		{name: "nested", input: `{"location":"Paris","nested":{}}`, issues: []string{`/nested: missing required property "name"`}},
+		{
+			name:   "multiple issues",
+			input:  `{"unit":"kelvin","days":0}`,
+			issues: []string{`missing required property "location"`, `/days: must be >= 1`, `/unit: must be one of ["celsius","fahrenheit"]`},
+		},
+		{name: "not an object", input: `[]`, issues: []string{`expected object, got array`}},
+		{name: "invalid json", input: `{"location":`, issues: []string{`input is not valid JSON: unexpected end of JSON input`}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateToolInput(tool, []byte(tt.input))
+			if tt.issues == nil {
+				assert.NoError(t, err)
+				return
+			}
+
+			var verr *ValidationError
+			require.True(t, errors.As(err, &verr), "expected ValidationError, got %v", err)
+			assert.Equal(t, "schema_tool", verr.Tool)
+
+			issues := make([]string, len(verr.Issues))
+			for i, issue := range verr.Issues {
+				issues[i] = issue.String()
+			}
+			assert.Equal(t, tt.issues, issues)
+		})
+	}
+}
+
+func TestValidateToolInput_Combinators(t *testing.T) {
+	tool := schemaTool{schema: &jsonschema.Schema{
+		OneOf: []*jsonschema.Schema{
+			{Type: "string"},
+			{Type: "integer"},
+		},
+		Not: &jsonschema.Schema{Const: "forbidden"},
+	}}
+
+	assert.NoError(t, ValidateToolInput(tool, []byte(`"ok"`)))
+	assert.NoError(t, ValidateToolInput(tool, []byte(`3`)))
+	assert.EqualError(t, ValidateToolInput(tool, []byte(`true`)),
+		`invalid input for tool "schema_tool": must match exactly one of the allowed schemas, matched 0`)
+	assert.EqualError(t, ValidateToolInput(tool, []byte(`"forbidden"`)),
+		`invalid input for tool "schema_tool": must not match the disallowed schema`)
+}
+
+func TestValidateToolInput_Refs(t *testing.T) {
+	reflector := jsonschema.Reflector{}
+	tool := schemaTool{schema: reflector.Reflect(&validateParams{})}
+
+	assert.NoError(t, ValidateToolInput(tool, []byte(`{"location":"Paris"}`)))
+	assert.Error(t, ValidateToolInput(tool, []byte(`{"location":1}`)))
+}
+
+func TestValidateToolInput_RecursiveRefs(t *testing.T) {
+	// A reference that loops back without descending into the value.
+	circular := schemaTool{schema: &jsonschema.Schema{
+		Definitions: jsonschema.Definitions{
+			"a": {Ref: "#/$defs/b"},
+			"b": {Ref: "#/$defs/a"},
+		},
+		Ref: "#/$defs/a",
+	}}
+	assert.ErrorContains(t, ValidateToolInput(circular, []byte(`{}`)), `schema reference "#/$defs/a" is circular`)
+
+	// A recursive schema validates nested values down to a limit.
+	tree := schemaTool{schema: &jsonschema.Schema{
+		Type: "object",
+		Properties: func() *orderedmap.OrderedMap[string, *jsonschema.Schema] {
+			props := orderedmap.New[string, *jsonschema.Schema]()
+			props.Set("child", &jsonschema.Schema{Ref: "#"})
+			return props
+		}(),
+	}}
+	assert.NoError(t, ValidateToolInput(tree, []byte(`{"child": {"child": {}}}`)))
+	assert.ErrorContains(t, ValidateToolInput(tree, []byte(`{"child": {"child": 1}}`)), "expected object, got number")
+	deep := strings.Repeat(`{"child": `, maxValidationDepth) + `{}` + strings.Repeat(`}`, maxValidationDepth)
+	assert.ErrorContains(t, ValidateToolInput(tree, []byte(deep)), "schema is nested more than 512 levels deep")
+}
+
+func TestValidateToolInput_NoSchema(t *testing.T) {
+	assert.NoError(t, ValidateToolInput(schemaTool{}, []byte(`anything`)))
+}
+
+func TestRunTools_InvalidInput(t *testing.T) {
+	fake := newFakeLLM(
+		fakeResult{resp: toolCallResponse(ToolCallPart{ID: "call_1", Name: "echo", Input: []byte(`{"text":1}`)})},
+		fakeResult{resp: textResponse("done")},
+	)
+
+	result, err := RunTools(context.Background(), fake, nil, []Tool{echoTool{}}, RunToolsOptions{})
+	require.NoError(t, err)
+
+	part := result.Messages[1].Parts[0].(ToolResultPart)
+	var verr *ValidationError
+	require.ErrorAs(t, part.Error, &verr)
+	assert.Equal(t, `invalid input for tool "echo": /text: expected string, got number`, part.Result)
+}
+
+type forecast struct {
+	High float64 `json:"high"`
+	Low  float64 `json:"low"`
+}
+
+func TestValidateToolOutput(t *testing.T) {
+	tool := NewStructuredTool("forecast", "Gets the forecast", func(ctx context.Context, p struct{}) (forecast, error) {
+		return forecast{High: 20, Low: 12}, nil
+	})
+
+	assert.NoError(t, ValidateToolOutput(tool, `{"high":20,"low":12}`))
+	assert.EqualError(t, ValidateToolOutput(tool, `{"high":"hot"}`),
+		`invalid output for tool "forecast": missing required property "low"; /high: expected number, got string`)
+	assert.ErrorContains(t, ValidateToolOutput(tool, `sunny`), "output is not valid JSON")
+
+	// Renamed tools keep their output schema.
+	assert.Error(t, ValidateToolOutput(RenameTool(tool, "weather__forecast"), `sunny`))
+
+	// Tools without an output schema may return anything.
+	assert.NoError(t, ValidateToolOutput(echoTool{}, `anything`))
+}
+
+// badOutputTool declares an output schema its results do not match.
+type badOutputTool struct{ echoTool }
+
+func (badOutputTool) OutputSchema() *jsonschema.Schema { return GenerateSchema[forecast]() }
+
+func TestRunTools_InvalidOutput(t *testing.T) {
+	call := ToolCallPart{ID: "call_1", Name: "echo", Input: []byte(`{"text":"hi"}`)}
+
+	fake := newFakeLLM(fakeResult{resp: toolCallResponse(call)}, fakeResult{resp: textResponse("done")})
+	result, err := RunTools(context.Background(), fake, nil, []Tool{badOutputTool{}}, RunToolsOptions{})
+	require.NoError(t, err)
+
+	part := result.Messages[1].Parts[0].(ToolResultPart)
+	var verr *ValidationError
+	require.ErrorAs(t, part.Error, &verr)
+	assert.True(t, verr.Output)
+
+	fake = newFakeLLM(fakeResult{resp: toolCallResponse(call)}, fakeResult{resp: textResponse("done")})
+	result, err = RunTools(context.Background(), fake, nil, []Tool{badOutputTool{}}, RunToolsOptions{SkipValidation: true})
+	require.NoError(t, err)
+
+	part = result.Messages[1].Parts[0].(ToolResultPart)
+	assert.NoError(t, part.Error)
+	assert.Equal(t, "hi", part.Result)
+}